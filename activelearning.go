@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Uncertainty strategies of the select command, chosen with -strategy
+const (
+	// SelectLeastConfidence ranks tasks by their least confident region
+	SelectLeastConfidence = "least-confidence"
+	// SelectMeanConfidence ranks tasks by the mean score of their regions
+	SelectMeanConfidence = "mean-confidence"
+	// SelectEntropy ranks tasks by the summed entropy of their regions,
+	// favoring images with many uncertain regions
+	SelectEntropy = "entropy"
+)
+
+// maxSelectedPrinted limits the selected tasks listed in the summary
+const maxSelectedPrinted = 10
+
+// SelectOptions configure the active-learning selection of the select
+// command
+type SelectOptions struct {
+	// SourceDir holds the Label Studio JSON export, unless TasksFile names it
+	SourceDir string
+	TasksFile string
+	Strategy  string
+	// Budget is how many tasks to select; 0 ranks every candidate
+	Budget int
+	// OutputFile receives the selection: a CSV, or the task IDs one per
+	// line for .txt files
+	OutputFile string
+	// IncludeAnnotated also ranks tasks that already have annotations
+	IncludeAnnotated bool
+}
+
+// SelectedTask is a task picked for annotation
+type SelectedTask struct {
+	Rank   int
+	TaskID int
+	Image  string
+	// Uncertainty is the strategy's score: higher is more uncertain
+	Uncertainty float64
+	// MinScore is the score of the least confident region
+	MinScore float64
+	Regions  int
+}
+
+// SelectionReport is the result of an active-learning selection
+type SelectionReport struct {
+	Strategy string
+	Tasks    int
+	// Candidates are the tasks with scored predictions that were ranked;
+	// the others were annotated already, had no predictions or no scores
+	Candidates  int
+	Annotated   int
+	Unpredicted int
+	Unscored    int
+	Selected    []SelectedTask
+}
+
+// validate checks the selection options
+func (o SelectOptions) validate() error {
+	switch o.Strategy {
+	case SelectLeastConfidence, SelectMeanConfidence, SelectEntropy:
+	default:
+		return fmt.Errorf("unknown strategy %q (expected least-confidence, mean-confidence or entropy)", o.Strategy)
+	}
+	if o.Budget < 0 {
+		return fmt.Errorf("-budget can't be negative")
+	}
+	if o.OutputFile == "" {
+		return fmt.Errorf("-list is required")
+	}
+	return nil
+}
+
+// taskAnnotated reports whether a task has an annotation that wasn't
+// cancelled
+func taskAnnotated(task LSTask) bool {
+	for _, a := range task.Annotations {
+		if !a.WasCancelled {
+			return true
+		}
+	}
+	return false
+}
+
+// predictionScores returns the scores of the regions of a prediction, each
+// region's own score taking precedence over the prediction's and clamped to
+// [0, 1]. A prediction without regions counts with its own score, as the
+// model's confidence that the image has no objects. It returns false when
+// nothing is scored.
+func predictionScores(prediction LSAnnotation) ([]float64, bool) {
+	var scores []float64
+	for _, result := range prediction.Result {
+		score := result.Score
+		if score == nil {
+			score = prediction.Score
+		}
+		if score != nil {
+			scores = append(scores, math.Max(0, math.Min(1, *score)))
+		}
+	}
+	if len(prediction.Result) == 0 && prediction.Score != nil {
+		scores = append(scores, math.Max(0, math.Min(1, *prediction.Score)))
+	}
+	return scores, len(scores) > 0
+}
+
+// binaryEntropy is the entropy in bits of a region being right with
+// probability p
+func binaryEntropy(p float64) float64 {
+	if p <= 0 || p >= 1 {
+		return 0
+	}
+	return -p*math.Log2(p) - (1-p)*math.Log2(1-p)
+}
+
+// uncertainty scores the scores of a task's regions by a strategy, higher
+// meaning more uncertain
+func uncertainty(strategy string, scores []float64) float64 {
+	switch strategy {
+	case SelectMeanConfidence:
+		sum := 0.0
+		for _, s := range scores {
+			sum += s
+		}
+		return 1 - sum/float64(len(scores))
+	case SelectEntropy:
+		sum := 0.0
+		for _, s := range scores {
+			sum += binaryEntropy(s)
+		}
+		return sum
+	default:
+		lowest := scores[0]
+		for _, s := range scores[1:] {
+			lowest = math.Min(lowest, s)
+		}
+		return 1 - lowest
+	}
+}
+
+// SelectTasks ranks the tasks of a Label Studio JSON export by how uncertain
+// their best prediction is and selects the Budget most uncertain ones for
+// annotation. Ties go to the lower task ID.
+func SelectTasks(o SelectOptions, tasks []LSTask) *SelectionReport {
+	report := &SelectionReport{Strategy: o.Strategy, Tasks: len(tasks)}
+	var ranked []SelectedTask
+	for _, task := range tasks {
+		if !o.IncludeAnnotated && taskAnnotated(task) {
+			report.Annotated++
+			continue
+		}
+		if len(task.Predictions) == 0 {
+			report.Unpredicted++
+			continue
+		}
+		best := bestPrediction(task.Predictions)
+		scores, ok := predictionScores(best)
+		if !ok {
+			report.Unscored++
+			continue
+		}
+
+		selected := SelectedTask{TaskID: task.ID, Uncertainty: uncertainty(o.Strategy, scores), Regions: len(best.Result), MinScore: 1}
+		for _, s := range scores {
+			selected.MinScore = math.Min(selected.MinScore, s)
+		}
+		ref := taskImage(task)
+		if video := taskVideo(task); video != "" {
+			ref = video
+		}
+		if ref != "" {
+			selected.Image = imageFileName(ref)
+		}
+		ranked = append(ranked, selected)
+	}
+	report.Candidates = len(ranked)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Uncertainty != ranked[j].Uncertainty {
+			return ranked[i].Uncertainty > ranked[j].Uncertainty
+		}
+		return ranked[i].TaskID < ranked[j].TaskID
+	})
+	if o.Budget > 0 && len(ranked) > o.Budget {
+		ranked = ranked[:o.Budget]
+	}
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+	report.Selected = ranked
+	return report
+}
+
+// write saves the selection: task IDs one per line for .txt files, which
+// paste into a Label Studio ID filter, and a CSV with the scores otherwise
+func (r *SelectionReport) write(path string) error {
+	var buf bytes.Buffer
+	if strings.EqualFold(filepath.Ext(path), ".txt") {
+		for _, task := range r.Selected {
+			fmt.Fprintln(&buf, task.TaskID)
+		}
+	} else {
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"rank", "task_id", "image", "uncertainty", "min_score", "regions"})
+		for _, task := range r.Selected {
+			w.Write([]string{
+				strconv.Itoa(task.Rank),
+				strconv.Itoa(task.TaskID),
+				task.Image,
+				strconv.FormatFloat(task.Uncertainty, 'f', 4, 64),
+				strconv.FormatFloat(task.MinScore, 'f', 4, 64),
+				strconv.Itoa(task.Regions),
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("failed to encode the selection: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write the selection: %w", err)
+	}
+	return nil
+}
+
+// print writes the selection summary and the most uncertain tasks
+func (r *SelectionReport) print(w io.Writer) {
+	fmt.Fprintf(w, "Ranked %d of %d tasks by %s: selected %d\n", r.Candidates, r.Tasks, r.Strategy, len(r.Selected))
+	if skipped := r.Annotated + r.Unpredicted + r.Unscored; skipped > 0 {
+		fmt.Fprintf(w, "Skipped %d tasks: %d annotated, %d without predictions, %d without scores\n",
+			skipped, r.Annotated, r.Unpredicted, r.Unscored)
+	}
+	for i, task := range r.Selected {
+		if i == 0 {
+			fmt.Fprintln(w, "Most uncertain:")
+		}
+		if i == maxSelectedPrinted {
+			fmt.Fprintf(w, "  ... and %d more\n", len(r.Selected)-maxSelectedPrinted)
+			break
+		}
+		fmt.Fprintf(w, "  %3d. task %d %s: uncertainty %.3f, lowest score %.2f, %d regions\n",
+			task.Rank, task.TaskID, task.Image, task.Uncertainty, task.MinScore, task.Regions)
+	}
+}
+
+// runSelect is the entry point of the select subcommand
+func runSelect(args []string) error {
+	var o SelectOptions
+	fs := flag.NewFlagSet("select", flag.ExitOnError)
+	fs.StringVar(&o.SourceDir, "source", ".", "Path to Label Studio export directory")
+	fs.StringVar(&o.TasksFile, "tasks", "", "Label Studio JSON export with predictions (default: the export file in -source)")
+	fs.StringVar(&o.Strategy, "strategy", SelectLeastConfidence, "How uncertainty is measured: least-confidence, mean-confidence or entropy")
+	fs.IntVar(&o.Budget, "budget", 100, "Number of tasks to select (0 ranks every candidate)")
+	fs.StringVar(&o.OutputFile, "list", "selection.csv", "Write the selected tasks to this file: CSV, or task IDs one per line for .txt")
+	fs.BoolVar(&o.IncludeAnnotated, "include-annotated", false, "Also rank tasks that already have annotations")
+	fs.Parse(args)
+
+	if err := o.validate(); err != nil {
+		return err
+	}
+	tasksPath, err := NewConverter(Config{SourceDir: o.SourceDir, TasksFile: o.TasksFile}).findTasksFile()
+	if err != nil {
+		return err
+	}
+	tasks, err := LoadLabelStudioTasks(tasksPath)
+	if err != nil {
+		return err
+	}
+	report := SelectTasks(o, tasks)
+	report.print(os.Stdout)
+	if err := report.write(o.OutputFile); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the selection to %s\n", o.OutputFile)
+	return nil
+}
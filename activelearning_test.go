@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// scoredBox is a predicted box with its own score
+func scoredBox(label string, score float64) LSResult {
+	result := box(label, 10, 10, 20, 20)
+	result.Score = &score
+	return result
+}
+
+// selectionTasks are tasks with predictions of varying certainty
+func selectionTasks() []LSTask {
+	score := func(s float64) *float64 { return &s }
+	image := func(name string) map[string]interface{} {
+		return map[string]interface{}{"image": "/data/upload/1/" + name}
+	}
+	return []LSTask{
+		// One confident and one doubtful box
+		{ID: 1, Data: image("one.jpg"), Predictions: []LSAnnotation{{Result: []LSResult{scoredBox("cat", 0.95), scoredBox("dog", 0.3)}}}},
+		// Many middling boxes scored by the prediction
+		{ID: 2, Data: image("two.jpg"), Predictions: []LSAnnotation{{Score: score(0.5), Result: []LSResult{
+			box("cat", 10, 10, 20, 20), box("cat", 40, 40, 20, 20), box("dog", 60, 10, 20, 20),
+		}}}},
+		// Confident, with a worse prediction that is ignored
+		{ID: 3, Data: image("three.jpg"), Predictions: []LSAnnotation{
+			{Score: score(0.2), Result: []LSResult{scoredBox("cat", 0.1)}},
+			{Score: score(0.9), Result: []LSResult{scoredBox("cat", 0.9)}},
+		}},
+		// Nothing detected, with little confidence in that
+		{ID: 4, Data: map[string]interface{}{"video": "/data/upload/1/clip.mp4"}, Predictions: []LSAnnotation{{Score: score(0.4)}}},
+		{ID: 5, Data: image("annotated.jpg"), Annotations: []LSAnnotation{{ID: 1}}, Predictions: []LSAnnotation{{Result: []LSResult{scoredBox("cat", 0.1)}}}},
+		{ID: 6, Data: image("cancelled.jpg"), Annotations: []LSAnnotation{{ID: 2, WasCancelled: true}}, Predictions: []LSAnnotation{{Result: []LSResult{scoredBox("cat", 0.6)}}}},
+		{ID: 7, Data: image("unpredicted.jpg")},
+		{ID: 8, Data: image("unscored.jpg"), Predictions: []LSAnnotation{{Result: []LSResult{box("cat", 10, 10, 20, 20)}}}},
+	}
+}
+
+// selectedIDs formats the task IDs of a selection in order
+func selectedIDs(report *SelectionReport) string {
+	var ids []string
+	for _, task := range report.Selected {
+		ids = append(ids, string(rune('0'+task.TaskID)))
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestSelectTasksStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		budget   int
+		want     string
+	}{
+		{SelectLeastConfidence, 0, "1,4,2,6,3"},
+		{SelectMeanConfidence, 0, "4,2,6,1,3"},
+		{SelectEntropy, 0, "2,1,4,6,3"},
+		{SelectLeastConfidence, 2, "1,4"},
+	}
+	for _, tt := range tests {
+		report := SelectTasks(SelectOptions{Strategy: tt.strategy, Budget: tt.budget}, selectionTasks())
+		if got := selectedIDs(report); got != tt.want {
+			t.Errorf("%s with budget %d: expected tasks %s, got %s", tt.strategy, tt.budget, tt.want, got)
+		}
+	}
+
+	report := SelectTasks(SelectOptions{Strategy: SelectLeastConfidence}, selectionTasks())
+	if report.Tasks != 8 || report.Candidates != 5 || report.Annotated != 1 || report.Unpredicted != 1 || report.Unscored != 1 {
+		t.Errorf("Unexpected counts: %+v", report)
+	}
+	first := report.Selected[0]
+	if first.Rank != 1 || first.Image != "one.jpg" || first.Regions != 2 || math.Abs(first.Uncertainty-0.7) > 1e-9 || math.Abs(first.MinScore-0.3) > 1e-9 {
+		t.Errorf("Unexpected first selection: %+v", first)
+	}
+	if video := report.Selected[1]; video.Image != "clip.mp4" || video.Regions != 0 {
+		t.Errorf("Expected the video task without regions second, got %+v", video)
+	}
+
+	report = SelectTasks(SelectOptions{Strategy: SelectLeastConfidence, IncludeAnnotated: true}, selectionTasks())
+	if got := selectedIDs(report); got != "5,1,4,2,6,3" {
+		t.Errorf("Expected annotated tasks to be ranked too, got %s", got)
+	}
+}
+
+func TestSelectionWrite(t *testing.T) {
+	report := SelectTasks(SelectOptions{Strategy: SelectLeastConfidence, Budget: 2}, selectionTasks())
+	dir := t.TempDir()
+
+	csvPath := filepath.Join(dir, "selection.csv")
+	if err := report.write(csvPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "rank,task_id,image,uncertainty,min_score,regions\n1,1,one.jpg,0.7000,0.3000,2\n2,4,clip.mp4,0.6000,0.4000,0\n"
+	if string(data) != want {
+		t.Errorf("Expected CSV:\n%s\ngot:\n%s", want, data)
+	}
+
+	txtPath := filepath.Join(dir, "selection.txt")
+	if err := report.write(txtPath); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = os.ReadFile(txtPath); err != nil || string(data) != "1\n4\n" {
+		t.Errorf("Expected task IDs one per line, got %q, %v", data, err)
+	}
+
+	var out strings.Builder
+	report.print(&out)
+	for _, want := range []string{"Ranked 5 of 8 tasks by least-confidence: selected 2", "1 annotated, 1 without predictions, 1 without scores", "task 1 one.jpg: uncertainty 0.700"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the summary to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestSelectOptionsValidate(t *testing.T) {
+	for _, o := range []SelectOptions{
+		{Strategy: "margin", OutputFile: "selection.csv"},
+		{Strategy: SelectEntropy, Budget: -1, OutputFile: "selection.csv"},
+		{Strategy: SelectEntropy},
+	} {
+		if err := o.validate(); err == nil {
+			t.Errorf("Expected an error for %+v", o)
+		}
+	}
+	if err := (SelectOptions{Strategy: SelectEntropy, OutputFile: "selection.txt"}).validate(); err != nil {
+		t.Errorf("Expected valid options, got %v", err)
+	}
+}
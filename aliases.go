@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadAliases reads an aliases file, a YAML mapping of each canonical class
+// name to the labels folded into it:
+//
+//	person: [Person, people]
+//
+// and returns the canonical name of every alias
+func loadAliases(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aliases file: %w", err)
+	}
+	var groups map[string][]string
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("failed to parse aliases file %s: %w", path, err)
+	}
+
+	aliases := make(map[string]string)
+	for canonical, names := range groups {
+		for _, name := range names {
+			if name == canonical {
+				continue
+			}
+			if other, ok := aliases[name]; ok && other != canonical {
+				return nil, fmt.Errorf("alias %q is listed under both %q and %q in %s", name, other, canonical, path)
+			}
+			aliases[name] = canonical
+		}
+	}
+	for canonical := range groups {
+		if other, ok := aliases[canonical]; ok {
+			return nil, fmt.Errorf("class %q is both canonical and an alias of %q in %s", canonical, other, path)
+		}
+	}
+	return aliases, nil
+}
+
+// foldClasses renames classes found in target and merges classes that end
+// up with the same name. Each merged class takes the place of the first class
+// folded into it; remap gives the new ID of every old one.
+func foldClasses(classes []string, target map[string]string) ([]string, map[int]int) {
+	var folded []string
+	ids := make(map[string]int)
+	remap := make(map[int]int, len(classes))
+	for i, name := range classes {
+		if renamed, ok := target[name]; ok {
+			name = renamed
+		}
+		id, ok := ids[name]
+		if !ok {
+			id = len(folded)
+			ids[name] = id
+			folded = append(folded, name)
+		}
+		remap[i] = id
+	}
+	return folded, remap
+}
+
+// foldAliases merges classes named by an alias into their canonical class.
+// Each canonical class takes the place of the first class folded into it,
+// and the labels' class IDs are renumbered to the merged list. The
+// annotations each alias contributed are reported.
+func (c *Converter) foldAliases(classes []string, pairs []LabelPair) ([]string, []LabelPair, error) {
+	aliases, err := loadAliases(c.config.Aliases)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	folded, remap := foldClasses(classes, aliases)
+	aliasOf := make(map[int]string)
+	for i, name := range classes {
+		if _, ok := aliases[name]; ok {
+			aliasOf[i] = name
+		}
+	}
+	if len(aliasOf) == 0 {
+		fmt.Fprintf(c.out, "No class matches an alias in %s\n", c.config.Aliases)
+		return classes, pairs, nil
+	}
+
+	counts := make(map[int]int)
+	pairs, _, _, err = c.rewriteLabels(pairs, "aliases", func(pair LabelPair, lines []string) ([]string, int) {
+		changed := 0
+		for i, line := range lines {
+			parts := strings.Fields(line)
+			if len(parts) == 0 {
+				continue
+			}
+			classID, err := strconv.Atoi(parts[0])
+			if err != nil {
+				continue
+			}
+			if _, ok := aliasOf[classID]; ok {
+				counts[classID]++
+			}
+			if newID, ok := remap[classID]; ok && newID != classID {
+				parts[0] = strconv.Itoa(newID)
+				lines[i] = strings.Join(parts, " ")
+				changed++
+			}
+		}
+		return lines, changed
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fmt.Fprintf(c.out, "Folded %d aliases into %d classes: %v\n", len(aliasOf), len(folded), folded)
+	aliasIDs := make([]int, 0, len(aliasOf))
+	for id := range aliasOf {
+		aliasIDs = append(aliasIDs, id)
+	}
+	sort.Ints(aliasIDs)
+	for _, id := range aliasIDs {
+		fmt.Fprintf(c.out, "  %s -> %s: %d annotations\n", aliasOf[id], folded[remap[id]], counts[id])
+	}
+	return folded, pairs, nil
+}
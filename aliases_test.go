@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAliases(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "aliases.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	aliases, err := loadAliases(write("person: [Person, people, person]\ncar:\n  - automobile\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliases) != 3 || aliases["Person"] != "person" || aliases["people"] != "person" || aliases["automobile"] != "car" {
+		t.Errorf("Unexpected aliases %v", aliases)
+	}
+
+	for content, want := range map[string]string{
+		"person: [people]\nhuman: [people]\n": "listed under both",
+		"person: [human]\nhuman: [man]\n":     "both canonical and an alias",
+		"person: people: [x]":                 "failed to parse",
+	} {
+		if _, err := loadAliases(write(content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error containing %q, got %v", content, want, err)
+		}
+	}
+}
+
+func TestConvertFoldsAliases(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	files := map[string]string{
+		"classes.txt":       "Person\nbook\nperson\npeople\n",
+		"labels/image1.txt": "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n",
+		"labels/image2.txt": "2 0.4 0.6 0.2 0.4\n",
+		"labels/image3.txt": "3 0.7 0.3 0.3 0.2\n3 0.1 0.9 0.1 0.1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	aliasesFile := filepath.Join(t.TempDir(), "aliases.yaml")
+	if err := os.WriteFile(aliasesFile, []byte("person: [Person, people]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, Aliases: aliasesFile})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	expected := map[string]string{
+		"image1.txt": "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n",
+		"image2.txt": "0 0.4 0.6 0.2 0.4\n",
+		"image3.txt": "0 0.7 0.3 0.3 0.2\n0 0.1 0.9 0.1 0.1\n",
+	}
+	for name, want := range expected {
+		got, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", name))
+		if err != nil || string(got) != want {
+			t.Errorf("Label %s: expected %q, got %q (%v)", name, want, got, err)
+		}
+	}
+	names, err := loadClassNames(filepath.Join(outputDir, "data.yaml"))
+	if err != nil || strings.Join(names, ",") != "person,book" {
+		t.Errorf("Expected the folded classes in data.yaml, got %v, %v", names, err)
+	}
+
+	// The source labels are left alone
+	if data, _ := os.ReadFile(filepath.Join(sourceDir, "labels", "image3.txt")); !strings.HasPrefix(string(data), "3 ") {
+		t.Errorf("Expected the source label to be unchanged, got %q", data)
+	}
+
+	log := out.String()
+	for _, want := range []string{"Folded 2 aliases into 2 classes: [person book]", "Person -> person: 1 annotations", "people -> person: 2 annotations"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, log)
+		}
+	}
+}
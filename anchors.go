@@ -0,0 +1,276 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Object size limits in pixels at the input resolution, as used by COCO
+const (
+	smallObjectSize  = 32
+	mediumObjectSize = 96
+)
+
+// anchorIterations caps the k-means iterations
+const anchorIterations = 300
+
+// AnchorOptions configures the anchor computation over a converted dataset
+type AnchorOptions struct {
+	DatasetDir string
+	// K is the number of anchors
+	K int
+	// ImageSize is the training input resolution; images are scaled so their
+	// longest side matches it
+	ImageSize int
+	Seed      int64
+}
+
+// AnchorReport is the result of an anchor computation
+type AnchorReport struct {
+	Boxes int
+	// Anchors are width, height pairs in pixels, sorted by area
+	Anchors [][2]float64
+	// MeanIoU is the mean IoU of every box with its best anchor
+	MeanIoU float64
+	// Recall is the fraction of boxes whose best anchor has an IoU above
+	// 0.25
+	Recall                float64
+	Small, Medium, Large  int
+	ImageSize, Iterations int
+}
+
+// whIoU is the IoU of two boxes of the given widths and heights sharing a
+// center
+func whIoU(a, b [2]float64) float64 {
+	inter := math.Min(a[0], b[0]) * math.Min(a[1], b[1])
+	union := a[0]*a[1] + b[0]*b[1] - inter
+	if union <= 0 {
+		return 0
+	}
+	return inter / union
+}
+
+// nearestAnchor returns the anchor with the highest IoU with a box
+func nearestAnchor(box [2]float64, anchors [][2]float64) (int, float64) {
+	best, bestIoU := 0, -1.0
+	for i, anchor := range anchors {
+		if iou := whIoU(box, anchor); iou > bestIoU {
+			best, bestIoU = i, iou
+		}
+	}
+	return best, bestIoU
+}
+
+// kmeansAnchors clusters box sizes with 1 - IoU as the distance, moving each
+// anchor to the median of its cluster, which is robust to outliers. Anchors
+// are seeded k-means++ style. It returns the anchors and the iterations
+// used.
+func kmeansAnchors(boxes [][2]float64, k int, rng *rand.Rand) ([][2]float64, int) {
+	anchors := [][2]float64{boxes[rng.Intn(len(boxes))]}
+	distances := make([]float64, len(boxes))
+	for len(anchors) < k {
+		total := 0.0
+		for i, box := range boxes {
+			_, iou := nearestAnchor(box, anchors)
+			distances[i] = (1 - iou) * (1 - iou)
+			total += distances[i]
+		}
+		if total == 0 {
+			break
+		}
+		target := rng.Float64() * total
+		i := 0
+		for ; i < len(boxes)-1 && target > distances[i]; i++ {
+			target -= distances[i]
+		}
+		anchors = append(anchors, boxes[i])
+	}
+
+	assignment := make([]int, len(boxes))
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	iterations := 0
+	for iterations < anchorIterations {
+		iterations++
+		changed := false
+		for i, box := range boxes {
+			if nearest, _ := nearestAnchor(box, anchors); nearest != assignment[i] {
+				assignment[i] = nearest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		for j := range anchors {
+			var widths, heights []float64
+			for i, box := range boxes {
+				if assignment[i] == j {
+					widths = append(widths, box[0])
+					heights = append(heights, box[1])
+				}
+			}
+			if len(widths) > 0 {
+				anchors[j] = [2]float64{median(widths), median(heights)}
+			}
+		}
+	}
+
+	sort.Slice(anchors, func(i, j int) bool {
+		return anchors[i][0]*anchors[i][1] < anchors[j][0]*anchors[j][1]
+	})
+	return anchors, iterations
+}
+
+// median returns the median of values, reordering them
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// datasetBoxSizes returns the pixel widths and heights of every region of a
+// converted dataset at the input resolution. Polygons and oriented boxes
+// count with their enclosing box; images that can't be read count as
+// square.
+func datasetBoxSizes(datasetDir string, imageSize int) ([][2]float64, error) {
+	data, err := os.ReadFile(filepath.Join(datasetDir, "data.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data.yaml of the dataset: %w", err)
+	}
+	var config struct {
+		Task string `yaml:"task"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse data.yaml of the dataset: %w", err)
+	}
+
+	var boxes [][2]float64
+	for _, split := range []string{"train", "val"} {
+		images, err := previewImages(datasetDir, split)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range images {
+			labelPath := filepath.Join(datasetDir, "labels", split, strings.TrimSuffix(name, filepath.Ext(name))+".txt")
+			shapes, err := readPreviewShapes(labelPath, config.Task)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, fmt.Errorf("failed to read label %s: %w", labelPath, err)
+			}
+			if len(shapes) == 0 {
+				continue
+			}
+
+			// Letterboxing scales the longest side to the input resolution
+			scaleX, scaleY := float64(imageSize), float64(imageSize)
+			if width, height, err := readImageSize(filepath.Join(datasetDir, "images", split, name)); err == nil && width > 0 && height > 0 {
+				longest := float64(max(width, height))
+				scaleX, scaleY = float64(imageSize)*float64(width)/longest, float64(imageSize)*float64(height)/longest
+			}
+			for _, shape := range shapes {
+				bounds := coordBounds(shape.outline)
+				if box := [2]float64{bounds[2] * scaleX, bounds[3] * scaleY}; box[0] > 0 && box[1] > 0 {
+					boxes = append(boxes, box)
+				}
+			}
+		}
+	}
+	return boxes, nil
+}
+
+// ComputeAnchors clusters the region sizes of a converted dataset into K
+// anchors for the given input resolution
+func ComputeAnchors(o AnchorOptions) (*AnchorReport, error) {
+	if o.K < 1 {
+		return nil, fmt.Errorf("number of anchors must be at least 1")
+	}
+	if o.ImageSize < 1 {
+		return nil, fmt.Errorf("image size must be at least 1")
+	}
+	boxes, err := datasetBoxSizes(o.DatasetDir, o.ImageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(boxes) < o.K {
+		return nil, fmt.Errorf("found %d boxes in %s, fewer than the %d anchors requested", len(boxes), o.DatasetDir, o.K)
+	}
+
+	report := &AnchorReport{Boxes: len(boxes), ImageSize: o.ImageSize}
+	report.Anchors, report.Iterations = kmeansAnchors(boxes, o.K, rand.New(rand.NewSource(o.Seed)))
+
+	recalled := 0
+	for _, box := range boxes {
+		_, iou := nearestAnchor(box, report.Anchors)
+		report.MeanIoU += iou
+		if iou > 0.25 {
+			recalled++
+		}
+		switch area := box[0] * box[1]; {
+		case area < smallObjectSize*smallObjectSize:
+			report.Small++
+		case area < mediumObjectSize*mediumObjectSize:
+			report.Medium++
+		default:
+			report.Large++
+		}
+	}
+	report.MeanIoU /= float64(len(boxes))
+	report.Recall = float64(recalled) / float64(len(boxes))
+	return report, nil
+}
+
+// print writes the anchors, grouped into three detection layers when they
+// divide evenly, in the format of YOLO model configs
+func (r *AnchorReport) print(w io.Writer) {
+	fmt.Fprintf(w, "Anchors for %dx%d input from %d boxes (%d iterations):\n", r.ImageSize, r.ImageSize, r.Boxes, r.Iterations)
+	perLayer := len(r.Anchors)
+	if perLayer%3 == 0 && perLayer > 3 {
+		perLayer /= 3
+	}
+	fmt.Fprintln(w, "anchors:")
+	for i := 0; i < len(r.Anchors); i += perLayer {
+		var pairs []string
+		for _, anchor := range r.Anchors[i:min(i+perLayer, len(r.Anchors))] {
+			pairs = append(pairs, fmt.Sprintf("%.0f,%.0f", anchor[0], anchor[1]))
+		}
+		fmt.Fprintf(w, "  - [%s]\n", strings.Join(pairs, ", "))
+	}
+	fmt.Fprintf(w, "Mean IoU with the best anchor: %.3f\n", r.MeanIoU)
+	fmt.Fprintf(w, "Boxes with an anchor above 0.25 IoU: %.1f%%\n", r.Recall*100)
+	fmt.Fprintf(w, "Object sizes: %d small (<%d px), %d medium (<%d px), %d large\n",
+		r.Small, smallObjectSize, r.Medium, mediumObjectSize, r.Large)
+}
+
+// runAnchors is the entry point of the anchors subcommand
+func runAnchors(args []string) error {
+	var o AnchorOptions
+	fs := flag.NewFlagSet("anchors", flag.ExitOnError)
+	fs.StringVar(&o.DatasetDir, "dataset", "./yolo_dataset", "Converted YOLO dataset (directory with data.yaml)")
+	fs.IntVar(&o.K, "k", 9, "Number of anchors")
+	fs.IntVar(&o.ImageSize, "img-size", 640, "Training input resolution in pixels")
+	fs.Int64Var(&o.Seed, "seed", 42, "Random seed for the cluster initialization")
+	fs.Parse(args)
+
+	report, err := ComputeAnchors(o)
+	if err != nil {
+		return err
+	}
+	report.print(os.Stdout)
+	return nil
+}
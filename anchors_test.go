@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKmeansAnchors(t *testing.T) {
+	var boxes [][2]float64
+	for i := 0; i < 20; i++ {
+		boxes = append(boxes, [2]float64{10 + float64(i%3), 20}, [2]float64{200, 100 + float64(i%5)})
+	}
+	anchors, _ := kmeansAnchors(boxes, 2, rand.New(rand.NewSource(1)))
+	if len(anchors) != 2 || anchors[0] != [2]float64{11, 20} || anchors[1] != [2]float64{200, 102} {
+		t.Errorf("Expected the medians of both clusters, got %v", anchors)
+	}
+}
+
+func TestComputeAnchors(t *testing.T) {
+	dir := writePreviewDataset(t)
+	// Two small boxes next to the dataset's large ones
+	if err := os.WriteFile(filepath.Join(dir, "labels", "train", "a.txt"), []byte("0 0.2 0.2 0.1 0.1\n0 0.7 0.7 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ComputeAnchors(AnchorOptions{DatasetDir: dir, K: 2, ImageSize: 640, Seed: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The 64x48 images are letterboxed to 640x480
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if report.Boxes != 5 || len(report.Anchors) != 2 ||
+		!near(report.Anchors[0][0], 64) || !near(report.Anchors[0][1], 48) ||
+		!near(report.Anchors[1][0], 320) || !near(report.Anchors[1][1], 240) {
+		t.Errorf("Unexpected anchors %+v", report)
+	}
+	if !near(report.MeanIoU, 1) || report.Small != 0 || report.Medium != 2 || report.Large != 3 {
+		t.Errorf("Unexpected statistics %+v", report)
+	}
+
+	var out bytes.Buffer
+	report.print(&out)
+	if !strings.Contains(out.String(), "  - [64,48, 320,240]") {
+		t.Errorf("Expected the anchors in config format, got:\n%s", out.String())
+	}
+
+	if _, err := ComputeAnchors(AnchorOptions{DatasetDir: dir, K: 9, ImageSize: 640}); err == nil {
+		t.Error("Expected an error for more anchors than boxes")
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Anonymization modes
+const (
+	AnonymizeBlur     = "blur"
+	AnonymizePixelate = "pixelate"
+)
+
+// anonymizeDivisions is how many blocks (pixelate) or blur radii (blur) fit
+// across the longer side of an anonymized region
+const anonymizeDivisions = 8
+
+// validateAnonymize checks the anonymization options
+func (c *Converter) validateAnonymize() error {
+	if c.config.Anonymize == "" {
+		return nil
+	}
+	switch c.config.AnonymizeMode {
+	case AnonymizeBlur, AnonymizePixelate:
+		return nil
+	}
+	return fmt.Errorf("unknown anonymize mode %q (use blur or pixelate)", c.config.AnonymizeMode)
+}
+
+// anonymizeImages blurs or pixelates the regions of the -anonymize classes,
+// such as faces and license plates, keeping their labels. Regions are covered
+// by their enclosing box. Changed images are written to the staging
+// directory.
+func (c *Converter) anonymizeImages(classes []string, pairs []LabelPair) ([]LabelPair, error) {
+	anonymized := make(map[int]bool)
+	for _, name := range splitList(c.config.Anonymize) {
+		found := false
+		for id, class := range classes {
+			if class == name {
+				anonymized[id] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown class %q in -anonymize", name)
+		}
+	}
+
+	staging, err := c.stagingDirectory()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(staging, "anonymized")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	result := make([]LabelPair, len(pairs))
+	copy(result, pairs)
+	images, regions := 0, 0
+	for i, pair := range pairs {
+		data, err := os.ReadFile(pair.LabelPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read label %s: %w", pair.LabelPath, err)
+		}
+		var bounds [][4]float64
+		for _, line := range strings.Split(string(data), "\n") {
+			parts := strings.Fields(line)
+			if len(parts) == 0 {
+				continue
+			}
+			kind, warning := c.parseLine(parts)
+			if warning != "" {
+				continue
+			}
+			if classID, _ := strconv.Atoi(parts[0]); anonymized[classID] {
+				bounds = append(bounds, labelBounds(kind, parts))
+			}
+		}
+		if len(bounds) == 0 {
+			continue
+		}
+
+		img, err := decodeImage(pair.ImagePath)
+		if err != nil {
+			// Never let an image with sensitive regions through untouched
+			return nil, fmt.Errorf("cannot anonymize %s: %w", pair.ImagePath, err)
+		}
+		// Labels are drawn on the upright image
+		img = orient(img, exifOrientation(pair.ImagePath))
+		rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+		for _, b := range bounds {
+			rect := cropRect(b, 0, rgba.Bounds())
+			if rect.Empty() {
+				continue
+			}
+			if c.config.AnonymizeMode == AnonymizePixelate {
+				pixelate(rgba, rect)
+			} else {
+				blur(rgba, rect)
+			}
+			regions++
+		}
+
+		encoded, ext, err := encodeCrop(rgba, filepath.Ext(pair.ImagePath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode anonymized %s: %w", pair.ImagePath, err)
+		}
+		base := strings.TrimSuffix(filepath.Base(pair.ImagePath), filepath.Ext(pair.ImagePath))
+		imagePath := filepath.Join(dir, base+ext)
+		if err := os.WriteFile(imagePath, encoded, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write anonymized image %s: %w", imagePath, err)
+		}
+		result[i].ImagePath = imagePath
+		images++
+	}
+
+	fmt.Fprintf(c.out, "Anonymized %d regions in %d images (%s)\n", regions, images, c.config.AnonymizeMode)
+	return result, nil
+}
+
+// anonymizeStep returns the block size or blur radius for a region
+func anonymizeStep(rect image.Rectangle) int {
+	return max(2, max(rect.Dx(), rect.Dy())/anonymizeDivisions)
+}
+
+// pixelate replaces every block of a region with its average color
+func pixelate(img *image.RGBA, rect image.Rectangle) {
+	step := anonymizeStep(rect)
+	for by := rect.Min.Y; by < rect.Max.Y; by += step {
+		for bx := rect.Min.X; bx < rect.Max.X; bx += step {
+			block := image.Rect(bx, by, bx+step, by+step).Intersect(rect)
+			var sum [4]int
+			for y := block.Min.Y; y < block.Max.Y; y++ {
+				for x := block.Min.X; x < block.Max.X; x++ {
+					p := img.Pix[img.PixOffset(x, y):]
+					for k := range sum {
+						sum[k] += int(p[k])
+					}
+				}
+			}
+			n := block.Dx() * block.Dy()
+			for y := block.Min.Y; y < block.Max.Y; y++ {
+				for x := block.Min.X; x < block.Max.X; x++ {
+					p := img.Pix[img.PixOffset(x, y):]
+					for k := range sum {
+						p[k] = uint8((sum[k] + n/2) / n)
+					}
+				}
+			}
+		}
+	}
+}
+
+// blur applies three passes of a box blur to a region, which approximates a
+// Gaussian blur. Only pixels inside the region are sampled, so nothing
+// outside it changes.
+func blur(img *image.RGBA, rect image.Rectangle) {
+	radius := anonymizeStep(rect)
+	for pass := 0; pass < 3; pass++ {
+		boxBlur(img, rect, radius, false)
+		boxBlur(img, rect, radius, true)
+	}
+}
+
+// boxBlur averages every pixel of a region with its neighbors up to radius
+// pixels away along its row, or its column when vertical
+func boxBlur(img *image.RGBA, rect image.Rectangle, radius int, vertical bool) {
+	length, lines := rect.Dx(), rect.Dy()
+	if vertical {
+		length, lines = rect.Dy(), rect.Dx()
+	}
+	at := func(line, i int) []uint8 {
+		if vertical {
+			return img.Pix[img.PixOffset(rect.Min.X+line, rect.Min.Y+i):]
+		}
+		return img.Pix[img.PixOffset(rect.Min.X+i, rect.Min.Y+line):]
+	}
+
+	src := make([][4]int, length)
+	for line := 0; line < lines; line++ {
+		for i := range src {
+			p := at(line, i)
+			src[i] = [4]int{int(p[0]), int(p[1]), int(p[2]), int(p[3])}
+		}
+		for i := range src {
+			lo, hi := max(0, i-radius), min(length-1, i+radius)
+			var sum [4]int
+			n := hi - lo + 1
+			for j := lo; j <= hi; j++ {
+				for k := range sum {
+					sum[k] += src[j][k]
+				}
+			}
+			p := at(line, i)
+			for k := range sum {
+				p[k] = uint8((sum[k] + n/2) / n)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnonymizeImages(t *testing.T) {
+	for _, mode := range []string{AnonymizePixelate, AnonymizeBlur} {
+		t.Run(mode, func(t *testing.T) {
+			dir := t.TempDir()
+			imagePath := filepath.Join(dir, "street.png")
+			writeTestImage(t, imagePath, 40, 40)
+			labelPath := filepath.Join(dir, "street.txt")
+			// A face in the top-left quarter and a car that stays visible
+			if err := os.WriteFile(labelPath, []byte("1 0.25 0.25 0.5 0.5\n0 0.75 0.75 0.5 0.5\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			otherPath := filepath.Join(dir, "road.png")
+			writeTestImage(t, otherPath, 40, 40)
+			otherLabel := filepath.Join(dir, "road.txt")
+			if err := os.WriteFile(otherLabel, []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			converter := NewConverter(Config{Anonymize: "face", AnonymizeMode: mode})
+			converter.SetOutput(io.Discard)
+			defer converter.removeStaging()
+			if err := converter.validateAnonymize(); err != nil {
+				t.Fatal(err)
+			}
+			pairs, err := converter.anonymizeImages([]string{"car", "face"}, []LabelPair{
+				{ImagePath: imagePath, LabelPath: labelPath},
+				{ImagePath: otherPath, LabelPath: otherLabel},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pairs[0].ImagePath == imagePath || pairs[0].LabelPath != labelPath {
+				t.Fatalf("Expected a staged image with the original labels, got %+v", pairs[0])
+			}
+			if pairs[1].ImagePath != otherPath {
+				t.Errorf("Expected an image without faces to be left alone, got %+v", pairs[1])
+			}
+
+			decoded, err := decodeImage(pairs[0].ImagePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			red := func(x, y int) uint32 {
+				r, _, _, _ := decoded.At(x, y).RGBA()
+				return r >> 8
+			}
+			// The test image's red channel is its x coordinate
+			if red(30, 30) != 30 {
+				t.Errorf("Expected pixels outside the face to be unchanged, got red %d", red(30, 30))
+			}
+			if red(0, 0) == 0 {
+				t.Error("Expected the face region to be anonymized")
+			}
+			if mode == AnonymizePixelate && red(0, 0) != red(1, 1) {
+				t.Errorf("Expected a uniform block, got %d and %d", red(0, 0), red(1, 1))
+			}
+		})
+	}
+}
+
+func TestAnonymizeUnknownClass(t *testing.T) {
+	converter := NewConverter(Config{Anonymize: "plate", AnonymizeMode: AnonymizeBlur})
+	converter.SetOutput(io.Discard)
+	defer converter.removeStaging()
+	if _, err := converter.anonymizeImages([]string{"car"}, nil); err == nil {
+		t.Error("Expected an error for a class that does not exist")
+	}
+	converter.config.AnonymizeMode = "mask"
+	if err := converter.validateAnonymize(); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,600 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DatasetWriter receives the files that make up a YOLO dataset. Names are
+// slash-separated paths relative to the dataset root.
+type DatasetWriter interface {
+	MkdirAll(dir string) error
+	CopyFile(name, srcPath string) error
+	WriteFile(name string, data []byte) error
+	Close() error
+	Abort() error
+}
+
+// archiveFormat returns the archive format implied by the output path, or an
+// empty string when the output is a plain directory
+func archiveFormat(outputPath string) string {
+	lower := strings.ToLower(outputPath)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	}
+	return ""
+}
+
+// NewDatasetWriter creates a writer for the output path, choosing an archive
+// writer when the path ends in .tar.gz, .tgz or .zip
+func NewDatasetWriter(outputPath string) (DatasetWriter, error) {
+	switch archiveFormat(outputPath) {
+	case "tar.gz":
+		return newTarGzWriter(outputPath)
+	case "zip":
+		return newZipWriter(outputPath)
+	}
+	return &DirWriter{Root: outputPath}, nil
+}
+
+// DirWriter writes the dataset into a directory on disk
+type DirWriter struct {
+	Root string
+
+	// SkipUnchanged leaves destination files alone when they already hold an
+	// up-to-date copy of the source
+	SkipUnchanged bool
+	// PreserveAttrs keeps the permissions, modification time and extended
+	// attributes of copied files
+	PreserveAttrs bool
+}
+
+// localPath returns the path on disk of a name below the dataset root
+func (w *DirWriter) localPath(name string) string {
+	return filepath.Join(w.Root, filepath.FromSlash(name))
+}
+
+// MkdirAll creates a directory below the dataset root
+func (w *DirWriter) MkdirAll(dir string) error {
+	return os.MkdirAll(w.localPath(dir), 0755)
+}
+
+// CopyFile copies srcPath to name below the dataset root
+func (w *DirWriter) CopyFile(name, srcPath string) error {
+	dst := w.localPath(name)
+	if !w.SkipUnchanged || !upToDate(srcPath, dst) {
+		if err := copyFile(srcPath, dst); err != nil {
+			return err
+		}
+	}
+	if w.PreserveAttrs {
+		return preserveAttrs(srcPath, dst)
+	}
+	return nil
+}
+
+// WriteFile writes data to name below the dataset root
+func (w *DirWriter) WriteFile(name string, data []byte) error {
+	if err := os.WriteFile(w.localPath(name), data, 0644); err != nil {
+		return err
+	}
+	metrics.AddCopied(int64(len(data)))
+	return nil
+}
+
+// Close is a no-op for directory output
+func (w *DirWriter) Close() error {
+	return nil
+}
+
+// Abort is a no-op for directory output; partially written files are kept
+func (w *DirWriter) Abort() error {
+	return nil
+}
+
+// archiveFile manages the on-disk file behind an archive writer. The archive
+// is written to a .partial file and only renamed into place on success, so a
+// failed conversion never leaves a truncated archive behind.
+type archiveFile struct {
+	finalPath string
+	tempPath  string
+	file      *os.File
+}
+
+func createArchiveFile(outputPath string) (*archiveFile, error) {
+	if dir := filepath.Dir(outputPath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	tempPath := outputPath + ".partial"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive %s: %w", outputPath, err)
+	}
+
+	return &archiveFile{finalPath: outputPath, tempPath: tempPath, file: file}, nil
+}
+
+func (a *archiveFile) commit() error {
+	if err := a.file.Sync(); err != nil {
+		a.file.Close()
+		return err
+	}
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(a.tempPath, a.finalPath)
+}
+
+func (a *archiveFile) discard() error {
+	a.file.Close()
+	return os.Remove(a.tempPath)
+}
+
+// TarGzWriter streams the dataset into a gzip-compressed tar archive
+type TarGzWriter struct {
+	// PreserveAttrs keeps the permissions and extended attributes of copied
+	// files; modification times are always kept
+	PreserveAttrs bool
+
+	mu      sync.Mutex
+	archive *archiveFile
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	dirs    map[string]bool
+}
+
+func newTarGzWriter(outputPath string) (*TarGzWriter, error) {
+	archive, err := createArchiveFile(outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(archive.file)
+	return &TarGzWriter{
+		archive: archive,
+		gz:      gz,
+		tw:      tar.NewWriter(gz),
+		dirs:    make(map[string]bool),
+	}, nil
+}
+
+// MkdirAll adds directory entries for dir and its parents
+func (w *TarGzWriter) MkdirAll(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.mkdirAll(dir)
+}
+
+func (w *TarGzWriter) mkdirAll(dir string) error {
+	dir = path.Clean(dir)
+	if dir == "." || dir == "/" || w.dirs[dir] {
+		return nil
+	}
+	if err := w.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     dir + "/",
+		Mode:     0755,
+		ModTime:  time.Now(),
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	w.dirs[dir] = true
+	return nil
+}
+
+// CopyFile streams srcPath into the archive as name
+func (w *TarGzWriter) CopyFile(name, srcPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     info.Size(),
+		Mode:     0644,
+		ModTime:  info.ModTime(),
+	}
+	if w.PreserveAttrs {
+		header.Mode = int64(info.Mode().Perm())
+		for attr, value := range readXattrs(srcPath) {
+			if header.PAXRecords == nil {
+				header.PAXRecords = make(map[string]string)
+			}
+			header.PAXRecords["SCHILY.xattr."+attr] = string(value)
+		}
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	n, err := copyBuffered(w.tw, src)
+	if err != nil {
+		return err
+	}
+	metrics.AddCopied(n)
+	return nil
+}
+
+// WriteFile adds an in-memory file to the archive
+func (w *TarGzWriter) WriteFile(name string, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     int64(len(data)),
+		Mode:     0644,
+		ModTime:  time.Now(),
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if _, err := w.tw.Write(data); err != nil {
+		return err
+	}
+	metrics.AddCopied(int64(len(data)))
+	return nil
+}
+
+// Close finishes the archive and moves it into place
+func (w *TarGzWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.tw.Close(); err != nil {
+		w.archive.discard()
+		return fmt.Errorf("failed to finish tar archive: %w", err)
+	}
+	if err := w.gz.Close(); err != nil {
+		w.archive.discard()
+		return fmt.Errorf("failed to finish gzip stream: %w", err)
+	}
+	return w.archive.commit()
+}
+
+// Abort discards the partially written archive
+func (w *TarGzWriter) Abort() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.archive.discard()
+}
+
+// ZipWriter streams the dataset into a zip archive
+type ZipWriter struct {
+	// PreserveAttrs keeps the permissions of copied files; modification
+	// times are always kept
+	PreserveAttrs bool
+
+	mu      sync.Mutex
+	archive *archiveFile
+	zw      *zip.Writer
+	dirs    map[string]bool
+}
+
+func newZipWriter(outputPath string) (*ZipWriter, error) {
+	archive, err := createArchiveFile(outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ZipWriter{
+		archive: archive,
+		zw:      zip.NewWriter(archive.file),
+		dirs:    make(map[string]bool),
+	}, nil
+}
+
+// MkdirAll adds directory entries for dir and its parents
+func (w *ZipWriter) MkdirAll(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.mkdirAll(dir)
+}
+
+func (w *ZipWriter) mkdirAll(dir string) error {
+	dir = path.Clean(dir)
+	if dir == "." || dir == "/" || w.dirs[dir] {
+		return nil
+	}
+	if err := w.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+
+	header := &zip.FileHeader{Name: dir + "/", Modified: time.Now()}
+	header.SetMode(os.ModeDir | 0755)
+	if _, err := w.zw.CreateHeader(header); err != nil {
+		return err
+	}
+	w.dirs[dir] = true
+	return nil
+}
+
+// CopyFile streams srcPath into the archive as name
+func (w *ZipWriter) CopyFile(name, srcPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: info.ModTime()}
+	header.SetMode(0644)
+	if w.PreserveAttrs {
+		header.SetMode(info.Mode().Perm())
+	}
+	dst, err := w.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	n, err := copyBuffered(dst, src)
+	if err != nil {
+		return err
+	}
+	metrics.AddCopied(n)
+	return nil
+}
+
+// WriteFile adds an in-memory file to the archive
+func (w *ZipWriter) WriteFile(name string, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()}
+	header.SetMode(0644)
+	dst, err := w.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	if _, err := dst.Write(data); err != nil {
+		return err
+	}
+	metrics.AddCopied(int64(len(data)))
+	return nil
+}
+
+// Close finishes the archive and moves it into place
+func (w *ZipWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.zw.Close(); err != nil {
+		w.archive.discard()
+		return fmt.Errorf("failed to finish zip archive: %w", err)
+	}
+	return w.archive.commit()
+}
+
+// Abort discards the partially written archive
+func (w *ZipWriter) Abort() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.archive.discard()
+}
+
+// extractLimits caps what extracting an archive may write, so a small
+// compressed archive can't fill the disk; zero fields are unlimited
+type extractLimits struct {
+	MaxBytes   int64
+	MaxEntries int
+
+	bytes   int64
+	entries int
+}
+
+// entry counts an archive entry against the entry limit
+func (l *extractLimits) entry() error {
+	l.entries++
+	if l.MaxEntries > 0 && l.entries > l.MaxEntries {
+		return fmt.Errorf("archive has more than %d entries", l.MaxEntries)
+	}
+	return nil
+}
+
+// copy writes r to w, counting the bytes against the size limit
+func (l *extractLimits) copy(w io.Writer, r io.Reader) error {
+	if l.MaxBytes <= 0 {
+		_, err := copyBuffered(w, r)
+		return err
+	}
+	// Read one byte past the limit to tell a full-size archive from a larger one
+	n, err := copyBuffered(w, io.LimitReader(r, l.MaxBytes-l.bytes+1))
+	l.bytes += n
+	if err != nil {
+		return err
+	}
+	if l.bytes > l.MaxBytes {
+		return fmt.Errorf("archive extracts to more than %s", formatBytes(uint64(l.MaxBytes)))
+	}
+	return nil
+}
+
+// extractArchive unpacks a .tar.gz or .zip archive into destDir, rejecting
+// entries that would escape it and archives exceeding the limits
+func extractArchive(archivePath, format, destDir string, limits extractLimits) error {
+	switch format {
+	case "tar.gz":
+		return extractTarGz(archivePath, destDir, &limits)
+	case "zip":
+		return extractZip(archivePath, destDir, &limits)
+	}
+	return fmt.Errorf("unsupported archive format: %s", archivePath)
+}
+
+// safeJoin joins an archive entry name onto destDir, failing when the entry
+// would land outside of it
+func safeJoin(destDir, name string) (string, error) {
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+	rel, err := filepath.Rel(destDir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry escapes destination: %s", name)
+	}
+	return target, nil
+}
+
+func extractTarGz(archivePath, destDir string, limits *extractLimits) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if err := limits.entry(); err != nil {
+			return err
+		}
+
+		target, err := safeJoin(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeExtracted(target, tr, limits); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(archivePath, destDir string, limits *extractLimits) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read zip archive: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if err := limits.entry(); err != nil {
+			return err
+		}
+		target, err := safeJoin(destDir, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeExtracted(target, rc, limits)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeExtracted(target string, r io.Reader, limits *extractLimits) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if err := limits.copy(dst, r); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// writeDirTarGz streams the contents of dir to w as a gzip-compressed tar
+func writeDirTarGz(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := tw.AddFS(os.DirFS(dir)); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchiveFormat(t *testing.T) {
+	tests := map[string]string{
+		"./yolo_dataset":       "",
+		"dataset.tar.gz":       "tar.gz",
+		"out/DATASET.TGZ":      "tar.gz",
+		"dataset.zip":          "zip",
+		"dataset.tar":          "",
+		"/tmp/archive.zip.bak": "",
+	}
+
+	for input, expected := range tests {
+		if got := archiveFormat(input); got != expected {
+			t.Errorf("archiveFormat(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+// readTarGz returns the entry names and regular file contents of a .tar.gz archive
+func readTarGz(t *testing.T, archivePath string) map[string]string {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Failed to open gzip stream: %v", err)
+	}
+
+	entries := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read tar entry: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("Failed to read tar entry %s: %v", header.Name, err)
+		}
+		entries[header.Name] = string(data)
+	}
+	return entries
+}
+
+// readZip returns the entry names and file contents of a .zip archive
+func readZip(t *testing.T, archivePath string) map[string]string {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to open zip archive: %v", err)
+	}
+	defer zr.Close()
+
+	entries := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open zip entry %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("Failed to read zip entry %s: %v", f.Name, err)
+		}
+		entries[f.Name] = string(data)
+	}
+	return entries
+}
+
+func checkArchiveEntries(t *testing.T, entries map[string]string) {
+	for _, dir := range []string{"images/", "images/train/", "images/val/", "labels/", "labels/train/", "labels/val/"} {
+		if _, ok := entries[dir]; !ok {
+			t.Errorf("Archive missing directory entry %s", dir)
+		}
+	}
+
+	yamlContent, ok := entries["data.yaml"]
+	if !ok {
+		t.Fatal("Archive missing data.yaml")
+	}
+	if strings.Contains(yamlContent, "path:") {
+		t.Error("Archived data.yaml should not contain an absolute path")
+	}
+	if !strings.Contains(yamlContent, "nc: 2") {
+		t.Error("Archived data.yaml should contain correct number of classes")
+	}
+
+	images, labels := 0, 0
+	for name := range entries {
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		if strings.HasPrefix(name, "images/") {
+			images++
+		}
+		if strings.HasPrefix(name, "labels/") {
+			labels++
+		}
+	}
+	if images != 3 || labels != 3 {
+		t.Errorf("Expected 3 images and 3 labels in archive, got %d and %d", images, labels)
+	}
+}
+
+func TestConvertToTarGz(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	archivePath := filepath.Join(tempDir, "out", "dataset.tar.gz")
+
+	converter := NewConverter(Config{
+		SourceDir:  tempDir,
+		OutputDir:  archivePath,
+		TrainSplit: 0.8,
+		Seed:       42,
+	})
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion to tar.gz failed: %v", err)
+	}
+
+	if _, err := os.Stat(archivePath + ".partial"); !os.IsNotExist(err) {
+		t.Error("Partial archive should be removed after a successful conversion")
+	}
+
+	checkArchiveEntries(t, readTarGz(t, archivePath))
+}
+
+func TestConvertToZip(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	archivePath := filepath.Join(tempDir, "dataset.zip")
+
+	converter := NewConverter(Config{
+		SourceDir:  tempDir,
+		OutputDir:  archivePath,
+		TrainSplit: 0.8,
+		Seed:       42,
+	})
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion to zip failed: %v", err)
+	}
+
+	checkArchiveEntries(t, readZip(t, archivePath))
+}
+
+func TestArchiveAbortRemovesPartialFile(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "dataset.tar.gz")
+
+	writer, err := NewDatasetWriter(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive writer: %v", err)
+	}
+	if err := writer.WriteFile("labels/train/a.txt", []byte("0 0.5 0.5 0.1 0.1\n")); err != nil {
+		t.Fatalf("Failed to write archive entry: %v", err)
+	}
+	if err := writer.Abort(); err != nil {
+		t.Fatalf("Failed to abort archive: %v", err)
+	}
+
+	for _, p := range []string{archivePath, archivePath + ".partial"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed after abort", p)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// preserveAttrs gives dst the permission bits, modification time and, where
+// the platform and file systems support them, the extended attributes of src
+func preserveAttrs(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %w", dst, err)
+	}
+	if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("failed to set modification time of %s: %w", dst, err)
+	}
+	copyXattrs(src, dst)
+	return nil
+}
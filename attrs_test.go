@@ -0,0 +1,114 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// writeAttrSource writes a file with unusual permissions and an old
+// modification time
+func writeAttrSource(t *testing.T, dir string) (string, time.Time) {
+	t.Helper()
+	src := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(src, []byte("pixels"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 5, 17, 8, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return src, mtime
+}
+
+func TestDirWriterPreservesAttrs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not preserved on Windows")
+	}
+	dir := t.TempDir()
+	src, mtime := writeAttrSource(t, dir)
+
+	for _, preserve := range []bool{false, true} {
+		root := filepath.Join(dir, "out")
+		w := &DirWriter{Root: root, PreserveAttrs: preserve}
+		if err := w.MkdirAll("images"); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.CopyFile("images/photo.jpg", src); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filepath.Join(root, "images", "photo.jpg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		kept := info.ModTime().Equal(mtime) && info.Mode().Perm() == 0600
+		if kept != preserve {
+			t.Errorf("PreserveAttrs %v: got mode %v and mtime %v", preserve, info.Mode().Perm(), info.ModTime())
+		}
+		os.RemoveAll(root)
+	}
+}
+
+func TestDirWriterPreservesXattrs(t *testing.T) {
+	dir := t.TempDir()
+	src, _ := writeAttrSource(t, dir)
+	if err := setTestXattr(src, "user.origin", "camera-7"); err != nil {
+		t.Skipf("extended attributes are not supported here: %v", err)
+	}
+
+	w := &DirWriter{Root: filepath.Join(dir, "out"), PreserveAttrs: true}
+	if err := w.MkdirAll("images"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.CopyFile("images/photo.jpg", src); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(readXattrs(filepath.Join(dir, "out", "images", "photo.jpg"))["user.origin"]); got != "camera-7" {
+		t.Errorf("Expected the user.origin attribute to be copied, got %q", got)
+	}
+}
+
+func TestTarGzWriterPreservesMode(t *testing.T) {
+	dir := t.TempDir()
+	src, _ := writeAttrSource(t, dir)
+	archivePath := filepath.Join(dir, "dataset.tar.gz")
+
+	w, err := newTarGzWriter(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.PreserveAttrs = true
+	if err := w.CopyFile("images/photo.jpg", src); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			t.Fatalf("Expected images/photo.jpg in the archive: %v", err)
+		}
+		if header.Name == "images/photo.jpg" {
+			if header.Mode != 0600 {
+				t.Errorf("Expected mode 0600, got %o", header.Mode)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// augmentBrightnessJitter is the largest change in brightness of an
+// augmented copy, as a fraction of the original
+const augmentBrightnessJitter = 0.2
+
+// augmentOrientations maps the geometric augmentations to the EXIF
+// orientation that orient applies for them
+var augmentOrientations = map[string]int{
+	"hflip":  2,
+	"rot180": 3,
+	"vflip":  4,
+	"rot90":  6,
+	"rot270": 8,
+}
+
+// augmentation is one augmented copy of an image: an EXIF-style orientation
+// (1 leaves the geometry alone) and a brightness factor
+type augmentation struct {
+	orientation int
+	brightness  float64
+}
+
+// validateAugment checks the augmentation options
+func (c *Converter) validateAugment() error {
+	for _, name := range splitList(c.config.Augment) {
+		if _, ok := augmentOrientations[name]; !ok && name != "brightness" {
+			return fmt.Errorf("unknown augmentation %q (use hflip, vflip, rot90, rot180, rot270 or brightness)", name)
+		}
+	}
+	if c.config.Augment == "" {
+		return nil
+	}
+	if c.config.AugmentMultiplier < 1 {
+		return fmt.Errorf("augment multiplier must be at least 1")
+	}
+	if c.config.Crop {
+		return fmt.Errorf("-augment cannot be combined with -crop")
+	}
+	if c.skeleton != nil {
+		return fmt.Errorf("augmentation is not supported for keypoint labels")
+	}
+	return nil
+}
+
+// augmentTraining adds -augment-multiplier augmented copies of every
+// training pair. Each copy gets one of the listed geometric transforms,
+// picked at random, and a brightness jitter when "brightness" is listed.
+// Validation is left alone so metrics reflect real images.
+func (c *Converter) augmentTraining(trainPairs []LabelPair) ([]LabelPair, error) {
+	var orientations []int
+	jitter := false
+	for _, name := range splitList(c.config.Augment) {
+		if name == "brightness" {
+			jitter = true
+			continue
+		}
+		orientations = append(orientations, augmentOrientations[name])
+	}
+
+	staging, err := c.stagingDirectory()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(staging, "augmented")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	rng := rand.New(rand.NewSource(c.config.Seed))
+	var augmented []LabelPair
+	for _, pair := range trainPairs {
+		img, err := decodeImage(pair.ImagePath)
+		if err != nil {
+			fmt.Fprintf(c.out, "Warning: Cannot decode %s; not augmenting it: %v\n", filepath.Base(pair.ImagePath), err)
+			continue
+		}
+		// Labels are drawn on the upright image
+		img = orient(img, exifOrientation(pair.ImagePath))
+		data, err := os.ReadFile(pair.LabelPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read label %s: %w", pair.LabelPath, err)
+		}
+		lines := strings.Split(string(data), "\n")
+		base := strings.TrimSuffix(filepath.Base(pair.ImagePath), filepath.Ext(pair.ImagePath))
+
+		for n := 1; n <= c.config.AugmentMultiplier; n++ {
+			aug := augmentation{orientation: 1, brightness: 1}
+			if len(orientations) > 0 {
+				aug.orientation = orientations[rng.Intn(len(orientations))]
+			}
+			if jitter {
+				aug.brightness = 1 + (rng.Float64()*2-1)*augmentBrightnessJitter
+			}
+
+			out := orient(img, aug.orientation)
+			if aug.brightness != 1 {
+				out = adjustBrightness(out, aug.brightness)
+			}
+			encoded, ext, err := encodeCrop(out, filepath.Ext(pair.ImagePath))
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode augmented copy of %s: %w", pair.ImagePath, err)
+			}
+
+			name := fmt.Sprintf("%s_aug%d", base, n)
+			dup := LabelPair{
+				ImagePath: filepath.Join(dir, name+ext),
+				LabelPath: filepath.Join(dir, name+".txt"),
+				Group:     pair.Group,
+			}
+			if err := os.WriteFile(dup.ImagePath, encoded, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write augmented image %s: %w", dup.ImagePath, err)
+			}
+			labels := c.augmentLabels(lines, aug.orientation)
+			if err := os.WriteFile(dup.LabelPath, []byte(strings.Join(labels, "")), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write label %s: %w", dup.LabelPath, err)
+			}
+			augmented = append(augmented, dup)
+		}
+	}
+
+	fmt.Fprintf(c.out, "Augmented %d training images into %d copies (%s)\n", len(trainPairs), len(augmented), c.config.Augment)
+	return append(trainPairs, augmented...), nil
+}
+
+// augmentPoint moves a normalized point the way orient moves its pixel
+func augmentPoint(x, y float64, orientation int) (float64, float64) {
+	switch orientation {
+	case 2:
+		return 1 - x, y
+	case 3:
+		return 1 - x, 1 - y
+	case 4:
+		return x, 1 - y
+	case 6:
+		return 1 - y, x
+	case 8:
+		return y, 1 - x
+	}
+	return x, y
+}
+
+// augmentLabels transforms box, polygon and oriented box labels along with
+// their image. Flipped oriented boxes have their corners reversed to stay
+// clockwise.
+func (c *Converter) augmentLabels(lines []string, orientation int) []string {
+	var labels []string
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		kind, warning := c.parseLine(parts)
+		if warning != "" {
+			continue
+		}
+		classID, _ := strconv.Atoi(parts[0])
+		coords := make([]float64, len(parts)-1)
+		for i := range coords {
+			coords[i], _ = strconv.ParseFloat(parts[i+1], 64)
+		}
+
+		if kind == AnnotationBox {
+			coords[0], coords[1] = augmentPoint(coords[0], coords[1], orientation)
+			if orientation >= 5 {
+				coords[2], coords[3] = coords[3], coords[2]
+			}
+			labels = append(labels, polygonLine(classID, coords))
+			continue
+		}
+
+		for i := 0; i+1 < len(coords); i += 2 {
+			coords[i], coords[i+1] = augmentPoint(coords[i], coords[i+1], orientation)
+		}
+		if kind == AnnotationOBB && signedArea(coords) < 0 {
+			for i, j := 0, len(coords)-2; i < j; i, j = i+2, j-2 {
+				coords[i], coords[i+1], coords[j], coords[j+1] = coords[j], coords[j+1], coords[i], coords[i+1]
+			}
+		}
+		labels = append(labels, polygonLine(classID, coords))
+	}
+	return labels
+}
+
+// adjustBrightness scales the color channels of an image by factor, keeping
+// them within the (premultiplied) alpha
+func adjustBrightness(img image.Image, factor float64) image.Image {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	for i := 0; i < len(out.Pix); i += 4 {
+		for j := i; j < i+3; j++ {
+			out.Pix[j] = uint8(min(float64(out.Pix[i+3]), float64(out.Pix[j])*factor+0.5))
+		}
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAugmentLabels(t *testing.T) {
+	converter := NewConverter(Config{})
+	box := []string{"0 0.2 0.3 0.1 0.4", ""}
+	tests := []struct {
+		orientation int
+		want        string
+	}{
+		{2, "0 0.800000 0.300000 0.100000 0.400000\n"},
+		{4, "0 0.200000 0.700000 0.100000 0.400000\n"},
+		{6, "0 0.700000 0.200000 0.400000 0.100000\n"},
+		{8, "0 0.300000 0.800000 0.400000 0.100000\n"},
+	}
+	for _, tt := range tests {
+		if got := converter.augmentLabels(box, tt.orientation); !reflect.DeepEqual(got, []string{tt.want}) {
+			t.Errorf("Orientation %d: expected %q, got %q", tt.orientation, tt.want, got)
+		}
+	}
+
+	// A flipped oriented box must stay clockwise to pass validation
+	obb := NewConverter(Config{OBB: true})
+	flipped := obb.augmentLabels([]string{"0 0.1 0.1 0.3 0.1 0.3 0.2 0.1 0.2"}, 2)
+	if len(flipped) != 1 || parseOBBLine(strings.Fields(flipped[0])) != "" {
+		t.Errorf("Expected a valid clockwise oriented box, got %q", flipped)
+	}
+}
+
+func TestAugmentTraining(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "street.png")
+	writeTestImage(t, imagePath, 40, 20)
+	labelPath := filepath.Join(dir, "street.txt")
+	if err := os.WriteFile(labelPath, []byte("0 0.25 0.5 0.1 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{Augment: "rot90,brightness", AugmentMultiplier: 2})
+	converter.SetOutput(io.Discard)
+	defer converter.removeStaging()
+	if err := converter.validateAugment(); err != nil {
+		t.Fatal(err)
+	}
+	pairs, err := converter.augmentTraining([]LabelPair{{ImagePath: imagePath, LabelPath: labelPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 3 || filepath.Base(pairs[2].ImagePath) != "street_aug2.png" {
+		t.Fatalf("Expected the original and two copies, got %v", pairs)
+	}
+	if width, height, err := readImageSize(pairs[1].ImagePath); err != nil || width != 20 || height != 40 {
+		t.Errorf("Expected a rotated 20x40 copy, got %dx%d, %v", width, height, err)
+	}
+	data, err := os.ReadFile(pairs[1].LabelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0 0.500000 0.250000 0.200000 0.100000\n"; string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+}
+
+func TestAugmentRejectsUnknownTransforms(t *testing.T) {
+	converter := NewConverter(Config{Augment: "hflip,mosaic", AugmentMultiplier: 1})
+	if err := converter.validateAugment(); err == nil {
+		t.Error("Expected an error for an unknown augmentation")
+	}
+}
+
+func TestAugmentRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Crop: true, Augment: "hflip", AugmentMultiplier: 1})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-augment") {
+		t.Errorf("Expected -augment to be rejected with -crop, got %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// labelClassCounts counts the valid lines of each class ID in a label file;
+// unreadable files count as empty
+func (c *Converter) labelClassCounts(labelPath string) map[int]int {
+	counts := make(map[int]int)
+	file, err := os.Open(labelPath)
+	if err != nil {
+		return counts
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+		if _, warning := c.parseLine(parts); warning != "" {
+			continue
+		}
+		classID, _ := strconv.Atoi(parts[0])
+		counts[classID]++
+	}
+	return counts
+}
+
+// splitUnit is a group of pairs that share a split, with their class counts
+type splitUnit struct {
+	pairs  []LabelPair
+	counts map[int]int
+}
+
+// ensureValClasses moves units from training to validation until every
+// annotated class has at least -min-val-instances validation instances.
+// Rarest classes are placed first and units with the most instances of the
+// class move first. A unit holding the last training instances of a class
+// never moves.
+func (c *Converter) ensureValClasses(trainPairs, valPairs []LabelPair, classes []string) ([]LabelPair, []LabelPair) {
+	minInstances := c.config.MinValInstances
+
+	var train []splitUnit
+	trainCounts, valCounts := make(map[int]int), make(map[int]int)
+	for _, unit := range groupPairs(trainPairs) {
+		u := splitUnit{pairs: unit, counts: make(map[int]int)}
+		for _, pair := range unit {
+			for classID, n := range c.labelClassCounts(pair.LabelPath) {
+				u.counts[classID] += n
+				trainCounts[classID] += n
+			}
+		}
+		train = append(train, u)
+	}
+	for _, pair := range valPairs {
+		for classID, n := range c.labelClassCounts(pair.LabelPath) {
+			valCounts[classID] += n
+		}
+	}
+
+	var classIDs []int
+	for classID := range trainCounts {
+		classIDs = append(classIDs, classID)
+	}
+	for classID := range valCounts {
+		if trainCounts[classID] == 0 {
+			classIDs = append(classIDs, classID)
+		}
+	}
+	sort.Slice(classIDs, func(i, j int) bool {
+		a, b := classIDs[i], classIDs[j]
+		if total := trainCounts[a] + valCounts[a] - trainCounts[b] - valCounts[b]; total != 0 {
+			return total < 0
+		}
+		return a < b
+	})
+
+	moved := 0
+	for _, classID := range classIDs {
+		for valCounts[classID] < minInstances {
+			best := -1
+			for i, u := range train {
+				if u.counts[classID] == 0 || holdsLastInstance(u, trainCounts) {
+					continue
+				}
+				if best < 0 || u.counts[classID] > train[best].counts[classID] {
+					best = i
+				}
+			}
+			if best < 0 {
+				break
+			}
+
+			u := train[best]
+			train = append(train[:best], train[best+1:]...)
+			valPairs = append(valPairs, u.pairs...)
+			for id, n := range u.counts {
+				trainCounts[id] -= n
+				valCounts[id] += n
+			}
+			moved += len(u.pairs)
+		}
+		if valCounts[classID] < minInstances {
+			fmt.Fprintf(c.out, "Warning: class %s has only %d validation instances (%d in training)\n",
+				className(classes, classID), valCounts[classID], trainCounts[classID])
+		}
+	}
+
+	if moved == 0 {
+		return trainPairs, valPairs
+	}
+	trainPairs = []LabelPair{}
+	for _, u := range train {
+		trainPairs = append(trainPairs, u.pairs...)
+	}
+	fmt.Fprintf(c.out, "Moved %d images to validation for at least %d instances per class: %d training, %d validation\n",
+		moved, minInstances, len(trainPairs), len(valPairs))
+	return trainPairs, valPairs
+}
+
+// holdsLastInstance reports whether moving a unit would leave a class without
+// training instances
+func holdsLastInstance(u splitUnit, trainCounts map[int]int) bool {
+	for classID, n := range u.counts {
+		if n > 0 && n == trainCounts[classID] {
+			return true
+		}
+	}
+	return false
+}
+
+// className names a class ID, falling back to the ID when it has no name
+func className(classes []string, classID int) string {
+	if classID >= 0 && classID < len(classes) {
+		return classes[classID]
+	}
+	return strconv.Itoa(classID)
+}
+
+// classBalance counts what balancing did to one class
+type classBalance struct {
+	before, after      int
+	dropped, duplicate int
+}
+
+// balanceTraining caps and oversamples the training pairs. Images whose
+// every class already has -max-per-class instances are dropped, then images
+// of classes below -min-per-class are duplicated until the class reaches it.
+// Validation is left alone so metrics stay comparable.
+func (c *Converter) balanceTraining(trainPairs []LabelPair, classes []string) ([]LabelPair, error) {
+	maxPerClass, minPerClass := c.config.MaxPerClass, c.config.MinPerClass
+	balance := make(map[int]*classBalance)
+	classBalanceOf := func(classID int) *classBalance {
+		if balance[classID] == nil {
+			balance[classID] = &classBalance{}
+		}
+		return balance[classID]
+	}
+
+	counts := make([]map[int]int, len(trainPairs))
+	for i, pair := range trainPairs {
+		counts[i] = c.labelClassCounts(pair.LabelPath)
+		for classID, n := range counts[i] {
+			classBalanceOf(classID).before += n
+		}
+	}
+
+	// Cap: keep an image while any of its classes is below the cap
+	var kept []LabelPair
+	var keptCounts []map[int]int
+	for i, pair := range trainPairs {
+		keep := maxPerClass <= 0 || len(counts[i]) == 0
+		for classID := range counts[i] {
+			if balance[classID].after < maxPerClass {
+				keep = true
+			}
+		}
+		if !keep {
+			for classID := range counts[i] {
+				balance[classID].dropped++
+			}
+			continue
+		}
+		for classID, n := range counts[i] {
+			balance[classID].after += n
+		}
+		kept = append(kept, pair)
+		keptCounts = append(keptCounts, counts[i])
+	}
+
+	// Oversample the rarest classes first, cycling through their images
+	classIDs := make([]int, 0, len(balance))
+	for classID := range balance {
+		classIDs = append(classIDs, classID)
+	}
+	sort.Slice(classIDs, func(i, j int) bool {
+		a, b := balance[classIDs[i]], balance[classIDs[j]]
+		if a.after != b.after {
+			return a.after < b.after
+		}
+		return classIDs[i] < classIDs[j]
+	})
+
+	copies := make(map[string]int)
+	var duplicates []LabelPair
+	for _, classID := range classIDs {
+		var sources []int
+		for i := range kept {
+			if keptCounts[i][classID] > 0 {
+				sources = append(sources, i)
+			}
+		}
+		for n := 0; len(sources) > 0 && balance[classID].after < minPerClass; n++ {
+			i := sources[n%len(sources)]
+			duplicate, err := c.duplicatePair(kept[i], copies)
+			if err != nil {
+				return nil, err
+			}
+			duplicates = append(duplicates, duplicate)
+			for id, count := range keptCounts[i] {
+				classBalanceOf(id).after += count
+				balance[id].duplicate++
+			}
+		}
+	}
+
+	dropped := len(trainPairs) - len(kept)
+	if dropped == 0 && len(duplicates) == 0 {
+		return trainPairs, nil
+	}
+
+	fmt.Fprintf(c.out, "Balanced training set: dropped %d images, duplicated %d images (%d training images)\n",
+		dropped, len(duplicates), len(kept)+len(duplicates))
+	sort.Ints(classIDs)
+	for _, classID := range classIDs {
+		b := balance[classID]
+		fmt.Fprintf(c.out, "  %s: %d -> %d instances, %d images dropped, %d duplicated\n",
+			className(classes, classID), b.before, b.after, b.dropped, b.duplicate)
+	}
+	return append(kept, duplicates...), nil
+}
+
+// duplicatePair stages a copy of a pair under a new name, so it is written
+// to the output a second time. copies numbers the copies of each image.
+func (c *Converter) duplicatePair(pair LabelPair, copies map[string]int) (LabelPair, error) {
+	staging, err := c.stagingDirectory()
+	if err != nil {
+		return LabelPair{}, err
+	}
+	dir := filepath.Join(staging, "oversampled")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return LabelPair{}, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	copies[pair.ImagePath]++
+	ext := filepath.Ext(pair.ImagePath)
+	stem := fmt.Sprintf("%s_dup%d", strings.TrimSuffix(filepath.Base(pair.ImagePath), ext), copies[pair.ImagePath])
+	duplicate := LabelPair{
+		ImagePath: filepath.Join(dir, stem+ext),
+		LabelPath: filepath.Join(dir, stem+".txt"),
+		Group:     pair.Group,
+	}
+	for _, file := range [][2]string{{pair.ImagePath, duplicate.ImagePath}, {pair.LabelPath, duplicate.LabelPath}} {
+		// Hard links avoid copying large images twice
+		if err := os.Link(file[0], file[1]); err != nil {
+			if err := copyFile(file[0], file[1]); err != nil {
+				return LabelPair{}, fmt.Errorf("failed to duplicate %s: %w", file[0], err)
+			}
+		}
+	}
+	return duplicate, nil
+}
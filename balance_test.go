@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRareClassPairs writes eight images of class 0, three of which also
+// hold class 1 (twice in rare_2)
+func writeRareClassPairs(t *testing.T, dir string) []LabelPair {
+	t.Helper()
+	var pairs []LabelPair
+	for i := 0; i < 8; i++ {
+		content := "0 0.5 0.5 0.2 0.2\n"
+		name := fmt.Sprintf("common_%d", i)
+		if i < 3 {
+			name = fmt.Sprintf("rare_%d", i)
+			content += strings.Repeat("1 0.2 0.2 0.1 0.1\n", 1+i/2)
+		}
+		labelPath := filepath.Join(dir, name+".txt")
+		if err := os.WriteFile(labelPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		imagePath := filepath.Join(dir, name+".jpg")
+		if err := os.WriteFile(imagePath, []byte("image"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		pairs = append(pairs, LabelPair{ImagePath: imagePath, LabelPath: labelPath})
+	}
+	return pairs
+}
+
+func TestEnsureValClasses(t *testing.T) {
+	pairs := writeRareClassPairs(t, t.TempDir())
+	train, val := pairs[:6], pairs[6:]
+
+	converter := NewConverter(Config{MinValInstances: 2})
+	var out strings.Builder
+	converter.SetOutput(&out)
+	train, val = converter.ensureValClasses(train, val, []string{"car", "bike"})
+
+	// rare_2 holds two bikes and moves alone
+	if len(train) != 5 || len(val) != 3 || filepath.Base(val[2].LabelPath) != "rare_2.txt" {
+		t.Errorf("Expected rare_2 to move to validation, got %d training and %v", len(train), val)
+	}
+	if !strings.Contains(out.String(), "Moved 1 images to validation") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+}
+
+func TestEnsureValClassesKeepsTrainingInstances(t *testing.T) {
+	pairs := writeRareClassPairs(t, t.TempDir())
+	train, val := pairs[:6], pairs[6:]
+
+	converter := NewConverter(Config{MinValInstances: 5})
+	var out strings.Builder
+	converter.SetOutput(&out)
+	train, _ = converter.ensureValClasses(train, val, []string{"car", "bike"})
+
+	bikes := 0
+	for _, pair := range train {
+		bikes += converter.labelClassCounts(pair.LabelPath)[1]
+	}
+	if bikes == 0 {
+		t.Error("Expected training to keep a bike")
+	}
+	if !strings.Contains(out.String(), "Warning: class bike has only 3 validation instances (1 in training)") {
+		t.Errorf("Expected a warning about bike, got: %s", out.String())
+	}
+}
+
+func TestBalanceTraining(t *testing.T) {
+	pairs := writeRareClassPairs(t, t.TempDir())
+
+	converter := NewConverter(Config{MaxPerClass: 4, MinPerClass: 6})
+	var out strings.Builder
+	converter.SetOutput(&out)
+	defer converter.removeStaging()
+
+	train, err := converter.balanceTraining(pairs, []string{"car", "bike"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[int]int)
+	names := make(map[string]bool)
+	for _, pair := range train {
+		if names[filepath.Base(pair.ImagePath)] {
+			t.Errorf("Duplicate output name %s", filepath.Base(pair.ImagePath))
+		}
+		names[filepath.Base(pair.ImagePath)] = true
+		for classID, n := range converter.labelClassCounts(pair.LabelPath) {
+			counts[classID] += n
+		}
+	}
+
+	// The cap keeps the three bike images and one more car image; bikes are
+	// then duplicated from 4 to at least 6 instances
+	if counts[1] < 6 {
+		t.Errorf("Expected at least 6 bikes, got %d", counts[1])
+	}
+	if names["common_4.jpg"] || !names["common_3.jpg"] {
+		t.Errorf("Expected car-only images after the cap to be dropped, got %v", names)
+	}
+	if !names["rare_0_dup1.jpg"] {
+		t.Errorf("Expected rare_0 to be duplicated, got %v", names)
+	}
+	if !strings.Contains(out.String(), "bike: 4 -> ") || !strings.Contains(out.String(), "car: 8 -> ") {
+		t.Errorf("Expected a per-class report, got:\n%s", out.String())
+	}
+}
+
+func TestMinValInstancesRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Crop: true, MinValInstances: 2})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-min-val-instances") {
+		t.Errorf("Expected -min-val-instances to be rejected with -crop, got %v", err)
+	}
+}
+
+func TestBalanceTrainingRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Crop: true, MaxPerClass: 10})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-max-per-class") {
+		t.Errorf("Expected -max-per-class and -min-per-class to be rejected with -crop, got %v", err)
+	}
+}
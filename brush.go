@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxBrushPixels caps the image size a brush mask is decoded at, 8192x8192,
+// so a crafted original_width and original_height can't demand gigabytes
+const maxBrushPixels = 1 << 26
+
+// rleBitReader reads big-endian bit fields from a Label Studio brush RLE
+// stream, which is produced by the @thi.ng/rle-pack encoder
+type rleBitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *rleBitReader) read(bits int) (int, error) {
+	if r.pos+bits > len(r.data)*8 {
+		return 0, fmt.Errorf("truncated RLE data")
+	}
+	v := 0
+	for i := 0; i < bits; i++ {
+		bit := (r.data[r.pos/8] >> (7 - uint(r.pos%8))) & 1
+		v = v<<1 | int(bit)
+		r.pos++
+	}
+	return v, nil
+}
+
+// decodeBrushRLE unpacks a brush RLE into its raw RGBA bytes. The header's
+// length must equal expected, so a corrupt header can't size the buffer.
+func decodeBrushRLE(rle []byte, expected int) ([]byte, error) {
+	r := &rleBitReader{data: rle}
+
+	num, err := r.read(32)
+	if err != nil {
+		return nil, err
+	}
+	if num != expected {
+		return nil, fmt.Errorf("RLE decodes to %d bytes, expected %d", num, expected)
+	}
+	wordSize, err := r.read(5)
+	if err != nil {
+		return nil, err
+	}
+	wordSize++
+
+	var rleSizes [4]int
+	for i := range rleSizes {
+		size, err := r.read(4)
+		if err != nil {
+			return nil, err
+		}
+		rleSizes[i] = size + 1
+	}
+
+	out := make([]byte, num)
+	for i := 0; i < num; {
+		repeat, err := r.read(1)
+		if err != nil {
+			return nil, err
+		}
+		sizeIdx, err := r.read(2)
+		if err != nil {
+			return nil, err
+		}
+		runLen, err := r.read(rleSizes[sizeIdx])
+		if err != nil {
+			return nil, err
+		}
+
+		j := i + 1 + runLen
+		if j > num {
+			return nil, fmt.Errorf("RLE run exceeds decoded size")
+		}
+
+		if repeat == 1 {
+			val, err := r.read(wordSize)
+			if err != nil {
+				return nil, err
+			}
+			for ; i < j; i++ {
+				out[i] = byte(val)
+			}
+		} else {
+			for ; i < j; i++ {
+				val, err := r.read(wordSize)
+				if err != nil {
+					return nil, err
+				}
+				out[i] = byte(val)
+			}
+		}
+	}
+	return out, nil
+}
+
+// brushMask decodes a brush RLE into a width x height mask using the alpha
+// channel, which is where Label Studio paints the brush strokes
+func brushMask(rle []byte, width, height int) ([]bool, error) {
+	if width <= 0 || height <= 0 || width > maxBrushPixels/height {
+		return nil, fmt.Errorf("brush mask size %dx%d must be positive and at most %d pixels", width, height, maxBrushPixels)
+	}
+	rgba, err := decodeBrushRLE(rle, width*height*4)
+	if err != nil {
+		return nil, err
+	}
+
+	mask := make([]bool, width*height)
+	for i := range mask {
+		mask[i] = rgba[i*4+3] > 0
+	}
+	return mask, nil
+}
+
+// maskPolygons traces the outer contour of every 8-connected region of the
+// mask and simplifies it with the given tolerance in pixels. Polygons are
+// returned in pixel coordinates; holes are not represented since YOLO
+// segmentation labels have no way to express them.
+func maskPolygons(mask []bool, width, height int, tolerance float64) [][][2]float64 {
+	components := make([]int, len(mask))
+	var polygons [][][2]float64
+	next := 0
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !mask[y*width+x] || components[y*width+x] != 0 {
+				continue
+			}
+
+			// Raster order guarantees this is the top-left pixel of the region
+			next++
+			labelComponent(mask, components, width, height, x, y, next)
+
+			contour := traceContour(components, width, height, next, x, y)
+			polygon := simplifyRing(contour, tolerance)
+			if len(polygon) >= 3 && ringArea(polygon) > 0 {
+				polygons = append(polygons, polygon)
+			}
+		}
+	}
+	return polygons
+}
+
+// labelComponent flood-fills the 8-connected region containing (x, y)
+func labelComponent(mask []bool, components []int, width, height, x, y, id int) {
+	stack := [][2]int{{x, y}}
+	components[y*width+x] = id
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := p[0]+dx, p[1]+dy
+				if nx < 0 || ny < 0 || nx >= width || ny >= height {
+					continue
+				}
+				idx := ny*width + nx
+				if mask[idx] && components[idx] == 0 {
+					components[idx] = id
+					stack = append(stack, [2]int{nx, ny})
+				}
+			}
+		}
+	}
+}
+
+// traceContour walks the pixel edges around a region, keeping the region on
+// the right, starting at the top-left corner of its top-left pixel. Only the
+// corners where the walk turns are returned.
+func traceContour(components []int, width, height, id, startX, startY int) [][2]float64 {
+	inside := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < width && y < height && components[y*width+x] == id
+	}
+
+	// East, south, west, north; turning right is +1 in image coordinates
+	dirs := [4][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+	x, y, d := startX, startY, 0
+	contour := [][2]float64{{float64(x), float64(y)}}
+	for {
+		x += dirs[d][0]
+		y += dirs[d][1]
+
+		// Pixels ahead of the corner, left and right of the direction of travel
+		var left, right bool
+		switch d {
+		case 0:
+			left, right = inside(x, y-1), inside(x, y)
+		case 1:
+			left, right = inside(x, y), inside(x-1, y)
+		case 2:
+			left, right = inside(x-1, y), inside(x-1, y-1)
+		case 3:
+			left, right = inside(x-1, y-1), inside(x, y-1)
+		}
+
+		nd := d
+		switch {
+		case left:
+			nd = (d + 3) % 4
+		case right:
+		default:
+			nd = (d + 1) % 4
+		}
+
+		if x == startX && y == startY && nd == 0 {
+			return contour
+		}
+		if nd != d {
+			contour = append(contour, [2]float64{float64(x), float64(y)})
+		}
+		d = nd
+	}
+}
+
+// simplifyRing applies Douglas-Peucker to a closed polygon by splitting it at
+// the vertex farthest from the first one
+func simplifyRing(ring [][2]float64, tolerance float64) [][2]float64 {
+	if tolerance <= 0 || len(ring) < 4 {
+		return ring
+	}
+
+	far, farDist := 0, -1.0
+	for i, p := range ring {
+		if d := math.Hypot(p[0]-ring[0][0], p[1]-ring[0][1]); d > farDist {
+			far, farDist = i, d
+		}
+	}
+
+	closed := append(append([][2]float64{}, ring[far:]...), ring[0])
+	first := douglasPeucker(ring[:far+1], tolerance)
+	second := douglasPeucker(closed, tolerance)
+
+	result := append([][2]float64{}, first[:len(first)-1]...)
+	return append(result, second[:len(second)-1]...)
+}
+
+// douglasPeucker simplifies an open polyline, keeping its end points
+func douglasPeucker(points [][2]float64, tolerance float64) [][2]float64 {
+	if len(points) < 3 {
+		return append([][2]float64{}, points...)
+	}
+
+	a, b := points[0], points[len(points)-1]
+	index, maxDist := 0, 0.0
+	for i := 1; i < len(points)-1; i++ {
+		if d := pointSegmentDistance(points[i], a, b); d > maxDist {
+			index, maxDist = i, d
+		}
+	}
+
+	if maxDist <= tolerance {
+		return [][2]float64{a, b}
+	}
+
+	left := douglasPeucker(points[:index+1], tolerance)
+	right := douglasPeucker(points[index:], tolerance)
+	return append(left[:len(left)-1], right...)
+}
+
+func pointSegmentDistance(p, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	lengthSq := dx*dx + dy*dy
+	if lengthSq == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1])
+	}
+	t := ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / lengthSq
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p[0]-(a[0]+t*dx), p[1]-(a[1]+t*dy))
+}
+
+// ringArea returns the area enclosed by a polygon of points
+func ringArea(ring [][2]float64) float64 {
+	flat := make([]float64, 0, len(ring)*2)
+	for _, p := range ring {
+		flat = append(flat, p[0], p[1])
+	}
+	return polygonArea(flat)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// encodeBrushRLE packs bytes in the rle-pack format Label Studio uses, with
+// 8-bit words and repeat runs of up to 65536 bytes
+func encodeBrushRLE(data []byte) []int {
+	var bits []byte
+	write := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, byte(v>>uint(i)&1))
+		}
+	}
+
+	write(len(data), 32)
+	write(7, 5)
+	for i := 0; i < 4; i++ {
+		write(15, 4)
+	}
+	for i := 0; i < len(data); {
+		j := i
+		for j < len(data) && data[j] == data[i] && j-i < 1<<16 {
+			j++
+		}
+		write(1, 1)
+		write(0, 2)
+		write(j-i-1, 16)
+		write(int(data[i]), 8)
+		i = j
+	}
+
+	out := make([]int, (len(bits)+7)/8)
+	for i, bit := range bits {
+		out[i/8] |= int(bit) << uint(7-i%8)
+	}
+	return out
+}
+
+// maskRLE encodes a mask as the RGBA brush RLE Label Studio exports
+func maskRLE(mask []bool) []int {
+	rgba := make([]byte, len(mask)*4)
+	for i, set := range mask {
+		if set {
+			rgba[i*4+3] = 255
+		}
+	}
+	return encodeBrushRLE(rgba)
+}
+
+// parseMask builds a mask from rows of '#' and '.'
+func parseMask(rows ...string) ([]bool, int, int) {
+	mask := make([]bool, 0, len(rows)*len(rows[0]))
+	for _, row := range rows {
+		for _, ch := range row {
+			mask = append(mask, ch == '#')
+		}
+	}
+	return mask, len(rows[0]), len(rows)
+}
+
+func TestBrushMaskRoundTrip(t *testing.T) {
+	mask, width, height := parseMask(
+		"......",
+		".###..",
+		".###..",
+		"....#.",
+	)
+
+	rle := maskRLE(mask)
+	data := make([]byte, len(rle))
+	for i, v := range rle {
+		data[i] = byte(v)
+	}
+
+	decoded, err := brushMask(data, width, height)
+	if err != nil {
+		t.Fatalf("Failed to decode mask: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, mask) {
+		t.Errorf("Decoded mask does not match:\n got %v\nwant %v", decoded, mask)
+	}
+
+	if _, err := brushMask(data, width+1, height); err == nil {
+		t.Error("Expected error for mismatched image size")
+	}
+	if _, err := brushMask(data[:len(data)/2], width, height); err == nil {
+		t.Error("Expected error for truncated RLE")
+	}
+
+	// A header claiming 4 GB must be rejected before anything is allocated
+	huge := append([]byte{0xff, 0xff, 0xff, 0xff}, data[4:]...)
+	if _, err := brushMask(huge, width, height); err == nil {
+		t.Error("Expected error for RLE length that does not match the image size")
+	}
+
+	// Image sizes that are empty or too large to decode are rejected up front
+	for _, size := range [][2]int{{0, height}, {width, -1}, {1 << 20, 1 << 20}, {1 << 62, 4}} {
+		if _, err := brushMask(data, size[0], size[1]); err == nil {
+			t.Errorf("Expected error for a %dx%d mask", size[0], size[1])
+		}
+	}
+}
+
+func TestMaskPolygons(t *testing.T) {
+	mask, width, height := parseMask(
+		"......",
+		".###..",
+		".###..",
+		"......",
+		".....#",
+	)
+
+	polygons := maskPolygons(mask, width, height, 0)
+	expected := [][][2]float64{
+		{{1, 1}, {4, 1}, {4, 3}, {1, 3}},
+		{{5, 4}, {6, 4}, {6, 5}, {5, 5}},
+	}
+	if !reflect.DeepEqual(polygons, expected) {
+		t.Errorf("Expected polygons %v, got %v", expected, polygons)
+	}
+}
+
+func TestMaskPolygonsLShapeAndSimplification(t *testing.T) {
+	mask, width, height := parseMask(
+		"#...",
+		"##..",
+		"###.",
+		"####",
+	)
+
+	// Without simplification every step of the staircase is kept
+	exact := maskPolygons(mask, width, height, 0)
+	if len(exact) != 1 || len(exact[0]) != 10 {
+		t.Fatalf("Expected one staircase polygon with 10 corners, got %v", exact)
+	}
+	if area := ringArea(exact[0]); area != 10 {
+		t.Errorf("Expected staircase area 10, got %v", area)
+	}
+
+	simplified := maskPolygons(mask, width, height, 1)
+	if len(simplified) != 1 || len(simplified[0]) >= len(exact[0]) || len(simplified[0]) < 3 {
+		t.Fatalf("Expected simplification to drop staircase corners, got %v", simplified)
+	}
+}
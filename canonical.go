@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// canonicalLabelText puts label lines into a canonical form: fields separated
+// by single spaces, lines sorted by class ID and then by coordinates, blank
+// lines and exact duplicates removed, and a trailing newline. Converting the
+// same annotations twice then gives byte-identical label files.
+func canonicalLabelText(text string) string {
+	var lines [][]string
+	for _, line := range strings.Split(text, "\n") {
+		if parts := strings.Fields(line); len(parts) > 0 {
+			lines = append(lines, parts)
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return compareLabelFields(lines[i], lines[j]) < 0
+	})
+
+	var b strings.Builder
+	previous := ""
+	for _, parts := range lines {
+		line := strings.Join(parts, " ")
+		if line == previous {
+			continue
+		}
+		previous = line
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// compareLabelFields orders two label lines field by field, numerically
+// where both fields are numbers, then the shorter line first, then by their
+// text, so that exact duplicates end up next to each other
+func compareLabelFields(a, b []string) int {
+	for k := 0; k < len(a) && k < len(b); k++ {
+		x, errX := strconv.ParseFloat(a[k], 64)
+		y, errY := strconv.ParseFloat(b[k], 64)
+		switch {
+		case errX == nil && errY == nil && x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		case (errX != nil || errY != nil) && a[k] != b[k]:
+			return strings.Compare(a[k], b[k])
+		}
+	}
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(strings.Join(a, " "), strings.Join(b, " "))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalLabelText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"sorted by class", "1 0.2 0.8 0.1 0.1\n0 0.5 0.5 0.3 0.3\n", "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n"},
+		{"numeric order", "10 0.5 0.5 0.1 0.1\n2 0.5 0.5 0.1 0.1\n2 0.25 0.5 0.1 0.1\n", "2 0.25 0.5 0.1 0.1\n2 0.5 0.5 0.1 0.1\n10 0.5 0.5 0.1 0.1\n"},
+		{"duplicates", "0 0.5 0.5 0.3 0.3\n0 0.50 0.5 0.3 0.3\n0 0.5  0.5 0.3 0.3\n", "0 0.5 0.5 0.3 0.3\n0 0.50 0.5 0.3 0.3\n"},
+		{"blank lines and no newline", "\n1 0.2 0.8 0.1 0.1\n\n0 0.5 0.5 0.3 0.3", "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalLabelText(tt.text); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertCanonicalLabels(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		sourceDir := t.TempDir()
+		createTestFiles(t, sourceDir)
+		source := "1 0.2 0.8 0.1 0.1\n0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n"
+		if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image1.txt"), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		outputDir := filepath.Join(t.TempDir(), "out")
+
+		var out strings.Builder
+		converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, Stream: stream, CanonicalLabels: true})
+		converter.SetOutput(&out)
+		if err := converter.Convert(); err != nil {
+			t.Fatalf("stream %v: conversion failed: %v\n%s", stream, err, out.String())
+		}
+		got, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", "image1.txt"))
+		if want := "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n"; err != nil || string(got) != want {
+			t.Errorf("stream %v: expected %q, got %q (%v)", stream, want, got, err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(sourceDir, "labels", "image1.txt")); string(data) != source {
+			t.Errorf("stream %v: expected the source label to be unchanged", stream)
+		}
+	}
+}
+
+func TestConvertCanonicalLabelsMove(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, Move: true, CanonicalLabels: true})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	if _, err := os.Stat(filepath.Join(sourceDir, "labels", "image1.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected the moved source label to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "labels", "train", "image1.txt")); err != nil {
+		t.Errorf("Expected the label in the output: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+)
+
+// catalogFile is the SQLite catalog written into the output by -catalog
+const catalogFile = "dataset.db"
+
+// catalogIndexes speed up the usual catalog queries: images by name, split
+// or class, and the annotations of an image
+var catalogIndexes = []sqliteIndex{
+	{name: "images_file_name", table: "images", sql: "CREATE INDEX images_file_name ON images (file_name)", columns: []int{1}},
+	{name: "splits_split", table: "splits", sql: "CREATE INDEX splits_split ON splits (split)", columns: []int{1}},
+	{name: "annotations_image_id", table: "annotations", sql: "CREATE INDEX annotations_image_id ON annotations (image_id)", columns: []int{1}},
+	{name: "annotations_class_id", table: "annotations", sql: "CREATE INDEX annotations_class_id ON annotations (class_id)", columns: []int{2}},
+}
+
+// writeCatalog writes the dataset.db SQLite catalog of the dataset: its
+// classes, images, split assignments and annotations, so tools can query
+// the dataset without parsing every label file
+func (c *Converter) writeCatalog(classes []string, splits map[string][]LabelPair) error {
+	tables := []sqliteTable{
+		{name: "classes", sql: "CREATE TABLE classes (id INTEGER PRIMARY KEY, name TEXT NOT NULL)"},
+		{name: "images", sql: "CREATE TABLE images (id INTEGER PRIMARY KEY, file_name TEXT NOT NULL)"},
+		{name: "splits", sql: "CREATE TABLE splits (image_id INTEGER PRIMARY KEY REFERENCES images (id), split TEXT NOT NULL)"},
+		{name: "annotations", sql: "CREATE TABLE annotations (id INTEGER PRIMARY KEY, image_id INTEGER NOT NULL REFERENCES images (id), " +
+			"class_id INTEGER NOT NULL REFERENCES classes (id), cx REAL NOT NULL, cy REAL NOT NULL, w REAL NOT NULL, h REAL NOT NULL)"},
+	}
+	for id, name := range classes {
+		tables[0].rows = append(tables[0].rows, []any{int64(id), name})
+	}
+
+	var imageID, annotationID int64
+	for _, split := range []string{"train", "val"} {
+		for _, pair := range splits[split] {
+			boxes, err := readLabelBoxes(pair.LabelPath, len(classes))
+			if err != nil {
+				return err
+			}
+			imageID++
+			tables[1].rows = append(tables[1].rows, []any{imageID, filepath.Base(pair.ImagePath)})
+			tables[2].rows = append(tables[2].rows, []any{imageID, split})
+			for _, box := range boxes {
+				annotationID++
+				tables[3].rows = append(tables[3].rows, []any{annotationID, imageID, int64(box.ClassID),
+					box.Bounds[0], box.Bounds[1], box.Bounds[2], box.Bounds[3]})
+			}
+		}
+	}
+
+	var db bytes.Buffer
+	if err := writeSQLite(&db, tables, catalogIndexes); err != nil {
+		return fmt.Errorf("failed to build %s: %w", catalogFile, err)
+	}
+	if err := c.output().WriteFile(catalogFile, db.Bytes()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", catalogFile, err)
+	}
+	fmt.Fprintf(c.out, "Wrote catalog of %d images and %d annotations to %s\n", imageID, annotationID, catalogFile)
+	return nil
+}
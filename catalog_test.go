@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readSQLiteVarint decodes an SQLite varint, returning it and its length
+func readSQLiteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := range 8 {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return v<<8 | uint64(b[8]), 9
+}
+
+// decodeSQLiteRecord decodes the values of a record
+func decodeSQLiteRecord(t *testing.T, record []byte) []any {
+	headerLen, n := readSQLiteVarint(record)
+	header, body := record[n:headerLen], record[headerLen:]
+	var values []any
+	for len(header) > 0 {
+		serial, n := readSQLiteVarint(header)
+		header = header[n:]
+		switch {
+		case serial == 0:
+			values = append(values, nil)
+		case serial == 8 || serial == 9:
+			values = append(values, int64(serial-8))
+		case serial == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(body)))
+			body = body[8:]
+		case serial >= 1 && serial <= 6:
+			size := []int{1, 2, 3, 4, 6, 8}[serial-1]
+			v := int64(int8(body[0]))
+			for _, b := range body[1:size] {
+				v = v<<8 | int64(b)
+			}
+			values = append(values, v)
+			body = body[size:]
+		case serial >= 13 && serial%2 == 1:
+			size := (serial - 13) / 2
+			values = append(values, string(body[:size]))
+			body = body[size:]
+		default:
+			t.Fatalf("Unexpected serial type %d", serial)
+		}
+	}
+	return values
+}
+
+// walkSQLiteTree visits the cells of a b-tree in key order, passing the
+// rowid of table rows and the payload of rows and index keys
+func walkSQLiteTree(t *testing.T, db []byte, root uint32, visit func(rowid int64, payload []byte)) {
+	page := db[int(root-1)*sqlitePageSize : int(root)*sqlitePageSize]
+	header := page
+	if root == 1 {
+		header = page[100:]
+	}
+	pageType := header[0]
+	cells := int(binary.BigEndian.Uint16(header[3:]))
+	pointers := header[headerSize(pageType):]
+	for i := range cells {
+		cell := page[binary.BigEndian.Uint16(pointers[2*i:]):]
+		switch pageType {
+		case sqliteTableInterior:
+			walkSQLiteTree(t, db, binary.BigEndian.Uint32(cell), visit)
+		case sqliteIndexInterior:
+			walkSQLiteTree(t, db, binary.BigEndian.Uint32(cell), visit)
+			size, n := readSQLiteVarint(cell[4:])
+			visit(0, cell[4+n:4+n+int(size)])
+		case sqliteTableLeaf:
+			size, n := readSQLiteVarint(cell)
+			rowid, m := readSQLiteVarint(cell[n:])
+			visit(int64(rowid), cell[n+m:n+m+int(size)])
+		case sqliteIndexLeaf:
+			size, n := readSQLiteVarint(cell)
+			visit(0, cell[n:n+int(size)])
+		default:
+			t.Fatalf("Unexpected page type %#x on page %d", pageType, root)
+		}
+	}
+	if pageType == sqliteTableInterior || pageType == sqliteIndexInterior {
+		walkSQLiteTree(t, db, binary.BigEndian.Uint32(header[8:]), visit)
+	}
+}
+
+// readSQLiteDatabase reads every table and index of a database by name:
+// table rows with the rowid as their first value, index keys in order
+func readSQLiteDatabase(t *testing.T, db []byte) map[string][][]any {
+	if !bytes.HasPrefix(db, []byte("SQLite format 3\x00")) {
+		t.Fatal("Missing SQLite header")
+	}
+	if pages := binary.BigEndian.Uint32(db[28:]); int(pages)*sqlitePageSize != len(db) {
+		t.Fatalf("Header counts %d pages in %d bytes", pages, len(db))
+	}
+	contents := make(map[string][][]any)
+	walkSQLiteTree(t, db, 1, func(_ int64, payload []byte) {
+		entry := decodeSQLiteRecord(t, payload)
+		kind, name, root := entry[0].(string), entry[1].(string), uint32(entry[3].(int64))
+		contents[name] = [][]any{}
+		walkSQLiteTree(t, db, root, func(rowid int64, payload []byte) {
+			values := decodeSQLiteRecord(t, payload)
+			if kind == "table" {
+				values[0] = rowid
+			}
+			contents[name] = append(contents[name], values)
+		})
+	})
+	return contents
+}
+
+func TestWriteSQLite(t *testing.T) {
+	// Enough rows for interior pages in both kinds of tree
+	var rows [][]any
+	for i := 20000; i > 0; i-- {
+		rows = append(rows, []any{int64(i), fmt.Sprintf("name%d", i%997), float64(i) / 4, int64(-i * 1000)})
+	}
+	var buf bytes.Buffer
+	err := writeSQLite(&buf, []sqliteTable{{name: "t", sql: "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, x REAL, y INTEGER)", rows: rows}},
+		[]sqliteIndex{{name: "t_name", table: "t", sql: "CREATE INDEX t_name ON t (name)", columns: []int{1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := readSQLiteDatabase(t, buf.Bytes())
+	table := contents["t"]
+	if len(table) != 20000 {
+		t.Fatalf("Expected 20000 rows, got %d", len(table))
+	}
+	for i, row := range table {
+		id := int64(i + 1)
+		if row[0] != id || row[1] != fmt.Sprintf("name%d", id%997) || row[2] != float64(id)/4 || row[3] != -id*1000 {
+			t.Fatalf("Unexpected row %d: %v", id, row)
+		}
+	}
+
+	keys := contents["t_name"]
+	if len(keys) != 20000 {
+		t.Fatalf("Expected 20000 index keys, got %d", len(keys))
+	}
+	for i := 1; i < len(keys); i++ {
+		if compareSQLiteKeys(keys[i-1], keys[i]) >= 0 {
+			t.Fatalf("Index keys out of order: %v before %v", keys[i-1], keys[i])
+		}
+	}
+}
+
+func TestSQLiteVarint(t *testing.T) {
+	for _, v := range []uint64{0, 127, 128, 16383, 16384, 1<<56 - 1, 1 << 56, math.MaxUint64} {
+		b := appendSQLiteVarint(nil, v)
+		if got, n := readSQLiteVarint(b); got != v || n != len(b) {
+			t.Errorf("Varint %d decoded as %d from %d of %d bytes", v, got, n, len(b))
+		}
+	}
+	if got := appendSQLiteVarint(nil, 300); !bytes.Equal(got, []byte{0x82, 0x2c}) {
+		t.Errorf("Unexpected encoding of 300: %x", got)
+	}
+}
+
+func TestConvertCatalog(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image2.txt"), []byte("0 0.2 0.2 0.6 0.2 0.6 0.8\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.7, Seed: 42, Catalog: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := os.ReadFile(filepath.Join(outputDir, catalogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := readSQLiteDatabase(t, db)
+	for _, name := range []string{"classes", "images", "splits", "annotations", "images_file_name", "splits_split", "annotations_image_id", "annotations_class_id"} {
+		if _, ok := contents[name]; !ok {
+			t.Errorf("Expected %s in the catalog", name)
+		}
+	}
+	if fmt.Sprint(contents["classes"]) != "[[0 book] [1 person]]" {
+		t.Errorf("Unexpected classes %v", contents["classes"])
+	}
+
+	images := make(map[int64]string)
+	for _, row := range contents["images"] {
+		images[row[0].(int64)] = row[1].(string)
+	}
+	splits := make(map[string]string)
+	for _, row := range contents["splits"] {
+		splits[images[row[0].(int64)]] = row[1].(string)
+	}
+	if len(splits) != 3 {
+		t.Fatalf("Expected 3 images with splits, got %v", splits)
+	}
+	for name, split := range splits {
+		if _, err := os.Stat(filepath.Join(outputDir, "images", split, name)); err != nil {
+			t.Errorf("Catalog puts %s in %s: %v", name, split, err)
+		}
+	}
+
+	boxes := make(map[string][]any)
+	for _, row := range contents["annotations"] {
+		boxes[fmt.Sprint(images[row[1].(int64)], " ", row[2])] = row[3:]
+	}
+	if len(contents["annotations"]) != 5 {
+		t.Errorf("Expected 5 annotations, got %d", len(contents["annotations"]))
+	}
+	// Polygons are stored as the box enclosing them
+	if got := fmt.Sprintf("%.2f", boxes["image2.png 0"]); got != "[0.40 0.50 0.40 0.60]" {
+		t.Errorf("Unexpected polygon box %s", got)
+	}
+	if len(contents["annotations_class_id"]) != 5 || contents["annotations_class_id"][0][0] != int64(0) {
+		t.Errorf("Unexpected class index %v", contents["annotations_class_id"])
+	}
+}
+
+func TestCatalogRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Catalog: true, Crop: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-catalog") {
+		t.Errorf("Expected -catalog to be rejected with -crop, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// checkpointName is the checkpoint of an unfinished conversion in the root
+// of a directory output
+const checkpointName = ".labelstudio-to-yolo.checkpoint"
+
+// ErrInterrupted is returned by a conversion stopped by its context, such as
+// on SIGINT or SIGTERM
+var ErrInterrupted = errors.New("conversion interrupted")
+
+// interrupted returns ErrInterrupted once the conversion's context is done;
+// long loops check it before scheduling more work
+func (c *Converter) interrupted() error {
+	if c.ctx != nil && c.ctx.Err() != nil {
+		return ErrInterrupted
+	}
+	return nil
+}
+
+// fingerprint identifies the options that decide the dataset's content, so
+// a checkpoint is only resumed by the same conversion
+func (c *Converter) fingerprint() string {
+	config := c.config
+	config.Resume, config.ForceUnlock = false, false
+	config.Retries, config.RetryBackoff, config.Parallel = 0, 0, 0
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%#v", config)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// checkpointWriter records the files of a directory output as they are
+// completed. A failed or interrupted conversion leaves them in a checkpoint,
+// which a run with -resume reads to skip them; a finished conversion removes
+// it.
+type checkpointWriter struct {
+	DatasetWriter
+	path        string
+	fingerprint string
+
+	mu      sync.Mutex
+	done    map[string]bool
+	resumed map[string]bool
+}
+
+// newCheckpointWriter wraps a directory output, reading the checkpoint of an
+// earlier run when resume is set
+func (c *Converter) newCheckpointWriter(writer DatasetWriter, resume bool) (*checkpointWriter, error) {
+	w := &checkpointWriter{
+		DatasetWriter: writer,
+		path:          filepath.Join(c.config.OutputDir, checkpointName),
+		fingerprint:   c.fingerprint(),
+		done:          make(map[string]bool),
+		resumed:       make(map[string]bool),
+	}
+	if !resume {
+		return w, nil
+	}
+
+	data, err := os.ReadFile(w.path)
+	if os.IsNotExist(err) {
+		fmt.Fprintf(c.out, "No checkpoint in %s, converting from the start\n", c.config.OutputDir)
+		return w, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() || strings.TrimPrefix(scanner.Text(), "# ") != w.fingerprint {
+		return nil, fmt.Errorf("the checkpoint in %s was written by a conversion with different options; convert without -resume", c.config.OutputDir)
+	}
+	for scanner.Scan() {
+		if name := scanner.Text(); name != "" {
+			w.resumed[name] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	fmt.Fprintf(c.out, "Resuming: %d files were completed by the interrupted conversion\n", len(w.resumed))
+	return w, nil
+}
+
+// complete records a finished file
+func (w *checkpointWriter) complete(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done[name] = true
+}
+
+func (w *checkpointWriter) isResumed(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.resumed[name]
+}
+
+// CopyFile copies srcPath to name unless the resumed conversion completed it
+func (w *checkpointWriter) CopyFile(name, srcPath string) error {
+	if !w.isResumed(name) {
+		if err := w.DatasetWriter.CopyFile(name, srcPath); err != nil {
+			return err
+		}
+	}
+	w.complete(name)
+	return nil
+}
+
+// WriteFile writes name unless the resumed conversion completed it
+func (w *checkpointWriter) WriteFile(name string, data []byte) error {
+	if !w.isResumed(name) {
+		if err := w.DatasetWriter.WriteFile(name, data); err != nil {
+			return err
+		}
+	}
+	w.complete(name)
+	return nil
+}
+
+// Close finishes the dataset and removes the checkpoint
+func (w *checkpointWriter) Close() error {
+	if err := w.DatasetWriter.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Abort writes the checkpoint of the completed files
+func (w *checkpointWriter) Abort() error {
+	w.mu.Lock()
+	names := make([]string, 0, len(w.done))
+	for name := range w.done {
+		names = append(names, name)
+	}
+	w.mu.Unlock()
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n", w.fingerprint)
+	for _, name := range names {
+		fmt.Fprintln(&buf, name)
+	}
+	err := os.WriteFile(w.path, buf.Bytes(), 0644)
+	if abortErr := w.DatasetWriter.Abort(); err == nil {
+		err = abortErr
+	}
+	return err
+}
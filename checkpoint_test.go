@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertInterruptedAndResumed(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	outputDir := filepath.Join(tempDir, "yolo_output")
+	config := Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	converter := NewConverter(config)
+	converter.SetOutput(io.Discard)
+	if err := converter.ConvertContext(ctx); !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("Expected ErrInterrupted, got %v", err)
+	}
+	checkpoint := filepath.Join(outputDir, checkpointName)
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Fatalf("Expected a checkpoint after the interruption: %v", err)
+	}
+
+	config.Resume = true
+	converter = NewConverter(config)
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkpoint to be removed, got %v", err)
+	}
+	images, _ := filepath.Glob(filepath.Join(outputDir, "images", "*", "*"))
+	if len(images) != 3 {
+		t.Errorf("Expected 3 images after resuming, got %v", images)
+	}
+}
+
+func TestCheckpointWriterResume(t *testing.T) {
+	outputDir := t.TempDir()
+	converter := NewConverter(Config{OutputDir: outputDir, Seed: 1})
+	converter.SetOutput(io.Discard)
+	src := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(src, []byte("0 0.5 0.5 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writer, err := converter.newCheckpointWriter(&DirWriter{Root: outputDir}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.CopyFile("a.txt", src); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, checkpointName))
+	if err != nil || !strings.HasSuffix(string(data), "\na.txt\n") {
+		t.Fatalf("Expected a.txt in the checkpoint, got %q, %v", data, err)
+	}
+
+	// The completed file is skipped, even though its source is gone
+	os.Remove(src)
+	resumed, err := converter.newCheckpointWriter(&DirWriter{Root: outputDir}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resumed.CopyFile("a.txt", src); err != nil {
+		t.Errorf("Expected the completed file to be skipped: %v", err)
+	}
+
+	// A checkpoint of different options isn't resumed
+	changed := NewConverter(Config{OutputDir: outputDir, Seed: 2})
+	changed.SetOutput(io.Discard)
+	if _, err := changed.newCheckpointWriter(&DirWriter{Root: outputDir}, true); err == nil {
+		t.Error("Expected a checkpoint of other options to be refused")
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// checksumManifest is the name of the checksum manifest in the dataset root
+const checksumManifest = "checksums.sha256"
+
+// checksumWriter records the SHA-256 of every file written through it and
+// adds a manifest in sha256sum format when the dataset is finished
+type checksumWriter struct {
+	DatasetWriter
+
+	mu      sync.Mutex
+	digests map[string]string
+}
+
+func newChecksumWriter(writer DatasetWriter) *checksumWriter {
+	return &checksumWriter{DatasetWriter: writer, digests: make(map[string]string)}
+}
+
+func (w *checksumWriter) record(name string, digest []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.digests[name] = hex.EncodeToString(digest)
+}
+
+// CopyFile copies srcPath to name, hashing the source first since moving
+// writers remove it
+func (w *checksumWriter) CopyFile(name, srcPath string) error {
+	digest, err := fileSHA256(srcPath)
+	if err != nil {
+		return err
+	}
+	if err := w.DatasetWriter.CopyFile(name, srcPath); err != nil {
+		return err
+	}
+	w.record(name, digest)
+	return nil
+}
+
+// WriteFile writes data to name, hashing it
+func (w *checksumWriter) WriteFile(name string, data []byte) error {
+	if err := w.DatasetWriter.WriteFile(name, data); err != nil {
+		return err
+	}
+	digest := sha256.Sum256(data)
+	w.record(name, digest[:])
+	return nil
+}
+
+// Close writes the manifest, sorted by file name, and finishes the dataset
+func (w *checksumWriter) Close() error {
+	w.mu.Lock()
+	names := make([]string, 0, len(w.digests))
+	for name := range w.digests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s  %s\n", w.digests[name], name)
+	}
+	w.mu.Unlock()
+
+	if err := w.DatasetWriter.WriteFile(checksumManifest, buf.Bytes()); err != nil {
+		w.DatasetWriter.Abort()
+		return fmt.Errorf("failed to write %s: %w", checksumManifest, err)
+	}
+	return w.DatasetWriter.Close()
+}
+
+// fileSHA256 hashes a file
+func fileSHA256(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readerSHA256(file)
+}
+
+// readerSHA256 hashes everything a reader returns
+func readerSHA256(r io.Reader) ([]byte, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// parseChecksums reads a manifest in sha256sum format into digests keyed by
+// file name
+func parseChecksums(data []byte) (map[string]string, error) {
+	digests := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		digest, name, ok := strings.Cut(text, " ")
+		if !ok || len(digest) != sha256.Size*2 {
+			return nil, fmt.Errorf("%s:%d: expected a SHA-256 digest and a file name", checksumManifest, line)
+		}
+		// sha256sum marks binary mode with an asterisk
+		digests[strings.TrimPrefix(strings.TrimSpace(name), "*")] = strings.ToLower(digest)
+	}
+	return digests, scanner.Err()
+}
+
+// VerifyReport is the result of checking a dataset against its manifest
+type VerifyReport struct {
+	Checked int
+	// Mismatched files have a different digest; Missing files are listed
+	// in the manifest but absent; Unlisted files are not in the manifest
+	Mismatched []string
+	Missing    []string
+	Unlisted   []string
+}
+
+// OK reports whether every listed file is present and intact
+func (r *VerifyReport) OK() bool {
+	return len(r.Mismatched) == 0 && len(r.Missing) == 0
+}
+
+// VerifyDataset checks the files of a dataset directory or archive against
+// its checksums.sha256
+func VerifyDataset(datasetPath string) (*VerifyReport, error) {
+	actual := make(map[string]string)
+	var manifest []byte
+	visit := func(name string, r io.Reader) error {
+		if name == checksumManifest {
+			data, err := io.ReadAll(r)
+			manifest = data
+			return err
+		}
+		digest, err := readerSHA256(r)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		actual[name] = hex.EncodeToString(digest)
+		return nil
+	}
+
+	var err error
+	switch archiveFormat(datasetPath) {
+	case "tar.gz":
+		err = walkTarGz(datasetPath, visit)
+	case "zip":
+		err = walkZip(datasetPath, visit)
+	default:
+		err = walkDir(datasetPath, visit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("no %s in %s (convert with -checksums)", checksumManifest, datasetPath)
+	}
+	expected, err := parseChecksums(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{}
+	for name, digest := range expected {
+		got, ok := actual[name]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, name)
+		case got != digest:
+			report.Mismatched = append(report.Mismatched, name)
+		}
+		report.Checked++
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			report.Unlisted = append(report.Unlisted, name)
+		}
+	}
+	for _, names := range [][]string{report.Mismatched, report.Missing, report.Unlisted} {
+		sort.Strings(names)
+	}
+	return report, nil
+}
+
+// walkDir visits the regular files below a directory by slash-separated
+// relative name
+func walkDir(root string, visit func(name string, r io.Reader) error) error {
+	return filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return visit(filepath.ToSlash(rel), file)
+	})
+}
+
+// walkTarGz visits the regular files of a gzip-compressed tar archive
+func walkTarGz(archivePath string, visit func(name string, r io.Reader) error) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", archivePath, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", archivePath, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := visit(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// walkZip visits the regular files of a zip archive
+func walkZip(archivePath string, visit func(name string, r io.Reader) error) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", archivePath, err)
+	}
+	defer zr.Close()
+	for _, entry := range zr.File {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		r, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", entry.Name, err)
+		}
+		err = visit(entry.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runVerify is the entry point of the verify subcommand
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	dataset := fs.String("dataset", "./yolo_dataset", "Dataset directory or archive (.tar.gz, .tgz, .zip) with a checksums.sha256")
+	fs.Parse(args)
+
+	report, err := VerifyDataset(*dataset)
+	if err != nil {
+		return err
+	}
+	for _, name := range report.Mismatched {
+		fmt.Printf("MISMATCH %s\n", name)
+	}
+	for _, name := range report.Missing {
+		fmt.Printf("MISSING  %s\n", name)
+	}
+	for _, name := range report.Unlisted {
+		fmt.Printf("Warning: %s is not listed in %s\n", name, checksumManifest)
+	}
+	if !report.OK() {
+		return fmt.Errorf("%d of %d files failed verification", len(report.Mismatched)+len(report.Missing), report.Checked)
+	}
+	fmt.Printf("Verified %d files\n", report.Checked)
+	return nil
+}
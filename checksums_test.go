@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChecksumsVerifyDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	outputDir := filepath.Join(tempDir, "yolo_output")
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42, Checksums: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(outputDir, checksumManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(manifest), "  data.yaml\n") || !strings.Contains(string(manifest), "  labels/train/") {
+		t.Errorf("Expected data.yaml and the labels in the manifest:\n%s", manifest)
+	}
+	report, err := VerifyDataset(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Checked == 0 || len(report.Unlisted) != 0 {
+		t.Fatalf("Expected a fresh dataset to verify, got %+v", report)
+	}
+
+	// Corrupt a label, lose an image and add a file
+	labels, _ := filepath.Glob(filepath.Join(outputDir, "labels", "train", "*.txt"))
+	images, _ := filepath.Glob(filepath.Join(outputDir, "images", "val", "*"))
+	if len(labels) == 0 || len(images) == 0 {
+		t.Fatal("Expected train labels and val images")
+	}
+	if err := os.WriteFile(labels[0], []byte("0 0.5 0.5 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(images[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "notes.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = VerifyDataset(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() {
+		t.Error("Expected verification to fail")
+	}
+	rel := func(path string) string {
+		name, _ := filepath.Rel(outputDir, path)
+		return filepath.ToSlash(name)
+	}
+	if !reflect.DeepEqual(report.Mismatched, []string{rel(labels[0])}) || !reflect.DeepEqual(report.Missing, []string{rel(images[0])}) ||
+		!reflect.DeepEqual(report.Unlisted, []string{"notes.txt"}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestChecksumsVerifyArchives(t *testing.T) {
+	for _, name := range []string{"dataset.tar.gz", "dataset.zip"} {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			createTestFiles(t, tempDir)
+			archivePath := filepath.Join(tempDir, name)
+
+			converter := NewConverter(Config{SourceDir: tempDir, OutputDir: archivePath, TrainSplit: 0.8, Seed: 42, Checksums: true})
+			converter.SetOutput(io.Discard)
+			if err := converter.Convert(); err != nil {
+				t.Fatal(err)
+			}
+			report, err := VerifyDataset(archivePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.OK() || report.Checked == 0 || len(report.Unlisted) != 0 {
+				t.Errorf("Expected the archive to verify, got %+v", report)
+			}
+		})
+	}
+}
+
+func TestVerifyWithoutManifest(t *testing.T) {
+	if _, err := VerifyDataset(t.TempDir()); err == nil {
+		t.Error("Expected an error for a dataset without checksums")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// readClassesFile reads the class names of a classes.txt: one name per line,
+// in class ID order. A UTF-8 byte order mark, Windows line endings, blank
+// lines and lines starting with # are ignored. When every line starts with
+// an index, as in "3 person", the indexes give the class IDs; they must run
+// from 0 without gaps but may appear in any order.
+func readClassesFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	names, err := parseClassNames(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return names, nil
+}
+
+// parseClassNames parses the contents of a classes.txt for readClassesFile
+func parseClassNames(r io.Reader) ([]string, error) {
+	type entry struct {
+		line  int
+		raw   string
+		index int
+		name  string
+	}
+	var entries []entry
+	indexed := true
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e := entry{line: n, raw: line, index: -1}
+		if i := strings.IndexFunc(line, unicode.IsSpace); i > 0 {
+			if index, err := strconv.Atoi(line[:i]); err == nil && index >= 0 {
+				e.index, e.name = index, strings.TrimSpace(line[i:])
+			}
+		}
+		if e.index < 0 {
+			indexed = false
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading classes: %w", err)
+	}
+
+	names := make([]string, len(entries))
+	if !indexed {
+		// Plain names, which may themselves start with a number
+		for i, e := range entries {
+			names[i] = e.raw
+		}
+		return names, nil
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].index < entries[j].index })
+	for i, e := range entries {
+		if e.index != i {
+			if i > 0 && e.index == entries[i-1].index {
+				return nil, fmt.Errorf("line %d: class index %d is used twice", e.line, e.index)
+			}
+			return nil, fmt.Errorf("class indexes must run from 0 without gaps, but %d is missing", i)
+		}
+		names[i] = e.name
+	}
+	return names, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseClassNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		err     string
+	}{
+		{name: "plain", content: "book\nperson\n", want: "book|person"},
+		{name: "bom and crlf", content: "\ufeffbook\r\nperson\r\n", want: "book|person"},
+		{name: "comments and blanks", content: "# exported 2025-01-01\n\nbook\n  # old: chair\nperson\n\n", want: "book|person"},
+		{name: "indexed", content: "1 person\n0 book\n2\ttraffic light\n", want: "book|person|traffic light"},
+		{name: "numbers in names", content: "3d printer\nperson\n", want: "3d printer|person"},
+		{name: "indexed with gap", content: "0 book\n2 person\n", err: "1 is missing"},
+		{name: "indexed twice", content: "0 book\n1 person\n1 car\n", err: "index 1 is used twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := parseClassNames(strings.NewReader(tt.content))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Expected an error containing %q, got %v, %v", tt.err, names, err)
+				}
+				return
+			}
+			if err != nil || strings.Join(names, "|") != tt.want {
+				t.Errorf("Expected %s, got %q, %v", tt.want, names, err)
+			}
+		})
+	}
+}
+
+func TestLoadClassesFromWindows(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	if err := os.WriteFile(filepath.Join(sourceDir, "classes.txt"), []byte("\ufeffbook\r\nperson\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	converter := NewConverter(Config{SourceDir: sourceDir})
+	converter.SetOutput(&strings.Builder{})
+	classes, err := converter.LoadClasses()
+	if err != nil || len(classes) != 2 || classes[0] != "book" {
+		t.Errorf("Expected book and person without a phantom class, got %q, %v", classes, err)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ClassifiedImage is an image with the choice labels it was annotated with
+type ClassifiedImage struct {
+	ImagePath string
+	Labels    []string
+}
+
+// choiceLabels returns the choices selected in an annotation's results, in
+// the order they appear
+func choiceLabels(results []LSResult) []string {
+	var labels []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result.Type != "choices" {
+			continue
+		}
+		for _, choice := range result.Value.Choices {
+			if !seen[choice] {
+				seen[choice] = true
+				labels = append(labels, choice)
+			}
+		}
+	}
+	return labels
+}
+
+// loadClassifiedImages reads the choice labels of every task in the Label
+// Studio JSON export, skipping tasks without an image or any choice
+func (c *Converter) loadClassifiedImages() ([]ClassifiedImage, error) {
+	if c.config.InputFormat != InputLSJSON {
+		return nil, fmt.Errorf("classification output requires -input-format %s", InputLSJSON)
+	}
+
+	tasks, err := c.loadTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	var images []ClassifiedImage
+	unlabeled := 0
+	for _, task := range tasks {
+		results, ok := c.taskResults(task)
+		if !ok {
+			continue
+		}
+		labels := choiceLabels(results)
+		if len(labels) == 0 {
+			unlabeled++
+			continue
+		}
+
+		imagePath, ok := c.resolveTaskImage(task)
+		if !ok {
+			continue
+		}
+		images = append(images, ClassifiedImage{ImagePath: imagePath, Labels: labels})
+	}
+
+	c.filter.report(c)
+	c.consensusStats.report(c)
+	c.predictionStats.report(c)
+	if unlabeled > 0 {
+		fmt.Fprintf(c.out, "Warning: Skipped %d tasks without a choice label\n", unlabeled)
+	}
+	fmt.Fprintf(c.out, "Found %d classified images\n", len(images))
+	return images, nil
+}
+
+// SplitStratified splits images into train and validation sets class by
+// class, so that the train split ratio holds for every class
+func (c *Converter) SplitStratified(images []ClassifiedImage) ([]ClassifiedImage, []ClassifiedImage) {
+	groups := make(map[string][]ClassifiedImage)
+	for _, image := range images {
+		groups[image.Labels[0]] = append(groups[image.Labels[0]], image)
+	}
+
+	classes := make([]string, 0, len(groups))
+	for class := range groups {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	rng := rand.New(rand.NewSource(c.config.Seed))
+
+	var train, val []ClassifiedImage
+	for _, class := range classes {
+		group := groups[class]
+		rng.Shuffle(len(group), func(i, j int) {
+			group[i], group[j] = group[j], group[i]
+		})
+		trainCount := int(float64(len(group)) * c.config.TrainSplit)
+		train = append(train, group[:trainCount]...)
+		val = append(val, group[trainCount:]...)
+	}
+
+	fmt.Fprintf(c.out, "Dataset split: %d training, %d validation\n", len(train), len(val))
+	return train, val
+}
+
+// classDirName turns a class name into a safe directory name
+func classDirName(class string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(class))
+	if name == "" || name == "." || name == ".." {
+		name = "_" + name
+	}
+	return name
+}
+
+// convertClassification builds the Ultralytics classification layout,
+// train/<class>/<image> and val/<class>/<image>, from choice labels
+func (c *Converter) convertClassification() (err error) {
+	if c.config.Incremental {
+		return fmt.Errorf("incremental conversion is not supported for classification output")
+	}
+	if c.config.OBB || c.config.SkeletonFile != "" {
+		return fmt.Errorf("-classify cannot be combined with -obb or -skeleton")
+	}
+
+	images, err := c.loadClassifiedImages()
+	if err != nil {
+		return err
+	}
+	if c.config.MultiLabel {
+		return c.convertMultiLabel(images)
+	}
+
+	for i, image := range images {
+		if len(image.Labels) > 1 {
+			fmt.Fprintf(c.out, "Warning: %s has %d choices, using %q\n", filepath.Base(image.ImagePath), len(image.Labels), image.Labels[0])
+			images[i].Labels = image.Labels[:1]
+		}
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("%w: no classified images found", ErrNoPairs)
+	}
+
+	train, val := c.SplitStratified(images)
+
+	files := make(map[string]string)
+	for split, images := range map[string][]ClassifiedImage{"train": train, "val": val} {
+		for _, image := range images {
+			files[path.Join(split, classDirName(image.Labels[0]), filepath.Base(image.ImagePath))] = image.ImagePath
+		}
+	}
+	if err := c.checkFreeSpace(files); err != nil {
+		return err
+	}
+
+	writer, err := c.openOutput()
+	if err != nil {
+		return err
+	}
+	defer finishOutput(writer, &err)
+	defer c.reportFileErrors()
+
+	classes := make(map[string]bool)
+	for _, split := range []struct {
+		name   string
+		images []ClassifiedImage
+	}{{"train", train}, {"val", val}} {
+		written := make(map[string]bool)
+		for _, image := range split.images {
+			if err := c.interrupted(); err != nil {
+				return err
+			}
+			classes[image.Labels[0]] = true
+
+			dir := path.Join(split.name, classDirName(image.Labels[0]))
+			name := path.Join(dir, filepath.Base(image.ImagePath))
+			if written[name] {
+				fmt.Fprintf(c.out, "Warning: Skipping duplicate image %s\n", name)
+				c.skipped++
+				continue
+			}
+			written[name] = true
+
+			if err := writer.MkdirAll(dir); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", dir, err)
+			}
+			if err := writer.CopyFile(name, image.ImagePath); err != nil {
+				return &CopyError{Kind: "image", Path: image.ImagePath, Err: err}
+			}
+		}
+		fmt.Fprintf(c.out, "Copied %d %s files\n", len(split.images), split.name)
+	}
+
+	c.counts = ProvenanceCounts{TrainImages: len(train), ValImages: len(val), Classes: len(classes)}
+	fmt.Fprintln(c.out, "\nConversion completed successfully!")
+	fmt.Fprintf(c.out, "Classification dataset ready at: %s\n", c.config.OutputDir)
+	fmt.Fprintf(c.out, "Classes: %d\n", len(classes))
+	fmt.Fprintf(c.out, "Training images: %d\n", len(train))
+	fmt.Fprintf(c.out, "Validation images: %d\n", len(val))
+	return nil
+}
+
+// multiLabelOrder returns the label columns: the -label-order list or
+// classes.txt when given, with any other labels found appended in sorted order
+func (c *Converter) multiLabelOrder(images []ClassifiedImage) ([]string, error) {
+	var order []string
+	switch {
+	case c.config.LabelOrder != "":
+		for _, label := range strings.Split(c.config.LabelOrder, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				order = append(order, label)
+			}
+		}
+	default:
+		if _, err := os.Stat(filepath.Join(c.config.SourceDir, "classes.txt")); err == nil {
+			classes, err := c.LoadClasses()
+			if err != nil {
+				return nil, err
+			}
+			order = classes
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, label := range order {
+		if known[label] {
+			return nil, fmt.Errorf("label %q is listed twice", label)
+		}
+		known[label] = true
+	}
+
+	var extra []string
+	for _, image := range images {
+		for _, label := range image.Labels {
+			if !known[label] {
+				known[label] = true
+				extra = append(extra, label)
+			}
+		}
+	}
+	sort.Strings(extra)
+	if len(order) > 0 && len(extra) > 0 {
+		fmt.Fprintf(c.out, "Warning: Labels missing from the label order were appended: %v\n", extra)
+	}
+	return append(order, extra...), nil
+}
+
+// convertMultiLabel writes images/train, images/val and a train.csv and
+// val.csv manifest with one 0/1 column per label, plus classes.txt
+func (c *Converter) convertMultiLabel(images []ClassifiedImage) (err error) {
+	if len(images) == 0 {
+		return fmt.Errorf("%w: no classified images found", ErrNoPairs)
+	}
+
+	labels, err := c.multiLabelOrder(images)
+	if err != nil {
+		return err
+	}
+	column := make(map[string]int, len(labels))
+	for i, label := range labels {
+		column[label] = i
+	}
+
+	shuffled := append([]ClassifiedImage{}, images...)
+	rng := rand.New(rand.NewSource(c.config.Seed))
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	trainCount := int(float64(len(shuffled)) * c.config.TrainSplit)
+	train, val := shuffled[:trainCount], shuffled[trainCount:]
+	fmt.Fprintf(c.out, "Dataset split: %d training, %d validation\n", len(train), len(val))
+
+	files := make(map[string]string)
+	for split, images := range map[string][]ClassifiedImage{"train": train, "val": val} {
+		for _, image := range images {
+			files[path.Join("images", split, filepath.Base(image.ImagePath))] = image.ImagePath
+		}
+	}
+	if err := c.checkFreeSpace(files); err != nil {
+		return err
+	}
+
+	writer, err := c.openOutput()
+	if err != nil {
+		return err
+	}
+	defer finishOutput(writer, &err)
+	defer c.reportFileErrors()
+
+	for _, split := range []struct {
+		name   string
+		images []ClassifiedImage
+	}{{"train", train}, {"val", val}} {
+		dir := path.Join("images", split.name)
+		if err := writer.MkdirAll(dir); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+
+		var buf bytes.Buffer
+		manifest := csv.NewWriter(&buf)
+		manifest.Write(append([]string{"image"}, labels...))
+
+		written := make(map[string]bool)
+		for _, image := range split.images {
+			if err := c.interrupted(); err != nil {
+				return err
+			}
+			name := path.Join(dir, filepath.Base(image.ImagePath))
+			if written[name] {
+				fmt.Fprintf(c.out, "Warning: Skipping duplicate image %s\n", name)
+				c.skipped++
+				continue
+			}
+			written[name] = true
+
+			if err := writer.CopyFile(name, image.ImagePath); err != nil {
+				return &CopyError{Kind: "image", Path: image.ImagePath, Err: err}
+			}
+
+			row := make([]string, len(labels)+1)
+			row[0] = name
+			for i := range labels {
+				row[i+1] = "0"
+			}
+			for _, label := range image.Labels {
+				row[column[label]+1] = "1"
+			}
+			manifest.Write(row)
+		}
+
+		manifest.Flush()
+		if err := manifest.Error(); err != nil {
+			return fmt.Errorf("failed to write %s manifest: %w", split.name, err)
+		}
+		if err := writer.WriteFile(split.name+".csv", buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to write %s manifest: %w", split.name, err)
+		}
+		fmt.Fprintf(c.out, "Copied %d %s files\n", len(split.images), split.name)
+	}
+
+	if err := writer.WriteFile("classes.txt", []byte(strings.Join(labels, "\n")+"\n")); err != nil {
+		return fmt.Errorf("failed to write classes.txt: %w", err)
+	}
+
+	c.counts = ProvenanceCounts{TrainImages: len(train), ValImages: len(val), Classes: len(labels)}
+	fmt.Fprintln(c.out, "\nConversion completed successfully!")
+	fmt.Fprintf(c.out, "Multi-label dataset ready at: %s\n", c.config.OutputDir)
+	fmt.Fprintf(c.out, "Labels: %d %v\n", len(labels), labels)
+	fmt.Fprintf(c.out, "Training images: %d\n", len(train))
+	fmt.Fprintf(c.out, "Validation images: %d\n", len(val))
+	return nil
+}
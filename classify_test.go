@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createChoicesExport writes a Label Studio JSON export where each task's
+// image is classified with the given choices
+func createChoicesExport(t *testing.T, baseDir string, choices map[string][]string) {
+	if err := os.MkdirAll(filepath.Join(baseDir, "images"), 0755); err != nil {
+		t.Fatalf("Failed to create images directory: %v", err)
+	}
+
+	var tasks []LSTask
+	id := 0
+	for image, labels := range choices {
+		if err := os.WriteFile(filepath.Join(baseDir, "images", image), []byte("fake image data"), 0644); err != nil {
+			t.Fatalf("Failed to create image: %v", err)
+		}
+		id++
+		task := LSTask{ID: id, Data: map[string]interface{}{"image": "/data/upload/1/" + image}}
+		if labels != nil {
+			task.Annotations = []LSAnnotation{{Result: []LSResult{{Type: "choices", Value: LSValue{Choices: labels}}}}}
+		}
+		tasks = append(tasks, task)
+	}
+
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		t.Fatalf("Failed to encode tasks: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "export.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+}
+
+func TestClassificationConversion(t *testing.T) {
+	choices := map[string][]string{"skip.jpg": nil, "both.jpg": {"cat", "dog"}}
+	for i := 0; i < 4; i++ {
+		choices[fmt.Sprintf("cat%d.jpg", i)] = []string{"cat"}
+		choices[fmt.Sprintf("dog%d.jpg", i)] = []string{"dog"}
+	}
+
+	sourceDir := t.TempDir()
+	createChoicesExport(t, sourceDir, choices)
+	outputDir := filepath.Join(t.TempDir(), "classify")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.5, Seed: 42, InputFormat: InputLSJSON, Classify: true})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	// The split is stratified, so each class is halved
+	counts := make(map[string]int)
+	for _, split := range []string{"train", "val"} {
+		for _, class := range []string{"cat", "dog"} {
+			entries, _ := os.ReadDir(filepath.Join(outputDir, split, class))
+			counts[split+"/"+class] = len(entries)
+		}
+	}
+	expected := map[string]int{"train/cat": 2, "val/cat": 3, "train/dog": 2, "val/dog": 2}
+	for key, want := range expected {
+		if counts[key] != want {
+			t.Errorf("Expected %d images in %s, got %d", want, key, counts[key])
+		}
+	}
+
+	log := out.String()
+	for _, want := range []string{"Skipped 1 tasks without a choice label", `both.jpg has 2 choices, using "cat"`} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, log)
+		}
+	}
+}
+
+func TestClassificationRequiresLabelStudioJSON(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: t.TempDir(), TrainSplit: 0.8, Classify: true})
+	converter.SetOutput(&strings.Builder{})
+	if err := converter.Convert(); err == nil {
+		t.Error("Expected error for classification from a YOLO export")
+	}
+}
+
+func TestClassDirName(t *testing.T) {
+	tests := map[string]string{
+		"cat":       "cat",
+		" big dog ": "big dog",
+		"cats/dogs": "cats_dogs",
+		"..":        "_..",
+		"":          "_",
+		"a\\b":      "a_b",
+	}
+	for class, want := range tests {
+		if got := classDirName(class); got != want {
+			t.Errorf("classDirName(%q) = %q, expected %q", class, got, want)
+		}
+	}
+}
+
+func TestMultiLabelConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	createChoicesExport(t, sourceDir, map[string][]string{
+		"a.jpg": {"outdoor", "sunny"},
+		"b.jpg": {"indoor"},
+		"c.jpg": {"outdoor", "night"},
+		"d.jpg": {"sunny"},
+	})
+	outputDir := filepath.Join(t.TempDir(), "multilabel.zip")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputLSJSON, MultiLabel: true, LabelOrder: "sunny, outdoor"})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	entries := readZip(t, outputDir)
+	if entries["classes.txt"] != "sunny\noutdoor\nindoor\nnight\n" {
+		t.Errorf("Expected label order followed by sorted extras, got %q", entries["classes.txt"])
+	}
+
+	rows := strings.Split(strings.TrimSpace(entries["train.csv"]), "\n")
+	if len(rows) != 5 || rows[0] != "image,sunny,outdoor,indoor,night" {
+		t.Fatalf("Unexpected train.csv:\n%s", entries["train.csv"])
+	}
+	for _, want := range []string{"images/train/a.jpg,1,1,0,0", "images/train/c.jpg,0,1,0,1"} {
+		if !strings.Contains(entries["train.csv"], want) {
+			t.Errorf("Expected train.csv to contain %q, got:\n%s", want, entries["train.csv"])
+		}
+	}
+	if _, ok := entries["images/train/d.jpg"]; !ok {
+		t.Error("Expected images to be copied into images/train")
+	}
+	if strings.TrimSpace(entries["val.csv"]) != "image,sunny,outdoor,indoor,night" {
+		t.Errorf("Expected empty val.csv with a header, got %q", entries["val.csv"])
+	}
+	if !strings.Contains(out.String(), "appended: [indoor night]") {
+		t.Errorf("Expected warning about labels missing from the order, got:\n%s", out.String())
+	}
+}
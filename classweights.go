@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Class weight formulas for -class-weights
+const (
+	// WeightsInverse weights classes by 1/n
+	WeightsInverse = "inverse"
+	// WeightsInverseSqrt weights classes by 1/sqrt(n), a milder correction
+	WeightsInverseSqrt = "inverse-sqrt"
+	// WeightsMedian is median frequency balancing: median(n)/n
+	WeightsMedian = "median"
+	// WeightsEffective weights classes by the inverse effective number of
+	// samples, (1-beta)/(1-beta^n)
+	WeightsEffective = "effective"
+)
+
+// defaultEffectiveBeta is the beta of the effective formula without one
+const defaultEffectiveBeta = 0.999
+
+// classWeightsName is the file the weights are written to, without the
+// json or yaml extension
+const classWeightsName = "class_weights"
+
+// ClassWeights are the per-class loss weights of a dataset, listed by class
+// ID so a training script can pass Weights straight to its loss
+type ClassWeights struct {
+	Formula string    `json:"formula" yaml:"formula"`
+	Split   string    `json:"split" yaml:"split"`
+	Classes []string  `json:"classes" yaml:"classes"`
+	Counts  []int     `json:"counts" yaml:"counts"`
+	Weights []float64 `json:"weights" yaml:"weights"`
+}
+
+// classWeightFormula is a parsed -class-weights value
+type classWeightFormula struct {
+	name string
+	beta float64
+}
+
+// parseClassWeightFormula parses a formula name, with an optional beta for
+// the effective formula: effective:0.99
+func parseClassWeightFormula(spec string) (classWeightFormula, error) {
+	name, param, hasParam := strings.Cut(spec, ":")
+	formula := classWeightFormula{name: name}
+	switch name {
+	case WeightsInverse, WeightsInverseSqrt, WeightsMedian:
+		if hasParam {
+			return formula, fmt.Errorf("-class-weights %s takes no parameter", name)
+		}
+	case WeightsEffective:
+		formula.beta = defaultEffectiveBeta
+		if hasParam {
+			beta, err := strconv.ParseFloat(param, 64)
+			if err != nil || beta <= 0 || beta >= 1 {
+				return formula, fmt.Errorf("-class-weights effective: beta must be between 0 and 1, got %q", param)
+			}
+			formula.beta = beta
+		}
+	default:
+		return formula, fmt.Errorf("invalid -class-weights %q: must be %s, %s, %s or %s[:beta]", spec, WeightsInverse, WeightsInverseSqrt, WeightsMedian, WeightsEffective)
+	}
+	return formula, nil
+}
+
+// String formats the formula as it is given on the command line
+func (f classWeightFormula) String() string {
+	if f.name == WeightsEffective {
+		return f.name + ":" + strconv.FormatFloat(f.beta, 'g', -1, 64)
+	}
+	return f.name
+}
+
+// weight returns the unnormalized weight of a class with n annotations.
+// median is the median count of the classes with annotations.
+func (f classWeightFormula) weight(n int, median float64) float64 {
+	switch f.name {
+	case WeightsInverseSqrt:
+		return 1 / math.Sqrt(float64(n))
+	case WeightsMedian:
+		return median / float64(n)
+	case WeightsEffective:
+		return (1 - f.beta) / (1 - math.Pow(f.beta, float64(n)))
+	default:
+		return 1 / float64(n)
+	}
+}
+
+// computeClassWeights weights the classes by their annotation counts. The
+// weights are scaled so they average 1 over the classes with annotations,
+// which keeps the loss at its usual magnitude; classes without annotations
+// get 0.
+func computeClassWeights(formula classWeightFormula, classes []string, counts map[string]int) []float64 {
+	var present []int
+	for _, name := range classes {
+		if n := counts[name]; n > 0 {
+			present = append(present, n)
+		}
+	}
+	weights := make([]float64, len(classes))
+	if len(present) == 0 {
+		return weights
+	}
+
+	sort.Ints(present)
+	median := float64(present[len(present)/2])
+	if len(present)%2 == 0 {
+		median = float64(present[len(present)/2-1]+present[len(present)/2]) / 2
+	}
+
+	sum := 0.0
+	for i, name := range classes {
+		if n := counts[name]; n > 0 {
+			weights[i] = formula.weight(n, median)
+			sum += weights[i]
+		}
+	}
+	scale := float64(len(present)) / sum
+	for i := range weights {
+		weights[i] = math.Round(weights[i]*scale*1e6) / 1e6
+	}
+	return weights
+}
+
+// validateClassWeights checks -class-weights and -class-weights-format
+// before the conversion starts
+func (c *Converter) validateClassWeights() error {
+	if c.config.ClassWeights == "" {
+		return nil
+	}
+	if _, err := parseClassWeightFormula(c.config.ClassWeights); err != nil {
+		return err
+	}
+	if format := c.classWeightsFormat(); format != "json" && format != "yaml" {
+		return fmt.Errorf("invalid -class-weights-format %q: must be json or yaml", format)
+	}
+	if c.config.Classify || c.config.MultiLabel || c.config.Crop {
+		return fmt.Errorf("-class-weights cannot be combined with -classify, -multi-label or -crop")
+	}
+	if c.config.OutputFormat != "" && c.config.OutputFormat != OutputYOLO {
+		return fmt.Errorf("-class-weights requires the yolo output format")
+	}
+	return nil
+}
+
+// classWeightsFormat returns -class-weights-format, json when unset
+func (c *Converter) classWeightsFormat() string {
+	if c.config.ClassWeightsFormat == "" {
+		return "json"
+	}
+	return c.config.ClassWeightsFormat
+}
+
+// writeClassWeights writes the class weights computed from the training
+// split's annotation counts next to data.yaml
+func (c *Converter) writeClassWeights(stats *DatasetStats) error {
+	if c.config.ClassWeights == "" {
+		return nil
+	}
+	formula, err := parseClassWeightFormula(c.config.ClassWeights)
+	if err != nil {
+		return err
+	}
+	train := stats.Splits["train"]
+	weights := ClassWeights{
+		Formula: formula.String(),
+		Split:   "train",
+		Classes: stats.Classes,
+		Counts:  make([]int, len(stats.Classes)),
+		Weights: computeClassWeights(formula, stats.Classes, train.ClassCounts),
+	}
+	for i, name := range stats.Classes {
+		weights.Counts[i] = train.ClassCounts[name]
+	}
+
+	var data []byte
+	format := c.classWeightsFormat()
+	if format == "yaml" {
+		data, err = yaml.Marshal(&weights)
+	} else {
+		data, err = json.MarshalIndent(&weights, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode class weights: %w", err)
+	}
+	name := classWeightsName + "." + format
+	if err := c.output().WriteFile(name, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	var missing []string
+	for i, name := range stats.Classes {
+		if weights.Counts[i] == 0 {
+			missing = append(missing, name)
+		}
+	}
+	fmt.Fprintf(c.out, "Wrote %s class weights to %s\n", formula, name)
+	if len(missing) > 0 {
+		fmt.Fprintf(c.out, "Warning: %d classes have no training annotations and get weight 0: %v\n", len(missing), missing)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestComputeClassWeights(t *testing.T) {
+	classes := []string{"car", "truck", "bus", "bike"}
+	counts := map[string]int{"car": 100, "truck": 25, "bike": 50}
+	tests := []struct {
+		spec string
+		want []float64
+	}{
+		// 1/n scaled to average 1 over the three classes with annotations
+		{"inverse", []float64{0.428571, 1.714286, 0, 0.857143}},
+		{"inverse-sqrt", []float64{0.679623, 1.359246, 0, 0.961132}},
+		// The median count is 50
+		{"median", []float64{0.428571, 1.714286, 0, 0.857143}},
+		{"effective:0.9", []float64{0.973246, 1.048491, 0, 0.978262}},
+	}
+	for _, tt := range tests {
+		formula, err := parseClassWeightFormula(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := computeClassWeights(formula, classes, counts)
+		for i := range tt.want {
+			if math.Abs(got[i]-tt.want[i]) > 1e-5 {
+				t.Errorf("%s: expected %v, got %v", tt.spec, tt.want, got)
+				break
+			}
+		}
+	}
+
+	for _, spec := range []string{"inverse:2", "effective:1", "effective:x", "balanced"} {
+		if _, err := parseClassWeightFormula(spec); err == nil {
+			t.Errorf("Expected an error for -class-weights %s", spec)
+		}
+	}
+}
+
+func TestConvertWritesClassWeights(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputYOLO, ClassWeights: "inverse"})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "class_weights.json"))
+	if err != nil {
+		t.Fatalf("Expected class_weights.json: %v", err)
+	}
+	var weights ClassWeights
+	if err := json.Unmarshal(data, &weights); err != nil {
+		t.Fatal(err)
+	}
+	// 3 books and 2 people: 1/3 and 1/2 scaled to average 1
+	if weights.Formula != "inverse" || weights.Counts[0] != 3 || weights.Counts[1] != 2 || weights.Weights[0] != 0.8 || weights.Weights[1] != 1.2 {
+		t.Errorf("Unexpected class weights: %+v", weights)
+	}
+
+	yamlDir := filepath.Join(t.TempDir(), "out")
+	converter = NewConverter(Config{SourceDir: sourceDir, OutputDir: yamlDir, TrainSplit: 1, Seed: 42, InputFormat: InputYOLO, ClassWeights: "effective", ClassWeightsFormat: "yaml"})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	data, err = os.ReadFile(filepath.Join(yamlDir, "class_weights.yaml"))
+	if err != nil || yaml.Unmarshal(data, &weights) != nil || weights.Formula != "effective:0.999" || len(weights.Weights) != 2 {
+		t.Errorf("Expected class_weights.yaml, got %s (%v)", data, err)
+	}
+
+	converter = NewConverter(Config{SourceDir: sourceDir, OutputDir: yamlDir, InputFormat: InputYOLO, ClassWeights: "inverse", ClassWeightsFormat: "toml"})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-class-weights-format") {
+		t.Errorf("Expected an error for an unknown format, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// clearMLIDPrefix starts the line the registration script prints the new
+// dataset's ID on
+const clearMLIDPrefix = "CLEARML_DATASET_ID="
+
+// clearMLScript registers a dataset with the ClearML SDK. The latest
+// completed version of the dataset becomes the parent of the new one, so
+// only changed files are uploaded. Its arguments are the dataset path, the
+// project, the name, the version (empty for ClearML's own numbering) and
+// comma-separated tags.
+const clearMLScript = `import sys
+
+from clearml import Dataset
+
+path, project, name, version, tags = sys.argv[1:6]
+parents = []
+try:
+    parents = [Dataset.get(dataset_project=project, dataset_name=name, only_completed=True).id]
+except Exception:
+    pass
+dataset = Dataset.create(
+    dataset_project=project,
+    dataset_name=name,
+    dataset_version=version or None,
+    dataset_tags=[tag for tag in tags.split(",") if tag],
+    parent_datasets=parents or None,
+)
+if path.endswith((".tar.gz", ".tgz", ".zip")):
+    dataset.add_files(path=path)
+else:
+    dataset.sync_folder(local_path=path)
+dataset.upload()
+dataset.finalize()
+print("` + clearMLIDPrefix + `" + dataset.id)
+`
+
+// validateClearML checks the ClearML options
+func (c *Converter) validateClearML() error {
+	if c.config.ClearMLProject == "" {
+		return nil
+	}
+	if c.config.Classify || c.config.MultiLabel || c.config.Crop {
+		return fmt.Errorf("-clearml-project cannot be combined with -classify, -multi-label or -crop")
+	}
+	return nil
+}
+
+// registerClearML registers the output as a version of a ClearML Dataset by
+// running the ClearML SDK, which reads its credentials from clearml.conf or
+// the CLEARML_* environment variables, and returns the dataset ID
+func (c *Converter) registerClearML() (string, error) {
+	python := c.config.ClearMLPython
+	if python == "" {
+		python = "python3"
+	}
+	if _, err := exec.LookPath(python); err != nil {
+		return "", fmt.Errorf("%s with the clearml package is required for -clearml-project (pip install clearml): %w", python, err)
+	}
+	name := c.config.ClearMLDataset
+	if name == "" {
+		name = datasetName(c.config.OutputDir)
+	}
+	var version string
+	if c.config.DatasetVersion != "" {
+		version = c.datasetVersion.String()
+	}
+	dataset, err := filepath.Abs(c.config.OutputDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	fmt.Fprintf(c.out, "Registering the dataset with ClearML as %s/%s...\n", c.config.ClearMLProject, name)
+	cmd := exec.CommandContext(ctx, python, "-c", clearMLScript, dataset, c.config.ClearMLProject, name, version, c.config.ClearMLTags)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("ClearML registration failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var id string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, clearMLIDPrefix) {
+			id = strings.TrimPrefix(line, clearMLIDPrefix)
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("ClearML registration printed no dataset ID: %s", strings.TrimSpace(string(output)))
+	}
+	fmt.Fprintf(c.out, "Registered ClearML dataset %s\n", id)
+	return id, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakePython writes a stand-in for the Python interpreter that records its
+// arguments and prints what the registration script prints
+func fakePython(t *testing.T, output string) (python, argsFile string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake interpreter is a shell script")
+	}
+	dir := t.TempDir()
+	python, argsFile = filepath.Join(dir, "python3"), filepath.Join(dir, "args")
+	// The script itself is the first argument after -c
+	script := "#!/bin/sh\nshift 2\nprintf '%s\\n' \"$@\" > " + argsFile + "\nprintf '" + output + "'\n"
+	if err := os.WriteFile(python, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return python, argsFile
+}
+
+func TestConvertRegistersClearMLDataset(t *testing.T) {
+	python, argsFile := fakePython(t, "Uploading...\\n"+clearMLIDPrefix+"f00d\\n")
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "parking")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42,
+		ClearMLProject: "vision", ClearMLTags: "yolo,lot", ClearMLPython: python, DatasetVersion: "1.2.0"})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Registered ClearML dataset f00d") {
+		t.Errorf("Expected the dataset ID in the output, got:\n%s", out.String())
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs, _ := filepath.Abs(outputDir)
+	if want := strings.Join([]string{abs, "vision", "parking", "1.2.0", "yolo,lot"}, "\n") + "\n"; string(args) != want {
+		t.Errorf("Expected the script arguments %q, got %q", want, args)
+	}
+}
+
+func TestClearMLRegistrationWithoutID(t *testing.T) {
+	python, _ := fakePython(t, "nothing here\\n")
+	converter := NewConverter(Config{OutputDir: t.TempDir(), ClearMLProject: "vision", ClearMLPython: python})
+	converter.SetOutput(&strings.Builder{})
+	if _, err := converter.registerClearML(); err == nil || !strings.Contains(err.Error(), "no dataset ID") {
+		t.Errorf("Expected an error about the missing dataset ID, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// hasConfidence reports whether a label line carries a trailing confidence
+// value. Box, polygon and oriented box lines have an odd number of values,
+// so an even number of six or more means one value too many.
+func hasConfidence(parts []string) bool {
+	return len(parts) >= 6 && len(parts)%2 == 0
+}
+
+// suggestConfidenceColumn points to -confidence-column when lines were
+// rejected for having one value too many
+func (c *Converter) suggestConfidenceColumn() {
+	if c.config.ConfidenceColumn || c.config.MinConf > 0 || c.skeleton != nil {
+		return
+	}
+	count := 0
+	for _, f := range c.findings {
+		if f.Rule == ruleID("Wrong number of values") && hasConfidence(strings.Fields(f.Content)) {
+			count++
+		}
+	}
+	if count > 0 {
+		fmt.Fprintf(c.out, "Hint: %d lines have one value too many; if the last is a confidence score, -confidence-column strips it (and -min-conf filters by it)\n", count)
+	}
+}
+
+// stripConfidence removes the confidence column that model-assisted labeling
+// appends to label lines, dropping lines whose confidence is below -min-conf.
+// Lines without the column are left as they are.
+func (c *Converter) stripConfidence(pairs []LabelPair) ([]LabelPair, error) {
+	stripped, dropped := 0, 0
+	pairs, _, _, err := c.rewriteLabels(pairs, "confidence", func(pair LabelPair, lines []string) ([]string, int) {
+		kept := lines[:0]
+		changed := 0
+		for _, line := range lines {
+			parts := strings.Fields(line)
+			if !hasConfidence(parts) {
+				kept = append(kept, line)
+				continue
+			}
+			confidence, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+			if err != nil {
+				// Validation reports the line
+				kept = append(kept, line)
+				continue
+			}
+			changed++
+			if confidence < c.config.MinConf {
+				dropped++
+				continue
+			}
+			stripped++
+			kept = append(kept, strings.Join(parts[:len(parts)-1], " "))
+		}
+		return kept, changed
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stripped > 0 {
+		fmt.Fprintf(c.out, "Stripped the confidence column from %d label lines\n", stripped)
+	}
+	if dropped > 0 {
+		fmt.Fprintf(c.out, "Dropped %d label lines with a confidence below %g (-min-conf)\n", dropped, c.config.MinConf)
+	}
+	return pairs, nil
+}
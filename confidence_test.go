@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfidenceLabels gives image1 and image3 of the test export label
+// lines with a confidence column
+func writeConfidenceLabels(t *testing.T, sourceDir string) {
+	files := map[string]string{
+		"image1.txt": "0 0.5 0.5 0.3 0.3 0.92\n1 0.2 0.8 0.1 0.1 0.31\n",
+		"image3.txt": "1 0.1 0.1 0.5 0.1 0.3 0.6 0.75\n0 0.1 0.9 0.1 0.1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, "labels", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConvertStripsConfidence(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		image1  string
+		image3  string
+		message string
+	}{
+		{
+			name:    "strip",
+			config:  Config{ConfidenceColumn: true},
+			image1:  "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n",
+			image3:  "1 0.1 0.1 0.5 0.1 0.3 0.6\n0 0.1 0.9 0.1 0.1\n",
+			message: "Stripped the confidence column from 3 label lines",
+		},
+		{
+			name:    "filter",
+			config:  Config{MinConf: 0.5},
+			image1:  "0 0.5 0.5 0.3 0.3\n",
+			image3:  "1 0.1 0.1 0.5 0.1 0.3 0.6\n0 0.1 0.9 0.1 0.1\n",
+			message: "Dropped 1 label lines with a confidence below 0.5 (-min-conf)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir := t.TempDir()
+			createTestFiles(t, sourceDir)
+			writeConfidenceLabels(t, sourceDir)
+			outputDir := filepath.Join(t.TempDir(), "out")
+
+			config := tt.config
+			config.SourceDir, config.OutputDir, config.TrainSplit, config.Seed = sourceDir, outputDir, 1, 42
+			var out strings.Builder
+			converter := NewConverter(config)
+			converter.SetOutput(&out)
+			if err := converter.Convert(); err != nil {
+				t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+			}
+			for name, want := range map[string]string{"image1.txt": tt.image1, "image3.txt": tt.image3} {
+				got, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", name))
+				if err != nil || string(got) != want {
+					t.Errorf("Label %s: expected %q, got %q (%v)", name, want, got, err)
+				}
+			}
+			if !strings.Contains(out.String(), tt.message) || !strings.Contains(out.String(), "InvalidLines:0") {
+				t.Errorf("Expected %q and no invalid lines, got:\n%s", tt.message, out.String())
+			}
+		})
+	}
+}
+
+func TestConfidenceColumnHint(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	writeConfidenceLabels(t, sourceDir)
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: filepath.Join(t.TempDir(), "out"), TrainSplit: 1, Seed: 42})
+	converter.SetOutput(&out)
+	converter.Convert()
+	if !strings.Contains(out.String(), "Hint: 3 lines have one value too many") {
+		t.Errorf("Expected a hint about -confidence-column, got:\n%s", out.String())
+	}
+}
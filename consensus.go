@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Consensus strategies for tasks with several annotations, selected with
+// -consensus
+const (
+	ConsensusFirst    = "first"
+	ConsensusLatest   = "latest"
+	ConsensusMajority = "majority"
+	ConsensusUnion    = "union"
+)
+
+// consensusStats counts how often annotators disagreed
+type consensusStats struct {
+	multiTasks      int
+	disputedTasks   int
+	regions         int
+	disputedRegions int
+}
+
+// report prints the disagreement rates, if any task had several annotations
+func (s *consensusStats) report(c *Converter) {
+	if s.multiTasks == 0 {
+		return
+	}
+	fmt.Fprintf(c.out, "Consensus (%s): %d tasks with multiple annotations, %d (%.1f%%) with disagreements, %d of %d regions (%.1f%%) not agreed by every annotator\n",
+		c.consensusStrategy(), s.multiTasks, s.disputedTasks, percent(s.disputedTasks, s.multiTasks),
+		s.disputedRegions, s.regions, percent(s.disputedRegions, s.regions))
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// consensusStrategy returns the configured strategy, defaulting to first
+func (c *Converter) consensusStrategy() string {
+	if c.config.Consensus == "" {
+		return ConsensusFirst
+	}
+	return c.config.Consensus
+}
+
+// validateConsensus checks the -consensus and -consensus-iou settings
+func (c *Converter) validateConsensus() error {
+	switch c.consensusStrategy() {
+	case ConsensusFirst, ConsensusLatest, ConsensusMajority, ConsensusUnion:
+	default:
+		return fmt.Errorf("unsupported consensus strategy: %s (expected first, latest, majority or union)", c.config.Consensus)
+	}
+	if c.config.ConsensusIoU < 0 || c.config.ConsensusIoU > 1 {
+		return fmt.Errorf("consensus IoU must be between 0 and 1")
+	}
+	return nil
+}
+
+// taskResults returns the results to convert for a task, merging the
+// annotations that pass the filters with the configured consensus strategy.
+// Tasks without annotations fall back to their predictions with -predictions.
+// It returns false for tasks that should be skipped altogether.
+func (c *Converter) taskResults(task LSTask) ([]LSResult, bool) {
+	annotations := task.Annotations
+	if c.filter != nil {
+		if c.filter.dropsTask(task) {
+			c.filter.tasks++
+			return nil, false
+		}
+		annotations = c.filter.apply(annotations)
+	}
+	if len(annotations) == 0 {
+		if c.config.UsePredictions {
+			return c.predictionResults(task)
+		}
+		return nil, true
+	}
+
+	var results []LSResult
+	switch strategy := c.consensusStrategy(); strategy {
+	case ConsensusMajority, ConsensusUnion:
+		minVotes := 1
+		if strategy == ConsensusMajority {
+			minVotes = len(annotations)/2 + 1
+		}
+		return mergeAnnotations(annotations, c.config.ConsensusIoU, minVotes, c.consensusStats), true
+	case ConsensusLatest:
+		results = latestAnnotation(annotations).Result
+	default:
+		results = annotations[0].Result
+	}
+
+	// Strategies picking one annotation still measure disagreement
+	if len(annotations) > 1 {
+		mergeAnnotations(annotations, c.config.ConsensusIoU, 1, c.consensusStats)
+	}
+	return results, true
+}
+
+// annotationTime returns when an annotation was last updated, falling back
+// to its creation, and the zero time when the export has neither
+func annotationTime(a LSAnnotation) time.Time {
+	for _, s := range []string{a.UpdatedAt, a.CreatedAt} {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// latestAnnotation returns the most recently updated annotation
+func latestAnnotation(annotations []LSAnnotation) LSAnnotation {
+	latest := annotations[0]
+	for _, a := range annotations[1:] {
+		if t, lt := annotationTime(a), annotationTime(latest); t.After(lt) || (t.Equal(lt) && a.ID > latest.ID) {
+			latest = a
+		}
+	}
+	return latest
+}
+
+// consensusRegion is a group of matching results from different annotations
+type consensusRegion struct {
+	key     string
+	bounds  [4]float64
+	result  LSResult
+	members map[int]bool
+}
+
+// mergeAnnotations groups matching results across annotations: regions match
+// when they share type and label and overlap by at least minIoU, choices when
+// they are the same choice. Regions found in at least minVotes annotations are
+// returned, represented by their earliest result. Results that can't be
+// matched, such as relations or video tracks, come from the first annotation.
+func mergeAnnotations(annotations []LSAnnotation, minIoU float64, minVotes int, stats *consensusStats) []LSResult {
+	var regions []*consensusRegion
+	var unmatched []LSResult
+
+	for i, annotation := range annotations {
+		for _, result := range annotation.Result {
+			for _, candidate := range consensusCandidates(result) {
+				key := candidate.Type + "/" + firstLabel(candidate)
+				bounds, geometric := resultBounds(candidate)
+				if !geometric && candidate.Type != "choices" {
+					if i == 0 {
+						unmatched = append(unmatched, candidate)
+					}
+					continue
+				}
+
+				var best *consensusRegion
+				bestIoU := -1.0
+				for _, region := range regions {
+					if region.key != key || region.members[i] {
+						continue
+					}
+					overlap := 1.0
+					if geometric {
+						overlap = boxIoU(region.bounds, bounds)
+						if overlap < minIoU || overlap == 0 {
+							continue
+						}
+					}
+					if overlap > bestIoU {
+						best, bestIoU = region, overlap
+					}
+				}
+
+				if best == nil {
+					best = &consensusRegion{key: key, bounds: bounds, result: candidate, members: make(map[int]bool)}
+					regions = append(regions, best)
+				}
+				best.members[i] = true
+			}
+		}
+	}
+
+	results := unmatched
+	disputed := 0
+	for _, region := range regions {
+		if len(region.members) < len(annotations) {
+			disputed++
+		}
+		if len(region.members) >= minVotes {
+			results = append(results, region.result)
+		}
+	}
+
+	if stats != nil && len(annotations) > 1 {
+		stats.multiTasks++
+		stats.regions += len(regions)
+		stats.disputedRegions += disputed
+		if disputed > 0 {
+			stats.disputedTasks++
+		}
+	}
+	return results
+}
+
+// consensusCandidates splits a choices result into one result per choice so
+// each choice is voted on separately
+func consensusCandidates(result LSResult) []LSResult {
+	if result.Type != "choices" || len(result.Value.Choices) < 2 {
+		return []LSResult{result}
+	}
+	candidates := make([]LSResult, 0, len(result.Value.Choices))
+	for _, choice := range result.Value.Choices {
+		single := result
+		single.Value.Choices = []string{choice}
+		candidates = append(candidates, single)
+	}
+	return candidates
+}
+
+func firstLabel(result LSResult) string {
+	if names := result.Value.LabelNames(); len(names) > 0 {
+		return names[0]
+	}
+	if len(result.Value.Choices) > 0 {
+		return result.Value.Choices[0]
+	}
+	return ""
+}
+
+// keypointRadius is the half-size, as a fraction of the image, of the box
+// keypoints are matched with
+const keypointRadius = 0.01
+
+// resultBounds returns the normalized box of a region result for matching
+func resultBounds(result LSResult) ([4]float64, bool) {
+	switch result.Type {
+	case "rectanglelabels":
+		return rectangleBounds(result), true
+	case "polygonlabels", "polylinelabels":
+		if coords := polylineCoords(result); len(coords) >= 4 {
+			return coordBounds(coords), true
+		}
+	case "ellipselabels":
+		return ellipseBounds(result), true
+	case "keypointlabels":
+		x, y := result.Value.X/100, result.Value.Y/100
+		return clippedBox(x-keypointRadius, y-keypointRadius, x+keypointRadius, y+keypointRadius), true
+	case "brushlabels":
+		polygons, err := brushPolygons(result, 0)
+		if err != nil || len(polygons) == 0 {
+			return [4]float64{}, false
+		}
+		var coords []float64
+		for _, polygon := range polygons {
+			coords = append(coords, polygon...)
+		}
+		return coordBounds(coords), true
+	}
+	return [4]float64{}, false
+}
+
+// boxIoU returns the intersection over union of two center-form boxes
+func boxIoU(a, b [4]float64) float64 {
+	ix := math.Min(a[0]+a[2]/2, b[0]+b[2]/2) - math.Max(a[0]-a[2]/2, b[0]-b[2]/2)
+	iy := math.Min(a[1]+a[3]/2, b[1]+b[3]/2) - math.Max(a[1]-a[3]/2, b[1]-b[3]/2)
+	if ix <= 0 || iy <= 0 {
+		return 0
+	}
+	inter := ix * iy
+	union := a[2]*a[3] + b[2]*b[3] - inter
+	if union <= 0 {
+		return 0
+	}
+	return inter / union
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// box is a rectangle result in Label Studio percentages
+func box(label string, x, y, w, h float64) LSResult {
+	return LSResult{Type: "rectanglelabels", Value: LSValue{X: x, Y: y, Width: w, Height: h, RectangleLabels: []string{label}}}
+}
+
+func TestConsensusStrategies(t *testing.T) {
+	task := LSTask{ID: 1, Annotations: []LSAnnotation{
+		{ID: 1, UpdatedAt: "2025-01-01T10:00:00Z", Result: []LSResult{box("cat", 10, 10, 20, 20), box("dog", 60, 60, 20, 20)}},
+		{ID: 2, UpdatedAt: "2025-01-03T10:00:00Z", Result: []LSResult{box("cat", 11, 11, 20, 20)}},
+		{ID: 3, UpdatedAt: "2025-01-02T10:00:00Z", Result: []LSResult{box("cat", 10, 12, 20, 20), box("bird", 0, 80, 10, 10)}},
+	}}
+
+	tests := []struct {
+		strategy string
+		labels   string
+	}{
+		{ConsensusFirst, "cat,dog"},
+		{ConsensusLatest, "cat"},
+		{ConsensusMajority, "cat"},
+		{ConsensusUnion, "cat,dog,bird"},
+	}
+
+	for _, tt := range tests {
+		converter := NewConverter(Config{Consensus: tt.strategy, ConsensusIoU: 0.5})
+		converter.consensusStats = &consensusStats{}
+
+		results, _ := converter.taskResults(task)
+		var labels []string
+		for _, result := range results {
+			labels = append(labels, firstLabel(result))
+		}
+		if got := strings.Join(labels, ","); got != tt.labels {
+			t.Errorf("%s consensus: expected %s, got %s", tt.strategy, tt.labels, got)
+		}
+
+		// cat is agreed on; dog and bird are each disputed
+		stats := converter.consensusStats
+		if stats.multiTasks != 1 || stats.disputedTasks != 1 || stats.regions != 3 || stats.disputedRegions != 2 {
+			t.Errorf("%s consensus: unexpected stats %+v", tt.strategy, *stats)
+		}
+	}
+}
+
+func TestConsensusRequiresOverlap(t *testing.T) {
+	annotations := []LSAnnotation{
+		{Result: []LSResult{box("cat", 10, 10, 20, 20)}},
+		{Result: []LSResult{box("cat", 25, 25, 20, 20)}},
+	}
+
+	if results := mergeAnnotations(annotations, 0.5, 2, nil); len(results) != 0 {
+		t.Errorf("Expected boxes with low IoU not to reach a majority, got %d results", len(results))
+	}
+	if results := mergeAnnotations(annotations, 0.02, 2, nil); len(results) != 1 {
+		t.Errorf("Expected boxes to match with a low IoU threshold, got %d results", len(results))
+	}
+}
+
+func TestConsensusChoicesVote(t *testing.T) {
+	choices := func(values ...string) []LSResult {
+		return []LSResult{{Type: "choices", Value: LSValue{Choices: values}}}
+	}
+	annotations := []LSAnnotation{
+		{Result: choices("outdoor", "sunny")},
+		{Result: choices("outdoor")},
+		{Result: choices("indoor", "sunny")},
+	}
+
+	labels := choiceLabels(mergeAnnotations(annotations, 0.5, 2, nil))
+	if strings.Join(labels, ",") != "outdoor,sunny" {
+		t.Errorf("Expected majority choices outdoor,sunny, got %v", labels)
+	}
+}
+
+func TestValidateConsensus(t *testing.T) {
+	if err := NewConverter(Config{Consensus: "average"}).validateConsensus(); err == nil {
+		t.Error("Expected error for unknown strategy")
+	}
+	if err := NewConverter(Config{ConsensusIoU: 1.5}).validateConsensus(); err == nil {
+		t.Error("Expected error for out of range IoU")
+	}
+}
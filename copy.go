@@ -0,0 +1,25 @@
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+// copyBufferSize is the buffer size of user-space copies. Large buffers cut
+// the round trips that dominate copies to network file systems.
+const copyBufferSize = 1 << 20
+
+var copyBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, copyBufferSize)
+		return &buf
+	},
+}
+
+// copyBuffered copies src to dst through a pooled copyBufferSize buffer
+func copyBuffered(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	// Hide ReadFrom and WriteTo so io.CopyBuffer uses the buffer
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// kernelCopyChunk caps a single copy_file_range or sendfile call
+const kernelCopyChunk = 1 << 30
+
+// preallocate reserves size bytes for dst without changing its length, so
+// the file system can lay the copy out in one piece. Failures are ignored;
+// not every file system supports it.
+func preallocate(dst *os.File, size int64) {
+	if size > 0 {
+		unix.Fallocate(int(dst.Fd()), unix.FALLOC_FL_KEEP_SIZE, 0, size)
+	}
+}
+
+// kernelCopy copies up to size bytes from src to dst without passing them
+// through user space: with copy_file_range, which NFS 4.2 turns into a
+// server-side copy, or with sendfile where that isn't supported. It returns
+// the bytes copied, leaving both file offsets behind them, so the caller
+// finishes any remainder with a regular copy.
+func kernelCopy(dst, src *os.File, size int64) int64 {
+	var written int64
+	rfd, wfd := int(src.Fd()), int(dst.Fd())
+	for written < size {
+		n, err := unix.CopyFileRange(rfd, nil, wfd, nil, int(min(size-written, kernelCopyChunk)), 0)
+		if err != nil || n == 0 {
+			break
+		}
+		written += int64(n)
+	}
+	for written < size {
+		n, err := unix.Sendfile(wfd, rfd, nil, int(min(size-written, kernelCopyChunk)))
+		if err != nil || n == 0 {
+			break
+		}
+		written += int64(n)
+	}
+	return written
+}
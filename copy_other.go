@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+// preallocate is a no-op on platforms without fallocate
+func preallocate(dst *os.File, size int64) {}
+
+// kernelCopy copies nothing on platforms without copy_file_range; callers
+// fall back to a regular copy
+func kernelCopy(dst, src *os.File, size int64) int64 {
+	return 0
+}
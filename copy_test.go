@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyFileLargeFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bin")
+	dst := filepath.Join(dir, "dst.bin")
+	// Several buffers and a partial one
+	data := make([]byte, 3*copyBufferSize+12345)
+	for i := range data {
+		data[i] = byte(i * 31 / 7)
+	}
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Expected an exact copy of %d bytes, got %d", len(data), len(got))
+	}
+}
+
+func TestCopyBuffered(t *testing.T) {
+	text := strings.Repeat("label studio ", 200000)
+	var buf bytes.Buffer
+	n, err := copyBuffered(&buf, strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(text)) || buf.String() != text {
+		t.Errorf("Expected %d bytes copied, got %d", len(text), n)
+	}
+}
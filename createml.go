@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"path/filepath"
+)
+
+// createMLAnnotationsFile is the annotation file in every split folder of a
+// Create ML dataset
+const createMLAnnotationsFile = "annotations.json"
+
+// CreateMLImage is one entry of a Create ML object detection annotation file
+type CreateMLImage struct {
+	Image       string               `json:"image"`
+	Annotations []CreateMLAnnotation `json:"annotations"`
+}
+
+// CreateMLAnnotation is a labeled box; its coordinates are the center and
+// size in pixels
+type CreateMLAnnotation struct {
+	Label       string              `json:"label"`
+	Coordinates CreateMLCoordinates `json:"coordinates"`
+}
+
+// CreateMLCoordinates locates a box in pixels from the top-left corner
+type CreateMLCoordinates struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// writeCreateML writes the splits as Create ML object detection folders: the
+// images of a split with an annotations.json listing their boxes, which
+// Create ML takes as training and validation data
+func (c *Converter) writeCreateML(classes []string, splits map[string][]LabelPair) error {
+	writer := c.output()
+	for _, split := range []string{"train", "val"} {
+		pairs := splits[split]
+		if err := writer.MkdirAll(split); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", split, err)
+		}
+
+		entries := make([]CreateMLImage, 0, len(pairs))
+		unstripped := 0
+		for _, pair := range pairs {
+			if err := c.interrupted(); err != nil {
+				return err
+			}
+			entry, err := createMLEntry(pair, classes)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+
+			kept, err := c.copyImage(path.Join(split, entry.Image), pair.ImagePath)
+			if err != nil {
+				return err
+			}
+			if kept {
+				unstripped++
+			}
+		}
+
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode %s annotations: %w", split, err)
+		}
+		name := path.Join(split, createMLAnnotationsFile)
+		if err := writer.WriteFile(name, append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if unstripped > 0 {
+			fmt.Fprintf(c.out, "Warning: metadata could not be stripped from %d %s images (only JPEG and PNG are supported)\n", unstripped, split)
+		}
+		fmt.Fprintf(c.out, "Wrote %d %s images for Create ML\n", len(pairs), split)
+	}
+	return nil
+}
+
+// createMLEntry converts the labels of an image to pixel boxes. The image
+// size is the displayed one, turned for EXIF orientations as Create ML does.
+func createMLEntry(pair LabelPair, classes []string) (CreateMLImage, error) {
+	width, height, err := readImageSize(pair.ImagePath)
+	if err != nil {
+		return CreateMLImage{}, fmt.Errorf("failed to read image size of %s: %w", pair.ImagePath, err)
+	}
+	boxes, err := readLabelBoxes(pair.LabelPath, len(classes))
+	if err != nil {
+		return CreateMLImage{}, err
+	}
+
+	entry := CreateMLImage{Image: filepath.Base(pair.ImagePath), Annotations: []CreateMLAnnotation{}}
+	for _, box := range boxes {
+		entry.Annotations = append(entry.Annotations, CreateMLAnnotation{
+			Label: classes[box.ClassID],
+			Coordinates: CreateMLCoordinates{
+				X:      roundPixels(box.Bounds[0] * float64(width)),
+				Y:      roundPixels(box.Bounds[1] * float64(height)),
+				Width:  roundPixels(box.Bounds[2] * float64(width)),
+				Height: roundPixels(box.Bounds[3] * float64(height)),
+			},
+		})
+	}
+	return entry, nil
+}
+
+// roundPixels rounds a pixel coordinate to hundredths
+func roundPixels(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertCreateML(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image1.jpg"), 100, 50)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image2.png"), 40, 40)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image3.jpeg"), 20, 20)
+
+	outputDir := filepath.Join(t.TempDir(), "createml")
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.7, Seed: 42, OutputFormat: OutputCreateML})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make(map[string]CreateMLImage)
+	for _, split := range []string{"train", "val"} {
+		data, err := os.ReadFile(filepath.Join(outputDir, split, createMLAnnotationsFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var images []CreateMLImage
+		if err := json.Unmarshal(data, &images); err != nil {
+			t.Fatal(err)
+		}
+		for _, image := range images {
+			if _, err := os.Stat(filepath.Join(outputDir, split, image.Image)); err != nil {
+				t.Errorf("Expected %s next to its annotations: %v", image.Image, err)
+			}
+			entries[image.Image] = image
+		}
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 annotated images, got %v", entries)
+	}
+
+	// image1.txt: "0 0.5 0.5 0.3 0.3" and "1 0.2 0.8 0.1 0.1" on 100x50 pixels
+	want := []CreateMLAnnotation{
+		{Label: "book", Coordinates: CreateMLCoordinates{X: 50, Y: 25, Width: 30, Height: 15}},
+		{Label: "person", Coordinates: CreateMLCoordinates{X: 20, Y: 40, Width: 10, Height: 5}},
+	}
+	got := entries["image1.jpg"].Annotations
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Annotation %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// labelBounds returns the normalized axis-aligned bounds (x_center, y_center,
+// width, height) of a valid label line of the given kind
+func labelBounds(kind string, parts []string) [4]float64 {
+	values := make([]float64, len(parts)-1)
+	for i := range values {
+		values[i], _ = strconv.ParseFloat(parts[i+1], 64)
+	}
+
+	switch kind {
+	case AnnotationBox, AnnotationPose:
+		return [4]float64{values[0], values[1], values[2], values[3]}
+	}
+
+	// Polygons and oriented boxes: the box enclosing the points
+	return coordBounds(values)
+}
+
+// cropRect converts normalized bounds into a pixel rectangle inside the image,
+// grown on every side by padding times the box size
+func cropRect(bounds [4]float64, padding float64, size image.Rectangle) image.Rectangle {
+	w, h := float64(size.Dx()), float64(size.Dy())
+	bw, bh := bounds[2]*w, bounds[3]*h
+	cx, cy := bounds[0]*w, bounds[1]*h
+
+	halfW := bw/2 + padding*bw
+	halfH := bh/2 + padding*bh
+	rect := image.Rect(
+		int(math.Floor(cx-halfW)), int(math.Floor(cy-halfH)),
+		int(math.Ceil(cx+halfW)), int(math.Ceil(cy+halfH)),
+	)
+	return rect.Add(size.Min).Intersect(size)
+}
+
+// encodeCrop encodes a crop in the format of its source image; formats other
+// than JPEG are written as PNG
+func encodeCrop(img image.Image, sourceExt string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch strings.ToLower(sourceExt) {
+	case ".jpg", ".jpeg":
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), ".jpg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), ".png", nil
+}
+
+// decodeImage reads an image file
+func decodeImage(imagePath string) (image.Image, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(bufio.NewReader(file))
+	return img, err
+}
+
+// convertCrops cuts every annotation out of its image and writes the crops as
+// a folder-per-class classification dataset. Images, not crops, are split so
+// that crops of one image never end up in both train and val.
+func (c *Converter) convertCrops(classes []string, pairs []LabelPair) (err error) {
+	if c.config.Incremental {
+		return fmt.Errorf("incremental conversion is not supported for crop output")
+	}
+	if c.config.CropPadding < 0 {
+		return fmt.Errorf("crop padding must not be negative")
+	}
+
+	train, val := c.SplitDataset(pairs)
+
+	writer, err := c.openOutput()
+	if err != nil {
+		return err
+	}
+	defer finishOutput(writer, &err)
+	defer c.reportFileErrors()
+
+	skipped := 0
+	for _, split := range []struct {
+		name  string
+		pairs []LabelPair
+	}{{"train", train}, {"val", val}} {
+		crops := 0
+		for _, pair := range split.pairs {
+			if err := c.interrupted(); err != nil {
+				return err
+			}
+			n, small, err := c.writeCrops(writer, split.name, classes, pair)
+			if err != nil {
+				return err
+			}
+			crops += n
+			skipped += small
+		}
+		fmt.Fprintf(c.out, "Wrote %d %s crops\n", crops, split.name)
+	}
+
+	if skipped > 0 {
+		fmt.Fprintf(c.out, "Skipped %d crops smaller than %d pixels\n", skipped, c.config.CropMinSize)
+	}
+	fmt.Fprintln(c.out, "\nConversion completed successfully!")
+	fmt.Fprintf(c.out, "Crop classification dataset ready at: %s\n", c.config.OutputDir)
+	return nil
+}
+
+// writeCrops writes the crops of one image into split/<class>/, returning the
+// number written and the number skipped for being too small
+func (c *Converter) writeCrops(writer DatasetWriter, split string, classes []string, pair LabelPair) (int, int, error) {
+	data, err := os.ReadFile(pair.LabelPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read label %s: %w", pair.LabelPath, err)
+	}
+
+	var img image.Image
+	written, skipped := 0, 0
+	base := strings.TrimSuffix(filepath.Base(pair.ImagePath), filepath.Ext(pair.ImagePath))
+
+	for i, line := range strings.Split(string(data), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		kind, warning := c.parseLine(parts)
+		if warning != "" {
+			continue
+		}
+		classID, _ := strconv.Atoi(parts[0])
+		if classID < 0 || classID >= len(classes) {
+			fmt.Fprintf(c.out, "Warning: Unknown class %d in %s:%d\n", classID, filepath.Base(pair.LabelPath), i+1)
+			continue
+		}
+
+		// Decode lazily so images without annotations are never read
+		if img == nil {
+			if img, err = decodeImage(pair.ImagePath); err != nil {
+				fmt.Fprintf(c.out, "Warning: Cannot decode %s: %v\n", filepath.Base(pair.ImagePath), err)
+				return written, skipped, nil
+			}
+			// Labels are drawn on the upright image
+			img = orient(img, exifOrientation(pair.ImagePath))
+		}
+
+		rect := cropRect(labelBounds(kind, parts), c.config.CropPadding, img.Bounds())
+		if rect.Dx() < c.config.CropMinSize || rect.Dy() < c.config.CropMinSize || rect.Empty() {
+			skipped++
+			continue
+		}
+
+		sub, ok := img.(interface {
+			SubImage(image.Rectangle) image.Image
+		})
+		if !ok {
+			return written, skipped, fmt.Errorf("image %s does not support cropping", pair.ImagePath)
+		}
+
+		encoded, ext, err := encodeCrop(sub.SubImage(rect), filepath.Ext(pair.ImagePath))
+		if err != nil {
+			return written, skipped, fmt.Errorf("failed to encode crop of %s: %w", pair.ImagePath, err)
+		}
+
+		dir := path.Join(split, classDirName(classes[classID]))
+		if err := writer.MkdirAll(dir); err != nil {
+			return written, skipped, fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+		name := path.Join(dir, fmt.Sprintf("%s_%d%s", base, i+1, ext))
+		if err := writer.WriteFile(name, encoded); err != nil {
+			return written, skipped, fmt.Errorf("failed to write crop %s: %w", name, err)
+		}
+		written++
+	}
+	return written, skipped, nil
+}
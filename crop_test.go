@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestImage writes a solid-color image in the format given by the name
+func writeTestImage(t *testing.T, path string, width, height int) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if strings.HasSuffix(path, ".png") {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write test image: %v", err)
+	}
+}
+
+func TestCropConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+
+	// Replace the placeholder images with decodable ones and give image1 a
+	// polygon and a box too small to keep
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image1.jpg"), 100, 50)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image2.png"), 100, 50)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image3.jpeg"), 100, 50)
+	labels := "0 0.25 0.5 0.2 0.4\n1 0.6 0.2 0.9 0.2 0.9 0.8\n0 0.5 0.5 0.01 0.01\n"
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image1.txt"), []byte(labels), 0644); err != nil {
+		t.Fatalf("Failed to write label: %v", err)
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "crops")
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, Crop: true, CropPadding: 0.5, CropMinSize: 4})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	expected := map[string]image.Point{
+		"train/book/image1_1.jpg":   {40, 40},
+		"train/person/image1_2.jpg": {55, 50},
+		"train/book/image2_1.png":   {40, 40},
+	}
+	for name, size := range expected {
+		img, err := decodeImage(filepath.Join(outputDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("Failed to decode crop %s: %v", name, err)
+			continue
+		}
+		if got := img.Bounds().Size(); got != size {
+			t.Errorf("Crop %s: expected size %v, got %v", name, size, got)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "train", "book", "image1_3.jpg")); err == nil {
+		t.Error("Expected crop below the minimum size to be skipped")
+	}
+	if !strings.Contains(out.String(), "Skipped 1 crops smaller than 4 pixels") {
+		t.Errorf("Expected skipped crop to be reported, got:\n%s", out.String())
+	}
+}
+
+func TestCropRectClipsToImage(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 100)
+	rect := cropRect([4]float64{0.05, 0.95, 0.2, 0.2}, 0.25, bounds)
+	if rect != image.Rect(0, 80, 20, 100) {
+		t.Errorf("Expected crop clipped to the image, got %v", rect)
+	}
+}
+
+func TestCropSkipsNegativeClassID(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image1.jpg"), 100, 50)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image2.png"), 100, 50)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image3.jpeg"), 100, 50)
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image1.txt"), []byte("-1 0.5 0.5 0.5 0.5\n"), 0644); err != nil {
+		t.Fatalf("Failed to write label: %v", err)
+	}
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: filepath.Join(t.TempDir(), "crops"), TrainSplit: 1, Seed: 42, Crop: true})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Unknown class -1 in image1.txt:1") {
+		t.Errorf("Expected negative class ID to be reported, got:\n%s", out.String())
+	}
+}
+
+func TestCropAppliesEXIFOrientation(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image2.png"), 100, 50)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image3.jpeg"), 100, 50)
+
+	// Stored as 100x50 and shown as 50x100, with the red half on top
+	writeOrientedJPEG(t, filepath.Join(sourceDir, "images", "image1.jpg"), 100, 50, 6)
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image1.txt"), []byte("0 0.5 0.25 1 0.5\n"), 0644); err != nil {
+		t.Fatalf("Failed to write label: %v", err)
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "crops")
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, Crop: true})
+	converter.SetOutput(&strings.Builder{})
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	img, err := decodeImage(filepath.Join(outputDir, "train", "book", "image1_1.jpg"))
+	if err != nil {
+		t.Fatalf("Failed to decode crop: %v", err)
+	}
+	if got := img.Bounds().Size(); got != (image.Point{50, 50}) {
+		t.Errorf("Expected a 50x50 crop of the upright image, got %v", got)
+	}
+	if r, _, b, _ := img.At(25, 25).RGBA(); r < b {
+		t.Error("Expected the crop to cover the red half of the upright image")
+	}
+}
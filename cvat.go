@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cvatAnnotationsFile is the annotation file of a CVAT for images export
+const cvatAnnotationsFile = "annotations.xml"
+
+// CVATAnnotations is a CVAT for images 1.1 XML export
+type CVATAnnotations struct {
+	Version string      `xml:"version"`
+	Labels  []string    `xml:"meta>task>labels>label>name"`
+	Project []string    `xml:"meta>project>labels>label>name"`
+	Job     []string    `xml:"meta>job>labels>label>name"`
+	Images  []CVATImage `xml:"image"`
+	Tracks  []struct{}  `xml:"track"`
+}
+
+// CVATImage is an image with its shapes, in pixels
+type CVATImage struct {
+	ID     int         `xml:"id,attr"`
+	Name   string      `xml:"name,attr"`
+	Width  int         `xml:"width,attr"`
+	Height int         `xml:"height,attr"`
+	Shapes []CVATShape `xml:",any"`
+}
+
+// CVATShape is a box, polygon, polyline, ellipse or another shape of an
+// image; the element name tells which
+type CVATShape struct {
+	XMLName  xml.Name
+	Label    string  `xml:"label,attr"`
+	XTL      float64 `xml:"xtl,attr"`
+	YTL      float64 `xml:"ytl,attr"`
+	XBR      float64 `xml:"xbr,attr"`
+	YBR      float64 `xml:"ybr,attr"`
+	Rotation float64 `xml:"rotation,attr"`
+	Points   string  `xml:"points,attr"`
+	CX       float64 `xml:"cx,attr"`
+	CY       float64 `xml:"cy,attr"`
+	RX       float64 `xml:"rx,attr"`
+	RY       float64 `xml:"ry,attr"`
+}
+
+// LoadCVATAnnotations reads a CVAT XML export
+func LoadCVATAnnotations(path string) (*CVATAnnotations, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CVAT export: %w", err)
+	}
+	var annotations CVATAnnotations
+	if err := xml.Unmarshal(data, &annotations); err != nil {
+		return nil, fmt.Errorf("failed to parse CVAT export %s: %w", path, err)
+	}
+	return &annotations, nil
+}
+
+// labelNames returns the labels declared in the export's meta section
+func (a *CVATAnnotations) labelNames() []string {
+	for _, names := range [][]string{a.Labels, a.Project, a.Job} {
+		if len(names) > 0 {
+			return names
+		}
+	}
+	return nil
+}
+
+// findCVATFile returns the configured XML export, annotations.xml or the
+// only .xml file in the source directory
+func (c *Converter) findCVATFile() (string, error) {
+	if c.config.TasksFile != "" {
+		return c.config.TasksFile, nil
+	}
+	path := filepath.Join(c.config.SourceDir, cvatAnnotationsFile)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(c.config.SourceDir, "*.xml"))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no CVAT XML export found in %s (use -tasks)", c.config.SourceDir)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("multiple XML files found in %s, choose one with -tasks", c.config.SourceDir)
+}
+
+// loadCVAT converts a CVAT for images XML export into YOLO label files in a
+// staging directory and pairs them with the images in the source's images/
+// directory. Shapes become the Label Studio results they correspond to, so
+// boxes, polygons, ellipses and polylines convert exactly like ls-json ones.
+func (c *Converter) loadCVAT() ([]string, []LabelPair, error) {
+	if c.skeleton != nil {
+		return nil, nil, fmt.Errorf("-skeleton requires -input-format %s", InputLSJSON)
+	}
+	path, err := c.findCVATFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	annotations, err := LoadCVATAnnotations(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(annotations.Images) == 0 && len(annotations.Tracks) > 0 {
+		return nil, nil, fmt.Errorf("%s holds video tracks; export the CVAT task in the \"CVAT for images\" format", path)
+	}
+	fmt.Fprintf(c.out, "Loaded %d images from %s\n", len(annotations.Images), path)
+
+	// Labels are numbered in the order the export declares them, unless
+	// classes.txt pins the IDs
+	classes := annotations.labelNames()
+	if _, err := os.Stat(filepath.Join(c.config.SourceDir, "classes.txt")); err == nil {
+		if classes, err = c.LoadClasses(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stagingDir, err := c.stagingDirectory()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	builder := newLabelBuilder(classes, c.config.MaskTolerance, nil)
+	builder.obb = c.config.OBB
+	if builder.shapeModes, err = parseShapeModes(c.config.ShapeModes); err != nil {
+		return nil, nil, err
+	}
+	var pairs []LabelPair
+
+	for _, image := range annotations.Images {
+		imagePath, ok := c.resolveCVATImage(image)
+		if !ok {
+			continue
+		}
+		results, err := cvatResults(image)
+		if err != nil {
+			return nil, nil, fmt.Errorf("image %s: %w", image.Name, err)
+		}
+		lines := builder.taskLines(c, image.ID, results)
+
+		imageName := filepath.Base(imagePath)
+		labelPath := filepath.Join(stagingDir, strings.TrimSuffix(imageName, filepath.Ext(imageName))+".txt")
+		if err := os.WriteFile(labelPath, []byte(strings.Join(lines, "")), 0644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write label %s: %w", labelPath, err)
+		}
+		pairs = append(pairs, LabelPair{ImagePath: imagePath, LabelPath: labelPath})
+	}
+
+	for shape, count := range builder.unsupported {
+		fmt.Fprintf(c.out, "Warning: Skipped %d unsupported %s shapes\n", count, strings.TrimSuffix(shape, "labels"))
+	}
+	fmt.Fprintf(c.out, "Found %d classes: %v\n", len(builder.classes), builder.classes)
+	fmt.Fprintf(c.out, "Found %d image-label pairs\n", len(pairs))
+	return builder.classes, pairs, nil
+}
+
+// resolveCVATImage returns the path of an image in the source's images/
+// directory, where CVAT keeps the image's own subdirectories, falling back
+// to its file name
+func (c *Converter) resolveCVATImage(image CVATImage) (string, bool) {
+	name := filepath.FromSlash(image.Name)
+	for _, candidate := range []string{name, filepath.Base(name)} {
+		imagePath := filepath.Join(c.config.SourceDir, "images", candidate)
+		if _, err := os.Stat(imagePath); err == nil {
+			return imagePath, true
+		}
+	}
+	fmt.Fprintf(c.out, "Warning: Image %s (CVAT image %d) not found\n", image.Name, image.ID)
+	return "", false
+}
+
+// cvatResults converts the shapes of an image into Label Studio results, in
+// percentages of the image size. CVAT rotates boxes around their center and
+// Label Studio around the top-left corner, so rotated boxes are moved to
+// where their top-left corner ends up.
+func cvatResults(image CVATImage) ([]LSResult, error) {
+	if image.Width <= 0 || image.Height <= 0 {
+		return nil, fmt.Errorf("missing image size")
+	}
+	w, h := float64(image.Width), float64(image.Height)
+
+	var results []LSResult
+	for _, shape := range image.Shapes {
+		result := LSResult{OriginalWidth: image.Width, OriginalHeight: image.Height}
+		value := &result.Value
+		labels := []string{shape.Label}
+
+		switch shape.XMLName.Local {
+		case "box":
+			result.Type = "rectanglelabels"
+			value.RectangleLabels = labels
+			bw, bh := shape.XBR-shape.XTL, shape.YBR-shape.YTL
+			x, y := shape.XTL, shape.YTL
+			if shape.Rotation != 0 {
+				sin, cos := math.Sincos(shape.Rotation * math.Pi / 180)
+				cx, cy := x+bw/2, y+bh/2
+				x = cx - bw/2*cos + bh/2*sin
+				y = cy - bw/2*sin - bh/2*cos
+			}
+			value.X, value.Y = x/w*100, y/h*100
+			value.Width, value.Height, value.Rotation = bw/w*100, bh/h*100, shape.Rotation
+		case "polygon", "polyline":
+			result.Type = shape.XMLName.Local + "labels"
+			if shape.XMLName.Local == "polygon" {
+				value.PolygonLabels = labels
+			} else {
+				value.PolylineLabels = labels
+			}
+			points, err := parseCVATPoints(shape.Points)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range points {
+				value.Points = append(value.Points, []float64{p[0] / w * 100, p[1] / h * 100})
+			}
+		case "ellipse":
+			result.Type = "ellipselabels"
+			value.EllipseLabels = labels
+			value.X, value.Y = shape.CX/w*100, shape.CY/h*100
+			value.RadiusX, value.RadiusY = shape.RX/w*100, shape.RY/h*100
+			value.Rotation = shape.Rotation
+		default:
+			// Points, masks, cuboids, skeletons and tags are counted as
+			// unsupported
+			result.Type = shape.XMLName.Local
+			value.Labels = labels
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// parseCVATPoints parses the "x1,y1;x2,y2;..." points of a CVAT shape
+func parseCVATPoints(points string) ([][2]float64, error) {
+	var parsed [][2]float64
+	for _, point := range strings.Split(points, ";") {
+		xy := strings.Split(strings.TrimSpace(point), ",")
+		if len(xy) != 2 {
+			return nil, fmt.Errorf("invalid point %q", point)
+		}
+		x, errX := strconv.ParseFloat(xy[0], 64)
+		y, errY := strconv.ParseFloat(xy[1], 64)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid point %q", point)
+		}
+		parsed = append(parsed, [2]float64{x, y})
+	}
+	return parsed, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cvatExport is a CVAT for images 1.1 export with every kind of shape
+const cvatExport = `<?xml version="1.0" encoding="utf-8"?>
+<annotations>
+  <version>1.1</version>
+  <meta>
+    <task>
+      <name>parking</name>
+      <labels>
+        <label><name>car</name><color>#ff0000</color></label>
+        <label><name>person</name><color>#00ff00</color></label>
+      </labels>
+    </task>
+  </meta>
+  <image id="0" name="lot/a.jpg" width="200" height="100">
+    <box label="person" occluded="0" source="manual" xtl="20" ytl="10" xbr="60" ybr="30" z_order="0">
+      <attribute name="pose">standing</attribute>
+    </box>
+    <polygon label="car" source="manual" points="20.0,10.0;100.0,10.0;60.0,60.0" z_order="0"/>
+    <points label="person" points="5,5"/>
+    <tag label="car"/>
+  </image>
+  <image id="1" name="b.png" width="100" height="100">
+    <box label="car" xtl="20" ytl="20" xbr="60" ybr="40" rotation="90"/>
+    <polyline label="car" points="10,10;30,10;30,50"/>
+  </image>
+  <image id="2" name="missing.jpg" width="100" height="100"/>
+</annotations>
+`
+
+func TestCVATConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, name := range []string{"lot/a.jpg", "b.png"} {
+		path := filepath.Join(sourceDir, "images", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("fake image data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, cvatAnnotationsFile), []byte(cvatExport), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputCVAT})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	expected := map[string]string{
+		// Classes follow the labels declared in the export, not first use
+		"a.txt": "1 0.200000 0.200000 0.200000 0.200000\n0 0.100000 0.100000 0.500000 0.100000 0.300000 0.600000\n",
+		// CVAT rotates boxes around their center; polylines become boxes
+		"b.txt": "0 0.400000 0.300000 0.200000 0.400000\n0 0.200000 0.300000 0.200000 0.400000\n",
+	}
+	for name, want := range expected {
+		got, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", name))
+		if err != nil {
+			t.Errorf("Missing label %s: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("Label %s: expected %q, got %q", name, want, got)
+		}
+	}
+
+	log := out.String()
+	for _, want := range []string{"Image missing.jpg (CVAT image 2) not found", "Skipped 1 unsupported points shapes", "Skipped 1 unsupported tag shapes", "Found 2 classes: [car person]"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, log)
+		}
+	}
+}
+
+func TestCVATRejectsVideoExports(t *testing.T) {
+	sourceDir := t.TempDir()
+	export := `<annotations><version>1.1</version><track id="0" label="car"><box frame="0" xtl="1" ytl="1" xbr="2" ybr="2"/></track></annotations>`
+	if err := os.WriteFile(filepath.Join(sourceDir, "video.xml"), []byte(export), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{SourceDir: sourceDir, InputFormat: InputCVAT})
+	converter.SetOutput(&strings.Builder{})
+	if _, _, err := converter.loadSource(); err == nil || !strings.Contains(err.Error(), "CVAT for images") {
+		t.Errorf("Expected video exports to be rejected, got %v", err)
+	}
+}
+
+func TestParseCVATPoints(t *testing.T) {
+	points, err := parseCVATPoints("1.5,2;3,4.25")
+	if err != nil || len(points) != 2 || points[0] != [2]float64{1.5, 2} || points[1] != [2]float64{3, 4.25} {
+		t.Errorf("Unexpected points %v, %v", points, err)
+	}
+	if _, err := parseCVATPoints("1,2;3"); err == nil {
+		t.Error("Expected an error for a point without y")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// darknetImagesDir holds the images of a Darknet dataset with their labels
+// next to them, where darknet looks for them
+const darknetImagesDir = "obj"
+
+// writeDarknet writes the classic Darknet layout: the images and labels of
+// both splits in obj/, train.txt and valid.txt listing the images by
+// absolute path, obj.names with the classes and obj.data tying them
+// together. Polygons and oriented boxes become the boxes enclosing them, as
+// darknet only reads boxes.
+func (c *Converter) writeDarknet(classes []string, splits map[string][]LabelPair) error {
+	writer := c.output()
+	root, err := filepath.Abs(c.config.OutputDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	for _, dir := range []string{darknetImagesDir, "backup"} {
+		if err := writer.MkdirAll(dir); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	lists := map[string]string{"train": "train.txt", "val": "valid.txt"}
+	for _, split := range []string{"train", "val"} {
+		pairs := splits[split]
+		var list strings.Builder
+		unstripped := 0
+		for _, pair := range pairs {
+			if err := c.interrupted(); err != nil {
+				return err
+			}
+			name := path.Join(darknetImagesDir, filepath.Base(pair.ImagePath))
+			kept, err := c.copyImage(name, pair.ImagePath)
+			if err != nil {
+				return err
+			}
+			if kept {
+				unstripped++
+			}
+
+			boxes, err := readLabelBoxes(pair.LabelPath, len(classes))
+			if err != nil {
+				return err
+			}
+			var label strings.Builder
+			for _, box := range boxes {
+				label.WriteString(boxLine(box.ClassID, box.Bounds))
+			}
+			labelName := path.Join(darknetImagesDir, filepath.Base(pair.LabelPath))
+			if err := writer.WriteFile(labelName, []byte(label.String())); err != nil {
+				return &CopyError{Kind: "label", Path: pair.LabelPath, Err: err}
+			}
+			fmt.Fprintln(&list, filepath.Join(root, filepath.FromSlash(name)))
+		}
+
+		if err := writer.WriteFile(lists[split], []byte(list.String())); err != nil {
+			return fmt.Errorf("failed to write %s: %w", lists[split], err)
+		}
+		if unstripped > 0 {
+			fmt.Fprintf(c.out, "Warning: metadata could not be stripped from %d %s images (only JPEG and PNG are supported)\n", unstripped, split)
+		}
+		fmt.Fprintf(c.out, "Copied %d %s files\n", len(pairs), split)
+	}
+
+	if err := writer.WriteFile("obj.names", []byte(strings.Join(classes, "\n")+"\n")); err != nil {
+		return fmt.Errorf("failed to write obj.names: %w", err)
+	}
+	data := fmt.Sprintf("classes = %d\ntrain = %s\nvalid = %s\nnames = %s\nbackup = %s\n",
+		len(classes), filepath.Join(root, "train.txt"), filepath.Join(root, "valid.txt"),
+		filepath.Join(root, "obj.names"), filepath.Join(root, "backup"))
+	if err := writer.WriteFile("obj.data", []byte(data)); err != nil {
+		return fmt.Errorf("failed to write obj.data: %w", err)
+	}
+	return nil
+}
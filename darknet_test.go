@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertDarknet(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image2.txt"), []byte("0 0.2 0.2 0.6 0.2 0.6 0.8\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "darknet")
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.7, Seed: 42, OutputFormat: OutputDarknet})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "obj.data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "classes = 2\ntrain = " + filepath.Join(outputDir, "train.txt") + "\nvalid = " + filepath.Join(outputDir, "valid.txt") +
+		"\nnames = " + filepath.Join(outputDir, "obj.names") + "\nbackup = " + filepath.Join(outputDir, "backup") + "\n"
+	if string(data) != want {
+		t.Errorf("Expected obj.data\n%s\ngot\n%s", want, data)
+	}
+	names, _ := os.ReadFile(filepath.Join(outputDir, "obj.names"))
+	if string(names) != "book\nperson\n" {
+		t.Errorf("Unexpected obj.names %q", names)
+	}
+
+	var listed []string
+	for _, list := range []string{"train.txt", "valid.txt"} {
+		data, err := os.ReadFile(filepath.Join(outputDir, list))
+		if err != nil {
+			t.Fatal(err)
+		}
+		listed = append(listed, strings.Fields(string(data))...)
+	}
+	if len(listed) != 3 {
+		t.Fatalf("Expected 3 listed images, got %v", listed)
+	}
+	for _, image := range listed {
+		if !filepath.IsAbs(image) {
+			t.Errorf("Expected an absolute path, got %s", image)
+		}
+		if _, err := os.Stat(image); err != nil {
+			t.Error(err)
+		}
+		label := strings.TrimSuffix(image, filepath.Ext(image)) + ".txt"
+		if _, err := os.Stat(label); err != nil {
+			t.Errorf("Expected the label next to its image: %v", err)
+		}
+	}
+
+	// Polygons become the box enclosing them
+	label, _ := os.ReadFile(filepath.Join(outputDir, darknetImagesDir, "image2.txt"))
+	if string(label) != "0 0.400000 0.500000 0.400000 0.600000\n" {
+		t.Errorf("Expected the polygon as a box, got %q", label)
+	}
+}
+
+func TestDarknetRequiresDirectoryOutput(t *testing.T) {
+	converter := NewConverter(Config{OutputDir: "dataset.zip", OutputFormat: OutputDarknet})
+	if err := converter.validateOutputFormat(); err == nil {
+		t.Error("Expected an archive output to be rejected")
+	}
+}
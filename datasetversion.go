@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// datasetChangelogFile is the changelog in the dataset root that every
+// versioned conversion adds an entry to
+const datasetChangelogFile = "CHANGELOG.md"
+
+// datasetChangelogTitle heads a new dataset changelog
+const datasetChangelogTitle = "# Dataset Changelog\n"
+
+// Parts of the previous dataset version -dataset-version can bump
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+)
+
+// datasetVersion is a semantic version of a dataset
+type datasetVersion struct {
+	Major, Minor, Patch int
+}
+
+func (v datasetVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// less reports whether v is an older version than other
+func (v datasetVersion) less(other datasetVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// parseDatasetVersion parses a MAJOR.MINOR.PATCH version, with an optional
+// leading v
+func parseDatasetVersion(s string) (datasetVersion, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) != 3 {
+		return datasetVersion{}, fmt.Errorf("invalid dataset version %q: expected MAJOR.MINOR.PATCH", s)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return datasetVersion{}, fmt.Errorf("invalid dataset version %q: expected MAJOR.MINOR.PATCH", s)
+		}
+		numbers[i] = n
+	}
+	return datasetVersion{numbers[0], numbers[1], numbers[2]}, nil
+}
+
+// validateDatasetVersion checks -dataset-version, which is a version or the
+// part of the previous version to bump
+func (c *Converter) validateDatasetVersion() error {
+	switch c.config.DatasetVersion {
+	case "", BumpMajor, BumpMinor, BumpPatch:
+	default:
+		if _, err := parseDatasetVersion(c.config.DatasetVersion); err != nil {
+			return err
+		}
+	}
+	if c.config.DatasetVersion == "" {
+		return nil
+	}
+	if c.config.Classify || c.config.MultiLabel || c.config.Crop {
+		return fmt.Errorf("-dataset-version cannot be combined with -classify, -multi-label or -crop")
+	}
+	if c.config.OutputFormat != "" && c.config.OutputFormat != OutputYOLO {
+		return fmt.Errorf("-dataset-version requires the YOLO output format")
+	}
+	return nil
+}
+
+// previousDataset is what a versioned conversion knows about the dataset it
+// replaces
+type previousDataset struct {
+	version datasetVersion
+	classes []string
+	summary *datasetSummary
+	// changelog is the existing changelog, empty when there is none
+	changelog string
+}
+
+// loadPreviousDataset reads the dataset already in a directory output. It
+// returns nil for archives and for new outputs.
+func (c *Converter) loadPreviousDataset() (*previousDataset, error) {
+	if archiveFormat(c.config.OutputDir) != "" {
+		return nil, nil
+	}
+	dataYAML := filepath.Join(c.config.OutputDir, "data.yaml")
+	data, err := os.ReadFile(dataYAML)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dataYAML, err)
+	}
+
+	previous := &previousDataset{}
+	var config struct {
+		Version string `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", dataYAML, err)
+	}
+	// A dataset converted without -dataset-version counts as 0.0.0
+	if config.Version != "" {
+		if previous.version, err = parseDatasetVersion(config.Version); err != nil {
+			return nil, fmt.Errorf("%s: %w", dataYAML, err)
+		}
+	}
+	if previous.classes, err = loadClassNames(dataYAML); err != nil {
+		return nil, err
+	}
+	if previous.summary, err = summarizeDataset(c.config.OutputDir); err != nil {
+		return nil, err
+	}
+	changelog, err := os.ReadFile(filepath.Join(c.config.OutputDir, datasetChangelogFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", datasetChangelogFile, err)
+	}
+	previous.changelog = string(changelog)
+	return previous, nil
+}
+
+// resolveDatasetVersion picks the version of the new dataset: the one given,
+// or the previous version with the given part bumped
+func (c *Converter) resolveDatasetVersion(previous *previousDataset) (datasetVersion, error) {
+	var old datasetVersion
+	if previous != nil {
+		old = previous.version
+	}
+	switch c.config.DatasetVersion {
+	case BumpMajor:
+		return datasetVersion{old.Major + 1, 0, 0}, nil
+	case BumpMinor:
+		return datasetVersion{old.Major, old.Minor + 1, 0}, nil
+	case BumpPatch:
+		return datasetVersion{old.Major, old.Minor, old.Patch + 1}, nil
+	}
+	version, err := parseDatasetVersion(c.config.DatasetVersion)
+	if err != nil {
+		return datasetVersion{}, err
+	}
+	if previous != nil && !old.less(version) {
+		fmt.Fprintf(c.out, "Warning: dataset version %s is not newer than the existing %s\n", version, old)
+	}
+	return version, nil
+}
+
+// summarizePairs summarizes the dataset a conversion writes, like
+// summarizeDataset does for a dataset on disk
+func summarizePairs(classes []string, splits map[string][]LabelPair) (*datasetSummary, error) {
+	summary := &datasetSummary{splits: make(map[string]string), counts: make(map[string]map[string]int)}
+	for split, pairs := range splits {
+		for _, pair := range pairs {
+			name := filepath.Base(pair.ImagePath)
+			counts, err := countLabelClasses(pair.LabelPath, classes)
+			if err != nil {
+				return nil, err
+			}
+			summary.splits[name], summary.counts[name] = split, counts
+		}
+	}
+	return summary, nil
+}
+
+// changelogEntry describes a dataset version: what changed since the
+// previous dataset or, for the first one, what it holds
+func changelogEntry(version datasetVersion, date time.Time, previous *previousDataset, classes []string, summary *datasetSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s - %s\n\n", version, date.Format("2006-01-02"))
+
+	totals := summary.totals()
+	annotations := 0
+	for _, n := range totals {
+		annotations += n
+	}
+	train := 0
+	for _, split := range summary.splits {
+		if split == "train" {
+			train++
+		}
+	}
+	fmt.Fprintf(&b, "- %d images (%d train, %d val), %d annotations, %d classes\n",
+		len(summary.splits), train, len(summary.splits)-train, annotations, len(classes))
+	if previous == nil {
+		b.WriteString("- Initial version\n")
+		return b.String()
+	}
+
+	diff := diffSummaries(previous.summary, summary)
+	fmt.Fprintf(&b, "- Images: %d added, %d removed, %d moved between splits, %d with changed annotations\n",
+		len(diff.Added), len(diff.Removed), len(diff.Moved), len(diff.Changed))
+	added, removed := classChanges(previous.classes, classes)
+	if len(added) > 0 {
+		fmt.Fprintf(&b, "- Classes added: %s\n", strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		fmt.Fprintf(&b, "- Classes removed: %s\n", strings.Join(removed, ", "))
+	}
+	if !equalStrings(previous.classes, classes) && len(added) == 0 && len(removed) == 0 {
+		b.WriteString("- Classes renumbered\n")
+	}
+	var deltas []string
+	for _, delta := range diff.Classes {
+		if delta.Delta != 0 {
+			deltas = append(deltas, fmt.Sprintf("%s %d -> %d (%+d)", delta.Class, delta.Old, delta.New, delta.Delta))
+		}
+	}
+	if len(deltas) > 0 {
+		fmt.Fprintf(&b, "- Annotations: %s\n", strings.Join(deltas, ", "))
+	}
+	return b.String()
+}
+
+// classChanges lists the class names only in updated and only in old
+func classChanges(old, updated []string) (added, removed []string) {
+	oldSet := make(map[string]bool)
+	for _, name := range old {
+		oldSet[name] = true
+	}
+	newSet := make(map[string]bool)
+	for _, name := range updated {
+		newSet[name] = true
+		if !oldSet[name] {
+			added = append(added, name)
+		}
+	}
+	for _, name := range old {
+		if !newSet[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// equalStrings reports whether two lists hold the same strings in order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeDatasetChangelog adds the entry of this version to the top of the
+// dataset's changelog
+func (c *Converter) writeDatasetChangelog(previous *previousDataset, classes []string, trainPairs, valPairs []LabelPair) error {
+	summary, err := summarizePairs(classes, map[string][]LabelPair{"train": trainPairs, "val": valPairs})
+	if err != nil {
+		return err
+	}
+	entry := changelogEntry(c.datasetVersion, time.Now(), previous, classes, summary)
+
+	existing := ""
+	if previous != nil {
+		existing = strings.TrimPrefix(previous.changelog, datasetChangelogTitle)
+	}
+	changelog := datasetChangelogTitle + "\n" + entry
+	if existing = strings.TrimLeft(existing, "\n"); existing != "" {
+		changelog += "\n" + existing
+	}
+	if err := c.output().WriteFile(datasetChangelogFile, []byte(changelog)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", datasetChangelogFile, err)
+	}
+	fmt.Fprintf(c.out, "Dataset version %s, changes added to %s\n", c.datasetVersion, datasetChangelogFile)
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDatasetVersion(t *testing.T) {
+	for _, s := range []string{"1.2.3", "v1.2.3"} {
+		if v, err := parseDatasetVersion(s); err != nil || v != (datasetVersion{1, 2, 3}) {
+			t.Errorf("%s: expected 1.2.3, got %v (%v)", s, v, err)
+		}
+	}
+	for _, s := range []string{"1.2", "1.2.x", "1.-2.3", "latest"} {
+		if _, err := parseDatasetVersion(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+func TestResolveDatasetVersion(t *testing.T) {
+	previous := &previousDataset{version: datasetVersion{1, 2, 3}}
+	tests := []struct {
+		flag     string
+		previous *previousDataset
+		want     string
+	}{
+		{"major", previous, "2.0.0"},
+		{"minor", previous, "1.3.0"},
+		{"patch", previous, "1.2.4"},
+		{"minor", nil, "0.1.0"},
+		{"3.0.0", previous, "3.0.0"},
+	}
+	for _, tt := range tests {
+		converter := NewConverter(Config{DatasetVersion: tt.flag})
+		converter.SetOutput(&strings.Builder{})
+		if got, err := converter.resolveDatasetVersion(tt.previous); err != nil || got.String() != tt.want {
+			t.Errorf("%s: expected %s, got %s (%v)", tt.flag, tt.want, got, err)
+		}
+	}
+}
+
+func TestConvertDatasetVersion(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "out")
+	convert := func(version string) string {
+		t.Helper()
+		var out strings.Builder
+		converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, Incremental: true, DatasetVersion: version})
+		converter.SetOutput(&out)
+		if err := converter.Convert(); err != nil {
+			t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+		}
+		return out.String()
+	}
+
+	convert("1.0.0")
+	// Drop image2 and add a person to image1
+	for _, name := range []string{"images/image2.png", "labels/image2.txt"} {
+		if err := os.Remove(filepath.Join(sourceDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image1.txt"), []byte("0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n1 0.6 0.6 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if log := convert("minor"); !strings.Contains(log, "Dataset version 1.1.0") {
+		t.Errorf("Expected version 1.1.0 in the output, got:\n%s", log)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil || !strings.Contains(string(data), "version: 1.1.0") {
+		t.Errorf("Expected data.yaml to record version 1.1.0, got %q (%v)", data, err)
+	}
+	changelog, err := os.ReadFile(filepath.Join(outputDir, datasetChangelogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(changelog)
+	for _, want := range []string{
+		"## 1.1.0 - ",
+		"- 2 images (2 train, 0 val), 5 annotations, 2 classes\n",
+		"- Images: 0 added, 1 removed, 0 moved between splits, 1 with changed annotations\n",
+		"- Annotations: book 3 -> 2 (-1), person 2 -> 3 (+1)\n",
+		"## 1.0.0 - ",
+		"- Initial version\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the changelog to contain %q, got:\n%s", want, text)
+		}
+	}
+	if !strings.HasPrefix(text, datasetChangelogTitle) || strings.Index(text, "## 1.1.0") > strings.Index(text, "## 1.0.0") {
+		t.Errorf("Expected the newest entry first, got:\n%s", text)
+	}
+}
+
+func TestValidateDatasetVersion(t *testing.T) {
+	for _, config := range []Config{
+		{DatasetVersion: "next"},
+		{DatasetVersion: "1.0.0", Stream: true},
+		{DatasetVersion: "1.0.0", OutputFormat: OutputTFRecord},
+	} {
+		config.SourceDir, config.OutputDir = t.TempDir(), t.TempDir()
+		converter := NewConverter(config)
+		converter.SetOutput(&strings.Builder{})
+		if err := converter.Convert(); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
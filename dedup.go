@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+)
+
+// Perceptual hashes of the dedup command, selected with -hash
+const (
+	DedupPHash = "phash"
+	DedupDHash = "dhash"
+)
+
+// maxClustersPrinted limits the clusters listed in the dedup summary
+const maxClustersPrinted = 10
+
+// DedupOptions configure the near-duplicate clustering of the dedup command
+type DedupOptions struct {
+	// Hash is the perceptual hash images are compared by: phash or dhash
+	Hash string
+	// Distance is how many bits the hashes of near-duplicates may differ in
+	Distance int
+	// ClustersFile receives the clusters as JSON when set
+	ClustersFile string
+	// DryRun only reports the clusters instead of converting
+	DryRun bool
+}
+
+// DedupReport summarizes a deduplication: the clusters of near-duplicate
+// images and what dropping all but one image per cluster saves
+type DedupReport struct {
+	Hash     string `json:"hash"`
+	Distance int    `json:"distance"`
+	Images   int    `json:"images"`
+	Kept     int    `json:"kept"`
+	Dropped  int    `json:"dropped"`
+	// Undecoded images were only compared by content
+	Undecoded  int            `json:"undecoded"`
+	Bytes      int64          `json:"bytes"`
+	BytesSaved int64          `json:"bytes_saved"`
+	Classes    []DedupClass   `json:"classes"`
+	Clusters   []DedupCluster `json:"clusters"`
+}
+
+// DedupClass compares the annotations of a class before and after dropping
+// the duplicates, showing how much duplicates over-weighted the class
+type DedupClass struct {
+	Name   string `json:"name"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+// DedupCluster is a representative image and its near-duplicates. Only
+// clusters with duplicates are reported.
+type DedupCluster struct {
+	Representative string           `json:"representative"`
+	Duplicates     []DedupDuplicate `json:"duplicates"`
+}
+
+// DedupDuplicate is a dropped image with its distance to the representative
+type DedupDuplicate struct {
+	Image     string `json:"image"`
+	Distance  int    `json:"distance"`
+	Identical bool   `json:"identical"`
+}
+
+// SetDedup makes the conversion drop near-duplicate images, keeping one
+// representative per cluster
+func (c *Converter) SetDedup(o DedupOptions) {
+	c.dedup = &o
+}
+
+// validateDedup checks the dedup options before the conversion starts
+func (c *Converter) validateDedup() error {
+	if c.dedup == nil {
+		return nil
+	}
+	if c.dedup.Hash != DedupPHash && c.dedup.Hash != DedupDHash {
+		return fmt.Errorf("unknown hash %q (expected phash or dhash)", c.dedup.Hash)
+	}
+	if c.dedup.Distance < 0 || c.dedup.Distance > 64 {
+		return fmt.Errorf("-distance must be between 0 and 64")
+	}
+	if c.config.Stream || c.config.Classify || c.config.MultiLabel {
+		return fmt.Errorf("dedup cannot be combined with -stream, -classify or -multi-label")
+	}
+	return nil
+}
+
+// dedupCandidate is a pair with what decides whether it represents its
+// cluster
+type dedupCandidate struct {
+	index       int
+	annotations int
+	size        int64
+}
+
+// dedupImages clusters near-duplicate images and keeps the one with the most
+// annotations, then the largest file, of every cluster. An image joins the
+// cluster of the first representative within the distance, so a cluster
+// can't drift through a chain of small edits.
+func (c *Converter) dedupImages(classes []string, pairs []LabelPair) ([]LabelPair, error) {
+	o := c.dedup
+	fmt.Fprintf(c.out, "\nHashing %d images for near-duplicates...\n", len(pairs))
+	hashes, err := c.hashPairs(pairs)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]map[int]int, len(pairs))
+	candidates := make([]dedupCandidate, len(pairs))
+	report := &DedupReport{Hash: o.Hash, Distance: o.Distance, Images: len(pairs), Clusters: []DedupCluster{}}
+	for i, pair := range pairs {
+		counts[i] = c.labelClassCounts(pair.LabelPath)
+		candidates[i] = dedupCandidate{index: i}
+		for _, n := range counts[i] {
+			candidates[i].annotations += n
+		}
+		if info, err := os.Stat(pair.ImagePath); err == nil {
+			candidates[i].size = info.Size()
+		}
+		report.Bytes += candidates[i].size
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.annotations != b.annotations {
+			return a.annotations > b.annotations
+		}
+		if a.size != b.size {
+			return a.size > b.size
+		}
+		return filepath.Base(pairs[a.index].ImagePath) < filepath.Base(pairs[b.index].ImagePath)
+	})
+
+	// Clusters are numbered by representative; the index holds their hashes
+	var clusters []DedupCluster
+	byContent := make(map[[sha256.Size]byte]int)
+	var index hashIndex
+	keep := make([]bool, len(pairs))
+	for _, candidate := range candidates {
+		h := hashes[candidate.index]
+		name := filepath.Base(pairs[candidate.index].ImagePath)
+		perceptual := h.PHash
+		if o.Hash == DedupDHash {
+			perceptual = h.DHash
+		}
+
+		cluster, distance, identical := -1, 0, false
+		if id, ok := byContent[h.Content]; ok {
+			cluster, identical = id, true
+		} else if !h.Decoded {
+			report.Undecoded++
+		} else if id, d, ok := index.nearest(perceptual, o.Distance); ok {
+			cluster, distance = id, d
+		}
+		if cluster < 0 {
+			cluster = len(clusters)
+			clusters = append(clusters, DedupCluster{Representative: name})
+			byContent[h.Content] = cluster
+			if h.Decoded {
+				index.add(perceptual, cluster)
+			}
+			keep[candidate.index] = true
+			continue
+		}
+		clusters[cluster].Duplicates = append(clusters[cluster].Duplicates, DedupDuplicate{Image: name, Distance: distance, Identical: identical})
+		report.BytesSaved += candidate.size
+	}
+
+	before := make([]int, len(classes))
+	after := make([]int, len(classes))
+	var kept []LabelPair
+	for i, pair := range pairs {
+		for classID, n := range counts[i] {
+			if classID < 0 || classID >= len(classes) {
+				continue
+			}
+			before[classID] += n
+			if keep[i] {
+				after[classID] += n
+			}
+		}
+		if keep[i] {
+			kept = append(kept, pair)
+		}
+	}
+	for i, name := range classes {
+		report.Classes = append(report.Classes, DedupClass{Name: name, Before: before[i], After: after[i]})
+	}
+	for _, cluster := range clusters {
+		if len(cluster.Duplicates) > 0 {
+			report.Clusters = append(report.Clusters, cluster)
+		}
+	}
+	sort.SliceStable(report.Clusters, func(i, j int) bool {
+		return len(report.Clusters[i].Duplicates) > len(report.Clusters[j].Duplicates)
+	})
+	report.Kept = len(kept)
+	report.Dropped = len(pairs) - len(kept)
+
+	report.print(c.out)
+	if o.ClustersFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode clusters: %w", err)
+		}
+		if err := os.WriteFile(o.ClustersFile, append(data, '\n'), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write clusters: %w", err)
+		}
+		fmt.Fprintf(c.out, "Wrote the clusters to %s\n", o.ClustersFile)
+	}
+	return kept, nil
+}
+
+// print writes the dedup summary: the savings, the largest clusters and how
+// the class balance changes
+func (r *DedupReport) print(w io.Writer) {
+	share := 0.0
+	if r.Images > 0 {
+		share = float64(r.Dropped) / float64(r.Images) * 100
+	}
+	fmt.Fprintf(w, "Found %d clusters of near-duplicates (%s, distance %d): keeping %d of %d images, dropping %d (%.1f%%)\n",
+		len(r.Clusters), r.Hash, r.Distance, r.Kept, r.Images, r.Dropped, share)
+	fmt.Fprintf(w, "Space saved: %s of %s\n", formatBytes(uint64(r.BytesSaved)), formatBytes(uint64(r.Bytes)))
+	if r.Undecoded > 0 {
+		fmt.Fprintf(w, "Warning: %d images could not be decoded and were only compared by content\n", r.Undecoded)
+	}
+
+	for i, cluster := range r.Clusters {
+		if i == 0 {
+			fmt.Fprintln(w, "Largest clusters:")
+		}
+		if i == maxClustersPrinted {
+			fmt.Fprintf(w, "  ... and %d more\n", len(r.Clusters)-maxClustersPrinted)
+			break
+		}
+		fmt.Fprintf(w, "  %s: %d duplicates\n", cluster.Representative, len(cluster.Duplicates))
+	}
+
+	if r.Dropped == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Annotations per class:")
+	for _, class := range r.Classes {
+		change := 0.0
+		if class.Before > 0 {
+			change = float64(class.After-class.Before) / float64(class.Before) * 100
+		}
+		fmt.Fprintf(w, "  %-20s %6d -> %6d (%+.1f%%)\n", class.Name, class.Before, class.After, change)
+	}
+}
+
+// runDedup clusters the near-duplicate images of an export and converts it
+// with one representative per cluster
+func runDedup(args []string) error {
+	var config Config
+	var o DedupOptions
+	fs := flag.NewFlagSet("dedup", flag.ExitOnError)
+	registerPathFlags(fs, &config)
+	registerConvertFlags(fs, &config)
+	fs.StringVar(&o.Hash, "hash", DedupPHash, "Perceptual hash to compare images by: phash or dhash")
+	fs.IntVar(&o.Distance, "distance", 8, "Bits the hashes of near-duplicate images may differ in (0-64)")
+	fs.StringVar(&o.ClustersFile, "clusters", "", "Write the clusters of near-duplicates as JSON to this file")
+	fs.BoolVar(&o.DryRun, "dry-run", false, "Only report the clusters without converting")
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	converter := NewConverter(config)
+	converter.SetDedup(o)
+	return converter.ConvertContext(ctx)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPerceptualHash(t *testing.T) {
+	dir := t.TempDir()
+	waves := func(x, y int) float64 { return math.Sin(float64(x)/6) * math.Cos(float64(y)/5) }
+	writePatternPNG(t, filepath.Join(dir, "a.png"), func(x, y int) uint8 { return uint8(128 + 100*waves(x, y)) })
+	// Brighter and with less contrast, but the same picture
+	writePatternPNG(t, filepath.Join(dir, "b.png"), func(x, y int) uint8 { return uint8(170 + 50*waves(x, y)) })
+	writePatternPNG(t, filepath.Join(dir, "c.png"), func(x, y int) uint8 { return uint8(255 - y*5) })
+
+	var hashes []imageHashes
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		h, err := hashImageFile(filepath.Join(dir, name))
+		if err != nil || !h.Decoded {
+			t.Fatalf("Failed to hash %s: %v", name, err)
+		}
+		hashes = append(hashes, h)
+	}
+	if d := hashDistance(hashes[0].PHash, hashes[1].PHash); d > 4 {
+		t.Errorf("Expected the edited copy within 4 bits, got %d", d)
+	}
+	if d := hashDistance(hashes[0].PHash, hashes[2].PHash); d < 16 {
+		t.Errorf("Expected a different picture at least 16 bits away, got %d", d)
+	}
+}
+
+func TestHashIndex(t *testing.T) {
+	var index hashIndex
+	hashes := []uint64{0x0, 0xff, 0xf0f0, 0xffff_ffff, 0x1}
+	for i, h := range hashes {
+		index.add(h, i)
+	}
+	tests := []struct {
+		hash     uint64
+		max      int
+		id, dist int
+		ok       bool
+	}{
+		{0x0, 0, 0, 0, true},
+		// 0x3 is one bit from 0x1 and two from 0x0
+		{0x3, 1, 4, 1, true},
+		{0xfe, 2, 1, 1, true},
+		{0xffff_0000_0000, 8, 0, 0, false},
+	}
+	for _, tt := range tests {
+		id, dist, ok := index.nearest(tt.hash, tt.max)
+		if ok != tt.ok || (ok && (id != tt.id || dist != tt.dist)) {
+			t.Errorf("nearest(%x, %d): expected %d at %d (%v), got %d at %d (%v)", tt.hash, tt.max, tt.id, tt.dist, tt.ok, id, dist, ok)
+		}
+	}
+}
+
+func TestRunDedup(t *testing.T) {
+	// a and c are identical, b and d near-identical, e unrelated
+	sourceDir := writeLeakySource(t)
+	// d has more annotations, so it represents its cluster
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "d.txt"), []byte("0 0.5 0.5 0.2 0.2\n0 0.2 0.2 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+	clustersPath := filepath.Join(t.TempDir(), "clusters.json")
+
+	err := runDedup([]string{"-source", sourceDir, "-output", outputDir, "-train-split", "1", "-hash", "dhash", "-distance", "5", "-clusters", clustersPath})
+	if err != nil {
+		t.Fatalf("Dedup failed: %v", err)
+	}
+
+	var report DedupReport
+	data, err := os.ReadFile(clustersPath)
+	if err != nil || json.Unmarshal(data, &report) != nil {
+		t.Fatalf("Expected a clusters file, got %s (%v)", data, err)
+	}
+	if report.Images != 5 || report.Kept != 3 || report.Dropped != 2 || len(report.Clusters) != 2 || report.BytesSaved == 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	representatives := map[string]DedupDuplicate{}
+	for _, cluster := range report.Clusters {
+		representatives[cluster.Representative] = cluster.Duplicates[0]
+	}
+	if dup, ok := representatives["d.png"]; !ok || dup.Image != "b.png" || dup.Identical {
+		t.Errorf("Expected d.png to represent b.png, got %+v", report.Clusters)
+	}
+	if dup, ok := representatives["a.png"]; !ok || dup.Image != "c.png" || !dup.Identical {
+		t.Errorf("Expected a.png to represent its identical copy c.png, got %+v", report.Clusters)
+	}
+	if report.Classes[0].Before != 6 || report.Classes[0].After != 4 {
+		t.Errorf("Expected 6 annotations before and 4 after, got %+v", report.Classes)
+	}
+
+	train, _ := os.ReadDir(filepath.Join(outputDir, "images", "train"))
+	var names []string
+	for _, entry := range train {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "a.png,d.png,e.png" {
+		t.Errorf("Expected the representatives a, d and e, got %v", names)
+	}
+
+	dryRunDir := filepath.Join(t.TempDir(), "out")
+	if err := runDedup([]string{"-source", sourceDir, "-output", dryRunDir, "-dry-run"}); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if _, err := os.Stat(dryRunDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the dry run not to write a dataset, got %v", err)
+	}
+	if err := runDedup([]string{"-source", sourceDir, "-hash", "ahash"}); err == nil || !strings.Contains(err.Error(), "unknown hash") {
+		t.Errorf("Expected an error for an unknown hash, got %v", err)
+	}
+}
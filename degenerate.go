@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// degenerateRegion checks that a region has an extent and lies on the image.
+// Widths or heights up to -degenerate-epsilon count as zero. With the image
+// size known, the region's edges are rounded to whole pixels, so a box that
+// only grazes the image border is outside. It returns the rule and message of
+// the problem, or empty strings.
+func (c *Converter) degenerateRegion(bounds [4]float64, width, height int) (string, string) {
+	if epsilon := c.config.DegenerateEpsilon; bounds[2] <= epsilon || bounds[3] <= epsilon {
+		return "degenerate-box", fmt.Sprintf("Degenerate box of size %g x %g", bounds[2], bounds[3])
+	}
+
+	left, right := bounds[0]-bounds[2]/2, bounds[0]+bounds[2]/2
+	top, bottom := bounds[1]-bounds[3]/2, bounds[1]+bounds[3]/2
+	maxX, maxY := 1.0, 1.0
+	if width > 0 && height > 0 {
+		maxX, maxY = float64(width), float64(height)
+		left, right = math.Round(left*maxX), math.Round(right*maxX)
+		top, bottom = math.Round(top*maxY), math.Round(bottom*maxY)
+	}
+	if math.Min(right, maxX) <= math.Max(left, 0) || math.Min(bottom, maxY) <= math.Max(top, 0) {
+		return "box-outside-image", "Box lies outside the image"
+	}
+	return "", ""
+}
+
+// dropDegenerateLabels removes zero-area regions and regions outside their
+// image. Image sizes are read from the image headers; when an image can't be
+// read its regions are checked in normalized coordinates.
+func (c *Converter) dropDegenerateLabels(pairs []LabelPair) ([]LabelPair, error) {
+	pairs, dropped, files, err := c.rewriteLabels(pairs, "nondegenerate", func(pair LabelPair, lines []string) ([]string, int) {
+		var kept []string
+		width, height := -1, -1
+		dropped := 0
+		for _, line := range lines {
+			parts := strings.Fields(line)
+			if len(parts) == 0 {
+				kept = append(kept, line)
+				continue
+			}
+			kind, warning := c.parseLine(parts)
+			if warning != "" {
+				kept = append(kept, line)
+				continue
+			}
+			if width < 0 {
+				var err error
+				if width, height, err = readImageSize(pair.ImagePath); err != nil {
+					fmt.Fprintf(c.out, "Warning: Cannot read size of %s; checking its regions in normalized coordinates\n", filepath.Base(pair.ImagePath))
+				}
+			}
+			if rule, _ := c.degenerateRegion(labelBounds(kind, parts), width, height); rule != "" {
+				dropped++
+				continue
+			}
+			kept = append(kept, line)
+		}
+		return kept, dropped
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dropped > 0 {
+		fmt.Fprintf(c.out, "Dropped %d degenerate regions from %d label files\n", dropped, files)
+	}
+	return pairs, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDegenerateRegion(t *testing.T) {
+	converter := NewConverter(Config{DegenerateEpsilon: 0.001})
+	tests := []struct {
+		name          string
+		bounds        [4]float64
+		width, height int
+		want          string
+	}{
+		{"normal box", [4]float64{0.5, 0.5, 0.2, 0.2}, 0, 0, ""},
+		{"zero width", [4]float64{0.5, 0.5, 0, 0.2}, 0, 0, "degenerate-box"},
+		{"below epsilon", [4]float64{0.5, 0.5, 0.2, 0.0005}, 0, 0, "degenerate-box"},
+		{"edge box in normalized coordinates", [4]float64{1, 0.5, 0.002, 0.2}, 0, 0, ""},
+		{"edge box rounds outside the image", [4]float64{1, 0.5, 0.002, 0.2}, 200, 100, "box-outside-image"},
+		{"edge box with a pixel inside", [4]float64{1, 0.5, 0.02, 0.2}, 200, 100, ""},
+		{"corner box", [4]float64{0, 0, 0.004, 0.004}, 200, 100, "box-outside-image"},
+	}
+	for _, tt := range tests {
+		if got, _ := converter.degenerateRegion(tt.bounds, tt.width, tt.height); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestDegenerateRegionsAreReported(t *testing.T) {
+	dir := t.TempDir()
+	labelPath := filepath.Join(dir, "flat.txt")
+	content := "0 0.5 0.5 0.2 0.2\n0 0.5 0.5 0 0.2\n0 0.5 0.5 0.2 0\n"
+	if err := os.WriteFile(labelPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{SourceDir: dir})
+	converter.SetOutput(io.Discard)
+	stats, err := converter.ValidateLabels([]LabelPair{{LabelPath: labelPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.DegenerateAnnotations != 2 || stats.InvalidLines != 0 {
+		t.Errorf("Expected 2 degenerate regions, got %+v", *stats)
+	}
+	if len(converter.findings) != 2 || converter.findings[0].Rule != "degenerate-box" || converter.findings[0].Line != 2 {
+		t.Errorf("Unexpected findings %+v", converter.findings)
+	}
+}
+
+func TestDropDegenerateLabels(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "edge.png")
+	writeTestImage(t, imagePath, 200, 100)
+	labelPath := filepath.Join(dir, "edge.txt")
+	content := "0 0.5 0.5 0.2 0.2\n0 0.5 0.5 0 0.2\n1 1 0.5 0.002 0.2\n"
+	if err := os.WriteFile(labelPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{DropDegenerate: true})
+	var out strings.Builder
+	converter.SetOutput(&out)
+	defer converter.removeStaging()
+
+	pairs, err := converter.dropDegenerateLabels([]LabelPair{{ImagePath: imagePath, LabelPath: labelPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(pairs[0].LabelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0 0.5 0.5 0.2 0.2\n"; string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+	if !strings.Contains(out.String(), "Dropped 2 degenerate regions from 1 label files") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+}
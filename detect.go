@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// InputAuto makes the converter detect the input format from the source
+const InputAuto = "auto"
+
+// resolveInputFormat replaces -input-format auto with the format detected
+// in the source directory
+func (c *Converter) resolveInputFormat() error {
+	if c.config.InputFormat != InputAuto {
+		return nil
+	}
+	format, reason, err := detectInputFormat(c.config.SourceDir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Detected input format: %s (%s)\n", format, reason)
+	c.config.InputFormat = format
+	return nil
+}
+
+// detectInputFormat inspects a source directory and returns the input
+// format of its export with the evidence for it. A labels/ directory means
+// a YOLO export; otherwise the annotation files at the top of the directory
+// are sniffed by their content. COCO and Pascal VOC datasets are recognized
+// and reported, as there is no parser for them.
+func detectInputFormat(dir string) (string, string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	if !info.IsDir() {
+		return "", "", fmt.Errorf("%w: %s is not a directory", ErrInvalidSource, dir)
+	}
+	if isDir(filepath.Join(dir, "labels")) {
+		return InputYOLO, "labels/ directory", nil
+	}
+	if isDir(filepath.Join(dir, "Annotations")) && isDir(filepath.Join(dir, "JPEGImages")) {
+		return "", "", fmt.Errorf("%w: %s looks like a Pascal VOC dataset (Annotations/ and JPEGImages/), which can't be read; use one of the -input-format options", ErrInvalidSource, dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	formats := make(map[string][]string)
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == "notes.json" || entry.Name() == "project.json" {
+			continue
+		}
+		format, err := sniffExportFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return "", "", err
+		}
+		if format != "" {
+			formats[format] = append(formats[format], entry.Name())
+		}
+	}
+
+	names := make([]string, 0, len(formats))
+	for format := range formats {
+		names = append(names, format)
+	}
+	sort.Strings(names)
+	switch len(names) {
+	case 0:
+		return "", "", fmt.Errorf("%w: can't tell the input format of %s: no labels/ directory or known export file; set -input-format", ErrInvalidSource, dir)
+	case 1:
+		return names[0], strings.Join(formats[names[0]], ", "), nil
+	}
+	var found []string
+	for _, format := range names {
+		found = append(found, fmt.Sprintf("%s (%s)", format, strings.Join(formats[format], ", ")))
+	}
+	return "", "", fmt.Errorf("%w: %s holds exports of several formats: %s; choose one with -input-format", ErrInvalidSource, dir, strings.Join(found, ", "))
+}
+
+// isDir reports whether path is a directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// sniffExportFile returns the input format of an annotation file from its
+// name and first bytes, or "" when it isn't an export. COCO JSON and VOC XML
+// files are errors, since they can't be converted.
+func sniffExportFile(path string) (string, error) {
+	name := strings.ToLower(filepath.Base(path))
+	switch filepath.Ext(name) {
+	case ".ndjson":
+		line, err := firstLine(path)
+		if err != nil {
+			return "", err
+		}
+		if bytes.Contains(line, []byte(`"data_row"`)) {
+			return InputLabelbox, nil
+		}
+	case ".csv":
+		if strings.HasSuffix(name, "annotations-bbox.csv") {
+			return InputOpenImages, nil
+		}
+		line, err := firstLine(path)
+		if err != nil {
+			return "", err
+		}
+		if bytes.Contains(line, []byte("region_shape_attributes")) {
+			return InputVIA, nil
+		}
+	case ".xml":
+		root, err := xmlRoot(path)
+		if err != nil {
+			return "", err
+		}
+		switch root {
+		case "annotations":
+			return InputCVAT, nil
+		case "annotation":
+			return "", fmt.Errorf("%w: %s is a Pascal VOC annotation, which can't be read; use one of the -input-format options", ErrInvalidSource, path)
+		}
+	case ".json":
+		return sniffJSON(path)
+	}
+	return "", nil
+}
+
+// firstLine returns the first line of a file, up to 64 KiB of it
+func firstLine(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	line, err := bufio.NewReaderSize(file, 64*1024).ReadSlice('\n')
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	return line, nil
+}
+
+// xmlRoot returns the name of the root element of an XML file
+func xmlRoot(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	decoder := xml.NewDecoder(file)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			// Not XML after all
+			return "", nil
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// sniffJSON tells Label Studio task lists, VIA projects and exports and COCO
+// files apart by the keys of the first task or of the top-level object. Values
+// are skipped token by token, so large exports are never held in memory.
+func sniffJSON(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	token, err := decoder.Token()
+	if err != nil {
+		return "", nil
+	}
+	switch token {
+	case json.Delim('['):
+		if !decoder.More() {
+			return "", nil
+		}
+		keys, err := objectKeys(decoder)
+		if err != nil {
+			return "", nil
+		}
+		if keys["data"] {
+			return InputLSJSON, nil
+		}
+	case json.Delim('{'):
+		keys := make(map[string]bool)
+		for first := true; decoder.More(); first = false {
+			key, err := decoder.Token()
+			if err != nil {
+				return "", nil
+			}
+			keys[key.(string)] = true
+			if keys["_via_img_metadata"] {
+				return InputVIA, nil
+			}
+			if keys["images"] && keys["annotations"] && keys["categories"] {
+				return "", fmt.Errorf("%w: %s is a COCO dataset, which can't be read; use one of the -input-format options", ErrInvalidSource, path)
+			}
+
+			// A VIA JSON export maps image IDs to images with a filename
+			// and regions
+			if first {
+				image, err := objectKeys(decoder)
+				if err != nil {
+					return "", nil
+				}
+				if image["filename"] && image["regions"] {
+					return InputVIA, nil
+				}
+				continue
+			}
+			if err := skipValue(decoder); err != nil {
+				return "", nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// objectKeys reads past the next value, returning its keys if it is an object
+func objectKeys(decoder *json.Decoder) (map[string]bool, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('['):
+		return nil, skipRest(decoder, 1)
+	case json.Delim('{'):
+	default:
+		return nil, nil
+	}
+
+	keys := make(map[string]bool)
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys[key.(string)] = true
+		if err := skipValue(decoder); err != nil {
+			return nil, err
+		}
+	}
+	// The closing brace
+	_, err = decoder.Token()
+	return keys, err
+}
+
+// skipValue reads past the next value without keeping it
+func skipValue(decoder *json.Decoder) error {
+	return skipRest(decoder, 0)
+}
+
+// skipRest reads tokens until depth open objects and arrays are closed and,
+// at depth 0, past one whole value
+func skipRest(decoder *json.Decoder, depth int) error {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectInputFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		format string
+		err    string
+	}{
+		{
+			name:   "yolo",
+			files:  map[string]string{"labels/a.txt": "0 0.5 0.5 0.1 0.1\n", "images/a.jpg": "", "classes.txt": "car\n"},
+			format: InputYOLO,
+		},
+		{
+			name:   "label studio json",
+			files:  map[string]string{"export.json": `[{"id": 1, "data": {"image": "a.jpg"}, "annotations": []}]`, "notes.json": `{"categories": []}`},
+			format: InputLSJSON,
+		},
+		{
+			name:   "cvat",
+			files:  map[string]string{"annotations.xml": `<?xml version="1.0"?><annotations><version>1.1</version></annotations>`},
+			format: InputCVAT,
+		},
+		{
+			name:   "open images",
+			files:  map[string]string{"train-annotations-bbox.csv": "ImageID,Source,LabelName\n", "class-descriptions-boxable.csv": "/m/0k4j,Car\n"},
+			format: InputOpenImages,
+		},
+		{
+			name:   "via project",
+			files:  map[string]string{"via_project.json": viaProjectFile},
+			format: InputVIA,
+		},
+		{
+			name:   "via json export",
+			files:  map[string]string{"export.json": `{"a.jpg100": {"filename": "a.jpg", "size": 100, "regions": []}}`},
+			format: InputVIA,
+		},
+		{
+			name:   "via csv export",
+			files:  map[string]string{"export.csv": "#filename,file_size,file_attributes,region_count,region_id,region_shape_attributes,region_attributes\n"},
+			format: InputVIA,
+		},
+		{
+			name:   "labelbox",
+			files:  map[string]string{"export.ndjson": `{"data_row": {"id": "r1"}, "projects": {}}` + "\n"},
+			format: InputLabelbox,
+		},
+		{
+			name:  "coco",
+			files: map[string]string{"instances.json": `{"info": {}, "images": [], "annotations": [], "categories": []}`},
+			err:   "COCO",
+		},
+		{
+			name:  "coco with images first",
+			files: map[string]string{"instances.json": `{"images": [{"id": 1, "file_name": "a.jpg"}], "annotations": [{"bbox": [1, 2, 3, 4], "segmentation": [[1, 2]]}], "categories": [{"id": 1}]}`},
+			err:   "COCO",
+		},
+		{
+			name:  "pascal voc",
+			files: map[string]string{"Annotations/a.xml": "<annotation/>", "JPEGImages/a.jpg": ""},
+			err:   "Pascal VOC",
+		},
+		{
+			name:  "pascal voc file",
+			files: map[string]string{"a.xml": "<annotation><filename>a.jpg</filename></annotation>"},
+			err:   "Pascal VOC",
+		},
+		{
+			name:  "several formats",
+			files: map[string]string{"export.json": `[{"data": {}}]`, "annotations.xml": "<annotations/>"},
+			err:   "cvat (annotations.xml), ls-json (export.json)",
+		},
+		{
+			name:  "unknown",
+			files: map[string]string{"images/a.jpg": "", "readme.txt": "hello"},
+			err:   "can't tell the input format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			format, _, err := detectInputFormat(dir)
+			if tt.err != "" {
+				if err == nil || !errors.Is(err, ErrInvalidSource) || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Expected an invalid source error containing %q, got %q, %v", tt.err, format, err)
+				}
+				return
+			}
+			if err != nil || format != tt.format {
+				t.Errorf("Expected %s, got %q, %v", tt.format, format, err)
+			}
+		})
+	}
+}
+
+func TestConvertDetectsInputFormat(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: filepath.Join(t.TempDir(), "out"), TrainSplit: 0.8, Seed: 42, InputFormat: InputAuto})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Detected input format: yolo (labels/ directory)") {
+		t.Errorf("Expected the detected format in the output, got:\n%s", out.String())
+	}
+	if converter.config.InputFormat != InputYOLO {
+		t.Errorf("Expected the detected format to be kept, got %q", converter.config.InputFormat)
+	}
+}
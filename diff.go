@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// diffListLimit is the number of image names listed per section of a
+// markdown diff
+const diffListLimit = 50
+
+// datasetSummary is what a dataset diff compares: the split and class counts
+// of every image, keyed by image file name
+type datasetSummary struct {
+	splits map[string]string
+	counts map[string]map[string]int
+}
+
+// ClassDelta is the change in annotations of one class between datasets
+type ClassDelta struct {
+	Class    string  `json:"class"`
+	Old      int     `json:"old"`
+	New      int     `json:"new"`
+	Delta    int     `json:"delta"`
+	OldShare float64 `json:"old_share"`
+	NewShare float64 `json:"new_share"`
+}
+
+// DatasetDiff compares two datasets. Images are matched by file name and
+// classes by name, so renumbered classes compare correctly.
+type DatasetDiff struct {
+	Old       string `json:"old"`
+	New       string `json:"new"`
+	OldImages int    `json:"old_images"`
+	NewImages int    `json:"new_images"`
+	// Added and Removed list images only in the new or old dataset
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	// Moved lists images that changed split, Changed images whose
+	// annotation counts per class changed
+	Moved   []string     `json:"moved"`
+	Changed []string     `json:"changed"`
+	Classes []ClassDelta `json:"classes"`
+	// TotalVariation and JSDivergence measure the drift of the class
+	// distribution, from 0 (identical) to 1
+	TotalVariation float64 `json:"total_variation"`
+	JSDivergence   float64 `json:"js_divergence"`
+}
+
+// countLabelClasses counts the label lines of every class in a label file,
+// naming the classes; a missing file counts as empty
+func countLabelClasses(labelPath string, classes []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	file, err := os.Open(labelPath)
+	if os.IsNotExist(err) {
+		return counts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open label %s: %w", labelPath, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 5 {
+			continue
+		}
+		if classID, err := strconv.Atoi(parts[0]); err == nil {
+			counts[className(classes, classID)]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read label %s: %w", labelPath, err)
+	}
+	return counts, nil
+}
+
+// summarizeDataset reads a converted dataset (a directory with data.yaml) or
+// a Label Studio YOLO export (classes.txt, images/ and labels/)
+func summarizeDataset(dir string) (*datasetSummary, error) {
+	summary := &datasetSummary{splits: make(map[string]string), counts: make(map[string]map[string]int)}
+
+	dataYAML := filepath.Join(dir, "data.yaml")
+	if _, err := os.Stat(dataYAML); err == nil {
+		classes, err := loadClassNames(dataYAML)
+		if err != nil {
+			return nil, err
+		}
+		for _, split := range []string{"train", "val"} {
+			images, err := previewImages(dir, split)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range images {
+				labelPath := filepath.Join(dir, "labels", split, strings.TrimSuffix(name, filepath.Ext(name))+".txt")
+				if summary.counts[name], err = countLabelClasses(labelPath, classes); err != nil {
+					return nil, err
+				}
+				summary.splits[name] = split
+			}
+		}
+		return summary, nil
+	}
+
+	c := NewConverter(Config{SourceDir: dir})
+	c.SetOutput(io.Discard)
+	if err := c.ValidateSourceStructure(); err != nil {
+		return nil, fmt.Errorf("%s is neither a converted dataset nor an export: %w", dir, err)
+	}
+	classes, err := c.LoadClasses()
+	if err != nil {
+		return nil, err
+	}
+	pairs, err := c.GetImageLabelPairs()
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		name := filepath.Base(pair.ImagePath)
+		if summary.counts[name], err = countLabelClasses(pair.LabelPath, classes); err != nil {
+			return nil, err
+		}
+		summary.splits[name] = ""
+	}
+	return summary, nil
+}
+
+// totals sums the class counts of every image
+func (s *datasetSummary) totals() map[string]int {
+	totals := make(map[string]int)
+	for _, counts := range s.counts {
+		for class, n := range counts {
+			totals[class] += n
+		}
+	}
+	return totals
+}
+
+// DiffDatasets compares two datasets, each a converted dataset or an export
+func DiffDatasets(oldDir, newDir string) (*DatasetDiff, error) {
+	old, err := summarizeDataset(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := summarizeDataset(newDir)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffSummaries(old, updated)
+	diff.Old, diff.New = oldDir, newDir
+	return diff, nil
+}
+
+// diffSummaries compares the summaries of two datasets
+func diffSummaries(old, updated *datasetSummary) *DatasetDiff {
+	diff := &DatasetDiff{
+		OldImages: len(old.splits), NewImages: len(updated.splits),
+		Added: []string{}, Removed: []string{}, Moved: []string{}, Changed: []string{}, Classes: []ClassDelta{},
+	}
+	for name, split := range updated.splits {
+		oldSplit, ok := old.splits[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+			continue
+		// An export has no splits, so only moves between splits count
+		case oldSplit != split && oldSplit != "" && split != "":
+			diff.Moved = append(diff.Moved, name)
+		}
+		if !sameCounts(old.counts[name], updated.counts[name]) {
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range old.splits {
+		if _, ok := updated.splits[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	for _, names := range [][]string{diff.Added, diff.Removed, diff.Moved, diff.Changed} {
+		sort.Strings(names)
+	}
+
+	oldTotals, newTotals := old.totals(), updated.totals()
+	oldSum, newSum := 0, 0
+	classSet := make(map[string]bool)
+	for class, n := range oldTotals {
+		classSet[class] = true
+		oldSum += n
+	}
+	for class, n := range newTotals {
+		classSet[class] = true
+		newSum += n
+	}
+	for class := range classSet {
+		delta := ClassDelta{Class: class, Old: oldTotals[class], New: newTotals[class]}
+		delta.Delta = delta.New - delta.Old
+		if oldSum > 0 {
+			delta.OldShare = float64(delta.Old) / float64(oldSum)
+		}
+		if newSum > 0 {
+			delta.NewShare = float64(delta.New) / float64(newSum)
+		}
+		diff.Classes = append(diff.Classes, delta)
+
+		diff.TotalVariation += math.Abs(delta.NewShare-delta.OldShare) / 2
+		mean := (delta.OldShare + delta.NewShare) / 2
+		for _, p := range []float64{delta.OldShare, delta.NewShare} {
+			if p > 0 {
+				diff.JSDivergence += p * math.Log2(p/mean) / 2
+			}
+		}
+	}
+	sort.Slice(diff.Classes, func(i, j int) bool {
+		return diff.Classes[i].Class < diff.Classes[j].Class
+	})
+	return diff
+}
+
+// sameCounts reports whether two images have the same class counts
+func sameCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for class, n := range a {
+		if b[class] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// writeMarkdown writes the diff as a markdown report for dataset reviews
+func (d *DatasetDiff) writeMarkdown(w io.Writer) {
+	fmt.Fprintf(w, "# Dataset diff\n\n")
+	fmt.Fprintf(w, "`%s` (%d images) -> `%s` (%d images)\n\n", d.Old, d.OldImages, d.New, d.NewImages)
+	fmt.Fprintf(w, "- %d images added, %d removed, %d moved between splits, %d with changed annotations\n",
+		len(d.Added), len(d.Removed), len(d.Moved), len(d.Changed))
+	fmt.Fprintf(w, "- Class distribution drift: total variation %.4f, Jensen-Shannon divergence %.4f\n\n", d.TotalVariation, d.JSDivergence)
+
+	fmt.Fprintf(w, "## Annotations per class\n\n")
+	fmt.Fprintf(w, "| Class | Old | New | Delta | Old share | New share |\n")
+	fmt.Fprintf(w, "|---|---:|---:|---:|---:|---:|\n")
+	for _, c := range d.Classes {
+		fmt.Fprintf(w, "| %s | %d | %d | %+d | %.1f%% | %.1f%% |\n", c.Class, c.Old, c.New, c.Delta, c.OldShare*100, c.NewShare*100)
+	}
+
+	for _, section := range []struct {
+		title string
+		names []string
+	}{
+		{"Added images", d.Added},
+		{"Removed images", d.Removed},
+		{"Moved images", d.Moved},
+		{"Changed images", d.Changed},
+	} {
+		if len(section.names) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n## %s\n\n", section.title)
+		for _, name := range section.names[:min(len(section.names), diffListLimit)] {
+			fmt.Fprintf(w, "- %s\n", name)
+		}
+		if len(section.names) > diffListLimit {
+			fmt.Fprintf(w, "- ... and %d more\n", len(section.names)-diffListLimit)
+		}
+	}
+}
+
+// runDiff is the entry point of the diff subcommand
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	oldDir := fs.String("old", "", "Earlier converted dataset or Label Studio export (required)")
+	newDir := fs.String("new", "", "Later converted dataset or Label Studio export (required)")
+	format := fs.String("format", "markdown", "Report format: markdown or json")
+	output := fs.String("output", "", "Write the report to this file (default: standard output)")
+	fs.Parse(args)
+
+	if *oldDir == "" || *newDir == "" {
+		return fmt.Errorf("-old and -new are required")
+	}
+	if *format != "markdown" && *format != "json" {
+		return fmt.Errorf("unknown format %q (use markdown or json)", *format)
+	}
+
+	diff, err := DiffDatasets(*oldDir, *newDir)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		return nil
+	}
+	diff.writeMarkdown(w)
+	return nil
+}
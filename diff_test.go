@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffDatasets(t *testing.T) {
+	oldDir := writePreviewDataset(t)
+	newDir := writePreviewDataset(t)
+	// c.png is removed, e.png added, a.png moved to val and b.png gains a car
+	for _, path := range []string{"images/train/c.png", "labels/train/c.txt"} {
+		if err := os.Remove(filepath.Join(newDir, path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestImage(t, filepath.Join(newDir, "images", "val", "e.png"), 64, 48)
+	for from, to := range map[string]string{"images/train/a.png": "images/val/a.png", "labels/train/a.txt": "labels/val/a.txt"} {
+		if err := os.Rename(filepath.Join(newDir, from), filepath.Join(newDir, to)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(newDir, "labels", "train", "b.txt"), []byte("1 0.5 0.5 0.5 0.5\n0 0.2 0.2 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := DiffDatasets(oldDir, newDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(diff.Added, []string{"e.png"}) || !reflect.DeepEqual(diff.Removed, []string{"c.png"}) ||
+		!reflect.DeepEqual(diff.Moved, []string{"a.png"}) || !reflect.DeepEqual(diff.Changed, []string{"b.png"}) {
+		t.Errorf("Unexpected image changes %+v", diff)
+	}
+	want := []ClassDelta{
+		{Class: "car", Old: 0, New: 1, Delta: 1, OldShare: 0, NewShare: 0.25},
+		{Class: "person", Old: 4, New: 3, Delta: -1, OldShare: 1, NewShare: 0.75},
+	}
+	if !reflect.DeepEqual(diff.Classes, want) {
+		t.Errorf("Expected class deltas %+v, got %+v", want, diff.Classes)
+	}
+	if diff.TotalVariation != 0.25 || diff.JSDivergence <= 0 || diff.JSDivergence >= 1 {
+		t.Errorf("Unexpected drift: total variation %v, JS divergence %v", diff.TotalVariation, diff.JSDivergence)
+	}
+
+	var out bytes.Buffer
+	diff.writeMarkdown(&out)
+	for _, line := range []string{"1 images added, 1 removed, 1 moved between splits, 1 with changed annotations", "| car | 0 | 1 | +1 | 0.0% | 25.0% |", "- e.png"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected %q in the report:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestDiffExportAgainstConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, dir := range []string{"images", "labels"} {
+		if err := os.MkdirAll(filepath.Join(sourceDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "classes.txt"), []byte("car\nperson\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		writeTestImage(t, filepath.Join(sourceDir, "images", name+".png"), 64, 48)
+		if err := os.WriteFile(filepath.Join(sourceDir, "labels", name+".txt"), []byte("1 0.5 0.5 0.5 0.5\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff, err := DiffDatasets(sourceDir, writePreviewDataset(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Moved)+len(diff.Changed) != 0 || diff.TotalVariation != 0 {
+		t.Errorf("Expected a conversion to match its export, got %+v", diff)
+	}
+}
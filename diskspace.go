@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// The free space kept on top of the dataset size: a share of the dataset for
+// file system overhead plus a fixed reserve, so the disk isn't left full
+const (
+	freeSpaceMargin  = 0.05
+	freeSpaceReserve = 64 << 20
+)
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// existingAncestor returns path or the nearest of its parents that exists
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// spaceNeeded sums the sizes of the source files, keyed by their
+// slash-separated name in the dataset. With a directory root, files that
+// already exist there are overwritten, so only growth counts, and with move
+// set, files on the root's file system are renamed and need no space.
+func spaceNeeded(root string, files map[string]string, move bool) uint64 {
+	rootDevice, hasDevice := fileDevice(existingAncestor(root))
+	var needed uint64
+	for name, src := range files {
+		info, err := os.Stat(src)
+		if err != nil {
+			// Missing files fail later with a clearer error
+			continue
+		}
+		if move && hasDevice {
+			if device, ok := fileDevice(src); ok && device == rootDevice {
+				continue
+			}
+		}
+		size := info.Size()
+		if existing, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err == nil {
+			size -= existing.Size()
+		}
+		if size > 0 {
+			needed += uint64(size)
+		}
+	}
+	return needed
+}
+
+// checkFreeSpace fails early when the file system of the output lacks the
+// space for the files about to be written, keyed by name in the dataset,
+// plus a margin. Platforms that can't report free space skip the check.
+func (c *Converter) checkFreeSpace(files map[string]string) error {
+	root, move := c.config.OutputDir, c.config.Move
+	if archiveFormat(root) != "" {
+		// Archives are written in full and moved sources are only removed
+		// afterwards; compression is ignored as images barely shrink
+		root, move = filepath.Dir(root), false
+	}
+	available, ok := freeSpace(existingAncestor(root))
+	if !ok {
+		return nil
+	}
+
+	needed := spaceNeeded(root, files, move)
+	if needed == 0 {
+		return nil
+	}
+	margin := uint64(float64(needed)*freeSpaceMargin) + freeSpaceReserve
+	if needed+margin > available {
+		return fmt.Errorf("not enough free space for the output: %s needed plus a %s margin, but only %s available at %s",
+			formatBytes(needed), formatBytes(margin), formatBytes(available), existingAncestor(root))
+	}
+	return nil
+}
+
+// pairFiles returns the source files of the split pairs keyed by their name
+// in the dataset
+func pairFiles(trainPairs, valPairs []LabelPair) map[string]string {
+	files := make(map[string]string)
+	for split, pairs := range map[string][]LabelPair{"train": trainPairs, "val": valPairs} {
+		for _, pair := range pairs {
+			files[path.Join("images", split, filepath.Base(pair.ImagePath))] = pair.ImagePath
+			files[path.Join("labels", split, filepath.Base(pair.LabelPath))] = pair.LabelPath
+		}
+	}
+	return files
+}
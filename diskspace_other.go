@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package main
+
+// freeSpace reports no free space information on platforms without statfs,
+// which skips the free space check
+func freeSpace(path string) (uint64, bool) {
+	return 0, false
+}
+
+// fileDevice reports no devices on platforms without stat device numbers
+func fileDevice(path string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[uint64]string{
+		512:           "512 B",
+		1536:          "1.5 KiB",
+		64 << 20:      "64.0 MiB",
+		3 << 30:       "3.0 GiB",
+		5<<40 + 1<<39: "5.5 TiB",
+	} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestSpaceNeeded(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "out")
+	if err := os.MkdirAll(filepath.Join(root, "labels", "train"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"a.jpg": 1000, "a.txt": 100, "b.jpg": 500} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An earlier copy of the label is overwritten, so only its growth counts
+	if err := os.WriteFile(filepath.Join(root, "labels", "train", "a.txt"), make([]byte, 40), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"images/train/a.jpg": filepath.Join(dir, "a.jpg"),
+		"labels/train/a.txt": filepath.Join(dir, "a.txt"),
+		"images/val/b.jpg":   filepath.Join(dir, "b.jpg"),
+		"images/val/c.jpg":   filepath.Join(dir, "missing.jpg"),
+	}
+	if got := spaceNeeded(root, files, false); got != 1560 {
+		t.Errorf("Expected 1560 bytes, got %d", got)
+	}
+
+	// Moves within one file system are renames
+	if _, ok := fileDevice(dir); ok {
+		if got := spaceNeeded(root, files, true); got != 0 {
+			t.Errorf("Expected moves on the same file system to need no space, got %d", got)
+		}
+	}
+}
+
+func TestExistingAncestor(t *testing.T) {
+	dir := t.TempDir()
+	if got := existingAncestor(filepath.Join(dir, "a", "b", "c")); got != dir {
+		t.Errorf("Expected %s, got %s", dir, got)
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Levels of doctor findings
+const (
+	DoctorOK      = "ok"
+	DoctorWarning = "warning"
+	DoctorError   = "error"
+)
+
+// doctorTimeout bounds each request the doctor sends to a service
+const doctorTimeout = 15 * time.Second
+
+// DoctorFinding is the result of one check, with a suggested fix for
+// warnings and errors
+type DoctorFinding struct {
+	Check   string `json:"check"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// doctor runs the checks for a conversion config and collects their findings
+type doctor struct {
+	ctx         context.Context
+	config      Config
+	labelStudio LabelStudioConfig
+	converter   *Converter
+	client      *http.Client
+	findings    []DoctorFinding
+}
+
+// Diagnose checks that a conversion with config can run: the source layout
+// and its files, the output location's write access, lock and free space,
+// the external tools the options need and the services they talk to,
+// including Label Studio when ls has a URL
+func Diagnose(ctx context.Context, config Config, ls LabelStudioConfig) []DoctorFinding {
+	converter := NewConverter(config)
+	converter.SetOutput(io.Discard)
+	d := &doctor{
+		ctx:         ctx,
+		config:      config,
+		labelStudio: ls,
+		converter:   converter,
+		client:      &http.Client{Timeout: doctorTimeout},
+	}
+
+	size := d.checkSource()
+	d.checkOutput(size)
+	d.checkTools()
+	d.checkServices()
+	return d.findings
+}
+
+func (d *doctor) add(check, level, message, fix string) {
+	d.findings = append(d.findings, DoctorFinding{Check: check, Level: level, Message: message, Fix: fix})
+}
+
+// checkSource checks the input format and counts the source files,
+// returning their total size
+func (d *doctor) checkSource() uint64 {
+	source := d.config.SourceDir
+	format := d.config.InputFormat
+	if format == "" || format == InputAuto {
+		detected, reason, err := detectInputFormat(source)
+		if err != nil {
+			d.add("source", DoctorError, err.Error(), "Point -source at the export directory, or set -input-format")
+			return 0
+		}
+		d.add("source", DoctorOK, fmt.Sprintf("Input format %s (%s)", detected, reason), "")
+		format = detected
+	} else if !isDir(source) {
+		d.add("source", DoctorError, fmt.Sprintf("%s is not a directory", source), "Point -source at the export directory")
+		return 0
+	}
+
+	if format != InputYOLO {
+		images, size := d.countImages(filepath.Join(source, "images"))
+		if images == 0 {
+			d.add("source", DoctorWarning, "No images in images/", "Images referenced by URL are only found if they exist below -source")
+		} else {
+			d.add("source", DoctorOK, fmt.Sprintf("%d images (%s) in images/", images, formatBytes(size)), "")
+		}
+		return size
+	}
+
+	if err := d.converter.ValidateSourceStructure(); err != nil {
+		d.add("source", DoctorError, err.Error(), "Export the project from Label Studio as YOLO, which writes images/, labels/ and classes.txt")
+		return 0
+	}
+	if classes, err := d.converter.LoadClasses(); err != nil {
+		d.add("source", DoctorError, err.Error(), "Fix classes.txt so it lists one class name per line")
+	} else {
+		d.add("source", DoctorOK, fmt.Sprintf("%d classes in classes.txt", len(classes)), "")
+	}
+
+	images, imageSize := d.stems(filepath.Join(source, "images"), d.converter.isImageFile)
+	labels, labelSize := d.stems(filepath.Join(source, "labels"), func(name string) bool {
+		return strings.EqualFold(filepath.Ext(name), ".txt")
+	})
+	var unlabeled, orphans []string
+	pairs := 0
+	for stem, name := range images {
+		if _, ok := labels[stem]; ok {
+			pairs++
+		} else {
+			unlabeled = append(unlabeled, name)
+		}
+	}
+	for stem, name := range labels {
+		if _, ok := images[stem]; !ok {
+			orphans = append(orphans, name)
+		}
+	}
+
+	if pairs == 0 {
+		d.add("source", DoctorError, fmt.Sprintf("No image-label pairs among %d images and %d label files", len(images), len(labels)), "Check that images/ and labels/ use the same file names")
+	} else {
+		d.add("source", DoctorOK, fmt.Sprintf("%d image-label pairs (%s)", pairs, formatBytes(imageSize+labelSize)), "")
+	}
+	if len(unlabeled) > 0 {
+		d.add("source", DoctorWarning, fmt.Sprintf("%d images have no label file (e.g. %s)", len(unlabeled), firstSorted(unlabeled)), "Unannotated tasks export without labels; finish them or filter them out in Label Studio")
+	}
+	if len(orphans) > 0 {
+		d.add("source", DoctorWarning, fmt.Sprintf("%d label files have no image (e.g. %s)", len(orphans), firstSorted(orphans)), "Export with images included, or copy the missing images into images/")
+	}
+	return imageSize + labelSize
+}
+
+// stems maps the extensionless relative path of every matching file below
+// dir to its relative path, and returns their total size
+func (d *doctor) stems(dir string, match func(string) bool) (map[string]string, uint64) {
+	stems := make(map[string]string)
+	var size uint64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !match(info.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		stems[strings.TrimSuffix(rel, filepath.Ext(rel))] = rel
+		size += uint64(info.Size())
+		return nil
+	})
+	return stems, size
+}
+
+// countImages counts the images below dir and their total size
+func (d *doctor) countImages(dir string) (int, uint64) {
+	images, size := d.stems(dir, d.converter.isImageFile)
+	return len(images), size
+}
+
+// firstSorted returns the alphabetically first name, for examples
+func firstSorted(names []string) string {
+	sort.Strings(names)
+	return names[0]
+}
+
+// checkOutput checks that the output location can be written, isn't locked
+// by another conversion and has room for the source files
+func (d *doctor) checkOutput(size uint64) {
+	root := d.config.OutputDir
+	if archiveFormat(root) != "" {
+		root = filepath.Dir(root)
+	}
+	existing := existingAncestor(root)
+	if !isDir(existing) {
+		d.add("output", DoctorError, fmt.Sprintf("%s is not a directory", existing), "Choose a different -output")
+		return
+	}
+
+	probe, err := os.CreateTemp(existing, ".labelstudio-to-yolo-doctor-*")
+	if err != nil {
+		d.add("output", DoctorError, fmt.Sprintf("Can't write to %s: %v", existing, err), "Choose a different -output or fix the permissions of "+existing)
+		return
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	d.add("output", DoctorOK, fmt.Sprintf("%s is writable", existing), "")
+
+	if lock, _, err := readLockFile(d.converter.lockPath()); err == nil {
+		d.add("output", DoctorWarning, fmt.Sprintf("The output is locked by process %d on %s since %s", lock.PID, lock.Hostname, lock.Started.Local().Format(time.RFC3339)),
+			"Wait for that conversion to finish, or pass -force-unlock if it is gone")
+	}
+
+	available, ok := freeSpace(existing)
+	if !ok || size == 0 {
+		return
+	}
+	margin := uint64(float64(size)*freeSpaceMargin) + freeSpaceReserve
+	if size+margin > available {
+		d.add("output", DoctorWarning, fmt.Sprintf("Only %s free at %s for about %s of source files", formatBytes(available), existing, formatBytes(size)),
+			"Free up space, choose another -output, or use -move to avoid copies")
+		return
+	}
+	d.add("output", DoctorOK, fmt.Sprintf("%s free for about %s of source files", formatBytes(available), formatBytes(size)), "")
+}
+
+// checkTools checks for the external programs the options need
+func (d *doctor) checkTools() {
+	if isDir(filepath.Join(d.config.SourceDir, "videos")) && d.config.FramesDir == "" {
+		if _, err := exec.LookPath("ffmpeg"); err != nil {
+			d.add("tools", DoctorError, "ffmpeg is needed to extract the frames of videos/ but wasn't found", "Install ffmpeg, or extract the frames yourself and pass -frames-dir")
+		} else {
+			d.add("tools", DoctorOK, "ffmpeg found for videos/", "")
+		}
+	}
+
+	if d.config.ClearMLProject != "" {
+		python := d.config.ClearMLPython
+		if python == "" {
+			python = "python3"
+		}
+		cmd := exec.CommandContext(d.ctx, python, "-c", "import clearml")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			d.add("tools", DoctorError, fmt.Sprintf("%s can't import clearml: %v %s", python, err, strings.TrimSpace(string(output))), "pip install clearml, or point -clearml-python at an interpreter that has it")
+		} else {
+			d.add("tools", DoctorOK, fmt.Sprintf("%s has the clearml package", python), "")
+		}
+	}
+}
+
+// checkServices tests the credentials of the services the options use.
+// Notification webhooks are only checked for a valid URL, since any request
+// would post a message.
+func (d *doctor) checkServices() {
+	if d.labelStudio.URL != "" {
+		d.checkLabelStudio()
+	}
+
+	if d.config.MLflowURI != "" {
+		experiment := d.config.MLflowExperiment
+		if experiment == "" {
+			experiment = mlflowDefaultExperiment
+		}
+		m := newMLflowClient(d.ctx, d.config.MLflowURI)
+		m.client.Timeout = doctorTimeout
+		err := m.call(http.MethodGet, "experiments/get-by-name?experiment_name="+url.QueryEscape(experiment), nil, nil)
+		var apiErr *mlflowError
+		switch {
+		case err == nil:
+			d.add("services", DoctorOK, fmt.Sprintf("MLflow experiment %q is reachable", experiment), "")
+		case errors.As(err, &apiErr) && apiErr.ErrorCode == "RESOURCE_DOES_NOT_EXIST":
+			d.add("services", DoctorOK, fmt.Sprintf("MLflow is reachable; experiment %q will be created", experiment), "")
+		default:
+			message := err.Error()
+			if !strings.HasPrefix(message, "MLflow") {
+				message = "MLflow is unreachable: " + message
+			}
+			d.add("services", DoctorError, message, "Check -mlflow-uri and MLFLOW_TRACKING_TOKEN or MLFLOW_TRACKING_USERNAME/MLFLOW_TRACKING_PASSWORD")
+		}
+	}
+
+	if d.config.NotifyURL != "" {
+		u, err := url.Parse(d.config.NotifyURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			d.add("services", DoctorError, fmt.Sprintf("-notify-url %s is not an http(s) URL", redactURL(d.config.NotifyURL)), "Pass the webhook URL from Slack or your endpoint")
+		} else {
+			d.add("services", DoctorOK, fmt.Sprintf("Notifications go to %s (not sent by doctor)", redactURL(d.config.NotifyURL)), "")
+		}
+	}
+}
+
+// checkLabelStudio checks the Label Studio URL and API token by looking up
+// the token's user
+func (d *doctor) checkLabelStudio() {
+	fix := "Check -ls-url and the API token from Account & Settings in Label Studio (-ls-token or $LABEL_STUDIO_TOKEN)"
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, strings.TrimRight(d.labelStudio.URL, "/")+"/api/current-user/whoami", nil)
+	if err != nil {
+		d.add("services", DoctorError, fmt.Sprintf("Invalid Label Studio URL: %v", err), fix)
+		return
+	}
+	if d.labelStudio.Token != "" {
+		req.Header.Set("Authorization", "Token "+d.labelStudio.Token)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.add("services", DoctorError, fmt.Sprintf("Label Studio is unreachable: %v", err), fix)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		d.add("services", DoctorError, fmt.Sprintf("Label Studio rejected the request: %s", resp.Status), fix)
+		return
+	}
+
+	var user struct {
+		Email    string `json:"email"`
+		Username string `json:"username"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&user)
+	name := user.Email
+	if name == "" {
+		name = user.Username
+	}
+	d.add("services", DoctorOK, fmt.Sprintf("Label Studio accepts the token (user %s)", name), "")
+}
+
+// printFindings writes the findings and a summary line, returning the number
+// of errors
+func printFindings(w io.Writer, findings []DoctorFinding) int {
+	errorCount, warnings := 0, 0
+	for _, f := range findings {
+		fmt.Fprintf(w, "%-7s %-8s %s\n", strings.ToUpper(f.Level), f.Check, f.Message)
+		if f.Fix != "" {
+			fmt.Fprintf(w, "%16s Fix: %s\n", "", f.Fix)
+		}
+		switch f.Level {
+		case DoctorError:
+			errorCount++
+		case DoctorWarning:
+			warnings++
+		}
+	}
+	if errorCount == 0 && warnings == 0 {
+		fmt.Fprintln(w, "All checks passed")
+	} else {
+		fmt.Fprintf(w, "%d errors, %d warnings\n", errorCount, warnings)
+	}
+	return errorCount
+}
+
+// runDoctor implements the doctor subcommand
+func runDoctor(args []string) error {
+	var config Config
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	registerPathFlags(fs, &config)
+	registerConvertFlags(fs, &config)
+	lsURL := fs.String("ls-url", "", "Label Studio base URL whose API token to test")
+	lsToken := fs.String("ls-token", os.Getenv("LABEL_STUDIO_TOKEN"), "Label Studio API token (default: $LABEL_STUDIO_TOKEN)")
+	fs.Parse(args)
+
+	findings := Diagnose(context.Background(), config, LabelStudioConfig{URL: *lsURL, Token: *lsToken})
+	if errorCount := printFindings(os.Stdout, findings); errorCount > 0 {
+		return fmt.Errorf("%d checks failed", errorCount)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// findingsWith returns the findings of a level whose message contains text
+func findingsWith(findings []DoctorFinding, level, text string) []DoctorFinding {
+	var matched []DoctorFinding
+	for _, f := range findings {
+		if f.Level == level && strings.Contains(f.Message, text) {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+func TestDiagnoseHealthySource(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+
+	findings := Diagnose(context.Background(), Config{SourceDir: sourceDir, OutputDir: filepath.Join(t.TempDir(), "out", "dataset"), InputFormat: InputAuto}, LabelStudioConfig{})
+	var out strings.Builder
+	if errors := printFindings(&out, findings); errors != 0 {
+		t.Errorf("Expected no errors, got:\n%s", out.String())
+	}
+	for _, text := range []string{"Input format yolo", "2 classes", "3 image-label pairs", "is writable"} {
+		if len(findingsWith(findings, DoctorOK, text)) != 1 {
+			t.Errorf("Expected an ok finding %q, got:\n%s", text, out.String())
+		}
+	}
+}
+
+func TestDiagnoseFindsProblems(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	if err := os.WriteFile(filepath.Join(sourceDir, "images", "unlabeled.jpg"), []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "orphan.txt"), []byte("0 0.5 0.5 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outputDir, outputLockName), []byte(`{"pid": 4242, "hostname": "trainer"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := Diagnose(context.Background(), Config{SourceDir: sourceDir, OutputDir: outputDir, InputFormat: InputYOLO}, LabelStudioConfig{})
+	for _, text := range []string{"1 images have no label file (e.g. unlabeled.jpg)", "1 label files have no image (e.g. orphan.txt)", "locked by process 4242 on trainer"} {
+		matched := findingsWith(findings, DoctorWarning, text)
+		if len(matched) != 1 || matched[0].Fix == "" {
+			t.Errorf("Expected a warning %q with a fix, got %+v", text, findings)
+		}
+	}
+
+	findings = Diagnose(context.Background(), Config{SourceDir: filepath.Join(sourceDir, "missing"), OutputDir: outputDir, InputFormat: InputAuto}, LabelStudioConfig{})
+	if len(findingsWith(findings, DoctorError, "invalid source structure")) != 1 {
+		t.Errorf("Expected an error for a missing source, got %+v", findings)
+	}
+}
+
+func TestDiagnoseServices(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	services := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/current-user/whoami":
+			if r.Header.Get("Authorization") != "Token good" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"email": "annotator@example.com"}`))
+		case "/api/2.0/mlflow/experiments/get-by-name":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code": "RESOURCE_DOES_NOT_EXIST", "message": "No experiment"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer services.Close()
+
+	config := Config{SourceDir: sourceDir, OutputDir: t.TempDir(), InputFormat: InputYOLO, MLflowURI: services.URL, NotifyURL: "ftp://example.com/hook"}
+	findings := Diagnose(context.Background(), config, LabelStudioConfig{URL: services.URL, Token: "good"})
+	if len(findingsWith(findings, DoctorOK, "user annotator@example.com")) != 1 {
+		t.Errorf("Expected the Label Studio token to be accepted, got %+v", findings)
+	}
+	if len(findingsWith(findings, DoctorOK, "will be created")) != 1 {
+		t.Errorf("Expected MLflow to be reachable, got %+v", findings)
+	}
+	if len(findingsWith(findings, DoctorError, "-notify-url")) != 1 {
+		t.Errorf("Expected an error for a non-http notify URL, got %+v", findings)
+	}
+
+	findings = Diagnose(context.Background(), Config{SourceDir: sourceDir, OutputDir: t.TempDir(), InputFormat: InputYOLO}, LabelStudioConfig{URL: services.URL, Token: "bad"})
+	if len(findingsWith(findings, DoctorError, "401")) != 1 {
+		t.Errorf("Expected a rejected Label Studio token, got %+v", findings)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// labeledRegion is a valid label line reduced to its class and bounds
+type labeledRegion struct {
+	line    int
+	classID int
+	bounds  [4]float64
+}
+
+// newLabeledRegion describes a valid label line of the given kind
+func newLabeledRegion(line int, kind string, parts []string) labeledRegion {
+	classID, _ := strconv.Atoi(parts[0])
+	return labeledRegion{line: line, classID: classID, bounds: labelBounds(kind, parts)}
+}
+
+// duplicateOf returns the earlier region of the same class that a region
+// overlaps by at least -duplicate-iou, if any. Regions are compared by their
+// bounding boxes.
+func (c *Converter) duplicateOf(regions []labeledRegion, region labeledRegion) (labeledRegion, float64, bool) {
+	if c.config.DuplicateIoU <= 0 {
+		return labeledRegion{}, 0, false
+	}
+	for _, earlier := range regions {
+		if earlier.classID != region.classID {
+			continue
+		}
+		if iou := boxIoU(earlier.bounds, region.bounds); iou >= c.config.DuplicateIoU {
+			return earlier, iou, true
+		}
+	}
+	return labeledRegion{}, 0, false
+}
+
+// dedupeLabels drops regions that duplicate an earlier region of the same
+// class in their file, keeping the first
+func (c *Converter) dedupeLabels(pairs []LabelPair) ([]LabelPair, error) {
+	pairs, dropped, files, err := c.rewriteLabels(pairs, "deduplicated", func(pair LabelPair, lines []string) ([]string, int) {
+		var kept []string
+		var regions []labeledRegion
+		dropped := 0
+		for i, line := range lines {
+			parts := strings.Fields(line)
+			if kind, warning := c.parseLine(parts); len(parts) > 0 && warning == "" {
+				region := newLabeledRegion(i+1, kind, parts)
+				if _, _, duplicate := c.duplicateOf(regions, region); duplicate {
+					dropped++
+					continue
+				}
+				regions = append(regions, region)
+			}
+			kept = append(kept, line)
+		}
+		return kept, dropped
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dropped > 0 {
+		fmt.Fprintf(c.out, "Removed %d duplicate regions from %d label files\n", dropped, files)
+	}
+	return pairs, nil
+}
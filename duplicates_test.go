@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const duplicateLabels = "0 0.5 0.5 0.2 0.2\n" +
+	"0 0.5 0.5 0.2 0.2\n" + // identical
+	"1 0.5 0.5 0.2 0.2\n" + // same place, other class
+	"0 0.505 0.5 0.2 0.2\n" + // near-identical
+	"0 0.6 0.5 0.2 0.2\n" // overlapping, but not a duplicate
+
+func TestDuplicateRegionsAreReported(t *testing.T) {
+	dir := t.TempDir()
+	labelPath := filepath.Join(dir, "crowd.txt")
+	if err := os.WriteFile(labelPath, []byte(duplicateLabels), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{SourceDir: dir, DuplicateIoU: 0.9})
+	converter.SetOutput(io.Discard)
+	stats, err := converter.ValidateLabels([]LabelPair{{LabelPath: labelPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.DuplicateAnnotations != 2 || stats.TotalAnnotations != 5 {
+		t.Errorf("Expected 2 duplicates among 5 annotations, got %+v", *stats)
+	}
+	if len(converter.findings) != 2 || converter.findings[0].Line != 2 || converter.findings[1].Line != 4 {
+		t.Fatalf("Expected duplicates on lines 2 and 4, got %+v", converter.findings)
+	}
+	if f := converter.findings[0]; f.Rule != "duplicate-region" || !strings.Contains(f.Message, "Duplicate of line 1") {
+		t.Errorf("Unexpected finding %+v", f)
+	}
+
+	converter.config.DuplicateIoU = 0
+	if stats, _ := converter.ValidateLabels([]LabelPair{{LabelPath: labelPath}}); stats.DuplicateAnnotations != 0 {
+		t.Error("Expected no duplicate checks with -duplicate-iou 0")
+	}
+}
+
+func TestDedupeLabels(t *testing.T) {
+	dir := t.TempDir()
+	labelPath := filepath.Join(dir, "crowd.txt")
+	if err := os.WriteFile(labelPath, []byte(duplicateLabels), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{DuplicateIoU: 0.9, Dedupe: true})
+	converter.SetOutput(io.Discard)
+	defer converter.removeStaging()
+
+	pairs, err := converter.dedupeLabels([]LabelPair{{LabelPath: labelPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(pairs[0].LabelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0 0.5 0.5 0.2 0.2\n1 0.5 0.5 0.2 0.2\n0 0.6 0.5 0.2 0.2\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+}
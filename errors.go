@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationError is a problem found in a label file
+type ValidationError struct {
+	ValidationFinding
+}
+
+func (e *ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Message)
+}
+
+// CopyError is a source file that couldn't be written to the output
+type CopyError struct {
+	// Kind is what the file is, such as image or label
+	Kind string
+	Path string
+	Err  error
+}
+
+func (e *CopyError) Error() string {
+	return fmt.Sprintf("failed to copy %s %s: %v", e.Kind, e.Path, e.Err)
+}
+
+func (e *CopyError) Unwrap() error {
+	return e.Err
+}
+
+// ConversionErrors is what went wrong in a conversion: the error that stopped
+// it, if any, and the problems found in the labels along the way. Convert
+// returns it on failure; errors.Is and errors.As see through it to every
+// error it holds.
+type ConversionErrors struct {
+	// Err stopped the conversion; nil when it completed
+	Err        error
+	Validation []*ValidationError
+	// Skipped counts the source files and annotations left out of the
+	// dataset, such as orphan labels or undecodable masks
+	Skipped int
+}
+
+func (e *ConversionErrors) Error() string {
+	var problems []string
+	if len(e.Validation) > 0 {
+		problems = append(problems, fmt.Sprintf("%d validation problems", len(e.Validation)))
+	}
+	if e.Skipped > 0 {
+		problems = append(problems, fmt.Sprintf("%d skipped inputs", e.Skipped))
+	}
+	summary := strings.Join(problems, ", ")
+	switch {
+	case e.Err == nil:
+		return summary
+	case summary == "":
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (and %s)", e.Err, summary)
+}
+
+func (e *ConversionErrors) Unwrap() []error {
+	var errs []error
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	for _, v := range e.Validation {
+		errs = append(errs, v)
+	}
+	return errs
+}
+
+// CountByRule counts the validation problems of every rule
+func (e *ConversionErrors) CountByRule() map[string]int {
+	counts := make(map[string]int)
+	for _, v := range e.Validation {
+		counts[v.Rule]++
+	}
+	return counts
+}
+
+// Errors returns the problems of the last conversion, or nil when there
+// were none. Validation problems are kept for every mode except -stream,
+// which only counts them.
+func (c *Converter) Errors() *ConversionErrors {
+	if len(c.findings) == 0 && c.skipped == 0 {
+		return nil
+	}
+	result := &ConversionErrors{Validation: make([]*ValidationError, len(c.findings)), Skipped: c.skipped}
+	for i, finding := range c.findings {
+		result.Validation[i] = &ValidationError{finding}
+	}
+	return result
+}
+
+// conversionError wraps the error that stopped a conversion with the
+// problems found before it
+func (c *Converter) conversionError(err error) error {
+	var collected *ConversionErrors
+	if err == nil || errors.As(err, &collected) {
+		return err
+	}
+	result := c.Errors()
+	if result == nil {
+		result = &ConversionErrors{}
+	}
+	result.Err = err
+	return result
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertCollectsValidationErrors(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	labelPath := filepath.Join(tempDir, "labels", "image1.txt")
+	if err := os.WriteFile(labelPath, []byte("0 0.5 0.5 0.2 0.2\n1 0.5 1.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: filepath.Join(tempDir, "out"), TrainSplit: 0.8, Seed: 42})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+	problems := converter.Errors()
+	if problems == nil || len(problems.Validation) != 1 {
+		t.Fatalf("Expected one validation problem, got %v", problems)
+	}
+	if got := problems.Validation[0].Error(); got != "labels/image1.txt:2: Non-normalized coordinates" {
+		t.Errorf("Unexpected validation error %q", got)
+	}
+	if counts := problems.CountByRule(); counts["non-normalized-coordinates"] != 1 {
+		t.Errorf("Expected the problem to be counted by rule, got %v", counts)
+	}
+
+	// A failed conversion returns the problems found before it stopped
+	converter = NewConverter(Config{SourceDir: tempDir, OutputDir: filepath.Join(tempDir, "out2"), TrainSplit: 0.8, Seed: 42, ReportFile: filepath.Join(tempDir, "missing", "report.json")})
+	converter.SetOutput(io.Discard)
+	err := converter.Convert()
+	var result *ConversionErrors
+	if !errors.As(err, &result) {
+		t.Fatalf("Expected *ConversionErrors, got %T: %v", err, err)
+	}
+	if result.Err == nil || len(result.Validation) != 1 {
+		t.Errorf("Expected the fatal error and one validation problem, got %+v", result)
+	}
+	if !strings.HasSuffix(err.Error(), "(and 1 validation problems)") {
+		t.Errorf("Unexpected message %q", err)
+	}
+	var validation *ValidationError
+	if !errors.As(err, &validation) || validation.Rule != "non-normalized-coordinates" {
+		t.Errorf("Expected errors.As to find the validation problem, got %v", validation)
+	}
+}
+
+func TestConversionErrorsUnwrap(t *testing.T) {
+	copyErr := &CopyError{Kind: "label", Path: "labels/a.txt", Err: os.ErrPermission}
+	err := (&Converter{}).conversionError(copyErr)
+	if err.Error() != "failed to copy label labels/a.txt: permission denied" {
+		t.Errorf("Unexpected message %q", err)
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		t.Error("Expected errors.Is to see through the copy error")
+	}
+	var target *CopyError
+	if !errors.As(err, &target) || target.Path != "labels/a.txt" {
+		t.Errorf("Expected errors.As to find the copy error, got %v", target)
+	}
+	if (&Converter{}).conversionError(nil) != nil {
+		t.Error("Expected no error for a successful conversion")
+	}
+	if (&Converter{}).Errors() != nil {
+		t.Error("Expected no problems without findings")
+	}
+}
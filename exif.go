@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// exifOrientation reads the EXIF orientation tag (1 to 8) of a JPEG image.
+// It returns 1, the upright orientation, for other formats and images
+// without the tag.
+func exifOrientation(imagePath string) int {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return 1
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xFF, 0xD8} {
+		return 1
+	}
+	for {
+		var marker [4]byte
+		if _, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xFF {
+			return 1
+		}
+		// Image data follows start of scan; metadata comes before it
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if length < 0 {
+			return 1
+		}
+		if marker[1] != 0xE1 {
+			if _, err := r.Discard(length); err != nil {
+				return 1
+			}
+			continue
+		}
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return 1
+		}
+		if bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+	}
+}
+
+// tiffOrientation finds the orientation tag in the first IFD of a TIFF
+// structure, as embedded in EXIF
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient returns an image with an EXIF orientation applied, so that its
+// pixels are upright
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise to display
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise to display
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
+
+// checkOrientation finds images with an EXIF orientation. Label Studio shows
+// them upright, so their labels are in the upright frame, but trainers that
+// ignore the tag see the stored pixels. With -apply-exif-orientation the
+// images are rewritten upright in the staging directory; otherwise they are
+// reported.
+func (c *Converter) checkOrientation(pairs []LabelPair) ([]LabelPair, error) {
+	result := make([]LabelPair, len(pairs))
+	copy(result, pairs)
+
+	var rotated []int
+	for i, pair := range pairs {
+		if exifOrientation(pair.ImagePath) > 1 {
+			rotated = append(rotated, i)
+		}
+	}
+	if len(rotated) == 0 {
+		return result, nil
+	}
+	// Stripping metadata drops the tag, so the pixels must be turned upright
+	if !c.config.ApplyEXIFOrientation && !c.config.StripMetadata {
+		fmt.Fprintf(c.out, "Warning: %d images carry an EXIF orientation (e.g. %s); trainers that ignore it see them rotated against their labels (use -apply-exif-orientation)\n",
+			len(rotated), filepath.Base(pairs[rotated[0]].ImagePath))
+		return result, nil
+	}
+
+	staging, err := c.stagingDirectory()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(staging, "oriented")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	for _, i := range rotated {
+		imagePath := pairs[i].ImagePath
+		orientedPath := filepath.Join(dir, strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))+".jpg")
+		if err := writeOriented(imagePath, orientedPath); err != nil {
+			return nil, err
+		}
+		result[i].ImagePath = orientedPath
+	}
+	fmt.Fprintf(c.out, "Applied the EXIF orientation of %d images\n", len(rotated))
+	return result, nil
+}
+
+// writeOriented rewrites a JPEG upright as a JPEG without metadata
+func writeOriented(src, dst string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	img, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", src, err)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if err := jpeg.Encode(out, orient(img, exifOrientation(src)), &jpeg.Options{Quality: 95}); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to encode %s: %w", dst, err)
+	}
+	return out.Close()
+}
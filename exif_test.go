@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeOrientedJPEG writes a JPEG with an EXIF orientation tag. The image is
+// red on its left half and blue on its right half as stored.
+func writeOrientedJPEG(t *testing.T, path string, width, height, orientation int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= width/2 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A little-endian TIFF header with one IFD entry: orientation
+	var tiff bytes.Buffer
+	tiff.WriteString("II*\x00")
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(1))
+	binary.Write(&tiff, binary.LittleEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, []uint16{uint16(orientation), 0})
+	binary.Write(&tiff, binary.LittleEndian, uint32(0))
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write(encoded.Bytes()[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(encoded.Bytes()[2:])
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEXIFOrientation(t *testing.T) {
+	dir := t.TempDir()
+	rotated := filepath.Join(dir, "rotated.jpg")
+	writeOrientedJPEG(t, rotated, 40, 20, 6)
+	plain := filepath.Join(dir, "plain.jpg")
+	writeTestImage(t, plain, 40, 20)
+
+	if got := exifOrientation(rotated); got != 6 {
+		t.Errorf("Expected orientation 6, got %d", got)
+	}
+	if got := exifOrientation(plain); got != 1 {
+		t.Errorf("Expected orientation 1 without EXIF, got %d", got)
+	}
+	// Sizes are reported as displayed
+	if width, height, err := readImageSize(rotated); err != nil || width != 20 || height != 40 {
+		t.Errorf("Expected 20x40, got %dx%d, %v", width, height, err)
+	}
+
+	pairs := []LabelPair{{ImagePath: rotated}, {ImagePath: plain}}
+	converter := NewConverter(Config{})
+	var out strings.Builder
+	converter.SetOutput(&out)
+	if _, err := converter.checkOrientation(pairs); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "1 images carry an EXIF orientation (e.g. rotated.jpg)") {
+		t.Errorf("Expected a warning, got %s", out.String())
+	}
+
+	converter = NewConverter(Config{ApplyEXIFOrientation: true})
+	converter.SetOutput(io.Discard)
+	defer converter.removeStaging()
+	oriented, err := converter.checkOrientation(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oriented[0].ImagePath == rotated || oriented[1].ImagePath != plain {
+		t.Fatalf("Expected only the rotated image to be rewritten, got %+v", oriented)
+	}
+
+	file, err := os.Open(oriented[0].ImagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := jpeg.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Turned 90° clockwise, the stored left half ends up on top
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Fatalf("Expected a 20x40 upright image, got %v", b)
+	}
+	if r, _, bl, _ := img.At(10, 5).RGBA(); r < bl {
+		t.Error("Expected red on top")
+	}
+	if r, _, bl, _ := img.At(10, 35).RGBA(); bl < r {
+		t.Error("Expected blue at the bottom")
+	}
+	if exifOrientation(oriented[0].ImagePath) != 1 {
+		t.Error("Expected the rewritten image to have no orientation tag")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3x2 image numbered by pixel, checked against the standard EXIF
+	// transforms
+	src := image.NewGray(image.Rect(0, 0, 3, 2))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i + 1)
+	}
+	tests := map[int][]uint8{
+		2: {3, 2, 1, 6, 5, 4},
+		3: {6, 5, 4, 3, 2, 1},
+		4: {4, 5, 6, 1, 2, 3},
+		5: {1, 4, 2, 5, 3, 6},
+		6: {4, 1, 5, 2, 6, 3},
+		7: {6, 3, 5, 2, 4, 1},
+		8: {3, 6, 2, 5, 1, 4},
+	}
+	for orientation, want := range tests {
+		img := orient(src, orientation)
+		var got []uint8
+		for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+			for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+				gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+				got = append(got, gray.Y)
+			}
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Orientation %d: expected %v, got %v", orientation, want, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// Exit statuses of a conversion, for scripts that handle failures
+// differently. Any other error exits with 1 and invalid flags with 2.
+const (
+	// exitInvalidSource: the source lacks images/, labels/ or classes.txt
+	exitInvalidSource = 3
+	// exitNoPairs: the source holds nothing to convert
+	exitNoPairs = 4
+	// exitValidationFailed: -strict found problems in the labels
+	exitValidationFailed = 5
+	// exitIOFailure: reading the source or writing the output failed
+	exitIOFailure = 6
+	// exitPartialSuccess: the dataset was written, but source files or
+	// annotations were left out of it
+	exitPartialSuccess = 7
+	// exitInterrupted: stopped by SIGINT or SIGTERM, which a rerun with
+	// -resume continues (EX_TEMPFAIL)
+	exitInterrupted = 75
+)
+
+var (
+	// ErrInvalidSource is returned for a source without the expected structure
+	ErrInvalidSource = errors.New("invalid source structure")
+	// ErrNoPairs is returned for a source with no images to convert
+	ErrNoPairs = errors.New("nothing to convert")
+	// ErrValidationFailed is returned when -strict finds problems in the labels
+	// or a -gates quality gate fails
+	ErrValidationFailed = errors.New("validation failed")
+)
+
+// exitCode returns the exit status of a conversion that returned err and
+// left the given problems
+func exitCode(err error, problems *ConversionErrors) int {
+	switch {
+	case err == nil:
+		if problems != nil && problems.Skipped > 0 {
+			return exitPartialSuccess
+		}
+		return 0
+	case errors.Is(err, ErrInterrupted):
+		return exitInterrupted
+	case errors.Is(err, ErrInvalidSource):
+		return exitInvalidSource
+	case errors.Is(err, ErrNoPairs):
+		return exitNoPairs
+	case errors.Is(err, ErrValidationFailed):
+		return exitValidationFailed
+	case isIOError(err):
+		return exitIOFailure
+	}
+	return 1
+}
+
+// isIOError reports whether err comes from the file system
+func isIOError(err error) bool {
+	var copyErr *CopyError
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+	var errno syscall.Errno
+	return errors.As(err, &copyErr) || errors.As(err, &pathErr) || errors.As(err, &linkErr) ||
+		errors.As(err, &syscallErr) || errors.As(err, &errno)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		problems *ConversionErrors
+		want     int
+	}{
+		{"success", nil, nil, 0},
+		{"validation problems only", nil, &ConversionErrors{Validation: []*ValidationError{{}}}, 0},
+		{"partial success", nil, &ConversionErrors{Skipped: 2}, exitPartialSuccess},
+		{"interrupted", &ConversionErrors{Err: ErrInterrupted}, nil, exitInterrupted},
+		{"invalid source", fmt.Errorf("%w: required file not found: classes.txt", ErrInvalidSource), nil, exitInvalidSource},
+		{"no pairs", fmt.Errorf("%w: no valid image-label pairs found", ErrNoPairs), nil, exitNoPairs},
+		{"strict", fmt.Errorf("%w: 3 problems in the labels (-strict)", ErrValidationFailed), nil, exitValidationFailed},
+		{"copy", &CopyError{Kind: "image", Path: "a.jpg", Err: errors.New("short write")}, nil, exitIOFailure},
+		{"path", fmt.Errorf("failed to open classes.txt: %w", &os.PathError{Op: "open", Path: "classes.txt", Err: os.ErrPermission}), nil, exitIOFailure},
+		{"subcommand conversion", fmt.Errorf("project 3: %w", &ConversionErrors{Err: fmt.Errorf("%w: no valid image-label pairs found", ErrNoPairs)}), nil, exitNoPairs},
+		{"other", errors.New("invalid train split"), nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err, tt.problems); got != tt.want {
+				t.Errorf("Expected exit status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertErrorCategories(t *testing.T) {
+	tempDir := t.TempDir()
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: filepath.Join(tempDir, "out"), TrainSplit: 0.8})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); !errors.Is(err, ErrInvalidSource) {
+		t.Errorf("Expected ErrInvalidSource for an empty source, got %v", err)
+	}
+
+	createTestFiles(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "labels", "image1.txt"), []byte("0 0.5 0.5 0.2 0.2\n1 0.5 1.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(tempDir, "strict")
+	converter = NewConverter(Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.8, Strict: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("Expected ErrValidationFailed with -strict, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "data.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected -strict to stop before writing the output, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "labels", "lost.txt"), []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	converter = NewConverter(Config{SourceDir: tempDir, OutputDir: filepath.Join(tempDir, "partial"), TrainSplit: 0.8, AllowOrphanLabels: true})
+	converter.SetOutput(io.Discard)
+	err := converter.Convert()
+	if code := exitCode(err, converter.Errors()); code != exitPartialSuccess {
+		t.Errorf("Expected a skipped orphan label to be a partial success, got status %d (%v)", code, err)
+	}
+}
+
+func TestStrictRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Crop: true, Strict: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-strict") {
+		t.Errorf("Expected -strict to be rejected with -crop, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fiftyOneScript is the loader written into the dataset by -fiftyone
+const fiftyOneScript = "load_fiftyone.py"
+
+// fiftyOneTemplate loads both splits of the dataset into FiftyOne, tagged
+// with their split, and opens the app. Its placeholders are the dataset name
+// and the FiftyOne label type.
+const fiftyOneTemplate = `#!/usr/bin/env python3
+# Loads this dataset into FiftyOne and opens the app:
+#
+#   pip install fiftyone
+#   python %[1]s
+#
+# Generated by labelstudio-to-yolo.
+import os
+
+import fiftyone as fo
+
+DATASET_DIR = os.path.dirname(os.path.abspath(__file__))
+NAME = %[2]s
+LABEL_TYPE = %[3]s
+
+if fo.dataset_exists(NAME):
+    fo.delete_dataset(NAME)
+dataset = fo.Dataset(NAME)
+for split in ("train", "val"):
+    dataset.add_dir(
+        dataset_dir=DATASET_DIR,
+        dataset_type=fo.types.YOLOv5Dataset,
+        yaml_path="data.yaml",
+        split=split,
+        label_type=LABEL_TYPE,
+        tags=split,
+    )
+print(dataset)
+
+if __name__ == "__main__":
+    session = fo.launch_app(dataset)
+    session.wait()
+`
+
+// validateFiftyOne rejects datasets FiftyOne can't import as YOLO
+func (c *Converter) validateFiftyOne() error {
+	if !c.config.FiftyOne {
+		return nil
+	}
+	switch {
+	case c.config.Classify || c.config.MultiLabel || c.config.Crop:
+		return fmt.Errorf("-fiftyone can't be combined with -classify, -multi-label or -crop")
+	case c.config.SkeletonFile != "", c.config.OBB:
+		return fmt.Errorf("-fiftyone supports detection and segmentation datasets, not -skeleton or -obb")
+	case c.config.OutputFormat != "" && c.config.OutputFormat != OutputYOLO:
+		return fmt.Errorf("-fiftyone requires -output-format %s", OutputYOLO)
+	}
+	return nil
+}
+
+// writeFiftyOneScript writes the FiftyOne loader next to data.yaml.
+// Segmentation datasets load as polylines, the rest as detections.
+func (c *Converter) writeFiftyOneScript() error {
+	labelType := "detections"
+	if c.task == TaskSegment {
+		labelType = "polylines"
+	}
+	script := fmt.Sprintf(fiftyOneTemplate, fiftyOneScript, pythonString(datasetName(c.config.OutputDir)), pythonString(labelType))
+	if err := c.output().WriteFile(fiftyOneScript, []byte(script)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fiftyOneScript, err)
+	}
+	fmt.Fprintf(c.out, "Wrote %s; run it to inspect the dataset in FiftyOne\n", fiftyOneScript)
+	return nil
+}
+
+// pythonString quotes s as a Python string literal
+func pythonString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(s) + "'"
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertFiftyOneScript(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "parking_lot")
+
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42, FiftyOne: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+	script, err := os.ReadFile(filepath.Join(outputDir, fiftyOneScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"NAME = 'parking_lot'\n", "LABEL_TYPE = 'detections'\n", `yaml_path="data.yaml"`, "fo.types.YOLOv5Dataset"} {
+		if !strings.Contains(string(script), want) {
+			t.Errorf("Expected the script to contain %q:\n%s", want, script)
+		}
+	}
+
+	converter = NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, FiftyOne: true, OBB: true})
+	if err := converter.validateFiftyOne(); err == nil {
+		t.Error("Expected -fiftyone with -obb to be rejected")
+	}
+	converter = NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, FiftyOne: true, Crop: true})
+	if err := converter.validateFiftyOne(); err == nil {
+		t.Error("Expected -fiftyone with -crop to be rejected")
+	}
+}
+
+func TestPythonString(t *testing.T) {
+	if got := pythonString(`it's a\b`); got != `'it\'s a\\b'` {
+		t.Errorf("Unexpected literal %s", got)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Review filters selected with -review-status
+const (
+	ReviewAny         = "any"
+	ReviewNotRejected = "not-rejected"
+	ReviewAccepted    = "accepted"
+)
+
+// LSUser identifies an annotator. Exports store completed_by either as a user
+// ID or, with annotator details enabled, as an object.
+type LSUser struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+}
+
+// UnmarshalJSON accepts both a bare user ID and a user object
+func (u *LSUser) UnmarshalJSON(data []byte) error {
+	if id, err := strconv.Atoi(string(data)); err == nil {
+		u.ID = id
+		return nil
+	}
+	type plain LSUser
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// LSReview is a reviewer's verdict on an annotation
+type LSReview struct {
+	Accepted bool `json:"accepted"`
+}
+
+// reviewState returns "accepted", "rejected" or "" for an annotation that
+// hasn't been reviewed. The last review wins.
+func reviewState(a LSAnnotation) string {
+	switch strings.ToLower(a.ReviewResult) {
+	case "accepted", "rejected":
+		return strings.ToLower(a.ReviewResult)
+	}
+	if len(a.Reviews) == 0 {
+		return ""
+	}
+	if a.Reviews[len(a.Reviews)-1].Accepted {
+		return "accepted"
+	}
+	return "rejected"
+}
+
+// annotationFilter drops annotations by cancellation, review state,
+// annotator and date, counting what it dropped
+type annotationFilter struct {
+	includeCancelled bool
+	review           string
+	include          map[string]bool
+	exclude          map[string]bool
+	// since and until bound when annotations were last updated, since
+	// inclusive and until exclusive; zero times leave that side open
+	since time.Time
+	until time.Time
+
+	cancelled  int
+	rejected   int
+	unreviewed int
+	annotator  int
+	outside    int
+	tasks      int
+}
+
+// newAnnotationFilter builds the filter from the configuration
+func (c *Converter) newAnnotationFilter() (*annotationFilter, error) {
+	f := &annotationFilter{
+		includeCancelled: c.config.IncludeCancelled,
+		review:           c.config.ReviewStatus,
+		include:          annotatorSet(c.config.Annotators),
+		exclude:          annotatorSet(c.config.ExcludeAnnotators),
+	}
+	switch f.review {
+	case "":
+		f.review = ReviewNotRejected
+	case ReviewAny, ReviewNotRejected, ReviewAccepted:
+	default:
+		return nil, fmt.Errorf("unsupported review status: %s (expected any, not-rejected or accepted)", f.review)
+	}
+
+	var err error
+	if f.since, err = parseAnnotationTime("-since", c.config.Since); err != nil {
+		return nil, err
+	}
+	if f.until, err = parseAnnotationTime("-until", c.config.Until); err != nil {
+		return nil, err
+	}
+	if !f.since.IsZero() && !f.until.IsZero() && !f.since.Before(f.until) {
+		return nil, fmt.Errorf("-since %s must be before -until %s", c.config.Since, c.config.Until)
+	}
+	return f, nil
+}
+
+// validateDateRange checks -since and -until before the conversion starts,
+// once the input format is known
+func (c *Converter) validateDateRange() error {
+	if c.config.Since == "" && c.config.Until == "" {
+		return nil
+	}
+	if c.config.InputFormat != InputLSJSON {
+		return fmt.Errorf("-since and -until require a Label Studio JSON export (-input-format ls-json)")
+	}
+	_, err := c.newAnnotationFilter()
+	return err
+}
+
+// annotationTimeLayouts are the -since and -until formats; times without a
+// zone are UTC
+var annotationTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// parseAnnotationTime parses a -since or -until value, the zero time when
+// it is empty
+func parseAnnotationTime(flag, value string) (time.Time, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range annotationTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q (expected a date such as 2024-03-01 or an RFC 3339 time)", flag, value)
+}
+
+// windowed reports whether -since or -until is set
+func (f *annotationFilter) windowed() bool {
+	return !f.since.IsZero() || !f.until.IsZero()
+}
+
+// inWindow reports whether an annotation was last updated within -since and
+// -until. Annotations without timestamps are outside any window.
+func (f *annotationFilter) inWindow(a LSAnnotation) bool {
+	if !f.windowed() {
+		return true
+	}
+	t := annotationTime(a)
+	if t.IsZero() {
+		return false
+	}
+	return !t.Before(f.since) && (f.until.IsZero() || t.Before(f.until))
+}
+
+// annotatorSet parses a comma-separated list of annotator IDs or emails
+func annotatorSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			set[entry] = true
+		}
+	}
+	return set
+}
+
+// matches reports whether an annotator is listed in the set by ID or email
+func (u LSUser) matches(set map[string]bool) bool {
+	return (u.ID != 0 && set[strconv.Itoa(u.ID)]) || (u.Email != "" && set[strings.ToLower(u.Email)])
+}
+
+// reason returns why an annotation is dropped, or "" when it is kept
+func (f *annotationFilter) reason(a LSAnnotation) string {
+	if a.WasCancelled && !f.includeCancelled {
+		return "cancelled"
+	}
+
+	switch state := reviewState(a); {
+	case state == "rejected" && f.review != ReviewAny:
+		return "rejected"
+	case state != "accepted" && f.review == ReviewAccepted:
+		return "unreviewed"
+	}
+
+	if (len(f.include) > 0 && !a.CompletedBy.matches(f.include)) || a.CompletedBy.matches(f.exclude) {
+		return "annotator"
+	}
+	if !f.inWindow(a) {
+		return "date"
+	}
+	return ""
+}
+
+// apply returns the annotations of a task that pass the filter, counting the
+// ones it drops
+func (f *annotationFilter) apply(annotations []LSAnnotation) []LSAnnotation {
+	var kept []LSAnnotation
+	for _, a := range annotations {
+		switch f.reason(a) {
+		case "":
+			kept = append(kept, a)
+		case "cancelled":
+			f.cancelled++
+		case "rejected":
+			f.rejected++
+		case "unreviewed":
+			f.unreviewed++
+		case "annotator":
+			f.annotator++
+		case "date":
+			f.outside++
+		}
+	}
+	return kept
+}
+
+// dropsTask reports whether a task was annotated but none of its annotations
+// pass the filter. Such tasks are skipped rather than treated as images
+// without objects. With -since or -until, tasks without annotations are
+// skipped too, as no work on them was completed in the window.
+func (f *annotationFilter) dropsTask(task LSTask) bool {
+	if len(task.Annotations) == 0 {
+		return f.windowed()
+	}
+	for _, a := range task.Annotations {
+		if f.reason(a) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// report prints how many annotations were dropped and why
+func (f *annotationFilter) report(c *Converter) {
+	if f.cancelled+f.rejected+f.unreviewed+f.annotator+f.outside == 0 && f.tasks == 0 {
+		return
+	}
+	fmt.Fprintf(c.out, "Filtered annotations: %d cancelled, %d rejected, %d not accepted, %d by annotator, %d outside -since/-until; skipped %d tasks left without annotations\n",
+		f.cancelled, f.rejected, f.unreviewed, f.annotator, f.outside, f.tasks)
+}
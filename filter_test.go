@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLSUserUnmarshal(t *testing.T) {
+	var annotations []LSAnnotation
+	data := `[{"completed_by": 7}, {"completed_by": {"id": 9, "email": "ann@example.com"}}, {}]`
+	if err := json.Unmarshal([]byte(data), &annotations); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	if annotations[0].CompletedBy.ID != 7 {
+		t.Errorf("Expected annotator 7, got %+v", annotations[0].CompletedBy)
+	}
+	if user := annotations[1].CompletedBy; user.ID != 9 || user.Email != "ann@example.com" {
+		t.Errorf("Expected annotator 9 with email, got %+v", user)
+	}
+	if annotations[2].CompletedBy.ID != 0 {
+		t.Errorf("Expected no annotator, got %+v", annotations[2].CompletedBy)
+	}
+}
+
+func TestAnnotationFilter(t *testing.T) {
+	annotations := []LSAnnotation{
+		{ID: 1, CompletedBy: LSUser{ID: 1}, ReviewResult: "accepted"},
+		{ID: 2, CompletedBy: LSUser{ID: 2}, ReviewResult: "rejected"},
+		{ID: 3, CompletedBy: LSUser{ID: 3, Email: "Bob@example.com"}},
+		{ID: 4, CompletedBy: LSUser{ID: 1}, WasCancelled: true},
+		{ID: 5, CompletedBy: LSUser{ID: 2}, Reviews: []LSReview{{Accepted: false}, {Accepted: true}}},
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		kept   string
+	}{
+		{"defaults", Config{}, "1,3,5"},
+		{"any review", Config{ReviewStatus: ReviewAny}, "1,2,3,5"},
+		{"accepted only", Config{ReviewStatus: ReviewAccepted}, "1,5"},
+		{"include cancelled", Config{IncludeCancelled: true}, "1,3,4,5"},
+		{"annotators", Config{Annotators: "1, bob@example.com"}, "1,3"},
+		{"exclude annotators", Config{ExcludeAnnotators: "3"}, "1,5"},
+	}
+
+	for _, tt := range tests {
+		filter, err := NewConverter(tt.config).newAnnotationFilter()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		var kept []string
+		for _, a := range filter.apply(annotations) {
+			kept = append(kept, string(rune('0'+a.ID)))
+		}
+		if got := strings.Join(kept, ","); got != tt.kept {
+			t.Errorf("%s: expected annotations %s, got %s", tt.name, tt.kept, got)
+		}
+	}
+}
+
+func TestFilterAppliesBeforeConsensus(t *testing.T) {
+	task := LSTask{ID: 1, Annotations: []LSAnnotation{
+		{ID: 1, WasCancelled: true},
+		{ID: 2, ReviewResult: "rejected", Result: []LSResult{box("dog", 10, 10, 20, 20)}},
+		{ID: 3, Result: []LSResult{box("cat", 10, 10, 20, 20)}},
+	}}
+
+	converter := NewConverter(Config{})
+	converter.consensusStats = &consensusStats{}
+	filter, err := converter.newAnnotationFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	converter.filter = filter
+
+	results, _ := converter.taskResults(task)
+	if len(results) != 1 || firstLabel(results[0]) != "cat" {
+		t.Errorf("Expected only the unreviewed cat annotation, got %+v", results)
+	}
+	if filter.cancelled != 1 || filter.rejected != 1 {
+		t.Errorf("Expected 1 cancelled and 1 rejected, got %+v", *filter)
+	}
+}
+
+func TestInvalidReviewStatus(t *testing.T) {
+	if _, err := NewConverter(Config{ReviewStatus: "approved"}).newAnnotationFilter(); err == nil {
+		t.Error("Expected error for unknown review status")
+	}
+}
+
+func TestFilterDropsTask(t *testing.T) {
+	filter, err := NewConverter(Config{}).newAnnotationFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejected := LSTask{Annotations: []LSAnnotation{{ReviewResult: "rejected"}, {WasCancelled: true}}}
+	if !filter.dropsTask(rejected) {
+		t.Error("Expected a task with only rejected and cancelled annotations to be dropped")
+	}
+	if filter.dropsTask(LSTask{}) {
+		t.Error("Expected an unannotated task to be kept")
+	}
+	if filter.dropsTask(LSTask{Annotations: []LSAnnotation{{ReviewResult: "rejected"}, {}}}) {
+		t.Error("Expected a task with a remaining annotation to be kept")
+	}
+}
+
+func TestAnnotationDateWindow(t *testing.T) {
+	annotations := []LSAnnotation{
+		{ID: 1, UpdatedAt: "2024-02-28T23:59:59Z"},
+		{ID: 2, UpdatedAt: "2024-03-01T00:00:00Z"},
+		{ID: 3, CreatedAt: "2024-03-04T12:30:00.123456Z"},
+		{ID: 4, UpdatedAt: "2024-03-08T00:00:00+01:00"},
+		{ID: 5},
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		kept   string
+	}{
+		{"no window", Config{}, "1,2,3,4,5"},
+		{"since", Config{Since: "2024-03-01"}, "2,3,4"},
+		{"until", Config{Until: "2024-03-04T12:30:00Z"}, "1,2"},
+		{"week", Config{Since: "2024-03-01", Until: "2024-03-08"}, "2,3,4"},
+		{"zoned until", Config{Since: "2024-03-01", Until: "2024-03-07T23:00:00Z"}, "2,3"},
+	}
+	for _, tt := range tests {
+		filter, err := NewConverter(tt.config).newAnnotationFilter()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var kept []string
+		for _, a := range filter.apply(annotations) {
+			kept = append(kept, string(rune('0'+a.ID)))
+		}
+		if got := strings.Join(kept, ","); got != tt.kept {
+			t.Errorf("%s: expected annotations %s, got %s", tt.name, tt.kept, got)
+		}
+	}
+
+	filter, err := NewConverter(Config{Since: "2024-03-01"}).newAnnotationFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filter.dropsTask(LSTask{}) {
+		t.Error("Expected an unannotated task to be dropped with -since")
+	}
+}
+
+func TestInvalidDateRange(t *testing.T) {
+	for _, config := range []Config{
+		{Since: "last week", InputFormat: InputLSJSON},
+		{Since: "2024-03-08", Until: "2024-03-01", InputFormat: InputLSJSON},
+		{Since: "2024-03-01", InputFormat: InputYOLO},
+	} {
+		if err := NewConverter(config).validateDateRange(); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+	if err := NewConverter(Config{Until: "2024-03-08", InputFormat: InputLSJSON}).validateDateRange(); err != nil {
+		t.Errorf("Expected a valid range, got %v", err)
+	}
+}
+
+func TestConvertSince(t *testing.T) {
+	sourceDir := t.TempDir()
+	createAttributedExport(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputLSJSON, Since: "2024-03-02", TaskMetadata: TaskMetadataFile})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	entries, err := os.ReadDir(filepath.Join(outputDir, "labels", "train"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "a1b2-cat.txt" {
+		t.Errorf("Expected only the task annotated in the window, got %v", entries)
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, "tasks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []TaskMetadata
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || len(records[0].AnnotationIDs) != 1 || records[0].AnnotationIDs[0] != 12 {
+		t.Errorf("Expected only annotation 12 to be used, got %+v", records)
+	}
+	if !strings.Contains(out.String(), "1 outside -since/-until; skipped 3 tasks") {
+		t.Errorf("Expected the filter report, got:\n%s", out.String())
+	}
+}
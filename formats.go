@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Output formats accepted by -output-format
+const (
+	OutputYOLO     = "yolo"
+	OutputTFRecord = "tfrecord"
+	OutputCreateML = "createml"
+	OutputDarknet  = "darknet"
+	// OutputHuggingFace is the imagefolder layout of Hugging Face datasets
+	OutputHuggingFace = "huggingface"
+	// OutputRoboflow is the YOLO layout Roboflow imports datasets from
+	OutputRoboflow = "roboflow"
+)
+
+// outputFormatNames are the display names of the output formats
+var outputFormatNames = map[string]string{
+	OutputYOLO:        "YOLO",
+	OutputTFRecord:    "TFRecord",
+	OutputCreateML:    "Create ML",
+	OutputDarknet:     "Darknet",
+	OutputHuggingFace: "Hugging Face",
+	OutputRoboflow:    "Roboflow",
+}
+
+// validateOutputFormat checks the output format and rejects the options it
+// can't be combined with. Every format other than YOLO writes detection
+// boxes from the validated pairs.
+func (c *Converter) validateOutputFormat() error {
+	type option struct {
+		set  bool
+		flag string
+	}
+	unsupported := []option{
+		{c.config.Classify || c.config.MultiLabel, "-classify and -multi-label"},
+		{c.config.Crop, "-crop"},
+		{c.config.SkeletonFile != "", "-skeleton"},
+		{c.config.Incremental, "-incremental and -watch"},
+	}
+	switch c.config.OutputFormat {
+	case "", OutputYOLO:
+		return nil
+	case OutputTFRecord:
+		// Images are embedded in the records as they are
+		unsupported = append(unsupported, option{c.config.Move, "-move"}, option{c.config.StripMetadata, "-strip-metadata"})
+	case OutputCreateML:
+	case OutputDarknet:
+		// The file lists name the images by absolute path, and the labels
+		// are rewritten as boxes rather than moved
+		if archiveFormat(c.config.OutputDir) != "" {
+			return fmt.Errorf("-output-format darknet requires a directory output")
+		}
+		unsupported = append(unsupported, option{c.config.Move, "-move"})
+	case OutputHuggingFace:
+	case OutputRoboflow:
+	default:
+		return fmt.Errorf("unsupported output format: %s", c.config.OutputFormat)
+	}
+	for _, option := range unsupported {
+		if option.set {
+			return fmt.Errorf("%s can't be combined with -output-format %s", option.flag, c.config.OutputFormat)
+		}
+	}
+	return nil
+}
+
+// writeOutputFormat writes the splits in an output format other than YOLO
+func (c *Converter) writeOutputFormat(classes []string, splits map[string][]LabelPair) error {
+	switch c.config.OutputFormat {
+	case OutputTFRecord:
+		return c.writeTFRecords(classes, splits)
+	case OutputCreateML:
+		return c.writeCreateML(classes, splits)
+	case OutputDarknet:
+		return c.writeDarknet(classes, splits)
+	case OutputHuggingFace:
+		return c.writeHuggingFace(classes, splits)
+	case OutputRoboflow:
+		return c.writeRoboflow(classes, splits)
+	}
+	return fmt.Errorf("unsupported output format: %s", c.config.OutputFormat)
+}
+
+// labelBox is a region of a label file as an axis-aligned box: the box
+// itself, or the box enclosing a polygon or oriented box
+type labelBox struct {
+	ClassID int
+	// Bounds are the normalized x_center, y_center, width and height
+	Bounds [4]float64
+}
+
+// corners returns the normalized corners of the box, clipped to the image
+func (b labelBox) corners() (x0, y0, x1, y1 float64) {
+	return clamp01(b.Bounds[0] - b.Bounds[2]/2), clamp01(b.Bounds[1] - b.Bounds[3]/2),
+		clamp01(b.Bounds[0] + b.Bounds[2]/2), clamp01(b.Bounds[1] + b.Bounds[3]/2)
+}
+
+// readLabelBoxes reads the regions of a label file as boxes, skipping
+// invalid lines and class IDs outside the class list; validation has already
+// reported those
+func readLabelBoxes(labelPath string, numClasses int) ([]labelBox, error) {
+	data, err := os.ReadFile(labelPath)
+	if err != nil {
+		return nil, err
+	}
+	var boxes []labelBox
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.Fields(line)
+		kind, _ := parseLabelLine(parts)
+		if kind == "" {
+			continue
+		}
+		classID, _ := strconv.Atoi(parts[0])
+		if classID < 0 || classID >= numClasses {
+			continue
+		}
+		boxes = append(boxes, labelBox{ClassID: classID, Bounds: labelBounds(kind, parts)})
+	}
+	return boxes, nil
+}
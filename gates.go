@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// QualityGates are the thresholds a dataset must meet before it is written,
+// read from the -gates YAML file:
+//
+//	min_images: 500
+//	min_annotations_per_class: 50
+//	class_min_annotations: {forklift: 20}
+//	max_invalid_line_rate: 0.01
+//	max_empty_file_rate: 0.05
+//
+// Gates that are left out aren't checked. The rates are fractions: invalid
+// lines of all label lines, and empty label files of all label files.
+type QualityGates struct {
+	MinImages              int `yaml:"min_images"`
+	MinAnnotationsPerClass int `yaml:"min_annotations_per_class"`
+	// ClassMinAnnotations overrides MinAnnotationsPerClass by class name
+	ClassMinAnnotations map[string]int `yaml:"class_min_annotations"`
+	MaxInvalidLineRate  *float64       `yaml:"max_invalid_line_rate"`
+	MaxEmptyFileRate    *float64       `yaml:"max_empty_file_rate"`
+}
+
+// GateResult is the outcome of one quality gate
+type GateResult struct {
+	Gate   string
+	Passed bool
+	// Detail states the measured value against the threshold
+	Detail string
+}
+
+// loadGates reads and checks a gates file. Unknown keys are errors, so a
+// misspelled gate can't silently pass.
+func loadGates(path string) (*QualityGates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gates file: %w", err)
+	}
+	var gates QualityGates
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&gates); err != nil {
+		return nil, fmt.Errorf("failed to parse gates file %s: %w", path, err)
+	}
+
+	if gates.MinImages < 0 || gates.MinAnnotationsPerClass < 0 {
+		return nil, fmt.Errorf("gates file %s: minimums can't be negative", path)
+	}
+	for name, min := range gates.ClassMinAnnotations {
+		if min < 0 {
+			return nil, fmt.Errorf("gates file %s: minimum for class %q can't be negative", path, name)
+		}
+	}
+	for _, rate := range []*float64{gates.MaxInvalidLineRate, gates.MaxEmptyFileRate} {
+		if rate != nil && (*rate < 0 || *rate > 1) {
+			return nil, fmt.Errorf("gates file %s: rates must be between 0 and 1", path)
+		}
+	}
+	return &gates, nil
+}
+
+// validateGates loads the -gates file before the conversion starts
+func (c *Converter) validateGates() error {
+	c.gates = nil
+	if c.config.Gates == "" {
+		return nil
+	}
+	if c.config.Classify || c.config.MultiLabel || c.config.Crop {
+		return fmt.Errorf("-gates cannot be combined with -classify, -multi-label or -crop")
+	}
+	gates, err := loadGates(c.config.Gates)
+	if err != nil {
+		return err
+	}
+	c.gates = gates
+	return nil
+}
+
+// evaluate checks the validated pairs against the gates
+func (g *QualityGates) evaluate(classes []string, pairs []LabelPair, stats *ValidationStats) []GateResult {
+	var results []GateResult
+	if g.MinImages > 0 {
+		results = append(results, GateResult{
+			Gate:   "min_images",
+			Passed: len(pairs) >= g.MinImages,
+			Detail: fmt.Sprintf("%d images (min %d)", len(pairs), g.MinImages),
+		})
+	}
+
+	if g.MinAnnotationsPerClass > 0 || len(g.ClassMinAnnotations) > 0 {
+		var short []string
+		for id, name := range classes {
+			min := g.MinAnnotationsPerClass
+			if override, ok := g.ClassMinAnnotations[name]; ok {
+				min = override
+			}
+			if count := stats.ClassCounts[id]; count < min {
+				short = append(short, fmt.Sprintf("%s %d/%d", name, count, min))
+			}
+		}
+		detail := fmt.Sprintf("every class has at least %d annotations", g.MinAnnotationsPerClass)
+		if len(g.ClassMinAnnotations) > 0 {
+			detail = "every class has its minimum of annotations"
+		}
+		if len(short) > 0 {
+			detail = fmt.Sprintf("%d classes below the minimum: %s", len(short), strings.Join(short, ", "))
+		}
+		results = append(results, GateResult{Gate: "min_annotations_per_class", Passed: len(short) == 0, Detail: detail})
+	}
+
+	if g.MaxInvalidLineRate != nil {
+		rate := fraction(stats.InvalidLines, stats.TotalAnnotations+stats.InvalidLines)
+		results = append(results, GateResult{
+			Gate:   "max_invalid_line_rate",
+			Passed: rate <= *g.MaxInvalidLineRate,
+			Detail: fmt.Sprintf("%d of %d lines invalid, %.2f%% (max %.2f%%)", stats.InvalidLines, stats.TotalAnnotations+stats.InvalidLines, rate*100, *g.MaxInvalidLineRate*100),
+		})
+	}
+
+	if g.MaxEmptyFileRate != nil {
+		rate := fraction(stats.EmptyFiles, stats.TotalFiles)
+		results = append(results, GateResult{
+			Gate:   "max_empty_file_rate",
+			Passed: rate <= *g.MaxEmptyFileRate,
+			Detail: fmt.Sprintf("%d of %d label files empty, %.2f%% (max %.2f%%)", stats.EmptyFiles, stats.TotalFiles, rate*100, *g.MaxEmptyFileRate*100),
+		})
+	}
+	return results
+}
+
+// fraction returns n/total, or 0 for an empty total
+func fraction(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// checkGates evaluates the quality gates after validation and prints every
+// result, failing the conversion before anything is written when any gate
+// fails
+func (c *Converter) checkGates(classes []string, pairs []LabelPair, stats *ValidationStats) error {
+	if c.gates == nil {
+		return nil
+	}
+	results := c.gates.evaluate(classes, pairs, stats)
+
+	fmt.Fprintln(c.out, "\nQuality gates:")
+	var failed []string
+	for _, result := range results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+			failed = append(failed, result.Gate)
+		}
+		fmt.Fprintf(c.out, "  %s %-26s %s\n", status, result.Gate, result.Detail)
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("%w: %d of %d quality gates failed: %s", ErrValidationFailed, len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return nil
+}
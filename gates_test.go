@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGates(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "gates.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadGatesErrors(t *testing.T) {
+	for _, content := range []string{
+		"min_image: 10\n",
+		"max_empty_file_rate: 1.5\n",
+		"min_annotations_per_class: -1\n",
+		"class_min_annotations: {person: -2}\n",
+	} {
+		if _, err := loadGates(writeGates(t, content)); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
+
+func TestConvertQualityGates(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	// One empty label file among four; book has 3 annotations, person 2
+	if err := os.WriteFile(filepath.Join(sourceDir, "images", "image4.jpg"), []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image4.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		gates  string
+		failed string
+	}{
+		{"passing", "min_images: 4\nmin_annotations_per_class: 2\nmax_invalid_line_rate: 0\nmax_empty_file_rate: 0.25\n", ""},
+		{"too few annotations", "min_annotations_per_class: 2\nclass_min_annotations: {person: 3}\n", "min_annotations_per_class"},
+		{"too many empty files", "min_images: 2\nmax_empty_file_rate: 0.2\n", "max_empty_file_rate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "out")
+			var out strings.Builder
+			converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42, Gates: writeGates(t, tt.gates)})
+			converter.SetOutput(&out)
+			err := converter.Convert()
+
+			if tt.failed == "" {
+				if err != nil {
+					t.Fatalf("Expected the gates to pass, got %v\n%s", err, out.String())
+				}
+				if strings.Contains(out.String(), "FAIL") {
+					t.Errorf("Expected every gate to pass:\n%s", out.String())
+				}
+				return
+			}
+			if !errors.Is(err, ErrValidationFailed) || !strings.Contains(err.Error(), tt.failed) {
+				t.Fatalf("Expected %s to fail, got %v", tt.failed, err)
+			}
+			if !strings.Contains(out.String(), "FAIL "+tt.failed) {
+				t.Errorf("Expected the report to show the failed gate:\n%s", out.String())
+			}
+			if _, err := os.Stat(filepath.Join(outputDir, "data.yaml")); !os.IsNotExist(err) {
+				t.Errorf("Expected nothing to be written when a gate fails")
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// matchGlob matches a slash-separated path against a glob pattern in which
+// "**" matches any number of directories. Patterns without a slash match the
+// file name alone.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+// sourceFilter selects source images with -include and -exclude patterns
+type sourceFilter struct {
+	include []string
+	exclude []string
+}
+
+// newSourceFilter parses comma-separated include and exclude patterns
+func newSourceFilter(include, exclude string) (*sourceFilter, error) {
+	f := &sourceFilter{include: splitList(include), exclude: splitList(exclude)}
+	for _, pattern := range append(append([]string{}, f.include...), f.exclude...) {
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return f, nil
+}
+
+// matches reports whether an image, given by its slash-separated path
+// relative to the images directory, is converted
+func (f *sourceFilter) matches(name string) bool {
+	for _, pattern := range f.exclude {
+		if matchGlob(pattern, name) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
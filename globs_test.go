@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"cam1_*.jpg", "cam1_0001.jpg", true},
+		{"cam1_*.jpg", "site/cam1_0001.jpg", true},
+		{"cam1_*.jpg", "cam2_0001.jpg", false},
+		{"**/thumbnails/**", "thumbnails/a.jpg", true},
+		{"**/thumbnails/**", "site/day1/thumbnails/a.jpg", true},
+		{"**/thumbnails/**", "site/a.jpg", false},
+		{"site/*.jpg", "site/a.jpg", true},
+		{"site/*.jpg", "site/day1/a.jpg", false},
+		{"site/**/*.jpg", "site/a.jpg", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, expected %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIncludeExcludePatterns(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	thumbnails := filepath.Join(tempDir, "images", "thumbnails")
+	if err := os.MkdirAll(thumbnails, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(thumbnails, "thumb1.jpg"), []byte("thumbnail"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "labels", "thumb1.txt"), []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{SourceDir: tempDir, Include: "image1.*,thumb*", Exclude: "**/thumbnails/**"})
+	converter.SetOutput(io.Discard)
+	pairs, err := converter.GetImageLabelPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 1 || filepath.Base(pairs[0].ImagePath) != "image1.jpg" {
+		t.Fatalf("Expected only image1, got %v", pairs)
+	}
+
+	// Labels of skipped images are not orphans
+	if orphans, err := converter.FindOrphanLabels(pairs); err != nil || len(orphans) != 0 {
+		t.Errorf("Expected no orphans, got %v, %v", orphans, err)
+	}
+
+	converter.config.Include = "[bad"
+	if _, err := converter.GetImageLabelPairs(); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
+
+func TestImageExtensions(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, dir := range []string{"images", "labels"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"images/IMG_1.JPG":  "image",
+		"labels/IMG_1.txt":  "0 0.5 0.5 0.2 0.2\n",
+		"images/scan.png":   "image",
+		"labels/scan.TXT":   "0 0.5 0.5 0.2 0.2\n",
+		"images/anim.GIF":   "image",
+		"labels/anim.txt":   "0 0.5 0.5 0.2 0.2\n",
+		"images/notes.docx": "not an image",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	converter := NewConverter(Config{SourceDir: tempDir})
+	converter.SetOutput(io.Discard)
+	pairs, err := converter.GetImageLabelPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("Expected IMG_1.JPG and scan.png to pair, got %v", pairs)
+	}
+	if filepath.Base(pairs[1].LabelPath) != "scan.TXT" {
+		t.Errorf("Expected the upper-case label extension to match, got %s", pairs[1].LabelPath)
+	}
+
+	converter.config.ImageExtensions = "gif, .AVIF"
+	pairs, err = converter.GetImageLabelPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 3 {
+		t.Errorf("Expected -image-ext gif to pick up anim.GIF, got %v", pairs)
+	}
+	if !converter.isImageFile("photo.avif") || converter.isImageFile("notes.docx") {
+		t.Error("Unexpected image extension matching")
+	}
+}
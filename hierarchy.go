@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Granularities of a conversion with a class hierarchy
+const (
+	// GranularityLeaf keeps the classes as annotated
+	GranularityLeaf = "leaf"
+	// GranularityParent rolls classes up to their top-level parent
+	GranularityParent = "parent"
+	// GranularityBoth writes the leaf dataset to -output and the rolled-up
+	// one next to it
+	GranularityBoth = "both"
+)
+
+// loadHierarchy reads a class hierarchy, a YAML mapping of each parent class
+// to its children; a child is a name, or a mapping of its own children:
+//
+//	vehicle:
+//	  car: [sedan, suv]
+//	  truck:
+//	animal: [cat, dog]
+//
+// and returns the top-level parent of every class below one
+func loadHierarchy(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hierarchy file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse hierarchy file %s: %w", path, err)
+	}
+	roots := make(map[string]string)
+	if len(doc.Content) == 0 {
+		return roots, nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("hierarchy file %s must map parent classes to their children", path)
+	}
+	if err := addHierarchyChildren(doc.Content[0], "", roots); err != nil {
+		return nil, fmt.Errorf("hierarchy file %s: %w", path, err)
+	}
+	return roots, nil
+}
+
+// addHierarchyChildren records root as the top-level parent of the classes
+// below a node; at the top level, where root is "", each key is a root
+func addHierarchyChildren(node *yaml.Node, root string, roots map[string]string) error {
+	add := func(name string) error {
+		if name == root {
+			return fmt.Errorf("class %q is its own child", name)
+		}
+		if other, ok := roots[name]; ok && other != root {
+			return fmt.Errorf("class %q is under both %q and %q", name, other, root)
+		}
+		roots[name] = root
+		return nil
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			childRoot := root
+			if root == "" {
+				childRoot = name
+			} else if err := add(name); err != nil {
+				return err
+			}
+			if err := addHierarchyChildren(node.Content[i+1], childRoot, roots); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := addHierarchyChildren(child, root, roots); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil
+		}
+		if root == "" {
+			return fmt.Errorf("line %d: expected a mapping of parent classes", node.Line)
+		}
+		return add(node.Value)
+	default:
+		return fmt.Errorf("line %d: unexpected %s", node.Line, node.Tag)
+	}
+	return nil
+}
+
+// validateHierarchy checks -granularity and the options a two-dataset
+// conversion can't honor
+func (c *Converter) validateHierarchy() error {
+	switch c.config.Granularity {
+	case "", GranularityLeaf:
+		return nil
+	case GranularityParent, GranularityBoth:
+	default:
+		return fmt.Errorf("unknown granularity %q (use leaf, parent or both)", c.config.Granularity)
+	}
+	if c.config.Hierarchy == "" {
+		return fmt.Errorf("-granularity %s requires -hierarchy", c.config.Granularity)
+	}
+	if c.config.Crop {
+		return fmt.Errorf("-granularity %s cannot be combined with -crop", c.config.Granularity)
+	}
+	if c.config.Granularity == GranularityBoth {
+		switch {
+		case c.config.Move:
+			return fmt.Errorf("-granularity both cannot be combined with -move")
+		case c.config.Incremental:
+			return fmt.Errorf("-granularity both cannot be combined with -incremental or -watch")
+		case c.config.Resume:
+			return fmt.Errorf("-granularity both cannot be combined with -resume")
+		}
+	}
+	return nil
+}
+
+// rollUpClasses renames every class below a parent in the hierarchy to its
+// top-level parent, merging the classes and renumbering the labels
+func (c *Converter) rollUpClasses(classes []string, pairs []LabelPair) ([]string, []LabelPair, error) {
+	roots, err := loadHierarchy(c.config.Hierarchy)
+	if err != nil {
+		return nil, nil, err
+	}
+	parents, remap := foldClasses(classes, roots)
+	if pairs, err = c.renumberClasses(pairs, remap, "hierarchy"); err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprintf(c.out, "Rolled %d classes up to %d: %v\n", len(classes), len(parents), parents)
+	return parents, pairs, nil
+}
+
+// parentOutputDir returns where -granularity both writes the rolled-up
+// dataset: next to the output, with _parent added to its name
+func parentOutputDir(outputDir string) string {
+	lower := strings.ToLower(outputDir)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			n := len(outputDir) - len(ext)
+			return outputDir[:n] + "_parent" + outputDir[n:]
+		}
+	}
+	return strings.TrimRight(outputDir, string(filepath.Separator)) + "_parent"
+}
+
+// writeParentDataset writes the rolled-up dataset of -granularity both, with
+// the same split as the leaf dataset
+func (c *Converter) writeParentDataset(classes []string, trainPairs, valPairs []LabelPair) error {
+	// Roll up both splits at once, then split them again
+	all := append(append([]LabelPair{}, trainPairs...), valPairs...)
+	parents, all, err := c.rollUpClasses(classes, all)
+	if err != nil {
+		return err
+	}
+
+	config := c.config
+	config.OutputDir = parentOutputDir(c.config.OutputDir)
+	parent := &Converter{config: config, out: c.out, ctx: c.ctx, task: c.task, skeleton: c.skeleton, labelConfig: c.labelConfig,
+		staging: c.staging, started: c.started, sourceSums: c.sourceSums, findings: c.findings, skipped: c.skipped}
+	fmt.Fprintf(c.out, "\nWriting the parent-class dataset to %s\n", config.OutputDir)
+	return parent.writeDataset(parents, all[:len(trainPairs)], all[len(trainPairs):])
+}
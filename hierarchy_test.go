@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadHierarchy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "hierarchy.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	roots, err := loadHierarchy(write("vehicle:\n  car: [sedan, suv]\n  truck:\nanimal: [cat, dog]\nplant:\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"car": "vehicle", "sedan": "vehicle", "suv": "vehicle", "truck": "vehicle", "cat": "animal", "dog": "animal"}
+	if !reflect.DeepEqual(roots, want) {
+		t.Errorf("Expected %v, got %v", want, roots)
+	}
+
+	for content, want := range map[string]string{
+		"vehicle: [car]\nanimal: [car]\n": "under both",
+		"vehicle: [vehicle]\n":            "its own child",
+		"- vehicle\n":                     "must map parent classes",
+	} {
+		if _, err := loadHierarchy(write(content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error containing %q, got %v", content, want, err)
+		}
+	}
+}
+
+func TestParentOutputDir(t *testing.T) {
+	for output, want := range map[string]string{
+		"./yolo_dataset": "./yolo_dataset_parent",
+		"out/":           "out_parent",
+		"data.tar.gz":    "data_parent.tar.gz",
+		"/tmp/DATA.ZIP":  "/tmp/DATA_parent.ZIP",
+		"export.v2.tgz":  "export.v2_parent.tgz",
+	} {
+		if got := parentOutputDir(output); got != want {
+			t.Errorf("parentOutputDir(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+// createHierarchySource writes a YOLO export with sedan, person and suv
+// labels and a hierarchy rolling the cars up to vehicle
+func createHierarchySource(t *testing.T) (string, string) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	files := map[string]string{
+		"classes.txt":       "sedan\nperson\nsuv\n",
+		"labels/image1.txt": "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n",
+		"labels/image2.txt": "2 0.4 0.6 0.2 0.4\n",
+		"labels/image3.txt": "1 0.7 0.3 0.3 0.2\n2 0.1 0.9 0.1 0.1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hierarchy := filepath.Join(t.TempDir(), "hierarchy.yaml")
+	if err := os.WriteFile(hierarchy, []byte("vehicle: [sedan, suv]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return sourceDir, hierarchy
+}
+
+func TestConvertGranularityBoth(t *testing.T) {
+	sourceDir, hierarchy := createHierarchySource(t)
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.6, Seed: 42, Hierarchy: hierarchy, Granularity: GranularityBoth})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	parentDir := outputDir + "_parent"
+	for dir, want := range map[string]string{outputDir: "sedan,person,suv", parentDir: "vehicle,person"} {
+		names, err := loadClassNames(filepath.Join(dir, "data.yaml"))
+		if err != nil || strings.Join(names, ",") != want {
+			t.Errorf("%s: expected classes %s, got %v, %v", dir, want, names, err)
+		}
+	}
+
+	// Both datasets share the split
+	for _, split := range []string{"train", "val"} {
+		leaf, _ := os.ReadDir(filepath.Join(outputDir, "images", split))
+		parent, _ := os.ReadDir(filepath.Join(parentDir, "images", split))
+		if len(leaf) == 0 && split == "train" || len(leaf) != len(parent) {
+			t.Fatalf("Expected the same %s images in both datasets, got %d and %d", split, len(leaf), len(parent))
+		}
+		for i := range leaf {
+			if leaf[i].Name() != parent[i].Name() {
+				t.Errorf("Split %s differs: %s and %s", split, leaf[i].Name(), parent[i].Name())
+			}
+		}
+	}
+
+	labels := map[string]string{}
+	for _, split := range []string{"train", "val"} {
+		for _, name := range []string{"image1.txt", "image2.txt", "image3.txt"} {
+			if data, err := os.ReadFile(filepath.Join(parentDir, "labels", split, name)); err == nil {
+				labels[name] = string(data)
+			}
+		}
+	}
+	want := map[string]string{
+		"image1.txt": "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n",
+		"image2.txt": "0 0.4 0.6 0.2 0.4\n",
+		"image3.txt": "1 0.7 0.3 0.3 0.2\n0 0.1 0.9 0.1 0.1\n",
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("Expected rolled-up labels %v, got %v", want, labels)
+	}
+}
+
+func TestConvertGranularityParent(t *testing.T) {
+	sourceDir, hierarchy := createHierarchySource(t)
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, Hierarchy: hierarchy, Granularity: GranularityParent})
+	converter.SetOutput(&strings.Builder{})
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+	names, err := loadClassNames(filepath.Join(outputDir, "data.yaml"))
+	if err != nil || strings.Join(names, ",") != "vehicle,person" {
+		t.Errorf("Expected the parent classes, got %v, %v", names, err)
+	}
+	if _, err := os.Stat(outputDir + "_parent"); !os.IsNotExist(err) {
+		t.Errorf("Expected no second dataset, got %v", err)
+	}
+
+	converter = NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, Granularity: GranularityParent})
+	converter.SetOutput(&strings.Builder{})
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "requires -hierarchy") {
+		t.Errorf("Expected -hierarchy to be required, got %v", err)
+	}
+}
+
+func TestGranularityRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Crop: true, Granularity: GranularityBoth, Hierarchy: "does-not-exist.yaml"})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-granularity both") {
+		t.Errorf("Expected -granularity both to be rejected with -crop, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Thresholds for the heuristics of the HTML report
+const (
+	// tinyBoxArea is the normalized area below which a box counts as tiny
+	tinyBoxArea = 0.0001
+	// overlapIoU is the IoU above which two regions of a file look duplicated
+	overlapIoU = 0.8
+	// thumbnailSize is the longest side of report thumbnails in pixels
+	thumbnailSize = 320
+)
+
+// reportShape is a valid region of a label file, as drawn on the thumbnail
+type reportShape struct {
+	bounds  [4]float64
+	outline []float64
+	flagged bool
+}
+
+// reportEntry is a label file shown in the HTML report
+type reportEntry struct {
+	Image     string
+	File      string
+	Score     int
+	Issues    []string
+	Thumbnail template.URL
+
+	pair   LabelPair
+	shapes []reportShape
+}
+
+// shapeOutline returns the points to draw for a valid label line
+func shapeOutline(kind string, parts []string, bounds [4]float64) []float64 {
+	if kind == AnnotationSegment || kind == AnnotationOBB {
+		coords := make([]float64, len(parts)-1)
+		for i := range coords {
+			coords[i], _ = strconv.ParseFloat(parts[i+1], 64)
+		}
+		return coords
+	}
+	x0, y0 := bounds[0]-bounds[2]/2, bounds[1]-bounds[3]/2
+	x1, y1 := bounds[0]+bounds[2]/2, bounds[1]+bounds[3]/2
+	return []float64{x0, y0, x1, y0, x1, y1, x0, y1}
+}
+
+// inspectLabelFile scores a label file: invalid lines weigh three, tiny boxes
+// and near-duplicate regions one each
+func (c *Converter) inspectLabelFile(pair LabelPair, findings []ValidationFinding) reportEntry {
+	entry := reportEntry{Image: filepath.Base(pair.ImagePath), File: filepath.Base(pair.LabelPath), pair: pair}
+	for _, f := range findings {
+		entry.Score += 3
+		if f.Line > 0 {
+			entry.Issues = append(entry.Issues, fmt.Sprintf("line %d: %s: %s", f.Line, f.Message, f.Content))
+		} else {
+			entry.Issues = append(entry.Issues, f.Message)
+		}
+	}
+
+	data, err := os.ReadFile(pair.LabelPath)
+	if err != nil {
+		return entry
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		kind, warning := c.parseLine(parts)
+		if warning != "" {
+			continue
+		}
+
+		bounds := labelBounds(kind, parts)
+		shape := reportShape{bounds: bounds, outline: shapeOutline(kind, parts, bounds)}
+		if bounds[2]*bounds[3] < tinyBoxArea {
+			shape.flagged = true
+			entry.Score++
+			entry.Issues = append(entry.Issues, fmt.Sprintf("line %d: tiny region (%.4f%% of the image)", i+1, bounds[2]*bounds[3]*100))
+		}
+		for j := range entry.shapes {
+			if iou := boxIoU(entry.shapes[j].bounds, bounds); iou > overlapIoU {
+				shape.flagged, entry.shapes[j].flagged = true, true
+				entry.Score++
+				entry.Issues = append(entry.Issues, fmt.Sprintf("line %d: overlaps an earlier region (IoU %.2f)", i+1, iou))
+				break
+			}
+		}
+		entry.shapes = append(entry.shapes, shape)
+	}
+	return entry
+}
+
+// drawLine draws a one pixel line with Bresenham's algorithm
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, col color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		img.Set(x0, y0, col)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if e2 := 2 * err; e2 >= dy {
+			err += dy
+			x0 += sx
+		} else {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// thumbnail renders a scaled copy of the entry's image with its regions
+// outlined, flagged ones in red, as a JPEG data URL
+func (e *reportEntry) thumbnail() (template.URL, error) {
+	src, err := decodeImage(e.pair.ImagePath)
+	if err != nil {
+		return "", err
+	}
+	// Regions are drawn on the upright image
+	src = orient(src, exifOrientation(e.pair.ImagePath))
+
+	b := src.Bounds()
+	scale := float64(thumbnailSize) / float64(max(b.Dx(), b.Dy()))
+	if scale > 1 {
+		scale = 1
+	}
+	w, h := max(1, int(float64(b.Dx())*scale)), max(1, int(float64(b.Dy())*scale))
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	ok, flagged := color.RGBA{0, 200, 0, 255}, color.RGBA{230, 0, 0, 255}
+	for _, shape := range e.shapes {
+		col := ok
+		if shape.flagged {
+			col = flagged
+		}
+		n := len(shape.outline) / 2
+		for i := 0; i < n; i++ {
+			j := (i + 1) % n
+			drawLine(dst,
+				int(shape.outline[2*i]*float64(w-1)), int(shape.outline[2*i+1]*float64(h-1)),
+				int(shape.outline[2*j]*float64(w-1)), int(shape.outline[2*j+1]*float64(h-1)),
+				col)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return "", err
+	}
+	return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Label validation report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.entry { display: flex; gap: 1.5em; padding: 1em 0; border-bottom: 1px solid #ddd; }
+.entry img { max-width: 320px; }
+.missing { width: 320px; color: #888; }
+h2 { font-size: 1.1em; margin: 0 0 .5em; }
+code { background: #f4f4f4; }
+</style>
+</head>
+<body>
+<h1>Label validation report</h1>
+<p>{{.Stats.TotalFiles}} files, {{.Stats.TotalAnnotations}} annotations, {{.Stats.InvalidLines}} invalid lines.
+Showing the {{len .Entries}} files with the most problems: invalid lines,
+<span style="color:#e60000">tiny or overlapping regions</span>.</p>
+{{range .Entries}}
+<div class="entry">
+{{if .Thumbnail}}<img src="{{.Thumbnail}}" alt="{{.Image}}">{{else}}<div class="missing">No preview</div>{{end}}
+<div>
+<h2>{{.Image}} <small>({{.File}}, score {{.Score}})</small></h2>
+<ul>{{range .Issues}}<li><code>{{.}}</code></li>{{end}}</ul>
+</div>
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// writeHTMLReport writes an HTML report with annotated thumbnails of the
+// worst label files to HTMLReportFile
+func (c *Converter) writeHTMLReport(pairs []LabelPair, stats *ValidationStats) error {
+	byFile := make(map[string][]ValidationFinding)
+	for _, f := range c.findings {
+		byFile[f.File] = append(byFile[f.File], f)
+	}
+
+	var entries []reportEntry
+	for _, pair := range pairs {
+		entry := c.inspectLabelFile(pair, byFile[c.findingFile(pair.LabelPath)])
+		if entry.Score > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Score > entries[j].Score
+	})
+
+	top := c.config.HTMLReportTop
+	if top <= 0 {
+		top = 50
+	}
+	if len(entries) > top {
+		entries = entries[:top]
+	}
+	for i := range entries {
+		if thumb, err := entries[i].thumbnail(); err == nil {
+			entries[i].Thumbnail = thumb
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, struct {
+		Stats   *ValidationStats
+		Entries []reportEntry
+	}{stats, entries}); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	if err := os.WriteFile(c.config.HTMLReportFile, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	fmt.Fprintf(c.out, "Wrote HTML report of %d files to %s\n", len(entries), c.config.HTMLReportFile)
+	return nil
+}
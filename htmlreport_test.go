@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInspectLabelFile(t *testing.T) {
+	dir := t.TempDir()
+	labelPath := filepath.Join(dir, "crowd.txt")
+	content := "0 0.5 0.5 0.4 0.4\n" +
+		"0 0.505 0.5 0.4 0.4\n" + // near duplicate of the first box
+		"1 0.1 0.1 0.005 0.005\n" + // tiny
+		"1 0.8 0.8 0.1 0.1\n"
+	if err := os.WriteFile(labelPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{SourceDir: dir})
+	findings := []ValidationFinding{{Line: 5, Message: "Invalid coordinate", Content: "1 x 0.5 0.1 0.1"}}
+	entry := converter.inspectLabelFile(LabelPair{ImagePath: filepath.Join(dir, "crowd.jpg"), LabelPath: labelPath}, findings)
+
+	if entry.Score != 5 || len(entry.Issues) != 3 {
+		t.Errorf("Expected score 5 with 3 issues, got %d: %v", entry.Score, entry.Issues)
+	}
+	flagged := 0
+	for _, shape := range entry.shapes {
+		if shape.flagged {
+			flagged++
+		}
+	}
+	if len(entry.shapes) != 4 || flagged != 3 {
+		t.Errorf("Expected 3 of 4 shapes flagged, got %d of %d", flagged, len(entry.shapes))
+	}
+}
+
+func TestDrawLine(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	red := color.RGBA{255, 0, 0, 255}
+	drawLine(img, 0, 0, 9, 9, red)
+	for i := 0; i < 10; i++ {
+		if img.RGBAAt(i, i) != red {
+			t.Errorf("Expected pixel %d,%d on the line", i, i)
+		}
+	}
+}
+
+func TestThumbnailAppliesEXIFOrientation(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "rotated.jpg")
+	writeOrientedJPEG(t, imagePath, 100, 50, 6)
+
+	entry := &reportEntry{pair: LabelPair{ImagePath: imagePath}}
+	url, err := entry.thumbnail()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(string(url), "data:image/jpeg;base64,"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds().Size(); got != (image.Point{50, 100}) {
+		t.Errorf("Expected an upright 50x100 thumbnail, got %v", got)
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "street.png")
+	writeTestImage(t, imagePath, 64, 48)
+	labelPath := filepath.Join(dir, "street.txt")
+	if err := os.WriteFile(labelPath, []byte("0 0.5 0.5 0.4 0.4\n0 0.5 0.5 0.4 0.4\n0 0.5 1.5 0.4 0.4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cleanPath := filepath.Join(dir, "clean.txt")
+	if err := os.WriteFile(cleanPath, []byte("0 0.5 0.5 0.4 0.4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pairs := []LabelPair{
+		{ImagePath: filepath.Join(dir, "clean.png"), LabelPath: cleanPath},
+		{ImagePath: imagePath, LabelPath: labelPath},
+	}
+
+	reportPath := filepath.Join(dir, "report.html")
+	converter := NewConverter(Config{SourceDir: dir, HTMLReportFile: reportPath})
+	converter.SetOutput(io.Discard)
+	stats, err := converter.ValidateLabels(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := converter.writeHTMLReport(pairs, stats); err != nil {
+		t.Fatalf("writeHTMLReport failed: %v", err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	for _, want := range []string{"street.png", "data:image/jpeg;base64,", "Non-normalized coordinates", "overlaps an earlier region"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected report to contain %q", want)
+		}
+	}
+	if strings.Contains(html, "clean.png") {
+		t.Error("Expected files without problems to be left out")
+	}
+}
+
+func TestHTMLReportRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Crop: true, HTMLReportFile: "report.html"})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-html-report") {
+		t.Errorf("Expected -html-report to be rejected with -crop, got %v", err)
+	}
+}
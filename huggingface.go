@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// huggingFaceSplits maps the converter's splits to the directories the
+// imagefolder loader recognizes
+var huggingFaceSplits = []struct{ split, dir string }{
+	{"train", "train"},
+	{"val", "validation"},
+}
+
+// HuggingFaceRecord is one line of a split's metadata.jsonl
+type HuggingFaceRecord struct {
+	FileName string             `json:"file_name"`
+	Objects  HuggingFaceObjects `json:"objects"`
+}
+
+// HuggingFaceObjects lists the boxes of an image as COCO-style pixel boxes
+// (x_min, y_min, width, height) and their class IDs
+type HuggingFaceObjects struct {
+	BBox     [][4]float64 `json:"bbox"`
+	Category []int        `json:"category"`
+}
+
+// huggingFaceStats counts images and boxes for the dataset card
+type huggingFaceStats struct {
+	images  map[string]int
+	boxes   map[string]int
+	byClass map[string][]int
+}
+
+// writeHuggingFace writes the splits in the imagefolder layout of Hugging
+// Face datasets: a folder per split with its images and a metadata.jsonl of
+// their boxes, plus a README.md dataset card with the class list and
+// statistics, ready for load_dataset or an upload to the Hub
+func (c *Converter) writeHuggingFace(classes []string, splits map[string][]LabelPair) error {
+	writer := c.output()
+	stats := huggingFaceStats{images: make(map[string]int), boxes: make(map[string]int), byClass: make(map[string][]int)}
+	for _, s := range huggingFaceSplits {
+		pairs := splits[s.split]
+		if err := writer.MkdirAll(s.dir); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", s.dir, err)
+		}
+		counts := make([]int, len(classes))
+
+		var metadata bytes.Buffer
+		unstripped := 0
+		for _, pair := range pairs {
+			if err := c.interrupted(); err != nil {
+				return err
+			}
+			record, err := huggingFaceRecord(pair, len(classes))
+			if err != nil {
+				return err
+			}
+			for _, id := range record.Objects.Category {
+				counts[id]++
+			}
+			stats.boxes[s.dir] += len(record.Objects.Category)
+			line, err := json.Marshal(record)
+			if err != nil {
+				return fmt.Errorf("failed to encode metadata of %s: %w", pair.ImagePath, err)
+			}
+			metadata.Write(append(line, '\n'))
+
+			kept, err := c.copyImage(path.Join(s.dir, record.FileName), pair.ImagePath)
+			if err != nil {
+				return err
+			}
+			if kept {
+				unstripped++
+			}
+		}
+		stats.images[s.dir] = len(pairs)
+		stats.byClass[s.dir] = counts
+
+		name := path.Join(s.dir, "metadata.jsonl")
+		if err := writer.WriteFile(name, metadata.Bytes()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if unstripped > 0 {
+			fmt.Fprintf(c.out, "Warning: metadata could not be stripped from %d %s images (only JPEG and PNG are supported)\n", unstripped, s.split)
+		}
+		fmt.Fprintf(c.out, "Copied %d %s images\n", len(pairs), s.split)
+	}
+
+	card, err := huggingFaceCard(datasetName(c.config.OutputDir), classes, stats)
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteFile("README.md", card); err != nil {
+		return fmt.Errorf("failed to write the dataset card: %w", err)
+	}
+	return nil
+}
+
+// huggingFaceRecord converts the labels of an image to pixel boxes
+func huggingFaceRecord(pair LabelPair, numClasses int) (HuggingFaceRecord, error) {
+	width, height, err := readImageSize(pair.ImagePath)
+	if err != nil {
+		return HuggingFaceRecord{}, fmt.Errorf("failed to read image size of %s: %w", pair.ImagePath, err)
+	}
+	boxes, err := readLabelBoxes(pair.LabelPath, numClasses)
+	if err != nil {
+		return HuggingFaceRecord{}, err
+	}
+
+	record := HuggingFaceRecord{
+		FileName: filepath.Base(pair.ImagePath),
+		Objects:  HuggingFaceObjects{BBox: [][4]float64{}, Category: []int{}},
+	}
+	for _, box := range boxes {
+		x0, y0, x1, y1 := box.corners()
+		record.Objects.BBox = append(record.Objects.BBox, [4]float64{
+			roundPixels(x0 * float64(width)), roundPixels(y0 * float64(height)),
+			roundPixels((x1 - x0) * float64(width)), roundPixels((y1 - y0) * float64(height)),
+		})
+		record.Objects.Category = append(record.Objects.Category, box.ClassID)
+	}
+	return record, nil
+}
+
+// datasetName returns the name of a dataset: its output directory or archive
+// without the extension
+func datasetName(outputPath string) string {
+	name := filepath.Base(outputPath)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// huggingFaceCard writes the dataset card: YAML metadata declaring the
+// features, with the classes as a ClassLabel, and the split sizes, followed
+// by tables of the statistics and a loading example
+func huggingFaceCard(name string, classes []string, stats huggingFaceStats) ([]byte, error) {
+	classNames := make(map[string]string, len(classes))
+	for i, class := range classes {
+		classNames[strconv.Itoa(i)] = class
+	}
+	type split struct {
+		Name        string `yaml:"name"`
+		NumExamples int    `yaml:"num_examples"`
+	}
+	var splitInfo []split
+	for _, s := range huggingFaceSplits {
+		splitInfo = append(splitInfo, split{s.dir, stats.images[s.dir]})
+	}
+	metadata := map[string]any{
+		"task_categories": []string{"object-detection"},
+		"tags":            []string{"label-studio", "yolo"},
+		"dataset_info": map[string]any{
+			"features": []any{
+				map[string]any{"name": "image", "dtype": "image"},
+				map[string]any{"name": "objects", "sequence": []any{
+					map[string]any{"name": "bbox", "sequence": "float32", "length": 4},
+					map[string]any{"name": "category", "dtype": map[string]any{"class_label": map[string]any{"names": classNames}}},
+				}},
+			},
+			"splits": splitInfo,
+		},
+	}
+	front, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the dataset card: %w", err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "---\n%s---\n\n# %s\n\n", front, name)
+	fmt.Fprintf(&b, "Object detection dataset converted from a Label Studio export with labelstudio-to-yolo. ")
+	fmt.Fprintf(&b, "Boxes are `[x_min, y_min, width, height]` in pixels; `category` indexes the classes below.\n\n")
+
+	fmt.Fprintf(&b, "## Splits\n\n| Split | Images | Objects |\n|-------|--------|---------|\n")
+	for _, s := range huggingFaceSplits {
+		fmt.Fprintf(&b, "| %s | %d | %d |\n", s.dir, stats.images[s.dir], stats.boxes[s.dir])
+	}
+
+	fmt.Fprintf(&b, "\n## Classes\n\n| ID | Class | Train objects | Validation objects |\n|----|-------|---------------|--------------------|\n")
+	for i, class := range classes {
+		fmt.Fprintf(&b, "| %d | %s | %d | %d |\n", i, strings.ReplaceAll(class, "|", `\|`), stats.byClass["train"][i], stats.byClass["validation"][i])
+	}
+
+	fmt.Fprintf(&b, "\n## Usage\n\n```python\nfrom datasets import load_dataset\n\ndataset = load_dataset(\"imagefolder\", data_dir=%q)\n```\n", name)
+	return b.Bytes(), nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConvertHuggingFace(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image1.jpg"), 100, 50)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image2.png"), 40, 40)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image3.jpeg"), 20, 20)
+
+	outputDir := filepath.Join(t.TempDir(), "street_signs")
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.7, Seed: 42, OutputFormat: OutputHuggingFace})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := make(map[string]HuggingFaceRecord)
+	for _, dir := range []string{"train", "validation"} {
+		file, err := os.Open(filepath.Join(outputDir, dir, "metadata.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var record HuggingFaceRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(outputDir, dir, record.FileName)); err != nil {
+				t.Errorf("Expected %s next to its metadata: %v", record.FileName, err)
+			}
+			records[record.FileName] = record
+		}
+		file.Close()
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %v", records)
+	}
+	// image1.txt: "0 0.5 0.5 0.3 0.3" and "1 0.2 0.8 0.1 0.1" on 100x50 pixels
+	objects := records["image1.jpg"].Objects
+	if len(objects.BBox) != 2 || objects.BBox[0] != [4]float64{35, 17.5, 30, 15} || objects.BBox[1] != [4]float64{15, 37.5, 10, 5} {
+		t.Errorf("Unexpected boxes %v", objects.BBox)
+	}
+	if len(objects.Category) != 2 || objects.Category[0] != 0 || objects.Category[1] != 1 {
+		t.Errorf("Unexpected categories %v", objects.Category)
+	}
+
+	card, err := os.ReadFile(filepath.Join(outputDir, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(string(card), "---\n", 3)
+	if len(parts) != 3 || parts[0] != "" {
+		t.Fatalf("Expected YAML front matter, got:\n%s", card)
+	}
+	var metadata struct {
+		DatasetInfo struct {
+			Features []struct {
+				Name     string `yaml:"name"`
+				Sequence []struct {
+					Name  string `yaml:"name"`
+					Dtype struct {
+						ClassLabel struct {
+							Names map[string]string `yaml:"names"`
+						} `yaml:"class_label"`
+					} `yaml:"dtype"`
+				} `yaml:"sequence"`
+			} `yaml:"features"`
+			Splits []struct {
+				Name        string `yaml:"name"`
+				NumExamples int    `yaml:"num_examples"`
+			} `yaml:"splits"`
+		} `yaml:"dataset_info"`
+	}
+	if err := yaml.Unmarshal([]byte(parts[1]), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	names := metadata.DatasetInfo.Features[1].Sequence[1].Dtype.ClassLabel.Names
+	if names["0"] != "book" || names["1"] != "person" {
+		t.Errorf("Expected the classes as a ClassLabel, got %v", names)
+	}
+	splits := metadata.DatasetInfo.Splits
+	if len(splits) != 2 || splits[0].NumExamples+splits[1].NumExamples != 3 {
+		t.Errorf("Unexpected split sizes %+v", splits)
+	}
+	for _, want := range []string{"# street_signs\n", "| 0 | book |", `data_dir="street_signs"`} {
+		if !strings.Contains(parts[2], want) {
+			t.Errorf("Expected the card to contain %q:\n%s", want, parts[2])
+		}
+	}
+}
+
+func TestDatasetName(t *testing.T) {
+	for path, want := range map[string]string{
+		"/data/street_signs":      "street_signs",
+		"out/street_signs.tar.gz": "street_signs",
+		"street_signs.zip":        "street_signs",
+	} {
+		if got := datasetName(path); got != want {
+			t.Errorf("datasetName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"os"
+	"sort"
+
+	"golang.org/x/image/draw"
+)
+
+// imageHashes identifies an image by its bytes and by its appearance
+type imageHashes struct {
+	// Content is the SHA-256 of the file
+	Content [sha256.Size]byte
+	// DHash and PHash are the difference and DCT hashes of the decoded
+	// image, valid when Decoded
+	DHash   uint64
+	PHash   uint64
+	Decoded bool
+}
+
+// hashImageFile hashes an image file. Files that can't be decoded still get
+// a content hash, so identical copies are found either way.
+func hashImageFile(path string) (imageHashes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return imageHashes{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	hashes := imageHashes{Content: sha256.Sum256(data)}
+	if img, err := decodeImage(path); err == nil {
+		hashes.DHash = differenceHash(img)
+		hashes.PHash = perceptualHash(img)
+		hashes.Decoded = true
+	}
+	return hashes, nil
+}
+
+// differenceHash computes the 64-bit dHash of an image: it is scaled down to
+// 9x8 gray pixels, and each bit records whether a pixel is brighter than its
+// right neighbour. Re-encoded, resized or slightly edited copies of an image
+// have hashes a few bits apart.
+func differenceHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.BiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// dctCosines holds cos((2x+1)uπ/64) for the 32-point DCT of perceptualHash,
+// indexed [u][x] for the 8 lowest frequencies
+var dctCosines = func() [8][32]float64 {
+	var table [8][32]float64
+	for u := range table {
+		for x := range table[u] {
+			table[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / 64)
+		}
+	}
+	return table
+}()
+
+// perceptualHash computes the 64-bit pHash of an image: it is scaled down to
+// 32x32 gray pixels, and each bit records whether one of the 8x8 lowest
+// frequencies of its DCT is above their median. It is more robust to
+// brightness and contrast changes than the dHash.
+func perceptualHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 32, 32))
+	draw.BiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	// Separable DCT: the rows first, then the columns of the low frequencies
+	var rows [32][8]float64
+	for y := 0; y < 32; y++ {
+		for u := 0; u < 8; u++ {
+			sum := 0.0
+			for x := 0; x < 32; x++ {
+				sum += float64(small.GrayAt(x, y).Y) * dctCosines[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+	var coefficients [64]float64
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			sum := 0.0
+			for y := 0; y < 32; y++ {
+				sum += rows[y][u] * dctCosines[v][y]
+			}
+			coefficients[v*8+u] = sum
+		}
+	}
+
+	// The DC term is the mean brightness and stays out of the median
+	sorted := make([]float64, 63)
+	copy(sorted, coefficients[1:])
+	sort.Float64s(sorted)
+	median := (sorted[31] + sorted[32]) / 2
+
+	var hash uint64
+	for _, coefficient := range coefficients {
+		hash <<= 1
+		if coefficient > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+// hashDistance is the number of bits two perceptual hashes differ in
+func hashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// hashIndex is a BK-tree of perceptual hashes, which finds the hashes within
+// a Hamming distance without comparing against every hash
+type hashIndex struct {
+	root *hashNode
+}
+
+type hashNode struct {
+	hash     uint64
+	id       int
+	children map[int]*hashNode
+}
+
+// add indexes a hash under an ID
+func (x *hashIndex) add(hash uint64, id int) {
+	node := &hashNode{hash: hash, id: id}
+	if x.root == nil {
+		x.root = node
+		return
+	}
+	current := x.root
+	for {
+		d := hashDistance(hash, current.hash)
+		next, ok := current.children[d]
+		if !ok {
+			if current.children == nil {
+				current.children = make(map[int]*hashNode)
+			}
+			current.children[d] = node
+			return
+		}
+		current = next
+	}
+}
+
+// nearest returns the ID and distance of the closest hash within
+// maxDistance bits, preferring the lowest ID among equally close hashes, and
+// false when there is none
+func (x *hashIndex) nearest(hash uint64, maxDistance int) (int, int, bool) {
+	bestID, bestDistance, found := 0, maxDistance+1, false
+	if x.root == nil {
+		return 0, 0, false
+	}
+	stack := []*hashNode{x.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d := hashDistance(hash, node.hash)
+		if d < bestDistance || (d == bestDistance && found && node.id < bestID) {
+			bestID, bestDistance, found = node.id, d, true
+		}
+		// By the triangle inequality, only children at distances within
+		// the search radius of d can hold closer hashes
+		for childDistance, child := range node.children {
+			if childDistance >= d-maxDistance && childDistance <= d+maxDistance {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return bestID, bestDistance, found
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// imageMetadata maps image file names to their attributes, such as camera,
+// site or weather
+type imageMetadata map[string]map[string]string
+
+// metadataCondition is one -where condition: attribute=value or
+// attribute!=value, with alternatives separated by |
+type metadataCondition struct {
+	attribute string
+	values    map[string]bool
+	negate    bool
+}
+
+// loadImageMetadata reads a sidecar file of per-image attributes: a CSV whose
+// header names the columns, with the image file name in an "image" column
+// (or the first one), or JSON, either an object keyed by image name or an
+// array of objects with an "image" key
+func loadImageMetadata(path string) (imageMetadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image metadata: %w", err)
+	}
+	var metadata imageMetadata
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		metadata, err = parseMetadataJSON(data)
+	} else {
+		metadata, err = parseMetadataCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("image metadata %s: %w", path, err)
+	}
+	return metadata, nil
+}
+
+// parseMetadataCSV reads CSV metadata; empty cells are missing attributes
+func parseMetadataCSV(data []byte) (imageMetadata, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header: %w", err)
+	}
+	imageColumn := 0
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		if strings.EqualFold(header[i], "image") {
+			imageColumn = i
+		}
+	}
+
+	metadata := make(imageMetadata)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		image := filepath.Base(strings.TrimSpace(record[imageColumn]))
+		if image == "" || image == "." {
+			return nil, fmt.Errorf("row %d has no image name", row)
+		}
+		attributes := make(map[string]string)
+		for i, value := range record {
+			if value = strings.TrimSpace(value); i != imageColumn && value != "" {
+				attributes[header[i]] = value
+			}
+		}
+		metadata[image] = attributes
+	}
+	return metadata, nil
+}
+
+// parseMetadataJSON reads JSON metadata. Values are compared as text, so
+// numbers and booleans are formatted as in the file.
+func parseMetadataJSON(data []byte) (imageMetadata, error) {
+	var byName map[string]map[string]any
+	if err := json.Unmarshal(data, &byName); err == nil {
+		metadata := make(imageMetadata, len(byName))
+		for image, attributes := range byName {
+			metadata[filepath.Base(image)] = metadataStrings(attributes)
+		}
+		return metadata, nil
+	}
+
+	var records []map[string]any
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("expected an object keyed by image name or an array of objects: %w", err)
+	}
+	metadata := make(imageMetadata, len(records))
+	for i, record := range records {
+		image, _ := record["image"].(string)
+		if image == "" {
+			return nil, fmt.Errorf("entry %d has no image name", i)
+		}
+		delete(record, "image")
+		metadata[filepath.Base(image)] = metadataStrings(record)
+	}
+	return metadata, nil
+}
+
+// metadataStrings formats JSON attribute values as text, dropping nulls
+func metadataStrings(attributes map[string]any) map[string]string {
+	values := make(map[string]string, len(attributes))
+	for name, value := range attributes {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			values[name] = v
+		default:
+			encoded, _ := json.Marshal(v)
+			values[name] = string(encoded)
+		}
+	}
+	return values
+}
+
+// parseWhere parses -where: comma-separated conditions that must all hold,
+// such as camera=cam3|cam4,weather!=rain
+func parseWhere(spec string) ([]metadataCondition, error) {
+	var conditions []metadataCondition
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		attribute, value, ok := strings.Cut(part, "=")
+		condition := metadataCondition{values: make(map[string]bool)}
+		if before, isNot := strings.CutSuffix(attribute, "!"); isNot {
+			attribute, condition.negate = before, true
+		}
+		condition.attribute = strings.TrimSpace(attribute)
+		if !ok || condition.attribute == "" {
+			return nil, fmt.Errorf("invalid -where condition %q (expected attribute=value or attribute!=value)", part)
+		}
+		for _, alternative := range strings.Split(value, "|") {
+			condition.values[strings.TrimSpace(alternative)] = true
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("-where has no conditions")
+	}
+	return conditions, nil
+}
+
+// matches reports whether an image's attributes meet the condition. A
+// missing attribute never equals a value.
+func (m metadataCondition) matches(attributes map[string]string) bool {
+	value, ok := attributes[m.attribute]
+	return (ok && m.values[value]) != m.negate
+}
+
+// validateImageMetadata loads the -image-metadata file and checks the options
+// using it before the conversion starts
+func (c *Converter) validateImageMetadata() error {
+	c.metadata, c.metadataStems = nil, nil
+	c.where = nil
+	uses := c.config.Where != "" || c.config.SplitGroupBy != "" || c.config.SplitStratifyBy != ""
+	if c.config.ImageMetadata == "" {
+		if uses {
+			return fmt.Errorf("-where, -split-group-by and -split-stratify-by require -image-metadata")
+		}
+		return nil
+	}
+	if c.config.Classify || c.config.MultiLabel {
+		return fmt.Errorf("-image-metadata cannot be combined with -classify or -multi-label")
+	}
+	if c.config.SplitGroupBy != "" && c.config.SplitStratifyBy != "" {
+		return fmt.Errorf("-split-group-by and -split-stratify-by cannot be combined")
+	}
+	if c.config.Where != "" {
+		conditions, err := parseWhere(c.config.Where)
+		if err != nil {
+			return err
+		}
+		c.where = conditions
+	}
+	metadata, err := loadImageMetadata(c.config.ImageMetadata)
+	if err != nil {
+		return err
+	}
+	c.metadata = metadata
+	c.metadataStems = make(imageMetadata, len(metadata))
+	for name, attributes := range metadata {
+		c.metadataStems[strings.TrimSuffix(name, filepath.Ext(name))] = attributes
+	}
+	return nil
+}
+
+// imageAttributes looks up the metadata of an image by file name, falling
+// back to the name without extension for transcoded or renamed images
+func (c *Converter) imageAttributes(imagePath string) (map[string]string, bool) {
+	name := filepath.Base(imagePath)
+	if attributes, ok := c.metadata[name]; ok {
+		return attributes, true
+	}
+	attributes, ok := c.metadataStems[strings.TrimSuffix(name, filepath.Ext(name))]
+	return attributes, ok
+}
+
+// applyImageMetadata keeps the pairs whose images match -where and groups
+// them by the -split-group-by attribute, so every value ends up in one split
+func (c *Converter) applyImageMetadata(pairs []LabelPair) []LabelPair {
+	if c.metadata == nil {
+		return pairs
+	}
+	var kept []LabelPair
+	missing := 0
+	for _, pair := range pairs {
+		attributes, ok := c.imageAttributes(pair.ImagePath)
+		if !ok {
+			missing++
+		}
+		if !c.matchesWhere(attributes) {
+			continue
+		}
+		if value, ok := attributes[c.config.SplitGroupBy]; ok && c.config.SplitGroupBy != "" {
+			pair.Group = c.config.SplitGroupBy + "=" + value
+		}
+		kept = append(kept, pair)
+	}
+	if missing > 0 {
+		fmt.Fprintf(c.out, "Warning: %d images have no entry in %s\n", missing, c.config.ImageMetadata)
+	}
+	if c.where != nil {
+		fmt.Fprintf(c.out, "Kept %d of %d images matching -where %s\n", len(kept), len(pairs), c.config.Where)
+	}
+	return kept
+}
+
+// matchesWhere reports whether attributes meet every -where condition
+func (c *Converter) matchesWhere(attributes map[string]string) bool {
+	for _, condition := range c.where {
+		if !condition.matches(attributes) {
+			return false
+		}
+	}
+	return true
+}
+
+// stratify partitions the pairs by their -split-stratify-by value, returning
+// the values in order; pairs without the attribute form their own stratum
+func (c *Converter) stratify(pairs []LabelPair) ([]string, map[string][]LabelPair) {
+	var values []string
+	strata := make(map[string][]LabelPair)
+	for _, pair := range pairs {
+		attributes, _ := c.imageAttributes(pair.ImagePath)
+		value, ok := attributes[c.config.SplitStratifyBy]
+		if !ok {
+			value = "(missing)"
+		}
+		if _, seen := strata[value]; !seen {
+			values = append(values, value)
+		}
+		strata[value] = append(strata[value], pair)
+	}
+	sort.Strings(values)
+	return values, strata
+}
+
+// splitStratified splits every stratum on its own, so each value of the
+// -split-stratify-by attribute is divided by -train-split
+func (c *Converter) splitStratified(pairs []LabelPair) ([]LabelPair, []LabelPair) {
+	values, strata := c.stratify(pairs)
+	trainPairs, valPairs := []LabelPair{}, []LabelPair{}
+	fmt.Fprintf(c.out, "Split stratified by %s:\n", c.config.SplitStratifyBy)
+	for _, value := range values {
+		train, val := c.splitPairs(strata[value])
+		fmt.Fprintf(c.out, "  %s=%s: %d training, %d validation\n", c.config.SplitStratifyBy, value, len(train), len(val))
+		trainPairs = append(trainPairs, train...)
+		valPairs = append(valPairs, val...)
+	}
+	return trainPairs, valPairs
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadImageMetadata(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"meta.csv":    "site,image,camera\nnorth, a.jpg ,cam3\nsouth,images/b.jpg,\n",
+		"byname.json": `{"a.jpg": {"camera": "cam3", "site": "north"}, "b.jpg": {"camera": null, "site": "south", "height": 4.5}}`,
+		"list.json":   `[{"image": "a.jpg", "camera": "cam3", "site": "north"}, {"image": "b.jpg", "site": "south"}]`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		metadata, err := loadImageMetadata(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if a := metadata["a.jpg"]; a["camera"] != "cam3" || a["site"] != "north" || len(a) != 2 {
+			t.Errorf("%s: unexpected attributes of a.jpg: %v", name, a)
+		}
+		if b := metadata["b.jpg"]; b["site"] != "south" {
+			t.Errorf("%s: unexpected attributes of b.jpg: %v", name, b)
+		}
+		if _, ok := metadata["b.jpg"]["camera"]; ok {
+			t.Errorf("%s: expected b.jpg to have no camera", name)
+		}
+	}
+	if _, err := loadImageMetadata(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestParseWhere(t *testing.T) {
+	conditions, err := parseWhere("camera=cam3|cam4, weather!=rain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		attributes map[string]string
+		want       bool
+	}{
+		{map[string]string{"camera": "cam3", "weather": "sun"}, true},
+		{map[string]string{"camera": "cam4"}, true},
+		{map[string]string{"camera": "cam4", "weather": "rain"}, false},
+		{map[string]string{"camera": "cam1"}, false},
+		{nil, false},
+	}
+	c := &Converter{where: conditions}
+	for _, tt := range tests {
+		if got := c.matchesWhere(tt.attributes); got != tt.want {
+			t.Errorf("%v: expected %v, got %v", tt.attributes, tt.want, got)
+		}
+	}
+	for _, spec := range []string{"camera", "=cam3", " , "} {
+		if _, err := parseWhere(spec); err == nil {
+			t.Errorf("Expected an error for -where %q", spec)
+		}
+	}
+}
+
+// writeSiteSource creates 12 images, 4 per site, with their metadata
+func writeSiteSource(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	for _, sub := range []string{"images", "labels"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	metadata := "image,site,camera\n"
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("img%02d", i)
+		if err := os.WriteFile(filepath.Join(dir, "images", name+".jpg"), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "labels", name+".txt"), []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		metadata += fmt.Sprintf("%s.jpg,%s,cam%d\n", name, []string{"north", "south", "east"}[i%3], i%2)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "classes.txt"), []byte("object\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	metadataPath := filepath.Join(t.TempDir(), "metadata.csv")
+	if err := os.WriteFile(metadataPath, []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, metadataPath
+}
+
+// splitSites converts and returns the sites of the images in each split
+func splitSites(t *testing.T, config Config) (map[string]map[string]int, string) {
+	config.OutputDir = filepath.Join(t.TempDir(), "out")
+	var out strings.Builder
+	converter := NewConverter(config)
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	sites := map[string]map[string]int{"train": {}, "val": {}}
+	for split := range sites {
+		entries, _ := os.ReadDir(filepath.Join(config.OutputDir, "images", split))
+		for _, entry := range entries {
+			var i int
+			fmt.Sscanf(entry.Name(), "img%02d.jpg", &i)
+			sites[split][[]string{"north", "south", "east"}[i%3]]++
+		}
+	}
+	return sites, out.String()
+}
+
+func TestImageMetadataSplits(t *testing.T) {
+	sourceDir, metadataPath := writeSiteSource(t)
+	base := Config{SourceDir: sourceDir, TrainSplit: 0.5, Seed: 7, InputFormat: InputYOLO, ImageMetadata: metadataPath}
+
+	config := base
+	config.Where = "camera=cam1,site!=east"
+	sites, out := splitSites(t, config)
+	// cam1 holds the odd images: 1, 3, 5, 7, 9, 11, of which 3 and 9 are east
+	if total := sites["train"]["north"] + sites["train"]["south"] + sites["val"]["north"] + sites["val"]["south"]; total != 4 || sites["train"]["east"]+sites["val"]["east"] != 0 {
+		t.Errorf("Expected the 4 cam1 images outside east, got %v", sites)
+	}
+	if !strings.Contains(out, "Kept 4 of 12 images matching -where camera=cam1,site!=east") {
+		t.Errorf("Expected the filter to be reported:\n%s", out)
+	}
+
+	config = base
+	config.SplitGroupBy = "site"
+	sites, _ = splitSites(t, config)
+	for _, site := range []string{"north", "south", "east"} {
+		if sites["train"][site] != 0 && sites["val"][site] != 0 {
+			t.Errorf("Expected site %s in one split, got %v", site, sites)
+		}
+	}
+
+	config = base
+	config.SplitStratifyBy = "site"
+	sites, out = splitSites(t, config)
+	for _, site := range []string{"north", "south", "east"} {
+		if sites["train"][site] != 2 || sites["val"][site] != 2 {
+			t.Errorf("Expected 2 training and 2 validation images of site %s, got %v", site, sites)
+		}
+	}
+	if !strings.Contains(out, "site=north: 2 training, 2 validation") {
+		t.Errorf("Expected the strata to be reported:\n%s", out)
+	}
+
+	config = base
+	config.ImageMetadata = ""
+	config.Where = "site=north"
+	config.OutputDir = t.TempDir()
+	if err := NewConverter(config).Convert(); err == nil || !strings.Contains(err.Error(), "require -image-metadata") {
+		t.Errorf("Expected -where to require -image-metadata, got %v", err)
+	}
+}
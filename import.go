@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ImportOptions configures the conversion of YOLO predictions into Label
+// Studio pre-annotations
+type ImportOptions struct {
+	// PredictionsDir holds one YOLO .txt file per image, named after it,
+	// optionally with a trailing confidence column
+	PredictionsDir string
+	// TasksFile is the original Label Studio task list
+	TasksFile string
+	// ClassesFile is a classes.txt or a data.yaml with the class names
+	ClassesFile string
+	// ImagesDir, when set, is read for image sizes
+	ImagesDir    string
+	OutputFile   string
+	ModelVersion string
+	// FromName and ToName are the control and object tag names of the
+	// labeling config
+	FromName string
+	ToName   string
+	// OBB reads eight-coordinate lines as oriented boxes instead of polygons
+	OBB      bool
+	MinScore float64
+}
+
+// LSImportTask is a task in Label Studio's import format
+type LSImportTask struct {
+	Data        map[string]interface{} `json:"data"`
+	Predictions []LSImportPrediction   `json:"predictions"`
+}
+
+// LSImportPrediction is a pre-annotation of an imported task
+type LSImportPrediction struct {
+	ModelVersion string           `json:"model_version,omitempty"`
+	Score        *float64         `json:"score,omitempty"`
+	Result       []LSImportResult `json:"result"`
+}
+
+// LSImportResult is a predicted region. The value is a map so that only the
+// keys of its result type are written.
+type LSImportResult struct {
+	ID             string                 `json:"id"`
+	Type           string                 `json:"type"`
+	FromName       string                 `json:"from_name"`
+	ToName         string                 `json:"to_name"`
+	OriginalWidth  int                    `json:"original_width,omitempty"`
+	OriginalHeight int                    `json:"original_height,omitempty"`
+	ImageRotation  int                    `json:"image_rotation"`
+	Value          map[string]interface{} `json:"value"`
+	Score          *float64               `json:"score,omitempty"`
+}
+
+// loadClassNames reads class names from a classes.txt or a data.yaml
+func loadClassNames(classesPath string) ([]string, error) {
+	ext := strings.ToLower(filepath.Ext(classesPath))
+	if ext == ".yaml" || ext == ".yml" {
+		data, err := os.ReadFile(classesPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", classesPath, err)
+		}
+
+		// names is a list in our data.yaml and often an index map elsewhere
+		var list struct {
+			Names []string `yaml:"names"`
+		}
+		if err := yaml.Unmarshal(data, &list); err == nil && len(list.Names) > 0 {
+			return list.Names, nil
+		}
+		var indexed struct {
+			Names map[int]string `yaml:"names"`
+		}
+		if err := yaml.Unmarshal(data, &indexed); err != nil {
+			return nil, fmt.Errorf("failed to parse class names in %s: %w", classesPath, err)
+		}
+		names := make([]string, len(indexed.Names))
+		for id, name := range indexed.Names {
+			if id < 0 || id >= len(names) {
+				return nil, fmt.Errorf("class IDs in %s are not contiguous", classesPath)
+			}
+			names[id] = name
+		}
+		return names, nil
+	}
+
+	names, err := readClassesFile(classesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read class names: %w", err)
+	}
+	return names, nil
+}
+
+// imageSize returns the pixel size of a task's image, from ImagesDir or from
+// the sizes Label Studio recorded in the task's existing results
+func (o ImportOptions) imageSize(task LSTask) (int, int) {
+	if o.ImagesDir != "" {
+		if file, err := os.Open(filepath.Join(o.ImagesDir, imageFileName(taskImage(task)))); err == nil {
+			defer file.Close()
+			if config, _, err := image.DecodeConfig(file); err == nil {
+				return config.Width, config.Height
+			}
+		}
+	}
+	for _, annotations := range [][]LSAnnotation{task.Annotations, task.Predictions} {
+		for _, a := range annotations {
+			for _, r := range a.Result {
+				if r.OriginalWidth > 0 && r.OriginalHeight > 0 {
+					return r.OriginalWidth, r.OriginalHeight
+				}
+			}
+		}
+	}
+	return 0, 0
+}
+
+// percentPoints converts flat normalized x, y pairs into Label Studio points
+func percentPoints(coords []float64) [][]float64 {
+	points := make([][]float64, 0, len(coords)/2)
+	for i := 0; i+1 < len(coords); i += 2 {
+		points = append(points, []float64{coords[i] * 100, coords[i+1] * 100})
+	}
+	return points
+}
+
+// obbValue converts clockwise normalized corners into a rotated rectangle:
+// the first corner is the rectangle's origin and the first edge its rotated
+// top side, undoing rotatedCorners
+func obbValue(coords []float64, width, height int) map[string]interface{} {
+	iw, ih := float64(width), float64(height)
+	if iw <= 0 || ih <= 0 {
+		iw, ih = 100, 100
+	}
+
+	x0, y0 := coords[0]*iw, coords[1]*ih
+	dx, dy := coords[2]*iw-x0, coords[3]*ih-y0
+	w := math.Hypot(dx, dy)
+	h := math.Hypot(coords[6]*iw-x0, coords[7]*ih-y0)
+
+	rotation := math.Atan2(dy, dx) * 180 / math.Pi
+	if rotation < 0 {
+		rotation += 360
+	}
+	return map[string]interface{}{
+		"x":        x0 / iw * 100,
+		"y":        y0 / ih * 100,
+		"width":    w / iw * 100,
+		"height":   h / ih * 100,
+		"rotation": rotation,
+	}
+}
+
+// predictionResult converts a YOLO prediction line into a Label Studio
+// result: four coordinates make a box, eight an oriented box with OBB, and
+// any other even count a polygon. An extra trailing column is the confidence.
+func (o ImportOptions) predictionResult(parts []string, classes []string, width, height int) (LSImportResult, error) {
+	classID, err := strconv.Atoi(parts[0])
+	if err != nil || classID < 0 || classID >= len(classes) {
+		return LSImportResult{}, fmt.Errorf("unknown class %q", parts[0])
+	}
+
+	values := make([]float64, len(parts)-1)
+	for i := range values {
+		if values[i], err = strconv.ParseFloat(parts[i+1], 64); err != nil {
+			return LSImportResult{}, fmt.Errorf("invalid number %q", parts[i+1])
+		}
+	}
+
+	var score *float64
+	if len(values)%2 == 1 {
+		score = &values[len(values)-1]
+		values = values[:len(values)-1]
+	}
+
+	result := LSImportResult{
+		FromName:       o.FromName,
+		ToName:         o.ToName,
+		OriginalWidth:  width,
+		OriginalHeight: height,
+		Score:          score,
+	}
+	label := []string{classes[classID]}
+
+	switch {
+	case len(values) == 4:
+		result.Type = "rectanglelabels"
+		result.Value = map[string]interface{}{
+			"x":        (values[0] - values[2]/2) * 100,
+			"y":        (values[1] - values[3]/2) * 100,
+			"width":    values[2] * 100,
+			"height":   values[3] * 100,
+			"rotation": 0.0,
+		}
+		result.Value[result.Type] = label
+	case len(values) == 8 && o.OBB:
+		result.Type = "rectanglelabels"
+		result.Value = obbValue(values, width, height)
+		result.Value[result.Type] = label
+	case len(values) >= 6:
+		result.Type = "polygonlabels"
+		result.Value = map[string]interface{}{
+			"points": percentPoints(values),
+			"closed": true,
+		}
+		result.Value[result.Type] = label
+	default:
+		return LSImportResult{}, fmt.Errorf("expected a box or polygon, got %d values", len(parts)-1)
+	}
+	return result, nil
+}
+
+// readPredictions converts a YOLO prediction file into a pre-annotation. The
+// prediction's score is the mean of its regions' confidences.
+func (o ImportOptions) readPredictions(path string, classes []string, width, height int, out io.Writer) (LSImportPrediction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LSImportPrediction{}, fmt.Errorf("failed to read predictions %s: %w", path, err)
+	}
+
+	prediction := LSImportPrediction{ModelVersion: o.ModelVersion, Result: []LSImportResult{}}
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	total, scored := 0.0, 0
+
+	for i, line := range strings.Split(string(data), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		result, err := o.predictionResult(parts, classes, width, height)
+		if err != nil {
+			fmt.Fprintf(out, "Warning: %s:%d: %v\n", filepath.Base(path), i+1, err)
+			continue
+		}
+		if result.Score != nil {
+			if *result.Score < o.MinScore {
+				continue
+			}
+			total += *result.Score
+			scored++
+		}
+		result.ID = fmt.Sprintf("%s_%d", stem, i+1)
+		prediction.Result = append(prediction.Result, result)
+	}
+
+	if scored > 0 {
+		mean := total / float64(scored)
+		prediction.Score = &mean
+	}
+	return prediction, nil
+}
+
+// ImportPredictions pairs the tasks of a Label Studio task list with YOLO
+// prediction files by image name and returns the tasks that have one, with
+// their predictions as pre-annotations
+func ImportPredictions(o ImportOptions, out io.Writer) ([]LSImportTask, error) {
+	classes, err := loadClassNames(o.ClassesFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("no class names in %s", o.ClassesFile)
+	}
+
+	tasks, err := LoadLabelStudioTasks(o.TasksFile)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := []LSImportTask{}
+	missing := 0
+	for _, task := range tasks {
+		ref := taskImage(task)
+		if ref == "" {
+			fmt.Fprintf(out, "Warning: Task %d has no image\n", task.ID)
+			continue
+		}
+
+		name := imageFileName(ref)
+		predictionsPath := filepath.Join(o.PredictionsDir, strings.TrimSuffix(name, filepath.Ext(name))+".txt")
+		if _, err := os.Stat(predictionsPath); err != nil {
+			missing++
+			continue
+		}
+
+		width, height := o.imageSize(task)
+		prediction, err := o.readPredictions(predictionsPath, classes, width, height, out)
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, LSImportTask{Data: task.Data, Predictions: []LSImportPrediction{prediction}})
+	}
+
+	fmt.Fprintf(out, "Imported predictions for %d of %d tasks\n", len(imported), len(tasks))
+	if missing > 0 {
+		fmt.Fprintf(out, "Warning: %d tasks have no prediction file\n", missing)
+	}
+	return imported, nil
+}
+
+// writeImportTasks writes tasks as Label Studio import JSON
+func writeImportTasks(tasks []LSImportTask, outputFile string) error {
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tasks: %w", err)
+	}
+	if err := os.WriteFile(outputFile, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputFile, err)
+	}
+	return nil
+}
+
+// runImport implements the import subcommand
+func runImport(args []string) error {
+	var o ImportOptions
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.StringVar(&o.PredictionsDir, "predictions", "", "Directory of YOLO prediction .txt files, one per image (required)")
+	fs.StringVar(&o.TasksFile, "tasks", "", "Label Studio task list (JSON export) the predictions were made for (required)")
+	fs.StringVar(&o.ClassesFile, "classes", "", "classes.txt or data.yaml with the class names (required)")
+	fs.StringVar(&o.ImagesDir, "images", "", "Directory of the task images, read for image sizes")
+	fs.StringVar(&o.OutputFile, "output", "predictions.json", "Label Studio import JSON to write")
+	fs.StringVar(&o.ModelVersion, "model-version", "", "Model version recorded with the predictions")
+	fs.StringVar(&o.FromName, "from-name", "label", "Name of the labels control tag in the labeling config")
+	fs.StringVar(&o.ToName, "to-name", "image", "Name of the image object tag in the labeling config")
+	fs.BoolVar(&o.OBB, "obb", false, "Read lines with eight coordinates as oriented boxes instead of polygons")
+	fs.Float64Var(&o.MinScore, "min-score", 0, "Drop predicted regions with a lower confidence")
+	fs.Parse(args)
+
+	if o.PredictionsDir == "" || o.TasksFile == "" || o.ClassesFile == "" {
+		return fmt.Errorf("-predictions, -tasks and -classes are required")
+	}
+
+	tasks, err := ImportPredictions(o, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if err := writeImportTasks(tasks, o.OutputFile); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", o.OutputFile)
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPredictionResult(t *testing.T) {
+	o := ImportOptions{FromName: "label", ToName: "image"}
+	classes := []string{"cat", "dog"}
+
+	box, err := o.predictionResult(strings.Fields("1 0.5 0.5 0.2 0.4 0.87"), classes, 640, 480)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if box.Type != "rectanglelabels" || scoreOf(box.Score) != 0.87 || box.OriginalWidth != 640 {
+		t.Errorf("Unexpected box result %+v", box)
+	}
+	for key, want := range map[string]float64{"x": 40, "y": 30, "width": 20, "height": 40} {
+		if got := box.Value[key].(float64); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Expected %s %v, got %v", key, want, got)
+		}
+	}
+	if labels := box.Value["rectanglelabels"].([]string); labels[0] != "dog" {
+		t.Errorf("Expected dog, got %v", labels)
+	}
+
+	polygon, err := o.predictionResult(strings.Fields("0 0.1 0.1 0.5 0.1 0.3 0.4"), classes, 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if polygon.Type != "polygonlabels" || polygon.Score != nil || len(polygon.Value["points"].([][]float64)) != 3 {
+		t.Errorf("Unexpected polygon result %+v", polygon)
+	}
+
+	for _, line := range []string{"2 0.5 0.5 0.2 0.2", "0 0.5 0.5", "x 0.5 0.5 0.2 0.2"} {
+		if _, err := o.predictionResult(strings.Fields(line), classes, 0, 0); err == nil {
+			t.Errorf("Expected error for %q", line)
+		}
+	}
+}
+
+func TestOBBValueInvertsRotation(t *testing.T) {
+	original := LSResult{
+		Type:          "rectanglelabels",
+		OriginalWidth: 800, OriginalHeight: 400,
+		Value: LSValue{X: 30, Y: 20, Width: 25, Height: 40, Rotation: 30},
+	}
+
+	coords, ok := obbCoords(original)
+	if !ok {
+		t.Fatal("Expected the rotated rectangle to fit the image")
+	}
+	value := obbValue(coords, 800, 400)
+	for key, want := range map[string]float64{"x": 30, "y": 20, "width": 25, "height": 40, "rotation": 30} {
+		if got := value[key].(float64); math.Abs(got-want) > 1e-6 {
+			t.Errorf("Expected %s %v, got %v", key, want, got)
+		}
+	}
+}
+
+func TestImportPredictions(t *testing.T) {
+	dir := t.TempDir()
+	predictionsDir := filepath.Join(dir, "labels")
+	if err := os.MkdirAll(predictionsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := `[
+		{"id": 1, "data": {"image": "/data/upload/1/cat.jpg"}},
+		{"id": 2, "data": {"image": "/data/upload/1/dog.jpg"},
+		 "annotations": [{"result": [{"original_width": 320, "original_height": 240}]}]},
+		{"id": 3, "data": {"image": "/data/upload/1/bird.jpg"}}
+	]`
+	files := map[string]string{
+		filepath.Join(dir, "tasks.json"):          tasks,
+		filepath.Join(dir, "data.yaml"):           "names:\n  0: cat\n  1: dog\n",
+		filepath.Join(predictionsDir, "cat.txt"):  "0 0.5 0.5 0.2 0.2 0.9\n0 0.2 0.2 0.1 0.1 0.1\n",
+		filepath.Join(predictionsDir, "dog.txt"):  "1 0.5 0.5 0.4 0.4 0.6\n",
+		filepath.Join(predictionsDir, "fish.txt"): "0 0.5 0.5 0.4 0.4\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o := ImportOptions{
+		PredictionsDir: predictionsDir,
+		TasksFile:      filepath.Join(dir, "tasks.json"),
+		ClassesFile:    filepath.Join(dir, "data.yaml"),
+		ModelVersion:   "yolo-v1",
+		FromName:       "label",
+		ToName:         "image",
+		MinScore:       0.25,
+	}
+	imported, err := ImportPredictions(o, io.Discard)
+	if err != nil {
+		t.Fatalf("ImportPredictions failed: %v", err)
+	}
+	if len(imported) != 2 {
+		t.Fatalf("Expected 2 imported tasks, got %d", len(imported))
+	}
+
+	cat := imported[0].Predictions[0]
+	if len(cat.Result) != 1 || scoreOf(cat.Score) != 0.9 || cat.ModelVersion != "yolo-v1" {
+		t.Errorf("Expected one confident cat region, got %+v", cat)
+	}
+	if dog := imported[1].Predictions[0].Result[0]; dog.OriginalWidth != 320 || dog.OriginalHeight != 240 {
+		t.Errorf("Expected the image size from the task's annotation, got %dx%d", dog.OriginalWidth, dog.OriginalHeight)
+	}
+
+	// The written JSON reads back as Label Studio tasks
+	outputPath := filepath.Join(dir, "predictions.json")
+	if err := writeImportTasks(imported, outputPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roundTrip []LSTask
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("Failed to parse written JSON: %v", err)
+	}
+	result := roundTrip[0].Predictions[0].Result[0]
+	if result.Type != "rectanglelabels" || result.Value.RectangleLabels[0] != "cat" || math.Abs(result.Value.X-40) > 1e-9 {
+		t.Errorf("Unexpected round-tripped result %+v", result)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// splitNames are the dataset splits written to the output
+var splitNames = []string{"train", "val"}
+
+// ExistingSplits reads the split assignment of images already present in the
+// output directory, keyed by image file name
+func (c *Converter) ExistingSplits() (map[string]string, error) {
+	existing := make(map[string]string)
+	for _, split := range splitNames {
+		dir := filepath.Join(c.config.OutputDir, "images", split)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing output %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				existing[entry.Name()] = split
+			}
+		}
+	}
+	return existing, nil
+}
+
+// SplitIncremental keeps images that are already in the output in their
+// current split and assigns new images so the overall ratio stays close to
+// TrainSplit
+func (c *Converter) SplitIncremental(pairs []LabelPair, existing map[string]string) ([]LabelPair, []LabelPair) {
+	// Groups follow the split their existing members are in
+	groupSplits := make(map[string]string)
+	for _, pair := range pairs {
+		if split := existing[filepath.Base(pair.ImagePath)]; split != "" && pair.Group != "" {
+			groupSplits[pair.Group] = split
+		}
+	}
+
+	var trainPairs, valPairs, newPairs []LabelPair
+	keptTrain, keptVal := 0, 0
+	for _, pair := range pairs {
+		split := existing[filepath.Base(pair.ImagePath)]
+		switch split {
+		case "train":
+			keptTrain++
+		case "val":
+			keptVal++
+		default:
+			split = groupSplits[pair.Group]
+		}
+		switch split {
+		case "train":
+			trainPairs = append(trainPairs, pair)
+		case "val":
+			valPairs = append(valPairs, pair)
+		default:
+			newPairs = append(newPairs, pair)
+		}
+	}
+
+	units := groupPairs(newPairs)
+	c.orderUnits(units)
+
+	// Existing assignments count towards the ratio
+	_, total := c.unitWeights(groupPairs(pairs))
+	_, trainWeight := c.unitWeights([][]LabelPair{trainPairs})
+	weights, _ := c.unitWeights(units)
+	needed := int(float64(total)*c.config.TrainSplit) - trainWeight
+	newTrain, newVal, _ := fillSplits(units, weights, needed)
+	trainPairs = append(trainPairs, newTrain...)
+	valPairs = append(valPairs, newVal...)
+
+	kept := keptTrain + keptVal
+	fmt.Fprintf(c.out, "Incremental split: %d new (%d training, %d validation), %d kept\n",
+		len(pairs)-kept, len(trainPairs)-keptTrain, len(valPairs)-keptVal, kept)
+	fmt.Fprintf(c.out, "Dataset split: %d training, %d validation\n", len(trainPairs), len(valPairs))
+	return trainPairs, valPairs
+}
+
+// PruneStale removes images and labels from the output that are no longer part
+// of the given split assignment, e.g. because they were deleted at the source
+func (c *Converter) PruneStale(trainPairs, valPairs []LabelPair) (int, error) {
+	keep := make(map[string]bool)
+	for split, pairs := range map[string][]LabelPair{"train": trainPairs, "val": valPairs} {
+		for _, pair := range pairs {
+			keep[filepath.Join("images", split, filepath.Base(pair.ImagePath))] = true
+			keep[filepath.Join("labels", split, filepath.Base(pair.LabelPath))] = true
+		}
+	}
+
+	removed := 0
+	for _, kind := range []string{"images", "labels"} {
+		for _, split := range splitNames {
+			dir := filepath.Join(c.config.OutputDir, kind, split)
+			entries, err := os.ReadDir(dir)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return removed, fmt.Errorf("failed to read output %s: %w", dir, err)
+			}
+			for _, entry := range entries {
+				if entry.IsDir() || keep[filepath.Join(kind, split, entry.Name())] {
+					continue
+				}
+				if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+					return removed, fmt.Errorf("failed to remove stale file: %w", err)
+				}
+				removed++
+			}
+		}
+	}
+
+	if removed > 0 {
+		fmt.Fprintf(c.out, "Removed %d stale files from output\n", removed)
+	}
+	return removed, nil
+}
+
+// upToDate reports whether dst already holds a copy of src: same size and not
+// older than the source
+func upToDate(src, dst string) bool {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false
+	}
+	dstInfo, err := os.Stat(dst)
+	if err != nil {
+		return false
+	}
+	return srcInfo.Size() == dstInfo.Size() && !dstInfo.ModTime().Before(srcInfo.ModTime())
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIncrementalConversion(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	outputDir := filepath.Join(tempDir, "yolo_output")
+
+	config := Config{
+		SourceDir:  tempDir,
+		OutputDir:  outputDir,
+		TrainSplit: 0.8,
+		Seed:       42,
+	}
+	if err := NewConverter(config).Convert(); err != nil {
+		t.Fatalf("Initial conversion failed: %v", err)
+	}
+
+	config.Incremental = true
+	converter := NewConverter(config)
+	before, err := converter.ExistingSplits()
+	if err != nil {
+		t.Fatalf("Failed to read existing splits: %v", err)
+	}
+	if len(before) != 3 {
+		t.Fatalf("Expected 3 existing images, got %d", len(before))
+	}
+
+	// Add a new pair and remove an existing one
+	if err := os.WriteFile(filepath.Join(tempDir, "images", "image4.jpg"), []byte("new image"), 0644); err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "labels", "image4.txt"), []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatalf("Failed to create label: %v", err)
+	}
+	if err := os.Remove(filepath.Join(tempDir, "images", "image2.png")); err != nil {
+		t.Fatalf("Failed to remove image: %v", err)
+	}
+	if err := os.Remove(filepath.Join(tempDir, "labels", "image2.txt")); err != nil {
+		t.Fatalf("Failed to remove label: %v", err)
+	}
+
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Incremental conversion failed: %v", err)
+	}
+
+	after, err := converter.ExistingSplits()
+	if err != nil {
+		t.Fatalf("Failed to read splits after update: %v", err)
+	}
+
+	for _, name := range []string{"image1.jpg", "image3.jpeg"} {
+		if after[name] != before[name] {
+			t.Errorf("Expected %s to stay in %s, now in %q", name, before[name], after[name])
+		}
+	}
+	if _, ok := after["image2.png"]; ok {
+		t.Error("Removed image should be pruned from the output")
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "labels", before["image2.png"], "image2.txt")); !os.IsNotExist(err) {
+		t.Error("Label of removed image should be pruned from the output")
+	}
+	if _, ok := after["image4.jpg"]; !ok {
+		t.Error("New image should be added to the output")
+	}
+}
+
+func TestSplitIncrementalKeepsRatio(t *testing.T) {
+	converter := NewConverter(Config{TrainSplit: 0.8, Seed: 42})
+
+	var pairs []LabelPair
+	existing := make(map[string]string)
+	for i := 0; i < 10; i++ {
+		name := filepath.Join("images", string(rune('a'+i))+".jpg")
+		pairs = append(pairs, LabelPair{ImagePath: name, LabelPath: name + ".txt"})
+		if i < 5 {
+			existing[filepath.Base(name)] = "train"
+		}
+	}
+
+	trainPairs, valPairs := converter.SplitIncremental(pairs, existing)
+	if len(trainPairs) != 8 || len(valPairs) != 2 {
+		t.Errorf("Expected 8/2 split, got %d/%d", len(trainPairs), len(valPairs))
+	}
+}
+
+func TestIncrementalRejectsArchiveOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+
+	converter := NewConverter(Config{
+		SourceDir:   tempDir,
+		OutputDir:   filepath.Join(tempDir, "dataset.zip"),
+		TrainSplit:  0.8,
+		Incremental: true,
+	})
+	if err := converter.Convert(); err == nil {
+		t.Error("Expected error for incremental conversion into an archive")
+	}
+}
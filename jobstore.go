@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files a job's state is kept in, inside its working directory
+const (
+	jobStateFile = "job.json"
+	jobLogFile   = "job.log"
+)
+
+// errInterrupted is recorded for jobs that were queued or running when the
+// server stopped without finishing them
+const errInterrupted = "interrupted by a server restart"
+
+// saveJob writes the job's state to its working directory, and its log once
+// it has finished, so the job survives a restart. Callers must hold the
+// server lock. Failures are reported but don't fail the job.
+func (s *Server) saveJob(job *Job) {
+	if err := s.writeJobState(job); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save job %s: %v\n", job.ID, err)
+	}
+}
+
+func (s *Server) writeJobState(job *Job) error {
+	dir := filepath.Join(s.dataDir, job.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	state := *job
+	state.Log = ""
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, jobStateFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, jobStateFile)); err != nil {
+		return err
+	}
+
+	if job.Status == JobSucceeded || job.Status == JobFailed {
+		return os.WriteFile(filepath.Join(dir, jobLogFile), []byte(job.log.String()), 0644)
+	}
+	return nil
+}
+
+// LoadJobs restores the jobs saved in the data directory by a previous run of
+// the server and returns how many were loaded. Jobs that never finished are
+// marked as failed; resubmit them with POST /jobs/{id}/rerun.
+func (s *Server) LoadJobs() (int, error) {
+	entries, err := os.ReadDir(s.dataDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loaded := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.dataDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, jobStateFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return loaded, fmt.Errorf("failed to read job %s: %w", entry.Name(), err)
+		}
+
+		job := &Job{log: &syncBuffer{}}
+		if err := json.Unmarshal(data, job); err != nil || job.ID != entry.Name() {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring invalid job state in %s\n", dir)
+			continue
+		}
+		if _, ok := s.jobs[job.ID]; ok {
+			continue
+		}
+		if log, err := os.ReadFile(filepath.Join(dir, jobLogFile)); err == nil {
+			job.log.Write(log)
+		}
+
+		switch job.Status {
+		case JobQueued, JobRunning:
+			now := time.Now().UTC()
+			job.Status = JobFailed
+			job.Error = errInterrupted
+			job.FinishedAt = &now
+			s.saveJob(job)
+		case JobSucceeded:
+			job.resultPath = filepath.Join(dir, "dataset."+job.Request.Format)
+			if job.Request.Project > 0 {
+				job.resultPath = s.projectOutputDir(job.Request.Project)
+			}
+		}
+		s.jobs[job.ID] = job
+		loaded++
+	}
+	return loaded, nil
+}
+
+// Rerun queues a new job with the same request as a previous one
+func (s *Server) Rerun(id string) (*Job, error) {
+	s.mu.Lock()
+	previous, ok := s.jobs[id]
+	var req JobRequest
+	if ok {
+		req = previous.Request
+	}
+	s.mu.Unlock()
+	// A project's export URL is rebuilt from the current -ls-url
+	if req.Project > 0 {
+		req.Source = ""
+	}
+
+	if !ok {
+		return nil, errJobNotFound
+	}
+	return s.submit(req, id)
+}
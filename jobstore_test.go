@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerJobsSurviveRestart(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	dataDir := t.TempDir()
+
+	server := NewServer(Config{TrainSplit: 0.8, Seed: 42}, dataDir, 4)
+	if err := server.EnableLocalSources(sourceDir); err != nil {
+		t.Fatal(err)
+	}
+	server.Start(1)
+	submitted, err := server.Submit(JobRequest{Source: sourceDir, Options: map[string]string{"train-split": "0.5"}})
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	if job := waitForJob(t, server, submitted.ID); job.Status != JobSucceeded {
+		t.Fatalf("Expected job to succeed, got %s: %s", job.Status, job.Error)
+	}
+	server.Stop()
+
+	// A job the previous server never finished
+	interrupted := Job{ID: "0123456789abcdef", Status: JobRunning, Request: JobRequest{Source: sourceDir, Format: "zip"}, CreatedAt: time.Now().UTC()}
+	data, _ := json.Marshal(interrupted)
+	if err := os.MkdirAll(filepath.Join(dataDir, interrupted.ID), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, interrupted.ID, jobStateFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewServer(Config{TrainSplit: 0.8, Seed: 42}, dataDir, 4)
+	if err := restarted.EnableLocalSources(sourceDir); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := restarted.LoadJobs()
+	if err != nil || loaded != 2 {
+		t.Fatalf("Expected 2 jobs to be loaded, got %d (%v)", loaded, err)
+	}
+	restarted.Start(1)
+	defer restarted.Stop()
+
+	job, ok := restarted.Job(submitted.ID)
+	if !ok || job.Status != JobSucceeded {
+		t.Fatalf("Expected the finished job to be restored, got %+v", job)
+	}
+	if !strings.Contains(job.Log, "Conversion completed successfully") {
+		t.Errorf("Expected the job log to be restored, got: %s", job.Log)
+	}
+	if job, _ := restarted.Job(interrupted.ID); job.Status != JobFailed || job.Error != errInterrupted {
+		t.Errorf("Expected the unfinished job to be marked as interrupted, got %s: %s", job.Status, job.Error)
+	}
+
+	ts := httptest.NewServer(restarted.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/jobs/" + submitted.ID + "/result")
+	if err != nil {
+		t.Fatalf("Failed to download result: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the restored result to be served, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(ts.URL+"/jobs/"+submitted.ID+"/rerun", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Failed to rerun job: %v", err)
+	}
+	var rerun Job
+	json.NewDecoder(resp.Body).Decode(&rerun)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || rerun.RerunOf != submitted.ID {
+		t.Fatalf("Expected a rerun of %s, got %d: %+v", submitted.ID, resp.StatusCode, rerun)
+	}
+	job = waitForJob(t, restarted, rerun.ID)
+	if job.Status != JobSucceeded || job.config.TrainSplit != 0.5 {
+		t.Errorf("Expected the rerun to succeed with the same options, got %s (train split %v)", job.Status, job.config.TrainSplit)
+	}
+
+	resp, err = http.Get(ts.URL + "/jobs?status=failed")
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	var failed []Job
+	json.NewDecoder(resp.Body).Decode(&failed)
+	resp.Body.Close()
+	if len(failed) != 1 || failed[0].ID != interrupted.ID {
+		t.Errorf("Expected only the interrupted job, got %+v", failed)
+	}
+
+	resp, err = http.Post(ts.URL+"/jobs/unknown/rerun", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Failed to rerun job: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %d", resp.StatusCode)
+	}
+}
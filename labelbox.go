@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// labelboxKeyEnv names the environment variable holding the Labelbox API
+// key sent with mask downloads
+const labelboxKeyEnv = "LABELBOX_API_KEY"
+
+// LabelboxRow is one data row of a Labelbox NDJSON export
+type LabelboxRow struct {
+	DataRow struct {
+		ID         string `json:"id"`
+		ExternalID string `json:"external_id"`
+		GlobalKey  string `json:"global_key"`
+		RowData    string `json:"row_data"`
+	} `json:"data_row"`
+	Media struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"media_attributes"`
+	Projects map[string]struct {
+		Labels []struct {
+			Annotations struct {
+				Objects []LabelboxObject `json:"objects"`
+			} `json:"annotations"`
+		} `json:"labels"`
+	} `json:"projects"`
+}
+
+// LabelboxObject is an object annotation in pixels; AnnotationKind tells
+// which geometry is set
+type LabelboxObject struct {
+	Name           string `json:"name"`
+	AnnotationKind string `json:"annotation_kind"`
+	BoundingBox    *struct {
+		Top    float64 `json:"top"`
+		Left   float64 `json:"left"`
+		Height float64 `json:"height"`
+		Width  float64 `json:"width"`
+	} `json:"bounding_box"`
+	Polygon []labelboxPoint `json:"polygon"`
+	Line    []labelboxPoint `json:"line"`
+	Mask    *struct {
+		URL string `json:"url"`
+	} `json:"mask"`
+	CompositeMask *struct {
+		URL   string `json:"url"`
+		Color []int  `json:"color_rgb"`
+	} `json:"composite_mask"`
+}
+
+type labelboxPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// loadLabelboxRows reads a Labelbox NDJSON export, one data row per line
+func loadLabelboxRows(path string) ([]LabelboxRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Labelbox export: %w", err)
+	}
+	defer file.Close()
+
+	var rows []LabelboxRow
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var row LabelboxRow
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return nil, fmt.Errorf("failed to parse Labelbox export %s:%d: %w", path, line, err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Labelbox export: %w", err)
+	}
+	return rows, nil
+}
+
+// findLabelboxFile returns the configured export or the only .ndjson file
+// in the source directory
+func (c *Converter) findLabelboxFile() (string, error) {
+	if c.config.TasksFile != "" {
+		return c.config.TasksFile, nil
+	}
+	matches, err := filepath.Glob(filepath.Join(c.config.SourceDir, "*.ndjson"))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no Labelbox NDJSON export found in %s (use -tasks)", c.config.SourceDir)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("multiple NDJSON files found in %s, choose one with -tasks", c.config.SourceDir)
+}
+
+// labelboxFetcher downloads the images and masks a Labelbox export refers
+// to. Mask URLs point to the Labelbox API and need the API key; image URLs
+// are usually signed and are fetched without it.
+type labelboxFetcher struct {
+	client *http.Client
+	ctx    context.Context
+	key    string
+	masks  map[string]image.Image
+}
+
+// get downloads a URL into w
+func (f *labelboxFetcher) get(rawURL string, auth bool, w io.Writer) error {
+	req, err := http.NewRequestWithContext(f.ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if auth && f.key != "" {
+		req.Header.Set("Authorization", "Bearer "+f.key)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// mask downloads and decodes a mask PNG, once per URL and image
+func (f *labelboxFetcher) mask(rawURL string) (image.Image, error) {
+	if mask, ok := f.masks[rawURL]; ok {
+		return mask, nil
+	}
+	var data bytes.Buffer
+	if err := f.get(rawURL, true, &data); err != nil {
+		return nil, err
+	}
+	mask, _, err := image.Decode(&data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mask %s: %w", rawURL, err)
+	}
+	f.masks[rawURL] = mask
+	return mask, nil
+}
+
+// loadLabelbox converts a Labelbox NDJSON export into YOLO label files in a
+// staging directory. Images are taken from the source's images/ directory
+// by external ID, or downloaded from the row's URL when they aren't there.
+// Boxes, polygons and polylines become the Label Studio results they
+// correspond to; segmentation masks are traced into polygons like brush
+// masks.
+func (c *Converter) loadLabelbox() ([]string, []LabelPair, error) {
+	if c.skeleton != nil {
+		return nil, nil, fmt.Errorf("-skeleton requires -input-format %s", InputLSJSON)
+	}
+	path, err := c.findLabelboxFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := loadLabelboxRows(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprintf(c.out, "Loaded %d data rows from %s\n", len(rows), path)
+
+	var classes []string
+	if _, err := os.Stat(filepath.Join(c.config.SourceDir, "classes.txt")); err == nil {
+		if classes, err = c.LoadClasses(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stagingDir, err := c.stagingDirectory()
+	if err != nil {
+		return nil, nil, err
+	}
+	downloadDir := filepath.Join(stagingDir, "images")
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return nil, nil, err
+	}
+
+	builder := newLabelBuilder(classes, c.config.MaskTolerance, nil)
+	builder.obb = c.config.OBB
+	if builder.shapeModes, err = parseShapeModes(c.config.ShapeModes); err != nil {
+		return nil, nil, err
+	}
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	fetcher := &labelboxFetcher{
+		client: &http.Client{Timeout: 5 * time.Minute},
+		ctx:    ctx,
+		key:    os.Getenv(labelboxKeyEnv),
+	}
+
+	var pairs []LabelPair
+	downloaded := 0
+	for i, row := range rows {
+		if err := c.interrupted(); err != nil {
+			return nil, nil, err
+		}
+		imagePath, fetched, err := c.labelboxImage(row, downloadDir, fetcher)
+		if err != nil {
+			fmt.Fprintf(c.out, "Warning: Skipping data row %s: %v\n", row.DataRow.ID, err)
+			c.skipped++
+			continue
+		}
+		if fetched {
+			downloaded++
+		}
+		// Composite masks are shared by the objects of one image
+		fetcher.masks = make(map[string]image.Image)
+
+		width, height := row.Media.Width, row.Media.Height
+		if width <= 0 || height <= 0 {
+			if width, height, err = readImageSize(imagePath); err != nil {
+				return nil, nil, fmt.Errorf("failed to read the size of %s: %w", imagePath, err)
+			}
+		}
+
+		var lines []string
+		for _, object := range row.objects() {
+			if object.Mask == nil && object.CompositeMask == nil {
+				lines = append(lines, builder.taskLines(c, i, []LSResult{labelboxResult(object, width, height)})...)
+				continue
+			}
+			if builder.obb {
+				builder.unsupported[object.AnnotationKind]++
+				continue
+			}
+			polygons, err := c.labelboxMaskPolygons(object, fetcher, width, height)
+			if err != nil {
+				fmt.Fprintf(c.out, "Warning: Cannot read %s mask of data row %s: %v\n", object.Name, row.DataRow.ID, err)
+				c.skipped++
+				continue
+			}
+			classID := builder.classID(object.Name)
+			for _, coords := range polygons {
+				lines = append(lines, polygonLine(classID, coords))
+			}
+		}
+
+		imageName := filepath.Base(imagePath)
+		labelPath := filepath.Join(stagingDir, strings.TrimSuffix(imageName, filepath.Ext(imageName))+".txt")
+		if err := os.WriteFile(labelPath, []byte(strings.Join(lines, "")), 0644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write label %s: %w", labelPath, err)
+		}
+		pairs = append(pairs, LabelPair{ImagePath: imagePath, LabelPath: labelPath})
+	}
+
+	if downloaded > 0 {
+		fmt.Fprintf(c.out, "Downloaded %d images\n", downloaded)
+	}
+	for shape, count := range builder.unsupported {
+		fmt.Fprintf(c.out, "Warning: Skipped %d unsupported %s objects\n", count, shape)
+	}
+	fmt.Fprintf(c.out, "Found %d classes: %v\n", len(builder.classes), builder.classes)
+	fmt.Fprintf(c.out, "Found %d image-label pairs\n", len(pairs))
+	return builder.classes, pairs, nil
+}
+
+// objects returns the objects of the first label of each project the row
+// is in, in project order
+func (row LabelboxRow) objects() []LabelboxObject {
+	ids := make([]string, 0, len(row.Projects))
+	for id := range row.Projects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var objects []LabelboxObject
+	for _, id := range ids {
+		if labels := row.Projects[id].Labels; len(labels) > 0 {
+			objects = append(objects, labels[0].Annotations.Objects...)
+		}
+	}
+	return objects
+}
+
+// labelboxImageName returns the file name of a row's image: its external
+// ID or global key when that is an image file name, otherwise the row ID
+// with the extension of the image URL
+func labelboxImageName(row LabelboxRow) string {
+	for _, name := range []string{row.DataRow.ExternalID, row.DataRow.GlobalKey} {
+		if name = imageFileName(name); imageExtensions[strings.ToLower(filepath.Ext(name))] {
+			return name
+		}
+	}
+	ext := ".jpg"
+	if u, err := url.Parse(row.DataRow.RowData); err == nil && imageExtensions[strings.ToLower(path.Ext(u.Path))] {
+		ext = strings.ToLower(path.Ext(u.Path))
+	}
+	return row.DataRow.ID + ext
+}
+
+// labelboxImage returns the path of a row's image in the source's images/
+// directory, downloading it into dir when it isn't there. It reports
+// whether the image was downloaded.
+func (c *Converter) labelboxImage(row LabelboxRow, dir string, fetcher *labelboxFetcher) (string, bool, error) {
+	name := labelboxImageName(row)
+	imagePath := filepath.Join(c.config.SourceDir, "images", name)
+	if _, err := os.Stat(imagePath); err == nil {
+		return imagePath, false, nil
+	}
+	if !isRemoteSource(row.DataRow.RowData) {
+		return "", false, fmt.Errorf("image %s not found", name)
+	}
+
+	imagePath = filepath.Join(dir, name)
+	file, err := os.Create(imagePath)
+	if err != nil {
+		return "", false, err
+	}
+	err = fetcher.get(row.DataRow.RowData, false, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(imagePath)
+		return "", false, fmt.Errorf("failed to download image: %w", err)
+	}
+	return imagePath, true, nil
+}
+
+// labelboxResult converts a vector object in pixels into a Label Studio
+// result in percentages of the image size
+func labelboxResult(object LabelboxObject, width, height int) LSResult {
+	w, h := float64(width), float64(height)
+	result := LSResult{OriginalWidth: width, OriginalHeight: height}
+	value := &result.Value
+	labels := []string{object.Name}
+	points := func(points []labelboxPoint) [][]float64 {
+		var scaled [][]float64
+		for _, p := range points {
+			scaled = append(scaled, []float64{p.X / w * 100, p.Y / h * 100})
+		}
+		return scaled
+	}
+
+	switch {
+	case object.BoundingBox != nil:
+		box := object.BoundingBox
+		result.Type = "rectanglelabels"
+		value.RectangleLabels = labels
+		value.X, value.Y = box.Left/w*100, box.Top/h*100
+		value.Width, value.Height = box.Width/w*100, box.Height/h*100
+	case object.Polygon != nil:
+		result.Type = "polygonlabels"
+		value.PolygonLabels = labels
+		value.Points = points(object.Polygon)
+	case object.Line != nil:
+		result.Type = "polylinelabels"
+		value.PolylineLabels = labels
+		value.Points = points(object.Line)
+	default:
+		// Points and other kinds are counted as unsupported
+		result.Type = object.AnnotationKind
+		value.Labels = labels
+	}
+	return result
+}
+
+// labelboxMaskPolygons downloads a segmentation mask and traces its regions
+// into normalized polygons. An object's own mask marks it with any non-black
+// pixel; a composite mask holds every object of the image, each in its own
+// color.
+func (c *Converter) labelboxMaskPolygons(object LabelboxObject, fetcher *labelboxFetcher, width, height int) ([][]float64, error) {
+	maskURL, color := "", []int(nil)
+	if object.CompositeMask != nil {
+		maskURL, color = object.CompositeMask.URL, object.CompositeMask.Color
+		if len(color) != 3 {
+			return nil, fmt.Errorf("composite mask has no color")
+		}
+	} else {
+		maskURL = object.Mask.URL
+	}
+	img, err := fetcher.mask(maskURL)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	mw, mh := bounds.Dx(), bounds.Dy()
+	if mw != width || mh != height {
+		return nil, fmt.Errorf("mask is %dx%d, the image %dx%d", mw, mh, width, height)
+	}
+	mask := make([]bool, mw*mh)
+	for y := range mh {
+		for x := range mw {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			if color != nil {
+				mask[y*mw+x] = int(r>>8) == color[0] && int(g>>8) == color[1] && int(b>>8) == color[2]
+			} else {
+				mask[y*mw+x] = r|g|b != 0
+			}
+		}
+	}
+
+	var polygons [][]float64
+	for _, ring := range maskPolygons(mask, mw, mh, c.config.MaskTolerance) {
+		coords := make([]float64, 0, len(ring)*2)
+		for _, p := range ring {
+			coords = append(coords, clamp01(p[0]/float64(mw)), clamp01(p[1]/float64(mh)))
+		}
+		polygons = append(polygons, coords)
+	}
+	return polygons, nil
+}
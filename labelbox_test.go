@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// encodeMaskPNG draws rectangles of colors on a black 10x10 PNG
+func encodeMaskPNG(t *testing.T, rects map[image.Rectangle]color.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := range 10 {
+		for x := range 10 {
+			img.Set(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	for rect, c := range rects {
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				img.Set(x, y, c)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLabelboxConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sourceDir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestImage(t, filepath.Join(sourceDir, "images", "local.jpg"), 200, 100)
+	remote := filepath.Join(t.TempDir(), "remote.png")
+	writeTestImage(t, remote, 10, 10)
+	remoteImage, _ := os.ReadFile(remote)
+
+	var auth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images/remote.png":
+			w.Write(remoteImage)
+		case "/masks/road":
+			auth = append(auth, r.Header.Get("Authorization"))
+			w.Write(encodeMaskPNG(t, map[image.Rectangle]color.RGBA{image.Rect(2, 2, 6, 5): {255, 255, 255, 255}}))
+		case "/masks/composite":
+			auth = append(auth, r.Header.Get("Authorization"))
+			w.Write(encodeMaskPNG(t, map[image.Rectangle]color.RGBA{
+				image.Rect(0, 0, 2, 2):   {255, 0, 0, 255},
+				image.Rect(8, 8, 10, 10): {0, 0, 255, 255},
+			}))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv(labelboxKeyEnv, "secret")
+
+	// One data row per line
+	records := []string{
+		`{"data_row": {"id": "r1", "external_id": "local.jpg", "row_data": "%[1]s/images/local.jpg"}, "media_attributes": {"width": 200, "height": 100}, "projects": {"p1": {"labels": [{"annotations": {"objects": [{"name": "car", "annotation_kind": "ImageBoundingBox", "bounding_box": {"top": 10, "left": 20, "height": 20, "width": 40}},{"name": "person", "annotation_kind": "ImagePolygon", "polygon": [{"x": 20, "y": 10}, {"x": 100, "y": 10}, {"x": 60, "y": 60}]},{"name": "car", "annotation_kind": "ImagePoint", "point": {"x": 1, "y": 1}}]}}]}}}`,
+		`{"data_row": {"id": "r2", "external_id": "scan-7", "row_data": "%[1]s/images/remote.png"}, "media_attributes": {"width": 10, "height": 10}, "projects": {"p1": {"labels": [{"annotations": {"objects": [{"name": "road", "annotation_kind": "ImageSegmentationMask", "mask": {"url": "%[1]s/masks/road"}},{"name": "car", "annotation_kind": "ImageSegmentationMask", "composite_mask": {"url": "%[1]s/masks/composite", "color_rgb": [0, 0, 255]}},{"name": "person", "annotation_kind": "ImageSegmentationMask", "composite_mask": {"url": "%[1]s/masks/composite", "color_rgb": [255, 0, 0]}}]}}]}}}`,
+		`{"data_row": {"id": "r3", "external_id": "gone.jpg", "row_data": "%[1]s/images/gone.jpg"}, "projects": {}}`,
+	}
+	export := fmt.Sprintf(strings.Join(records, "\n")+"\n", server.URL)
+	if err := os.WriteFile(filepath.Join(sourceDir, "export.ndjson"), []byte(export), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: filepath.Join(t.TempDir(), "out"), TrainSplit: 1, Seed: 42, InputFormat: InputLabelbox})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	labels := filepath.Join(converter.config.OutputDir, "labels", "train")
+
+	local, err := os.ReadFile(filepath.Join(labels, "local.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(local) != "0 0.200000 0.200000 0.200000 0.200000\n1 0.100000 0.100000 0.500000 0.100000 0.300000 0.600000\n" {
+		t.Errorf("Unexpected label local.txt %q", local)
+	}
+
+	// The remote image is named after its row, and each mask region becomes
+	// a polygon; the composite mask is downloaded once
+	if _, err := os.Stat(filepath.Join(converter.config.OutputDir, "images", "train", "r2.png")); err != nil {
+		t.Errorf("Expected the downloaded image in the output: %v", err)
+	}
+	remoteLabel, err := os.ReadFile(filepath.Join(labels, "r2.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var classes []string
+	for _, line := range strings.Split(strings.TrimSpace(string(remoteLabel)), "\n") {
+		classes = append(classes, strings.Fields(line)[0])
+	}
+	if strings.Join(classes, " ") != "2 0 1" {
+		t.Errorf("Expected road, car and person polygons, got %q", remoteLabel)
+	}
+	if strings.Join(auth, " ") != "Bearer secret Bearer secret" {
+		t.Errorf("Expected two authorized mask downloads, got %v", auth)
+	}
+
+	log := out.String()
+	for _, want := range []string{"Skipping data row r3", "Downloaded 1 images", "Skipped 1 unsupported ImagePoint objects", "Found 3 classes: [car person road]"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, log)
+		}
+	}
+}
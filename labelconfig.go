@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// labelConfigFiles are the files in the source directory the labeling
+// config is read from when -label-config is not set: the config itself, or
+// the project as returned by the Label Studio API
+var labelConfigFiles = []string{"label_config.xml", "project.json"}
+
+// LabelConfigClass is a label of a Label Studio labeling config
+type LabelConfigClass struct {
+	Name string
+	// Color is the label's background as #rrggbb, empty when it has none or
+	// it can't be parsed
+	Color string
+}
+
+// parseLabelConfig returns the labels of the *Labels control tags
+// (RectangleLabels, PolygonLabels, BrushLabels and the like) of a labeling
+// config, in the order they appear. A label used by several controls is
+// listed once, with the first color it was given.
+func parseLabelConfig(data []byte) ([]LabelConfigClass, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	var labels []LabelConfigClass
+	seen := make(map[string]bool)
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse labeling config: %w", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if depth > 0 {
+				depth++
+				if token.Name.Local != "Label" {
+					continue
+				}
+				value, background := xmlAttr(token, "value"), xmlAttr(token, "background")
+				if value == "" || seen[value] {
+					continue
+				}
+				seen[value] = true
+				labels = append(labels, LabelConfigClass{Name: value, Color: parseLabelColor(background)})
+			} else if strings.HasSuffix(token.Name.Local, "Labels") {
+				depth = 1
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("labeling config has no <Label> in a labels control")
+	}
+	return labels, nil
+}
+
+// xmlAttr returns the value of an element's attribute, or ""
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// labelColorNames are the CSS color names commonly set as label backgrounds
+var labelColorNames = map[string]string{
+	"red": "#ff0000", "green": "#008000", "blue": "#0000ff", "yellow": "#ffff00",
+	"orange": "#ffa500", "purple": "#800080", "pink": "#ffc0cb", "brown": "#a52a2a",
+	"black": "#000000", "white": "#ffffff", "gray": "#808080", "grey": "#808080",
+	"cyan": "#00ffff", "magenta": "#ff00ff", "lime": "#00ff00", "navy": "#000080",
+	"teal": "#008080", "olive": "#808000", "maroon": "#800000", "violet": "#ee82ee",
+}
+
+// parseLabelColor normalizes a label background (#rgb, #rrggbb, #rrggbbaa,
+// rgb(), rgba() or a common color name) to #rrggbb, or returns ""
+func parseLabelColor(background string) string {
+	s := strings.ToLower(strings.TrimSpace(background))
+	if named, ok := labelColorNames[s]; ok {
+		return named
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		switch len(hex) {
+		case 3:
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		case 8:
+			hex = hex[:6]
+		}
+		if _, err := strconv.ParseUint(hex, 16, 32); err != nil || len(hex) != 6 {
+			return ""
+		}
+		return "#" + hex
+	}
+	for _, prefix := range []string{"rgba(", "rgb("} {
+		inner, ok := strings.CutPrefix(s, prefix)
+		if !ok {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(inner, ")"), ",")
+		if len(parts) < 3 {
+			return ""
+		}
+		var rgb [3]uint64
+		for i := range rgb {
+			v, err := strconv.ParseUint(strings.TrimSpace(parts[i]), 10, 8)
+			if err != nil {
+				return ""
+			}
+			rgb[i] = v
+		}
+		return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+	}
+	return ""
+}
+
+// loadLabelConfig reads the labeling config named by -label-config, or found
+// in the source directory, returning nil when there is none
+func (c *Converter) loadLabelConfig() ([]LabelConfigClass, error) {
+	path := c.config.LabelConfig
+	if path == "" {
+		for _, name := range labelConfigFiles {
+			candidate := filepath.Join(c.config.SourceDir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return nil, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read labeling config: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var project struct {
+			LabelConfig string `json:"label_config"`
+		}
+		if err := json.Unmarshal(data, &project); err != nil {
+			return nil, fmt.Errorf("failed to parse project %s: %w", path, err)
+		}
+		if project.LabelConfig == "" {
+			if c.config.LabelConfig == "" {
+				// Some other project.json
+				return nil, nil
+			}
+			return nil, fmt.Errorf("project %s has no label_config", path)
+		}
+		data = []byte(project.LabelConfig)
+	}
+	labels, err := parseLabelConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	fmt.Fprintf(c.out, "Read %d labels from labeling config %s\n", len(labels), filepath.Base(path))
+	return labels, nil
+}
+
+// checkLabelConfig warns about classes missing from the labeling config and
+// configured labels that aren't classes
+func (c *Converter) checkLabelConfig(classes []string) {
+	if c.labelConfig == nil {
+		return
+	}
+	configured := make(map[string]bool, len(c.labelConfig))
+	for _, label := range c.labelConfig {
+		configured[label.Name] = true
+	}
+	known := make(map[string]bool, len(classes))
+	var unconfigured, unused []string
+	for _, class := range classes {
+		known[class] = true
+		if !configured[class] {
+			unconfigured = append(unconfigured, class)
+		}
+	}
+	for _, label := range c.labelConfig {
+		if !known[label.Name] {
+			unused = append(unused, label.Name)
+		}
+	}
+	if len(unconfigured) > 0 {
+		fmt.Fprintf(c.out, "Warning: Classes not in the labeling config: %v\n", unconfigured)
+	}
+	if len(unused) > 0 {
+		fmt.Fprintf(c.out, "Warning: Labeling config labels missing from the classes: %v\n", unused)
+	}
+}
+
+// classColors returns the configured color of each class, "" for classes
+// without one, or nil when the labeling config sets no colors
+func (c *Converter) classColors(classes []string) []string {
+	colors := make(map[string]string)
+	for _, label := range c.labelConfig {
+		if label.Color != "" {
+			colors[label.Name] = label.Color
+		}
+	}
+	if len(colors) == 0 {
+		return nil
+	}
+	result := make([]string, len(classes))
+	for i, class := range classes {
+		result[i] = colors[class]
+	}
+	return result
+}
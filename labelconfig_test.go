@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// testLabelConfig labels boxes and polygons, repeating a label across the
+// two controls
+const testLabelConfig = `<View>
+  <Image name="image" value="$image"/>
+  <RectangleLabels name="box" toName="image">
+    <Label value="person" background="#0a0"/>
+    <Label value="book" background="rgba(255, 0, 0, 0.7)"/>
+  </RectangleLabels>
+  <PolygonLabels name="poly" toName="image">
+    <Label value="book" background="blue"/>
+    <Label value="shelf"/>
+  </PolygonLabels>
+  <Choices name="quality" toName="image">
+    <Choice value="blurry"/>
+  </Choices>
+</View>`
+
+func TestParseLabelConfig(t *testing.T) {
+	labels, err := parseLabelConfig([]byte(testLabelConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LabelConfigClass{{"person", "#00aa00"}, {"book", "#ff0000"}, {"shelf", ""}}
+	if len(labels) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, labels)
+	}
+	for i := range want {
+		if labels[i] != want[i] {
+			t.Errorf("Label %d: expected %v, got %v", i, want[i], labels[i])
+		}
+	}
+
+	if _, err := parseLabelConfig([]byte(`<View><Choices name="c"><Choice value="a"/></Choices></View>`)); err == nil {
+		t.Error("Expected an error for a config without labels")
+	}
+}
+
+func TestParseLabelColor(t *testing.T) {
+	for background, want := range map[string]string{
+		"#FFA500":          "#ffa500",
+		"#f00":             "#ff0000",
+		"#11223380":        "#112233",
+		"rgb(1, 2, 3)":     "#010203",
+		"Orange":           "#ffa500",
+		"#12":              "",
+		"rgb(300, 0, 0)":   "",
+		"hsl(0, 50%, 50%)": "",
+		"":                 "",
+	} {
+		if got := parseLabelColor(background); got != want {
+			t.Errorf("parseLabelColor(%q) = %q, want %q", background, got, want)
+		}
+	}
+}
+
+func TestConvertChecksLabelConfig(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	if err := os.WriteFile(filepath.Join(sourceDir, "label_config.xml"), []byte(testLabelConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Warning: Labeling config labels missing from the classes: [shelf]") {
+		t.Errorf("Expected a warning about shelf, got:\n%s", out.String())
+	}
+
+	// Colors follow the classes.txt order, book then person
+	data, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config YAMLConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(config.Colors, " ") != "#ff0000 #00aa00" {
+		t.Errorf("Expected the configured colors in data.yaml, got %v", config.Colors)
+	}
+	palette := previewPalette(config.Names, config.Colors)
+	if palette[0].R != 255 || palette[1].G != 0xaa {
+		t.Errorf("Expected the preview to use the configured colors, got %v", palette)
+	}
+}
+
+func TestLabelStudioJSONUsesLabelConfig(t *testing.T) {
+	sourceDir := t.TempDir()
+	createLabelStudioExport(t, sourceDir)
+	project, err := json.Marshal(map[string]any{"id": 3, "label_config": `<View><RectangleLabels name="l" toName="i"><Label value="dog"/><Label value="bird"/><Label value="cat"/></RectangleLabels></View>`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "project.json"), project, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, InputFormat: InputLSJSON})
+	converter.SetOutput(&out)
+	defer converter.removeStaging()
+	if converter.labelConfig, err = converter.loadLabelConfig(); err != nil {
+		t.Fatal(err)
+	}
+	classes, _, err := converter.loadSource()
+	if err != nil {
+		t.Fatalf("Failed to load export: %v", err)
+	}
+	if strings.Join(classes, ",") != "dog,bird,cat" {
+		t.Errorf("Expected the labeling config order, got %v", classes)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// Problems normalizeLabelText fixes in label files
+const (
+	labelIssueUTF16      = "UTF-16"
+	labelIssueBOM        = "byte order mark"
+	labelIssueCRLF       = "CRLF line endings"
+	labelIssueWhitespace = "trailing whitespace"
+)
+
+// normalizeLabelText decodes label file contents saved by Windows tooling,
+// UTF-16 with or without a byte order mark, or UTF-8 with one, and returns
+// them as UTF-8 with LF line endings and no trailing whitespace on any line.
+// It also returns the problems it fixed, none for a clean file.
+func normalizeLabelText(data []byte) (string, []string) {
+	var issues []string
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		data, issues = decodeUTF16(data[2:], false), append(issues, labelIssueUTF16)
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		data, issues = decodeUTF16(data[2:], true), append(issues, labelIssueUTF16)
+	case len(data) >= 2 && len(data)%2 == 0 && data[0] != 0 && data[1] == 0:
+		data, issues = decodeUTF16(data, false), append(issues, labelIssueUTF16)
+	case len(data) >= 2 && len(data)%2 == 0 && data[0] == 0 && data[1] != 0:
+		data, issues = decodeUTF16(data, true), append(issues, labelIssueUTF16)
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		data, issues = data[3:], append(issues, labelIssueBOM)
+	}
+
+	text := string(data)
+	if strings.ContainsRune(text, '\r') {
+		text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+		issues = append(issues, labelIssueCRLF)
+	}
+	lines := strings.Split(text, "\n")
+	trimmed := false
+	for i, line := range lines {
+		if stripped := strings.TrimRight(line, " \t"); stripped != line {
+			lines[i] = stripped
+			trimmed = true
+		}
+	}
+	if trimmed {
+		issues = append(issues, labelIssueWhitespace)
+		text = strings.Join(lines, "\n")
+	}
+	return text, issues
+}
+
+// decodeUTF16 converts UTF-16 text to UTF-8
+func decodeUTF16(data []byte, bigEndian bool) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// labelIssueCounts counts the label files with each problem fixed by
+// normalization; it is safe for concurrent use
+type labelIssueCounts struct {
+	mu     sync.Mutex
+	files  int
+	counts map[string]int
+}
+
+// add records the problems of one label file
+func (l *labelIssueCounts) add(issues []string) {
+	if len(issues) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts == nil {
+		l.counts = make(map[string]int)
+	}
+	l.files++
+	for _, issue := range issues {
+		l.counts[issue]++
+	}
+}
+
+// report prints how many label files were normalized and why
+func (l *labelIssueCounts) report(c *Converter) {
+	if l.files == 0 {
+		return
+	}
+	issues := make([]string, 0, len(l.counts))
+	for issue := range l.counts {
+		issues = append(issues, issue)
+	}
+	sort.Strings(issues)
+	var parts []string
+	for _, issue := range issues {
+		parts = append(parts, fmt.Sprintf("%d %s", l.counts[issue], issue))
+	}
+	fmt.Fprintf(c.out, "Normalized %d label files (%s)\n", l.files, strings.Join(parts, ", "))
+}
+
+// normalizeLabel rewrites a label file that needs normalizing into dir and
+// returns the pair pointing there, along with the problems it fixed
+func normalizeLabel(pair LabelPair, dir string) (LabelPair, []string, error) {
+	data, err := os.ReadFile(pair.LabelPath)
+	if err != nil {
+		// Validation reports unreadable files
+		return pair, nil, nil
+	}
+	text, issues := normalizeLabelText(data)
+	if len(issues) == 0 {
+		return pair, nil, nil
+	}
+	labelPath := filepath.Join(dir, filepath.Base(pair.LabelPath))
+	if err := os.WriteFile(labelPath, []byte(text), 0644); err != nil {
+		return pair, nil, fmt.Errorf("failed to write label %s: %w", labelPath, err)
+	}
+	pair.LabelPath = labelPath
+	return pair, issues, nil
+}
+
+// normalizedLabelDir creates the staging directory normalized labels are
+// written to
+func (c *Converter) normalizedLabelDir() (string, error) {
+	staging, err := c.stagingDirectory()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(staging, "normalized")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return dir, nil
+}
+
+// normalizeLabels normalizes the encoding and line endings of every label
+// file that needs it, so that validation and the output see clean UTF-8
+// labels with LF line endings; source files are never modified
+func (c *Converter) normalizeLabels(pairs []LabelPair) ([]LabelPair, error) {
+	var counts labelIssueCounts
+	var dir string
+	result := make([]LabelPair, len(pairs))
+	for i, pair := range pairs {
+		result[i] = pair
+		data, err := os.ReadFile(pair.LabelPath)
+		if err != nil {
+			continue
+		}
+		if _, issues := normalizeLabelText(data); len(issues) == 0 {
+			continue
+		}
+		if dir == "" {
+			if dir, err = c.normalizedLabelDir(); err != nil {
+				return nil, err
+			}
+		}
+		normalized, issues, err := normalizeLabel(pair, dir)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = normalized
+		counts.add(issues)
+	}
+	counts.report(c)
+	return result, nil
+}
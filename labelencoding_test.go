@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 encodes text as UTF-16, little-endian unless bigEndian, with an
+// optional byte order mark
+func encodeUTF16(text string, bigEndian, bom bool) []byte {
+	units := utf16.Encode([]rune(text))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	data := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		if bigEndian {
+			data = append(data, byte(u>>8), byte(u))
+		} else {
+			data = append(data, byte(u), byte(u>>8))
+		}
+	}
+	return data
+}
+
+func TestNormalizeLabelText(t *testing.T) {
+	const clean = "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n"
+	tests := []struct {
+		name   string
+		data   []byte
+		issues string
+	}{
+		{"clean", []byte(clean), ""},
+		{"crlf", []byte(strings.ReplaceAll(clean, "\n", "\r\n")), "CRLF line endings"},
+		{"trailing tabs", []byte("0 0.5 0.5 0.3 0.3\t\n1 0.2 0.8 0.1 0.1 \n"), "trailing whitespace"},
+		{"utf-8 bom", append([]byte{0xef, 0xbb, 0xbf}, clean...), "byte order mark"},
+		{"utf-16le bom crlf", encodeUTF16(strings.ReplaceAll(clean, "\n", "\r\n"), false, true), "UTF-16,CRLF line endings"},
+		{"utf-16be bom", encodeUTF16(clean, true, true), "UTF-16"},
+		{"utf-16le", encodeUTF16(clean, false, false), "UTF-16"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, issues := normalizeLabelText(tt.data)
+			if text != clean {
+				t.Errorf("Expected %q, got %q", clean, text)
+			}
+			if got := strings.Join(issues, ","); got != tt.issues {
+				t.Errorf("Expected issues %q, got %q", tt.issues, got)
+			}
+		})
+	}
+}
+
+func TestConvertNormalizesLabels(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		sourceDir := t.TempDir()
+		createTestFiles(t, sourceDir)
+		windows := encodeUTF16("0 0.5 0.5 0.3 0.3\t\r\n1 0.2 0.8 0.1 0.1\r\n", false, true)
+		if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image1.txt"), windows, 0644); err != nil {
+			t.Fatal(err)
+		}
+		outputDir := filepath.Join(t.TempDir(), "out")
+
+		var out strings.Builder
+		converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, Stream: stream})
+		converter.SetOutput(&out)
+		if err := converter.Convert(); err != nil {
+			t.Fatalf("stream %v: conversion failed: %v\n%s", stream, err, out.String())
+		}
+		log := out.String()
+		for _, want := range []string{"Normalized 1 label files (1 CRLF line endings, 1 UTF-16, 1 trailing whitespace)", "TotalAnnotations:5", "InvalidLines:0"} {
+			if !strings.Contains(log, want) {
+				t.Errorf("stream %v: expected output to contain %q, got:\n%s", stream, want, log)
+			}
+		}
+
+		var label []byte
+		for _, split := range []string{"train", "val"} {
+			if data, err := os.ReadFile(filepath.Join(outputDir, "labels", split, "image1.txt")); err == nil {
+				label = data
+			}
+		}
+		if string(label) != "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n" {
+			t.Errorf("stream %v: expected a normalized output label, got %q", stream, label)
+		}
+		if data, _ := os.ReadFile(filepath.Join(sourceDir, "labels", "image1.txt")); string(data) != string(windows) {
+			t.Errorf("stream %v: expected the source label to be unchanged", stream)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"strconv"
+)
+
+// Annotation kinds found in label files
+const (
+	AnnotationBox     = "box"
+	AnnotationSegment = "segment"
+)
+
+// Dataset tasks; anything other than detection is recorded in data.yaml
+const (
+	TaskDetect  = "detect"
+	TaskSegment = "segment"
+)
+
+// parseLabelLine checks the fields of one label line. Lines are either boxes
+// (class_id x_center y_center width height) or polygons
+// (class_id x1 y1 x2 y2 x3 y3 ...). It returns the annotation kind, or an
+// empty kind and a warning describing why the line is invalid.
+func parseLabelLine(parts []string) (string, string) {
+	if len(parts) != 5 && (len(parts) < 7 || len(parts)%2 == 0) {
+		return "", "Wrong number of values"
+	}
+
+	if _, err := strconv.Atoi(parts[0]); err != nil {
+		return "", "Invalid class_id"
+	}
+
+	coords := make([]float64, len(parts)-1)
+	for i := 1; i < len(parts); i++ {
+		coord, err := strconv.ParseFloat(parts[i], 64)
+		if err != nil {
+			return "", "Invalid coordinate"
+		}
+		if coord < 0 || coord > 1 {
+			return "", "Non-normalized coordinates"
+		}
+		coords[i-1] = coord
+	}
+
+	if len(parts) == 5 {
+		return AnnotationBox, ""
+	}
+
+	if polygonArea(coords) == 0 {
+		return "", "Degenerate polygon"
+	}
+	return AnnotationSegment, ""
+}
+
+// polygonArea returns the area of a polygon given as flat x, y pairs
+func polygonArea(coords []float64) float64 {
+	return math.Abs(signedArea(coords))
+}
+
+// signedArea returns the shoelace area of a polygon given as flat x, y pairs.
+// With y pointing down, as in images, clockwise polygons are positive.
+func signedArea(coords []float64) float64 {
+	n := len(coords) / 2
+	area := 0.0
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += coords[2*i]*coords[2*j+1] - coords[2*j]*coords[2*i+1]
+	}
+	return area / 2
+}
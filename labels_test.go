@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLabelLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		kind    string
+		warning string
+	}{
+		{"0 0.5 0.5 0.3 0.3", AnnotationBox, ""},
+		{"1 0.1 0.1 0.9 0.1 0.5 0.8", AnnotationSegment, ""},
+		{"1 0.1 0.1 0.9 0.1 0.9 0.9 0.1 0.9", AnnotationSegment, ""},
+		{"0 0.5 0.5", "", "Wrong number of values"},
+		{"0 0.1 0.1 0.9 0.1 0.5", "", "Wrong number of values"},
+		{"0 0.1 0.1 0.9 0.1 0.5 0.8 0.2 0.2 0.3", "", "Wrong number of values"},
+		{"a 0.5 0.5 0.3 0.3", "", "Invalid class_id"},
+		{"0 0.1 0.1 0.9 x 0.5 0.8", "", "Invalid coordinate"},
+		{"0 0.1 0.1 1.5 0.1 0.5 0.8", "", "Non-normalized coordinates"},
+		{"0 0.1 0.1 0.5 0.5 0.9 0.9", "", "Degenerate polygon"},
+	}
+
+	for _, tt := range tests {
+		kind, warning := parseLabelLine(strings.Fields(tt.line))
+		if kind != tt.kind || warning != tt.warning {
+			t.Errorf("parseLabelLine(%q) = (%q, %q), expected (%q, %q)", tt.line, kind, warning, tt.kind, tt.warning)
+		}
+	}
+}
+
+func TestSegmentationConversion(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+
+	// Replace the box labels with polygons
+	polygons := map[string]string{
+		"image1.txt": "0 0.1 0.1 0.4 0.1 0.4 0.5 0.1 0.5\n",
+		"image2.txt": "1 0.5 0.5 0.9 0.5 0.7 0.9\n",
+		"image3.txt": "0 0.2 0.2 0.3 0.2 0.3 0.3\n1 0.6 0.6 0.8 0.6 0.8 0.8 0.6 0.8\n",
+	}
+	for name, content := range polygons {
+		if err := os.WriteFile(filepath.Join(tempDir, "labels", name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write label: %v", err)
+		}
+	}
+
+	outputDir := filepath.Join(tempDir, "yolo_output")
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42})
+
+	pairs, err := converter.GetImageLabelPairs()
+	if err != nil {
+		t.Fatalf("Failed to get pairs: %v", err)
+	}
+	stats, err := converter.ValidateLabels(pairs)
+	if err != nil {
+		t.Fatalf("Failed to validate labels: %v", err)
+	}
+	if stats.SegmentAnnotations != 4 || stats.BoxAnnotations != 0 || stats.InvalidLines != 0 {
+		t.Errorf("Expected 4 segment annotations and no boxes or errors, got %+v", stats)
+	}
+
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read data.yaml: %v", err)
+	}
+	if !strings.Contains(string(content), "task: segment") {
+		t.Errorf("Expected data.yaml to mark a segmentation dataset, got:\n%s", content)
+	}
+}
+
+func TestDetectionYAMLHasNoTask(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	outputDir := filepath.Join(tempDir, "yolo_output")
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42})
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read data.yaml: %v", err)
+	}
+	if strings.Contains(string(content), "task:") {
+		t.Errorf("Detection data.yaml should not set a task, got:\n%s", content)
+	}
+}
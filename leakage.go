@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// Leakage modes, selected with -leakage: what to do with validation images
+// that also appear in training
+const (
+	LeakageReport = "report"
+	LeakageFix    = "fix"
+)
+
+// maxLeaksPrinted limits the leaked images listed in the output
+const maxLeaksPrinted = 10
+
+// Leak is a validation image that is identical or nearly identical to a
+// training image
+type Leak struct {
+	Val   LabelPair
+	Train LabelPair
+	// Distance is the number of bits the perceptual hashes differ in, 0 for
+	// identical files
+	Distance  int
+	Identical bool
+}
+
+// validateLeakage checks the -leakage options before the conversion starts
+func (c *Converter) validateLeakage() error {
+	switch c.config.Leakage {
+	case "":
+		return nil
+	case LeakageReport, LeakageFix:
+	default:
+		return fmt.Errorf("unknown leakage mode %q (expected report or fix)", c.config.Leakage)
+	}
+	if c.config.LeakageDistance < 0 || c.config.LeakageDistance > 64 {
+		return fmt.Errorf("-leakage-distance must be between 0 and 64")
+	}
+	if c.config.Classify || c.config.MultiLabel || c.config.Crop {
+		return fmt.Errorf("-leakage cannot be combined with -classify, -multi-label or -crop")
+	}
+	return nil
+}
+
+// hashPairs hashes the images of the pairs concurrently, in order
+func (c *Converter) hashPairs(pairs []LabelPair) ([]imageHashes, error) {
+	hashes := make([]imageHashes, len(pairs))
+	errs := make([]error, len(pairs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < c.parallelism(len(pairs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				hashes[i], errs[i] = hashImageFile(pairs[i].ImagePath)
+			}
+		}()
+	}
+	for i := range pairs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+// findLeaks matches every validation image against the training images: by
+// content hash, then by the closest perceptual hash within maxDistance bits
+func (c *Converter) findLeaks(trainPairs, valPairs []LabelPair, maxDistance int) ([]Leak, int, error) {
+	trainHashes, err := c.hashPairs(trainPairs)
+	if err != nil {
+		return nil, 0, err
+	}
+	valHashes, err := c.hashPairs(valPairs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byContent := make(map[[sha256.Size]byte]int, len(trainHashes))
+	var index hashIndex
+	undecoded := 0
+	for i, h := range trainHashes {
+		if _, ok := byContent[h.Content]; !ok {
+			byContent[h.Content] = i
+		}
+		if h.Decoded {
+			index.add(h.DHash, i)
+		} else {
+			undecoded++
+		}
+	}
+
+	var leaks []Leak
+	for i, h := range valHashes {
+		if j, ok := byContent[h.Content]; ok {
+			leaks = append(leaks, Leak{Val: valPairs[i], Train: trainPairs[j], Identical: true})
+			continue
+		}
+		if !h.Decoded {
+			undecoded++
+			continue
+		}
+		if j, distance, ok := index.nearest(h.DHash, maxDistance); ok {
+			leaks = append(leaks, Leak{Val: valPairs[i], Train: trainPairs[j], Distance: distance})
+		}
+	}
+	return leaks, undecoded, nil
+}
+
+// checkLeakage reports validation images that duplicate training images
+// after the split. With -leakage fix they are moved to training, together
+// with their group, so the validation split only holds unseen images.
+func (c *Converter) checkLeakage(trainPairs, valPairs []LabelPair) ([]LabelPair, []LabelPair, error) {
+	if c.config.Leakage == "" || len(trainPairs) == 0 || len(valPairs) == 0 {
+		return trainPairs, valPairs, nil
+	}
+	leaks, undecoded, err := c.findLeaks(trainPairs, valPairs, c.config.LeakageDistance)
+	if err != nil {
+		return nil, nil, err
+	}
+	if undecoded > 0 {
+		fmt.Fprintf(c.out, "Warning: %d images could not be decoded and were only compared by content\n", undecoded)
+	}
+	if len(leaks) == 0 {
+		fmt.Fprintf(c.out, "Leakage check: no validation images match training images\n")
+		return trainPairs, valPairs, nil
+	}
+
+	identical := 0
+	for _, leak := range leaks {
+		if leak.Identical {
+			identical++
+		}
+	}
+	fmt.Fprintf(c.out, "Leakage check: %d validation images match training images (%d identical, %d near-duplicates)\n",
+		len(leaks), identical, len(leaks)-identical)
+	for i, leak := range leaks {
+		if i == maxLeaksPrinted {
+			fmt.Fprintf(c.out, "  ... and %d more\n", len(leaks)-maxLeaksPrinted)
+			break
+		}
+		match := "identical"
+		if !leak.Identical {
+			match = fmt.Sprintf("distance %d", leak.Distance)
+		}
+		fmt.Fprintf(c.out, "  val/%s ~ train/%s (%s)\n", filepath.Base(leak.Val.ImagePath), filepath.Base(leak.Train.ImagePath), match)
+	}
+
+	if c.config.Leakage != LeakageFix {
+		return trainPairs, valPairs, nil
+	}
+	move := make(map[string]bool)
+	groups := make(map[string]bool)
+	for _, leak := range leaks {
+		move[leak.Val.ImagePath] = true
+		if leak.Val.Group != "" {
+			groups[leak.Val.Group] = true
+		}
+	}
+	var kept []LabelPair
+	moved := 0
+	for _, pair := range valPairs {
+		if move[pair.ImagePath] || (pair.Group != "" && groups[pair.Group]) {
+			trainPairs = append(trainPairs, pair)
+			moved++
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	if kept == nil {
+		kept = []LabelPair{}
+	}
+	fmt.Fprintf(c.out, "Moved %d validation images to training to fix the leakage\n", moved)
+	fmt.Fprintf(c.out, "Dataset split: %d training, %d validation\n", len(trainPairs), len(kept))
+	return trainPairs, kept, nil
+}
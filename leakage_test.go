@@ -0,0 +1,150 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePatternPNG writes a gray image whose pixels are computed by shade
+func writePatternPNG(t *testing.T, path string, shade func(x, y int) uint8) {
+	img := image.NewGray(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetGray(x, y, color.Gray{Y: shade(x, y)})
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeLeakySource creates a source whose sequential split puts a copy and
+// an edited copy of the training images into validation
+func writeLeakySource(t *testing.T) string {
+	dir := t.TempDir()
+	for _, sub := range []string{"images", "labels"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	noise := rand.New(rand.NewSource(1))
+	noisy := make([]uint8, 64*48)
+	for i := range noisy {
+		noisy[i] = uint8(noise.Intn(256))
+	}
+	patterns := map[string]func(x, y int) uint8{
+		"a": func(x, y int) uint8 { return uint8(255 - x*3) },
+		"b": func(x, y int) uint8 { return uint8(y*5) ^ uint8(x*4) },
+		// d is b brightened, e is unrelated
+		"d": func(x, y int) uint8 { return min(uint8(y*5)^uint8(x*4), 245) + 10 },
+		"e": func(x, y int) uint8 { return noisy[y*64+x] },
+	}
+	for name, shade := range patterns {
+		writePatternPNG(t, filepath.Join(dir, "images", name+".png"), shade)
+	}
+	// c is a byte-for-byte copy of a
+	data, err := os.ReadFile(filepath.Join(dir, "images", "a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "images", "c.png"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := os.WriteFile(filepath.Join(dir, "labels", name+".txt"), []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "classes.txt"), []byte("object\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestDifferenceHash(t *testing.T) {
+	dir := t.TempDir()
+	writePatternPNG(t, filepath.Join(dir, "left.png"), func(x, y int) uint8 { return uint8(255 - x*3) })
+	writePatternPNG(t, filepath.Join(dir, "right.png"), func(x, y int) uint8 { return uint8(x * 3) })
+
+	left, err := hashImageFile(filepath.Join(dir, "left.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	right, err := hashImageFile(filepath.Join(dir, "right.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every pixel of a left-to-right darkening gradient is brighter than its
+	// right neighbour, and none of the opposite gradient is
+	if !left.Decoded || left.DHash != ^uint64(0) || right.DHash != 0 || hashDistance(left.DHash, right.DHash) != 64 {
+		t.Errorf("Unexpected hashes %016x and %016x", left.DHash, right.DHash)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.png"), []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if broken, err := hashImageFile(filepath.Join(dir, "broken.png")); err != nil || broken.Decoded {
+		t.Errorf("Expected an undecoded content hash, got %+v (%v)", broken, err)
+	}
+}
+
+func TestLeakage(t *testing.T) {
+	sourceDir := writeLeakySource(t)
+	config := Config{SourceDir: sourceDir, TrainSplit: 0.4, SplitStrategy: SplitSequential, SplitOrder: SplitOrderName, InputFormat: InputYOLO, Leakage: LeakageReport, LeakageDistance: 5}
+
+	config.OutputDir = filepath.Join(t.TempDir(), "out")
+	var out strings.Builder
+	converter := NewConverter(config)
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	for _, text := range []string{
+		"2 validation images match training images (1 identical, 1 near-duplicates)",
+		"val/c.png ~ train/a.png (identical)",
+		"val/d.png ~ train/b.png (distance",
+	} {
+		if !strings.Contains(out.String(), text) {
+			t.Errorf("Expected %q in the output:\n%s", text, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "val/e.png") {
+		t.Errorf("Expected e.png not to match:\n%s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(config.OutputDir, "images", "val", "c.png")); err != nil {
+		t.Errorf("Expected -leakage report to keep the split: %v", err)
+	}
+
+	config.Leakage = LeakageFix
+	config.OutputDir = filepath.Join(t.TempDir(), "out")
+	out.Reset()
+	converter = NewConverter(config)
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Moved 2 validation images to training") {
+		t.Errorf("Expected the leaked images to be moved:\n%s", out.String())
+	}
+	val, _ := os.ReadDir(filepath.Join(config.OutputDir, "images", "val"))
+	train, _ := os.ReadDir(filepath.Join(config.OutputDir, "images", "train"))
+	if len(val) != 1 || val[0].Name() != "e.png" || len(train) != 4 {
+		t.Errorf("Expected only e.png in validation, got %d training and %v", len(train), val)
+	}
+
+	config.Leakage = "delete"
+	if err := NewConverter(config).Convert(); err == nil || !strings.Contains(err.Error(), "leakage mode") {
+		t.Errorf("Expected an error for an unknown mode, got %v", err)
+	}
+}
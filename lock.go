@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// outputLockName is the lock file in a directory output; archive outputs are
+// locked by a file next to them named after the archive
+const outputLockName = ".labelstudio-to-yolo.lock"
+
+// outputLock is the content of a lock file, identifying the conversion that
+// holds it
+type outputLock struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Started  time.Time `json:"started"`
+}
+
+// lockPath returns the lock file of the output
+func (c *Converter) lockPath() string {
+	if archiveFormat(c.config.OutputDir) != "" {
+		return c.config.OutputDir + ".lock"
+	}
+	return filepath.Join(c.config.OutputDir, outputLockName)
+}
+
+// lockOutput takes an advisory lock on the output so two conversions can't
+// write into it at once, and returns the function releasing it. A lock left
+// by a process that is gone from this host is stale and taken over;
+// ForceUnlock removes any existing lock first.
+func (c *Converter) lockOutput() (func(), error) {
+	lockPath := c.lockPath()
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if c.config.ForceUnlock {
+		if err := os.Remove(lockPath); err == nil {
+			fmt.Fprintf(c.out, "Removed the lock %s\n", lockPath)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove lock: %w", err)
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	data, err := json.Marshal(outputLock{PID: os.Getpid(), Hostname: hostname, Started: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	// One retry after removing a stale lock
+	for attempt := 0; ; attempt++ {
+		err := writeLockFile(lockPath, data)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock %s: %w", lockPath, err)
+		}
+
+		existing, raw, readErr := readLockFile(lockPath)
+		if readErr != nil {
+			if os.IsNotExist(readErr) && attempt == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("output %s is locked by %s, which can't be read (%v); use -force-unlock if no conversion is running", c.config.OutputDir, lockPath, readErr)
+		}
+		if attempt > 0 || existing.Hostname != hostname || processAlive(existing.PID) {
+			return nil, fmt.Errorf("output %s is locked by process %d on %s since %s; use -force-unlock if that conversion is gone",
+				c.config.OutputDir, existing.PID, existing.Hostname, existing.Started.Local().Format(time.RFC3339))
+		}
+		// Only remove the stale lock if no other process took it over since
+		if current, err := os.ReadFile(lockPath); err != nil || !bytes.Equal(current, raw) {
+			continue
+		}
+		fmt.Fprintf(c.out, "Warning: removing the stale lock of process %d, which is no longer running\n", existing.PID)
+		if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale lock: %w", err)
+		}
+	}
+
+	return func() {
+		os.Remove(lockPath)
+	}, nil
+}
+
+// writeLockFile creates the lock file, failing with os.ErrExist when it
+// already exists
+func writeLockFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+// readLockFile reads a lock file and its raw content
+func readLockFile(path string) (outputLock, []byte, error) {
+	var lock outputLock
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lock, nil, err
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return lock, data, err
+	}
+	return lock, data, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package main
+
+// processAlive can't check processes on this platform, so locks are never
+// considered stale and need -force-unlock
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLockOutput(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "out")
+	converter := NewConverter(Config{OutputDir: outputDir})
+	converter.SetOutput(io.Discard)
+
+	unlock, err := converter.lockOutput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockPath := filepath.Join(outputDir, outputLockName)
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("Expected a lock file: %v", err)
+	}
+
+	// This process is alive, so a second conversion is refused
+	other := NewConverter(Config{OutputDir: outputDir})
+	other.SetOutput(io.Discard)
+	if _, err := other.lockOutput(); err == nil {
+		t.Fatal("Expected the locked output to be refused")
+	}
+
+	other.config.ForceUnlock = true
+	unlockOther, err := other.lockOutput()
+	if err != nil {
+		t.Fatalf("Expected -force-unlock to take the lock: %v", err)
+	}
+	unlockOther()
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	}
+	unlock()
+}
+
+func TestLockOutputStale(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("stale locks are only detected on Linux and macOS")
+	}
+	// The ID of a process that has exited
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("true is not available")
+	}
+	outputDir := t.TempDir()
+	hostname, _ := os.Hostname()
+	data, _ := json.Marshal(outputLock{PID: cmd.Process.Pid, Hostname: hostname, Started: time.Now()})
+	if err := os.WriteFile(filepath.Join(outputDir, outputLockName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{OutputDir: outputDir})
+	converter.SetOutput(io.Discard)
+	unlock, err := converter.lockOutput()
+	if err != nil {
+		t.Fatalf("Expected the stale lock to be taken over: %v", err)
+	}
+	unlock()
+
+	// Locks from other hosts can't be checked
+	data, _ = json.Marshal(outputLock{PID: cmd.Process.Pid, Hostname: hostname + "-elsewhere", Started: time.Now()})
+	if err := os.WriteFile(filepath.Join(outputDir, outputLockName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := converter.lockOutput(); err == nil {
+		t.Error("Expected a lock from another host to be kept")
+	}
+}
+
+func TestConvertReleasesLock(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	outputDir := filepath.Join(tempDir, "yolo_output")
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, outputLockName)); !os.IsNotExist(err) {
+		t.Errorf("Expected no lock after the conversion, got %v", err)
+	}
+}
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Input formats accepted by -input-format
+const (
+	InputYOLO       = "yolo"
+	InputLSJSON     = "ls-json"
+	InputCVAT       = "cvat"
+	InputOpenImages = "openimages"
+	InputVIA        = "via"
+	InputLabelbox   = "labelbox"
+)
+
+// LSTask is one task of a Label Studio JSON export
+type LSTask struct {
+	ID          int                    `json:"id"`
+	Data        map[string]interface{} `json:"data"`
+	Annotations []LSAnnotation         `json:"annotations"`
+	Predictions []LSAnnotation         `json:"predictions"`
+}
+
+// LSAnnotation is an annotation (or prediction) of a task
+type LSAnnotation struct {
+	ID           int        `json:"id"`
+	Result       []LSResult `json:"result"`
+	WasCancelled bool       `json:"was_cancelled"`
+	CreatedAt    string     `json:"created_at"`
+	UpdatedAt    string     `json:"updated_at"`
+	CompletedBy  LSUser     `json:"completed_by"`
+	ReviewResult string     `json:"review_result"`
+	Reviews      []LSReview `json:"reviews"`
+
+	// Predictions carry the model version and an optional confidence
+	ModelVersion string   `json:"model_version"`
+	Score        *float64 `json:"score"`
+}
+
+// LSResult is a single region or classification inside an annotation
+type LSResult struct {
+	ID             string  `json:"id"`
+	Type           string  `json:"type"`
+	FromName       string  `json:"from_name"`
+	ToName         string  `json:"to_name"`
+	ParentID       string  `json:"parentID"`
+	OriginalWidth  int     `json:"original_width"`
+	OriginalHeight int     `json:"original_height"`
+	Value          LSValue `json:"value"`
+
+	// Score is the confidence of a predicted region, when the model sets one
+	Score *float64 `json:"score"`
+}
+
+// LSValue holds the geometry and labels of a result. Coordinates are
+// percentages of the image size, as Label Studio stores them.
+type LSValue struct {
+	X        float64     `json:"x"`
+	Y        float64     `json:"y"`
+	Width    float64     `json:"width"`
+	Height   float64     `json:"height"`
+	Rotation float64     `json:"rotation"`
+	Points   [][]float64 `json:"points"`
+	RadiusX  float64     `json:"radiusX"`
+	RadiusY  float64     `json:"radiusY"`
+
+	Format string `json:"format"`
+	RLE    []int  `json:"rle"`
+
+	Labels          []string `json:"labels"`
+	RectangleLabels []string `json:"rectanglelabels"`
+	PolygonLabels   []string `json:"polygonlabels"`
+	BrushLabels     []string `json:"brushlabels"`
+	EllipseLabels   []string `json:"ellipselabels"`
+	PolylineLabels  []string `json:"polylinelabels"`
+	Choices         []string `json:"choices"`
+
+	// Sequence holds the keyframes of a video object track
+	Sequence []LSKeyframe `json:"sequence"`
+}
+
+// LabelNames returns the labels attached to the value, whichever control
+// tag produced it
+func (v LSValue) LabelNames() []string {
+	for _, names := range [][]string{v.RectangleLabels, v.PolygonLabels, v.BrushLabels, v.EllipseLabels, v.PolylineLabels, v.Labels} {
+		if len(names) > 0 {
+			return names
+		}
+	}
+	return nil
+}
+
+// LoadLabelStudioTasks reads a Label Studio JSON export
+func LoadLabelStudioTasks(tasksPath string) ([]LSTask, error) {
+	data, err := os.ReadFile(tasksPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Label Studio export: %w", err)
+	}
+
+	var tasks []LSTask
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to parse Label Studio export %s: %w", tasksPath, err)
+	}
+	return tasks, nil
+}
+
+// findTasksFile returns the configured JSON export, or the only .json file in
+// the source directory besides notes.json and project.json
+func (c *Converter) findTasksFile() (string, error) {
+	if c.config.TasksFile != "" {
+		return c.config.TasksFile, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(c.config.SourceDir, "*.json"))
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	for _, match := range matches {
+		if name := filepath.Base(match); name != "notes.json" && name != "project.json" {
+			candidates = append(candidates, match)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no Label Studio JSON export found in %s (use -tasks)", c.config.SourceDir)
+	case 1:
+		return candidates[0], nil
+	}
+	return "", fmt.Errorf("multiple JSON files found in %s, choose one with -tasks", c.config.SourceDir)
+}
+
+// taskImage returns the image reference of a task: data.image when present,
+// otherwise the first data value that looks like an image
+func taskImage(task LSTask) string {
+	if image, ok := task.Data["image"].(string); ok {
+		return image
+	}
+
+	keys := make([]string, 0, len(task.Data))
+	for key := range task.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if value, ok := task.Data[key].(string); ok && imageExtensions[strings.ToLower(path.Ext(stripQuery(value)))] {
+			return value
+		}
+	}
+	return ""
+}
+
+// stripQuery removes a URL query string, leaving plain paths untouched
+func stripQuery(ref string) string {
+	if i := strings.IndexByte(ref, '?'); i >= 0 {
+		return ref[:i]
+	}
+	return ref
+}
+
+// imageFileName maps an image reference such as /data/upload/1/abc-photo.jpg,
+// /data/local-files/?d=dir/photo.jpg or an URL to the file name it is stored
+// under in the export
+func imageFileName(ref string) string {
+	if u, err := url.Parse(ref); err == nil {
+		if d := u.Query().Get("d"); d != "" {
+			return path.Base(d)
+		}
+	}
+	ref = stripQuery(ref)
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	return path.Base(ref)
+}
+
+// loadTasks finds and reads the Label Studio JSON export
+func (c *Converter) loadTasks() ([]LSTask, error) {
+	if err := c.validateConsensus(); err != nil {
+		return nil, err
+	}
+	if err := c.validatePredictions(); err != nil {
+		return nil, err
+	}
+	c.consensusStats = &consensusStats{}
+	c.predictionStats = &predictionStats{}
+	filter, err := c.newAnnotationFilter()
+	if err != nil {
+		return nil, err
+	}
+	c.filter = filter
+
+	tasksPath, err := c.findTasksFile()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := LoadLabelStudioTasks(tasksPath)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(c.out, "Loaded %d tasks from %s\n", len(tasks), tasksPath)
+	return tasks, nil
+}
+
+// resolveTaskImage returns the path of a task's image in the source's images/
+// directory, warning when it can't be found
+func (c *Converter) resolveTaskImage(task LSTask) (string, bool) {
+	ref := taskImage(task)
+	if ref == "" {
+		fmt.Fprintf(c.out, "Warning: Task %d has no image\n", task.ID)
+		return "", false
+	}
+
+	imageName := imageFileName(ref)
+	imagePath := filepath.Join(c.config.SourceDir, "images", imageName)
+	if _, err := os.Stat(imagePath); err != nil {
+		fmt.Fprintf(c.out, "Warning: Image %s for task %d not found\n", imageName, task.ID)
+		return "", false
+	}
+	return imagePath, true
+}
+
+// loadLabelStudioJSON converts the tasks of a Label Studio JSON export into
+// YOLO label files in a staging directory and pairs them with the images in
+// the source's images/ directory, or with frames of the task's video
+func (c *Converter) loadLabelStudioJSON() ([]string, []LabelPair, error) {
+	tasks, err := c.loadTasks()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// classes.txt is optional here; when present it pins the class IDs,
+	// otherwise the labeling config does
+	var classes []string
+	pinnedBy := "classes.txt"
+	if _, err := os.Stat(filepath.Join(c.config.SourceDir, "classes.txt")); err == nil {
+		if classes, err = c.LoadClasses(); err != nil {
+			return nil, nil, err
+		}
+		c.checkLabelConfig(classes)
+	} else if c.labelConfig != nil {
+		for _, label := range c.labelConfig {
+			classes = append(classes, label.Name)
+		}
+		pinnedBy = "the labeling config"
+	}
+
+	stagingDir, err := c.stagingDirectory()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	builder := newLabelBuilder(classes, c.config.MaskTolerance, c.skeleton)
+	builder.obb = c.config.OBB
+	if builder.shapeModes, err = parseShapeModes(c.config.ShapeModes); err != nil {
+		return nil, nil, err
+	}
+	var pairs []LabelPair
+
+	for _, task := range tasks {
+		results, ok := c.taskResults(task)
+		if !ok {
+			continue
+		}
+
+		if taskVideo(task) != "" {
+			videoPairs, err := c.videoPairs(builder, task, results, stagingDir)
+			if err != nil {
+				return nil, nil, fmt.Errorf("task %d: %w", task.ID, err)
+			}
+			for _, pair := range videoPairs {
+				c.recordTask(filepath.Base(pair.ImagePath), task)
+			}
+			pairs = append(pairs, videoPairs...)
+			continue
+		}
+
+		imagePath, ok := c.resolveTaskImage(task)
+		if !ok {
+			continue
+		}
+		imageName := filepath.Base(imagePath)
+
+		lines := builder.taskLines(c, task.ID, results)
+
+		labelPath := filepath.Join(stagingDir, strings.TrimSuffix(imageName, filepath.Ext(imageName))+".txt")
+		if err := os.WriteFile(labelPath, []byte(strings.Join(lines, "")), 0644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write label %s: %w", labelPath, err)
+		}
+		pairs = append(pairs, LabelPair{ImagePath: imagePath, LabelPath: labelPath})
+		c.recordTask(imageName, task)
+	}
+
+	c.filter.report(c)
+	c.consensusStats.report(c)
+	c.predictionStats.report(c)
+	for resultType, count := range builder.unsupported {
+		fmt.Fprintf(c.out, "Warning: Skipped %d unsupported %s results\n", count, resultType)
+	}
+
+	if classes != nil && len(builder.classes) > len(classes) {
+		fmt.Fprintf(c.out, "Warning: Labels not in %s were appended: %v\n", pinnedBy, builder.classes[len(classes):])
+	}
+	fmt.Fprintf(c.out, "Found %d classes: %v\n", len(builder.classes), builder.classes)
+	fmt.Fprintf(c.out, "Found %d image-label pairs\n", len(pairs))
+	return builder.classes, pairs, nil
+}
+
+// labelBuilder turns Label Studio results into YOLO label lines, assigning
+// class IDs as new label names are seen
+type labelBuilder struct {
+	classes       []string
+	classIDs      map[string]int
+	maskTolerance float64
+	skeleton      *Skeleton
+	obb           bool
+	shapeModes    map[string]string
+	unsupported   map[string]int
+}
+
+func newLabelBuilder(classes []string, maskTolerance float64, skeleton *Skeleton) *labelBuilder {
+	b := &labelBuilder{
+		classIDs:      make(map[string]int),
+		maskTolerance: maskTolerance,
+		skeleton:      skeleton,
+		unsupported:   make(map[string]int),
+	}
+	for _, name := range classes {
+		b.classID(name)
+	}
+	return b
+}
+
+func (b *labelBuilder) classID(name string) int {
+	if id, ok := b.classIDs[name]; ok {
+		return id
+	}
+	id := len(b.classes)
+	b.classes = append(b.classes, name)
+	b.classIDs[name] = id
+	return id
+}
+
+// taskLines converts the results of one annotation into label lines
+func (b *labelBuilder) taskLines(c *Converter, taskID int, results []LSResult) []string {
+	if b.skeleton != nil {
+		return b.poseLines(c, taskID, results)
+	}
+
+	var lines []string
+	for _, result := range results {
+		names := result.Value.LabelNames()
+		if len(names) == 0 {
+			continue
+		}
+		// Classes are assigned only to results that become label lines, so
+		// a label seen only on skipped results doesn't become a class
+		name := names[0]
+
+		if b.obb && result.Type != "rectanglelabels" {
+			b.unsupported[result.Type]++
+			continue
+		}
+
+		switch result.Type {
+		case "rectanglelabels":
+			if b.obb {
+				coords, ok := obbCoords(result)
+				if !ok {
+					fmt.Fprintf(c.out, "Warning: Skipping oriented box in task %d: it extends outside the image\n", taskID)
+					c.skipped++
+					continue
+				}
+				lines = append(lines, polygonLine(b.classID(name), coords))
+			} else {
+				lines = append(lines, boxLine(b.classID(name), rectangleBounds(result)))
+			}
+
+		case "polygonlabels":
+			var coords []float64
+			for _, p := range result.Value.Points {
+				if len(p) >= 2 {
+					coords = append(coords, clamp01(p[0]/100), clamp01(p[1]/100))
+				}
+			}
+			if len(coords) >= 6 {
+				lines = append(lines, polygonLine(b.classID(name), coords))
+			}
+
+		case "ellipselabels", "polylinelabels":
+			mode := b.shapeModes[result.Type]
+			if mode == ShapeSkip {
+				b.unsupported[result.Type]++
+				continue
+			}
+			coords, reason := shapeCoords(result, mode)
+			if coords == nil {
+				fmt.Fprintf(c.out, "Warning: Skipping %s in task %d: %s\n", result.Type, taskID, reason)
+				c.skipped++
+				continue
+			}
+			lines = append(lines, polygonLine(b.classID(name), coords))
+
+		case "brushlabels":
+			polygons, err := brushPolygons(result, b.maskTolerance)
+			if err != nil {
+				fmt.Fprintf(c.out, "Warning: Cannot decode brush mask in task %d: %v\n", taskID, err)
+				c.skipped++
+				continue
+			}
+			for _, coords := range polygons {
+				lines = append(lines, polygonLine(b.classID(name), coords))
+			}
+
+		default:
+			b.unsupported[result.Type]++
+		}
+	}
+	return lines
+}
+
+// rectangleBounds returns the normalized axis-aligned bounds of a rectangle
+// result as x_center, y_center, width, height. Rotated rectangles are
+// replaced by the box enclosing them; -obb keeps the rotation instead.
+func rectangleBounds(result LSResult) [4]float64 {
+	v := result.Value
+	x, y, w, h := v.X/100, v.Y/100, v.Width/100, v.Height/100
+
+	if v.Rotation != 0 {
+		corners := rotatedCorners(result)
+		minX, minY := math.Inf(1), math.Inf(1)
+		maxX, maxY := math.Inf(-1), math.Inf(-1)
+		for _, p := range corners {
+			minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+			minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+		}
+		x, y, w, h = minX, minY, maxX-minX, maxY-minY
+	}
+
+	return clippedBox(x, y, x+w, y+h)
+}
+
+// rotatedCorners returns the normalized corners of a rectangle result rotated
+// clockwise around its top-left corner. The rotation happens in pixel space,
+// so the original image size is needed when the image isn't square.
+func rotatedCorners(result LSResult) [4][2]float64 {
+	v := result.Value
+	iw, ih := float64(result.OriginalWidth), float64(result.OriginalHeight)
+	if iw <= 0 || ih <= 0 {
+		iw, ih = 100, 100
+	}
+
+	x, y := v.X/100*iw, v.Y/100*ih
+	w, h := v.Width/100*iw, v.Height/100*ih
+	sin, cos := math.Sincos(v.Rotation * math.Pi / 180)
+
+	offsets := [4][2]float64{{0, 0}, {w, 0}, {w, h}, {0, h}}
+	var corners [4][2]float64
+	for i, o := range offsets {
+		corners[i] = [2]float64{
+			(x + o[0]*cos - o[1]*sin) / iw,
+			(y + o[0]*sin + o[1]*cos) / ih,
+		}
+	}
+	return corners
+}
+
+// brushPolygons converts a brush result into normalized polygons
+func brushPolygons(result LSResult, tolerance float64) ([][]float64, error) {
+	if result.Value.Format != "rle" {
+		return nil, fmt.Errorf("unsupported brush format %q", result.Value.Format)
+	}
+
+	width, height := result.OriginalWidth, result.OriginalHeight
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("brush result is missing the original image size")
+	}
+
+	rle := make([]byte, len(result.Value.RLE))
+	for i, v := range result.Value.RLE {
+		rle[i] = byte(v)
+	}
+
+	mask, err := brushMask(rle, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	var polygons [][]float64
+	for _, ring := range maskPolygons(mask, width, height, tolerance) {
+		coords := make([]float64, 0, len(ring)*2)
+		for _, p := range ring {
+			coords = append(coords, clamp01(p[0]/float64(width)), clamp01(p[1]/float64(height)))
+		}
+		polygons = append(polygons, coords)
+	}
+	return polygons, nil
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// formatCoord formats a normalized coordinate for a label file
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+// boxLine formats a YOLO detection label line
+func boxLine(classID int, box [4]float64) string {
+	return polygonLine(classID, box[:])
+}
+
+// polygonLine formats a label line of a class followed by coordinates
+func polygonLine(classID int, coords []float64) string {
+	var sb strings.Builder
+	sb.WriteString(strconv.Itoa(classID))
+	for _, v := range coords {
+		sb.WriteByte(' ')
+		sb.WriteString(formatCoord(v))
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createLabelStudioExport writes a Label Studio JSON export with a rectangle,
+// a polygon and a brush mask task next to an images/ directory
+func createLabelStudioExport(t *testing.T, baseDir string) {
+	if err := os.MkdirAll(filepath.Join(baseDir, "images"), 0755); err != nil {
+		t.Fatalf("Failed to create images directory: %v", err)
+	}
+	for _, name := range []string{"a1b2-cat.jpg", "dog photo.png", "mask.png"} {
+		if err := os.WriteFile(filepath.Join(baseDir, "images", name), []byte("fake image data"), 0644); err != nil {
+			t.Fatalf("Failed to create image: %v", err)
+		}
+	}
+
+	mask, width, height := parseMask(
+		"..........",
+		"..####....",
+		"..####....",
+		"..####....",
+		"..........",
+	)
+
+	tasks := []LSTask{
+		{
+			ID:   1,
+			Data: map[string]interface{}{"image": "/data/upload/1/a1b2-cat.jpg"},
+			Annotations: []LSAnnotation{{Result: []LSResult{{
+				Type:  "rectanglelabels",
+				Value: LSValue{X: 10, Y: 20, Width: 30, Height: 40, RectangleLabels: []string{"cat"}},
+			}}}},
+		},
+		{
+			ID:   2,
+			Data: map[string]interface{}{"image": "/data/local-files/?d=photos/dog%20photo.png"},
+			Annotations: []LSAnnotation{{Result: []LSResult{
+				{
+					Type:  "polygonlabels",
+					Value: LSValue{Points: [][]float64{{10, 10}, {50, 10}, {30, 60}}, PolygonLabels: []string{"dog"}},
+				},
+				{
+					Type:  "choices",
+					Value: LSValue{},
+				},
+				{
+					Type:  "keypointlabels",
+					Value: LSValue{X: 5, Y: 5, Labels: []string{"dog"}},
+				},
+			}}},
+		},
+		{
+			ID:   3,
+			Data: map[string]interface{}{"image": "mask.png"},
+			Annotations: []LSAnnotation{{Result: []LSResult{{
+				Type:           "brushlabels",
+				OriginalWidth:  width,
+				OriginalHeight: height,
+				Value:          LSValue{Format: "rle", RLE: maskRLE(mask), BrushLabels: []string{"cat"}},
+			}}}},
+		},
+		{
+			ID:   4,
+			Data: map[string]interface{}{"image": "missing.jpg"},
+		},
+	}
+
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		t.Fatalf("Failed to encode tasks: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "export.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+}
+
+func TestLabelStudioJSONConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	createLabelStudioExport(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputLSJSON})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	expected := map[string]string{
+		"a1b2-cat.txt":  "0 0.250000 0.400000 0.300000 0.400000\n",
+		"dog photo.txt": "1 0.100000 0.100000 0.500000 0.100000 0.300000 0.600000\n",
+		"mask.txt":      "0 0.200000 0.200000 0.600000 0.200000 0.600000 0.800000 0.200000 0.800000\n",
+	}
+	for name, want := range expected {
+		got, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", name))
+		if err != nil {
+			t.Errorf("Missing label %s: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("Label %s: expected %q, got %q", name, want, got)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read data.yaml: %v", err)
+	}
+	for _, want := range []string{"task: segment", "- cat", "- dog"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected data.yaml to contain %q, got:\n%s", want, content)
+		}
+	}
+
+	log := out.String()
+	for _, want := range []string{"Image missing.jpg for task 4 not found", "Skipped 1 unsupported keypointlabels results"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, log)
+		}
+	}
+	if converter.staging != "" {
+		t.Error("Expected staging directory to be removed after conversion")
+	}
+}
+
+func TestLabelStudioJSONUsesClassesFile(t *testing.T) {
+	sourceDir := t.TempDir()
+	createLabelStudioExport(t, sourceDir)
+	if err := os.WriteFile(filepath.Join(sourceDir, "classes.txt"), []byte("dog\ncat\n"), 0644); err != nil {
+		t.Fatalf("Failed to write classes.txt: %v", err)
+	}
+
+	converter := NewConverter(Config{SourceDir: sourceDir, InputFormat: InputLSJSON})
+	converter.SetOutput(&strings.Builder{})
+	defer converter.removeStaging()
+
+	classes, pairs, err := converter.loadSource()
+	if err != nil {
+		t.Fatalf("Failed to load export: %v", err)
+	}
+	if strings.Join(classes, ",") != "dog,cat" {
+		t.Errorf("Expected classes.txt order, got %v", classes)
+	}
+	if len(pairs) != 3 {
+		t.Errorf("Expected 3 pairs, got %d", len(pairs))
+	}
+}
+
+func TestRotatedRectangleBounds(t *testing.T) {
+	// A 20x20 square rotated 90 degrees clockwise around its top-left corner
+	result := LSResult{
+		OriginalWidth:  100,
+		OriginalHeight: 100,
+		Value:          LSValue{X: 50, Y: 10, Width: 20, Height: 20, Rotation: 90},
+	}
+
+	got := rectangleBounds(result)
+	want := [4]float64{0.4, 0.2, 0.2, 0.2}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("Expected bounds %v, got %v", want, got)
+		}
+	}
+}
+
+func TestFindTasksFile(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+
+	converter := NewConverter(Config{SourceDir: sourceDir})
+	if _, err := converter.findTasksFile(); err == nil {
+		t.Error("Expected error when only notes.json is present")
+	}
+
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte("[]"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if _, err := converter.findTasksFile(); err == nil {
+		t.Error("Expected error for ambiguous JSON files")
+	}
+
+	converter.config.TasksFile = filepath.Join(sourceDir, "b.json")
+	if path, err := converter.findTasksFile(); err != nil || path != converter.config.TasksFile {
+		t.Errorf("Expected -tasks to be used, got %q, %v", path, err)
+	}
+}
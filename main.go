@@ -2,15 +2,22 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"io"
-	"math/rand"
+	"math"
+	"net/http"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
 	"runtime"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -29,30 +36,326 @@ type Config struct {
 	OutputDir  string
 	TrainSplit float64
 	Seed       int64
+
+	// Incremental updates an existing output directory in place: images keep
+	// their split, unchanged files are skipped and removed pairs are pruned
+	Incremental bool
+
+	// InputFormat selects the export type read from SourceDir: a YOLO export
+	// (the default), another tool's export with images alongside, or
+	// InputAuto to detect it from the source
+	InputFormat string
+	// TasksFile is the Label Studio JSON export, found in SourceDir when empty
+	TasksFile string
+	// MaskTolerance is the polygon simplification tolerance, in pixels, for
+	// brush masks
+	MaskTolerance float64
+	// SkeletonFile defines the keypoints of a pose dataset; setting it
+	// switches the conversion to YOLO-pose labels
+	SkeletonFile string
+	// OBB writes rectangles as oriented boxes (four corners) instead of
+	// axis-aligned boxes
+	OBB bool
+	// Classify builds a folder-per-class classification dataset from the
+	// choice labels of a Label Studio JSON export
+	Classify bool
+	// MultiLabel writes classification labels as per-image label vectors in
+	// CSV manifests, ordered by LabelOrder (comma-separated) when set
+	MultiLabel bool
+	LabelOrder string
+	// Crop cuts each annotation out of its image into a folder-per-class
+	// classification dataset, padded by CropPadding times the box size and
+	// skipping crops smaller than CropMinSize pixels
+	Crop        bool
+	CropPadding float64
+	CropMinSize int
+	// ShapeModes converts ellipses and polylines to boxes or polygons, e.g.
+	// "ellipse=polygon,polyline=box"
+	ShapeModes string
+	// FramesDir holds pre-extracted video frames, one directory per video
+	// named after it; without it frames are extracted with ffmpeg
+	FramesDir string
+	// FrameStep keeps every FrameStep-th frame of a video
+	FrameStep int
+	// Consensus merges tasks with several annotations: first, latest,
+	// majority or union, matching regions by ConsensusIoU
+	Consensus    string
+	ConsensusIoU float64
+	// ReviewStatus keeps annotations by review: any, not-rejected or
+	// accepted. Cancelled annotations are dropped unless IncludeCancelled.
+	ReviewStatus     string
+	IncludeCancelled bool
+	// Annotators and ExcludeAnnotators are comma-separated annotator IDs or
+	// emails whose annotations are kept or dropped
+	Annotators        string
+	ExcludeAnnotators string
+	// UsePredictions labels tasks without annotations from their best
+	// prediction, keeping regions scored at least MinScore
+	UsePredictions bool
+	MinScore       float64
+	// Parallel is the number of label files validated and source
+	// directories scanned at once; 0 uses every CPU
+	Parallel int
+	// ReportFile receives every validation finding as JSON, or NDJSON for
+	// .ndjson and .jsonl files
+	ReportFile string
+	// HTMLReportFile receives thumbnails of the HTMLReportTop worst label
+	// files with their regions drawn
+	HTMLReportFile string
+	HTMLReportTop  int
+	// Plausibility checks on pixel sizes, read from the image headers; zero
+	// disables a check
+	MinBoxPixels   int
+	MinImageSize   int
+	MaxAspectRatio float64
+	// NormalizePixels divides label coordinates above 1, taken to be pixel
+	// values, by the image size
+	NormalizePixels bool
+	// DuplicateIoU is the IoU at which two regions of the same class in a
+	// file count as duplicates (0 disables); Dedupe drops the later ones
+	DuplicateIoU float64
+	Dedupe       bool
+	// DegenerateEpsilon is the normalized width or height at or below which
+	// a region counts as zero-area; DropDegenerate drops such regions and
+	// regions outside their image
+	DegenerateEpsilon float64
+	DropDegenerate    bool
+	// PruneEmptyClasses removes classes without annotations from data.yaml,
+	// renumbering the class IDs of the labels
+	PruneEmptyClasses bool
+	// Sample converts a random fraction of the pairs, at most MaxImages;
+	// zero disables either limit
+	Sample    float64
+	MaxImages int
+	// SplitUnit is what TrainSplit is a fraction of: images or annotations
+	SplitUnit string
+	// SplitStrategy is random, or sequential to take the last pairs in
+	// SplitOrder (name or mtime) as validation
+	SplitStrategy string
+	SplitOrder    string
+	// SplitFile is a CSV of image, split rows: listed images keep their
+	// split, new ones are assigned and the file is updated
+	SplitFile string
+	// MinValInstances moves images from training to validation until every
+	// class has this many validation instances
+	MinValInstances int
+	// MaxPerClass and MinPerClass balance the training split: images only
+	// adding to classes with MaxPerClass instances are dropped and images of
+	// classes below MinPerClass instances are duplicated
+	MaxPerClass int
+	MinPerClass int
+	// Include and Exclude are comma-separated glob patterns selecting source
+	// images by their path below images/; "**" matches any directories
+	Include string
+	Exclude string
+	// ImageExtensions adds comma-separated file extensions, such as .gif,
+	// to the image types picked up from the source
+	ImageExtensions string
+	// Transcode converts images in formats trainers can't read, such as HEIC
+	// and AVIF, to JPEG
+	Transcode bool
+	// ApplyEXIFOrientation rewrites images with an EXIF orientation upright,
+	// matching the frame their labels were drawn in
+	ApplyEXIFOrientation bool
+	// StripMetadata removes EXIF, XMP and text metadata such as GPS
+	// positions and device serials from copied images
+	StripMetadata bool
+	// TileSize slices larger images into square tiles of this many pixels,
+	// overlapping by TileOverlap (a fraction of the tile size). Tiles without
+	// labels are dropped except for a TileBackground fraction.
+	TileSize       int
+	TileOverlap    float64
+	TileBackground float64
+	// Augment adds offline augmented copies of the training images: a
+	// comma-separated list of hflip, vflip, rot90, rot180, rot270 and
+	// brightness. AugmentMultiplier is the number of copies per image.
+	Augment           string
+	AugmentMultiplier int
+	// Anonymize is a comma-separated list of classes, such as faces and
+	// license plates, whose regions are blurred or pixelated (AnonymizeMode)
+	// in the output images
+	Anonymize     string
+	AnonymizeMode string
+	// Checksums writes a checksums.sha256 manifest of every output file
+	Checksums bool
+	// PreserveAttrs keeps the permissions, modification times and extended
+	// attributes of copied files
+	PreserveAttrs bool
+	// Retries is the number of times a file operation on a directory output
+	// is retried after a transient error such as EIO or ESTALE, waiting
+	// RetryBackoff before the first retry and twice as long before each next
+	Retries      int
+	RetryBackoff time.Duration
+	// Move moves the source images and labels into the output instead of
+	// copying them, for exports that are discarded after conversion
+	Move bool
+	// Stream converts in a pipeline that holds only the pairs in flight,
+	// assigning splits by a hash of the image name; options that need every
+	// pair at once are unavailable
+	Stream bool
+	// Resume continues an interrupted conversion into the same directory
+	// output, skipping the files its checkpoint lists as completed
+	Resume bool
+	// ForceUnlock removes the lock of another conversion from the output,
+	// for locks left behind on other hosts
+	ForceUnlock bool
+	// AllowOrphanLabels converts despite label files that have no image
+	AllowOrphanLabels bool
+	// Strict fails the conversion when validation finds any problem in the
+	// labels, before the output is written
+	Strict bool
+	// OutputFormat selects the dataset written to OutputDir: YOLO (the
+	// default) or TFRecord files for the TF Object Detection API
+	OutputFormat string
+	// TFRecordShards is the number of TFRecord files per split; 0 sizes
+	// them by the number of images
+	TFRecordShards int
+	// FiftyOne writes a script that loads the dataset into FiftyOne
+	FiftyOne bool
+	// AnnotationsTable is a CSV or Parquet file to list every annotation in,
+	// one row each
+	AnnotationsTable string
+	// Catalog writes dataset.db, an SQLite catalog of the images, splits
+	// and annotations
+	Catalog bool
+	// OpenImagesClasses are the comma-separated display names of the Open
+	// Images classes to convert
+	OpenImagesClasses string
+	// VIAAttribute is the VIA region attribute that names the class
+	VIAAttribute string
+	// LabelConfig is the Label Studio labeling config (XML, or a project
+	// JSON with label_config) giving class names and colors, found in
+	// SourceDir when empty
+	LabelConfig string
+	// Aliases is a YAML file mapping canonical class names to the synonym
+	// labels folded into them
+	Aliases string
+	// Hierarchy is a YAML file of parent classes and their children
+	Hierarchy string
+	// Granularity is GranularityLeaf, GranularityParent or GranularityBoth
+	Granularity string
+	// ConfidenceColumn accepts label lines with a trailing confidence value
+	// and strips it
+	ConfidenceColumn bool
+	// MinConf drops label lines whose confidence is below it; it implies
+	// ConfidenceColumn
+	MinConf float64
+	// Precision is the number of decimal places of coordinates in label
+	// files the converter generates or rewrites; 0 keeps six
+	Precision int
+	// CanonicalLabels sorts the lines of output label files and drops exact
+	// duplicates, so re-conversions are byte-identical
+	CanonicalLabels bool
+	// Provenance writes provenance.json, recording the converter build, this
+	// configuration, source checksums, timestamps and counts
+	Provenance bool
+	// DatasetVersion is the version of the dataset, or major, minor or patch
+	// to bump the version of the dataset already in the output; it is
+	// recorded in data.yaml with a changelog entry
+	DatasetVersion string
+	// MLflowURI is the MLflow tracking server the dataset is logged to, in
+	// run MLflowRunID or a new run of MLflowExperiment; MLflowLogDataset
+	// also uploads the dataset archive
+	MLflowURI        string
+	MLflowExperiment string
+	MLflowRunID      string
+	MLflowLogDataset bool
+	// ClearMLProject registers the output as a version of the ClearML
+	// Dataset ClearMLDataset in this project, tagged with the
+	// comma-separated ClearMLTags, by running the ClearML SDK with
+	// ClearMLPython
+	ClearMLProject string
+	ClearMLDataset string
+	ClearMLTags    string
+	ClearMLPython  string
+	// NotifyURL receives a summary of every finished conversion, successful
+	// or not: a message for Slack webhooks, JSON for other URLs
+	NotifyURL string
+	// Gates is a YAML file of quality gates the validated dataset must pass
+	// before it is written
+	Gates string
+	// ClassWeights is the formula of the class weights written next to
+	// data.yaml, in ClassWeightsFormat (json or yaml); empty writes none
+	ClassWeights       string
+	ClassWeightsFormat string
+	// Leakage checks the split for validation images that duplicate
+	// training images: report lists them, fix moves them to training.
+	// LeakageDistance is how many bits perceptual hashes may differ in.
+	Leakage         string
+	LeakageDistance int
+	// ImageQuality screens the split for blurry and badly exposed images:
+	// flag reports them, exclude drops them. Images whose variance of the
+	// Laplacian is below BlurThreshold are blurry; those with more than
+	// ExposureClip of their pixels near black or white are badly exposed.
+	ImageQuality  string
+	BlurThreshold float64
+	ExposureClip  float64
+	// ImageMetadata is a CSV or JSON sidecar of per-image attributes. Where
+	// keeps the images matching its conditions; SplitGroupBy keeps the
+	// images sharing an attribute value in one split, and SplitStratifyBy
+	// splits the images of every value by TrainSplit.
+	ImageMetadata   string
+	Where           string
+	SplitGroupBy    string
+	SplitStratifyBy string
+	// TaskMetadata writes the Label Studio task of every image of an LS JSON
+	// export: sidecar per image, or file for one tasks.json
+	TaskMetadata string
+	// Mapping writes mapping.csv, linking every output image to its Label
+	// Studio task ID, original file and split
+	Mapping bool
+	// Since and Until keep the annotations last updated in a window, Since
+	// inclusive and Until exclusive, given as dates or RFC 3339 times
+	Since string
+	Until string
 }
 
 // LabelPair represents an image-label file pair
 type LabelPair struct {
 	ImagePath string
 	LabelPath string
+
+	// Group keeps related pairs, such as the frames of one video, in the
+	// same split; empty means the pair stands alone
+	Group string
 }
 
 // ValidationStats holds statistics about label validation
 type ValidationStats struct {
-	TotalFiles           int `json:"total_files"`
-	TotalAnnotations     int `json:"total_annotations"`
-	FilesWithAnnotations int `json:"files_with_annotations"`
-	EmptyFiles           int `json:"empty_files"`
-	InvalidLines         int `json:"invalid_lines"`
+	TotalFiles            int `json:"total_files"`
+	TotalAnnotations      int `json:"total_annotations"`
+	FilesWithAnnotations  int `json:"files_with_annotations"`
+	EmptyFiles            int `json:"empty_files"`
+	InvalidLines          int `json:"invalid_lines"`
+	BoxAnnotations        int `json:"box_annotations"`
+	SegmentAnnotations    int `json:"segment_annotations"`
+	PoseAnnotations       int `json:"pose_annotations"`
+	OBBAnnotations        int `json:"obb_annotations"`
+	PlausibilityWarnings  int `json:"plausibility_warnings"`
+	DuplicateAnnotations  int `json:"duplicate_annotations"`
+	DegenerateAnnotations int `json:"degenerate_annotations"`
+
+	// ClassCounts counts the valid annotations of each class ID
+	ClassCounts map[int]int `json:"class_counts,omitempty"`
 }
 
 // YAMLConfig represents the YOLO dataset configuration
 type YAMLConfig struct {
-	Path  string   `yaml:"path"`
+	Path  string   `yaml:"path,omitempty"`
 	Train string   `yaml:"train"`
 	Val   string   `yaml:"val"`
+	Task  string   `yaml:"task,omitempty"`
 	NC    int      `yaml:"nc"`
 	Names []string `yaml:"names"`
+
+	KptShape []int `yaml:"kpt_shape,omitempty,flow"`
+	FlipIdx  []int `yaml:"flip_idx,omitempty,flow"`
+
+	// Colors are the classes' colors from the labeling config, for preview
+	Colors []string `yaml:"colors,omitempty"`
+
+	// Version is the dataset version given by -dataset-version
+	Version string `yaml:"version,omitempty"`
 }
 
 // NotesInfo represents the structure of notes.json from Label Studio
@@ -71,11 +374,99 @@ type NotesInfo struct {
 // Converter handles the Label Studio to YOLO conversion
 type Converter struct {
 	config Config
+	writer DatasetWriter
+	out    io.Writer
+	task   string
+	// ctx stops the conversion between files when it is done
+	ctx context.Context
+
+	// mover is the writer that removes moved source files with -move
+	mover *moveWriter
+	// retrier retries transient file errors and counts them for the report
+	retrier *retryWriter
+
+	// skeleton is loaded from SkeletonFile for pose conversions
+	skeleton *Skeleton
+	// labelConfig holds the labels of the project's labeling config, when
+	// the source has one
+	labelConfig []LabelConfigClass
+
+	// consensusStats measures annotator disagreement in ls-json input
+	consensusStats *consensusStats
+	// filter drops cancelled, rejected or unwanted annotations
+	filter *annotationFilter
+	// predictionStats counts tasks pseudo-labeled from predictions
+	predictionStats *predictionStats
+	// findings lists the problems found by the last ValidateLabels
+	findings []ValidationFinding
+	// skipped counts the source files and annotations the last conversion
+	// left out of the dataset
+	skipped int
+	// filteredLabels names the label files of images skipped by -include
+	// and -exclude, which are not orphans
+	filteredLabels map[string]bool
+
+	// started is when the last conversion started
+	started time.Time
+	// sourceSums caches the source checksums of the last conversion for
+	// -provenance
+	sourceSums map[string]string
+	// datasetVersion is the version of the dataset being written with
+	// -dataset-version
+	datasetVersion datasetVersion
+	// dataYAML is the last data.yaml written
+	dataYAML []byte
+	// counts counts the dataset of the last conversion for -notify-url
+	counts ProvenanceCounts
+	// schedule also triggers conversions in watch mode
+	schedule *Schedule
+	// gates are the quality gates loaded from -gates
+	gates *QualityGates
+	// datasetStats are the split statistics commented into data.yaml
+	datasetStats *DatasetStats
+	// dedup drops near-duplicate images for the dedup command
+	dedup *DedupOptions
+	// metadata holds the -image-metadata attributes by image name, and by
+	// name without extension in metadataStems; where is the parsed -where
+	metadata      imageMetadata
+	metadataStems imageMetadata
+	where         []metadataCondition
+	// taskMetadata holds the Label Studio task of every converted image by
+	// file name, and taskStems by name without extension
+	taskMetadata map[string]TaskMetadata
+	taskStems    map[string]TaskMetadata
+	// originals holds the original file of every image for mapping.csv by
+	// file name, and originalStems by name without extension
+	originals     map[string]string
+	originalStems map[string]string
+
+	// staging holds label files generated from non-YOLO inputs
+	staging string
 }
 
 // NewConverter creates a new converter instance
 func NewConverter(config Config) *Converter {
-	return &Converter{config: config}
+	return &Converter{config: config, out: os.Stdout, task: TaskDetect}
+}
+
+// SetOutput sets the destination for progress messages (default os.Stdout)
+func (c *Converter) SetOutput(w io.Writer) {
+	c.out = w
+}
+
+// SetSchedule makes watch mode also convert whenever the schedule fires, even
+// when the source hasn't changed
+func (c *Converter) SetSchedule(schedule *Schedule) {
+	c.schedule = schedule
+}
+
+// output returns the writer the dataset is written to, defaulting to the
+// output directory when Convert has not opened one
+func (c *Converter) output() DatasetWriter {
+	if c.writer == nil {
+		c.writer = &DirWriter{Root: c.config.OutputDir}
+	}
+	return c.writer
 }
 
 // ValidateSourceStructure checks if the source directory has the expected structure
@@ -91,13 +482,13 @@ func (c *Converter) ValidateSourceStructure() error {
 
 	for _, dir := range requiredDirs {
 		if _, err := os.Stat(dir); os.IsNotExist(err) {
-			return fmt.Errorf("required directory not found: %s", dir)
+			return fmt.Errorf("%w: required directory not found: %s", ErrInvalidSource, dir)
 		}
 	}
 
 	for _, file := range requiredFiles {
 		if _, err := os.Stat(file); os.IsNotExist(err) {
-			return fmt.Errorf("required file not found: %s", file)
+			return fmt.Errorf("%w: required file not found: %s", ErrInvalidSource, file)
 		}
 	}
 
@@ -106,308 +497,982 @@ func (c *Converter) ValidateSourceStructure() error {
 
 // LoadClasses loads class names from classes.txt
 func (c *Converter) LoadClasses() ([]string, error) {
-	classesPath := filepath.Join(c.config.SourceDir, "classes.txt")
-	file, err := os.Open(classesPath)
+	classes, err := readClassesFile(filepath.Join(c.config.SourceDir, "classes.txt"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to open classes.txt: %w", err)
-	}
-	defer file.Close()
-
-	var classes []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			classes = append(classes, line)
-		}
+		return nil, fmt.Errorf("failed to read classes.txt: %w", err)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading classes.txt: %w", err)
-	}
-
-	fmt.Printf("Found %d classes: %v\n", len(classes), classes)
+	fmt.Fprintf(c.out, "Found %d classes: %v\n", len(classes), classes)
 	return classes, nil
 }
 
-// GetImageLabelPairs finds matching image and label file pairs
-func (c *Converter) GetImageLabelPairs() ([]LabelPair, error) {
-	imagesDir := filepath.Join(c.config.SourceDir, "images")
-	labelsDir := filepath.Join(c.config.SourceDir, "labels")
+// imageExtensions are the image file types picked up from exports, in
+// lower case
+var imageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".bmp":  true,
+	".tiff": true,
+	".webp": true,
+}
 
-	imageExtensions := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".bmp":  true,
-		".tiff": true,
-		".webp": true,
+// isImageFile reports whether a file name has one of imageExtensions or the
+// -image-ext extensions, ignoring case
+func (c *Converter) isImageFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	if imageExtensions[ext] {
+		return true
 	}
-
-	var pairs []LabelPair
-
-	err := filepath.Walk(imagesDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	for _, extra := range splitList(c.config.ImageExtensions) {
+		if ext == "."+strings.ToLower(strings.TrimPrefix(extra, ".")) {
+			return true
 		}
+	}
+	return false
+}
 
-		if info.IsDir() {
-			return nil
-		}
+// labelFileFor returns the label file of an image stem in labelsDir,
+// accepting a .txt extension in any case
+func labelFileFor(labelsDir, stem string) (string, bool) {
+	labelPath := filepath.Join(labelsDir, stem+".txt")
+	if _, err := os.Stat(labelPath); err == nil {
+		return labelPath, true
+	}
+	matches, _ := filepath.Glob(filepath.Join(labelsDir, globEscape(stem)+".[tT][xX][tT]"))
+	if len(matches) > 0 {
+		return matches[0], true
+	}
+	return labelPath, false
+}
 
-		ext := strings.ToLower(filepath.Ext(info.Name()))
-		if !imageExtensions[ext] {
-			return nil
+// globEscape escapes the glob metacharacters of a file name
+func globEscape(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
 		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
 
-		// Find corresponding label file
-		baseName := strings.TrimSuffix(info.Name(), ext)
-		labelPath := filepath.Join(labelsDir, baseName+".txt")
-
-		if _, err := os.Stat(labelPath); err == nil {
-			pairs = append(pairs, LabelPair{
-				ImagePath: path,
-				LabelPath: labelPath,
-			})
+// GetImageLabelPairs finds matching image and label file pairs, in the
+// order of a sequential walk so seeded splits don't depend on the scan
+func (c *Converter) GetImageLabelPairs() ([]LabelPair, error) {
+	var mu sync.Mutex
+	var pairs []LabelPair
+	var unlabeled []string
+	filtered, err := c.walkImageLabelPairs(func(pair LabelPair, found bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if found {
+			pairs = append(pairs, pair)
 		} else {
-			fmt.Printf("Warning: No label file found for %s\n", info.Name())
+			unlabeled = append(unlabeled, pair.ImagePath)
 		}
-
 		return nil
 	})
-
 	if err != nil {
 		return nil, fmt.Errorf("error scanning images directory: %w", err)
 	}
 
-	fmt.Printf("Found %d image-label pairs\n", len(pairs))
+	sort.Slice(pairs, func(i, j int) bool {
+		return walkLess(pairs[i].ImagePath, pairs[j].ImagePath)
+	})
+	sort.Slice(unlabeled, func(i, j int) bool {
+		return walkLess(unlabeled[i], unlabeled[j])
+	})
+	for _, imagePath := range unlabeled {
+		fmt.Fprintf(c.out, "Warning: No label file found for %s\n", filepath.Base(imagePath))
+	}
+	if filtered > 0 {
+		fmt.Fprintf(c.out, "Skipped %d images by -include/-exclude patterns\n", filtered)
+	}
+	fmt.Fprintf(c.out, "Found %d image-label pairs\n", len(pairs))
 	return pairs, nil
 }
 
+// walkImageLabelPairs streams the images of the source to fn while the
+// images directory is scanned in parallel, with found reporting whether the
+// image has a label file. fn is called from several goroutines at once and
+// in no particular order, and stops the scan by returning an error. Images
+// left out by -include and -exclude are skipped and counted.
+func (c *Converter) walkImageLabelPairs(fn func(pair LabelPair, found bool) error) (int, error) {
+	imagesDir := filepath.Join(c.config.SourceDir, "images")
+	labelsDir := filepath.Join(c.config.SourceDir, "labels")
+
+	filter, err := newSourceFilter(c.config.Include, c.config.Exclude)
+	if err != nil {
+		return 0, err
+	}
+	c.filteredLabels = make(map[string]bool)
+
+	var mu sync.Mutex
+	filtered := 0
+	err = walkParallel(imagesDir, c.parallelism(math.MaxInt), func(path string, entry os.DirEntry) error {
+		if !c.isImageFile(entry.Name()) {
+			return nil
+		}
+
+		// Find corresponding label file
+		baseName := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		labelPath, found := labelFileFor(labelsDir, baseName)
+
+		if rel, err := filepath.Rel(imagesDir, path); err == nil && !filter.matches(filepath.ToSlash(rel)) {
+			mu.Lock()
+			c.filteredLabels[filepath.Base(labelPath)] = true
+			filtered++
+			mu.Unlock()
+			return nil
+		}
+		return fn(LabelPair{ImagePath: path, LabelPath: labelPath}, found)
+	})
+	return filtered, err
+}
+
 // SplitDataset splits the dataset into train and validation sets
 func (c *Converter) SplitDataset(pairs []LabelPair) ([]LabelPair, []LabelPair) {
-	// Set random seed for reproducible splits
-	rand.Seed(c.config.Seed)
-
-	// Shuffle the pairs
-	shuffled := make([]LabelPair, len(pairs))
-	copy(shuffled, pairs)
-	rand.Shuffle(len(shuffled), func(i, j int) {
-		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
-	})
+	var trainPairs, valPairs []LabelPair
+	if c.config.SplitStratifyBy != "" && c.metadata != nil {
+		trainPairs, valPairs = c.splitStratified(pairs)
+	} else {
+		trainPairs, valPairs = c.splitPairs(pairs)
+	}
+	fmt.Fprintf(c.out, "Dataset split: %d training, %d validation\n", len(trainPairs), len(valPairs))
+	return trainPairs, valPairs
+}
 
-	// Calculate split index
-	trainCount := int(float64(len(shuffled)) * c.config.TrainSplit)
+// splitPairs divides pairs by -train-split
+func (c *Converter) splitPairs(pairs []LabelPair) ([]LabelPair, []LabelPair) {
+	// Shuffle or sort the pairs, keeping grouped pairs together
+	units := groupPairs(pairs)
+	c.orderUnits(units)
 
-	trainPairs := shuffled[:trainCount]
-	valPairs := shuffled[trainCount:]
+	// Calculate how much of the data goes to training
+	weights, total := c.unitWeights(units)
+	trainWeight := int(float64(total) * c.config.TrainSplit)
 
-	fmt.Printf("Dataset split: %d training, %d validation\n", len(trainPairs), len(valPairs))
+	trainPairs, valPairs, filled := fillSplits(units, weights, trainWeight)
+
+	if c.config.SplitUnit == SplitUnitAnnotations {
+		fmt.Fprintf(c.out, "Split by annotations: %d of %d in training\n", filled, total)
+	}
 	return trainPairs, valPairs
 }
 
+// groupPairs returns the pairs as units that must share a split: one unit
+// per group, in order of first appearance, and one per ungrouped pair
+func groupPairs(pairs []LabelPair) [][]LabelPair {
+	var units [][]LabelPair
+	index := make(map[string]int)
+	for _, pair := range pairs {
+		if pair.Group == "" {
+			units = append(units, []LabelPair{pair})
+			continue
+		}
+		if i, ok := index[pair.Group]; ok {
+			units[i] = append(units[i], pair)
+			continue
+		}
+		index[pair.Group] = len(units)
+		units = append(units, []LabelPair{pair})
+	}
+	return units
+}
+
+// fillSplits assigns units to training until their weights add up to
+// trainWeight and the rest to validation. It also returns the weight
+// assigned to training.
+func fillSplits(units [][]LabelPair, weights []int, trainWeight int) ([]LabelPair, []LabelPair, int) {
+	trainPairs, valPairs := []LabelPair{}, []LabelPair{}
+	filled := 0
+	for i, unit := range units {
+		if filled < trainWeight {
+			trainPairs = append(trainPairs, unit...)
+			filled += weights[i]
+		} else {
+			valPairs = append(valPairs, unit...)
+		}
+	}
+	return trainPairs, valPairs, filled
+}
+
 // CreateYOLOStructure creates the YOLO directory structure
 func (c *Converter) CreateYOLOStructure() error {
 	dirsToCreate := []string{
-		filepath.Join(c.config.OutputDir, "images", "train"),
-		filepath.Join(c.config.OutputDir, "images", "val"),
-		filepath.Join(c.config.OutputDir, "labels", "train"),
-		filepath.Join(c.config.OutputDir, "labels", "val"),
+		"images/train",
+		"images/val",
+		"labels/train",
+		"labels/val",
 	}
 
 	for _, dir := range dirsToCreate {
-		if err := os.MkdirAll(dir, 0755); err != nil {
+		if err := c.output().MkdirAll(dir); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
 	}
 
-	fmt.Printf("Created YOLO directory structure in: %s\n", c.config.OutputDir)
+	fmt.Fprintf(c.out, "Created YOLO directory structure in: %s\n", c.config.OutputDir)
 	return nil
 }
 
 // CopyFiles copies image and label files to the appropriate YOLO directories
 func (c *Converter) CopyFiles(pairs []LabelPair, splitType string) error {
-	imagesDestDir := filepath.Join(c.config.OutputDir, "images", splitType)
-	labelsDestDir := filepath.Join(c.config.OutputDir, "labels", splitType)
+	imagesDestDir := path.Join("images", splitType)
+	labelsDestDir := path.Join("labels", splitType)
 
+	unstripped := 0
 	for _, pair := range pairs {
-		// Copy image
-		imageName := filepath.Base(pair.ImagePath)
-		imageDest := filepath.Join(imagesDestDir, imageName)
-		if err := copyFile(pair.ImagePath, imageDest); err != nil {
-			return fmt.Errorf("failed to copy image %s: %w", pair.ImagePath, err)
+		if err := c.interrupted(); err != nil {
+			return err
 		}
-
-		// Copy label
-		labelName := filepath.Base(pair.LabelPath)
-		labelDest := filepath.Join(labelsDestDir, labelName)
-		if err := copyFile(pair.LabelPath, labelDest); err != nil {
-			return fmt.Errorf("failed to copy label %s: %w", pair.LabelPath, err)
+		kept, err := c.copyPair(pair, imagesDestDir, labelsDestDir)
+		if err != nil {
+			return err
+		}
+		if kept {
+			unstripped++
 		}
 	}
 
-	fmt.Printf("Copied %d %s files\n", len(pairs), splitType)
+	if unstripped > 0 {
+		fmt.Fprintf(c.out, "Warning: metadata could not be stripped from %d %s images (only JPEG and PNG are supported)\n", unstripped, splitType)
+	}
+	fmt.Fprintf(c.out, "Copied %d %s files\n", len(pairs), splitType)
 	return nil
 }
 
-// CreateYAMLConfig creates the YAML configuration file for YOLO
-func (c *Converter) CreateYAMLConfig(classes []string) error {
-	absOutputDir, err := filepath.Abs(c.config.OutputDir)
+// copyPair copies the image and label of a pair into the given directories,
+// reporting whether the image kept metadata that -strip-metadata couldn't
+// remove
+func (c *Converter) copyPair(pair LabelPair, imagesDestDir, labelsDestDir string) (bool, error) {
+	// Copy image
+	unstripped, err := c.copyImage(path.Join(imagesDestDir, filepath.Base(pair.ImagePath)), pair.ImagePath)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
+		return false, err
 	}
 
+	// Copy label, rounding the coordinates of generated and rewritten ones
+	// with -precision and sorting its lines with -canonical-labels
+	labelDest := path.Join(labelsDestDir, filepath.Base(pair.LabelPath))
+	staged := c.isStagedLabel(pair.LabelPath)
+	round := c.config.Precision > 0 && staged
+	if !round && !c.config.CanonicalLabels {
+		if err := c.output().CopyFile(labelDest, pair.LabelPath); err != nil {
+			return false, &CopyError{Kind: "label", Path: pair.LabelPath, Err: err}
+		}
+		return unstripped, nil
+	}
+	data, err := os.ReadFile(pair.LabelPath)
+	if err != nil {
+		return false, &CopyError{Kind: "label", Path: pair.LabelPath, Err: err}
+	}
+	text := string(data)
+	if round {
+		text = roundLabelText(text, c.config.Precision)
+	}
+	if c.config.CanonicalLabels {
+		text = canonicalLabelText(text)
+	}
+	if err := c.output().WriteFile(labelDest, []byte(text)); err != nil {
+		return false, &CopyError{Kind: "label", Path: pair.LabelPath, Err: err}
+	}
+	if c.mover != nil && !staged {
+		c.mover.consume(pair.LabelPath)
+	}
+	return unstripped, nil
+}
+
+// copyImage copies an image to name in the output, without its metadata
+// under -strip-metadata. It reports whether the image kept metadata that
+// couldn't be removed.
+func (c *Converter) copyImage(name, srcPath string) (bool, error) {
+	if !c.config.StripMetadata {
+		if err := c.output().CopyFile(name, srcPath); err != nil {
+			return false, &CopyError{Kind: "image", Path: srcPath, Err: err}
+		}
+		return false, nil
+	}
+	stripped, err := c.copyStripped(name, srcPath)
+	if err != nil {
+		return false, &CopyError{Kind: "image", Path: srcPath, Err: err}
+	}
+	return !stripped, nil
+}
+
+// CreateYAMLConfig creates the YAML configuration file for YOLO
+func (c *Converter) CreateYAMLConfig(classes []string) error {
 	config := YAMLConfig{
-		Path:  absOutputDir,
 		Train: "images/train",
 		Val:   "images/val",
 		NC:    len(classes),
 		Names: classes,
 	}
+	if c.task != TaskDetect {
+		config.Task = c.task
+	}
+	if c.skeleton != nil {
+		config.KptShape = c.skeleton.KptShape()
+		config.FlipIdx = c.skeleton.FlipIdx()
+	}
+	config.Colors = c.classColors(classes)
+	if c.config.DatasetVersion != "" {
+		config.Version = c.datasetVersion.String()
+	}
 
-	yamlPath := filepath.Join(c.config.OutputDir, "data.yaml")
-	file, err := os.Create(yamlPath)
-	if err != nil {
-		return fmt.Errorf("failed to create YAML file: %w", err)
+	// Archives are extracted somewhere we can't know, so leave path unset and
+	// let the trainer resolve the splits relative to data.yaml
+	if archiveFormat(c.config.OutputDir) == "" {
+		absOutputDir, err := filepath.Abs(c.config.OutputDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		config.Path = absOutputDir
 	}
-	defer file.Close()
+
+	var buf bytes.Buffer
 
 	// Write header comment
 	header := fmt.Sprintf("# YOLO Dataset Configuration\n# Generated from Label Studio export\n# Generated at: %s\n\n", time.Now().Format(time.RFC3339))
-	if _, err := file.WriteString(header); err != nil {
-		return fmt.Errorf("failed to write YAML header: %w", err)
-	}
+	buf.WriteString(header)
 
-	encoder := yaml.NewEncoder(file)
+	encoder := yaml.NewEncoder(&buf)
 	encoder.SetIndent(2)
-	defer encoder.Close()
 
 	if err := encoder.Encode(&config); err != nil {
 		return fmt.Errorf("failed to encode YAML: %w", err)
 	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if c.datasetStats != nil {
+		buf.WriteString(c.datasetStats.yamlComment())
+	}
+
+	if err := c.output().WriteFile("data.yaml", buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to create YAML file: %w", err)
+	}
+	c.dataYAML = buf.Bytes()
 
-	fmt.Printf("Created YAML config: %s\n", yamlPath)
+	fmt.Fprintf(c.out, "Created YAML config: %s\n", filepath.Join(c.config.OutputDir, "data.yaml"))
 	return nil
 }
 
 // ValidateLabels validates label files and counts annotations
 func (c *Converter) ValidateLabels(pairs []LabelPair) (*ValidationStats, error) {
+	// Files are validated concurrently; results are kept by index so the
+	// warnings come out in the same order as a sequential pass
+	results := make([]labelFileResult, len(pairs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < c.parallelism(len(pairs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = c.validateLabelFile(pairs[i])
+			}
+		}()
+	}
+	for i := range pairs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
 	stats := &ValidationStats{
 		TotalFiles: len(pairs),
 	}
+	c.findings = nil
+	for _, result := range results {
+		for _, message := range result.messages {
+			fmt.Fprint(c.out, message)
+		}
+		stats.add(result.stats)
+		c.findings = append(c.findings, result.findings...)
+	}
+	return stats, nil
+}
 
-	for _, pair := range pairs {
-		file, err := os.Open(pair.LabelPath)
-		if err != nil {
-			fmt.Printf("Error reading %s: %v\n", pair.LabelPath, err)
-			stats.InvalidLines++
-			continue
+// labelFileResult is the outcome of validating one label file
+type labelFileResult struct {
+	stats    ValidationStats
+	messages []string
+	findings []ValidationFinding
+}
+
+// add accumulates the annotation counts of one file, leaving TotalFiles alone
+func (s *ValidationStats) add(file ValidationStats) {
+	s.TotalAnnotations += file.TotalAnnotations
+	s.FilesWithAnnotations += file.FilesWithAnnotations
+	s.EmptyFiles += file.EmptyFiles
+	s.InvalidLines += file.InvalidLines
+	s.BoxAnnotations += file.BoxAnnotations
+	s.SegmentAnnotations += file.SegmentAnnotations
+	s.PoseAnnotations += file.PoseAnnotations
+	s.OBBAnnotations += file.OBBAnnotations
+	s.PlausibilityWarnings += file.PlausibilityWarnings
+	s.DuplicateAnnotations += file.DuplicateAnnotations
+	s.DegenerateAnnotations += file.DegenerateAnnotations
+	for classID, count := range file.ClassCounts {
+		if s.ClassCounts == nil {
+			s.ClassCounts = make(map[int]int)
 		}
+		s.ClassCounts[classID] += count
+	}
+}
 
-		validLines := 0
-		scanner := bufio.NewScanner(file)
-		lineNum := 0
+// parallelism returns how many files to process at once
+func (c *Converter) parallelism(files int) int {
+	workers := c.config.Parallel
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > files {
+		workers = files
+	}
+	return workers
+}
 
-		for scanner.Scan() {
-			lineNum++
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" {
-				continue
-			}
+// validateLabelFile checks every line of one label file
+func (c *Converter) validateLabelFile(pair LabelPair) labelFileResult {
+	var result labelFileResult
+	stats := &result.stats
 
-			parts := strings.Fields(line)
-			if len(parts) != 5 {
-				fmt.Printf("Warning: Wrong number of values in %s:%d\n", filepath.Base(pair.LabelPath), lineNum)
-				stats.InvalidLines++
-				continue
-			}
+	file, err := os.Open(pair.LabelPath)
+	if err != nil {
+		result.messages = append(result.messages, fmt.Sprintf("Error reading %s: %v\n", pair.LabelPath, err))
+		result.findings = append(result.findings, c.newFinding(pair, 0, "unreadable-file", err.Error(), ""))
+		stats.InvalidLines++
+		return result
+	}
+	defer file.Close()
 
-			// Validate format: class_id x_center y_center width height
-			if _, err := strconv.Atoi(parts[0]); err != nil {
-				fmt.Printf("Warning: Invalid class_id in %s:%d\n", filepath.Base(pair.LabelPath), lineNum)
-				stats.InvalidLines++
-				continue
-			}
+	var width, height int
+	if c.checksPlausibility() {
+		width, height = c.checkImage(pair, &result)
+	}
 
-			allValid := true
-			for i := 1; i < 5; i++ {
-				coord, err := strconv.ParseFloat(parts[i], 64)
-				if err != nil {
-					fmt.Printf("Warning: Invalid coordinate in %s:%d\n", filepath.Base(pair.LabelPath), lineNum)
-					stats.InvalidLines++
-					allValid = false
-					break
-				}
-				if coord < 0 || coord > 1 {
-					fmt.Printf("Warning: Non-normalized coordinates in %s:%d\n", filepath.Base(pair.LabelPath), lineNum)
-					stats.InvalidLines++
-					allValid = false
-					break
-				}
-			}
+	validLines := 0
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	var regions []labeledRegion
 
-			if allValid {
-				validLines++
-			}
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
 		}
 
-		file.Close()
-
-		if err := scanner.Err(); err != nil {
-			fmt.Printf("Error scanning %s: %v\n", pair.LabelPath, err)
+		parts := strings.Fields(line)
+		kind, warning := c.parseLine(parts)
+		if warning != "" {
+			result.messages = append(result.messages, fmt.Sprintf("Warning: %s in %s:%d\n", warning, filepath.Base(pair.LabelPath), lineNum))
+			result.findings = append(result.findings, c.newFinding(pair, lineNum, ruleID(warning), warning, line))
 			stats.InvalidLines++
 			continue
 		}
 
-		stats.TotalAnnotations += validLines
-		if validLines > 0 {
-			stats.FilesWithAnnotations++
-		} else {
-			stats.EmptyFiles++
+		validLines++
+		switch kind {
+		case AnnotationPose:
+			stats.PoseAnnotations++
+		case AnnotationOBB:
+			stats.OBBAnnotations++
+		case AnnotationSegment:
+			stats.SegmentAnnotations++
+		default:
+			stats.BoxAnnotations++
+		}
+		if width > 0 && height > 0 {
+			c.checkRegion(pair, &result, lineNum, line, kind, parts, width, height)
 		}
+
+		region := newLabeledRegion(lineNum, kind, parts)
+		if stats.ClassCounts == nil {
+			stats.ClassCounts = make(map[int]int)
+		}
+		stats.ClassCounts[region.classID]++
+		if rule, message := c.degenerateRegion(region.bounds, width, height); rule != "" {
+			result.flag(c, pair, lineNum, rule, message, line)
+			stats.DegenerateAnnotations++
+		}
+		if earlier, iou, duplicate := c.duplicateOf(regions, region); duplicate {
+			result.flag(c, pair, lineNum, "duplicate-region", fmt.Sprintf("Duplicate of line %d (IoU %.2f)", earlier.line, iou), line)
+			stats.DuplicateAnnotations++
+		}
+		regions = append(regions, region)
 	}
 
-	return stats, nil
+	if err := scanner.Err(); err != nil {
+		result.messages = append(result.messages, fmt.Sprintf("Error scanning %s: %v\n", pair.LabelPath, err))
+		result.findings = append(result.findings, c.newFinding(pair, lineNum+1, "unreadable-line", err.Error(), ""))
+		stats.InvalidLines++
+		return result
+	}
+
+	stats.TotalAnnotations = validLines
+	if validLines > 0 {
+		stats.FilesWithAnnotations = 1
+	} else {
+		stats.EmptyFiles = 1
+	}
+	return result
+}
+
+// parseLine checks a label line in the format of the conversion mode,
+// returning its annotation kind or a warning
+func (c *Converter) parseLine(parts []string) (string, string) {
+	switch {
+	case c.skeleton != nil:
+		if warning := parsePoseLine(parts, c.skeleton); warning != "" {
+			return "", warning
+		}
+		return AnnotationPose, ""
+	case c.config.OBB:
+		if warning := parseOBBLine(parts); warning != "" {
+			return "", warning
+		}
+		return AnnotationOBB, ""
+	}
+	return parseLabelLine(parts)
 }
 
 // Convert performs the main conversion process
 func (c *Converter) Convert() error {
-	fmt.Println("Starting Label Studio to YOLO conversion...")
-	fmt.Printf("Source: %s\n", c.config.SourceDir)
-	fmt.Printf("Output: %s\n", c.config.OutputDir)
-	fmt.Printf("Train split: %.1f%%\n", c.config.TrainSplit*100)
+	return c.ConvertContext(context.Background())
+}
+
+// ConvertContext runs the conversion until ctx is done, when it stops before
+// the next file and returns ErrInterrupted. A directory output keeps a
+// checkpoint of the completed files for -resume.
+func (c *Converter) ConvertContext(ctx context.Context) (err error) {
+	c.ctx = ctx
+	c.findings = nil
+	c.skipped = 0
+	c.sourceSums = nil
+	c.counts = ProvenanceCounts{}
+	start := time.Now()
+	c.started = start
+	defer func() {
+		metrics.ObserveConversion(time.Since(start), err)
+		err = c.conversionError(err)
+		if c.config.NotifyURL != "" {
+			c.notify(err)
+		}
+	}()
+
+	fmt.Fprintln(c.out, "Starting Label Studio to YOLO conversion...")
+	fmt.Fprintf(c.out, "Source: %s\n", c.config.SourceDir)
+	fmt.Fprintf(c.out, "Output: %s\n", c.config.OutputDir)
+	fmt.Fprintf(c.out, "Train split: %.1f%%\n", c.config.TrainSplit*100)
 
-	// Validate source structure
-	if err := c.ValidateSourceStructure(); err != nil {
+	if err := c.resolveInputFormat(); err != nil {
+		return err
+	}
+	if c.config.Move && c.config.Incremental {
+		return fmt.Errorf("-move cannot be combined with -incremental or -watch")
+	}
+	if err := c.validateOutputFormat(); err != nil {
+		return err
+	}
+	if err := c.validateFiftyOne(); err != nil {
+		return err
+	}
+	if err := c.validateHierarchy(); err != nil {
+		return err
+	}
+	if err := c.validatePrecision(); err != nil {
+		return err
+	}
+	if err := c.validateDatasetVersion(); err != nil {
 		return err
 	}
+	if err := c.validateMLflow(); err != nil {
+		return err
+	}
+	if err := c.validateClearML(); err != nil {
+		return err
+	}
+	if err := c.validateGates(); err != nil {
+		return err
+	}
+	if err := c.validateClassWeights(); err != nil {
+		return err
+	}
+	if err := c.validateLeakage(); err != nil {
+		return err
+	}
+	if err := c.validateDedup(); err != nil {
+		return err
+	}
+	if err := c.validateImageQuality(); err != nil {
+		return err
+	}
+	if err := c.validateImageMetadata(); err != nil {
+		return err
+	}
+	if err := c.validateTaskMetadata(); err != nil {
+		return err
+	}
+	if err := c.validateMapping(); err != nil {
+		return err
+	}
+	if err := c.validateDateRange(); err != nil {
+		return err
+	}
+	if c.config.Provenance && (c.config.Classify || c.config.MultiLabel || c.config.Crop) {
+		return fmt.Errorf("-provenance can't be combined with -classify, -multi-label or -crop")
+	}
+	if c.config.AnnotationsTable != "" && (c.config.Classify || c.config.MultiLabel || c.config.Crop) {
+		return fmt.Errorf("-annotations-table can't be combined with -classify, -multi-label or -crop")
+	}
+	if c.config.Catalog && (c.config.Classify || c.config.MultiLabel || c.config.Crop) {
+		return fmt.Errorf("-catalog can't be combined with -classify, -multi-label or -crop")
+	}
+	if c.config.SplitFile != "" && c.config.Crop {
+		return fmt.Errorf("-split-file can't be combined with -crop")
+	}
+	if c.config.ReportFile != "" && c.config.Crop {
+		return fmt.Errorf("-report can't be combined with -crop")
+	}
+	if c.config.HTMLReportFile != "" && c.config.Crop {
+		return fmt.Errorf("-html-report can't be combined with -crop")
+	}
+	if c.config.Strict && c.config.Crop {
+		return fmt.Errorf("-strict can't be combined with -crop")
+	}
+	if c.config.MinValInstances > 0 && c.config.Crop {
+		return fmt.Errorf("-min-val-instances can't be combined with -crop")
+	}
+	if (c.config.MaxPerClass > 0 || c.config.MinPerClass > 0) && c.config.Crop {
+		return fmt.Errorf("-max-per-class and -min-per-class can't be combined with -crop")
+	}
+	if c.config.Aliases != "" && (c.config.Classify || c.config.MultiLabel) {
+		return fmt.Errorf("-aliases can't be combined with -classify or -multi-label")
+	}
+	if c.config.Stream {
+		if err := c.validateStream(); err != nil {
+			return err
+		}
+	}
+	if c.config.Resume {
+		if archiveFormat(c.config.OutputDir) != "" {
+			return fmt.Errorf("-resume requires a directory output")
+		}
+		if c.config.Move {
+			return fmt.Errorf("-move cannot be combined with -resume")
+		}
+	}
+	// A dedup dry run writes nothing, so it leaves the output unlocked
+	if c.dedup == nil || !c.dedup.DryRun {
+		unlock, err := c.lockOutput()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
 
-	// Load classes
-	classes, err := c.LoadClasses()
-	if err != nil {
+	if c.config.Classify || c.config.MultiLabel {
+		if c.config.Crop {
+			return fmt.Errorf("-crop cannot be combined with -classify or -multi-label")
+		}
+		return c.convertClassification()
+	}
+
+	if c.config.OBB && c.config.SkeletonFile != "" {
+		return fmt.Errorf("-obb and -skeleton cannot be combined")
+	}
+	if (c.config.ConfidenceColumn || c.config.MinConf > 0) && c.config.SkeletonFile != "" {
+		return fmt.Errorf("-confidence-column and -min-conf cannot be combined with -skeleton")
+	}
+	if err := c.validateSplit(); err != nil {
+		return err
+	}
+	if err := c.validateSample(); err != nil {
+		return err
+	}
+	c.skeleton = nil
+	if c.config.SkeletonFile != "" {
+		if c.skeleton, err = LoadSkeleton(c.config.SkeletonFile); err != nil {
+			return err
+		}
+	}
+	c.labelConfig = nil
+	switch c.config.InputFormat {
+	case "", InputYOLO, InputLSJSON:
+		if c.labelConfig, err = c.loadLabelConfig(); err != nil {
+			return err
+		}
+	}
+	if err := c.validateTiling(); err != nil {
+		return err
+	}
+	if err := c.validateAugment(); err != nil {
+		return err
+	}
+	if err := c.validateAnonymize(); err != nil {
 		return err
 	}
 
-	// Get image-label pairs
-	pairs, err := c.GetImageLabelPairs()
+	if c.config.Stream {
+		return c.convertStreaming()
+	}
+
+	// Load classes and image-label pairs from the export
+	defer c.removeStaging()
+	classes, pairs, err := c.loadSource()
 	if err != nil {
 		return err
 	}
+	if pairs, err = c.normalizeLabels(pairs); err != nil {
+		return err
+	}
+	if c.config.ConfidenceColumn || c.config.MinConf > 0 {
+		if pairs, err = c.stripConfidence(pairs); err != nil {
+			return err
+		}
+	}
+	if c.config.Aliases != "" {
+		if classes, pairs, err = c.foldAliases(classes, pairs); err != nil {
+			return err
+		}
+	}
+	if c.config.Granularity == GranularityParent {
+		if classes, pairs, err = c.rollUpClasses(classes, pairs); err != nil {
+			return err
+		}
+	}
 
 	if len(pairs) == 0 {
-		return fmt.Errorf("no valid image-label pairs found")
+		return fmt.Errorf("%w: no valid image-label pairs found", ErrNoPairs)
+	}
+	if pairs = c.applyImageMetadata(pairs); len(pairs) == 0 {
+		return fmt.Errorf("%w: no images match -where %s", ErrNoPairs, c.config.Where)
+	}
+	pairs = c.samplePairs(pairs)
+	if c.dedup != nil {
+		if pairs, err = c.dedupImages(classes, pairs); err != nil {
+			return err
+		}
+		if c.dedup.DryRun {
+			return nil
+		}
+	}
+	c.recordOriginals(pairs)
+	if pairs, err = c.transcodeImages(pairs); err != nil {
+		return err
+	}
+	if pairs, err = c.checkOrientation(pairs); err != nil {
+		return err
+	}
+
+	if c.config.NormalizePixels {
+		if pairs, err = c.normalizePixelLabels(pairs); err != nil {
+			return err
+		}
+	}
+
+	if c.config.Dedupe {
+		if pairs, err = c.dedupeLabels(pairs); err != nil {
+			return err
+		}
+	}
+
+	if c.config.DropDegenerate {
+		if pairs, err = c.dropDegenerateLabels(pairs); err != nil {
+			return err
+		}
+	}
+
+	if c.config.TileSize > 0 {
+		if pairs, err = c.tileImages(pairs); err != nil {
+			return err
+		}
+	}
+
+	if c.config.Anonymize != "" {
+		if pairs, err = c.anonymizeImages(classes, pairs); err != nil {
+			return err
+		}
+	}
+
+	if c.config.Crop {
+		return c.convertCrops(classes, pairs)
 	}
 
 	// Validate labels
-	fmt.Println("\nValidating labels...")
+	fmt.Fprintln(c.out, "\nValidating labels...")
 	stats, err := c.ValidateLabels(pairs)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Validation stats: %+v\n", stats)
+	fmt.Fprintf(c.out, "Validation stats: %+v\n", stats)
+	metrics.AddValidationErrors(stats.InvalidLines)
+	c.suggestPixelNormalization()
+	c.suggestConfidenceColumn()
+	if classes, pairs, err = c.checkEmptyClasses(classes, pairs, stats); err != nil {
+		return err
+	}
+	if c.config.ReportFile != "" {
+		if err := c.writeValidationReport(stats); err != nil {
+			return err
+		}
+	}
+	if c.config.HTMLReportFile != "" {
+		if err := c.writeHTMLReport(pairs, stats); err != nil {
+			return err
+		}
+	}
+	if c.config.Strict && len(c.findings) > 0 {
+		return fmt.Errorf("%w: %d problems in the labels (-strict)", ErrValidationFailed, len(c.findings))
+	}
+	if err := c.checkGates(classes, pairs, stats); err != nil {
+		return err
+	}
+
+	c.selectTask(stats)
 
 	// Split dataset
-	trainPairs, valPairs := c.SplitDataset(pairs)
+	var trainPairs, valPairs []LabelPair
+	if c.config.Incremental || c.config.SplitFile != "" {
+		existing := make(map[string]string)
+		if c.config.Incremental {
+			if archiveFormat(c.config.OutputDir) != "" {
+				return fmt.Errorf("incremental conversion requires a directory output")
+			}
+			if existing, err = c.ExistingSplits(); err != nil {
+				return err
+			}
+		}
+		// The split file wins over the output's current layout
+		if c.config.SplitFile != "" {
+			assigned, err := ReadSplitFile(c.config.SplitFile)
+			if err != nil {
+				return err
+			}
+			for image, split := range assigned {
+				existing[image] = split
+			}
+		}
+		trainPairs, valPairs = c.SplitIncremental(pairs, existing)
+	} else {
+		trainPairs, valPairs = c.SplitDataset(pairs)
+		if c.config.MinValInstances > 0 {
+			trainPairs, valPairs = c.ensureValClasses(trainPairs, valPairs, classes)
+		}
+	}
+	if trainPairs, valPairs, err = c.checkLeakage(trainPairs, valPairs); err != nil {
+		return err
+	}
+	trainPairs, valPairs = c.screenImageQuality(trainPairs, valPairs)
+
+	if c.config.SplitFile != "" {
+		if err := WriteSplitFile(c.config.SplitFile, trainPairs, valPairs); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "Updated split file: %s\n", c.config.SplitFile)
+	}
+	if c.config.MaxPerClass > 0 || c.config.MinPerClass > 0 {
+		if trainPairs, err = c.balanceTraining(trainPairs, classes); err != nil {
+			return err
+		}
+	}
+	if c.config.Augment != "" {
+		if trainPairs, err = c.augmentTraining(trainPairs); err != nil {
+			return err
+		}
+	}
+
+	if c.config.AnnotationsTable != "" {
+		if err := c.writeAnnotationTable(classes, map[string][]LabelPair{"train": trainPairs, "val": valPairs}); err != nil {
+			return err
+		}
+	}
+
+	if err := c.checkFreeSpace(pairFiles(trainPairs, valPairs)); err != nil {
+		return err
+	}
+
+	c.counts = ProvenanceCounts{TrainImages: len(trainPairs), ValImages: len(valPairs),
+		Annotations: stats.TotalAnnotations, Classes: len(classes), ValidationFindings: len(c.findings)}
+	if err := c.writeDataset(classes, trainPairs, valPairs); err != nil {
+		return err
+	}
+	if c.config.Granularity == GranularityBoth {
+		if err := c.writeParentDataset(classes, trainPairs, valPairs); err != nil {
+			return err
+		}
+	}
+	if c.config.MLflowURI != "" {
+		if err := c.logToMLflow(classes, stats, len(trainPairs), len(valPairs)); err != nil {
+			return err
+		}
+	}
+	if c.config.ClearMLProject != "" {
+		if _, err := c.registerClearML(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(c.out, "\nConversion completed successfully!")
+	if c.config.OutputFormat != "" && c.config.OutputFormat != OutputYOLO {
+		fmt.Fprintf(c.out, "%s dataset ready at: %s\n", outputFormatNames[c.config.OutputFormat], c.config.OutputDir)
+		return nil
+	}
+	fmt.Fprintf(c.out, "Dataset ready for YOLO training at: %s\n", c.config.OutputDir)
+	fmt.Fprintf(c.out, "Training images: %d\n", len(trainPairs))
+	fmt.Fprintf(c.out, "Validation images: %d\n", len(valPairs))
+	fmt.Fprintf(c.out, "Total annotations: %d\n", stats.TotalAnnotations)
+
+	return nil
+}
+
+// writeDataset opens the output, which is either a directory or an archive,
+// and writes the split pairs to it in the configured output format
+func (c *Converter) writeDataset(classes []string, trainPairs, valPairs []LabelPair) (err error) {
+	// The dataset in the output is read before it is overwritten
+	var previous *previousDataset
+	if c.config.DatasetVersion != "" {
+		if previous, err = c.loadPreviousDataset(); err != nil {
+			return err
+		}
+		if c.datasetVersion, err = c.resolveDatasetVersion(previous); err != nil {
+			return err
+		}
+	}
+
+	writer, err := c.openOutput()
+	if err != nil {
+		return err
+	}
+	defer finishOutput(writer, &err)
+	defer c.reportFileErrors()
+
+	if c.config.Provenance {
+		provenance, err := c.newProvenance(classes, trainPairs, valPairs)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = c.writeProvenance(provenance)
+			}
+		}()
+	}
+
+	if c.config.Catalog {
+		if err := c.writeCatalog(classes, map[string][]LabelPair{"train": trainPairs, "val": valPairs}); err != nil {
+			return err
+		}
+	}
+
+	if c.config.OutputFormat != "" && c.config.OutputFormat != OutputYOLO {
+		return c.writeOutputFormat(classes, map[string][]LabelPair{"train": trainPairs, "val": valPairs})
+	}
 
 	// Create YOLO structure
 	if err := c.CreateYOLOStructure(); err != nil {
@@ -421,28 +1486,185 @@ func (c *Converter) Convert() error {
 	if err := c.CopyFiles(valPairs, "val"); err != nil {
 		return err
 	}
+	if c.config.Incremental {
+		if _, err := c.PruneStale(trainPairs, valPairs); err != nil {
+			return err
+		}
+	}
+	if err := c.writeTaskMetadata(trainPairs, valPairs); err != nil {
+		return err
+	}
+	if err := c.writeMapping(trainPairs, valPairs); err != nil {
+		return err
+	}
 
 	// Create YAML config
+	stats := c.computeDatasetStats(classes, trainPairs, valPairs)
+	if err := c.writeDatasetStats(stats); err != nil {
+		return err
+	}
+	if err := c.writeClassWeights(stats); err != nil {
+		return err
+	}
 	if err := c.CreateYAMLConfig(classes); err != nil {
 		return err
 	}
+	if c.config.DatasetVersion != "" {
+		if err := c.writeDatasetChangelog(previous, classes, trainPairs, valPairs); err != nil {
+			return err
+		}
+	}
+	if c.config.FiftyOne {
+		if err := c.writeFiftyOneScript(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	fmt.Println("\nConversion completed successfully!")
-	fmt.Printf("Dataset ready for YOLO training at: %s\n", c.config.OutputDir)
-	fmt.Printf("Training images: %d\n", len(trainPairs))
-	fmt.Printf("Validation images: %d\n", len(valPairs))
-	fmt.Printf("Total annotations: %d\n", stats.TotalAnnotations)
+// selectTask picks the dataset task from the validated annotations: polygon
+// labels make a segmentation dataset, and a skeleton or -obb pick the pose
+// and OBB tasks
+func (c *Converter) selectTask(stats *ValidationStats) {
+	c.task = TaskDetect
+	if c.skeleton != nil {
+		c.task = TaskPose
+	} else if c.config.OBB {
+		c.task = TaskOBB
+	} else if stats.SegmentAnnotations > 0 {
+		c.task = TaskSegment
+		if stats.BoxAnnotations > 0 {
+			fmt.Fprintf(c.out, "Warning: %d box and %d polygon annotations are mixed; Ultralytics drops all polygons when box and segment counts differ\n",
+				stats.BoxAnnotations, stats.SegmentAnnotations)
+		}
+	}
+}
 
-	return nil
+// loadSource reads the classes and image-label pairs of the configured input
+// format
+func (c *Converter) loadSource() ([]string, []LabelPair, error) {
+	switch c.config.InputFormat {
+	case "", InputYOLO:
+		if err := c.ValidateSourceStructure(); err != nil {
+			return nil, nil, err
+		}
+		classes, err := c.LoadClasses()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.checkLabelConfig(classes)
+		pairs, err := c.GetImageLabelPairs()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := c.checkOrphanLabels(pairs); err != nil {
+			return nil, nil, err
+		}
+		return classes, pairs, nil
+	case InputLSJSON:
+		return c.loadLabelStudioJSON()
+	case InputCVAT:
+		return c.loadCVAT()
+	case InputOpenImages:
+		return c.loadOpenImages()
+	case InputVIA:
+		return c.loadVIA()
+	case InputLabelbox:
+		return c.loadLabelbox()
+	}
+	return nil, nil, fmt.Errorf("unsupported input format: %s", c.config.InputFormat)
+}
+
+// stagingDirectory returns a temporary directory for generated label files,
+// removed again when Convert returns
+func (c *Converter) stagingDirectory() (string, error) {
+	if c.staging == "" {
+		dir, err := os.MkdirTemp("", "labelstudio-to-yolo-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		c.staging = dir
+	}
+	return c.staging, nil
 }
 
-// copyFile copies a file from src to dst
+func (c *Converter) removeStaging() {
+	if c.staging != "" {
+		os.RemoveAll(c.staging)
+		c.staging = ""
+	}
+}
+
+// openOutput opens the dataset writer for the output location
+func (c *Converter) openOutput() (DatasetWriter, error) {
+	writer, err := NewDatasetWriter(c.config.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	switch w := writer.(type) {
+	case *DirWriter:
+		w.SkipUnchanged = c.config.Incremental
+		w.PreserveAttrs = c.config.PreserveAttrs
+	case *TarGzWriter:
+		w.PreserveAttrs = c.config.PreserveAttrs
+	case *ZipWriter:
+		w.PreserveAttrs = c.config.PreserveAttrs
+	}
+	c.mover = nil
+	if c.config.Move {
+		c.mover = newMoveWriter(writer)
+		writer = c.mover
+	}
+	if archiveFormat(c.config.OutputDir) == "" {
+		if writer, err = c.newCheckpointWriter(writer, c.config.Resume); err != nil {
+			return nil, err
+		}
+	}
+	if c.config.Checksums {
+		writer = newChecksumWriter(writer)
+	}
+	c.retrier = nil
+	if c.config.Retries > 0 && archiveFormat(c.config.OutputDir) == "" {
+		c.retrier = newRetryWriter(writer, c.config.Retries, c.config.RetryBackoff)
+		writer = c.retrier
+	}
+	c.writer = writer
+	return writer, nil
+}
+
+// finishOutput aborts the output when *err is set and finalizes it otherwise;
+// it is meant to be deferred with the caller's named error result
+func finishOutput(writer DatasetWriter, err *error) {
+	if *err != nil {
+		writer.Abort()
+		return
+	}
+	if closeErr := writer.Close(); closeErr != nil {
+		*err = fmt.Errorf("failed to finalize output: %w", closeErr)
+	}
+}
+
+// copyFile copies a file from src to dst. Where the file system supports it,
+// dst becomes a copy-on-write clone that shares src's data blocks; otherwise
+// the data is copied, inside the kernel where possible, into a preallocated
+// file.
 func copyFile(src, dst string) error {
+	if err := cloneFile(src, dst); err == nil {
+		if info, err := os.Stat(dst); err == nil {
+			metrics.AddCopied(info.Size())
+		}
+		return nil
+	}
+
 	sourceFile, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer sourceFile.Close()
+	info, err := sourceFile.Stat()
+	if err != nil {
+		return err
+	}
 
 	destFile, err := os.Create(dst)
 	if err != nil {
@@ -450,21 +1672,191 @@ func copyFile(src, dst string) error {
 	}
 	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
+	preallocate(destFile, info.Size())
+	n := kernelCopy(destFile, sourceFile, info.Size())
+	rest, err := copyBuffered(destFile, sourceFile)
 	if err != nil {
 		return err
 	}
+	n += rest
+
+	if err := destFile.Sync(); err != nil {
+		return err
+	}
+	metrics.AddCopied(n)
+	return nil
+}
 
-	return destFile.Sync()
+// subcommand is an entry point selected by the first command line argument
+type subcommand struct {
+	run     func(args []string) error
+	summary string
+}
+
+// subcommands maps subcommand names to their entry points
+var subcommands = map[string]subcommand{
+	"serve":     {runServe, "Run the conversion REST API server"},
+	"import":    {runImport, "Convert YOLO predictions into Label Studio pre-annotations"},
+	"roundtrip": {runRoundTrip, "Check that annotations survive Label Studio -> YOLO -> Label Studio"},
+	"preview":   {runPreview, "Draw the labels of random dataset images for a quick visual check"},
+	"anchors":   {runAnchors, "Suggest anchor boxes for a dataset with k-means over its box sizes"},
+	"diff":      {runDiff, "Compare two datasets: images, class counts and distribution drift"},
+	"verify":    {runVerify, "Check a dataset against its checksums.sha256"},
+	"merge":     {runMerge, "Pull several Label Studio projects and convert them into one dataset"},
+	"doctor":    {runDoctor, "Check the source, output, tools and credentials before a long conversion"},
+	"dedup":     {runDedup, "Drop near-duplicate images, keeping one per cluster, and convert the rest"},
+	"select":    {runSelect, "Pick the tasks whose predictions are least certain for annotation"},
+}
+
+// registerPathFlags registers the source and output locations
+func registerPathFlags(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.SourceDir, "source", ".", "Path to Label Studio export directory")
+	fs.StringVar(&config.OutputDir, "output", "./yolo_dataset", "Path where YOLO dataset will be created (.tar.gz, .tgz or .zip writes an archive)")
+	fs.StringVar(&config.FramesDir, "frames-dir", "", "Directory of pre-extracted video frames, one subdirectory per video named after it (default: extract with ffmpeg)")
+	fs.StringVar(&config.SkeletonFile, "skeleton", "", "Keypoint skeleton definition (YAML); converts keypoints to YOLO-pose labels")
+	fs.StringVar(&config.ReportFile, "report", "", "Write every validation finding to this file (JSON, or NDJSON for .ndjson/.jsonl)")
+	fs.StringVar(&config.HTMLReportFile, "html-report", "", "Write an HTML report with annotated thumbnails of the worst label files")
+	fs.IntVar(&config.HTMLReportTop, "html-report-top", 50, "Number of files shown in the HTML report")
+	fs.StringVar(&config.AnnotationsTable, "annotations-table", "", "Write every annotation as a row (image, split, class, box) to this file: Parquet for .parquet, CSV otherwise")
+	fs.StringVar(&config.TasksFile, "tasks", "", "Export file to read with -input-format ls-json, cvat, via or labelbox (default: the export file in -source)")
+}
+
+// registerConvertFlags registers the conversion options shared by the CLI and
+// the subcommands that run conversions
+func registerConvertFlags(fs *flag.FlagSet, config *Config) {
+	fs.Float64Var(&config.TrainSplit, "train-split", 0.8, "Fraction of data for training (default: 0.8)")
+	fs.Int64Var(&config.Seed, "seed", 42, "Random seed for reproducible splits (default: 42)")
+	fs.Float64Var(&config.Sample, "sample", 0, "Convert a random (seeded) fraction of the image-label pairs, e.g. 0.1 (0 converts all)")
+	fs.IntVar(&config.MaxImages, "max-images", 0, "Convert at most this many randomly chosen image-label pairs (0 converts all)")
+	fs.StringVar(&config.SplitUnit, "split-unit", SplitUnitImages, "What -train-split is a fraction of: images or annotations")
+	fs.StringVar(&config.SplitStrategy, "split-strategy", SplitRandom, "How pairs are split: random, or sequential to validate on the last pairs in -split-order")
+	fs.StringVar(&config.SplitOrder, "split-order", SplitOrderName, "Order of sequential splits: name or mtime (image modification time)")
+	fs.StringVar(&config.SplitFile, "split-file", "", "CSV of image,split assignments to keep; new images are assigned and added to it")
+	fs.IntVar(&config.MinValInstances, "min-val-instances", 0, "Move images to validation until every class has at least this many validation instances (0 disables)")
+	fs.IntVar(&config.MaxPerClass, "max-per-class", 0, "Drop training images whose classes all have this many instances already (0 disables)")
+	fs.IntVar(&config.MinPerClass, "min-per-class", 0, "Duplicate training images of classes with fewer instances than this (0 disables)")
+	fs.BoolVar(&config.Incremental, "incremental", false, "Update an existing output directory in place, keeping existing split assignments")
+	fs.BoolVar(&config.OBB, "obb", false, "Write rectangles as oriented bounding boxes (YOLO-OBB, four corners) keeping their rotation")
+	fs.BoolVar(&config.Classify, "classify", false, "Build a folder-per-class classification dataset from choice labels (requires -input-format ls-json)")
+	fs.BoolVar(&config.MultiLabel, "multi-label", false, "Write a multi-label classification dataset: images plus train.csv/val.csv label vectors (implies -classify)")
+	fs.StringVar(&config.LabelOrder, "label-order", "", "Comma-separated label column order for -multi-label (default: classes.txt in -source, otherwise sorted)")
+	fs.BoolVar(&config.Crop, "crop", false, "Crop every annotation out of its image into a folder-per-class classification dataset")
+	fs.Float64Var(&config.CropPadding, "crop-padding", 0, "Padding added around crops on each side, as a fraction of the box size")
+	fs.IntVar(&config.CropMinSize, "crop-min-size", 8, "Skip crops narrower or shorter than this many pixels")
+	fs.StringVar(&config.ShapeModes, "shape-mode", defaultShapeModes, "How ls-json ellipses and polylines are converted: shape=box|polygon|skip, comma-separated")
+	fs.IntVar(&config.FrameStep, "frame-step", 1, "Keep every Nth frame of annotated videos")
+	fs.StringVar(&config.Consensus, "consensus", ConsensusFirst, "How tasks with several annotations are merged: first, latest, majority or union")
+	fs.Float64Var(&config.ConsensusIoU, "consensus-iou", 0.5, "Minimum IoU for regions of different annotators to match in majority and union consensus")
+	fs.StringVar(&config.ReviewStatus, "review-status", ReviewNotRejected, "Annotations kept by review status: any, not-rejected or accepted")
+	fs.BoolVar(&config.IncludeCancelled, "include-cancelled", false, "Keep annotations marked as cancelled (skipped)")
+	fs.StringVar(&config.Annotators, "annotators", "", "Comma-separated annotator IDs or emails to keep annotations from")
+	fs.StringVar(&config.ExcludeAnnotators, "exclude-annotators", "", "Comma-separated annotator IDs or emails to drop annotations from")
+	fs.StringVar(&config.Since, "since", "", "Keep annotations last updated at or after this date or RFC 3339 time (ls-json)")
+	fs.StringVar(&config.Until, "until", "", "Keep annotations last updated before this date or RFC 3339 time (ls-json)")
+	fs.BoolVar(&config.UsePredictions, "predictions", false, "Label tasks without annotations from their best prediction (pseudo-labels)")
+	fs.Float64Var(&config.MinScore, "min-score", 0.5, "Minimum prediction score for pseudo-label regions with -predictions")
+	fs.IntVar(&config.MinBoxPixels, "min-box-pixels", 0, "Warn about regions narrower or shorter than this many pixels (0 disables)")
+	fs.IntVar(&config.MinImageSize, "min-image-size", 0, "Warn about images narrower or shorter than this many pixels (0 disables)")
+	fs.Float64Var(&config.MaxAspectRatio, "max-aspect-ratio", 0, "Warn about regions whose pixel aspect ratio exceeds this (0 disables)")
+	fs.BoolVar(&config.NormalizePixels, "normalize-pixels", false, "Convert label lines with pixel coordinates to normalized ones using the image size")
+	fs.Float64Var(&config.DuplicateIoU, "duplicate-iou", 0.9, "IoU at which same-class regions of a file are reported as duplicates (0 disables)")
+	fs.BoolVar(&config.Dedupe, "dedupe", false, "Drop regions that duplicate an earlier region of the same class in their file")
+	fs.Float64Var(&config.DegenerateEpsilon, "degenerate-epsilon", 0, "Normalized width or height at or below which a region is reported as zero-area")
+	fs.BoolVar(&config.DropDegenerate, "drop-degenerate", false, "Drop zero-area regions and regions lying outside their image")
+	fs.BoolVar(&config.PruneEmptyClasses, "prune-empty-classes", false, "Remove classes without annotations from data.yaml, renumbering the labels' class IDs")
+	fs.StringVar(&config.Include, "include", "", "Comma-separated glob patterns of source images to convert, e.g. 'cam1_*.jpg' (default: all)")
+	fs.StringVar(&config.Exclude, "exclude", "", "Comma-separated glob patterns of source images to skip, e.g. '**/thumbnails/**'")
+	fs.StringVar(&config.ImageExtensions, "image-ext", "", "Comma-separated extra image extensions to pick up, e.g. .gif,.avif (matched case-insensitively)")
+	fs.BoolVar(&config.Transcode, "transcode", false, "Convert images in formats YOLO can't train on (e.g. HEIC, AVIF, GIF) to JPEG, using ffmpeg where Go can't decode them")
+	fs.BoolVar(&config.ApplyEXIFOrientation, "apply-exif-orientation", false, "Rewrite images with an EXIF orientation upright, as Label Studio displayed them")
+	fs.BoolVar(&config.StripMetadata, "strip-metadata", false, "Remove EXIF, XMP and text metadata (GPS, device serials) from copied JPEG and PNG images")
+	fs.IntVar(&config.TileSize, "tile-size", 0, "Slice images larger than this many pixels into square tiles, clipping their labels (0 disables)")
+	fs.Float64Var(&config.TileOverlap, "tile-overlap", 0.2, "Overlap between neighboring tiles, as a fraction of the tile size")
+	fs.Float64Var(&config.TileBackground, "tile-background", 0, "Fraction of tiles without labels to keep as background images")
+	fs.StringVar(&config.Augment, "augment", "", "Comma-separated augmentations for extra training copies: hflip, vflip, rot90, rot180, rot270, brightness")
+	fs.IntVar(&config.AugmentMultiplier, "augment-multiplier", 1, "Number of augmented copies added per training image")
+	fs.StringVar(&config.Anonymize, "anonymize", "", "Comma-separated classes whose regions are blurred or pixelated in the output images, e.g. face,license_plate")
+	fs.StringVar(&config.AnonymizeMode, "anonymize-mode", AnonymizeBlur, "How -anonymize regions are hidden: blur or pixelate")
+	fs.BoolVar(&config.PreserveAttrs, "preserve-attrs", false, "Keep the modification time, permissions and extended attributes of copied files")
+	fs.BoolVar(&config.Checksums, "checksums", false, "Write a checksums.sha256 manifest of every output file, checked by the verify command")
+	fs.BoolVar(&config.Stream, "stream", false, "Convert in a streaming pipeline with flat memory use for huge exports; splits are assigned by a hash of the image name")
+	fs.IntVar(&config.Retries, "retries", 3, "Retries of a file operation after a transient error such as EIO or ESTALE (directory output)")
+	fs.DurationVar(&config.RetryBackoff, "retry-backoff", 500*time.Millisecond, "Wait before the first retry, doubled for every further retry")
+	fs.BoolVar(&config.Move, "move", false, "Move the source images and labels into the output instead of copying them (the export is consumed)")
+	fs.BoolVar(&config.Resume, "resume", false, "Continue an interrupted conversion into the same directory output, skipping the files it completed")
+	fs.BoolVar(&config.ForceUnlock, "force-unlock", false, "Remove the lock another conversion left on the output (check that it is no longer running)")
+	fs.BoolVar(&config.AllowOrphanLabels, "allow-orphan-labels", false, "Convert even if some label files have no image (they are reported and skipped)")
+	fs.BoolVar(&config.Strict, "strict", false, "Fail before writing the output when validation finds any problem in the labels")
+	fs.IntVar(&config.Parallel, "parallel", 0, "Number of label files validated and source directories scanned concurrently (default: number of CPUs)")
+	fs.StringVar(&config.InputFormat, "input-format", InputAuto, "Source export format: auto (detect it from the source), yolo, ls-json (Label Studio JSON with an images/ directory), cvat (CVAT for images 1.1 XML), openimages (Open Images box CSVs), via (VGG Image Annotator JSON or CSV) or labelbox (Labelbox NDJSON), each with an images/ directory")
+	fs.Float64Var(&config.MaskTolerance, "mask-tolerance", 1.0, "Polygon simplification tolerance in pixels for brush masks (0 keeps every contour corner)")
+	fs.StringVar(&config.OutputFormat, "output-format", OutputYOLO, "Dataset format written to -output: yolo, tfrecord (TF Object Detection API), createml (Apple Create ML), darknet (obj.data layout), huggingface (imagefolder with a dataset card) or roboflow (Roboflow upload layout)")
+	fs.IntVar(&config.TFRecordShards, "tfrecord-shards", 0, "TFRecord files per split with -output-format tfrecord (default: one per 1000 images)")
+	fs.BoolVar(&config.FiftyOne, "fiftyone", false, "Write load_fiftyone.py into the dataset, which loads it into FiftyOne and opens the app")
+	fs.BoolVar(&config.Catalog, "catalog", false, "Write dataset.db, an SQLite catalog of the images, split assignments, classes and annotations")
+	fs.StringVar(&config.OpenImagesClasses, "openimages-classes", "", "Comma-separated Open Images class names to convert with -input-format openimages (default: the names in classes.txt)")
+	fs.StringVar(&config.VIAAttribute, "via-attribute", "", "VIA region attribute holding the class with -input-format via (default: the only region attribute)")
+	fs.StringVar(&config.LabelConfig, "label-config", "", "Label Studio labeling config XML, or project JSON, with the class names and colors (default: label_config.xml or project.json in -source)")
+	fs.StringVar(&config.Aliases, "aliases", "", "YAML file mapping canonical class names to synonym labels folded into them, e.g. 'person: [Person, people]'")
+	fs.StringVar(&config.Hierarchy, "hierarchy", "", "YAML file mapping parent classes to their children, e.g. 'vehicle: [sedan, suv]'")
+	fs.StringVar(&config.Granularity, "granularity", GranularityLeaf, "Classes written with -hierarchy: leaf (as annotated), parent (rolled up to top-level parents) or both (the parent dataset goes to <output>_parent)")
+	fs.BoolVar(&config.ConfidenceColumn, "confidence-column", false, "Accept label lines with a trailing confidence value, as written by model-assisted labeling, and strip it")
+	fs.Float64Var(&config.MinConf, "min-conf", 0, "Drop label lines whose confidence column is below this (implies -confidence-column)")
+	fs.IntVar(&config.Precision, "precision", 0, "Decimal places of coordinates in label files the converter generates or rewrites, 1-6 (default: 6); copied labels are unchanged")
+	fs.BoolVar(&config.CanonicalLabels, "canonical-labels", false, "Sort the lines of output label files by class and coordinates and drop exact duplicates, so re-conversions are byte-identical")
+	fs.BoolVar(&config.Provenance, "provenance", false, "Write provenance.json with the converter version, configuration, source checksums, timestamps and counts")
+	fs.StringVar(&config.MLflowURI, "mlflow-uri", os.Getenv("MLFLOW_TRACKING_URI"), "MLflow tracking server to log data.yaml and the validation report to (default: $MLFLOW_TRACKING_URI)")
+	fs.StringVar(&config.MLflowExperiment, "mlflow-experiment", os.Getenv("MLFLOW_EXPERIMENT_NAME"), "MLflow experiment to create the run in (default: $MLFLOW_EXPERIMENT_NAME, or Default)")
+	fs.StringVar(&config.MLflowRunID, "mlflow-run-id", os.Getenv("MLFLOW_RUN_ID"), "Existing MLflow run to log to instead of creating one (default: $MLFLOW_RUN_ID)")
+	fs.BoolVar(&config.MLflowLogDataset, "mlflow-log-dataset", false, "Also log the dataset archive to MLflow (requires a .tar.gz, .tgz or .zip output)")
+	fs.StringVar(&config.ClearMLProject, "clearml-project", "", "Register the output as a ClearML Dataset in this project")
+	fs.StringVar(&config.ClearMLDataset, "clearml-dataset", "", "Name of the ClearML Dataset (default: the output name)")
+	fs.StringVar(&config.ClearMLTags, "clearml-tags", "", "Comma-separated tags of the ClearML Dataset version")
+	fs.StringVar(&config.ClearMLPython, "clearml-python", "python3", "Python interpreter with the clearml package")
+	fs.StringVar(&config.NotifyURL, "notify-url", "", "POST a summary of the finished conversion, successful or not, to this Slack or generic webhook URL")
+	fs.StringVar(&config.DatasetVersion, "dataset-version", "", "Version the dataset: MAJOR.MINOR.PATCH, or major, minor or patch to bump the version in the output; records it in data.yaml and adds the changes to CHANGELOG.md")
+	fs.StringVar(&config.Gates, "gates", "", "YAML file of quality gates (min_images, min_annotations_per_class, max_invalid_line_rate, max_empty_file_rate); failing any aborts the conversion")
+	fs.StringVar(&config.ClassWeights, "class-weights", "", "Write class weights from the training split's annotation counts: inverse, inverse-sqrt, median or effective[:beta]")
+	fs.StringVar(&config.ClassWeightsFormat, "class-weights-format", "json", "Format of the class weights file: json or yaml")
+	fs.StringVar(&config.Leakage, "leakage", "", "Check the split for validation images identical or nearly identical to training images: report lists them, fix moves them to training")
+	fs.IntVar(&config.LeakageDistance, "leakage-distance", 5, "Bits the perceptual hashes of near-identical images may differ in for -leakage (0-64)")
+	fs.StringVar(&config.ImageQuality, "image-quality", "", "Screen the split for blurry and badly exposed images: flag reports them per split, exclude also drops them")
+	fs.Float64Var(&config.BlurThreshold, "blur-threshold", 100, "Variance of the Laplacian below which -image-quality counts an image as blurry (0 disables the blur check)")
+	fs.Float64Var(&config.ExposureClip, "exposure-clip", defaultExposureClip, "Share of pixels near black or near white above which -image-quality counts an image as under- or overexposed")
+	fs.StringVar(&config.ImageMetadata, "image-metadata", "", "CSV or JSON file of per-image attributes (camera, site, weather...) keyed by image file name")
+	fs.StringVar(&config.Where, "where", "", "Keep only images whose -image-metadata matches every comma-separated condition, such as camera=cam3|cam4,weather!=rain")
+	fs.StringVar(&config.SplitGroupBy, "split-group-by", "", "Keep all images sharing a value of this -image-metadata attribute in the same split")
+	fs.StringVar(&config.SplitStratifyBy, "split-stratify-by", "", "Split the images of every value of this -image-metadata attribute by -train-split on its own")
+	fs.StringVar(&config.TaskMetadata, "task-metadata", "", "Write the Label Studio task (ID, annotators, completion time, source URL) of every image: sidecar writes tasks/<split>/<image>.json, file writes tasks.json")
+	fs.BoolVar(&config.Mapping, "mapping", false, "Write mapping.csv linking every output image to its Label Studio task ID, original file name and split")
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err, nil))
+			}
+			return
+		}
+	}
+
 	var config Config
+	registerPathFlags(flag.CommandLine, &config)
+	registerConvertFlags(flag.CommandLine, &config)
+	var watch bool
+	var watchDebounce time.Duration
+	flag.BoolVar(&watch, "watch", false, "Keep running and incrementally convert whenever the source changes")
+	flag.DurationVar(&watchDebounce, "watch-debounce", 2*time.Second, "Quiet period after the last source change before converting in watch mode")
+	var schedule string
+	flag.StringVar(&schedule, "schedule", "", "Also convert on this cron schedule (minute hour day month weekday, local time) in watch mode, e.g. \"0 2 * * *\"")
 
-	flag.StringVar(&config.SourceDir, "source", ".", "Path to Label Studio export directory")
-	flag.StringVar(&config.OutputDir, "output", "./yolo_dataset", "Path where YOLO dataset will be created")
-	flag.Float64Var(&config.TrainSplit, "train-split", 0.8, "Fraction of data for training (default: 0.8)")
-	flag.Int64Var(&config.Seed, "seed", 42, "Random seed for reproducible splits (default: 42)")
+	var metricsAddr string
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address in watch mode (e.g. :9090)")
+
+	var profiling profileOptions
+	registerProfileFlags(flag.CommandLine, &profiling)
 
 	var showHelp bool
 	flag.BoolVar(&showHelp, "help", false, "Show help message")
@@ -492,6 +1884,17 @@ func main() {
 		fmt.Println()
 		fmt.Println("Usage:")
 		fmt.Printf("  %s [flags]\n", os.Args[0])
+		fmt.Printf("  %s <command> [flags]\n", os.Args[0])
+		fmt.Println()
+		fmt.Println("Commands:")
+		names := make([]string, 0, len(subcommands))
+		for name := range subcommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  %-10s %s\n", name, subcommands[name].summary)
+		}
 		fmt.Println()
 		fmt.Println("Flags:")
 		flag.PrintDefaults()
@@ -499,12 +1902,68 @@ func main() {
 		fmt.Println("Examples:")
 		fmt.Printf("  %s -source . -output ./yolo_dataset\n", os.Args[0])
 		fmt.Printf("  %s -source /path/to/labelstudio -output /path/to/yolo -train-split 0.7\n", os.Args[0])
+		fmt.Printf("  %s -source . -output dataset.tar.gz\n", os.Args[0])
+		fmt.Printf("  %s -source ./export -input-format ls-json -output ./yolo_dataset\n", os.Args[0])
+		fmt.Printf("  %s -source /mnt/share/export -output ./yolo_dataset -watch\n", os.Args[0])
 		return
 	}
 
 	converter := NewConverter(config)
-	if err := converter.Convert(); err != nil {
+	if schedule != "" {
+		if !watch {
+			fmt.Fprintln(os.Stderr, "Error: -schedule requires -watch")
+			os.Exit(1)
+		}
+		sched, err := ParseSchedule(schedule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		converter.SetSchedule(sched)
+	}
+	stopProfiling, err := profiling.start()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if watch {
+		if metricsAddr != "" {
+			go func() {
+				mux := http.NewServeMux()
+				mux.Handle("GET /metrics", metrics.Handler())
+				if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+					fmt.Fprintf(os.Stderr, "Metrics server error: %v\n", err)
+				}
+			}()
+		}
+		err = converter.Watch(ctx, watchDebounce)
+	} else {
+		err = converter.ConvertContext(ctx)
+	}
+	if stopErr := stopProfiling(); stopErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", stopErr)
+	}
+	var problems *ConversionErrors
+	if !watch {
+		problems = converter.Errors()
+	}
+	switch code := exitCode(err, problems); code {
+	case 0:
+	case exitInterrupted:
+		if archiveFormat(config.OutputDir) != "" {
+			fmt.Fprintln(os.Stderr, "Interrupted; the partial archive was removed")
+		} else {
+			fmt.Fprintln(os.Stderr, "Interrupted; the completed files are checkpointed, rerun with -resume to continue")
+		}
+		os.Exit(code)
+	case exitPartialSuccess:
+		fmt.Fprintf(os.Stderr, "Partial success: %d source files or annotations were left out of the dataset\n", problems.Skipped)
+		os.Exit(code)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(code)
+	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -316,6 +317,54 @@ func TestValidateLabelsInvalidFormat(t *testing.T) {
 	}
 }
 
+func TestValidateLabelsParallelIsDeterministic(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var pairs []LabelPair
+	for i := 0; i < 200; i++ {
+		labelPath := filepath.Join(tempDir, fmt.Sprintf("label_%03d.txt", i))
+		content := "0 0.5 0.5 0.2 0.2\n"
+		switch i % 3 {
+		case 1:
+			content += "1 bad 0.5 0.2 0.2\n"
+		case 2:
+			content = ""
+		}
+		if err := os.WriteFile(labelPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create label: %v", err)
+		}
+		pairs = append(pairs, LabelPair{LabelPath: labelPath})
+	}
+	pairs = append(pairs, LabelPair{LabelPath: filepath.Join(tempDir, "missing.txt")})
+
+	validate := func(parallel int) (*ValidationStats, string) {
+		var out strings.Builder
+		converter := NewConverter(Config{Parallel: parallel})
+		converter.SetOutput(&out)
+		stats, err := converter.ValidateLabels(pairs)
+		if err != nil {
+			t.Fatalf("Failed to validate labels: %v", err)
+		}
+		return stats, out.String()
+	}
+
+	sequential, sequentialLog := validate(1)
+	parallel, parallelLog := validate(8)
+
+	if !reflect.DeepEqual(sequential, parallel) {
+		t.Errorf("Expected identical stats, got %+v and %+v", *sequential, *parallel)
+	}
+	if sequentialLog != parallelLog {
+		t.Errorf("Expected identical warning order:\n%s\nvs\n%s", sequentialLog, parallelLog)
+	}
+	if parallel.TotalAnnotations != 134 || parallel.EmptyFiles != 66 || parallel.InvalidLines != 68 {
+		t.Errorf("Unexpected stats %+v", *parallel)
+	}
+	if !strings.HasPrefix(parallelLog, "Warning: Invalid") || !strings.Contains(parallelLog, "label_001.txt:2") {
+		t.Errorf("Unexpected log:\n%s", parallelLog)
+	}
+}
+
 func TestCopyFiles(t *testing.T) {
 	tempDir := t.TempDir()
 	createTestFiles(t, tempDir)
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mappingFile links every output image to where it came from
+const mappingFile = "mapping.csv"
+
+// validateMapping checks -mapping before the conversion starts
+func (c *Converter) validateMapping() error {
+	c.originals, c.originalStems = nil, nil
+	if !c.config.Mapping {
+		return nil
+	}
+	if c.config.Classify || c.config.MultiLabel || c.config.Crop {
+		return fmt.Errorf("-mapping cannot be combined with -classify, -multi-label or -crop")
+	}
+	if c.config.OutputFormat != "" && c.config.OutputFormat != OutputYOLO {
+		return fmt.Errorf("-mapping requires the yolo output format")
+	}
+	return nil
+}
+
+// recordOriginals remembers the original file of every pair before images
+// are transcoded, tiled or augmented: the file name of the task's image or
+// video for Label Studio JSON exports, the path within the source otherwise
+func (c *Converter) recordOriginals(pairs []LabelPair) {
+	if !c.config.Mapping {
+		return
+	}
+	c.originals = make(map[string]string, len(pairs))
+	c.originalStems = make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name := filepath.Base(pair.ImagePath)
+		original := name
+		if task, ok := c.taskFor(pair.ImagePath); ok && task.SourceURL != "" {
+			original = imageFileName(task.SourceURL)
+		} else if rel, err := filepath.Rel(c.config.SourceDir, pair.ImagePath); err == nil && !strings.HasPrefix(rel, "..") {
+			original = filepath.ToSlash(rel)
+		}
+		c.originals[name] = original
+		c.originalStems[strings.TrimSuffix(name, filepath.Ext(name))] = original
+	}
+}
+
+// originalFor returns the original file of a dataset image, matching by file
+// name or, for transcoded images, by name without extension
+func (c *Converter) originalFor(imagePath string) (string, bool) {
+	name := filepath.Base(imagePath)
+	if original, ok := c.originals[name]; ok {
+		return original, true
+	}
+	original, ok := c.originalStems[strings.TrimSuffix(name, filepath.Ext(name))]
+	return original, ok
+}
+
+// writeMapping writes mapping.csv: the output image, its Label Studio task
+// ID, its original file and its split, one row per image sorted by image.
+// Images that didn't come from one file, such as tiles and augmented copies,
+// have empty task and original columns.
+func (c *Converter) writeMapping(trainPairs, valPairs []LabelPair) error {
+	if !c.config.Mapping {
+		return nil
+	}
+	var rows [][]string
+	for _, split := range []struct {
+		name  string
+		pairs []LabelPair
+	}{{"train", trainPairs}, {"val", valPairs}} {
+		for _, pair := range split.pairs {
+			taskID := ""
+			if task, ok := c.taskFor(pair.ImagePath); ok {
+				taskID = strconv.Itoa(task.TaskID)
+			}
+			original, _ := c.originalFor(pair.ImagePath)
+			image := path.Join("images", split.name, filepath.Base(pair.ImagePath))
+			rows = append(rows, []string{image, taskID, original, split.name})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"image", "task_id", "original", "split"})
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", mappingFile, err)
+	}
+	if err := c.output().WriteFile(mappingFile, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", mappingFile, err)
+	}
+	fmt.Fprintf(c.out, "Wrote the origin of %d images to %s\n", len(rows), mappingFile)
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readMapping reads the rows of a mapping.csv, header included
+func readMapping(t *testing.T, outputDir string) [][]string {
+	file, err := os.Open(filepath.Join(outputDir, "mapping.csv"))
+	if err != nil {
+		t.Fatalf("Missing mapping.csv: %v", err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read mapping.csv: %v", err)
+	}
+	return rows
+}
+
+func TestMappingLabelStudioJSON(t *testing.T) {
+	sourceDir := t.TempDir()
+	createLabelStudioExport(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputLSJSON, Mapping: true})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	want := [][]string{
+		{"image", "task_id", "original", "split"},
+		{"images/train/a1b2-cat.jpg", "1", "a1b2-cat.jpg", "train"},
+		{"images/train/dog photo.png", "2", "dog photo.png", "train"},
+		{"images/train/mask.png", "3", "mask.png", "train"},
+	}
+	rows := readMapping(t, outputDir)
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %v", len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("Row %d: expected %v, got %v", i, want[i], rows[i])
+		}
+	}
+	if !strings.Contains(out.String(), "Wrote the origin of 3 images to mapping.csv") {
+		t.Errorf("Expected a summary, got:\n%s", out.String())
+	}
+	// Without -task-metadata the tasks only feed the mapping
+	if _, err := os.Stat(filepath.Join(outputDir, "tasks")); !os.IsNotExist(err) {
+		t.Errorf("Expected no task sidecars, got %v", err)
+	}
+}
+
+func TestMappingTranscodedYOLO(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	file, err := os.Create(filepath.Join(sourceDir, "images", "anim.gif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(file, image.NewPaletted(image.Rect(0, 0, 40, 30), palette.Plan9), nil); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "anim.txt"), []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, ImageExtensions: ".gif", Transcode: true, Mapping: true})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	rows := readMapping(t, outputDir)
+	found := false
+	for _, row := range rows[1:] {
+		if row[1] != "" {
+			t.Errorf("Expected no task IDs for a YOLO export, got %v", row)
+		}
+		if row[0] == "images/train/anim.jpg" {
+			found = true
+			if row[2] != "images/anim.gif" {
+				t.Errorf("Expected the transcoded image to map to images/anim.gif, got %v", row)
+			}
+		}
+	}
+	if !found || len(rows) != 5 {
+		t.Errorf("Expected 4 images including anim.jpg, got %v", rows)
+	}
+}
+
+func TestValidateMapping(t *testing.T) {
+	if err := NewConverter(Config{Mapping: true, Crop: true}).validateMapping(); err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Errorf("Expected -crop to be rejected, got %v", err)
+	}
+	if err := NewConverter(Config{Mapping: true, OutputFormat: OutputTFRecord}).validateMapping(); err == nil || !strings.Contains(err.Error(), "requires the yolo output format") {
+		t.Errorf("Expected tfrecord output to be rejected, got %v", err)
+	}
+	if err := NewConverter(Config{Mapping: true}).validateMapping(); err != nil {
+		t.Errorf("Expected a valid configuration, got %v", err)
+	}
+}
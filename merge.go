@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// mergeSource is one project export to merge
+type mergeSource struct {
+	Project int
+	Dir     string
+}
+
+// MergeReport describes how the class spaces of merged projects were unified
+type MergeReport struct {
+	// Classes is the combined class list, in order of first appearance
+	Classes  []string        `json:"classes"`
+	Projects []MergedProject `json:"projects"`
+}
+
+// MergedProject reports what one project contributed and how its classes
+// were renumbered
+type MergedProject struct {
+	Project int          `json:"project"`
+	Images  int          `json:"images"`
+	Labels  int          `json:"labels"`
+	Classes []ClassRemap `json:"classes"`
+	// Dropped counts annotations whose class ID isn't in the project's
+	// classes.txt
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// ClassRemap maps a project's class to its class in the combined dataset
+type ClassRemap struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	NewID   int    `json:"new_id"`
+	NewName string `json:"new_name"`
+}
+
+// mergePrefix is prepended to the file names of a project's images and labels
+// so that files from different projects can't collide
+func mergePrefix(projectID int) string {
+	return fmt.Sprintf("p%d_", projectID)
+}
+
+// mergeExports combines YOLO exports into one export in dir. Classes with
+// the same name, after applying aliases, become one class; every label is
+// renumbered to the combined class list.
+func mergeExports(sources []mergeSource, aliases map[string]string, dir string) (*MergeReport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	report := &MergeReport{}
+	ids := make(map[string]int)
+
+	for _, source := range sources {
+		classes, err := readClassesFile(filepath.Join(source.Dir, "classes.txt"))
+		if err != nil {
+			return nil, fmt.Errorf("project %d: failed to read classes: %w", source.Project, err)
+		}
+
+		project := MergedProject{Project: source.Project}
+		remap := make(map[int]int, len(classes))
+		for i, name := range classes {
+			newName := name
+			if canonical, ok := aliases[name]; ok {
+				newName = canonical
+			}
+			id, ok := ids[newName]
+			if !ok {
+				id = len(report.Classes)
+				ids[newName] = id
+				report.Classes = append(report.Classes, newName)
+			}
+			remap[i] = id
+			project.Classes = append(project.Classes, ClassRemap{ID: i, Name: name, NewID: id, NewName: newName})
+		}
+
+		if err := mergeProject(source, remap, dir, &project); err != nil {
+			return nil, fmt.Errorf("project %d: %w", source.Project, err)
+		}
+		report.Projects = append(report.Projects, project)
+	}
+
+	classes := strings.Join(report.Classes, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "classes.txt"), []byte(classes), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write classes: %w", err)
+	}
+	return report, nil
+}
+
+// mergeProject links a project's images and writes its renumbered labels into
+// the combined export, prefixing every file name with the project ID
+func mergeProject(source mergeSource, remap map[int]int, dir string, project *MergedProject) error {
+	prefix := mergePrefix(source.Project)
+	for _, sub := range []string{"images", "labels"} {
+		root := filepath.Join(source.Dir, sub)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			dst := filepath.Join(dir, sub, filepath.Dir(rel), prefix+filepath.Base(rel))
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return err
+			}
+
+			if sub == "images" {
+				project.Images++
+				return stageFrame(path, dst)
+			}
+			if !strings.EqualFold(filepath.Ext(path), ".txt") {
+				return nil
+			}
+			project.Labels++
+			dropped, err := remapLabelFile(path, dst, remap)
+			project.Dropped += dropped
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to merge %s: %w", sub, err)
+		}
+	}
+	return nil
+}
+
+// remapLabelFile writes a label file with its class IDs renumbered, dropping
+// annotations with unknown class IDs. Lines that don't start with a class ID
+// are kept for validation to report.
+func remapLabelFile(src, dst string, remap map[int]int) (int, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return 0, err
+	}
+
+	var out strings.Builder
+	dropped := 0
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			if id, err := strconv.Atoi(fields[0]); err == nil {
+				newID, ok := remap[id]
+				if !ok {
+					dropped++
+					continue
+				}
+				fields[0] = strconv.Itoa(newID)
+				line = strings.Join(fields, " ")
+			}
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return dropped, fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	return dropped, os.WriteFile(dst, []byte(out.String()), 0644)
+}
+
+// print writes the combined classes and every class whose ID or name changed
+func (r *MergeReport) print(w io.Writer) {
+	fmt.Fprintf(w, "Merged %d projects into %d classes:\n", len(r.Projects), len(r.Classes))
+	for id, name := range r.Classes {
+		var projects []string
+		for _, project := range r.Projects {
+			for _, class := range project.Classes {
+				if class.NewID == id {
+					projects = append(projects, strconv.Itoa(project.Project))
+					break
+				}
+			}
+		}
+		fmt.Fprintf(w, "  %3d %-20s projects %s\n", id, name, strings.Join(projects, ", "))
+	}
+
+	for _, project := range r.Projects {
+		var changes []string
+		for _, class := range project.Classes {
+			if class.ID != class.NewID || class.Name != class.NewName {
+				changes = append(changes, fmt.Sprintf("%d %s -> %d %s", class.ID, class.Name, class.NewID, class.NewName))
+			}
+		}
+		fmt.Fprintf(w, "Project %d: %d images, %d labels", project.Project, project.Images, project.Labels)
+		if len(changes) == 0 {
+			fmt.Fprintf(w, ", class IDs unchanged\n")
+		} else {
+			fmt.Fprintf(w, ", remapped %s\n", strings.Join(changes, ", "))
+		}
+		if project.Dropped > 0 {
+			fmt.Fprintf(w, "Warning: dropped %d annotations of project %d with unknown class IDs\n", project.Dropped, project.Project)
+		}
+	}
+}
+
+// runMerge implements the merge subcommand
+func runMerge(args []string) error {
+	var config Config
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	lsURL := fs.String("ls-url", "", "Label Studio base URL")
+	lsToken := fs.String("ls-token", os.Getenv("LABEL_STUDIO_TOKEN"), "Label Studio API token (default: $LABEL_STUDIO_TOKEN)")
+	projectList := fs.String("projects", "", "Comma-separated IDs of the Label Studio projects to merge")
+	fs.StringVar(&config.OutputDir, "output", "./yolo_dataset", "Path where the combined YOLO dataset will be created (.tar.gz, .tgz or .zip writes an archive)")
+	reportPath := fs.String("remap-report", "", "Write the class remap report as JSON to this file")
+	registerConvertFlags(fs, &config)
+	fs.Parse(args)
+
+	if *lsURL == "" {
+		return fmt.Errorf("-ls-url is required")
+	}
+	projects, err := parseProjectIDs(*projectList)
+	if err != nil {
+		return fmt.Errorf("-projects: %w", err)
+	}
+	var aliases map[string]string
+	if config.Aliases != "" {
+		if aliases, err = loadAliases(config.Aliases); err != nil {
+			return err
+		}
+		// The merge already folded them
+		config.Aliases = ""
+	}
+
+	workDir, err := os.MkdirTemp("", "labelstudio-merge-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	ls := LabelStudioConfig{URL: *lsURL, Token: *lsToken}
+	client := &http.Client{Timeout: 30 * time.Minute}
+	var sources []mergeSource
+	for _, id := range projects {
+		fmt.Printf("Downloading project %d...\n", id)
+		dir, err := fetchSource(client, ls.ProjectExportURL(id), ls.Token, filepath.Join(workDir, "projects", strconv.Itoa(id)), SourceLimits{})
+		if err != nil {
+			return fmt.Errorf("project %d: %w", id, err)
+		}
+		sources = append(sources, mergeSource{Project: id, Dir: dir})
+	}
+
+	config.SourceDir = filepath.Join(workDir, "merged")
+	report, err := mergeExports(sources, aliases, config.SourceDir)
+	if err != nil {
+		return err
+	}
+	report.print(os.Stdout)
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		if err := os.WriteFile(*reportPath, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return NewConverter(config).ConvertContext(ctx)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeSecondProject creates an export whose classes overlap the test files'
+// book/person classes in a different order
+func writeSecondProject(t *testing.T) string {
+	dir := t.TempDir()
+	for _, sub := range []string{"images", "labels"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"classes.txt":       "Person\ncar\n",
+		"images/image1.jpg": "other image",
+		"images/road.jpg":   "road image",
+		"labels/image1.txt": "0 0.5 0.5 0.2 0.2\n1 0.3 0.3 0.1 0.1\n",
+		"labels/road.txt":   "1 0.5 0.5 0.4 0.4\n5 0.5 0.5 0.1 0.1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMergeExports(t *testing.T) {
+	first := t.TempDir()
+	createTestFiles(t, first)
+	second := writeSecondProject(t)
+	merged := filepath.Join(t.TempDir(), "merged")
+
+	report, err := mergeExports([]mergeSource{{Project: 3, Dir: first}, {Project: 7, Dir: second}}, map[string]string{"Person": "person"}, merged)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if got := strings.Join(report.Classes, ","); got != "book,person,car" {
+		t.Errorf("Expected classes book,person,car, got %s", got)
+	}
+	want := []ClassRemap{{ID: 0, Name: "Person", NewID: 1, NewName: "person"}, {ID: 1, Name: "car", NewID: 2, NewName: "car"}}
+	if project := report.Projects[1]; len(project.Classes) != 2 || project.Classes[0] != want[0] || project.Classes[1] != want[1] {
+		t.Errorf("Expected remap %+v, got %+v", want, project.Classes)
+	}
+	if project := report.Projects[1]; project.Images != 2 || project.Labels != 2 || project.Dropped != 1 {
+		t.Errorf("Expected 2 images, 2 labels and 1 dropped annotation, got %+v", project)
+	}
+
+	labels := map[string]string{
+		// Same name in both projects, kept apart by the prefix
+		"p3_image1.txt": "0 0.5 0.5 0.3 0.3\n1 0.2 0.8 0.1 0.1\n",
+		"p7_image1.txt": "1 0.5 0.5 0.2 0.2\n2 0.3 0.3 0.1 0.1\n",
+		"p7_road.txt":   "2 0.5 0.5 0.4 0.4\n",
+	}
+	for name, content := range labels {
+		got, err := os.ReadFile(filepath.Join(merged, "labels", name))
+		if err != nil || string(got) != content {
+			t.Errorf("Label %s: expected %q, got %q (%v)", name, content, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(merged, "images", "p7_road.jpg")); err != nil {
+		t.Errorf("Expected the second project's images to be merged: %v", err)
+	}
+}
+
+func TestRunMerge(t *testing.T) {
+	exports := map[string]string{
+		"/api/projects/3/export": zipExport(t),
+		"/api/projects/7/export": zipDir(t, writeSecondProject(t)),
+	}
+	labelStudio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		archive, ok := exports[r.URL.Path]
+		if !ok || r.Header.Get("Authorization") != "Token secret" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		data, _ := os.ReadFile(archive)
+		w.Write(data)
+	}))
+	defer labelStudio.Close()
+
+	outputDir := filepath.Join(t.TempDir(), "combined")
+	reportPath := filepath.Join(t.TempDir(), "remap.json")
+	err := runMerge([]string{"-ls-url", labelStudio.URL, "-ls-token", "secret", "-projects", "3,7", "-output", outputDir, "-remap-report", reportPath, "-train-split", "1"})
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read data.yaml: %v", err)
+	}
+	var config YAMLConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.NC != 4 {
+		t.Errorf("Expected 4 classes (book, person, Person, car), got %d: %v", config.NC, config.Names)
+	}
+	for _, name := range []string{"p3_image1.jpg", "p7_image1.jpg", "p7_road.jpg"} {
+		if _, err := os.Stat(filepath.Join(outputDir, "images", "train", name)); err != nil {
+			t.Errorf("Expected %s in the combined dataset: %v", name, err)
+		}
+	}
+
+	var report MergeReport
+	data, err = os.ReadFile(reportPath)
+	if err != nil || json.Unmarshal(data, &report) != nil || len(report.Projects) != 2 {
+		t.Errorf("Expected a remap report for both projects, got %s (%v)", data, err)
+	}
+
+	if err := runMerge([]string{"-projects", "3"}); err == nil || !strings.Contains(err.Error(), "-ls-url") {
+		t.Errorf("Expected an error without -ls-url, got %v", err)
+	}
+}
+
+// zipDir archives an export directory for serving it as a project export
+func zipDir(t *testing.T, dir string) string {
+	archivePath := filepath.Join(t.TempDir(), "export.zip")
+	writer, err := NewDatasetWriter(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		return writer.CopyFile(filepath.ToSlash(rel), p)
+	})
+	if err != nil {
+		t.Fatalf("Failed to build archive: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	return archivePath
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// strippedPNGChunks are the PNG chunks holding text and EXIF metadata
+var strippedPNGChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+// stripMetadata removes EXIF, XMP, comments and similar metadata from a JPEG
+// or PNG image without re-encoding it. It returns false for other formats and
+// for files it can't parse.
+func stripMetadata(data []byte, name string) ([]byte, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg":
+		return stripJPEGMetadata(data)
+	case ".png":
+		return stripPNGMetadata(data)
+	}
+	return nil, false
+}
+
+// stripJPEGMetadata drops the APP1 to APP15 segments (EXIF, XMP, maker
+// notes) and comments, keeping JFIF and ICC color profiles
+func stripJPEGMetadata(data []byte) ([]byte, bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, false
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	for i := 2; ; {
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, false
+		}
+		marker := data[i+1]
+		// The entropy-coded image data follows start of scan
+		if marker == 0xDA {
+			out.Write(data[i:])
+			return out.Bytes(), true
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			return nil, false
+		}
+		isICC := marker == 0xE2 && bytes.HasPrefix(data[i+4:end], []byte("ICC_PROFILE\x00"))
+		if !(marker >= 0xE1 && marker <= 0xEF && !isICC) && marker != 0xFE {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+}
+
+// stripPNGMetadata drops text, EXIF and timestamp chunks
+func stripPNGMetadata(data []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, false
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+	for i := len(pngSignature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, false
+		}
+		// Length, type, data and CRC
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) {
+			return nil, false
+		}
+		if !strippedPNGChunks[string(data[i+4:i+8])] {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	return out.Bytes(), true
+}
+
+// copyStripped writes an image to the output without its metadata. Formats
+// that can't be stripped are copied as they are and reported as false.
+func (c *Converter) copyStripped(name, srcPath string) (bool, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return false, err
+	}
+	stripped, ok := stripMetadata(data, srcPath)
+	if ok {
+		data = stripped
+	}
+	if err := c.output().WriteFile(name, data); err != nil {
+		return false, err
+	}
+	if c.mover != nil {
+		c.mover.consume(srcPath)
+	}
+	return ok, nil
+}
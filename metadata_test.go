@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStripJPEGMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gps.jpg")
+	writeOrientedJPEG(t, path, 16, 8, 1)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("Exif")) {
+		t.Fatal("Expected the test image to carry EXIF")
+	}
+
+	stripped, ok := stripMetadata(data, path)
+	if !ok {
+		t.Fatal("Expected the JPEG to be stripped")
+	}
+	if bytes.Contains(stripped, []byte("Exif")) {
+		t.Error("Expected the EXIF segment to be removed")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("Expected a valid JPEG, got %v", err)
+	}
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert a tEXt chunk after the IHDR chunk
+	text := []byte("Author\x00Jane Doe, GPS 52.5 13.4")
+	var chunk bytes.Buffer
+	binary.Write(&chunk, binary.BigEndian, uint32(len(text)))
+	chunk.WriteString("tEXt")
+	chunk.Write(text)
+	binary.Write(&chunk, binary.BigEndian, crc32.ChecksumIEEE(append([]byte("tEXt"), text...)))
+	ihdrEnd := len(pngSignature) + 12 + 13
+	data := append(append(append([]byte{}, encoded.Bytes()[:ihdrEnd]...), chunk.Bytes()...), encoded.Bytes()[ihdrEnd:]...)
+
+	stripped, ok := stripMetadata(data, "scan.PNG")
+	if !ok {
+		t.Fatal("Expected the PNG to be stripped")
+	}
+	if !bytes.Equal(stripped, encoded.Bytes()) {
+		t.Error("Expected only the tEXt chunk to be removed")
+	}
+
+	if _, ok := stripMetadata(data, "scan.webp"); ok {
+		t.Error("Expected other formats to be left to the caller")
+	}
+}
+
+func TestCopyFilesStripsMetadata(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "gps.jpg")
+	writeOrientedJPEG(t, imagePath, 16, 8, 1)
+	labelPath := filepath.Join(dir, "gps.txt")
+	if err := os.WriteFile(labelPath, []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := filepath.Join(dir, "out")
+	converter := NewConverter(Config{OutputDir: outputDir, StripMetadata: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.CreateYOLOStructure(); err != nil {
+		t.Fatal(err)
+	}
+	if err := converter.CopyFiles([]LabelPair{{ImagePath: imagePath, LabelPath: labelPath}}, "train"); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := os.ReadFile(filepath.Join(outputDir, "images", "train", "gps.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(copied, []byte("Exif")) {
+		t.Error("Expected the copied image to have no EXIF")
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// metricPrefix namespaces every exported metric
+const metricPrefix = "labelstudio_to_yolo_"
+
+// durationBuckets are the histogram buckets, in seconds, for conversions and jobs
+var durationBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600}
+
+// histogram is a cumulative Prometheus-style histogram
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Metrics collects process-wide counters for the long-running modes and
+// renders them in the Prometheus text exposition format
+type Metrics struct {
+	mu sync.Mutex
+
+	conversions        map[string]float64
+	filesCopied        float64
+	bytesWritten       float64
+	validationErrors   float64
+	fileRetries        float64
+	conversionDuration *histogram
+
+	jobs        map[string]float64
+	jobsRunning float64
+	jobDuration *histogram
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{
+		conversions:        make(map[string]float64),
+		conversionDuration: newHistogram(durationBuckets),
+		jobs:               make(map[string]float64),
+		jobDuration:        newHistogram(durationBuckets),
+	}
+}
+
+// metrics is the registry the converter and server report to
+var metrics = NewMetrics()
+
+// ObserveConversion records a finished conversion
+func (m *Metrics) ObserveConversion(duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+	m.conversions[status]++
+	m.conversionDuration.observe(duration.Seconds())
+}
+
+// AddCopied records a file written to the output
+func (m *Metrics) AddCopied(bytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.filesCopied++
+	m.bytesWritten += float64(bytes)
+}
+
+// AddValidationErrors records invalid label lines found during validation
+func (m *Metrics) AddValidationErrors(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.validationErrors += float64(n)
+}
+
+// AddRetry records a file operation retried after a transient error
+func (m *Metrics) AddRetry() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fileRetries++
+}
+
+// JobStarted records a server job starting
+func (m *Metrics) JobStarted() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobsRunning++
+}
+
+// JobFinished records a server job ending with the given status
+func (m *Metrics) JobFinished(status string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobsRunning--
+	m.jobs[status]++
+	m.jobDuration.observe(duration.Seconds())
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+
+	writeLabeled(cw, "conversions_total", "counter", "Conversions run, by result.", "status", m.conversions, []string{"success", "failure"})
+	writeSingle(cw, "files_copied_total", "counter", "Files written to dataset outputs.", m.filesCopied)
+	writeSingle(cw, "bytes_written_total", "counter", "Bytes written to dataset outputs.", m.bytesWritten)
+	writeSingle(cw, "validation_errors_total", "counter", "Invalid label lines found during validation.", m.validationErrors)
+	writeSingle(cw, "file_retries_total", "counter", "File operations retried after transient errors.", m.fileRetries)
+	writeHistogram(cw, "conversion_duration_seconds", "Duration of conversions.", m.conversionDuration)
+	writeLabeled(cw, "jobs_total", "counter", "Server jobs finished, by status.", "status", m.jobs, []string{JobSucceeded, JobFailed})
+	writeSingle(cw, "jobs_running", "gauge", "Server jobs currently running.", m.jobsRunning)
+	writeHistogram(cw, "job_duration_seconds", "Duration of server jobs.", m.jobDuration)
+
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+// Handler serves the metrics for scraping
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteTo(w)
+	})
+}
+
+// countingWriter tracks bytes written and the first error, so the write
+// helpers can stay free of error plumbing
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) printf(format string, args ...interface{}) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeSingle(cw *countingWriter, name, kind, help string, value float64) {
+	cw.printf("# HELP %s%s %s\n", metricPrefix, name, help)
+	cw.printf("# TYPE %s%s %s\n", metricPrefix, name, kind)
+	cw.printf("%s%s %s\n", metricPrefix, name, formatFloat(value))
+}
+
+// writeLabeled writes a metric with one label, always including the known
+// label values so that rate() works from the first scrape
+func writeLabeled(cw *countingWriter, name, kind, help, label string, values map[string]float64, known []string) {
+	cw.printf("# HELP %s%s %s\n", metricPrefix, name, help)
+	cw.printf("# TYPE %s%s %s\n", metricPrefix, name, kind)
+
+	seen := make(map[string]bool)
+	var keys []string
+	for _, k := range known {
+		keys = append(keys, k)
+		seen[k] = true
+	}
+	var extra []string
+	for k := range values {
+		if !seen[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	keys = append(keys, extra...)
+
+	for _, k := range keys {
+		cw.printf("%s%s{%s=%q} %s\n", metricPrefix, name, label, k, formatFloat(values[k]))
+	}
+}
+
+func writeHistogram(cw *countingWriter, name, help string, h *histogram) {
+	cw.printf("# HELP %s%s %s\n", metricPrefix, name, help)
+	cw.printf("# TYPE %s%s histogram\n", metricPrefix, name)
+	for i, upper := range h.buckets {
+		cw.printf("%s%s_bucket{le=%q} %d\n", metricPrefix, name, formatFloat(upper), h.counts[i])
+	}
+	cw.printf("%s%s_bucket{le=\"+Inf\"} %d\n", metricPrefix, name, h.count)
+	cw.printf("%s%s_sum %s\n", metricPrefix, name, formatFloat(h.sum))
+	cw.printf("%s%s_count %d\n", metricPrefix, name, h.count)
+}
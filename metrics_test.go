@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	m := NewMetrics()
+	m.ObserveConversion(2*time.Second, nil)
+	m.ObserveConversion(700*time.Millisecond, errors.New("boom"))
+	m.AddCopied(100)
+	m.AddCopied(50)
+	m.AddValidationErrors(3)
+	m.JobStarted()
+	m.JobFinished(JobSucceeded, 20*time.Second)
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		`# TYPE labelstudio_to_yolo_conversions_total counter`,
+		`labelstudio_to_yolo_conversions_total{status="success"} 1`,
+		`labelstudio_to_yolo_conversions_total{status="failure"} 1`,
+		`labelstudio_to_yolo_files_copied_total 2`,
+		`labelstudio_to_yolo_bytes_written_total 150`,
+		`labelstudio_to_yolo_validation_errors_total 3`,
+		`labelstudio_to_yolo_conversion_duration_seconds_bucket{le="0.5"} 0`,
+		`labelstudio_to_yolo_conversion_duration_seconds_bucket{le="1"} 1`,
+		`labelstudio_to_yolo_conversion_duration_seconds_bucket{le="5"} 2`,
+		`labelstudio_to_yolo_conversion_duration_seconds_bucket{le="+Inf"} 2`,
+		`labelstudio_to_yolo_conversion_duration_seconds_count 2`,
+		`labelstudio_to_yolo_jobs_total{status="succeeded"} 1`,
+		`labelstudio_to_yolo_jobs_total{status="failed"} 0`,
+		`labelstudio_to_yolo_jobs_running 0`,
+		`labelstudio_to_yolo_job_duration_seconds_sum 20`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected metrics output to contain %q\nGot:\n%s", line, out)
+		}
+	}
+}
+
+func TestConvertRecordsMetrics(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+
+	before := metrics.filesCopied
+	converter := NewConverter(Config{
+		SourceDir:  tempDir,
+		OutputDir:  filepath.Join(tempDir, "yolo_output"),
+		TrainSplit: 0.8,
+		Seed:       42,
+	})
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	// 3 images, 3 labels, stats.json and data.yaml
+	metrics.mu.Lock()
+	copied := metrics.filesCopied - before
+	metrics.mu.Unlock()
+	if copied != 8 {
+		t.Errorf("Expected 8 files copied, got %v", copied)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Unexpected content type: %s", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "labelstudio_to_yolo_conversions_total") {
+		t.Error("Expected metrics handler to expose conversion counter")
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables MLflow's own clients read, used for the credentials
+// of the tracking server
+const (
+	mlflowTokenEnv    = "MLFLOW_TRACKING_TOKEN"
+	mlflowUsernameEnv = "MLFLOW_TRACKING_USERNAME"
+	mlflowPasswordEnv = "MLFLOW_TRACKING_PASSWORD"
+)
+
+// mlflowDefaultExperiment is the experiment every MLflow server has
+const mlflowDefaultExperiment = "Default"
+
+// mlflowArtifactDir is the directory of the run's artifacts the dataset
+// files are logged to
+const mlflowArtifactDir = "dataset"
+
+// mlflowClient talks to the REST API of an MLflow tracking server
+type mlflowClient struct {
+	base     string
+	client   *http.Client
+	ctx      context.Context
+	token    string
+	username string
+	password string
+}
+
+// mlflowRunInfo is the part of a run's info the converter needs
+type mlflowRunInfo struct {
+	RunID        string `json:"run_id"`
+	ExperimentID string `json:"experiment_id"`
+	ArtifactURI  string `json:"artifact_uri"`
+}
+
+// mlflowParam and mlflowMetric are logged to a run with runs/log-batch
+type mlflowParam struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type mlflowMetric struct {
+	Key       string  `json:"key"`
+	Value     float64 `json:"value"`
+	Timestamp int64   `json:"timestamp"`
+	Step      int64   `json:"step"`
+}
+
+// mlflowError is an error response of the tracking server
+type mlflowError struct {
+	Status    int
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (e *mlflowError) Error() string {
+	if e.ErrorCode != "" {
+		return fmt.Sprintf("MLflow: %s: %s", e.ErrorCode, e.Message)
+	}
+	return fmt.Sprintf("MLflow: HTTP %d", e.Status)
+}
+
+// newMLflowClient connects to the tracking server at uri, with the
+// credentials from the environment
+func newMLflowClient(ctx context.Context, uri string) *mlflowClient {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &mlflowClient{
+		base:     strings.TrimRight(uri, "/"),
+		client:   &http.Client{Timeout: 30 * time.Minute},
+		ctx:      ctx,
+		token:    os.Getenv(mlflowTokenEnv),
+		username: os.Getenv(mlflowUsernameEnv),
+		password: os.Getenv(mlflowPasswordEnv),
+	}
+}
+
+// do sends a request to endpoint below the server's URI and decodes a JSON
+// response into response when it isn't nil
+func (m *mlflowClient) do(method, endpoint string, body io.Reader, size int64, response any) error {
+	req, err := http.NewRequestWithContext(m.ctx, method, m.base+endpoint, body)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	switch {
+	case m.token != "":
+		req.Header.Set("Authorization", "Bearer "+m.token)
+	case m.username != "":
+		req.SetBasicAuth(m.username, m.password)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &mlflowError{Status: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("MLflow: invalid response from %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+// call sends a JSON request to an API endpoint
+func (m *mlflowClient) call(method, endpoint string, request, response any) error {
+	var body io.Reader
+	size := int64(-1)
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body, size = bytes.NewReader(data), int64(len(data))
+	}
+	return m.do(method, "/api/2.0/mlflow/"+endpoint, body, size, response)
+}
+
+// experimentID returns the ID of the named experiment, creating it when it
+// doesn't exist
+func (m *mlflowClient) experimentID(name string) (string, error) {
+	var found struct {
+		Experiment struct {
+			ExperimentID string `json:"experiment_id"`
+		} `json:"experiment"`
+	}
+	err := m.call(http.MethodGet, "experiments/get-by-name?experiment_name="+url.QueryEscape(name), nil, &found)
+	if err == nil {
+		return found.Experiment.ExperimentID, nil
+	}
+	var apiErr *mlflowError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != "RESOURCE_DOES_NOT_EXIST" {
+		return "", err
+	}
+	var created struct {
+		ExperimentID string `json:"experiment_id"`
+	}
+	if err := m.call(http.MethodPost, "experiments/create", map[string]string{"name": name}, &created); err != nil {
+		return "", err
+	}
+	return created.ExperimentID, nil
+}
+
+// createRun starts a run in an experiment
+func (m *mlflowClient) createRun(experimentID, name string) (mlflowRunInfo, error) {
+	var created struct {
+		Run struct {
+			Info mlflowRunInfo `json:"info"`
+		} `json:"run"`
+	}
+	request := map[string]any{
+		"experiment_id": experimentID,
+		"run_name":      name,
+		"start_time":    time.Now().UnixMilli(),
+		"tags":          []mlflowParam{{Key: "mlflow.source.name", Value: "labelstudio-to-yolo"}},
+	}
+	if err := m.call(http.MethodPost, "runs/create", request, &created); err != nil {
+		return mlflowRunInfo{}, err
+	}
+	return created.Run.Info, nil
+}
+
+// getRun looks up an existing run
+func (m *mlflowClient) getRun(runID string) (mlflowRunInfo, error) {
+	var found struct {
+		Run struct {
+			Info mlflowRunInfo `json:"info"`
+		} `json:"run"`
+	}
+	if err := m.call(http.MethodGet, "runs/get?run_id="+url.QueryEscape(runID), nil, &found); err != nil {
+		return mlflowRunInfo{}, err
+	}
+	return found.Run.Info, nil
+}
+
+// finishRun marks a run the converter created as finished
+func (m *mlflowClient) finishRun(runID string) error {
+	return m.call(http.MethodPost, "runs/update", map[string]any{
+		"run_id": runID, "status": "FINISHED", "end_time": time.Now().UnixMilli(),
+	}, nil)
+}
+
+// logBatch logs parameters and metrics to a run
+func (m *mlflowClient) logBatch(runID string, params []mlflowParam, metrics []mlflowMetric) error {
+	return m.call(http.MethodPost, "runs/log-batch", map[string]any{
+		"run_id": runID, "params": params, "metrics": metrics,
+	}, nil)
+}
+
+// uploadArtifact uploads an artifact to a run through the tracking server's
+// artifact proxy, which serves artifact URIs of the mlflow-artifacts scheme
+func (m *mlflowClient) uploadArtifact(run mlflowRunInfo, name string, body io.Reader, size int64) error {
+	u, err := url.Parse(run.ArtifactURI)
+	if err != nil || u.Scheme != "mlflow-artifacts" {
+		return fmt.Errorf("MLflow: the artifact store of run %s (%s) isn't served by the tracking server; start it with --serve-artifacts",
+			run.RunID, run.ArtifactURI)
+	}
+	artifactPath := path.Join(strings.TrimPrefix(u.Path, "/"), mlflowArtifactDir, name)
+	return m.do(http.MethodPut, "/api/2.0/mlflow-artifacts/artifacts/"+artifactPath, body, size, nil)
+}
+
+// validateMLflow checks the MLflow options
+func (c *Converter) validateMLflow() error {
+	if c.config.MLflowURI == "" {
+		if c.config.MLflowLogDataset {
+			return fmt.Errorf("-mlflow-log-dataset requires -mlflow-uri")
+		}
+		return nil
+	}
+	if c.config.Classify || c.config.MultiLabel || c.config.Crop {
+		return fmt.Errorf("-mlflow-uri cannot be combined with -classify, -multi-label or -crop")
+	}
+	if c.config.MLflowLogDataset && archiveFormat(c.config.OutputDir) == "" {
+		return fmt.Errorf("-mlflow-log-dataset requires an archive output (.tar.gz, .tgz or .zip)")
+	}
+	return nil
+}
+
+// logToMLflow logs the converted dataset to an MLflow run: its size as
+// parameters and metrics, and data.yaml, the validation report and, with
+// -mlflow-log-dataset, the dataset archive as artifacts. Without a run ID a
+// run is created in the experiment and finished afterwards.
+func (c *Converter) logToMLflow(classes []string, stats *ValidationStats, trainImages, valImages int) error {
+	m := newMLflowClient(c.ctx, c.config.MLflowURI)
+
+	var run mlflowRunInfo
+	var err error
+	created := c.config.MLflowRunID == ""
+	if created {
+		experiment := c.config.MLflowExperiment
+		if experiment == "" {
+			experiment = mlflowDefaultExperiment
+		}
+		experimentID, err := m.experimentID(experiment)
+		if err != nil {
+			return fmt.Errorf("failed to find MLflow experiment %q: %w", experiment, err)
+		}
+		if run, err = m.createRun(experimentID, "convert-"+time.Now().Format("20060102-150405")); err != nil {
+			return fmt.Errorf("failed to create MLflow run: %w", err)
+		}
+	} else if run, err = m.getRun(c.config.MLflowRunID); err != nil {
+		return fmt.Errorf("failed to find MLflow run %s: %w", c.config.MLflowRunID, err)
+	}
+
+	params := []mlflowParam{
+		{Key: "dataset.source", Value: c.config.SourceDir},
+		{Key: "dataset.output", Value: c.config.OutputDir},
+		{Key: "dataset.train_split", Value: strconv.FormatFloat(c.config.TrainSplit, 'f', -1, 64)},
+		{Key: "dataset.seed", Value: strconv.FormatInt(c.config.Seed, 10)},
+		{Key: "dataset.converter_version", Value: Version},
+	}
+	if c.config.DatasetVersion != "" {
+		params = append(params, mlflowParam{Key: "dataset.version", Value: c.datasetVersion.String()})
+	}
+	now := time.Now().UnixMilli()
+	metrics := []mlflowMetric{
+		{Key: "dataset.train_images", Value: float64(trainImages), Timestamp: now},
+		{Key: "dataset.val_images", Value: float64(valImages), Timestamp: now},
+		{Key: "dataset.classes", Value: float64(len(classes)), Timestamp: now},
+		{Key: "dataset.annotations", Value: float64(stats.TotalAnnotations), Timestamp: now},
+		{Key: "dataset.invalid_lines", Value: float64(stats.InvalidLines), Timestamp: now},
+	}
+	if err := m.logBatch(run.RunID, params, metrics); err != nil {
+		return fmt.Errorf("failed to log to MLflow run %s: %w", run.RunID, err)
+	}
+
+	findings := c.findings
+	if findings == nil {
+		findings = []ValidationFinding{}
+	}
+	report, err := json.MarshalIndent(ValidationReport{Stats: stats, Findings: findings}, "", "  ")
+	if err != nil {
+		return err
+	}
+	artifacts := map[string][]byte{"validation_report.json": append(report, '\n')}
+	if c.dataYAML != nil {
+		artifacts["data.yaml"] = c.dataYAML
+	}
+	for _, name := range []string{"data.yaml", "validation_report.json"} {
+		data, ok := artifacts[name]
+		if !ok {
+			continue
+		}
+		if err := m.uploadArtifact(run, name, bytes.NewReader(data), int64(len(data))); err != nil {
+			return fmt.Errorf("failed to log %s to MLflow: %w", name, err)
+		}
+	}
+	if c.config.MLflowLogDataset {
+		if err := c.uploadMLflowDataset(m, run); err != nil {
+			return err
+		}
+	}
+
+	if created {
+		if err := m.finishRun(run.RunID); err != nil {
+			return fmt.Errorf("failed to finish MLflow run %s: %w", run.RunID, err)
+		}
+	}
+	fmt.Fprintf(c.out, "Logged the dataset to MLflow run %s\n", run.RunID)
+	return nil
+}
+
+// uploadMLflowDataset logs the dataset archive to a run
+func (c *Converter) uploadMLflowDataset(m *mlflowClient, run mlflowRunInfo) error {
+	file, err := os.Open(c.config.OutputDir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(c.config.OutputDir)
+	if err := m.uploadArtifact(run, name, file, info.Size()); err != nil {
+		return fmt.Errorf("failed to log %s to MLflow: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeMLflow records what the converter logs to an MLflow tracking server
+type fakeMLflow struct {
+	mu        sync.Mutex
+	calls     []string
+	batch     map[string]any
+	artifacts map[string]string
+	auth      string
+}
+
+func (f *fakeMLflow) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+	f.auth = r.Header.Get("Authorization")
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.URL.Path == "/api/2.0/mlflow/experiments/get-by-name":
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error_code":"RESOURCE_DOES_NOT_EXIST","message":"no experiment"}`)
+	case r.URL.Path == "/api/2.0/mlflow/experiments/create":
+		io.WriteString(w, `{"experiment_id":"7"}`)
+	case r.URL.Path == "/api/2.0/mlflow/runs/create":
+		io.WriteString(w, `{"run":{"info":{"run_id":"abc","experiment_id":"7","artifact_uri":"mlflow-artifacts:/7/abc/artifacts"}}}`)
+	case r.URL.Path == "/api/2.0/mlflow/runs/log-batch":
+		json.Unmarshal(body, &f.batch)
+		io.WriteString(w, `{}`)
+	case r.URL.Path == "/api/2.0/mlflow/runs/update":
+		io.WriteString(w, `{}`)
+	case strings.HasPrefix(r.URL.Path, "/api/2.0/mlflow-artifacts/artifacts/"):
+		f.artifacts[strings.TrimPrefix(r.URL.Path, "/api/2.0/mlflow-artifacts/artifacts/")] = string(body)
+		io.WriteString(w, `{}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestConvertLogsToMLflow(t *testing.T) {
+	fake := &fakeMLflow{artifacts: make(map[string]string)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	t.Setenv(mlflowTokenEnv, "secret")
+
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "dataset.tar.gz")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42,
+		MLflowURI: server.URL, MLflowExperiment: "datasets", MLflowLogDataset: true})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Logged the dataset to MLflow run abc") {
+		t.Errorf("Expected the run in the output, got:\n%s", out.String())
+	}
+
+	wantCalls := []string{
+		"GET /api/2.0/mlflow/experiments/get-by-name",
+		"POST /api/2.0/mlflow/experiments/create",
+		"POST /api/2.0/mlflow/runs/create",
+		"POST /api/2.0/mlflow/runs/log-batch",
+	}
+	for i, want := range wantCalls {
+		if i >= len(fake.calls) || fake.calls[i] != want {
+			t.Fatalf("Expected call %d to be %s, got %v", i, want, fake.calls)
+		}
+	}
+	if last := fake.calls[len(fake.calls)-1]; last != "POST /api/2.0/mlflow/runs/update" {
+		t.Errorf("Expected the run to be finished last, got %s", last)
+	}
+	if fake.auth != "Bearer secret" {
+		t.Errorf("Expected the token from %s, got %q", mlflowTokenEnv, fake.auth)
+	}
+
+	for _, name := range []string{"data.yaml", "validation_report.json", "dataset.tar.gz"} {
+		if fake.artifacts["7/abc/artifacts/dataset/"+name] == "" {
+			t.Errorf("Expected %s to be logged, got %v", name, fake.calls)
+		}
+	}
+	if !strings.Contains(fake.artifacts["7/abc/artifacts/dataset/data.yaml"], "nc: 2") {
+		t.Errorf("Expected data.yaml as the artifact, got %q", fake.artifacts["7/abc/artifacts/dataset/data.yaml"])
+	}
+	metrics, _ := fake.batch["metrics"].([]any)
+	found := false
+	for _, metric := range metrics {
+		if m := metric.(map[string]any); m["key"] == "dataset.annotations" && m["value"] == 5.0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a dataset.annotations metric of 5, got %v", fake.batch)
+	}
+}
+
+func TestMLflowArtifactStoreNotServed(t *testing.T) {
+	m := newMLflowClient(context.Background(), "http://localhost")
+	err := m.uploadArtifact(mlflowRunInfo{RunID: "abc", ArtifactURI: "s3://bucket/abc/artifacts"}, "data.yaml", strings.NewReader(""), 0)
+	if err == nil || !strings.Contains(err.Error(), "--serve-artifacts") {
+		t.Errorf("Expected an error about the artifact store, got %v", err)
+	}
+}
+
+func TestValidateMLflow(t *testing.T) {
+	for _, config := range []Config{
+		{MLflowLogDataset: true},
+		{MLflowURI: "http://localhost:5000", MLflowLogDataset: true},
+		{MLflowURI: "http://localhost:5000", Stream: true},
+	} {
+		config.SourceDir, config.OutputDir = t.TempDir(), t.TempDir()
+		converter := NewConverter(config)
+		converter.SetOutput(&strings.Builder{})
+		if err := converter.Convert(); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// moveWriter moves files into the dataset instead of copying them. A
+// directory output on the same file system as a source file gets it by an
+// immediate rename, so a conversion failing partway leaves those sources
+// partly moved into the output. Every other source file is copied and only
+// removed once the dataset has been finished.
+type moveWriter struct {
+	DatasetWriter
+
+	mu      sync.Mutex
+	sources []string
+}
+
+func newMoveWriter(writer DatasetWriter) *moveWriter {
+	return &moveWriter{DatasetWriter: writer}
+}
+
+// CopyFile moves srcPath to name
+func (w *moveWriter) CopyFile(name, srcPath string) error {
+	if dir, ok := w.DatasetWriter.(*DirWriter); ok {
+		// Renames fail across file systems; those files are copied instead
+		if err := os.Rename(srcPath, dir.localPath(name)); err == nil {
+			return nil
+		}
+	}
+	if err := w.DatasetWriter.CopyFile(name, srcPath); err != nil {
+		return err
+	}
+	w.consume(srcPath)
+	return nil
+}
+
+// consume marks a source file as written to the dataset, to be removed
+// when the dataset is finished
+func (w *moveWriter) consume(srcPath string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sources = append(w.sources, srcPath)
+}
+
+// Close finishes the dataset and then removes the moved source files
+func (w *moveWriter) Close() error {
+	if err := w.DatasetWriter.Close(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, src := range w.sources {
+		if err := os.Remove(src); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove moved file %s: %w", src, err)
+		}
+	}
+	w.sources = nil
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertMove(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	outputDir := filepath.Join(tempDir, "yolo_output")
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42, Move: true, Checksums: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"images", "labels"} {
+		remaining, _ := filepath.Glob(filepath.Join(tempDir, dir, "image*"))
+		if len(remaining) != 0 {
+			t.Errorf("Expected the source %s to be moved, found %v", dir, remaining)
+		}
+	}
+	images, _ := filepath.Glob(filepath.Join(outputDir, "images", "*", "image*"))
+	labels, _ := filepath.Glob(filepath.Join(outputDir, "labels", "*", "image*.txt"))
+	if len(images) != 3 || len(labels) != 3 {
+		t.Errorf("Expected 3 images and 3 labels in the output, got %v and %v", images, labels)
+	}
+	// The checksums are taken before the sources disappear
+	report, err := VerifyDataset(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("Expected the moved dataset to verify, got %+v", report)
+	}
+}
+
+func TestMoveWriterKeepsSourcesUntilClose(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(src, []byte("0 0.5 0.5 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inner, err := newTarGzWriter(filepath.Join(dir, "out.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := newMoveWriter(inner)
+	if err := writer.CopyFile("labels/train/a.txt", src); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Fatalf("Expected the source to stay until the archive is finished: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("Expected the source to be removed after closing, got %v", err)
+	}
+	files := readTarGz(t, filepath.Join(dir, "out.tar.gz"))
+	if files["labels/train/a.txt"] != "0 0.5 0.5 0.1 0.1\n" {
+		t.Errorf("Expected the label in the archive, got %v", files)
+	}
+}
+
+func TestConvertMoveRejectsIncremental(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: filepath.Join(tempDir, "out"), TrainSplit: 0.8, Move: true, Incremental: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil {
+		t.Error("Expected -move with -incremental to fail")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "images", "image1.jpg")); err != nil {
+		t.Errorf("Expected the source to be untouched: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// notifyTimeout bounds the webhook request sent when a conversion finishes
+const notifyTimeout = 30 * time.Second
+
+// Conversion outcomes reported by -notify-url
+const (
+	NotifySuccess     = "success"
+	NotifyPartial     = "partial"
+	NotifyFailure     = "failure"
+	NotifyInterrupted = "interrupted"
+)
+
+// ConversionSummary is the JSON body -notify-url posts to a generic webhook
+// when a conversion finishes
+type ConversionSummary struct {
+	Status          string           `json:"status"`
+	Error           string           `json:"error,omitempty"`
+	Source          string           `json:"source"`
+	Output          string           `json:"output"`
+	StartedAt       time.Time        `json:"started_at"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	Counts          ProvenanceCounts `json:"counts"`
+	DatasetVersion  string           `json:"dataset_version,omitempty"`
+	Host            string           `json:"host,omitempty"`
+}
+
+// conversionSummary summarizes the finished conversion, err being its
+// result
+func (c *Converter) conversionSummary(err error) ConversionSummary {
+	summary := ConversionSummary{
+		Status:          NotifySuccess,
+		Source:          c.config.SourceDir,
+		Output:          c.config.OutputDir,
+		StartedAt:       c.started.UTC(),
+		DurationSeconds: time.Since(c.started).Seconds(),
+		Counts:          c.counts,
+	}
+	summary.Host, _ = os.Hostname()
+	summary.Counts.Skipped = c.skipped
+	if c.config.DatasetVersion != "" && err == nil {
+		summary.DatasetVersion = c.datasetVersion.String()
+	}
+	switch {
+	case errors.Is(err, ErrInterrupted):
+		summary.Status, summary.Error = NotifyInterrupted, err.Error()
+	case err != nil:
+		summary.Status, summary.Error = NotifyFailure, err.Error()
+	case c.skipped > 0:
+		summary.Status = NotifyPartial
+	}
+	return summary
+}
+
+// slackMessage formats a summary as the text of a Slack message
+func slackMessage(s ConversionSummary) string {
+	icon := map[string]string{
+		NotifySuccess: ":white_check_mark:", NotifyPartial: ":warning:",
+		NotifyFailure: ":x:", NotifyInterrupted: ":double_vertical_bar:",
+	}[s.Status]
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Label Studio to YOLO conversion %s: `%s` -> `%s` in %s",
+		icon, s.Status, s.Source, s.Output, (time.Duration(s.DurationSeconds * float64(time.Second))).Round(time.Second))
+	if s.Host != "" {
+		fmt.Fprintf(&b, " on %s", s.Host)
+	}
+	if s.Error != "" {
+		fmt.Fprintf(&b, "\n> %s", s.Error)
+	} else {
+		fmt.Fprintf(&b, "\n%d training and %d validation images, %d annotations in %d classes",
+			s.Counts.TrainImages, s.Counts.ValImages, s.Counts.Annotations, s.Counts.Classes)
+		if s.Counts.Skipped > 0 {
+			fmt.Fprintf(&b, ", %d skipped", s.Counts.Skipped)
+		}
+		if s.DatasetVersion != "" {
+			fmt.Fprintf(&b, " (version %s)", s.DatasetVersion)
+		}
+	}
+	return b.String()
+}
+
+// isSlackWebhook reports whether a URL is a Slack incoming webhook, which
+// takes a message rather than the summary
+func isSlackWebhook(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && strings.EqualFold(u.Hostname(), "hooks.slack.com")
+}
+
+// notify posts the summary of the finished conversion to -notify-url. A
+// failed notification is only a warning, so it never changes the result of
+// the conversion.
+func (c *Converter) notify(err error) {
+	summary := c.conversionSummary(err)
+	var body any = summary
+	if isSlackWebhook(c.config.NotifyURL) {
+		body = map[string]string{"text": slackMessage(summary)}
+	}
+	if err := postJSON(c.config.NotifyURL, body); err != nil {
+		fmt.Fprintf(c.out, "Warning: failed to send the notification: %v\n", err)
+	}
+}
+
+// postJSON posts a JSON document to a URL, expecting a 2xx response
+func postJSON(rawURL string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	// The conversion's context may be cancelled, and interruptions are
+	// reported too
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("POST %s: %w", redactURL(rawURL), urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("POST %s: %s", redactURL(rawURL), resp.Status)
+	}
+	return nil
+}
+
+// redactURL drops the path and query of a webhook URL, which usually hold
+// its secret, for messages
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "webhook"
+	}
+	return u.Scheme + "://" + u.Host + "/..."
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordWebhook serves a webhook that records the JSON body it receives
+func recordWebhook(t *testing.T, body *map[string]any) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			t.Errorf("Invalid notification body: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestConvertNotifiesSuccess(t *testing.T) {
+	var body map[string]any
+	server := recordWebhook(t, &body)
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, NotifyURL: server.URL})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	if body["status"] != NotifySuccess || body["output"] != outputDir || body["error"] != nil {
+		t.Errorf("Expected a success summary, got %v", body)
+	}
+	counts, _ := body["counts"].(map[string]any)
+	if counts["train_images"] != 3.0 || counts["annotations"] != 5.0 || counts["classes"] != 2.0 {
+		t.Errorf("Expected 3 images, 5 annotations and 2 classes, got %v", counts)
+	}
+}
+
+func TestConvertNotifiesFailure(t *testing.T) {
+	var body map[string]any
+	server := recordWebhook(t, &body)
+
+	converter := NewConverter(Config{SourceDir: filepath.Join(t.TempDir(), "missing"), OutputDir: t.TempDir(), NotifyURL: server.URL})
+	converter.SetOutput(&strings.Builder{})
+	if err := converter.Convert(); err == nil {
+		t.Fatal("Expected the conversion to fail")
+	}
+	if body["status"] != NotifyFailure || body["error"] == nil {
+		t.Errorf("Expected a failure summary with the error, got %v", body)
+	}
+}
+
+func TestSlackMessage(t *testing.T) {
+	summary := ConversionSummary{Status: NotifyPartial, Source: "export", Output: "dataset", DurationSeconds: 61.4,
+		Counts: ProvenanceCounts{TrainImages: 80, ValImages: 20, Annotations: 300, Classes: 4, Skipped: 2}}
+	want := ":warning: Label Studio to YOLO conversion partial: `export` -> `dataset` in 1m1s\n" +
+		"80 training and 20 validation images, 300 annotations in 4 classes, 2 skipped"
+	if got := slackMessage(summary); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if !isSlackWebhook("https://hooks.slack.com/services/T0/B0/x") || isSlackWebhook("https://example.com/hook") {
+		t.Error("Expected only hooks.slack.com URLs to be Slack webhooks")
+	}
+}
+
+func TestNotifyFailureIsAWarning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: filepath.Join(t.TempDir(), "out"), TrainSplit: 1, Seed: 42, NotifyURL: server.URL + "/secret"})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Expected a failed notification not to fail the conversion: %v", err)
+	}
+	if !strings.Contains(out.String(), "Warning: failed to send the notification") || strings.Contains(out.String(), "secret") {
+		t.Errorf("Expected a warning without the webhook path, got:\n%s", out.String())
+	}
+}
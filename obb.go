@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strconv"
+)
+
+// AnnotationOBB is an oriented box given by its four corners
+const AnnotationOBB = "obb"
+
+// TaskOBB marks an oriented bounding box dataset
+const TaskOBB = "obb"
+
+// parseOBBLine checks a YOLO-OBB label line (class_id x1 y1 x2 y2 x3 y3 x4 y4).
+// The corners must form a convex quadrilateral listed clockwise in image
+// coordinates, which is the order Label Studio's rotation produces and the
+// one DOTA-style tooling expects.
+func parseOBBLine(parts []string) string {
+	if len(parts) != 9 {
+		return "Wrong number of values"
+	}
+
+	if _, err := strconv.Atoi(parts[0]); err != nil {
+		return "Invalid class_id"
+	}
+
+	coords := make([]float64, 8)
+	for i := 1; i < len(parts); i++ {
+		coord, err := strconv.ParseFloat(parts[i], 64)
+		if err != nil {
+			return "Invalid coordinate"
+		}
+		if coord < 0 || coord > 1 {
+			return "Non-normalized coordinates"
+		}
+		coords[i-1] = coord
+	}
+
+	area := signedArea(coords)
+	switch {
+	case area == 0:
+		return "Degenerate oriented box"
+	case area < 0:
+		return "Counter-clockwise oriented box corners"
+	case !isConvex(coords):
+		return "Non-convex oriented box"
+	}
+	return ""
+}
+
+// isConvex reports whether every corner of a polygon turns the same way
+func isConvex(coords []float64) bool {
+	n := len(coords) / 2
+	sign := 0.0
+	for i := 0; i < n; i++ {
+		a, b, c := i, (i+1)%n, (i+2)%n
+		cross := (coords[2*b]-coords[2*a])*(coords[2*c+1]-coords[2*b+1]) -
+			(coords[2*b+1]-coords[2*a+1])*(coords[2*c]-coords[2*b])
+		if cross == 0 {
+			continue
+		}
+		if sign != 0 && (cross > 0) != (sign > 0) {
+			return false
+		}
+		sign = cross
+	}
+	return true
+}
+
+// obbTolerance is how far outside the image a corner may fall, from rounding
+// in Label Studio's percentages, and still be clamped to its edge
+const obbTolerance = 1e-4
+
+// obbCoords returns the clockwise normalized corners of a rectangle result.
+// It returns false when a corner lies outside the image: clamping corners one
+// by one would distort the box, and clipping it leaves no rectangle.
+func obbCoords(result LSResult) ([]float64, bool) {
+	corners := rotatedCorners(result)
+	coords := make([]float64, 0, 8)
+	for _, p := range corners {
+		for _, v := range p {
+			if v < -obbTolerance || v > 1+obbTolerance {
+				return nil, false
+			}
+		}
+		coords = append(coords, clamp01(p[0]), clamp01(p[1]))
+	}
+	return coords, true
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseOBBLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		warning string
+	}{
+		{"0 0.1 0.1 0.5 0.1 0.5 0.3 0.1 0.3", ""},
+		{"0 0.1 0.1 0.1 0.3 0.5 0.3 0.5 0.1", "Counter-clockwise oriented box corners"},
+		{"0 0.1 0.1 0.5 0.1 0.2 0.2 0.1 0.3", "Non-convex oriented box"},
+		{"0 0.1 0.1 0.5 0.1 0.5 0.1 0.1 0.1", "Degenerate oriented box"},
+		{"0 0.5 0.5 0.3 0.3", "Wrong number of values"},
+		{"0 0.1 0.1 1.5 0.1 0.5 0.3 0.1 0.3", "Non-normalized coordinates"},
+		{"a 0.1 0.1 0.5 0.1 0.5 0.3 0.1 0.3", "Invalid class_id"},
+	}
+
+	for _, tt := range tests {
+		if warning := parseOBBLine(strings.Fields(tt.line)); warning != tt.warning {
+			t.Errorf("parseOBBLine(%q) = %q, expected %q", tt.line, warning, tt.warning)
+		}
+	}
+}
+
+func TestLabelStudioOBBConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sourceDir, "images"), 0755); err != nil {
+		t.Fatalf("Failed to create images directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "images", "ship.jpg"), []byte("fake image data"), 0644); err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+
+	// A 200x100 pixel box rotated 90 degrees in a 400x200 image
+	tasks := []LSTask{{
+		ID:   1,
+		Data: map[string]interface{}{"image": "ship.jpg"},
+		Annotations: []LSAnnotation{{Result: []LSResult{
+			{
+				Type:           "rectanglelabels",
+				OriginalWidth:  400,
+				OriginalHeight: 200,
+				Value:          LSValue{X: 50, Y: 0, Width: 50, Height: 50, Rotation: 90, RectangleLabels: []string{"ship"}},
+			},
+			{
+				Type:  "polygonlabels",
+				Value: LSValue{Points: [][]float64{{10, 10}, {50, 10}, {30, 60}}, PolygonLabels: []string{"ship"}},
+			},
+		}}},
+	}}
+	data, _ := json.Marshal(tasks)
+	if err := os.WriteFile(filepath.Join(sourceDir, "export.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputLSJSON, OBB: true})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	label, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", "ship.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read label: %v", err)
+	}
+	expected := "0 0.500000 0.000000 0.500000 1.000000 0.250000 1.000000 0.250000 0.000000\n"
+	if string(label) != expected {
+		t.Errorf("Expected OBB label %q, got %q", expected, label)
+	}
+	if warning := parseOBBLine(strings.Fields(expected)); warning != "" {
+		t.Errorf("Converted OBB label is invalid: %s", warning)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read data.yaml: %v", err)
+	}
+	if !strings.Contains(string(content), "task: obb") {
+		t.Errorf("Expected data.yaml to mark an OBB dataset, got:\n%s", content)
+	}
+	if !strings.Contains(out.String(), "Skipped 1 unsupported polygonlabels results") {
+		t.Errorf("Expected polygons to be skipped in OBB mode, got:\n%s", out.String())
+	}
+}
+
+func TestOBBOutsideImageSkipped(t *testing.T) {
+	builder := newLabelBuilder(nil, 0, nil)
+	builder.obb = true
+	var out strings.Builder
+	converter := NewConverter(Config{})
+	converter.SetOutput(&out)
+
+	// The second box, rotated 45 degrees near the right edge, pokes out of
+	// the image and is skipped instead of having its corners clamped
+	lines := builder.taskLines(converter, 1, []LSResult{
+		{Type: "rectanglelabels", OriginalWidth: 100, OriginalHeight: 100, Value: LSValue{X: 30, Y: 10, Width: 20, Height: 20, Rotation: 45, RectangleLabels: []string{"ship"}}},
+		{Type: "rectanglelabels", OriginalWidth: 100, OriginalHeight: 100, Value: LSValue{X: 80, Y: 10, Width: 30, Height: 20, Rotation: 45, RectangleLabels: []string{"buoy"}}},
+	})
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "0 0.300000 0.100000 ") {
+		t.Errorf("Expected only the box inside the image, got %q", lines)
+	}
+	if converter.skipped != 1 || !strings.Contains(out.String(), "Skipping oriented box in task 1: it extends outside the image") {
+		t.Errorf("Expected the box outside the image to be skipped with a warning, got %d skipped:\n%s", converter.skipped, out.String())
+	}
+	if strings.Join(builder.classes, ",") != "ship" {
+		t.Errorf("Expected only the ship class, got %v", builder.classes)
+	}
+}
+
+func TestOBBRejectsSkeleton(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: t.TempDir(), OBB: true, SkeletonFile: "skeleton.yaml"})
+	converter.SetOutput(&strings.Builder{})
+	if err := converter.Convert(); err == nil {
+		t.Error("Expected error when combining -obb and -skeleton")
+	}
+}
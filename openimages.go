@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Open Images annotation files, matched in the source directory: box CSVs
+// such as train-annotations-bbox.csv and the class descriptions that name
+// the label MIDs, such as oidv7-class-descriptions-boxable.csv
+const (
+	openImagesBoxFiles   = "*annotations-bbox.csv"
+	openImagesClassFiles = "*class-descriptions*.csv"
+)
+
+// openImagesBoxColumns are the columns read from box CSVs
+var openImagesBoxColumns = []string{"ImageID", "LabelName", "XMin", "XMax", "YMin", "YMax"}
+
+// loadOpenImages converts the Open Images box CSVs in the source directory
+// into YOLO label files for the images present in its images/ directory.
+// Only boxes of the chosen classes are kept, and images without any are
+// left out, so a few classes can be picked from the full annotation files.
+func (c *Converter) loadOpenImages() ([]string, []LabelPair, error) {
+	descriptions, err := c.loadOpenImagesClasses()
+	if err != nil {
+		return nil, nil, err
+	}
+	classes, classIDs, err := c.openImagesSubset(descriptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	images, err := openImagesFiles(filepath.Join(c.config.SourceDir, "images"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	boxFiles, err := filepath.Glob(filepath.Join(c.config.SourceDir, openImagesBoxFiles))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(boxFiles) == 0 {
+		return nil, nil, fmt.Errorf("%w: no Open Images box annotations (%s) found in %s", ErrInvalidSource, openImagesBoxFiles, c.config.SourceDir)
+	}
+
+	lines := make(map[string][]string)
+	missing := make(map[string]bool)
+	for _, path := range boxFiles {
+		boxes, err := c.readOpenImagesBoxes(path, classIDs, images, lines, missing)
+		if err != nil {
+			return nil, nil, err
+		}
+		fmt.Fprintf(c.out, "Loaded %d boxes of the chosen classes from %s\n", boxes, path)
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(c.out, "Warning: %d annotated images of the chosen classes are not in images/\n", len(missing))
+	}
+
+	stagingDir, err := c.stagingDirectory()
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]string, 0, len(lines))
+	for id := range lines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	pairs := make([]LabelPair, 0, len(ids))
+	for _, id := range ids {
+		labelPath := filepath.Join(stagingDir, id+".txt")
+		if err := os.WriteFile(labelPath, []byte(strings.Join(lines[id], "")), 0644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write label %s: %w", labelPath, err)
+		}
+		pairs = append(pairs, LabelPair{ImagePath: images[id], LabelPath: labelPath})
+	}
+
+	fmt.Fprintf(c.out, "Found %d classes: %v\n", len(classes), classes)
+	fmt.Fprintf(c.out, "Found %d image-label pairs\n", len(pairs))
+	return classes, pairs, nil
+}
+
+// openImagesClass is a label MID with its display name
+type openImagesClass struct {
+	mid  string
+	name string
+}
+
+// loadOpenImagesClasses reads the class descriptions, which come with or
+// without a LabelName,DisplayName header
+func (c *Converter) loadOpenImagesClasses() ([]openImagesClass, error) {
+	matches, err := filepath.Glob(filepath.Join(c.config.SourceDir, openImagesClassFiles))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("%w: expected one Open Images class description file (%s) in %s, found %d",
+			ErrInvalidSource, openImagesClassFiles, c.config.SourceDir, len(matches))
+	}
+
+	file, err := os.Open(matches[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to open class descriptions: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse class descriptions %s: %w", matches[0], err)
+	}
+	var classes []openImagesClass
+	for i, record := range records {
+		if len(record) < 2 || i == 0 && record[0] == "LabelName" {
+			continue
+		}
+		classes = append(classes, openImagesClass{mid: record[0], name: strings.TrimSpace(record[1])})
+	}
+	return classes, nil
+}
+
+// openImagesSubset returns the chosen classes and the class ID of each of
+// their MIDs. -openimages-classes picks display names, matched regardless
+// of case and numbered in the order given; classes.txt does the same when
+// the flag isn't set.
+func (c *Converter) openImagesSubset(descriptions []openImagesClass) ([]string, map[string]int, error) {
+	var chosen []string
+	if c.config.OpenImagesClasses != "" {
+		for _, name := range strings.Split(c.config.OpenImagesClasses, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				chosen = append(chosen, name)
+			}
+		}
+	} else if _, err := os.Stat(filepath.Join(c.config.SourceDir, "classes.txt")); err == nil {
+		if chosen, err = c.LoadClasses(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(chosen) == 0 {
+		return nil, nil, fmt.Errorf("choose the Open Images classes to convert with -openimages-classes or classes.txt")
+	}
+
+	classIDs := make(map[string]int)
+	var classes []string
+	for _, name := range chosen {
+		found := false
+		for _, class := range descriptions {
+			if strings.EqualFold(class.name, name) {
+				if !found {
+					classes = append(classes, class.name)
+					found = true
+				}
+				classIDs[class.mid] = len(classes) - 1
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("class %q is not in the Open Images class descriptions", name)
+		}
+	}
+	return classes, classIDs, nil
+}
+
+// openImagesFiles maps the image IDs of the images in dir, their file
+// names without extension, to their paths
+func openImagesFiles(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read images directory: %v", ErrInvalidSource, err)
+	}
+	images := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if !entry.IsDir() && imageExtensions[strings.ToLower(ext)] {
+			images[strings.TrimSuffix(entry.Name(), ext)] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return images, nil
+}
+
+// readOpenImagesBoxes reads a box CSV, adding the label lines of the chosen
+// classes to the images that are present and recording the missing ones.
+// The files have millions of rows, so they are read one record at a time.
+func (c *Converter) readOpenImagesBoxes(path string, classIDs map[string]int, images map[string]string, lines map[string][]string, missing map[string]bool) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open box annotations: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	index := make([]int, len(openImagesBoxColumns))
+	for i, name := range openImagesBoxColumns {
+		col, ok := columns[name]
+		if !ok {
+			return 0, fmt.Errorf("%s has no %s column", path, name)
+		}
+		index[i] = col
+	}
+
+	boxes := 0
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return boxes, nil
+		}
+		if err != nil {
+			return boxes, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := c.interrupted(); err != nil {
+			return boxes, err
+		}
+
+		classID, ok := classIDs[record[index[1]]]
+		if !ok {
+			continue
+		}
+		id := record[index[0]]
+		if _, ok := images[id]; !ok {
+			missing[id] = true
+			continue
+		}
+		var coords [4]float64
+		for i := range coords {
+			if coords[i], err = strconv.ParseFloat(record[index[2+i]], 64); err != nil {
+				return boxes, fmt.Errorf("%s:%d: invalid %s %q", path, line, openImagesBoxColumns[2+i], record[index[2+i]])
+			}
+		}
+		xmin, xmax, ymin, ymax := coords[0], coords[1], coords[2], coords[3]
+		lines[id] = append(lines[id], boxLine(classID, clippedBox(xmin, ymin, xmax, ymax)))
+		boxes++
+	}
+}
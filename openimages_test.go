@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createOpenImagesSource writes class descriptions without a header, a
+// train and a validation box CSV and three of the annotated images
+func createOpenImagesSource(t *testing.T, sourceDir string) {
+	files := map[string]string{
+		"oidv7-class-descriptions-boxable.csv": "/m/0k4j,Car\n/m/01g317,Person\n/m/0199g,Bicycle\n",
+		"train-annotations-bbox.csv": "ImageID,Source,LabelName,Confidence,XMin,XMax,YMin,YMax,IsOccluded,IsTruncated,IsGroupOf,IsDepiction,IsInside\n" +
+			"000a1,xclick,/m/0k4j,1,0.1,0.5,0.2,0.6,0,0,0,0,0\n" +
+			"000a1,xclick,/m/01g317,1,0.6,0.8,0.1,0.9,1,0,0,0,0\n" +
+			"000a1,xclick,/m/0199g,1,0.0,0.2,0.0,0.2,0,0,0,0,0\n" +
+			"000b2,xclick,/m/0199g,1,0.0,0.2,0.0,0.2,0,0,0,0,0\n" +
+			"000c3,xclick,/m/0k4j,1,0.0,1.0,0.0,1.0,0,0,0,0,0\n",
+		"validation-annotations-bbox.csv": "ImageID,Source,LabelName,Confidence,XMin,XMax,YMin,YMax,IsOccluded,IsTruncated,IsGroupOf,IsDepiction,IsInside\n" +
+			"000d4,xclick,/m/01g317,1,0.25,0.75,0.25,0.75,0,0,0,0,0\n" +
+			"000e5,xclick,/m/01g317,1,0.25,0.75,0.25,0.75,0,0,0,0,0\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(sourceDir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"000a1.jpg", "000b2.jpg", "000d4.jpg"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, "images", name), []byte("fake image data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOpenImagesConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	createOpenImagesSource(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42,
+		InputFormat: InputOpenImages, OpenImagesClasses: "person, car"})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	// Classes are numbered in the chosen order; other classes are dropped
+	expected := map[string]string{
+		"000a1.txt": "1 0.300000 0.400000 0.400000 0.400000\n0 0.700000 0.500000 0.200000 0.800000\n",
+		"000d4.txt": "0 0.500000 0.500000 0.500000 0.500000\n",
+	}
+	entries, _ := os.ReadDir(filepath.Join(outputDir, "labels", "train"))
+	if len(entries) != len(expected) {
+		t.Errorf("Expected only the images with chosen classes, got %d labels", len(entries))
+	}
+	for name, want := range expected {
+		got, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", name))
+		if err != nil {
+			t.Errorf("Missing label %s: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("Label %s: expected %q, got %q", name, want, got)
+		}
+	}
+
+	log := out.String()
+	for _, want := range []string{"Found 2 classes: [Person Car]", "2 annotated images of the chosen classes are not in images/"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, log)
+		}
+	}
+}
+
+func TestOpenImagesClassSelection(t *testing.T) {
+	sourceDir := t.TempDir()
+	createOpenImagesSource(t, sourceDir)
+
+	converter := NewConverter(Config{SourceDir: sourceDir, InputFormat: InputOpenImages})
+	converter.SetOutput(&strings.Builder{})
+	if _, _, err := converter.loadSource(); err == nil || !strings.Contains(err.Error(), "-openimages-classes") {
+		t.Errorf("Expected an error asking for classes, got %v", err)
+	}
+
+	converter = NewConverter(Config{SourceDir: sourceDir, InputFormat: InputOpenImages, OpenImagesClasses: "Unicorn"})
+	converter.SetOutput(&strings.Builder{})
+	if _, _, err := converter.loadSource(); err == nil || !strings.Contains(err.Error(), `"Unicorn"`) {
+		t.Errorf("Expected an error for an unknown class, got %v", err)
+	}
+
+	// classes.txt chooses the classes when the flag isn't set
+	if err := os.WriteFile(filepath.Join(sourceDir, "classes.txt"), []byte("bicycle\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	converter = NewConverter(Config{SourceDir: sourceDir, InputFormat: InputOpenImages})
+	converter.SetOutput(&strings.Builder{})
+	classes, pairs, err := converter.loadSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer converter.removeStaging()
+	if strings.Join(classes, ",") != "Bicycle" || len(pairs) != 2 {
+		t.Errorf("Expected Bicycle in 2 images, got %v in %d", classes, len(pairs))
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FindOrphanLabels returns the label files in labels/ that no image pairs
+// with, sorted by name. Labels of images skipped by -include and -exclude
+// are not orphans.
+func (c *Converter) FindOrphanLabels(pairs []LabelPair) ([]string, error) {
+	labelsDir := filepath.Join(c.config.SourceDir, "labels")
+	entries, err := os.ReadDir(labelsDir)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning labels directory: %w", err)
+	}
+
+	paired := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		paired[filepath.Base(pair.LabelPath)] = true
+	}
+
+	var orphans []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(name), ".txt") || paired[name] || c.filteredLabels[name] {
+			continue
+		}
+		orphans = append(orphans, filepath.Join(labelsDir, name))
+	}
+	sort.Strings(orphans)
+	return orphans, nil
+}
+
+// checkOrphanLabels reports label files without an image. These usually
+// mean images failed to export, so they stop the conversion unless
+// -allow-orphan-labels is set.
+func (c *Converter) checkOrphanLabels(pairs []LabelPair) error {
+	orphans, err := c.FindOrphanLabels(pairs)
+	if err != nil {
+		return err
+	}
+	for _, orphan := range orphans {
+		fmt.Fprintf(c.out, "Warning: No image file found for %s\n", filepath.Base(orphan))
+	}
+	if len(orphans) > 0 && !c.config.AllowOrphanLabels {
+		return fmt.Errorf("found %d label files without images; images may have failed to export (use -allow-orphan-labels to continue)", len(orphans))
+	}
+	c.skipped += len(orphans)
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOrphanLabelsBlockConversion(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "labels", "lost.txt"), []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{SourceDir: tempDir, OutputDir: filepath.Join(tempDir, "out"), TrainSplit: 0.8, Seed: 42}
+	converter := NewConverter(config)
+	converter.SetOutput(io.Discard)
+	pairs, err := converter.GetImageLabelPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphans, err := converter.FindOrphanLabels(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 1 || filepath.Base(orphans[0]) != "lost.txt" {
+		t.Fatalf("Expected lost.txt to be an orphan, got %v", orphans)
+	}
+
+	err = converter.Convert()
+	if err == nil || !strings.Contains(err.Error(), "1 label files without images") {
+		t.Fatalf("Expected orphan labels to stop the conversion, got %v", err)
+	}
+
+	config.AllowOrphanLabels = true
+	converter = NewConverter(config)
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Expected -allow-orphan-labels to convert, got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Physical types of Parquet columns
+const (
+	parquetInt32     = 1
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// parquetColumn is a required column of a Parquet file; the slice of its
+// type holds the values
+type parquetColumn struct {
+	name    string
+	kind    int
+	strings []string
+	int32s  []int32
+	doubles []float64
+}
+
+// plain encodes the values of the column in the PLAIN encoding
+func (col *parquetColumn) plain() []byte {
+	var b []byte
+	switch col.kind {
+	case parquetByteArray:
+		for _, v := range col.strings {
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+			b = append(b, v...)
+		}
+	case parquetInt32:
+		for _, v := range col.int32s {
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		}
+	case parquetDouble:
+		for _, v := range col.doubles {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+		}
+	}
+	return b
+}
+
+// writeParquet writes the columns as a Parquet file of one row group, with
+// one uncompressed, PLAIN-encoded data page per column. Byte array columns
+// are annotated as UTF-8 strings. That is the simplest file every Parquet
+// reader accepts, and enough for annotation tables.
+func writeParquet(w io.Writer, columns []parquetColumn, rows int) error {
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+
+	var chunks thriftWriter
+	chunks.beginList(thriftStruct, len(columns))
+	total := 0
+	for i := range columns {
+		col := &columns[i]
+		values := col.plain()
+
+		var page thriftWriter
+		page.i32(1, 0) // DATA_PAGE
+		page.i32(2, int32(len(values)))
+		page.i32(3, int32(len(values)))
+		page.beginStruct(5) // DataPageHeader
+		page.i32(1, int32(rows))
+		page.i32(2, 0) // PLAIN
+		page.i32(3, 3) // RLE definition levels, unused by required columns
+		page.i32(4, 3) // RLE repetition levels
+		page.endStruct()
+		page.stop()
+
+		offset := int64(file.Len())
+		size := int64(page.buf.Len() + len(values))
+		file.Write(page.buf.Bytes())
+		file.Write(values)
+		total += int(size)
+
+		chunks.beginElement()
+		chunks.i64(2, offset)
+		chunks.beginStruct(3) // ColumnMetaData
+		chunks.i32(1, int32(col.kind))
+		chunks.field(2, thriftList)
+		chunks.beginList(thriftI32, 1)
+		chunks.varint(0) // PLAIN
+		chunks.field(3, thriftList)
+		chunks.beginList(thriftBinary, 1)
+		chunks.binary(col.name)
+		chunks.i32(4, 0) // UNCOMPRESSED
+		chunks.i64(5, int64(rows))
+		chunks.i64(6, size)
+		chunks.i64(7, size)
+		chunks.i64(9, offset)
+		chunks.endStruct()
+		chunks.endElement()
+	}
+
+	var meta thriftWriter
+	meta.i32(1, 1)
+	meta.field(2, thriftList)
+	meta.beginList(thriftStruct, len(columns)+1)
+	meta.beginElement()
+	meta.str(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.endElement()
+	for _, col := range columns {
+		meta.beginElement()
+		meta.i32(1, int32(col.kind))
+		meta.i32(3, 0) // REQUIRED
+		meta.str(4, col.name)
+		if col.kind == parquetByteArray {
+			meta.i32(6, 0) // UTF8
+		}
+		meta.endElement()
+	}
+	meta.i64(3, int64(rows))
+	meta.field(4, thriftList)
+	meta.beginList(thriftStruct, 1)
+	meta.beginElement()
+	meta.field(1, thriftList)
+	meta.buf.Write(chunks.buf.Bytes())
+	meta.i64(2, int64(total))
+	meta.i64(3, int64(rows))
+	meta.endElement()
+	meta.str(6, "labelstudio-to-yolo")
+	meta.stop()
+
+	file.Write(meta.buf.Bytes())
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(meta.buf.Len())))
+	file.WriteString("PAR1")
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// Types of the Thrift compact protocol, which encodes Parquet metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol. Field IDs
+// are written as deltas from the previous field of the same struct, so
+// nested structs keep a stack of them.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int
+	id   int
+}
+
+func (t *thriftWriter) varint(v int64) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(v<<1^v>>63)))
+}
+
+func (t *thriftWriter) field(id, kind int) {
+	if delta := id - t.id; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta<<4 | kind))
+	} else {
+		t.buf.WriteByte(byte(kind))
+		t.varint(int64(id))
+	}
+	t.id = id
+}
+
+func (t *thriftWriter) i32(id int, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) str(id int, s string) {
+	t.field(id, thriftBinary)
+	t.binary(s)
+}
+
+func (t *thriftWriter) binary(s string) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) beginList(kind, size int) {
+	if size < 15 {
+		t.buf.WriteByte(byte(size<<4 | kind))
+		return
+	}
+	t.buf.WriteByte(byte(0xf0 | kind))
+	t.buf.Write(binary.AppendUvarint(nil, uint64(size)))
+}
+
+// beginStruct starts a struct field
+func (t *thriftWriter) beginStruct(id int) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+func (t *thriftWriter) endStruct() {
+	t.endElement()
+}
+
+// beginElement starts a struct that is a list element
+func (t *thriftWriter) beginElement() {
+	t.last = append(t.last, t.id)
+	t.id = 0
+}
+
+func (t *thriftWriter) endElement() {
+	t.stop()
+	t.id = t.last[len(t.last)-1]
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
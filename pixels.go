@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// coordinateAxes returns, for each value after the class ID of a line with n
+// values, 'x' or 'y' for coordinates and 0 for keypoint visibility flags
+func (c *Converter) coordinateAxes(n int) []byte {
+	axes := make([]byte, n)
+	for i := range axes {
+		position := i
+		if c.skeleton != nil && c.skeleton.Dims == 3 && i >= 4 {
+			// Keypoints are x, y, visibility triples after the box
+			if (i-4)%3 == 2 {
+				continue
+			}
+			position = (i - 4) % 3
+		}
+		axes[i] = "xy"[position%2]
+	}
+	return axes
+}
+
+// normalizeLine divides the coordinates of a label line that uses pixel
+// values by the image size. It returns false when the line is malformed or
+// already normalized.
+func (c *Converter) normalizeLine(parts []string, width, height int) (string, bool) {
+	if len(parts) < 5 {
+		return "", false
+	}
+	values := make([]float64, len(parts)-1)
+	for i := range values {
+		v, err := strconv.ParseFloat(parts[i+1], 64)
+		if err != nil || v < 0 {
+			return "", false
+		}
+		values[i] = v
+	}
+
+	axes := c.coordinateAxes(len(values))
+	pixels := false
+	for i, axis := range axes {
+		if axis != 0 && values[i] > 1 {
+			pixels = true
+		}
+	}
+	if !pixels {
+		return "", false
+	}
+
+	fields := []string{parts[0]}
+	for i, v := range values {
+		switch axes[i] {
+		case 'x':
+			fields = append(fields, formatCoord(v/float64(width)))
+		case 'y':
+			fields = append(fields, formatCoord(v/float64(height)))
+		default:
+			fields = append(fields, parts[i+1])
+		}
+	}
+	return strings.Join(fields, " "), true
+}
+
+// normalizePixelLabels rewrites label files containing pixel coordinates,
+// normalized by their image's size
+func (c *Converter) normalizePixelLabels(pairs []LabelPair) ([]LabelPair, error) {
+	pairs, normalized, files, err := c.rewriteLabels(pairs, "normalized", func(pair LabelPair, lines []string) ([]string, int) {
+		width, height := 0, 0
+		changed := 0
+		for j, line := range lines {
+			parts := strings.Fields(line)
+			if len(parts) == 0 {
+				continue
+			}
+			if width == 0 {
+				var err error
+				if width, height, err = readImageSize(pair.ImagePath); err != nil {
+					fmt.Fprintf(c.out, "Warning: Cannot read size of %s to normalize its labels: %v\n", filepath.Base(pair.ImagePath), err)
+					return lines, 0
+				}
+			}
+			if fixed, ok := c.normalizeLine(parts, width, height); ok {
+				lines[j] = fixed
+				changed++
+			}
+		}
+		return lines, changed
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if normalized > 0 {
+		fmt.Fprintf(c.out, "Normalized %d pixel-coordinate lines in %d label files\n", normalized, files)
+	}
+	return pairs, nil
+}
+
+// suggestPixelNormalization points at -normalize-pixels when validation found
+// coordinates above 1
+func (c *Converter) suggestPixelNormalization() {
+	if c.config.NormalizePixels {
+		return
+	}
+	count := 0
+	for _, f := range c.findings {
+		if f.Rule == ruleID("Non-normalized coordinates") {
+			count++
+		}
+	}
+	if count > 0 {
+		fmt.Fprintf(c.out, "Hint: %d lines have coordinates outside 0-1; if they are pixel values, -normalize-pixels converts them using the image sizes\n", count)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeLine(t *testing.T) {
+	converter := NewConverter(Config{})
+	tests := []struct {
+		line string
+		want string
+		ok   bool
+	}{
+		{"0 320 240 64 48", "0 0.500000 0.500000 0.100000 0.100000", true},
+		{"1 64 48 128 48 128 96", "1 0.100000 0.100000 0.200000 0.100000 0.200000 0.200000", true},
+		{"0 0.5 0.5 0.1 0.1", "", false},
+		{"0 320 x 64 48", "", false},
+		{"0 -5 240 64 48", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := converter.normalizeLine(strings.Fields(tt.line), 640, 480)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("normalizeLine(%q) = %q, %v; expected %q, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeLineKeepsVisibility(t *testing.T) {
+	converter := NewConverter(Config{})
+	converter.skeleton = &Skeleton{Keypoints: []string{"nose", "tail"}, Dims: 3}
+
+	got, ok := converter.normalizeLine(strings.Fields("0 320 240 64 48 300 200 2 0 0 0"), 640, 480)
+	want := "0 0.500000 0.500000 0.100000 0.100000 0.468750 0.416667 2 0.000000 0.000000 0"
+	if !ok || got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestNormalizePixelLabels(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "street.png")
+	writeTestImage(t, imagePath, 200, 100)
+
+	pixelLabel := filepath.Join(dir, "street.txt")
+	if err := os.WriteFile(pixelLabel, []byte("0 100 50 20 10\n1 0.5 0.5 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cleanLabel := filepath.Join(dir, "clean.txt")
+	if err := os.WriteFile(cleanLabel, []byte("0 0.5 0.5 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{NormalizePixels: true})
+	var out strings.Builder
+	converter.SetOutput(&out)
+	defer converter.removeStaging()
+
+	pairs, err := converter.normalizePixelLabels([]LabelPair{
+		{ImagePath: imagePath, LabelPath: pixelLabel},
+		{ImagePath: filepath.Join(dir, "clean.png"), LabelPath: cleanLabel},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pairs[1].LabelPath != cleanLabel {
+		t.Error("Expected normalized labels to be left alone")
+	}
+	if pairs[0].LabelPath == pixelLabel {
+		t.Fatal("Expected the pixel label to be rewritten")
+	}
+	data, err := os.ReadFile(pairs[0].LabelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0 0.500000 0.500000 0.100000 0.100000\n1 0.5 0.5 0.1 0.1\n"; string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+	if !strings.Contains(out.String(), "Normalized 1 pixel-coordinate lines in 1 label files") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+
+	// The source label is untouched
+	if original, _ := os.ReadFile(pixelLabel); !strings.HasPrefix(string(original), "0 100 50") {
+		t.Errorf("Expected the source label to be unchanged, got %q", original)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// readImageSize reads the pixel size of an image from its header, as
+// displayed: width and height are swapped for EXIF orientations that turn the
+// image sideways
+func readImageSize(imagePath string) (int, int, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	if exifOrientation(imagePath) >= 5 {
+		return config.Height, config.Width, nil
+	}
+	return config.Width, config.Height, nil
+}
+
+// checksPlausibility reports whether any check needs the image sizes
+func (c *Converter) checksPlausibility() bool {
+	return c.config.MinBoxPixels > 0 || c.config.MinImageSize > 0 || c.config.MaxAspectRatio > 0
+}
+
+// flag records a warning about a file or line that stays valid
+func (r *labelFileResult) flag(c *Converter, pair LabelPair, line int, rule, message, content string) {
+	location := filepath.Base(pair.LabelPath)
+	if line > 0 {
+		location = fmt.Sprintf("%s:%d", location, line)
+	}
+	r.messages = append(r.messages, fmt.Sprintf("Warning: %s in %s\n", message, location))
+	r.findings = append(r.findings, c.newFinding(pair, line, rule, message, content))
+}
+
+// checkImage flags images below -min-image-size. It returns the image size,
+// or zeros when the image can't be read.
+func (c *Converter) checkImage(pair LabelPair, result *labelFileResult) (int, int) {
+	width, height, err := readImageSize(pair.ImagePath)
+	if err != nil {
+		result.flag(c, pair, 0, "unreadable-image", fmt.Sprintf("Cannot read size of %s", filepath.Base(pair.ImagePath)), "")
+		result.stats.PlausibilityWarnings++
+		return 0, 0
+	}
+	if minSize := c.config.MinImageSize; minSize > 0 && (width < minSize || height < minSize) {
+		result.flag(c, pair, 0, "small-image", fmt.Sprintf("Image of %dx%d pixels is below %d pixels", width, height, minSize), "")
+		result.stats.PlausibilityWarnings++
+	}
+	return width, height
+}
+
+// checkRegion flags regions smaller than -min-box-pixels or with an aspect
+// ratio beyond -max-aspect-ratio, measured in pixels
+func (c *Converter) checkRegion(pair LabelPair, result *labelFileResult, lineNum int, line, kind string, parts []string, width, height int) {
+	bounds := labelBounds(kind, parts)
+	w, h := bounds[2]*float64(width), bounds[3]*float64(height)
+
+	if minSize := float64(c.config.MinBoxPixels); minSize > 0 && (w < minSize || h < minSize) {
+		result.flag(c, pair, lineNum, "small-box", fmt.Sprintf("Region of %.1fx%.1f pixels is below %d pixels", w, h, c.config.MinBoxPixels), line)
+		result.stats.PlausibilityWarnings++
+		return
+	}
+	if maxRatio := c.config.MaxAspectRatio; maxRatio > 0 && w > 0 && h > 0 {
+		if ratio := math.Max(w/h, h/w); ratio > maxRatio {
+			result.flag(c, pair, lineNum, "extreme-aspect-ratio", fmt.Sprintf("Region aspect ratio %.1f exceeds %.1f", ratio, maxRatio), line)
+			result.stats.PlausibilityWarnings++
+		}
+	}
+}
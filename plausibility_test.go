@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlausibilityChecks(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "small.png")
+	writeTestImage(t, imagePath, 200, 100)
+	labelPath := filepath.Join(dir, "small.txt")
+	content := "0 0.5 0.5 0.5 0.5\n" + // 100x50 pixels, fine
+		"0 0.5 0.5 0.01 0.5\n" + // 2 pixels wide
+		"0 0.5 0.5 0.9 0.05\n" // 180x5 pixels, aspect ratio 36
+	if err := os.WriteFile(labelPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fakeLabel := filepath.Join(dir, "fake.txt")
+	if err := os.WriteFile(fakeLabel, []byte("0 0.5 0.5 0.5 0.5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pairs := []LabelPair{
+		{ImagePath: imagePath, LabelPath: labelPath},
+		{ImagePath: filepath.Join(dir, "missing.jpg"), LabelPath: fakeLabel},
+	}
+
+	converter := NewConverter(Config{SourceDir: dir, MinBoxPixels: 4, MinImageSize: 128, MaxAspectRatio: 20})
+	converter.SetOutput(io.Discard)
+	stats, err := converter.ValidateLabels(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rules []string
+	for _, f := range converter.findings {
+		rules = append(rules, f.Rule)
+	}
+	expected := []string{"small-image", "small-box", "extreme-aspect-ratio", "unreadable-image"}
+	if len(rules) != len(expected) {
+		t.Fatalf("Expected findings %v, got %v", expected, rules)
+	}
+	for i := range expected {
+		if rules[i] != expected[i] {
+			t.Errorf("Finding %d: expected %s, got %s", i, expected[i], rules[i])
+		}
+	}
+
+	// Plausibility problems are warnings; the lines stay valid
+	if stats.PlausibilityWarnings != 4 || stats.InvalidLines != 0 || stats.TotalAnnotations != 4 {
+		t.Errorf("Unexpected stats %+v", *stats)
+	}
+}
+
+func TestPlausibilityChecksDisabledByDefault(t *testing.T) {
+	dir := t.TempDir()
+	labelPath := filepath.Join(dir, "label.txt")
+	if err := os.WriteFile(labelPath, []byte("0 0.5 0.5 0.001 0.5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter(Config{SourceDir: dir})
+	converter.SetOutput(io.Discard)
+	stats, err := converter.ValidateLabels([]LabelPair{{ImagePath: filepath.Join(dir, "missing.jpg"), LabelPath: labelPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.PlausibilityWarnings != 0 || len(converter.findings) != 0 {
+		t.Errorf("Expected no plausibility checks without thresholds, got %+v", converter.findings)
+	}
+}
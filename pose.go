@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AnnotationPose is a box followed by its keypoints
+const AnnotationPose = "pose"
+
+// TaskPose marks a keypoint dataset
+const TaskPose = "pose"
+
+// Skeleton describes the keypoints of a pose dataset, loaded from the file
+// given with -skeleton:
+//
+//	keypoints: [nose, left_eye, right_eye]
+//	flip:
+//	  left_eye: right_eye
+//	dims: 3
+type Skeleton struct {
+	// Keypoints are the Label Studio keypoint labels in label file order
+	Keypoints []string `yaml:"keypoints"`
+	// Flip pairs keypoints that swap under a horizontal flip; listing one
+	// direction is enough
+	Flip map[string]string `yaml:"flip"`
+	// Dims is 2 for x, y or 3 to add a visibility flag (default 3)
+	Dims int `yaml:"dims"`
+
+	index map[string]int
+}
+
+// LoadSkeleton reads and validates a skeleton definition file
+func LoadSkeleton(path string) (*Skeleton, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read skeleton: %w", err)
+	}
+
+	var s Skeleton
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse skeleton %s: %w", path, err)
+	}
+	if err := s.init(); err != nil {
+		return nil, fmt.Errorf("invalid skeleton %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// init validates the definition and builds the keypoint index
+func (s *Skeleton) init() error {
+	if len(s.Keypoints) == 0 {
+		return fmt.Errorf("no keypoints defined")
+	}
+	if s.Dims == 0 {
+		s.Dims = 3
+	}
+	if s.Dims != 2 && s.Dims != 3 {
+		return fmt.Errorf("dims must be 2 or 3, got %d", s.Dims)
+	}
+
+	s.index = make(map[string]int, len(s.Keypoints))
+	for i, name := range s.Keypoints {
+		if _, ok := s.index[name]; ok {
+			return fmt.Errorf("duplicate keypoint %q", name)
+		}
+		s.index[name] = i
+	}
+
+	for a, b := range s.Flip {
+		if _, ok := s.index[a]; !ok {
+			return fmt.Errorf("flip refers to unknown keypoint %q", a)
+		}
+		if _, ok := s.index[b]; !ok {
+			return fmt.Errorf("flip refers to unknown keypoint %q", b)
+		}
+		if other, ok := s.Flip[b]; ok && other != a {
+			return fmt.Errorf("keypoint %q flips to both %q and %q", b, a, other)
+		}
+	}
+	return nil
+}
+
+// KptShape returns the kpt_shape entry of data.yaml
+func (s *Skeleton) KptShape() []int {
+	return []int{len(s.Keypoints), s.Dims}
+}
+
+// FlipIdx returns the flip_idx entry of data.yaml: for each keypoint the
+// index of its mirror image, or its own index when it has none
+func (s *Skeleton) FlipIdx() []int {
+	idx := make([]int, len(s.Keypoints))
+	for i := range idx {
+		idx[i] = i
+	}
+	for a, b := range s.Flip {
+		idx[s.index[a]] = s.index[b]
+		idx[s.index[b]] = s.index[a]
+	}
+	return idx
+}
+
+// Index returns the position of a keypoint label
+func (s *Skeleton) Index(name string) (int, bool) {
+	i, ok := s.index[name]
+	return i, ok
+}
+
+// parsePoseLine checks a YOLO-pose label line
+// (class_id x_center y_center width height px1 py1 [v1] ...) against the
+// skeleton, returning a warning when the line is invalid
+func parsePoseLine(parts []string, s *Skeleton) string {
+	if len(parts) != 5+len(s.Keypoints)*s.Dims {
+		return "Wrong number of values"
+	}
+
+	if _, err := strconv.Atoi(parts[0]); err != nil {
+		return "Invalid class_id"
+	}
+
+	for i := 1; i < len(parts); i++ {
+		v, err := strconv.ParseFloat(parts[i], 64)
+		if err != nil {
+			return "Invalid coordinate"
+		}
+
+		// Visibility flags follow each keypoint's x and y when dims is 3
+		if i > 4 && s.Dims == 3 && (i-5)%3 == 2 {
+			if v != 0 && v != 1 && v != 2 {
+				return "Invalid keypoint visibility"
+			}
+			continue
+		}
+		if v < 0 || v > 1 {
+			return "Non-normalized coordinates"
+		}
+	}
+	return ""
+}
+
+// poseLines groups keypoint results with the rectangles they belong to and
+// formats one pose line per rectangle. A keypoint belongs to the rectangle
+// named by its parentID, or else to the smallest rectangle containing it.
+func (b *labelBuilder) poseLines(c *Converter, taskID int, results []LSResult) []string {
+	type poseBox struct {
+		classID   int
+		bounds    [4]float64
+		keypoints [][2]float64
+		found     []bool
+	}
+
+	s := b.skeleton
+	var boxes []*poseBox
+	byID := make(map[string]*poseBox)
+
+	for _, result := range results {
+		names := result.Value.LabelNames()
+		if result.Type != "rectanglelabels" || len(names) == 0 {
+			continue
+		}
+		box := &poseBox{
+			classID:   b.classID(names[0]),
+			bounds:    rectangleBounds(result),
+			keypoints: make([][2]float64, len(s.Keypoints)),
+			found:     make([]bool, len(s.Keypoints)),
+		}
+		boxes = append(boxes, box)
+		if result.ID != "" {
+			byID[result.ID] = box
+		}
+	}
+
+	for _, result := range results {
+		names := result.Value.LabelNames()
+		switch {
+		case result.Type == "rectanglelabels":
+			continue
+		case result.Type != "keypointlabels" || len(names) == 0:
+			b.unsupported[result.Type]++
+			continue
+		}
+
+		k, ok := s.Index(names[0])
+		if !ok {
+			fmt.Fprintf(c.out, "Warning: Keypoint %q in task %d is not in the skeleton\n", names[0], taskID)
+			continue
+		}
+
+		x, y := clamp01(result.Value.X/100), clamp01(result.Value.Y/100)
+		box := byID[result.ParentID]
+		if box == nil {
+			smallest := math.Inf(1)
+			for _, candidate := range boxes {
+				bx := candidate.bounds
+				inside := math.Abs(x-bx[0]) <= bx[2]/2 && math.Abs(y-bx[1]) <= bx[3]/2
+				if area := bx[2] * bx[3]; inside && area < smallest {
+					box, smallest = candidate, area
+				}
+			}
+		}
+		if box == nil {
+			fmt.Fprintf(c.out, "Warning: Keypoint %q in task %d is outside every box\n", names[0], taskID)
+			continue
+		}
+
+		if !box.found[k] {
+			box.keypoints[k] = [2]float64{x, y}
+			box.found[k] = true
+		}
+	}
+
+	lines := make([]string, 0, len(boxes))
+	for _, box := range boxes {
+		coords := append([]float64{}, box.bounds[:]...)
+		for k, p := range box.keypoints {
+			coords = append(coords, p[0], p[1])
+			if s.Dims == 3 {
+				// 2 marks a labeled visible keypoint, 0 one that wasn't placed
+				visibility := 0.0
+				if box.found[k] {
+					visibility = 2
+				}
+				coords = append(coords, visibility)
+			}
+		}
+		lines = append(lines, polygonLine(box.classID, coords))
+	}
+	return lines
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeSkeleton writes a skeleton definition and returns its path
+func writeSkeleton(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "skeleton.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write skeleton: %v", err)
+	}
+	return path
+}
+
+func TestLoadSkeleton(t *testing.T) {
+	s, err := LoadSkeleton(writeSkeleton(t, "keypoints: [nose, left_eye, right_eye, tail]\nflip:\n  left_eye: right_eye\n"))
+	if err != nil {
+		t.Fatalf("Failed to load skeleton: %v", err)
+	}
+	if shape := s.KptShape(); !reflect.DeepEqual(shape, []int{4, 3}) {
+		t.Errorf("Expected kpt_shape [4 3], got %v", shape)
+	}
+	if idx := s.FlipIdx(); !reflect.DeepEqual(idx, []int{0, 2, 1, 3}) {
+		t.Errorf("Expected flip_idx [0 2 1 3], got %v", idx)
+	}
+
+	invalid := []string{
+		"keypoints: []\n",
+		"keypoints: [a, a]\n",
+		"keypoints: [a, b]\ndims: 4\n",
+		"keypoints: [a, b]\nflip:\n  a: c\n",
+		"keypoints: [a, b, c]\nflip:\n  a: b\n  b: c\n",
+	}
+	for _, content := range invalid {
+		if _, err := LoadSkeleton(writeSkeleton(t, content)); err == nil {
+			t.Errorf("Expected error for skeleton %q", content)
+		}
+	}
+}
+
+func TestParsePoseLine(t *testing.T) {
+	s := &Skeleton{Keypoints: []string{"a", "b"}}
+	if err := s.init(); err != nil {
+		t.Fatalf("Failed to init skeleton: %v", err)
+	}
+
+	tests := []struct {
+		line    string
+		warning string
+	}{
+		{"0 0.5 0.5 0.2 0.2 0.4 0.4 2 0 0 0", ""},
+		{"0 0.5 0.5 0.2 0.2 0.4 0.4 1 0.6 0.6 2", ""},
+		{"0 0.5 0.5 0.2 0.2", "Wrong number of values"},
+		{"0 0.5 0.5 0.2 0.2 0.4 0.4 3 0 0 0", "Invalid keypoint visibility"},
+		{"0 0.5 0.5 0.2 0.2 1.4 0.4 2 0 0 0", "Non-normalized coordinates"},
+		{"x 0.5 0.5 0.2 0.2 0.4 0.4 2 0 0 0", "Invalid class_id"},
+	}
+	for _, tt := range tests {
+		if warning := parsePoseLine(strings.Fields(tt.line), s); warning != tt.warning {
+			t.Errorf("parsePoseLine(%q) = %q, expected %q", tt.line, warning, tt.warning)
+		}
+	}
+}
+
+func TestLabelStudioPoseConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sourceDir, "images"), 0755); err != nil {
+		t.Fatalf("Failed to create images directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "images", "people.jpg"), []byte("fake image data"), 0644); err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+
+	// Two people; the second one's left hand sits inside the first box but is
+	// linked to its own box through parentID
+	tasks := []LSTask{{
+		ID:   1,
+		Data: map[string]interface{}{"image": "people.jpg"},
+		Annotations: []LSAnnotation{{Result: []LSResult{
+			{ID: "p1", Type: "rectanglelabels", Value: LSValue{X: 0, Y: 0, Width: 50, Height: 100, RectangleLabels: []string{"person"}}},
+			{ID: "p2", Type: "rectanglelabels", Value: LSValue{X: 40, Y: 0, Width: 60, Height: 100, RectangleLabels: []string{"person"}}},
+			{Type: "keypointlabels", Value: LSValue{X: 20, Y: 30, Labels: []string{"head"}}},
+			{Type: "keypointlabels", Value: LSValue{X: 70, Y: 20, Labels: []string{"head"}}},
+			{Type: "keypointlabels", ParentID: "p2", Value: LSValue{X: 45, Y: 50, Labels: []string{"left_hand"}}},
+			{Type: "keypointlabels", Value: LSValue{X: 10, Y: 10, Labels: []string{"tail"}}},
+		}}},
+	}}
+	data, _ := json.Marshal(tasks)
+	if err := os.WriteFile(filepath.Join(sourceDir, "export.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+
+	skeleton := writeSkeleton(t, "keypoints: [head, left_hand, right_hand]\nflip:\n  left_hand: right_hand\n")
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputLSJSON, SkeletonFile: skeleton})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	label, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", "people.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read label: %v", err)
+	}
+	expected := "0 0.250000 0.500000 0.500000 1.000000 0.200000 0.300000 2.000000 0.000000 0.000000 0.000000 0.000000 0.000000 0.000000\n" +
+		"0 0.700000 0.500000 0.600000 1.000000 0.700000 0.200000 2.000000 0.450000 0.500000 2.000000 0.000000 0.000000 0.000000\n"
+	if string(label) != expected {
+		t.Errorf("Expected pose label:\n%s\ngot:\n%s", expected, label)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read data.yaml: %v", err)
+	}
+	for _, want := range []string{"task: pose", "kpt_shape: [3, 3]", "flip_idx: [0, 2, 1]"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected data.yaml to contain %q, got:\n%s", want, content)
+		}
+	}
+	if !strings.Contains(out.String(), `Keypoint "tail" in task 1 is not in the skeleton`) {
+		t.Errorf("Expected warning for unknown keypoint, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxPrecision is the number of decimal places labels are generated with
+// (see formatCoord), and so the most -precision can keep
+const maxPrecision = 6
+
+// validatePrecision checks -precision
+func (c *Converter) validatePrecision() error {
+	if c.config.Precision < 0 || c.config.Precision > maxPrecision {
+		return fmt.Errorf("-precision must be between 1 and %d", maxPrecision)
+	}
+	return nil
+}
+
+// isStagedLabel reports whether a label file was generated or rewritten by
+// the converter rather than copied from the source
+func (c *Converter) isStagedLabel(labelPath string) bool {
+	if c.staging == "" {
+		return false
+	}
+	rel, err := filepath.Rel(c.staging, labelPath)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+// roundLabelText rounds the coordinates of label lines to precision decimal
+// places. Class IDs and integer values such as keypoint visibility flags
+// are kept as they are.
+func roundLabelText(text string, precision int) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+		for j := 1; j < len(parts); j++ {
+			if !strings.ContainsAny(parts[j], ".eE") {
+				continue
+			}
+			if v, err := strconv.ParseFloat(parts[j], 64); err == nil {
+				parts[j] = strconv.FormatFloat(v, 'f', precision, 64)
+			}
+		}
+		lines[i] = strings.Join(parts, " ")
+	}
+	return strings.Join(lines, "\n")
+}
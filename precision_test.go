@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoundLabelText(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		precision int
+		want      string
+	}{
+		{"box", "0 0.123456 0.5 0.333333 1\n", 3, "0 0.123 0.500 0.333 1\n"},
+		{"keypoints keep visibility", "1 0.5 0.5 0.2 0.2 0.456789 0.1 2\n", 2, "1 0.50 0.50 0.20 0.20 0.46 0.10 2\n"},
+		{"exponent", "2 1e-07 0.999999 0.25 0.25", 4, "2 0.0000 1.0000 0.2500 0.2500"},
+		{"blank lines", "0 0.5 0.5 0.1 0.1\n\n", 1, "0 0.5 0.5 0.1 0.1\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roundLabelText(tt.text, tt.precision); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertPrecision(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	labels := map[string]string{
+		"image1.txt": "0 0.512345 0.487654 0.3 0.3 0.9\n",
+		"image2.txt": "1 0.512345 0.487654 0.1 0.1\n",
+	}
+	for name, content := range labels {
+		if err := os.WriteFile(filepath.Join(sourceDir, "labels", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, ConfidenceColumn: true, Precision: 2})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	want := map[string]string{
+		// Rewritten to strip the confidence column, so rounded
+		"image1.txt": "0 0.51 0.49 0.30 0.30\n",
+		// Copied as it is
+		"image2.txt": labels["image2.txt"],
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", name))
+		if err != nil || string(got) != content {
+			t.Errorf("Label %s: expected %q, got %q (%v)", name, content, got, err)
+		}
+	}
+}
+
+func TestValidatePrecision(t *testing.T) {
+	for _, precision := range []int{-1, 7} {
+		converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Precision: precision})
+		converter.SetOutput(&strings.Builder{})
+		if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-precision") {
+			t.Errorf("Precision %d: expected a -precision error, got %v", precision, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+)
+
+// predictionStats counts tasks labeled from model predictions
+type predictionStats struct {
+	tasks   int
+	regions int
+	dropped int
+}
+
+// report prints how many tasks were pseudo-labeled, if any
+func (s *predictionStats) report(c *Converter) {
+	if s == nil || s.tasks+s.dropped == 0 {
+		return
+	}
+	fmt.Fprintf(c.out, "Pseudo-labeled %d tasks from predictions: kept %d regions, dropped %d below score %.2f\n",
+		s.tasks, s.regions, s.dropped, c.config.MinScore)
+}
+
+// validatePredictions checks the -min-score setting
+func (c *Converter) validatePredictions() error {
+	if c.config.MinScore < 0 || c.config.MinScore > 1 {
+		return fmt.Errorf("minimum score must be between 0 and 1")
+	}
+	return nil
+}
+
+// predictionResults returns the results of a task's best prediction that
+// reach the minimum score. A region's own score takes precedence over the
+// prediction's; unscored regions only pass a minimum score of 0. It returns
+// false when every predicted region fell below the minimum, since the image
+// would otherwise look like it has no objects.
+func (c *Converter) predictionResults(task LSTask) ([]LSResult, bool) {
+	if len(task.Predictions) == 0 {
+		return nil, true
+	}
+
+	best := bestPrediction(task.Predictions)
+	var results []LSResult
+	dropped := 0
+	for _, result := range best.Result {
+		score := result.Score
+		if score == nil {
+			score = best.Score
+		}
+		if scoreOf(score) < c.config.MinScore {
+			dropped++
+			continue
+		}
+		results = append(results, result)
+	}
+
+	if c.predictionStats != nil {
+		c.predictionStats.dropped += dropped
+		if len(results) > 0 {
+			c.predictionStats.tasks++
+			c.predictionStats.regions += len(results)
+		}
+	}
+	return results, len(results) > 0 || dropped == 0
+}
+
+// bestPrediction returns the highest scored of a task's predictions, the
+// first one among equals
+func bestPrediction(predictions []LSAnnotation) LSAnnotation {
+	best := predictions[0]
+	for _, p := range predictions[1:] {
+		if scoreOf(p.Score) > scoreOf(best.Score) {
+			best = p
+		}
+	}
+	return best
+}
+
+// scoreOf returns a score, treating a missing one as 0
+func scoreOf(score *float64) float64 {
+	if score == nil {
+		return 0
+	}
+	return *score
+}
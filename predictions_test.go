@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPredictionsUnmarshal(t *testing.T) {
+	data := `{"id": 1, "predictions": [{"model_version": "v2", "score": 0.8,
+		"result": [{"type": "rectanglelabels", "score": 0.95, "value": {"rectanglelabels": ["cat"]}}]}]}`
+
+	var task LSTask
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	prediction := task.Predictions[0]
+	if prediction.ModelVersion != "v2" || scoreOf(prediction.Score) != 0.8 || scoreOf(prediction.Result[0].Score) != 0.95 {
+		t.Errorf("Unexpected prediction %+v", prediction)
+	}
+}
+
+func TestPredictionPseudoLabels(t *testing.T) {
+	score := func(s float64) *float64 { return &s }
+	scored := func(result LSResult, s float64) LSResult {
+		result.Score = score(s)
+		return result
+	}
+
+	task := LSTask{ID: 1, Predictions: []LSAnnotation{
+		{Score: score(0.4), Result: []LSResult{box("bird", 10, 10, 20, 20)}},
+		{Score: score(0.7), Result: []LSResult{
+			scored(box("cat", 10, 10, 20, 20), 0.9),
+			scored(box("dog", 50, 50, 20, 20), 0.3),
+			box("fox", 70, 70, 10, 10),
+		}},
+	}}
+
+	converter := NewConverter(Config{UsePredictions: true, MinScore: 0.5})
+	converter.predictionStats = &predictionStats{}
+
+	results, ok := converter.taskResults(task)
+	if !ok {
+		t.Fatal("Expected the task to be kept")
+	}
+	// The best prediction wins; fox inherits its 0.7 score, dog is too weak
+	var labels []string
+	for _, result := range results {
+		labels = append(labels, firstLabel(result))
+	}
+	if len(labels) != 2 || labels[0] != "cat" || labels[1] != "fox" {
+		t.Errorf("Expected cat and fox, got %v", labels)
+	}
+	if stats := converter.predictionStats; stats.tasks != 1 || stats.regions != 2 || stats.dropped != 1 {
+		t.Errorf("Unexpected stats %+v", *stats)
+	}
+
+	// Annotations take precedence over predictions
+	task.Annotations = []LSAnnotation{{Result: []LSResult{box("owl", 10, 10, 5, 5)}}}
+	if results, _ := converter.taskResults(task); len(results) != 1 || firstLabel(results[0]) != "owl" {
+		t.Errorf("Expected the human annotation, got %+v", results)
+	}
+
+	// Without -predictions an unannotated task stays empty
+	task.Annotations = nil
+	if results, ok := NewConverter(Config{MinScore: 0.5}).taskResults(task); !ok || len(results) != 0 {
+		t.Errorf("Expected no results without -predictions, got %+v", results)
+	}
+}
+
+func TestPredictionBelowScoreSkipsTask(t *testing.T) {
+	low := 0.2
+	task := LSTask{ID: 1, Predictions: []LSAnnotation{{Score: &low, Result: []LSResult{box("cat", 10, 10, 20, 20)}}}}
+
+	converter := NewConverter(Config{UsePredictions: true, MinScore: 0.5})
+	if _, ok := converter.taskResults(task); ok {
+		t.Error("Expected a task whose predictions all fall below the score to be skipped")
+	}
+
+	converter.config.MinScore = 0
+	if results, ok := converter.taskResults(task); !ok || len(results) != 1 {
+		t.Errorf("Expected the prediction with a minimum score of 0, got %+v", results)
+	}
+}
+
+func TestValidatePredictions(t *testing.T) {
+	if err := NewConverter(Config{MinScore: 1.5}).validatePredictions(); err == nil {
+		t.Error("Expected error for out of range minimum score")
+	}
+}
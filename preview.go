@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"gopkg.in/yaml.v3"
+)
+
+// Sizes of rendered previews in pixels
+const (
+	// previewMaxSize is the longest side of a preview image
+	previewMaxSize = 1024
+	// previewCellSize is the side of a contact sheet cell
+	previewCellSize = 256
+)
+
+// previewColors are the outline colors of classes, cycled by class ID
+var previewColors = []color.RGBA{
+	{230, 25, 75, 255}, {60, 180, 75, 255}, {0, 130, 200, 255}, {245, 130, 48, 255},
+	{145, 30, 180, 255}, {70, 240, 240, 255}, {240, 50, 230, 255}, {210, 245, 60, 255},
+	{0, 128, 128, 255}, {170, 110, 40, 255},
+}
+
+// PreviewOptions configures the rendering of a converted dataset's samples
+type PreviewOptions struct {
+	DatasetDir string
+	OutputDir  string
+	// Count is the number of images drawn per split
+	Count  int
+	Seed   int64
+	Mosaic bool
+}
+
+// previewShape is a region to draw: its class and outline in normalized
+// coordinates
+type previewShape struct {
+	classID int
+	outline []float64
+}
+
+// readPreviewShapes reads the regions of a label file. Pose labels are drawn
+// as their boxes; other lines of four coordinates are boxes and longer ones
+// polygons or oriented boxes.
+func readPreviewShapes(labelPath, task string) ([]previewShape, error) {
+	data, err := os.ReadFile(labelPath)
+	if err != nil {
+		return nil, err
+	}
+	var shapes []previewShape
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 5 {
+			continue
+		}
+		classID, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		coords := make([]float64, len(parts)-1)
+		for i := range coords {
+			coords[i], _ = strconv.ParseFloat(parts[i+1], 64)
+		}
+		if task == TaskPose || len(coords) == 4 {
+			bounds := [4]float64{coords[0], coords[1], coords[2], coords[3]}
+			coords = shapeOutline(AnnotationBox, nil, bounds)
+		}
+		shapes = append(shapes, previewShape{classID: classID, outline: coords})
+	}
+	return shapes, nil
+}
+
+// previewPalette returns the outline color of each class: its color in
+// data.yaml when one is set, otherwise one of previewColors
+func previewPalette(names, colors []string) []color.RGBA {
+	palette := make([]color.RGBA, len(names))
+	for i := range palette {
+		palette[i] = previewColors[i%len(previewColors)]
+		if i < len(colors) {
+			var r, g, b uint8
+			if _, err := fmt.Sscanf(colors[i], "#%02x%02x%02x", &r, &g, &b); err == nil {
+				palette[i] = color.RGBA{r, g, b, 255}
+			}
+		}
+	}
+	return palette
+}
+
+// renderPreview draws the regions of a label file with their class names on
+// its image, scaled to fit within size pixels. Classes are outlined in their
+// palette color, and classes past the palette in one of previewColors.
+func renderPreview(imagePath, labelPath, task string, names []string, palette []color.RGBA, size int) (*image.RGBA, error) {
+	src, err := decodeImage(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	src = orient(src, exifOrientation(imagePath))
+	shapes, err := readPreviewShapes(labelPath, task)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read label %s: %w", labelPath, err)
+	}
+
+	b := src.Bounds()
+	scale := math.Min(1, float64(size)/float64(max(b.Dx(), b.Dy())))
+	w, h := max(1, int(float64(b.Dx())*scale)), max(1, int(float64(b.Dy())*scale))
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	for _, shape := range shapes {
+		col := previewColors[shape.classID%len(previewColors)]
+		if shape.classID >= 0 && shape.classID < len(palette) {
+			col = palette[shape.classID]
+		}
+		points := make([]image.Point, len(shape.outline)/2)
+		for i := range points {
+			points[i] = image.Pt(int(shape.outline[2*i]*float64(w-1)), int(shape.outline[2*i+1]*float64(h-1)))
+		}
+		for i := range points {
+			p, q := points[i], points[(i+1)%len(points)]
+			drawLine(dst, p.X, p.Y, q.X, q.Y, col)
+		}
+		if len(points) > 0 {
+			drawCaption(dst, className(names, shape.classID), points, col)
+		}
+	}
+	return dst, nil
+}
+
+// drawCaption writes a class name on a colored background above the top-left
+// corner of a region, or inside it at the top edge of the image
+func drawCaption(img *image.RGBA, text string, points []image.Point, col color.RGBA) {
+	face := basicfont.Face7x13
+	left, top := points[0].X, points[0].Y
+	for _, p := range points {
+		left, top = min(left, p.X), min(top, p.Y)
+	}
+	width := font.MeasureString(face, text).Ceil() + 4
+	height := face.Metrics().Height.Ceil() + 2
+	if top-height >= 0 {
+		top -= height
+	}
+	box := image.Rect(left, top, left+width, top+height).Intersect(img.Bounds())
+	draw.Draw(img, box, image.NewUniform(col), image.Point{}, draw.Src)
+
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.P(left+2, top+face.Metrics().Ascent.Ceil()+1),
+	}
+	d.DrawString(text)
+}
+
+// previewImages returns the images of a dataset split, sorted by name
+func previewImages(datasetDir, split string) ([]string, error) {
+	dir := filepath.Join(datasetDir, "images", split)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	var c Converter
+	var images []string
+	for _, entry := range entries {
+		if !entry.IsDir() && c.isImageFile(entry.Name()) {
+			images = append(images, entry.Name())
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// Preview draws the labels of Count random images of every split of a
+// converted dataset into OutputDir: one JPEG per image under <split>/, or a
+// <split>.jpg contact sheet with Mosaic. It returns the number of images
+// drawn.
+func Preview(o PreviewOptions, out io.Writer) (int, error) {
+	data, err := os.ReadFile(filepath.Join(o.DatasetDir, "data.yaml"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read data.yaml of the dataset: %w", err)
+	}
+	names, err := loadClassNames(filepath.Join(o.DatasetDir, "data.yaml"))
+	if err != nil {
+		return 0, err
+	}
+	var config struct {
+		Task   string   `yaml:"task"`
+		Colors []string `yaml:"colors"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return 0, fmt.Errorf("failed to parse data.yaml of the dataset: %w", err)
+	}
+
+	palette := previewPalette(names, config.Colors)
+
+	rng := rand.New(rand.NewSource(o.Seed))
+	drawn := 0
+	for _, split := range []string{"train", "val"} {
+		images, err := previewImages(o.DatasetDir, split)
+		if err != nil {
+			return drawn, err
+		}
+		rng.Shuffle(len(images), func(i, j int) { images[i], images[j] = images[j], images[i] })
+		if o.Count > 0 && len(images) > o.Count {
+			images = images[:o.Count]
+		}
+		if len(images) == 0 {
+			continue
+		}
+		sort.Strings(images)
+
+		size := previewMaxSize
+		if o.Mosaic {
+			size = previewCellSize
+		}
+		var cells []*image.RGBA
+		count := 0
+		for _, name := range images {
+			imagePath := filepath.Join(o.DatasetDir, "images", split, name)
+			labelPath := filepath.Join(o.DatasetDir, "labels", split, strings.TrimSuffix(name, filepath.Ext(name))+".txt")
+			img, err := renderPreview(imagePath, labelPath, config.Task, names, palette, size)
+			if err != nil {
+				fmt.Fprintf(out, "Warning: Cannot preview %s: %v\n", name, err)
+				continue
+			}
+			count++
+			if o.Mosaic {
+				cells = append(cells, img)
+				continue
+			}
+			path := filepath.Join(o.OutputDir, split, strings.TrimSuffix(name, filepath.Ext(name))+".jpg")
+			if err := writePreview(path, img); err != nil {
+				return drawn, err
+			}
+		}
+		if len(cells) > 0 {
+			if err := writePreview(filepath.Join(o.OutputDir, split+".jpg"), contactSheet(cells)); err != nil {
+				return drawn, err
+			}
+		}
+		fmt.Fprintf(out, "Drew %d %s images\n", count, split)
+		drawn += count
+	}
+	return drawn, nil
+}
+
+// contactSheet lays out previews in a square grid of cells, centering each
+// preview in its cell
+func contactSheet(previews []*image.RGBA) *image.RGBA {
+	columns := int(math.Ceil(math.Sqrt(float64(len(previews)))))
+	rows := (len(previews) + columns - 1) / columns
+	sheet := image.NewRGBA(image.Rect(0, 0, columns*previewCellSize, rows*previewCellSize))
+	draw.Draw(sheet, sheet.Bounds(), image.Black, image.Point{}, draw.Src)
+	for i, preview := range previews {
+		b := preview.Bounds()
+		x := (i%columns)*previewCellSize + (previewCellSize-b.Dx())/2
+		y := (i/columns)*previewCellSize + (previewCellSize-b.Dy())/2
+		draw.Draw(sheet, b.Add(image.Pt(x, y)), preview, b.Min, draw.Src)
+	}
+	return sheet
+}
+
+// writePreview writes a preview as a JPEG, creating its directory
+func writePreview(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create preview directory: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return fmt.Errorf("failed to encode preview %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write preview %s: %w", path, err)
+	}
+	return nil
+}
+
+// runPreview is the entry point of the preview subcommand
+func runPreview(args []string) error {
+	var o PreviewOptions
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	fs.StringVar(&o.DatasetDir, "dataset", "./yolo_dataset", "Converted YOLO dataset (directory with data.yaml)")
+	fs.StringVar(&o.OutputDir, "output", "./preview", "Directory to write the previews to")
+	fs.IntVar(&o.Count, "count", 16, "Number of random images drawn per split (0 draws all)")
+	fs.Int64Var(&o.Seed, "seed", 42, "Random seed for choosing the images")
+	fs.BoolVar(&o.Mosaic, "mosaic", false, "Write one contact sheet per split instead of separate images")
+	fs.Parse(args)
+
+	drawn, err := Preview(o, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if drawn == 0 {
+		return fmt.Errorf("no images found in %s", o.DatasetDir)
+	}
+	fmt.Printf("Wrote previews to %s\n", o.OutputDir)
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePreviewDataset writes a converted dataset with three train images and
+// one val image
+func writePreviewDataset(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.yaml"), []byte("train: images/train\nval: images/val\nnc: 2\nnames: [car, person]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for split, names := range map[string][]string{"train": {"a", "b", "c"}, "val": {"d"}} {
+		for _, dir := range []string{filepath.Join(dir, "images", split), filepath.Join(dir, "labels", split)} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, name := range names {
+			writeTestImage(t, filepath.Join(dir, "images", split, name+".png"), 64, 48)
+			if err := os.WriteFile(filepath.Join(dir, "labels", split, name+".txt"), []byte("1 0.5 0.5 0.5 0.5\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return dir
+}
+
+func TestRenderPreview(t *testing.T) {
+	dir := writePreviewDataset(t)
+	img, err := renderPreview(filepath.Join(dir, "images", "train", "a.png"), filepath.Join(dir, "labels", "train", "a.txt"),
+		TaskDetect, []string{"car", "person"}, nil, previewMaxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 48 {
+		t.Errorf("Expected small images to keep their size, got %v", b)
+	}
+	// The left edge of the box in the color of class 1
+	if got := img.RGBAAt(15, 30); got != previewColors[1] {
+		t.Errorf("Expected the box outline at 15,30, got %v", got)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	dir := writePreviewDataset(t)
+	output := filepath.Join(t.TempDir(), "preview")
+
+	drawn, err := Preview(PreviewOptions{DatasetDir: dir, OutputDir: output, Count: 2, Seed: 1}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drawn != 3 {
+		t.Errorf("Expected 2 train and 1 val previews, got %d", drawn)
+	}
+	for split, want := range map[string]int{"train": 2, "val": 1} {
+		entries, err := os.ReadDir(filepath.Join(output, split))
+		if err != nil || len(entries) != want {
+			t.Errorf("Expected %d %s previews, got %d (%v)", want, split, len(entries), err)
+		}
+	}
+
+	mosaic := filepath.Join(t.TempDir(), "mosaic")
+	if _, err := Preview(PreviewOptions{DatasetDir: dir, OutputDir: mosaic, Mosaic: true}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	width, height, err := readImageSize(filepath.Join(mosaic, "train.jpg"))
+	if err != nil || width != 2*previewCellSize || height != 2*previewCellSize {
+		t.Errorf("Expected a 2x2 contact sheet of three images, got %dx%d, %v", width, height, err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	pprofile "runtime/pprof"
+	"runtime/trace"
+)
+
+// profileOptions name the files that profiles of a conversion are written
+// to; empty names disable a profile
+type profileOptions struct {
+	CPUProfile string
+	MemProfile string
+	Trace      string
+}
+
+// registerProfileFlags registers the profiling flags on a flag set
+func registerProfileFlags(fs *flag.FlagSet, o *profileOptions) {
+	fs.StringVar(&o.CPUProfile, "cpuprofile", "", "Write a CPU profile to this file (inspect with go tool pprof)")
+	fs.StringVar(&o.MemProfile, "memprofile", "", "Write a heap profile to this file when the conversion ends")
+	fs.StringVar(&o.Trace, "trace", "", "Write an execution trace to this file (inspect with go tool trace), showing where goroutines wait on I/O")
+}
+
+// start starts the CPU profile and the execution trace. The returned
+// function stops them and writes the heap profile; it must run before the
+// process exits.
+func (o profileOptions) start() (func() error, error) {
+	var stops []func() error
+	stop := func() error {
+		var first error
+		for i := len(stops) - 1; i >= 0; i-- {
+			if err := stops[i](); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+
+	if o.CPUProfile != "" {
+		file, err := os.Create(o.CPUProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := pprofile.StartCPUProfile(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		stops = append(stops, func() error {
+			pprofile.StopCPUProfile()
+			return file.Close()
+		})
+	}
+	if o.Trace != "" {
+		file, err := os.Create(o.Trace)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to create trace: %w", err)
+		}
+		if err := trace.Start(file); err != nil {
+			file.Close()
+			stop()
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+		stops = append(stops, func() error {
+			trace.Stop()
+			return file.Close()
+		})
+	}
+	if o.MemProfile != "" {
+		stops = append(stops, func() error {
+			file, err := os.Create(o.MemProfile)
+			if err != nil {
+				return fmt.Errorf("failed to create heap profile: %w", err)
+			}
+			defer file.Close()
+			// Collect garbage so the profile shows live memory
+			runtime.GC()
+			if err := pprofile.WriteHeapProfile(file); err != nil {
+				return fmt.Errorf("failed to write heap profile: %w", err)
+			}
+			return nil
+		})
+	}
+	return stop, nil
+}
+
+// pprofHandler serves the net/http/pprof endpoints below /debug/pprof/
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileOptions(t *testing.T) {
+	dir := t.TempDir()
+	o := profileOptions{
+		CPUProfile: filepath.Join(dir, "cpu.pprof"),
+		MemProfile: filepath.Join(dir, "mem.pprof"),
+		Trace:      filepath.Join(dir, "trace.out"),
+	}
+	stop, err := o.start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTestFiles(t, filepath.Join(dir, "export"))
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{o.CPUProfile, o.MemProfile, o.Trace} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("Expected a profile at %s, got %v", path, err)
+		}
+	}
+
+	if _, err := (profileOptions{CPUProfile: filepath.Join(dir, "missing", "cpu.pprof")}).start(); err == nil {
+		t.Error("Expected an error for an unwritable profile")
+	}
+}
+
+func TestPprofHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	pprofHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the pprof index, got status %d", rec.Code)
+	}
+}
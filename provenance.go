@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// provenanceFile is the name of the provenance record in the dataset root
+const provenanceFile = "provenance.json"
+
+// Provenance records how a dataset was produced: the converter build, the
+// configuration, the checksums of the source files and what came out
+type Provenance struct {
+	Tool       ProvenanceTool    `json:"tool"`
+	Config     Config            `json:"config"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Sources    map[string]string `json:"sources"`
+	Counts     ProvenanceCounts  `json:"counts"`
+	Classes    []string          `json:"classes"`
+}
+
+// ProvenanceTool identifies the converter build
+type ProvenanceTool struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// ProvenanceCounts counts the dataset's contents and what was left out
+type ProvenanceCounts struct {
+	TrainImages        int `json:"train_images"`
+	ValImages          int `json:"val_images"`
+	Annotations        int `json:"annotations"`
+	Classes            int `json:"classes"`
+	ValidationFindings int `json:"validation_findings"`
+	Skipped            int `json:"skipped"`
+}
+
+// newProvenance hashes the source files and counts the dataset's contents.
+// It runs before the files are copied, since -move consumes the source.
+func (c *Converter) newProvenance(classes []string, trainPairs, valPairs []LabelPair) (*Provenance, error) {
+	sources, err := c.sourceChecksums()
+	if err != nil {
+		return nil, err
+	}
+	annotations := 0
+	for _, pair := range append(append([]LabelPair{}, trainPairs...), valPairs...) {
+		data, err := os.ReadFile(pair.LabelPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read label %s: %w", pair.LabelPath, err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) != "" {
+				annotations++
+			}
+		}
+	}
+	return &Provenance{
+		Tool: ProvenanceTool{
+			Name:      "labelstudio-to-yolo",
+			Version:   Version,
+			Commit:    Commit,
+			BuildTime: BuildTime,
+			GoVersion: runtime.Version(),
+		},
+		Config:    c.provenanceConfig(),
+		StartedAt: c.started.UTC(),
+		Sources:   sources,
+		Counts: ProvenanceCounts{
+			TrainImages:        len(trainPairs),
+			ValImages:          len(valPairs),
+			Annotations:        annotations,
+			Classes:            len(classes),
+			ValidationFindings: len(c.findings),
+			Skipped:            c.skipped,
+		},
+		Classes: classes,
+	}, nil
+}
+
+// provenanceConfig returns the configuration recorded in provenance.json.
+// The dataset is shared, so URLs that carry secrets are redacted: the path
+// of a webhook URL is its token, and MLflow URIs may hold credentials.
+func (c *Converter) provenanceConfig() Config {
+	config := c.config
+	if config.NotifyURL != "" {
+		config.NotifyURL = redactURL(config.NotifyURL)
+	}
+	if config.MLflowURI != "" {
+		config.MLflowURI = redactCredentials(config.MLflowURI)
+	}
+	return config
+}
+
+// redactCredentials removes the user info, query and fragment of a URL,
+// where tokens and passwords are passed
+func redactCredentials(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "redacted"
+	}
+	if u.User == nil && u.RawQuery == "" && u.Fragment == "" {
+		return rawURL
+	}
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	return u.String()
+}
+
+// sourceChecksums returns the SHA-256 of every file in the source directory,
+// keyed by its slash-separated path below it, and of the input files given
+// by options, keyed by the path they were given as. An output directory
+// inside the source, such as the default ./yolo_dataset, is skipped. The
+// checksums are kept for the parent dataset of -granularity both.
+func (c *Converter) sourceChecksums() (map[string]string, error) {
+	if c.sourceSums != nil {
+		return c.sourceSums, nil
+	}
+	sums := make(map[string]string)
+	output, _ := filepath.Abs(c.config.OutputDir)
+	err := filepath.WalkDir(c.config.SourceDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == output {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(c.config.SourceDir, path)
+		if err != nil {
+			return err
+		}
+		digest, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		sums[filepath.ToSlash(rel)] = hex.EncodeToString(digest)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash source files: %w", err)
+	}
+
+	for _, path := range []string{c.config.TasksFile, c.config.SkeletonFile, c.config.SplitFile,
+		c.config.LabelConfig, c.config.Aliases, c.config.Hierarchy} {
+		if path == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(c.config.SourceDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			continue
+		}
+		digest, err := fileSHA256(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// -split-file is created by the conversion
+				continue
+			}
+			return nil, fmt.Errorf("failed to hash %s: %w", path, err)
+		}
+		sums[path] = hex.EncodeToString(digest)
+	}
+	c.sourceSums = sums
+	return sums, nil
+}
+
+// writeProvenance writes the provenance record to the dataset root
+func (c *Converter) writeProvenance(provenance *Provenance) error {
+	provenance.FinishedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := c.output().WriteFile(provenanceFile, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", provenanceFile, err)
+	}
+	fmt.Fprintf(c.out, "Wrote %s (%d source files)\n", provenanceFile, len(provenance.Sources))
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// checkEmptyClasses warns about classes without annotations. With
+// -prune-empty-classes they are removed from the class list and the labels'
+// class IDs are renumbered to match.
+func (c *Converter) checkEmptyClasses(classes []string, pairs []LabelPair, stats *ValidationStats) ([]string, []LabelPair, error) {
+	var empty []string
+	var kept []string
+	remap := make(map[int]int, len(classes))
+	for i, name := range classes {
+		if stats.ClassCounts[i] == 0 {
+			empty = append(empty, name)
+			continue
+		}
+		remap[i] = len(kept)
+		kept = append(kept, name)
+	}
+	if len(empty) == 0 {
+		return classes, pairs, nil
+	}
+
+	if !c.config.PruneEmptyClasses {
+		fmt.Fprintf(c.out, "Warning: %d classes have no annotations: %v (use -prune-empty-classes to remove them from data.yaml)\n", len(empty), empty)
+		return classes, pairs, nil
+	}
+
+	pairs, err := c.renumberClasses(pairs, remap, "renumbered")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Keep the per-class counts in line with the new IDs
+	counts := make(map[int]int, len(stats.ClassCounts))
+	for classID, count := range stats.ClassCounts {
+		if newID, ok := remap[classID]; ok {
+			counts[newID] = count
+		} else {
+			counts[classID] = count
+		}
+	}
+	stats.ClassCounts = counts
+
+	fmt.Fprintf(c.out, "Pruned %d classes without annotations: %v\n", len(empty), empty)
+	return kept, pairs, nil
+}
+
+// renumberClasses rewrites the class ID of every label line found in remap,
+// staging the changed files under name
+func (c *Converter) renumberClasses(pairs []LabelPair, remap map[int]int, name string) ([]LabelPair, error) {
+	pairs, _, _, err := c.rewriteLabels(pairs, name, func(pair LabelPair, lines []string) ([]string, int) {
+		changed := 0
+		for i, line := range lines {
+			parts := strings.Fields(line)
+			if len(parts) == 0 {
+				continue
+			}
+			classID, err := strconv.Atoi(parts[0])
+			if err != nil {
+				continue
+			}
+			if newID, ok := remap[classID]; ok && newID != classID {
+				parts[0] = strconv.Itoa(newID)
+				lines[i] = strings.Join(parts, " ")
+				changed++
+			}
+		}
+		return lines, changed
+	})
+	return pairs, err
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeClassesWithGap lists a class between book and person that no label uses
+func writeClassesWithGap(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "classes.txt"), []byte("book\nunused\nperson\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Labels of person move from ID 1 to ID 2
+	labels := map[string]string{
+		"image1.txt": "0 0.5 0.5 0.3 0.3\n2 0.2 0.8 0.1 0.1\n",
+		"image2.txt": "0 0.4 0.6 0.2 0.4\n",
+		"image3.txt": "2 0.7 0.3 0.3 0.2\n0 0.1 0.9 0.1 0.1\n",
+	}
+	for name, content := range labels {
+		if err := os.WriteFile(filepath.Join(dir, "labels", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEmptyClassesWarning(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	writeClassesWithGap(t, tempDir)
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: filepath.Join(tempDir, "out"), TrainSplit: 0.8, Seed: 42})
+	var out strings.Builder
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Warning: 1 classes have no annotations: [unused]") {
+		t.Errorf("Expected an empty class warning, got:\n%s", out.String())
+	}
+
+	config := readDataYAML(t, filepath.Join(tempDir, "out"))
+	if config.NC != 3 {
+		t.Errorf("Expected all 3 classes without pruning, got %v", config.Names)
+	}
+}
+
+func TestPruneEmptyClasses(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	writeClassesWithGap(t, tempDir)
+	outputDir := filepath.Join(tempDir, "out")
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42, PruneEmptyClasses: true})
+	var out strings.Builder
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Pruned 1 classes without annotations: [unused]") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+
+	config := readDataYAML(t, outputDir)
+	if config.NC != 2 || config.Names[0] != "book" || config.Names[1] != "person" {
+		t.Errorf("Expected book and person, got %d %v", config.NC, config.Names)
+	}
+
+	// person is renumbered from 2 to 1 wherever image3 ended up
+	matches, _ := filepath.Glob(filepath.Join(outputDir, "labels", "*", "image3.txt"))
+	if len(matches) != 1 {
+		t.Fatalf("Expected one image3 label, got %v", matches)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1 0.7 0.3 0.3 0.2\n0 0.1 0.9 0.1 0.1\n"; string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+
+	// The source labels keep their IDs
+	if source, _ := os.ReadFile(filepath.Join(tempDir, "labels", "image3.txt")); !strings.HasPrefix(string(source), "2 ") {
+		t.Errorf("Expected the source label to be unchanged, got %q", source)
+	}
+}
+
+// readDataYAML parses the data.yaml of an output directory
+func readDataYAML(t *testing.T, outputDir string) YAMLConfig {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config YAMLConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/image/draw"
+)
+
+// Image quality modes, selected with -image-quality: what to do with blurry
+// and badly exposed images
+const (
+	QualityFlag    = "flag"
+	QualityExclude = "exclude"
+)
+
+// Luminance bounds of the pixels counted as clipped by the exposure check
+const (
+	darkLuminance   = 16
+	brightLuminance = 240
+)
+
+// defaultExposureClip is the share of clipped pixels above which an image is
+// badly exposed, when ExposureClip is unset
+const defaultExposureClip = 0.5
+
+// maxQualityIssuesPrinted limits the low-quality images listed per split
+const maxQualityIssuesPrinted = 10
+
+// ImageQuality are the quality scores of one image
+type ImageQuality struct {
+	// Blur is the variance of the Laplacian of the gray image: sharp edges
+	// give high values, blurry images low ones
+	Blur float64
+	// Dark and Bright are the shares of pixels near black and near white
+	Dark   float64
+	Bright float64
+}
+
+// qualityIssue is an image that fails the quality thresholds
+type qualityIssue struct {
+	pair    LabelPair
+	reasons []string
+}
+
+// validateImageQuality checks the -image-quality options before the
+// conversion starts
+func (c *Converter) validateImageQuality() error {
+	switch c.config.ImageQuality {
+	case "":
+		return nil
+	case QualityFlag, QualityExclude:
+	default:
+		return fmt.Errorf("unknown image quality mode %q (expected flag or exclude)", c.config.ImageQuality)
+	}
+	if c.config.BlurThreshold < 0 {
+		return fmt.Errorf("-blur-threshold can't be negative")
+	}
+	if c.config.ExposureClip < 0 || c.config.ExposureClip > 1 {
+		return fmt.Errorf("-exposure-clip must be between 0 and 1")
+	}
+	if c.config.Classify || c.config.MultiLabel || c.config.Crop {
+		return fmt.Errorf("-image-quality cannot be combined with -classify, -multi-label or -crop")
+	}
+	return nil
+}
+
+// grayImage returns the luminance of an image, using the Y plane of JPEGs
+// directly
+func grayImage(img image.Image) *image.Gray {
+	if ycbcr, ok := img.(*image.YCbCr); ok {
+		return &image.Gray{Pix: ycbcr.Y, Stride: ycbcr.YStride, Rect: ycbcr.Rect}
+	}
+	if gray, ok := img.(*image.Gray); ok {
+		return gray
+	}
+	gray := image.NewGray(img.Bounds())
+	draw.Draw(gray, gray.Bounds(), img, img.Bounds().Min, draw.Src)
+	return gray
+}
+
+// measureImageQuality scores the sharpness and exposure of an image
+func measureImageQuality(img image.Image) ImageQuality {
+	gray := grayImage(img)
+	b := gray.Bounds()
+	width, height := b.Dx(), b.Dy()
+	var quality ImageQuality
+	if width == 0 || height == 0 {
+		return quality
+	}
+
+	dark, bright := 0, 0
+	var sum, sumSquares float64
+	for y := 0; y < height; y++ {
+		row := gray.Pix[y*gray.Stride : y*gray.Stride+width]
+		for x, v := range row {
+			if v <= darkLuminance {
+				dark++
+			} else if v >= brightLuminance {
+				bright++
+			}
+			// The 3x3 Laplacian over the interior pixels
+			if x == 0 || y == 0 || x == width-1 || y == height-1 {
+				continue
+			}
+			up := gray.Pix[(y-1)*gray.Stride+x]
+			down := gray.Pix[(y+1)*gray.Stride+x]
+			laplacian := float64(int(up) + int(down) + int(row[x-1]) + int(row[x+1]) - 4*int(v))
+			sum += laplacian
+			sumSquares += laplacian * laplacian
+		}
+	}
+	pixels := float64(width * height)
+	quality.Dark = float64(dark) / pixels
+	quality.Bright = float64(bright) / pixels
+	if interior := float64((width - 2) * (height - 2)); interior > 0 {
+		mean := sum / interior
+		quality.Blur = sumSquares/interior - mean*mean
+	}
+	return quality
+}
+
+// exposureClip returns -exposure-clip, defaultExposureClip when unset
+func (c *Converter) exposureClip() float64 {
+	if c.config.ExposureClip == 0 {
+		return defaultExposureClip
+	}
+	return c.config.ExposureClip
+}
+
+// qualityReasons lists the thresholds an image fails
+func (c *Converter) qualityReasons(q ImageQuality) []string {
+	var reasons []string
+	clip := c.exposureClip()
+	if q.Blur < c.config.BlurThreshold {
+		reasons = append(reasons, fmt.Sprintf("blurry (blur score %.1f < %g)", q.Blur, c.config.BlurThreshold))
+	}
+	if q.Dark > clip {
+		reasons = append(reasons, fmt.Sprintf("underexposed (%.0f%% of pixels near black)", q.Dark*100))
+	}
+	if q.Bright > clip {
+		reasons = append(reasons, fmt.Sprintf("overexposed (%.0f%% of pixels near white)", q.Bright*100))
+	}
+	return reasons
+}
+
+// screenSplit scores the images of one split concurrently and returns the
+// ones failing the thresholds, in order, with the number that couldn't be
+// decoded
+func (c *Converter) screenSplit(pairs []LabelPair) ([]qualityIssue, int) {
+	reasons := make([][]string, len(pairs))
+	decoded := make([]bool, len(pairs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < c.parallelism(len(pairs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				img, err := decodeImage(pairs[i].ImagePath)
+				if err != nil {
+					continue
+				}
+				decoded[i] = true
+				reasons[i] = c.qualityReasons(measureImageQuality(img))
+			}
+		}()
+	}
+	for i := range pairs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var issues []qualityIssue
+	undecoded := 0
+	for i, pair := range pairs {
+		if !decoded[i] {
+			undecoded++
+		} else if len(reasons[i]) > 0 {
+			issues = append(issues, qualityIssue{pair: pair, reasons: reasons[i]})
+		}
+	}
+	return issues, undecoded
+}
+
+// screenImageQuality checks every image of both splits for blur and bad
+// exposure and reports the low-quality ones per split. With -image-quality
+// exclude they are dropped from the dataset.
+func (c *Converter) screenImageQuality(trainPairs, valPairs []LabelPair) ([]LabelPair, []LabelPair) {
+	if c.config.ImageQuality == "" {
+		return trainPairs, valPairs
+	}
+	fmt.Fprintln(c.out, "\nScreening image quality...")
+	splits := []struct {
+		name  string
+		pairs *[]LabelPair
+	}{{"train", &trainPairs}, {"val", &valPairs}}
+	for _, split := range splits {
+		issues, undecoded := c.screenSplit(*split.pairs)
+		if undecoded > 0 {
+			fmt.Fprintf(c.out, "Warning: %d %s images could not be decoded and were not screened\n", undecoded, split.name)
+		}
+		fmt.Fprintf(c.out, "Image quality (%s): %d of %d images below the thresholds\n", split.name, len(issues), len(*split.pairs))
+		for i, issue := range issues {
+			if i == maxQualityIssuesPrinted {
+				fmt.Fprintf(c.out, "  ... and %d more\n", len(issues)-maxQualityIssuesPrinted)
+				break
+			}
+			for _, reason := range issue.reasons {
+				fmt.Fprintf(c.out, "  %s/%s: %s\n", split.name, filepath.Base(issue.pair.ImagePath), reason)
+			}
+		}
+
+		if c.config.ImageQuality != QualityExclude || len(issues) == 0 {
+			continue
+		}
+		excluded := make(map[string]bool, len(issues))
+		for _, issue := range issues {
+			excluded[issue.pair.ImagePath] = true
+		}
+		kept := []LabelPair{}
+		for _, pair := range *split.pairs {
+			if !excluded[pair.ImagePath] {
+				kept = append(kept, pair)
+			}
+		}
+		*split.pairs = kept
+		fmt.Fprintf(c.out, "Excluded %d %s images for low quality\n", len(issues), split.name)
+	}
+	if c.config.ImageQuality == QualityExclude {
+		fmt.Fprintf(c.out, "Dataset split: %d training, %d validation\n", len(trainPairs), len(valPairs))
+	}
+	return trainPairs, valPairs
+}
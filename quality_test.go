@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMeasureImageQuality(t *testing.T) {
+	dir := t.TempDir()
+	shades := map[string]func(x, y int) uint8{
+		"checker": func(x, y int) uint8 { return uint8(((x/4 + y/4) % 2) * 200) },
+		"flat":    func(x, y int) uint8 { return 128 },
+		"dark":    func(x, y int) uint8 { return uint8(x % 8) },
+		"bright":  func(x, y int) uint8 { return uint8(250 - y%4) },
+	}
+	quality := make(map[string]ImageQuality)
+	for name, shade := range shades {
+		path := filepath.Join(dir, name+".png")
+		writePatternPNG(t, path, shade)
+		img, err := decodeImage(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		quality[name] = measureImageQuality(img)
+	}
+
+	if q := quality["checker"]; q.Blur < 1000 || q.Dark != 0.5 || q.Bright != 0 {
+		t.Errorf("Expected a sharp checkerboard with half its pixels black, got %+v", q)
+	}
+	if q := quality["flat"]; q.Blur != 0 || q.Dark != 0 || q.Bright != 0 {
+		t.Errorf("Expected a flat image without edges or clipping, got %+v", q)
+	}
+	if q := quality["dark"]; q.Dark != 1 {
+		t.Errorf("Expected every pixel of the dark image near black, got %+v", q)
+	}
+	if q := quality["bright"]; q.Bright != 1 {
+		t.Errorf("Expected every pixel of the bright image near white, got %+v", q)
+	}
+}
+
+func TestScreenImageQuality(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, sub := range []string{"images", "labels"} {
+		if err := os.MkdirAll(filepath.Join(sourceDir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shades := map[string]func(x, y int) uint8{
+		"sharp": func(x, y int) uint8 { return uint8(((x/4+y/4)%2)*160 + 40) },
+		"soft":  func(x, y int) uint8 { return uint8(100 + x/8) },
+		"night": func(x, y int) uint8 { return uint8(((x/4 + y/4) % 2) * 60) },
+	}
+	for name, shade := range shades {
+		writePatternPNG(t, filepath.Join(sourceDir, "images", name+".png"), shade)
+		if err := os.WriteFile(filepath.Join(sourceDir, "labels", name+".txt"), []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "classes.txt"), []byte("object\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{SourceDir: sourceDir, OutputDir: filepath.Join(t.TempDir(), "out"), TrainSplit: 1, InputFormat: InputYOLO,
+		ImageQuality: QualityFlag, BlurThreshold: 100, ExposureClip: 0.4}
+	var out strings.Builder
+	converter := NewConverter(config)
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	for _, text := range []string{
+		"Image quality (train): 2 of 3 images below the thresholds",
+		"train/soft.png: blurry (blur score",
+		"train/night.png: underexposed (50% of pixels near black)",
+		"Image quality (val): 0 of 0 images",
+	} {
+		if !strings.Contains(out.String(), text) {
+			t.Errorf("Expected %q in the output:\n%s", text, out.String())
+		}
+	}
+	if train, _ := os.ReadDir(filepath.Join(config.OutputDir, "images", "train")); len(train) != 3 {
+		t.Errorf("Expected flag to keep every image, got %d", len(train))
+	}
+
+	config.ImageQuality = QualityExclude
+	config.OutputDir = filepath.Join(t.TempDir(), "out")
+	out.Reset()
+	converter = NewConverter(config)
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+	train, _ := os.ReadDir(filepath.Join(config.OutputDir, "images", "train"))
+	if len(train) != 1 || train[0].Name() != "sharp.png" || !strings.Contains(out.String(), "Excluded 2 train images for low quality") {
+		t.Errorf("Expected only sharp.png to be kept, got %v:\n%s", train, out.String())
+	}
+
+	config.ImageQuality = "drop"
+	if err := NewConverter(config).Convert(); err == nil || !strings.Contains(err.Error(), "image quality mode") {
+		t.Errorf("Expected an error for an unknown mode, got %v", err)
+	}
+}
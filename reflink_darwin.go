@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile makes dst a copy-on-write clone of src with clonefile(2), which
+// shares the data blocks instead of copying them. It fails unless both files
+// are on the same APFS volume.
+func cloneFile(src, dst string) error {
+	// clonefile refuses to replace an existing file
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return unix.Clonefile(src, dst, unix.CLONE_NOFOLLOW)
+}
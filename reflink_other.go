@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package main
+
+import "errors"
+
+// cloneFile is unsupported on this platform, so files are always copied
+func cloneFile(src, dst string) error {
+	return errors.New("reflinks are not supported on this platform")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValidationFinding is one problem found while validating a label file
+type ValidationFinding struct {
+	File    string `json:"file"`
+	Image   string `json:"image,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Content string `json:"content,omitempty"`
+}
+
+// ValidationReport is the JSON validation report written with -report
+type ValidationReport struct {
+	Stats    *ValidationStats    `json:"stats"`
+	Findings []ValidationFinding `json:"findings"`
+}
+
+// ruleID turns a validation warning into a stable rule identifier, e.g.
+// "Invalid class_id" becomes "invalid-class-id"
+func ruleID(warning string) string {
+	return strings.NewReplacer(" ", "-", "_", "-").Replace(strings.ToLower(warning))
+}
+
+// findingFile names a label file relative to the source directory when it
+// lives there, and by its base name otherwise
+func (c *Converter) findingFile(labelPath string) string {
+	if rel, err := filepath.Rel(c.config.SourceDir, labelPath); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.Base(labelPath)
+}
+
+// newFinding describes a problem in a label file
+func (c *Converter) newFinding(pair LabelPair, line int, rule, message, content string) ValidationFinding {
+	finding := ValidationFinding{File: c.findingFile(pair.LabelPath), Line: line, Rule: rule, Message: message, Content: content}
+	if pair.ImagePath != "" {
+		finding.Image = filepath.Base(pair.ImagePath)
+	}
+	return finding
+}
+
+// writeValidationReport writes the findings of the last validation to
+// ReportFile: NDJSON, one finding per line, for .ndjson and .jsonl files and a
+// JSON document with the stats otherwise
+func (c *Converter) writeValidationReport(stats *ValidationStats) error {
+	file, err := os.Create(c.config.ReportFile)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	switch strings.ToLower(filepath.Ext(c.config.ReportFile)) {
+	case ".ndjson", ".jsonl":
+		encoder := json.NewEncoder(w)
+		for _, finding := range c.findings {
+			if err := encoder.Encode(finding); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+		}
+	default:
+		findings := c.findings
+		if findings == nil {
+			findings = []ValidationFinding{}
+		}
+		data, err := json.MarshalIndent(ValidationReport{Stats: stats, Findings: findings}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		w.Write(append(data, '\n'))
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	fmt.Fprintf(c.out, "Wrote %d validation findings to %s\n", len(c.findings), c.config.ReportFile)
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidationFindings(t *testing.T) {
+	sourceDir := t.TempDir()
+	labelsDir := filepath.Join(sourceDir, "labels")
+	if err := os.MkdirAll(labelsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	labelPath := filepath.Join(labelsDir, "street.txt")
+	if err := os.WriteFile(labelPath, []byte("0 0.5 0.5 0.2 0.2\n\n1 0.5 1.5 0.2 0.2\n2 x 0.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pairs := []LabelPair{
+		{ImagePath: filepath.Join(sourceDir, "images", "street.jpg"), LabelPath: labelPath},
+		{ImagePath: filepath.Join(sourceDir, "images", "gone.jpg"), LabelPath: filepath.Join(labelsDir, "gone.txt")},
+	}
+
+	converter := NewConverter(Config{SourceDir: sourceDir})
+	converter.SetOutput(io.Discard)
+	stats, err := converter.ValidateLabels(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ValidationFinding{
+		{File: "labels/street.txt", Image: "street.jpg", Line: 3, Rule: "non-normalized-coordinates", Message: "Non-normalized coordinates", Content: "1 0.5 1.5 0.2 0.2"},
+		{File: "labels/street.txt", Image: "street.jpg", Line: 4, Rule: "invalid-coordinate", Message: "Invalid coordinate", Content: "2 x 0.5 0.2 0.2"},
+		{File: "labels/gone.txt", Image: "gone.jpg", Rule: "unreadable-file"},
+	}
+	if len(converter.findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %+v", len(expected), converter.findings)
+	}
+	for i, want := range expected {
+		got := converter.findings[i]
+		if want.Rule == "unreadable-file" {
+			// The message is the operating system's error
+			want.Message = got.Message
+		}
+		if got != want {
+			t.Errorf("Finding %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	// NDJSON has one finding per line
+	converter.config.ReportFile = filepath.Join(t.TempDir(), "findings.ndjson")
+	if err := converter.writeValidationReport(stats); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(converter.config.ReportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 NDJSON lines, got %d", len(lines))
+	}
+	var finding ValidationFinding
+	if err := json.Unmarshal([]byte(lines[1]), &finding); err != nil || finding.Line != 4 {
+		t.Errorf("Unexpected NDJSON line %q: %v", lines[1], err)
+	}
+
+	// JSON carries the stats alongside the findings
+	converter.config.ReportFile = filepath.Join(t.TempDir(), "findings.json")
+	if err := converter.writeValidationReport(stats); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = os.ReadFile(converter.config.ReportFile); err != nil {
+		t.Fatal(err)
+	}
+	var report ValidationReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if report.Stats.InvalidLines != 3 || len(report.Findings) != 3 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestRuleID(t *testing.T) {
+	if got := ruleID("Invalid class_id"); got != "invalid-class-id" {
+		t.Errorf("Unexpected rule ID %q", got)
+	}
+	if got := ruleID("Counter-clockwise oriented box corners"); got != "counter-clockwise-oriented-box-corners" {
+		t.Errorf("Unexpected rule ID %q", got)
+	}
+}
+
+func TestReportRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Crop: true, ReportFile: "report.json"})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-report") {
+		t.Errorf("Expected -report to be rejected with -crop, got %v", err)
+	}
+}
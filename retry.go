@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// transientErrnos are the errors network file systems such as NFS and SMB
+// return sporadically and that usually clear up on a retry
+var transientErrnos = []syscall.Errno{
+	syscall.EIO, syscall.ESTALE, syscall.EAGAIN, syscall.EINTR, syscall.EBUSY, syscall.ETIMEDOUT,
+}
+
+// isTransient reports whether an error is worth retrying
+func isTransient(err error) bool {
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryStats counts the file operations that failed
+type retryStats struct {
+	// Retried operations failed with a transient error, Recovered of them
+	// succeeded on a retry
+	Retried, Recovered int
+	// Transient operations still failed after the last retry; Fatal
+	// operations failed with an error that isn't worth retrying
+	Transient, Fatal int
+}
+
+// retryWriter retries the file operations of a directory output that fail
+// with transient errors, doubling the wait before every retry. Directory
+// writes can be repeated safely; archive entries can't, so archives aren't
+// retried.
+type retryWriter struct {
+	DatasetWriter
+	retries int
+	backoff time.Duration
+
+	mu    sync.Mutex
+	stats retryStats
+}
+
+func newRetryWriter(writer DatasetWriter, retries int, backoff time.Duration) *retryWriter {
+	return &retryWriter{DatasetWriter: writer, retries: retries, backoff: backoff}
+}
+
+// do runs a file operation, retrying it after transient errors
+func (w *retryWriter) do(op func() error) error {
+	err := op()
+	if err == nil {
+		return nil
+	}
+	if !isTransient(err) {
+		w.count(func(s *retryStats) { s.Fatal++ })
+		return err
+	}
+
+	w.count(func(s *retryStats) { s.Retried++ })
+	delay := w.backoff
+	for attempt := 1; attempt <= w.retries; attempt++ {
+		time.Sleep(delay)
+		delay *= 2
+		metrics.AddRetry()
+		if err = op(); err == nil {
+			w.count(func(s *retryStats) { s.Recovered++ })
+			return nil
+		}
+		if !isTransient(err) {
+			w.count(func(s *retryStats) { s.Fatal++ })
+			return err
+		}
+	}
+	w.count(func(s *retryStats) { s.Transient++ })
+	return fmt.Errorf("%w (transient error, gave up after %d retries)", err, w.retries)
+}
+
+func (w *retryWriter) count(update func(*retryStats)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	update(&w.stats)
+}
+
+// MkdirAll creates a directory, retrying transient errors
+func (w *retryWriter) MkdirAll(dir string) error {
+	return w.do(func() error { return w.DatasetWriter.MkdirAll(dir) })
+}
+
+// CopyFile copies srcPath to name, retrying transient errors
+func (w *retryWriter) CopyFile(name, srcPath string) error {
+	return w.do(func() error { return w.DatasetWriter.CopyFile(name, srcPath) })
+}
+
+// WriteFile writes data to name, retrying transient errors
+func (w *retryWriter) WriteFile(name string, data []byte) error {
+	return w.do(func() error { return w.DatasetWriter.WriteFile(name, data) })
+}
+
+// reportFileErrors prints the file operations that failed, transient errors
+// apart from fatal ones; it is meant to be deferred after openOutput
+func (c *Converter) reportFileErrors() {
+	if c.retrier == nil {
+		return
+	}
+	c.retrier.mu.Lock()
+	defer c.retrier.mu.Unlock()
+	s := c.retrier.stats
+	if s.Retried == 0 && s.Fatal == 0 {
+		return
+	}
+	fmt.Fprintf(c.out, "File errors: %d transient (%d recovered on retry, %d gave up), %d fatal\n",
+		s.Retried, s.Recovered, s.Transient, s.Fatal)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// flakyWriter is a directory writer whose copies fail with err the first
+// failures times
+type flakyWriter struct {
+	*DirWriter
+	err      error
+	failures int
+	calls    int
+}
+
+func (w *flakyWriter) CopyFile(name, srcPath string) error {
+	w.calls++
+	if w.calls <= w.failures {
+		return &fs.PathError{Op: "write", Path: name, Err: w.err}
+	}
+	return w.DirWriter.CopyFile(name, srcPath)
+}
+
+func TestRetryWriter(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(src, []byte("0 0.5 0.5 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		err       error
+		failures  int
+		wantCalls int
+		wantErr   bool
+		want      retryStats
+	}{
+		{syscall.EIO, 2, 3, false, retryStats{Retried: 1, Recovered: 1}},
+		{syscall.ESTALE, 5, 4, true, retryStats{Retried: 1, Transient: 1}},
+		{syscall.EACCES, 1, 1, true, retryStats{Fatal: 1}},
+	} {
+		t.Run(fmt.Sprint(tc.err), func(t *testing.T) {
+			inner := &flakyWriter{DirWriter: &DirWriter{Root: t.TempDir()}, err: tc.err, failures: tc.failures}
+			writer := newRetryWriter(inner, 3, 0)
+			err := writer.CopyFile("a.txt", src)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("Expected the error to wrap %v, got %v", tc.err, err)
+			}
+			if inner.calls != tc.wantCalls {
+				t.Errorf("Expected %d attempts, got %d", tc.wantCalls, inner.calls)
+			}
+			if writer.stats != tc.want {
+				t.Errorf("Expected stats %+v, got %+v", tc.want, writer.stats)
+			}
+		})
+	}
+}
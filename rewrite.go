@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// labelFix rewrites the lines of one label file, returning the new lines and
+// how many lines it changed or dropped
+type labelFix func(pair LabelPair, lines []string) ([]string, int)
+
+// rewriteLabels applies a fix to every label file. Files the fix changes are
+// written to a subdirectory of the staging directory and their pairs point
+// there; source files are never modified. It returns the pairs and how many
+// lines and files changed.
+func (c *Converter) rewriteLabels(pairs []LabelPair, name string, fix labelFix) ([]LabelPair, int, int, error) {
+	lines, files := 0, 0
+	var dir string
+
+	result := make([]LabelPair, len(pairs))
+	for i, pair := range pairs {
+		result[i] = pair
+
+		data, err := os.ReadFile(pair.LabelPath)
+		if err != nil {
+			// Validation reports unreadable files
+			continue
+		}
+
+		fixed, changed := fix(pair, strings.Split(string(data), "\n"))
+		if changed == 0 {
+			continue
+		}
+
+		if dir == "" {
+			staging, err := c.stagingDirectory()
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			dir = filepath.Join(staging, name)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, 0, 0, fmt.Errorf("failed to create staging directory: %w", err)
+			}
+		}
+
+		labelPath := filepath.Join(dir, filepath.Base(pair.LabelPath))
+		if err := os.WriteFile(labelPath, []byte(strings.Join(fixed, "\n")), 0644); err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to write label %s: %w", labelPath, err)
+		}
+		result[i].LabelPath = labelPath
+		lines += changed
+		files++
+	}
+	return result, lines, files, nil
+}
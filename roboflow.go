@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// roboflowSplitDirs are the split directories of a Roboflow YOLO dataset;
+// Roboflow calls the validation split valid
+var roboflowSplitDirs = map[string]string{"train": "train", "val": "valid"}
+
+// roboflowYAML is the data.yaml of a Roboflow YOLO dataset, which points to
+// the splits relative to the dataset's parent directory the way Roboflow's
+// own exports do
+type roboflowYAML struct {
+	Train string   `yaml:"train"`
+	Val   string   `yaml:"val"`
+	NC    int      `yaml:"nc"`
+	Names []string `yaml:"names,flow"`
+}
+
+// writeRoboflow writes the layout Roboflow imports YOLO datasets from:
+// train/ and valid/, each with images/ and labels/, and a data.yaml naming
+// the classes. Labels are copied like those of the YOLO output, so boxes,
+// polygons and oriented boxes all keep their shape.
+func (c *Converter) writeRoboflow(classes []string, splits map[string][]LabelPair) error {
+	for _, split := range []string{"train", "val"} {
+		dir := roboflowSplitDirs[split]
+		imagesDir, labelsDir := path.Join(dir, "images"), path.Join(dir, "labels")
+		for _, d := range []string{imagesDir, labelsDir} {
+			if err := c.output().MkdirAll(d); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", d, err)
+			}
+		}
+
+		unstripped := 0
+		for _, pair := range splits[split] {
+			if err := c.interrupted(); err != nil {
+				return err
+			}
+			kept, err := c.copyPair(pair, imagesDir, labelsDir)
+			if err != nil {
+				return err
+			}
+			if kept {
+				unstripped++
+			}
+		}
+		if unstripped > 0 {
+			fmt.Fprintf(c.out, "Warning: metadata could not be stripped from %d %s images (only JPEG and PNG are supported)\n", unstripped, split)
+		}
+		fmt.Fprintf(c.out, "Copied %d %s files\n", len(splits[split]), split)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	config := roboflowYAML{Train: "../train/images", Val: "../valid/images", NC: len(classes), Names: classes}
+	if err := encoder.Encode(&config); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := c.output().WriteFile("data.yaml", buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to create YAML file: %w", err)
+	}
+	c.dataYAML = buf.Bytes()
+	return nil
+}
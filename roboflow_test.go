@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertRoboflow(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "roboflow")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.5, Seed: 42, OutputFormat: OutputRoboflow})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "train: ../train/images\nval: ../valid/images\nnc: 2\nnames: [book, person]\n"; string(data) != want {
+		t.Errorf("Expected data.yaml %q, got %q", want, data)
+	}
+
+	images := 0
+	for _, split := range []string{"train", "valid"} {
+		entries, _ := os.ReadDir(filepath.Join(outputDir, split, "images"))
+		for _, entry := range entries {
+			images++
+			label := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())) + ".txt"
+			got, err := os.ReadFile(filepath.Join(outputDir, split, "labels", label))
+			want, _ := os.ReadFile(filepath.Join(sourceDir, "labels", label))
+			if err != nil || string(got) != string(want) {
+				t.Errorf("Expected %s/labels/%s to be copied, got %q (%v)", split, label, got, err)
+			}
+		}
+	}
+	if images != 3 {
+		t.Errorf("Expected 3 images in train/ and valid/, got %d", images)
+	}
+	if !strings.Contains(out.String(), "Roboflow dataset ready at") {
+		t.Errorf("Expected the Roboflow completion message, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RoundTripIssue is a region that did not survive conversion unchanged
+type RoundTripIssue struct {
+	TaskID  int     `json:"task_id"`
+	Image   string  `json:"image"`
+	Problem string  `json:"problem"`
+	IoU     float64 `json:"iou,omitempty"`
+}
+
+// RoundTripReport summarizes how well annotations survive LS -> YOLO -> LS
+type RoundTripReport struct {
+	Tasks           int              `json:"tasks"`
+	SkippedTasks    int              `json:"skipped_tasks"`
+	Regions         int              `json:"regions"`
+	Matched         int              `json:"matched"`
+	Missing         int              `json:"missing"`
+	Extra           int              `json:"extra"`
+	ClassMismatches int              `json:"class_mismatches"`
+	Drifted         int              `json:"drifted"`
+	MeanIoU         float64          `json:"mean_iou"`
+	MinIoU          float64          `json:"min_iou"`
+	Issues          []RoundTripIssue `json:"issues"`
+}
+
+// roundTripRegion is a region reduced to what the check compares
+type roundTripRegion struct {
+	label  string
+	bounds [4]float64
+}
+
+// roundTripComparable reports whether a result is expected to come back from
+// a YOLO detection, segmentation or OBB dataset
+func roundTripComparable(result LSResult, shapeModes map[string]string) bool {
+	if len(result.Value.LabelNames()) == 0 {
+		return false
+	}
+	switch result.Type {
+	case "rectanglelabels", "polygonlabels", "brushlabels":
+		return true
+	case "ellipselabels", "polylinelabels":
+		return shapeModes[result.Type] != ShapeSkip
+	}
+	return false
+}
+
+// originalBounds returns the box of an original region. Rectangles are not
+// clipped to the image, so clipping during conversion shows up as drift.
+func originalBounds(result LSResult) ([4]float64, bool) {
+	if result.Type != "rectanglelabels" {
+		return resultBounds(result)
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range rotatedCorners(result) {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	return [4]float64{(minX + maxX) / 2, (minY + maxY) / 2, maxX - minX, maxY - minY}, true
+}
+
+// readBackLabels converts a YOLO label file into regions through the import
+// path, serializing each result as Label Studio JSON on the way
+func readBackLabels(labelPath string, o ImportOptions, classes []string, width, height int) ([]roundTripRegion, error) {
+	data, err := os.ReadFile(labelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read label %s: %w", labelPath, err)
+	}
+
+	var regions []roundTripRegion
+	for i, line := range strings.Split(string(data), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		imported, err := o.predictionResult(parts, classes, width, height)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filepath.Base(labelPath), i+1, err)
+		}
+
+		encoded, err := json.Marshal(imported)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode result: %w", err)
+		}
+		var result LSResult
+		if err := json.Unmarshal(encoded, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+
+		bounds, _ := resultBounds(result)
+		regions = append(regions, roundTripRegion{firstLabel(result), bounds})
+	}
+	return regions, nil
+}
+
+// matchRegions pairs original and converted regions greedily by IoU,
+// returning the pairs and the unmatched indices of both sides
+func matchRegions(original, converted []roundTripRegion) (pairs [][2]int, missing, extra []int) {
+	type candidate struct {
+		i, j int
+		iou  float64
+	}
+	var candidates []candidate
+	for i, a := range original {
+		for j, b := range converted {
+			if iou := boxIoU(a.bounds, b.bounds); iou > 0 {
+				candidates = append(candidates, candidate{i, j, iou})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(x, y int) bool {
+		return candidates[x].iou > candidates[y].iou
+	})
+
+	usedOriginal := make(map[int]bool)
+	usedConverted := make(map[int]bool)
+	for _, c := range candidates {
+		if usedOriginal[c.i] || usedConverted[c.j] {
+			continue
+		}
+		usedOriginal[c.i], usedConverted[c.j] = true, true
+		pairs = append(pairs, [2]int{c.i, c.j})
+	}
+
+	for i := range original {
+		if !usedOriginal[i] {
+			missing = append(missing, i)
+		}
+	}
+	for j := range converted {
+		if !usedConverted[j] {
+			extra = append(extra, j)
+		}
+	}
+	return pairs, missing, extra
+}
+
+// findLabelFile returns the converted label of an image in either split
+func findLabelFile(datasetDir, imageName string) (string, bool) {
+	stem := strings.TrimSuffix(imageName, filepath.Ext(imageName))
+	for _, split := range []string{"train", "val"} {
+		labelPath := filepath.Join(datasetDir, "labels", split, stem+".txt")
+		if _, err := os.Stat(labelPath); err == nil {
+			return labelPath, true
+		}
+	}
+	return "", false
+}
+
+// RoundTrip converts a Label Studio JSON export to YOLO, reads the labels back
+// as Label Studio results and compares them with the original annotations.
+// Regions match by IoU; drifted regions are those matched below minIoU.
+func RoundTrip(config Config, minIoU float64, out io.Writer) (*RoundTripReport, error) {
+	if config.SkeletonFile != "" || config.Classify || config.MultiLabel || config.Crop {
+		return nil, fmt.Errorf("round-trip checks support detection, segmentation and OBB datasets only")
+	}
+	config.InputFormat = InputLSJSON
+	config.Incremental = false
+
+	if config.OutputDir == "" {
+		dir, err := os.MkdirTemp("", "roundtrip-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		config.OutputDir = filepath.Join(dir, "dataset")
+	}
+
+	converter := NewConverter(config)
+	converter.SetOutput(out)
+	if err := converter.Convert(); err != nil {
+		return nil, fmt.Errorf("conversion failed: %w", err)
+	}
+
+	classes, err := loadClassNames(filepath.Join(config.OutputDir, "data.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	shapeModes, err := parseShapeModes(config.ShapeModes)
+	if err != nil {
+		return nil, err
+	}
+
+	// A second converter selects the original results exactly as the
+	// conversion did, with the same filters and consensus
+	reference := NewConverter(config)
+	reference.SetOutput(io.Discard)
+	tasks, err := reference.loadTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	importer := ImportOptions{OBB: config.OBB, ImagesDir: filepath.Join(config.SourceDir, "images")}
+	report := &RoundTripReport{MinIoU: 1}
+	totalIoU := 0.0
+
+	for _, task := range tasks {
+		results, ok := reference.taskResults(task)
+		if !ok || taskVideo(task) != "" {
+			continue
+		}
+		imageName := imageFileName(taskImage(task))
+		labelPath, found := findLabelFile(config.OutputDir, imageName)
+		if !found {
+			report.SkippedTasks++
+			continue
+		}
+		report.Tasks++
+
+		var original []roundTripRegion
+		for _, result := range results {
+			if !roundTripComparable(result, shapeModes) {
+				continue
+			}
+			if bounds, geometric := originalBounds(result); geometric {
+				original = append(original, roundTripRegion{firstLabel(result), bounds})
+			}
+		}
+
+		width, height := importer.imageSize(task)
+		converted, err := readBackLabels(labelPath, importer, classes, width, height)
+		if err != nil {
+			return nil, err
+		}
+
+		issue := func(problem string, iou float64) {
+			report.Issues = append(report.Issues, RoundTripIssue{TaskID: task.ID, Image: imageName, Problem: problem, IoU: iou})
+		}
+
+		pairs, missing, extra := matchRegions(original, converted)
+		report.Regions += len(original)
+		for _, pair := range pairs {
+			a, b := original[pair[0]], converted[pair[1]]
+			iou := boxIoU(a.bounds, b.bounds)
+			report.Matched++
+			totalIoU += iou
+			if iou < report.MinIoU {
+				report.MinIoU = iou
+			}
+			if a.label != b.label {
+				report.ClassMismatches++
+				issue(fmt.Sprintf("class %s came back as %s", a.label, b.label), iou)
+			} else if iou < minIoU {
+				report.Drifted++
+				issue(fmt.Sprintf("%s drifted", a.label), iou)
+			}
+		}
+		for _, i := range missing {
+			report.Missing++
+			issue(fmt.Sprintf("%s missing after conversion", original[i].label), 0)
+		}
+		for _, j := range extra {
+			report.Extra++
+			issue(fmt.Sprintf("unexpected %s after conversion", converted[j].label), 0)
+		}
+	}
+
+	if report.Matched > 0 {
+		report.MeanIoU = totalIoU / float64(report.Matched)
+	} else {
+		report.MinIoU = 0
+	}
+	return report, nil
+}
+
+// print writes the summary and the first issues of a report
+func (r *RoundTripReport) print(out io.Writer, maxIssues int) {
+	fmt.Fprintf(out, "\nRound-trip: %d tasks, %d regions, %d matched (mean IoU %.6f, min IoU %.6f)\n",
+		r.Tasks, r.Regions, r.Matched, r.MeanIoU, r.MinIoU)
+	fmt.Fprintf(out, "  %d drifted, %d class mismatches, %d missing, %d extra\n",
+		r.Drifted, r.ClassMismatches, r.Missing, r.Extra)
+	if r.SkippedTasks > 0 {
+		fmt.Fprintf(out, "  %d tasks were not converted\n", r.SkippedTasks)
+	}
+	for i, issue := range r.Issues {
+		if i == maxIssues {
+			fmt.Fprintf(out, "  ... and %d more\n", len(r.Issues)-maxIssues)
+			break
+		}
+		fmt.Fprintf(out, "  task %d (%s): %s", issue.TaskID, issue.Image, issue.Problem)
+		if issue.IoU > 0 {
+			fmt.Fprintf(out, " (IoU %.6f)", issue.IoU)
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// runRoundTrip implements the roundtrip subcommand. It fails when any region
+// drifted, changed class, or was lost or added.
+func runRoundTrip(args []string) error {
+	var config Config
+	fs := flag.NewFlagSet("roundtrip", flag.ExitOnError)
+	fs.StringVar(&config.SourceDir, "source", ".", "Path to the Label Studio export directory (JSON export plus images/)")
+	fs.StringVar(&config.TasksFile, "tasks", "", "Label Studio JSON export (default: the .json file in -source)")
+	fs.StringVar(&config.OutputDir, "output", "", "Keep the intermediate YOLO dataset here (default: a temporary directory)")
+	registerConvertFlags(fs, &config)
+	minIoU := fs.Float64("min-iou", 0.99, "Minimum IoU between an original region and its round-tripped copy")
+	reportPath := fs.String("report", "", "Write the full report as JSON to this file")
+	verbose := fs.Bool("verbose", false, "Show the conversion log")
+	fs.Parse(args)
+
+	var log io.Writer = io.Discard
+	if *verbose {
+		log = os.Stdout
+	}
+	report, err := RoundTrip(config, *minIoU, log)
+	if err != nil {
+		return err
+	}
+	report.print(os.Stdout, 20)
+
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		if err := os.WriteFile(*reportPath, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	if len(report.Issues) > 0 {
+		return fmt.Errorf("round-trip check failed with %d issues", len(report.Issues))
+	}
+	fmt.Println("Round-trip check passed")
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoundTripPasses(t *testing.T) {
+	sourceDir := t.TempDir()
+	createLabelStudioExport(t, sourceDir)
+
+	report, err := RoundTrip(Config{SourceDir: sourceDir, TrainSplit: 0.5, Seed: 42, MaskTolerance: 1}, 0.99, io.Discard)
+	if err != nil {
+		t.Fatalf("RoundTrip failed: %v", err)
+	}
+
+	if report.Tasks != 3 || report.Regions != 3 || report.Matched != 3 {
+		t.Errorf("Expected 3 tasks with 3 matched regions, got %+v", *report)
+	}
+	if len(report.Issues) != 0 || report.MinIoU < 0.999 {
+		t.Errorf("Expected a clean round trip, got %+v", *report)
+	}
+}
+
+func TestRoundTripReportsDrift(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sourceDir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "images", "edge.jpg"), []byte("fake image data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second box hangs off the image and is clipped by the conversion
+	tasks := []LSTask{{
+		ID:   1,
+		Data: map[string]interface{}{"image": "edge.jpg"},
+		Annotations: []LSAnnotation{{Result: []LSResult{
+			box("cat", 10, 10, 20, 20),
+			box("dog", 80, 80, 40, 40),
+		}}},
+	}}
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "export.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := RoundTrip(Config{SourceDir: sourceDir, TrainSplit: 1, Seed: 42}, 0.99, io.Discard)
+	if err != nil {
+		t.Fatalf("RoundTrip failed: %v", err)
+	}
+	if report.Matched != 2 || report.Drifted != 1 || len(report.Issues) != 1 {
+		t.Fatalf("Expected one drifted region, got %+v", *report)
+	}
+	if issue := report.Issues[0]; !strings.Contains(issue.Problem, "dog drifted") || issue.IoU > 0.3 {
+		t.Errorf("Unexpected issue %+v", issue)
+	}
+}
+
+func TestMatchRegions(t *testing.T) {
+	original := []roundTripRegion{
+		{"cat", [4]float64{0.2, 0.2, 0.2, 0.2}},
+		{"dog", [4]float64{0.7, 0.7, 0.2, 0.2}},
+	}
+	converted := []roundTripRegion{
+		{"dog", [4]float64{0.71, 0.7, 0.2, 0.2}},
+		{"bird", [4]float64{0.5, 0.1, 0.05, 0.05}},
+	}
+
+	pairs, missing, extra := matchRegions(original, converted)
+	if len(pairs) != 1 || pairs[0] != [2]int{1, 0} {
+		t.Errorf("Expected dog to match, got %v", pairs)
+	}
+	if len(missing) != 1 || missing[0] != 0 || len(extra) != 1 || extra[0] != 1 {
+		t.Errorf("Expected cat missing and bird extra, got %v and %v", missing, extra)
+	}
+}
+
+func TestRoundTripRejectsUnsupportedModes(t *testing.T) {
+	if _, err := RoundTrip(Config{Classify: true}, 0.99, io.Discard); err == nil {
+		t.Error("Expected error for classification round trips")
+	}
+}
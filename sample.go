@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// validateSample checks the -sample and -max-images options
+func (c *Converter) validateSample() error {
+	if c.config.Sample < 0 || c.config.Sample > 1 {
+		return fmt.Errorf("sample fraction must be between 0 and 1, got %g", c.config.Sample)
+	}
+	if c.config.MaxImages < 0 {
+		return fmt.Errorf("max images must not be negative")
+	}
+	return nil
+}
+
+// samplePairs keeps a random subset of the pairs, seeded with -seed: a
+// -sample fraction of them, at most -max-images. Grouped pairs are kept or
+// dropped together and the kept pairs stay in their original order.
+func (c *Converter) samplePairs(pairs []LabelPair) []LabelPair {
+	limit := len(pairs)
+	if c.config.Sample > 0 {
+		// A fraction too small for a whole image still keeps one
+		limit = max(1, int(float64(len(pairs))*c.config.Sample))
+	}
+	if c.config.MaxImages > 0 && c.config.MaxImages < limit {
+		limit = c.config.MaxImages
+	}
+	if limit >= len(pairs) {
+		return pairs
+	}
+
+	units := groupPairs(pairs)
+	order := rand.New(rand.NewSource(c.config.Seed)).Perm(len(units))
+	keep := make([]bool, len(units))
+	kept := 0
+	for _, i := range order {
+		if kept >= limit {
+			break
+		}
+		keep[i] = true
+		kept += len(units[i])
+	}
+
+	var sampled []LabelPair
+	for i, unit := range units {
+		if keep[i] {
+			sampled = append(sampled, unit...)
+		}
+	}
+	fmt.Fprintf(c.out, "Sampled %d of %d image-label pairs\n", len(sampled), len(pairs))
+	return sampled
+}
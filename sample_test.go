@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestSamplePairs(t *testing.T) {
+	var pairs []LabelPair
+	for i := 0; i < 100; i++ {
+		pairs = append(pairs, LabelPair{ImagePath: fmt.Sprintf("image%03d.jpg", i), LabelPath: fmt.Sprintf("image%03d.txt", i)})
+	}
+
+	converter := NewConverter(Config{Sample: 0.1, Seed: 7})
+	converter.SetOutput(io.Discard)
+	sampled := converter.samplePairs(pairs)
+	if len(sampled) != 10 {
+		t.Fatalf("Expected 10 pairs, got %d", len(sampled))
+	}
+	for i := 1; i < len(sampled); i++ {
+		if sampled[i-1].ImagePath >= sampled[i].ImagePath {
+			t.Fatalf("Expected sampled pairs in their original order, got %v", sampled)
+		}
+	}
+
+	// The same seed picks the same subset
+	again := converter.samplePairs(pairs)
+	for i := range sampled {
+		if again[i] != sampled[i] {
+			t.Fatalf("Expected a reproducible sample, got %v and %v", sampled, again)
+		}
+	}
+
+	converter.config.MaxImages = 4
+	if got := len(converter.samplePairs(pairs)); got != 4 {
+		t.Errorf("Expected -max-images to cap the sample at 4, got %d", got)
+	}
+	converter.config.Sample = 0.001
+	converter.config.MaxImages = 0
+	if got := len(converter.samplePairs(pairs)); got != 1 {
+		t.Errorf("Expected a tiny fraction to keep one pair, got %d", got)
+	}
+	converter.config.Sample = 0
+	converter.config.MaxImages = 500
+	if got := len(converter.samplePairs(pairs)); got != 100 {
+		t.Errorf("Expected every pair below -max-images, got %d", got)
+	}
+}
+
+func TestSampleKeepsGroupsTogether(t *testing.T) {
+	var pairs []LabelPair
+	for video := 0; video < 10; video++ {
+		for frame := 0; frame < 3; frame++ {
+			pairs = append(pairs, LabelPair{ImagePath: fmt.Sprintf("v%d_f%d.jpg", video, frame), Group: fmt.Sprintf("v%d", video)})
+		}
+	}
+
+	converter := NewConverter(Config{MaxImages: 7, Seed: 1})
+	converter.SetOutput(io.Discard)
+	sampled := converter.samplePairs(pairs)
+
+	frames := make(map[string]int)
+	for _, pair := range sampled {
+		frames[pair.Group]++
+	}
+	for group, n := range frames {
+		if n != 3 {
+			t.Errorf("Expected all frames of %s, got %d", group, n)
+		}
+	}
+	if len(sampled) != 9 {
+		t.Errorf("Expected three whole videos, got %d frames", len(sampled))
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleSearchYears bounds the search for the next run, so expressions that
+// can never match (e.g. February 30th) don't loop forever
+const scheduleSearchYears = 5
+
+// scheduleMacros are the shorthand expressions accepted in place of the five
+// fields
+var scheduleMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// Schedule is a parsed cron expression with the usual five fields: minute,
+// hour, day of month, month and day of week. Each field is a bit set of the
+// values it matches.
+type Schedule struct {
+	spec    string
+	minute  uint64
+	hour    uint64
+	day     uint64
+	month   uint64
+	weekday uint64
+	// anyDay and anyWeekday record unrestricted day fields; when both are
+	// restricted a time matches if either does, as in cron
+	anyDay     bool
+	anyWeekday bool
+}
+
+// scheduleField describes the values one cron field accepts
+type scheduleField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField  = scheduleField{name: "minute", min: 0, max: 59}
+	hourField    = scheduleField{name: "hour", min: 0, max: 23}
+	dayField     = scheduleField{name: "day of month", min: 1, max: 31}
+	monthField   = scheduleField{name: "month", min: 1, max: 12, names: map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}}
+	weekdayField = scheduleField{name: "day of week", min: 0, max: 7, names: map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}}
+)
+
+// ParseSchedule parses a cron expression such as "0 2 * * *" or "@daily".
+// Fields accept *, values, ranges (1-5), lists (1,15) and steps (*/15, 0-30/10);
+// months and weekdays also accept their three-letter names, and 7 is Sunday.
+func ParseSchedule(spec string) (*Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if macro, ok := scheduleMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day month weekday), got %d", spec, len(fields))
+	}
+
+	s := &Schedule{
+		spec:       strings.TrimSpace(spec),
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	for i, target := range []*uint64{&s.minute, &s.hour, &s.day, &s.month, &s.weekday} {
+		field := []scheduleField{minuteField, hourField, dayField, monthField, weekdayField}[i]
+		if *target, err = parseScheduleField(fields[i], field); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	// Sunday is both 0 and 7
+	if s.weekday&(1<<7) != 0 {
+		s.weekday |= 1
+	}
+
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("invalid schedule %q: it never runs", spec)
+	}
+	return s, nil
+}
+
+// parseScheduleField parses one comma-separated cron field into a bit set
+func parseScheduleField(text string, field scheduleField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(text, ",") {
+		rangeText, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in the %s field", stepText, field.name)
+			}
+			step = n
+		}
+
+		low, high := field.min, field.max
+		switch {
+		case rangeText == "*":
+		case strings.Contains(rangeText, "-"):
+			lowText, highText, _ := strings.Cut(rangeText, "-")
+			var err error
+			if low, err = field.value(lowText); err != nil {
+				return 0, err
+			}
+			if high, err = field.value(highText); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q in the %s field", rangeText, field.name)
+			}
+		default:
+			value, err := field.value(rangeText)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			// "5/10" means from 5 to the end in steps of 10
+			if !hasStep {
+				high = value
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single number or name and checks its bounds
+func (f scheduleField) value(text string) (int, error) {
+	if v, ok := f.names[strings.ToLower(text)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in the %s field (%d-%d)", text, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time after t that matches the schedule, in t's
+// location, or the zero time if there is none within the search window
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + scheduleSearchYears
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay applies cron's day rule: when both the day of month and the day
+// of week are restricted, either may match
+func (s *Schedule) matchesDay(t time.Time) bool {
+	day := s.day&(1<<uint(t.Day())) != 0
+	weekday := s.weekday&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// ScheduleSkipped is the status of a scheduled run that was skipped because
+// the project was still being converted
+const ScheduleSkipped = "skipped"
+
+// maxScheduledRuns bounds the run history the server keeps
+const maxScheduledRuns = 500
+
+// ScheduledRun records one project conversion triggered by the schedule.
+// Status follows the job it queued, or is skipped or failed when none was.
+type ScheduledRun struct {
+	Project     int       `json:"project"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	JobID       string    `json:"job_id,omitempty"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// ScheduleStatus is the body returned by GET /schedule
+type ScheduleStatus struct {
+	Schedule string         `json:"schedule"`
+	Projects []int          `json:"projects"`
+	NextRun  time.Time      `json:"next_run"`
+	Runs     []ScheduledRun `json:"runs"`
+}
+
+// EnableSchedule pulls and converts the given Label Studio projects whenever
+// the schedule fires, until the server stops
+func (s *Server) EnableSchedule(schedule *Schedule, projects []int) {
+	s.mu.Lock()
+	s.schedule = schedule
+	s.scheduleProjects = projects
+	s.scheduleStop = make(chan struct{})
+	stop := s.scheduleStop
+	s.mu.Unlock()
+
+	fmt.Printf("Converting projects %v on schedule %q (next run at %s)\n", projects, schedule, schedule.Next(time.Now()).Format(time.RFC3339))
+	go func() {
+		for {
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				return
+			}
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+				s.runScheduled(next)
+			}
+		}
+	}()
+}
+
+// runScheduled queues a job for each scheduled project. A project that still
+// has a queued or running job is skipped, so slow conversions don't pile up.
+func (s *Server) runScheduled(at time.Time) {
+	for _, projectID := range s.scheduleProjects {
+		run := ScheduledRun{Project: projectID, ScheduledAt: at.UTC()}
+		if busy := s.activeProjectJob(projectID); busy != "" {
+			run.Status = ScheduleSkipped
+			run.Error = fmt.Sprintf("job %s for the project is still in progress", busy)
+			fmt.Printf("Skipped the scheduled conversion of project %d: job %s is still in progress\n", projectID, busy)
+		} else if job, err := s.Submit(JobRequest{Project: projectID}); err != nil {
+			run.Status = JobFailed
+			run.Error = err.Error()
+			fmt.Fprintf(os.Stderr, "Failed to queue the scheduled conversion of project %d: %v\n", projectID, err)
+		} else {
+			run.JobID = job.ID
+			run.Status = JobQueued
+			fmt.Printf("Queued scheduled job %s for project %d\n", job.ID, projectID)
+		}
+
+		s.mu.Lock()
+		s.scheduledRuns = append(s.scheduledRuns, run)
+		if len(s.scheduledRuns) > maxScheduledRuns {
+			s.scheduledRuns = s.scheduledRuns[len(s.scheduledRuns)-maxScheduledRuns:]
+		}
+		s.mu.Unlock()
+	}
+}
+
+// activeProjectJob returns the ID of a queued or running job for the project,
+// or "" if there is none
+func (s *Server) activeProjectJob(projectID int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Request.Project == projectID && (job.Status == JobQueued || job.Status == JobRunning) {
+			return job.ID
+		}
+	}
+	return ""
+}
+
+// ScheduledRuns returns the history of scheduled runs, oldest first, with the
+// current status of the jobs they queued
+func (s *Server) ScheduledRuns() []ScheduledRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := make([]ScheduledRun, len(s.scheduledRuns))
+	copy(runs, s.scheduledRuns)
+	for i, run := range runs {
+		if job, ok := s.jobs[run.JobID]; ok {
+			runs[i].Status = job.Status
+			runs[i].Error = job.Error
+		}
+	}
+	return runs
+}
+
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.schedule == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no schedule is configured"))
+		return
+	}
+	writeJSON(w, http.StatusOK, ScheduleStatus{
+		Schedule: s.schedule.String(),
+		Projects: s.scheduleProjects,
+		NextRun:  s.schedule.Next(time.Now()),
+		Runs:     s.ScheduledRuns(),
+	})
+}
+
+// parseProjectIDs parses a comma-separated list of Label Studio project IDs
+func parseProjectIDs(text string) ([]int, error) {
+	var ids []int
+	for _, field := range strings.Split(text, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid project ID %q", field)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no project IDs given")
+	}
+	return ids, nil
+}
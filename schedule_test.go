@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2024, 5, 15, 10, 30, 45, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"0 2 * * *", time.Date(2024, 5, 16, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 5, 15, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2024, 5, 16, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * mon-fri", time.Date(2024, 5, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,20 * *", time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)},
+		// Either the day of month or the day of week matches
+		{"0 0 1 * fri", time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule failed: %v", err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{"", "0 2 * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 30 feb *", "0 0 * * funday"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestScheduledRunsSkipOverlap(t *testing.T) {
+	archivePath := zipExport(t)
+	release := make(chan struct{})
+	labelStudio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hold the export until the overlapping run has been skipped
+		<-release
+		data, _ := os.ReadFile(archivePath)
+		w.Write(data)
+	}))
+	defer labelStudio.Close()
+
+	schedule, err := ParseSchedule("0 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(Config{TrainSplit: 0.8, Seed: 42}, t.TempDir(), 4)
+	server.EnableWebhooks(LabelStudioConfig{URL: labelStudio.URL}, "", nil, time.Second)
+	server.EnableSchedule(schedule, []int{7})
+	server.Start(1)
+	defer server.Stop()
+
+	server.runScheduled(time.Date(2024, 5, 15, 2, 0, 0, 0, time.UTC))
+	server.runScheduled(time.Date(2024, 5, 16, 2, 0, 0, 0, time.UTC))
+	close(release)
+
+	runs := server.ScheduledRuns()
+	if len(runs) != 2 {
+		t.Fatalf("Expected 2 scheduled runs, got %d", len(runs))
+	}
+	if runs[1].Status != ScheduleSkipped || runs[1].JobID != "" {
+		t.Errorf("Expected the overlapping run to be skipped, got %+v", runs[1])
+	}
+	if job := waitForJob(t, server, runs[0].JobID); job.Status != JobSucceeded {
+		t.Fatalf("Expected the scheduled job to succeed, got %s: %s", job.Status, job.Error)
+	}
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/schedule")
+	if err != nil {
+		t.Fatalf("Failed to get the schedule: %v", err)
+	}
+	defer resp.Body.Close()
+	var status ScheduleStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode the schedule: %v", err)
+	}
+	if status.Schedule != "0 2 * * *" || len(status.Runs) != 2 || status.Runs[0].Status != JobSucceeded {
+		t.Errorf("Unexpected schedule status: %+v", status)
+	}
+	if status.NextRun.Hour() != 2 || status.NextRun.Minute() != 0 {
+		t.Errorf("Expected the next run at 02:00, got %s", status.NextRun)
+	}
+}
+
+func TestParseProjectIDs(t *testing.T) {
+	ids, err := parseProjectIDs("7, 9")
+	if err != nil || len(ids) != 2 || ids[0] != 7 || ids[1] != 9 {
+		t.Errorf("Expected [7 9], got %v (%v)", ids, err)
+	}
+	for _, text := range []string{"", "7,x", "-1"} {
+		if _, err := parseProjectIDs(text); err == nil || !strings.Contains(err.Error(), "project") {
+			t.Errorf("Expected an error for %q, got %v", text, err)
+		}
+	}
+}
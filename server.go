@@ -0,0 +1,990 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Job statuses reported by the server
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// ErrQueueFull is returned when a job is submitted while the queue is at capacity
+var ErrQueueFull = errors.New("job queue is full")
+
+// errJobNotFound is returned for unknown job IDs
+var errJobNotFound = errors.New("job not found")
+
+// Default limits on the sources jobs download and extract
+const (
+	defaultMaxDownload       = 10 << 30
+	defaultMaxExtract        = 50 << 30
+	defaultMaxExtractEntries = 1000000
+)
+
+// SourceLimits caps the source archives jobs download and what extracting
+// them writes; zero fields are unlimited
+type SourceLimits struct {
+	MaxDownload       int64
+	MaxExtract        int64
+	MaxExtractEntries int
+}
+
+// JobRequest is the body accepted by POST /jobs. Source is a local export
+// directory below the server's source root or an http(s) URL to a
+// .zip/.tar.gz export. Options holds any conversion flag by name (without the
+// leading dash), e.g. "train-split".
+//
+// Project selects a Label Studio project instead of a source, and can't be
+// combined with one; its YOLO export is pulled from the API and converted
+// incrementally into a directory that is kept between jobs.
+type JobRequest struct {
+	Source  string            `json:"source,omitempty"`
+	Project int               `json:"project,omitempty"`
+	Format  string            `json:"format,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// LabelStudioConfig holds the API connection used to pull project exports
+type LabelStudioConfig struct {
+	URL   string
+	Token string
+}
+
+// ProjectExportURL returns the API URL of a project's YOLO export, including
+// the images
+func (ls LabelStudioConfig) ProjectExportURL(projectID int) string {
+	return fmt.Sprintf("%s/api/projects/%d/export?exportType=YOLO&download_resources=true",
+		strings.TrimRight(ls.URL, "/"), projectID)
+}
+
+// Job tracks a conversion submitted to the server
+type Job struct {
+	ID      string     `json:"id"`
+	Status  string     `json:"status"`
+	Request JobRequest `json:"request"`
+	Error   string     `json:"error,omitempty"`
+	// Problems counts the validation problems found, by rule
+	Problems   map[string]int `json:"problems,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Log        string         `json:"log,omitempty"`
+	// RerunOf is the ID of the job this one repeats
+	RerunOf string `json:"rerun_of,omitempty"`
+
+	config     Config
+	resultPath string
+	log        *syncBuffer
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use, so a job's log
+// can be read while its conversion is still writing to it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Server runs conversion jobs submitted over HTTP on a pool of workers
+type Server struct {
+	defaults Config
+	dataDir  string
+	client   *http.Client
+	queue    chan *Job
+	wg       sync.WaitGroup
+
+	// sourceRoot is the directory local job sources must lie below; local
+	// sources are rejected while it is empty
+	sourceRoot   string
+	sourceLimits SourceLimits
+
+	labelStudio    LabelStudioConfig
+	webhookSecret  string
+	webhookActions map[string]bool
+	debounce       time.Duration
+
+	schedule         *Schedule
+	scheduleProjects []int
+	scheduleStop     chan struct{}
+
+	mu           sync.Mutex
+	jobs         map[string]*Job
+	stopped      bool
+	pending      map[int]*time.Timer
+	projectLocks map[int]*sync.Mutex
+	// scheduledRuns is the history of scheduled runs, oldest first
+	scheduledRuns []ScheduledRun
+}
+
+// NewServer creates a server whose jobs start from the defaults config and
+// write their results below dataDir
+func NewServer(defaults Config, dataDir string, queueSize int) *Server {
+	return &Server{
+		defaults: defaults,
+		dataDir:  dataDir,
+		client:   &http.Client{Timeout: 30 * time.Minute},
+		queue:    make(chan *Job, queueSize),
+		jobs:     make(map[string]*Job),
+
+		sourceLimits: SourceLimits{
+			MaxDownload:       defaultMaxDownload,
+			MaxExtract:        defaultMaxExtract,
+			MaxExtractEntries: defaultMaxExtractEntries,
+		},
+
+		pending:      make(map[int]*time.Timer),
+		projectLocks: make(map[int]*sync.Mutex),
+	}
+}
+
+// EnableWebhooks lets Label Studio trigger conversions. Matching webhook
+// events for a project are debounced and then queue an incremental project
+// job; secret, when set, must be sent in the X-Webhook-Secret header.
+func (s *Server) EnableWebhooks(ls LabelStudioConfig, secret string, actions []string, debounce time.Duration) {
+	s.labelStudio = ls
+	s.webhookSecret = secret
+	s.debounce = debounce
+	s.webhookActions = make(map[string]bool)
+	for _, action := range actions {
+		s.webhookActions[strings.TrimSpace(action)] = true
+	}
+}
+
+// EnableLocalSources lets jobs convert local export directories below root.
+// Relative sources are resolved against root.
+func (s *Server) EnableLocalSources(root string) error {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("invalid source root: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return fmt.Errorf("invalid source root: %w", err)
+	}
+	s.sourceRoot = resolved
+	return nil
+}
+
+// SetSourceLimits limits the source archives jobs download and extract
+func (s *Server) SetSourceLimits(limits SourceLimits) {
+	s.sourceLimits = limits
+}
+
+// localSource resolves a local job source, following symlinks, and checks
+// that it lies below the source root
+func (s *Server) localSource(source string) (string, error) {
+	if s.sourceRoot == "" {
+		return "", fmt.Errorf("local sources are disabled; start the server with -source-root to allow them")
+	}
+	path := source
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.sourceRoot, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("source not found: %s", source)
+	}
+	rel, err := filepath.Rel(s.sourceRoot, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("source is outside the source root: %s", source)
+	}
+	return resolved, nil
+}
+
+// Start launches the worker goroutines
+func (s *Server) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for job := range s.queue {
+				s.runJob(job)
+			}
+		}()
+	}
+}
+
+// Stop cancels pending webhook triggers, closes the queue and waits for
+// running and queued jobs to finish
+func (s *Server) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	if s.scheduleStop != nil {
+		close(s.scheduleStop)
+		s.scheduleStop = nil
+	}
+	for id, timer := range s.pending {
+		timer.Stop()
+		delete(s.pending, id)
+	}
+	close(s.queue)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// jobOptions are the conversion flags API clients may set in a job's
+// options. Jobs are submitted without authentication, so the other flags
+// are left to the server defaults.
+var jobOptions = map[string]bool{
+	"train-split": true, "seed": true, "sample": true, "max-images": true,
+	"split-unit": true, "split-strategy": true, "split-order": true,
+	"min-val-instances": true, "max-per-class": true, "min-per-class": true,
+	"incremental": true, "obb": true, "classify": true, "multi-label": true,
+	"label-order": true, "crop": true, "crop-padding": true, "crop-min-size": true,
+	"shape-mode": true, "frame-step": true, "consensus": true, "consensus-iou": true,
+	"review-status": true, "include-cancelled": true, "annotators": true,
+	"exclude-annotators": true, "since": true, "until": true, "predictions": true,
+	"min-score": true, "min-box-pixels": true, "min-image-size": true,
+	"max-aspect-ratio": true, "normalize-pixels": true, "duplicate-iou": true,
+	"dedupe": true, "degenerate-epsilon": true, "drop-degenerate": true,
+	"prune-empty-classes": true, "include": true, "exclude": true, "image-ext": true,
+	"transcode": true, "apply-exif-orientation": true, "strip-metadata": true,
+	"tile-size": true, "tile-overlap": true, "tile-background": true, "augment": true,
+	"augment-multiplier": true, "anonymize": true, "anonymize-mode": true,
+	"preserve-attrs": true, "checksums": true, "stream": true, "retries": true,
+	"retry-backoff": true, "resume": true, "force-unlock": true,
+	"allow-orphan-labels": true, "strict": true, "parallel": true, "input-format": true,
+	"mask-tolerance": true, "output-format": true, "tfrecord-shards": true,
+	"fiftyone": true, "catalog": true, "openimages-classes": true, "via-attribute": true,
+	"granularity": true, "confidence-column": true, "min-conf": true, "precision": true,
+	"canonical-labels": true, "provenance": true, "mlflow-experiment": true,
+	"mlflow-run-id": true, "mlflow-log-dataset": true, "clearml-project": true,
+	"clearml-dataset": true, "clearml-tags": true, "dataset-version": true,
+	"class-weights": true, "class-weights-format": true, "leakage": true,
+	"leakage-distance": true, "image-quality": true, "blur-threshold": true,
+	"exposure-clip": true, "where": true, "split-group-by": true,
+	"split-stratify-by": true, "task-metadata": true, "mapping": true,
+}
+
+// jobConfig builds the conversion config for a request on top of the server
+// defaults, using the same flag definitions as the command line
+func (s *Server) jobConfig(req JobRequest) (Config, error) {
+	var config Config
+	fs := flag.NewFlagSet("job", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerConvertFlags(fs, &config)
+	config = s.defaults
+
+	names := make([]string, 0, len(req.Options))
+	for name := range req.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			return config, fmt.Errorf("unknown option: %s", name)
+		}
+		if !jobOptions[name] {
+			return config, fmt.Errorf("option %s can only be set in the server defaults", name)
+		}
+		if err := fs.Set(name, req.Options[name]); err != nil {
+			return config, fmt.Errorf("invalid value for option %s: %w", name, err)
+		}
+	}
+
+	if config.TrainSplit < 0 || config.TrainSplit > 1 {
+		return config, fmt.Errorf("train-split must be between 0 and 1")
+	}
+	switch config.InputFormat {
+	case "", InputAuto, InputYOLO, InputLSJSON, InputCVAT, InputOpenImages, InputVIA, InputLabelbox:
+	default:
+		return config, fmt.Errorf("unsupported input format: %s", config.InputFormat)
+	}
+	return config, nil
+}
+
+// Submit validates a request and queues it as a new job
+func (s *Server) Submit(req JobRequest) (*Job, error) {
+	return s.submit(req, "")
+}
+
+// submit queues a new job, recording the job it repeats if any
+func (s *Server) submit(req JobRequest, rerunOf string) (*Job, error) {
+	// Project jobs update the shared project dataset, so they only ever
+	// convert the project's own export
+	if req.Project > 0 {
+		if s.labelStudio.URL == "" {
+			return nil, fmt.Errorf("project jobs require a Label Studio URL")
+		}
+		if req.Source != "" {
+			return nil, fmt.Errorf("project jobs can't set a source")
+		}
+		req.Source = s.labelStudio.ProjectExportURL(req.Project)
+	}
+	if req.Source == "" {
+		return nil, fmt.Errorf("source is required")
+	}
+	if !isRemoteSource(req.Source) {
+		if _, err := s.localSource(req.Source); err != nil {
+			return nil, err
+		}
+	}
+	if req.Format == "" {
+		req.Format = "tar.gz"
+	}
+	if req.Format != "tar.gz" && req.Format != "zip" {
+		return nil, fmt.Errorf("unsupported format: %s", req.Format)
+	}
+
+	config, err := s.jobConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:        id,
+		Status:    JobQueued,
+		Request:   req,
+		CreatedAt: time.Now().UTC(),
+		RerunOf:   rerunOf,
+		config:    config,
+		log:       &syncBuffer{},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return nil, fmt.Errorf("server is shutting down")
+	}
+
+	select {
+	case s.queue <- job:
+	default:
+		return nil, ErrQueueFull
+	}
+	s.jobs[job.ID] = job
+	s.saveJob(job)
+	return job, nil
+}
+
+// Job returns a snapshot of the job with the given ID
+func (s *Server) Job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.snapshot(), true
+}
+
+// Jobs returns snapshots of all jobs, oldest first
+func (s *Server) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		snap := job.snapshot()
+		snap.Log = ""
+		jobs = append(jobs, snap)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].ID < jobs[j].ID
+		}
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// snapshot copies the exported job state; callers must hold the server lock
+func (j *Job) snapshot() Job {
+	snap := *j
+	snap.Log = j.log.String()
+	return snap
+}
+
+func (s *Server) setStatus(job *Job, status string, jobErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	job.Status = status
+	switch status {
+	case JobRunning:
+		job.StartedAt = &now
+		metrics.JobStarted()
+	case JobSucceeded, JobFailed:
+		job.FinishedAt = &now
+		if job.StartedAt != nil {
+			metrics.JobFinished(status, now.Sub(*job.StartedAt))
+		}
+	}
+	if jobErr != nil {
+		job.Error = jobErr.Error()
+	}
+	s.saveJob(job)
+}
+
+// runJob fetches the job's source if needed and runs the conversion into an
+// archive in the job's working directory
+func (s *Server) runJob(job *Job) {
+	s.setStatus(job, JobRunning, nil)
+
+	workDir := filepath.Join(s.dataDir, job.ID)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		s.setStatus(job, JobFailed, err)
+		return
+	}
+
+	config := job.config
+	config.OutputDir = filepath.Join(workDir, "dataset."+job.Request.Format)
+	if job.Request.Project > 0 {
+		// Project jobs share one output directory, so run them one at a time
+		unlock := s.lockProject(job.Request.Project)
+		defer unlock()
+		config.OutputDir = s.projectOutputDir(job.Request.Project)
+		config.Incremental = true
+	}
+
+	if !isRemoteSource(job.Request.Source) {
+		sourceDir, err := s.localSource(job.Request.Source)
+		if err != nil {
+			s.setStatus(job, JobFailed, err)
+			return
+		}
+		config.SourceDir = sourceDir
+	} else {
+		fmt.Fprintf(job.log, "Downloading %s\n", job.Request.Source)
+
+		// Only send the API token to Label Studio itself
+		token := ""
+		if s.labelStudio.URL != "" && strings.HasPrefix(job.Request.Source, strings.TrimRight(s.labelStudio.URL, "/")+"/") {
+			token = s.labelStudio.Token
+		}
+
+		// Downloaded sources are only needed for the conversion itself
+		defer os.RemoveAll(filepath.Join(workDir, "source"))
+		sourceDir, err := fetchSource(s.client, job.Request.Source, token, filepath.Join(workDir, "source"), s.sourceLimits)
+		if err != nil {
+			s.setStatus(job, JobFailed, err)
+			return
+		}
+		config.SourceDir = sourceDir
+	}
+
+	converter := NewConverter(config)
+	converter.SetOutput(job.log)
+	err := converter.Convert()
+	if problems := converter.Errors(); problems != nil {
+		s.mu.Lock()
+		job.Problems = problems.CountByRule()
+		s.mu.Unlock()
+	}
+	if err != nil {
+		s.setStatus(job, JobFailed, err)
+		return
+	}
+
+	s.mu.Lock()
+	job.resultPath = config.OutputDir
+	s.mu.Unlock()
+	s.setStatus(job, JobSucceeded, nil)
+}
+
+// projectOutputDir returns the directory a project's dataset is kept in
+func (s *Server) projectOutputDir(projectID int) string {
+	return filepath.Join(s.dataDir, "projects", fmt.Sprint(projectID), "dataset")
+}
+
+// lockProject serializes jobs for one project and returns the unlock function
+func (s *Server) lockProject(projectID int) func() {
+	s.mu.Lock()
+	lock, ok := s.projectLocks[projectID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.projectLocks[projectID] = lock
+	}
+	s.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// scheduleProject queues a project job once no further events for it have
+// arrived within the debounce window
+func (s *Server) scheduleProject(projectID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+	if timer, ok := s.pending[projectID]; ok {
+		timer.Reset(s.debounce)
+		return
+	}
+
+	s.pending[projectID] = time.AfterFunc(s.debounce, func() {
+		s.mu.Lock()
+		delete(s.pending, projectID)
+		s.mu.Unlock()
+
+		job, err := s.Submit(JobRequest{Project: projectID})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to queue conversion for project %d: %v\n", projectID, err)
+			return
+		}
+		fmt.Printf("Queued job %s for project %d\n", job.ID, projectID)
+	})
+}
+
+// isRemoteSource reports whether the source is an http(s) URL
+func isRemoteSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// fetchSource downloads an export archive and extracts it into destDir,
+// returning the directory that holds the export. A non-empty token is sent as
+// a Label Studio API token. Archives exceeding the limits are rejected.
+func fetchSource(client *http.Client, sourceURL, token, destDir string, limits SourceLimits) (string, error) {
+	maxBytes := limits.MaxDownload
+	req, err := http.NewRequest(http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid source URL: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download source: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download source: %s", resp.Status)
+	}
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return "", fmt.Errorf("source is larger than the %s download limit", formatBytes(uint64(maxBytes)))
+	}
+
+	format := ""
+	if u, err := url.Parse(sourceURL); err == nil {
+		format = archiveFormat(u.Path)
+	}
+	if format == "" {
+		switch resp.Header.Get("Content-Type") {
+		case "application/zip", "application/x-zip-compressed":
+			format = "zip"
+		case "application/gzip", "application/x-gzip", "application/x-tar+gzip":
+			format = "tar.gz"
+		default:
+			return "", fmt.Errorf("source URL must point to a .zip or .tar.gz export")
+		}
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", err
+	}
+
+	download, err := os.CreateTemp(filepath.Dir(destDir), "download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(download.Name())
+
+	// Read one byte past the limit to tell a full-size download from a larger one
+	var body io.Reader = resp.Body
+	if maxBytes > 0 {
+		body = io.LimitReader(resp.Body, maxBytes+1)
+	}
+	n, err := io.Copy(download, body)
+	if closeErr := download.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download source: %w", err)
+	}
+	if maxBytes > 0 && n > maxBytes {
+		return "", fmt.Errorf("source is larger than the %s download limit", formatBytes(uint64(maxBytes)))
+	}
+
+	extract := extractLimits{MaxBytes: limits.MaxExtract, MaxEntries: limits.MaxExtractEntries}
+	if err := extractArchive(download.Name(), format, destDir, extract); err != nil {
+		return "", err
+	}
+	return findExportRoot(destDir), nil
+}
+
+// findExportRoot descends through single-directory wrappers, which archives
+// commonly have, until it finds the directory containing classes.txt
+func findExportRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "classes.txt")); err == nil {
+			return dir
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+			return dir
+		}
+		dir = filepath.Join(dir, entries[0].Name())
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Handler returns the HTTP API:
+//
+//	POST /jobs              submit a conversion job
+//	GET  /jobs              list jobs
+//	GET  /jobs/{id}         job status and log
+//	GET  /jobs/{id}/result  download the converted dataset
+//	POST /jobs/{id}/rerun   queue a job with the same request
+//	POST /webhooks/label-studio  Label Studio webhook receiver
+//	GET  /schedule          scheduled runs and the next run time
+//	GET  /metrics           Prometheus metrics
+//	GET  /healthz           liveness check
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleSubmit)
+	mux.HandleFunc("GET /jobs", s.handleList)
+	mux.HandleFunc("GET /jobs/{id}", s.handleStatus)
+	mux.HandleFunc("GET /jobs/{id}/result", s.handleResult)
+	mux.HandleFunc("POST /jobs/{id}/rerun", s.handleRerun)
+	mux.HandleFunc("POST /webhooks/label-studio", s.handleWebhook)
+	mux.HandleFunc("GET /schedule", s.handleSchedule)
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	job, err := s.Submit(req)
+	if errors.Is(err, ErrQueueFull) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	snap, _ := s.Job(job.ID)
+	w.Header().Set("Location", path.Join("/jobs", job.ID))
+	writeJSON(w, http.StatusAccepted, snap)
+}
+
+// handleList lists jobs, optionally filtered by the status and project query
+// parameters; limit keeps only the most recent jobs
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	project := 0
+	if text := query.Get("project"); text != "" {
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid project: %s", text))
+			return
+		}
+		project = n
+	}
+	limit := 0
+	if text := query.Get("limit"); text != "" {
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %s", text))
+			return
+		}
+		limit = n
+	}
+
+	jobs := []Job{}
+	for _, job := range s.Jobs() {
+		if status := query.Get("status"); status != "" && job.Status != status {
+			continue
+		}
+		if project > 0 && job.Request.Project != project {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	if limit > 0 && len(jobs) > limit {
+		jobs = jobs[len(jobs)-limit:]
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleRerun(w http.ResponseWriter, r *http.Request) {
+	job, err := s.Rerun(r.PathValue("id"))
+	switch {
+	case errors.Is(err, errJobNotFound):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, ErrQueueFull):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	snap, _ := s.Job(job.ID)
+	w.Header().Set("Location", path.Join("/jobs", job.ID))
+	writeJSON(w, http.StatusAccepted, snap)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job not found"))
+		return
+	}
+	if job.Status != JobSucceeded {
+		writeError(w, http.StatusConflict, fmt.Errorf("job is %s", job.Status))
+		return
+	}
+
+	// Project datasets are directories, so package them as a tar.gz first; a
+	// failure then still gets an error status instead of a truncated archive
+	if job.Request.Project > 0 {
+		archive, err := os.CreateTemp(s.dataDir, "result-*.tar.gz")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		defer os.Remove(archive.Name())
+		defer archive.Close()
+
+		unlock := s.lockProject(job.Request.Project)
+		err = writeDirTarGz(archive, job.resultPath)
+		unlock()
+		if err == nil {
+			_, err = archive.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to package the result of job %s: %v\n", job.ID, err)
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to package the result: %w", err))
+			return
+		}
+
+		name := fmt.Sprintf("project-%d.tar.gz", job.Request.Project)
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		http.ServeContent(w, r, name, time.Time{}, archive)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.ID+"."+job.Request.Format))
+	http.ServeFile(w, r, job.resultPath)
+}
+
+// webhookPayload is the part of a Label Studio webhook body we act on
+type webhookPayload struct {
+	Action  string `json:"action"`
+	Project struct {
+		ID int `json:"id"`
+	} `json:"project"`
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.labelStudio.URL == "" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("webhooks are not enabled"))
+		return
+	}
+	if s.webhookSecret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Webhook-Secret")), []byte(s.webhookSecret)) != 1 {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid webhook secret"))
+		return
+	}
+
+	var payload webhookPayload
+	if err := json.NewDecoder(io.LimitReader(r.Body, 10<<20)).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid webhook body: %w", err))
+		return
+	}
+
+	if !s.webhookActions[payload.Action] || payload.Project.ID <= 0 {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+
+	s.scheduleProject(payload.Project.ID)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"status": "scheduled", "project": payload.Project.ID})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// runServe implements the serve subcommand
+func runServe(args []string) error {
+	var defaults Config
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on; jobs are unauthenticated, so only listen on other interfaces behind an authenticating proxy")
+	sourceRoot := fs.String("source-root", "", "Directory that local job sources must lie below; local sources are rejected unless set")
+	maxDownloadMB := fs.Int64("max-download-mb", defaultMaxDownload>>20, "Largest source archive a job downloads, in MB")
+	maxExtractMB := fs.Int64("max-extract-mb", defaultMaxExtract>>20, "Most a job's source archive may extract to, in MB")
+	maxExtractEntries := fs.Int("max-extract-entries", defaultMaxExtractEntries, "Most entries a job's source archive may hold")
+	dataDir := fs.String("data-dir", "./jobs", "Directory for job downloads and results")
+	workers := fs.Int("workers", 2, "Number of concurrent conversion workers")
+	queueSize := fs.Int("queue-size", 16, "Maximum number of queued jobs")
+	lsURL := fs.String("ls-url", "", "Label Studio base URL; enables project jobs and the webhook endpoint")
+	lsToken := fs.String("ls-token", os.Getenv("LABEL_STUDIO_TOKEN"), "Label Studio API token (default: $LABEL_STUDIO_TOKEN)")
+	webhookSecret := fs.String("webhook-secret", "", "Shared secret expected in the X-Webhook-Secret header")
+	webhookActions := fs.String("webhook-actions", "ANNOTATION_CREATED,ANNOTATIONS_CREATED,ANNOTATION_UPDATED,ANNOTATIONS_DELETED", "Comma-separated webhook actions that trigger a conversion")
+	debounce := fs.Duration("webhook-debounce", 30*time.Second, "Quiet period after the last webhook before a project is converted")
+	schedule := fs.String("schedule", "", "Cron expression (minute hour day month weekday, local time) on which -schedule-projects are pulled and converted, e.g. \"0 2 * * *\"")
+	scheduleProjects := fs.String("schedule-projects", "", "Comma-separated Label Studio project IDs converted on -schedule")
+	pprofAddr := fs.String("pprof-addr", "", "Serve the pprof profiling endpoints on this address, e.g. localhost:6060 (keep it private)")
+	registerConvertFlags(fs, &defaults)
+	fs.Parse(args)
+
+	if *workers < 1 {
+		return fmt.Errorf("workers must be at least 1")
+	}
+	if *maxDownloadMB < 1 || *maxExtractMB < 1 || *maxExtractEntries < 1 {
+		return fmt.Errorf("max-download-mb, max-extract-mb and max-extract-entries must be at least 1")
+	}
+	if err := os.MkdirAll(*dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	server := NewServer(defaults, *dataDir, *queueSize)
+	server.SetSourceLimits(SourceLimits{
+		MaxDownload:       *maxDownloadMB << 20,
+		MaxExtract:        *maxExtractMB << 20,
+		MaxExtractEntries: *maxExtractEntries,
+	})
+	if *sourceRoot != "" {
+		if err := server.EnableLocalSources(*sourceRoot); err != nil {
+			return err
+		}
+	}
+	loaded, err := server.LoadJobs()
+	if err != nil {
+		return err
+	}
+	if loaded > 0 {
+		fmt.Printf("Loaded %d jobs from %s\n", loaded, *dataDir)
+	}
+	if *lsURL != "" {
+		server.EnableWebhooks(LabelStudioConfig{URL: *lsURL, Token: *lsToken}, *webhookSecret, strings.Split(*webhookActions, ","), *debounce)
+	}
+	if *schedule != "" || *scheduleProjects != "" {
+		if *lsURL == "" {
+			return fmt.Errorf("-schedule requires -ls-url")
+		}
+		sched, err := ParseSchedule(*schedule)
+		if err != nil {
+			return err
+		}
+		projects, err := parseProjectIDs(*scheduleProjects)
+		if err != nil {
+			return fmt.Errorf("-schedule-projects: %w", err)
+		}
+		server.EnableSchedule(sched, projects)
+	}
+	server.Start(*workers)
+
+	httpServer := &http.Server{Addr: *addr, Handler: server.Handler()}
+	if *pprofAddr != "" {
+		go func() {
+			fmt.Printf("Serving pprof on %s/debug/pprof/\n", *pprofAddr)
+			if err := http.ListenAndServe(*pprofAddr, pprofHandler()); err != nil {
+				fmt.Fprintf(os.Stderr, "pprof server error: %v\n", err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Printf("Listening on %s (%d workers, queue size %d)\n", *addr, *workers, *queueSize)
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	case <-ctx.Done():
+		fmt.Println("Shutting down, waiting for running jobs...")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	httpServer.Shutdown(shutdownCtx)
+	server.Stop()
+	return nil
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForJob polls the server until the job leaves the queued/running states
+func waitForJob(t *testing.T, server *Server, id string) Job {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := server.Job(id)
+		if !ok {
+			t.Fatalf("Job %s not found", id)
+		}
+		if job.Status == JobSucceeded || job.Status == JobFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for job %s", id)
+	return Job{}
+}
+
+func TestServerJobLifecycle(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+
+	server := NewServer(Config{TrainSplit: 0.8, Seed: 42}, t.TempDir(), 4)
+	if err := server.EnableLocalSources(sourceDir); err != nil {
+		t.Fatal(err)
+	}
+	server.Start(1)
+	defer server.Stop()
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	body, _ := json.Marshal(JobRequest{Source: sourceDir, Options: map[string]string{"train-split": "0.5"}})
+	resp, err := http.Post(ts.URL+"/jobs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	var submitted Job
+	if err := json.NewDecoder(resp.Body).Decode(&submitted); err != nil {
+		t.Fatalf("Failed to decode job: %v", err)
+	}
+
+	job := waitForJob(t, server, submitted.ID)
+	if job.Status != JobSucceeded {
+		t.Fatalf("Expected job to succeed, got %s: %s", job.Status, job.Error)
+	}
+	if job.config.TrainSplit != 0.5 {
+		t.Errorf("Expected train split option to be applied, got %v", job.config.TrainSplit)
+	}
+	if !strings.Contains(job.Log, "Conversion completed successfully") {
+		t.Errorf("Expected job log to contain conversion output, got: %s", job.Log)
+	}
+
+	statusResp, err := http.Get(ts.URL + "/jobs/" + submitted.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job status: %v", err)
+	}
+	statusResp.Body.Close()
+	if statusResp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", statusResp.StatusCode)
+	}
+
+	resultResp, err := http.Get(ts.URL + "/jobs/" + submitted.ID + "/result")
+	if err != nil {
+		t.Fatalf("Failed to download result: %v", err)
+	}
+	defer resultResp.Body.Close()
+	if resultResp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 for result, got %d", resultResp.StatusCode)
+	}
+
+	resultPath := filepath.Join(t.TempDir(), "result.tar.gz")
+	data, _ := io.ReadAll(resultResp.Body)
+	if err := os.WriteFile(resultPath, data, 0644); err != nil {
+		t.Fatalf("Failed to save result: %v", err)
+	}
+	checkArchiveEntries(t, readTarGz(t, resultPath))
+
+	missingResp, err := http.Get(ts.URL + "/jobs/unknown")
+	if err != nil {
+		t.Fatalf("Failed to get unknown job: %v", err)
+	}
+	missingResp.Body.Close()
+	if missingResp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown job, got %d", missingResp.StatusCode)
+	}
+}
+
+func TestServerSubmitValidation(t *testing.T) {
+	server := NewServer(Config{TrainSplit: 0.8, Seed: 42}, t.TempDir(), 1)
+	if err := server.EnableLocalSources(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := server.Submit(JobRequest{}); err == nil {
+		t.Error("Expected error for missing source")
+	}
+	if _, err := server.Submit(JobRequest{Source: ".", Format: "rar"}); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+	if _, err := server.Submit(JobRequest{Source: ".", Options: map[string]string{"source": "/etc"}}); err == nil {
+		t.Error("Expected error for option that is not a conversion flag")
+	}
+	if _, err := server.Submit(JobRequest{Source: ".", Options: map[string]string{"train-split": "1.5"}}); err == nil {
+		t.Error("Expected error for out of range train split")
+	}
+	if _, err := server.Submit(JobRequest{Source: "/etc"}); err == nil {
+		t.Error("Expected error for source outside the source root")
+	}
+	if _, err := server.Submit(JobRequest{Source: "../"}); err == nil {
+		t.Error("Expected error for relative source escaping the source root")
+	}
+
+	// No workers are running, so the second job can't fit in the queue
+	if _, err := server.Submit(JobRequest{Source: "."}); err != nil {
+		t.Fatalf("Expected first job to be queued, got: %v", err)
+	}
+	if _, err := server.Submit(JobRequest{Source: "."}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got: %v", err)
+	}
+}
+
+func TestServerProjectJobsUseTheProjectExport(t *testing.T) {
+	server := NewServer(Config{TrainSplit: 0.8, Seed: 42}, t.TempDir(), 4)
+	if _, err := server.Submit(JobRequest{Project: 7}); err == nil {
+		t.Error("Expected project jobs to require a Label Studio URL")
+	}
+
+	server.EnableWebhooks(LabelStudioConfig{URL: "http://labelstudio:8080"}, "", nil, time.Second)
+	if _, err := server.Submit(JobRequest{Project: 7, Source: "https://example.com/export.zip"}); err == nil {
+		t.Error("Expected a project job with its own source to be rejected")
+	}
+	job, err := server.Submit(JobRequest{Project: 7})
+	if err != nil {
+		t.Fatalf("Failed to submit project job: %v", err)
+	}
+	if job.Request.Source != server.labelStudio.ProjectExportURL(7) {
+		t.Errorf("Expected the project export URL as source, got %s", job.Request.Source)
+	}
+	if _, err := server.Rerun(job.ID); err != nil {
+		t.Errorf("Expected a project job to be rerun, got: %v", err)
+	}
+}
+
+func TestServerRejectsLocalSourcesByDefault(t *testing.T) {
+	server := NewServer(Config{TrainSplit: 0.8, Seed: 42}, t.TempDir(), 1)
+	if _, err := server.Submit(JobRequest{Source: t.TempDir()}); err == nil || !strings.Contains(err.Error(), "-source-root") {
+		t.Errorf("Expected local sources to be disabled, got: %v", err)
+	}
+}
+
+func TestFetchSource(t *testing.T) {
+	exportDir := filepath.Join(t.TempDir(), "project")
+	createTestFiles(t, exportDir)
+
+	// Package the export inside a wrapper directory, as LS downloads are
+	archivePath := filepath.Join(t.TempDir(), "export.zip")
+	writer, err := NewDatasetWriter(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	err = filepath.Walk(exportDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(filepath.Dir(exportDir), p)
+		return writer.CopyFile(filepath.ToSlash(rel), p)
+	})
+	if err != nil {
+		t.Fatalf("Failed to build archive: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, archivePath)
+	}))
+	defer ts.Close()
+
+	if _, err := fetchSource(ts.Client(), ts.URL+"/export.zip", "", filepath.Join(t.TempDir(), "source"), SourceLimits{MaxDownload: 64}); err == nil || !strings.Contains(err.Error(), "download limit") {
+		t.Errorf("Expected download over the limit to be rejected, got: %v", err)
+	}
+	if _, err := fetchSource(ts.Client(), ts.URL+"/export.zip", "", filepath.Join(t.TempDir(), "source"), SourceLimits{MaxExtract: 64}); err == nil || !strings.Contains(err.Error(), "extracts to more than") {
+		t.Errorf("Expected archive extracting past the limit to be rejected, got: %v", err)
+	}
+	if _, err := fetchSource(ts.Client(), ts.URL+"/export.zip", "", filepath.Join(t.TempDir(), "source"), SourceLimits{MaxExtractEntries: 2}); err == nil || !strings.Contains(err.Error(), "more than 2 entries") {
+		t.Errorf("Expected archive with too many entries to be rejected, got: %v", err)
+	}
+
+	sourceDir, err := fetchSource(ts.Client(), ts.URL+"/export.zip", "", filepath.Join(t.TempDir(), "source"), SourceLimits{MaxDownload: defaultMaxDownload, MaxExtract: defaultMaxExtract, MaxExtractEntries: defaultMaxExtractEntries})
+	if err != nil {
+		t.Fatalf("Failed to fetch source: %v", err)
+	}
+	if filepath.Base(sourceDir) != "project" {
+		t.Errorf("Expected export root to be the wrapper directory, got %s", sourceDir)
+	}
+
+	converter := NewConverter(Config{SourceDir: sourceDir})
+	if err := converter.ValidateSourceStructure(); err != nil {
+		t.Errorf("Fetched source has invalid structure: %v", err)
+	}
+}
+
+func TestSafeJoinRejectsTraversal(t *testing.T) {
+	dest := t.TempDir()
+	if _, err := safeJoin(dest, "../evil.txt"); err == nil {
+		t.Error("Expected error for entry escaping destination")
+	}
+	if _, err := safeJoin(dest, "images/ok.jpg"); err != nil {
+		t.Errorf("Expected nested entry to be allowed, got: %v", err)
+	}
+}
+
+// zipExport packages a test export as the zip Label Studio's export API returns
+func zipExport(t *testing.T) string {
+	exportDir := t.TempDir()
+	createTestFiles(t, exportDir)
+	return zipDir(t, exportDir)
+}
+
+func TestWebhookTriggersProjectConversion(t *testing.T) {
+	archivePath := zipExport(t)
+
+	var authHeader string
+	labelStudio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/projects/7/export" || r.URL.Query().Get("exportType") != "YOLO" {
+			http.NotFound(w, r)
+			return
+		}
+		authHeader = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/zip")
+		data, _ := os.ReadFile(archivePath)
+		w.Write(data)
+	}))
+	defer labelStudio.Close()
+
+	dataDir := t.TempDir()
+	server := NewServer(Config{TrainSplit: 0.8, Seed: 42}, dataDir, 4)
+	server.EnableWebhooks(LabelStudioConfig{URL: labelStudio.URL, Token: "secret-token"}, "hook", []string{"ANNOTATION_CREATED"}, 20*time.Millisecond)
+	server.Start(1)
+	defer server.Stop()
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	post := func(secret, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/webhooks/label-studio", strings.NewReader(body))
+		req.Header.Set("X-Webhook-Secret", secret)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to post webhook: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post("wrong", `{"action": "ANNOTATION_CREATED", "project": {"id": 7}}`); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for wrong secret, got %d", status)
+	}
+	if status := post("hook", `{"action": "PROJECT_UPDATED", "project": {"id": 7}}`); status != http.StatusOK {
+		t.Errorf("Expected 200 for ignored action, got %d", status)
+	}
+
+	// A burst of events should result in a single conversion
+	for i := 0; i < 3; i++ {
+		if status := post("hook", `{"action": "ANNOTATION_CREATED", "project": {"id": 7}}`); status != http.StatusAccepted {
+			t.Fatalf("Expected 202 for annotation event, got %d", status)
+		}
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	var jobs []Job
+	for time.Now().Before(deadline) {
+		jobs = server.Jobs()
+		if len(jobs) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("Expected exactly one debounced job, got %d", len(jobs))
+	}
+
+	job := waitForJob(t, server, jobs[0].ID)
+	if job.Status != JobSucceeded {
+		t.Fatalf("Expected project job to succeed, got %s: %s", job.Status, job.Error)
+	}
+	if authHeader != "Token secret-token" {
+		t.Errorf("Expected Label Studio token to be sent, got %q", authHeader)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "projects", "7", "dataset", "data.yaml")); err != nil {
+		t.Errorf("Expected project dataset directory to be written: %v", err)
+	}
+
+	resp, err := http.Get(ts.URL + "/jobs/" + job.ID + "/result")
+	if err != nil {
+		t.Fatalf("Failed to download result: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 for project result, got %d", resp.StatusCode)
+	}
+	resultPath := filepath.Join(t.TempDir(), "project-7.tar.gz")
+	data, _ := io.ReadAll(resp.Body)
+	if err := os.WriteFile(resultPath, data, 0644); err != nil {
+		t.Fatalf("Failed to save result: %v", err)
+	}
+	if _, ok := readTarGz(t, resultPath)["data.yaml"]; !ok {
+		t.Error("Expected the project result to contain data.yaml")
+	}
+}
+
+func TestJobOptionsAllowlist(t *testing.T) {
+	server := NewServer(Config{TrainSplit: 0.8, Seed: 42, ClearMLPython: "python3"}, t.TempDir(), 1)
+	for _, name := range []string{"clearml-python", "move", "split-file", "notify-url", "mlflow-uri", "aliases", "hierarchy", "gates", "image-metadata", "label-config"} {
+		_, err := server.jobConfig(JobRequest{Source: ".", Options: map[string]string{name: "/tmp/x"}})
+		if err == nil || !strings.Contains(err.Error(), "server defaults") {
+			t.Errorf("Expected option %s to be rejected, got %v", name, err)
+		}
+	}
+	config, err := server.jobConfig(JobRequest{Source: ".", Options: map[string]string{"train-split": "0.7"}})
+	if err != nil || config.TrainSplit != 0.7 || config.ClearMLPython != "python3" {
+		t.Errorf("Expected the allowed option on top of the defaults, got %+v, %v", config, err)
+	}
+
+	// Every allowed option is a conversion flag
+	fs := flag.NewFlagSet("job", flag.ContinueOnError)
+	registerConvertFlags(fs, &Config{})
+	for name := range jobOptions {
+		if fs.Lookup(name) == nil {
+			t.Errorf("Allowed option %s is not a conversion flag", name)
+		}
+	}
+}
+
+func TestServerRemovesSourceOfFailedJob(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not a zip"))
+	}))
+	defer ts.Close()
+
+	dataDir := t.TempDir()
+	server := NewServer(Config{TrainSplit: 0.8, Seed: 42}, dataDir, 1)
+	server.Start(1)
+	defer server.Stop()
+
+	submitted, err := server.Submit(JobRequest{Source: ts.URL + "/export.zip"})
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	if job := waitForJob(t, server, submitted.ID); job.Status != JobFailed {
+		t.Fatalf("Expected job to fail, got %s", job.Status)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, submitted.ID, "source")); !os.IsNotExist(err) {
+		t.Errorf("Expected the downloaded source of a failed job to be removed, got: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Ways to convert shapes YOLO has no format for, selected with -shape-mode
+const (
+	ShapeBox     = "box"
+	ShapePolygon = "polygon"
+	ShapeSkip    = "skip"
+)
+
+// defaultShapeModes is the -shape-mode default
+const defaultShapeModes = "ellipse=polygon,polyline=box"
+
+// shapeResultTypes maps -shape-mode keys to Label Studio result types
+var shapeResultTypes = map[string]string{
+	"ellipse":  "ellipselabels",
+	"polyline": "polylinelabels",
+}
+
+// ellipseSegments is the number of polygon vertices an ellipse becomes
+const ellipseSegments = 32
+
+// parseShapeModes parses a -shape-mode value such as "ellipse=box,polyline=skip"
+// into result type -> mode, starting from the defaults
+func parseShapeModes(spec string) (map[string]string, error) {
+	modes := make(map[string]string)
+	for _, s := range []string{defaultShapeModes, spec} {
+		for _, entry := range strings.Split(s, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			shape, mode, ok := strings.Cut(entry, "=")
+			resultType, known := shapeResultTypes[strings.TrimSpace(shape)]
+			if !ok || !known {
+				return nil, fmt.Errorf("invalid shape mode %q (expected ellipse=... or polyline=...)", entry)
+			}
+
+			switch mode = strings.TrimSpace(mode); mode {
+			case ShapeBox, ShapePolygon, ShapeSkip:
+				modes[resultType] = mode
+			default:
+				return nil, fmt.Errorf("invalid shape mode %q (expected box, polygon or skip)", entry)
+			}
+		}
+	}
+	return modes, nil
+}
+
+// ellipsePolygon approximates an ellipse result with a polygon in normalized
+// coordinates. Label Studio stores the center and radii as percentages and
+// rotates clockwise around the center, in pixel space.
+func ellipsePolygon(result LSResult) []float64 {
+	v := result.Value
+	iw, ih := float64(result.OriginalWidth), float64(result.OriginalHeight)
+	if iw <= 0 || ih <= 0 {
+		iw, ih = 100, 100
+	}
+
+	cx, cy := v.X/100*iw, v.Y/100*ih
+	rx, ry := v.RadiusX/100*iw, v.RadiusY/100*ih
+	sin, cos := math.Sincos(v.Rotation * math.Pi / 180)
+
+	coords := make([]float64, 0, ellipseSegments*2)
+	for i := 0; i < ellipseSegments; i++ {
+		t := 2 * math.Pi * float64(i) / ellipseSegments
+		ex, ey := rx*math.Cos(t), ry*math.Sin(t)
+		coords = append(coords,
+			clamp01((cx+ex*cos-ey*sin)/iw),
+			clamp01((cy+ex*sin+ey*cos)/ih),
+		)
+	}
+	return coords
+}
+
+// ellipseBounds returns the normalized box enclosing an ellipse result,
+// computed exactly rather than from its polygon
+func ellipseBounds(result LSResult) [4]float64 {
+	v := result.Value
+	iw, ih := float64(result.OriginalWidth), float64(result.OriginalHeight)
+	if iw <= 0 || ih <= 0 {
+		iw, ih = 100, 100
+	}
+
+	rx, ry := v.RadiusX/100*iw, v.RadiusY/100*ih
+	sin, cos := math.Sincos(v.Rotation * math.Pi / 180)
+	halfW := math.Hypot(rx*cos, ry*sin) / iw
+	halfH := math.Hypot(rx*sin, ry*cos) / ih
+
+	cx, cy := v.X/100, v.Y/100
+	return clippedBox(cx-halfW, cy-halfH, cx+halfW, cy+halfH)
+}
+
+// polylineCoords returns the normalized points of a polyline result
+func polylineCoords(result LSResult) []float64 {
+	var coords []float64
+	for _, p := range result.Value.Points {
+		if len(p) >= 2 {
+			coords = append(coords, clamp01(p[0]/100), clamp01(p[1]/100))
+		}
+	}
+	return coords
+}
+
+// coordBounds returns the box enclosing flat normalized x, y pairs
+func coordBounds(coords []float64) [4]float64 {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i := 0; i+1 < len(coords); i += 2 {
+		minX, maxX = math.Min(minX, coords[i]), math.Max(maxX, coords[i])
+		minY, maxY = math.Min(minY, coords[i+1]), math.Max(maxY, coords[i+1])
+	}
+	return clippedBox(minX, minY, maxX, maxY)
+}
+
+// clippedBox converts corner bounds into a center box clipped to the image
+func clippedBox(x0, y0, x1, y1 float64) [4]float64 {
+	x0, y0 = clamp01(x0), clamp01(y0)
+	x1, y1 = clamp01(x1), clamp01(y1)
+	return [4]float64{(x0 + x1) / 2, (y0 + y1) / 2, x1 - x0, y1 - y0}
+}
+
+// shapeCoords converts an ellipse or polyline result according to its mode
+// into the coordinates of a label line: a center box or a polygon. It returns
+// no coordinates, with a reason, when the shape can't be represented.
+func shapeCoords(result LSResult, mode string) ([]float64, string) {
+	var coords []float64
+	if result.Type == "ellipselabels" {
+		if mode == ShapeBox {
+			box := ellipseBounds(result)
+			return box[:], ""
+		}
+		coords = ellipsePolygon(result)
+	} else {
+		// A polyline used as a polygon is closed back to its first point
+		coords = polylineCoords(result)
+		if len(coords) < 4 {
+			return nil, "too few points"
+		}
+		if mode == ShapeBox {
+			box := coordBounds(coords)
+			return box[:], ""
+		}
+	}
+
+	if len(coords) < 6 || polygonArea(coords) == 0 {
+		return nil, "degenerate polygon"
+	}
+	return coords, ""
+}
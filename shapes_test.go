@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseShapeModes(t *testing.T) {
+	modes, err := parseShapeModes("")
+	if err != nil {
+		t.Fatalf("Failed to parse default modes: %v", err)
+	}
+	if !reflect.DeepEqual(modes, map[string]string{"ellipselabels": ShapePolygon, "polylinelabels": ShapeBox}) {
+		t.Errorf("Unexpected default modes: %v", modes)
+	}
+
+	modes, err = parseShapeModes(" ellipse=box, polyline=skip ")
+	if err != nil {
+		t.Fatalf("Failed to parse modes: %v", err)
+	}
+	if modes["ellipselabels"] != ShapeBox || modes["polylinelabels"] != ShapeSkip {
+		t.Errorf("Expected overrides to apply, got %v", modes)
+	}
+
+	for _, spec := range []string{"circle=box", "ellipse=mask", "ellipse"} {
+		if _, err := parseShapeModes(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestEllipseConversion(t *testing.T) {
+	// A 40x10 pixel ellipse (radii 20 and 5) rotated 90 degrees in a 100x100 image
+	result := LSResult{
+		Type:           "ellipselabels",
+		OriginalWidth:  100,
+		OriginalHeight: 100,
+		Value:          LSValue{X: 50, Y: 50, RadiusX: 20, RadiusY: 5, Rotation: 90},
+	}
+
+	box := ellipseBounds(result)
+	want := [4]float64{0.5, 0.5, 0.1, 0.4}
+	for i := range want {
+		if math.Abs(box[i]-want[i]) > 1e-9 {
+			t.Fatalf("Expected ellipse bounds %v, got %v", want, box)
+		}
+	}
+
+	coords := ellipsePolygon(result)
+	if len(coords) != ellipseSegments*2 {
+		t.Fatalf("Expected %d points, got %d", ellipseSegments, len(coords)/2)
+	}
+	area := polygonArea(coords) * 100 * 100
+	if exact := math.Pi * 20 * 5; math.Abs(area-exact)/exact > 0.01 {
+		t.Errorf("Expected polygon area close to %.1f, got %.1f", exact, area)
+	}
+
+	coords, reason := shapeCoords(result, ShapeBox)
+	if line := polygonLine(0, coords); line != "0 0.500000 0.500000 0.100000 0.400000\n" || reason != "" {
+		t.Errorf("Unexpected ellipse box line %q (%s)", line, reason)
+	}
+}
+
+func TestPolylineConversion(t *testing.T) {
+	result := LSResult{
+		Type:  "polylinelabels",
+		Value: LSValue{Points: [][]float64{{10, 10}, {50, 10}, {50, 30}}},
+	}
+
+	if coords, _ := shapeCoords(result, ShapeBox); polygonLine(1, coords) != "1 0.300000 0.200000 0.400000 0.200000\n" {
+		t.Errorf("Unexpected polyline box %v", coords)
+	}
+	if coords, _ := shapeCoords(result, ShapePolygon); !strings.HasPrefix(polygonLine(1, coords), "1 0.100000 0.100000 0.500000 0.100000 0.500000 0.300000") {
+		t.Errorf("Unexpected polyline polygon %v", coords)
+	}
+
+	straight := LSResult{Type: "polylinelabels", Value: LSValue{Points: [][]float64{{10, 10}, {20, 20}, {30, 30}}}}
+	if coords, reason := shapeCoords(straight, ShapePolygon); coords != nil || reason != "degenerate polygon" {
+		t.Errorf("Expected straight polyline to be rejected as a polygon, got %v (%s)", coords, reason)
+	}
+}
+
+func TestLabelBuilderShapeModes(t *testing.T) {
+	modes, _ := parseShapeModes("polyline=skip")
+	builder := newLabelBuilder(nil, 0, nil)
+	builder.shapeModes = modes
+
+	converter := NewConverter(Config{})
+	converter.SetOutput(&strings.Builder{})
+	lines := builder.taskLines(converter, 1, []LSResult{
+		{Type: "ellipselabels", Value: LSValue{X: 50, Y: 50, RadiusX: 10, RadiusY: 10, EllipseLabels: []string{"ball"}}},
+		{Type: "polylinelabels", Value: LSValue{Points: [][]float64{{10, 10}, {50, 10}}, PolylineLabels: []string{"lane"}}},
+		{Type: "labels", Value: LSValue{Labels: []string{"word"}}},
+	})
+
+	if len(lines) != 1 || len(strings.Fields(lines[0])) != 1+ellipseSegments*2 {
+		t.Errorf("Expected one ellipse polygon line, got %q", lines)
+	}
+	if builder.unsupported["polylinelabels"] != 1 {
+		t.Errorf("Expected skipped polyline to be counted, got %v", builder.unsupported)
+	}
+	// Labels only seen on skipped results don't become classes
+	if strings.Join(builder.classes, ",") != "ball" {
+		t.Errorf("Expected only the ball class, got %v", builder.classes)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Split units, selected with -split-unit: what -train-split is a fraction of
+const (
+	SplitUnitImages      = "images"
+	SplitUnitAnnotations = "annotations"
+)
+
+// Split strategies, selected with -split-strategy, and the orders of
+// sequential splits, selected with -split-order
+const (
+	SplitRandom     = "random"
+	SplitSequential = "sequential"
+
+	SplitOrderName  = "name"
+	SplitOrderMtime = "mtime"
+)
+
+// validateSplit checks the -split-unit, -split-strategy and -split-order
+// options
+func (c *Converter) validateSplit() error {
+	switch c.config.SplitUnit {
+	case "", SplitUnitImages, SplitUnitAnnotations:
+	default:
+		return fmt.Errorf("unknown split unit %q (expected images or annotations)", c.config.SplitUnit)
+	}
+	switch c.config.SplitStrategy {
+	case "", SplitRandom, SplitSequential:
+	default:
+		return fmt.Errorf("unknown split strategy %q (expected random or sequential)", c.config.SplitStrategy)
+	}
+	switch c.config.SplitOrder {
+	case "", SplitOrderName, SplitOrderMtime:
+	default:
+		return fmt.Errorf("unknown split order %q (expected name or mtime)", c.config.SplitOrder)
+	}
+	return nil
+}
+
+// orderUnits puts units in the order they are assigned to training, the rest
+// going to validation: shuffled with -seed, or sorted by image name or
+// modification time for sequential splits so validation holds the latest
+// data. Groups sort by their first image.
+func (c *Converter) orderUnits(units [][]LabelPair) {
+	if c.config.SplitStrategy != SplitSequential {
+		// Use a private source so splits are reproducible and concurrent
+		// conversions don't share random state
+		rng := rand.New(rand.NewSource(c.config.Seed))
+		rng.Shuffle(len(units), func(i, j int) {
+			units[i], units[j] = units[j], units[i]
+		})
+		return
+	}
+
+	type sortedUnit struct {
+		pairs    []LabelPair
+		name     string
+		modified time.Time
+	}
+	sorted := make([]sortedUnit, len(units))
+	for i, unit := range units {
+		sorted[i] = sortedUnit{pairs: unit, name: filepath.Base(unit[0].ImagePath)}
+		if c.config.SplitOrder == SplitOrderMtime {
+			if info, err := os.Stat(unit[0].ImagePath); err == nil {
+				sorted[i].modified = info.ModTime()
+			}
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].modified.Equal(sorted[j].modified) {
+			return sorted[i].modified.Before(sorted[j].modified)
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	for i := range sorted {
+		units[i] = sorted[i].pairs
+	}
+}
+
+// pairWeight returns how much a pair counts towards -train-split: one per
+// image, or its number of valid annotations
+func (c *Converter) pairWeight(pair LabelPair) int {
+	if c.config.SplitUnit != SplitUnitAnnotations {
+		return 1
+	}
+	total := 0
+	for _, n := range c.labelClassCounts(pair.LabelPath) {
+		total += n
+	}
+	return total
+}
+
+// unitWeights weighs every unit, returning the weights and their total
+func (c *Converter) unitWeights(units [][]LabelPair) ([]int, int) {
+	weights := make([]int, len(units))
+	total := 0
+	for i, unit := range units {
+		for _, pair := range unit {
+			weights[i] += c.pairWeight(pair)
+		}
+		total += weights[i]
+	}
+	return weights, total
+}
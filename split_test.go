@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitByAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	var pairs []LabelPair
+	for i := 0; i < 10; i++ {
+		// One crowded image holds as many boxes as the others together
+		boxes := 1
+		if i == 0 {
+			boxes = 9
+		}
+		labelPath := filepath.Join(dir, fmt.Sprintf("image%d.txt", i))
+		if err := os.WriteFile(labelPath, []byte(strings.Repeat("0 0.5 0.5 0.1 0.1\n", boxes)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		pairs = append(pairs, LabelPair{ImagePath: filepath.Join(dir, fmt.Sprintf("image%d.jpg", i)), LabelPath: labelPath})
+	}
+
+	for _, seed := range []int64{1, 2, 3, 42} {
+		converter := NewConverter(Config{TrainSplit: 0.5, Seed: seed, SplitUnit: SplitUnitAnnotations})
+		converter.SetOutput(io.Discard)
+		train, val := converter.SplitDataset(pairs)
+		if len(train)+len(val) != len(pairs) {
+			t.Fatalf("Seed %d: lost pairs", seed)
+		}
+
+		trainBoxes := 0
+		for _, pair := range train {
+			trainBoxes += converter.pairWeight(pair)
+		}
+		// Training stops filling once it holds half of the 18 boxes, so it
+		// can overshoot by at most the crowded image
+		if trainBoxes < 9 || trainBoxes > 17 {
+			t.Errorf("Seed %d: expected about half of the boxes in training, got %d", seed, trainBoxes)
+		}
+	}
+}
+
+func TestSplitValidation(t *testing.T) {
+	for _, config := range []Config{{SplitUnit: "boxes"}, {SplitStrategy: "stratified"}, {SplitOrder: "size"}} {
+		if err := NewConverter(config).validateSplit(); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+
+	converter := NewConverter(Config{SplitStrategy: SplitSequential, SplitOrder: SplitOrderMtime})
+	for _, unit := range []string{"", SplitUnitImages, SplitUnitAnnotations} {
+		converter.config.SplitUnit = unit
+		if err := converter.validateSplit(); err != nil {
+			t.Errorf("Expected %q to be accepted, got %v", unit, err)
+		}
+	}
+}
+
+func TestSequentialSplit(t *testing.T) {
+	dir := t.TempDir()
+	var pairs []LabelPair
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Names run against capture time: cam_9 was recorded first
+	for i := 0; i < 10; i++ {
+		imagePath := filepath.Join(dir, fmt.Sprintf("cam_%d.jpg", i))
+		if err := os.WriteFile(imagePath, []byte("image"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modified := base.Add(time.Duration(9-i) * time.Hour)
+		if err := os.Chtimes(imagePath, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+		pairs = append(pairs, LabelPair{ImagePath: imagePath, LabelPath: filepath.Join(dir, fmt.Sprintf("cam_%d.txt", i))})
+	}
+	// Input order must not matter
+	pairs[0], pairs[5] = pairs[5], pairs[0]
+
+	names := func(pairs []LabelPair) string {
+		var names []string
+		for _, pair := range pairs {
+			names = append(names, strings.TrimSuffix(filepath.Base(pair.ImagePath), ".jpg"))
+		}
+		return strings.Join(names, ",")
+	}
+
+	converter := NewConverter(Config{TrainSplit: 0.8, SplitStrategy: SplitSequential, SplitOrder: SplitOrderName})
+	converter.SetOutput(io.Discard)
+	_, val := converter.SplitDataset(pairs)
+	if got := names(val); got != "cam_8,cam_9" {
+		t.Errorf("Expected the last names in validation, got %s", got)
+	}
+
+	converter.config.SplitOrder = SplitOrderMtime
+	_, val = converter.SplitDataset(pairs)
+	if got := names(val); got != "cam_1,cam_0" {
+		t.Errorf("Expected the newest images in validation, got %s", got)
+	}
+}
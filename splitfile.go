@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// splitAliases maps the split names accepted in split files to output splits
+var splitAliases = map[string]string{
+	"train":      "train",
+	"training":   "train",
+	"val":        "val",
+	"valid":      "val",
+	"validation": "val",
+}
+
+// ReadSplitFile reads a CSV of image, split rows into assignments keyed by
+// image file name. A missing file has no assignments; a header row is
+// skipped.
+func ReadSplitFile(path string) (map[string]string, error) {
+	existing := make(map[string]string)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return existing, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open split file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read split file: %w", err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("split file %s:%d: expected image,split", path, row)
+		}
+		image, split := filepath.Base(strings.TrimSpace(record[0])), strings.ToLower(strings.TrimSpace(record[1]))
+		if row == 1 && split == "split" {
+			continue
+		}
+		name, ok := splitAliases[split]
+		if !ok {
+			return nil, fmt.Errorf("split file %s:%d: unknown split %q (expected train or val)", path, row, record[1])
+		}
+		existing[image] = name
+	}
+	return existing, nil
+}
+
+// WriteSplitFile records the split of every pair, sorted by image name
+func WriteSplitFile(path string, trainPairs, valPairs []LabelPair) error {
+	var rows [][]string
+	for _, pair := range trainPairs {
+		rows = append(rows, []string{filepath.Base(pair.ImagePath), "train"})
+	}
+	for _, pair := range valPairs {
+		rows = append(rows, []string{filepath.Base(pair.ImagePath), "val"})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"image", "split"})
+	writer.WriteAll(rows)
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to encode split file: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write split file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitFile(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	splitFile := filepath.Join(tempDir, "splits.csv")
+	content := "image,split\nimage1.jpg,validation\nimages/image2.png,train\ngone.jpg,val\n"
+	if err := os.WriteFile(splitFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := filepath.Join(tempDir, "out")
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42, SplitFile: splitFile})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	for image, split := range map[string]string{"image1.jpg": "val", "image2.png": "train"} {
+		if _, err := os.Stat(filepath.Join(outputDir, "images", split, image)); err != nil {
+			t.Errorf("Expected %s in %s: %v", image, split, err)
+		}
+	}
+
+	// The file now lists every converted image, including the new image3
+	assigned, err := ReadSplitFile(splitFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assigned) != 3 || assigned["image1.jpg"] != "val" || assigned["image2.png"] != "train" || assigned["image3.jpeg"] == "" {
+		t.Errorf("Unexpected assignments %v", assigned)
+	}
+	data, _ := os.ReadFile(splitFile)
+	if !strings.HasPrefix(string(data), "image,split\nimage1.jpg,val\n") {
+		t.Errorf("Unexpected split file:\n%s", data)
+	}
+}
+
+func TestReadSplitFile(t *testing.T) {
+	dir := t.TempDir()
+	if assigned, err := ReadSplitFile(filepath.Join(dir, "missing.csv")); err != nil || len(assigned) != 0 {
+		t.Errorf("Expected a missing split file to be empty, got %v, %v", assigned, err)
+	}
+
+	badFile := filepath.Join(dir, "bad.csv")
+	if err := os.WriteFile(badFile, []byte("a.jpg,train\nb.jpg,test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSplitFile(badFile); err == nil || !strings.Contains(err.Error(), "bad.csv:2") {
+		t.Errorf("Expected an error for the test split, got %v", err)
+	}
+}
+
+func TestSplitFileRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), Crop: true, SplitFile: "splits.csv"})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-split-file") {
+		t.Errorf("Expected -split-file to be rejected with -crop, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statsFile holds the per-split statistics, next to data.yaml
+const statsFile = "stats.json"
+
+// DatasetStats counts the images and annotations of every split and class of
+// a written dataset
+type DatasetStats struct {
+	Classes []string               `json:"classes"`
+	Splits  map[string]*SplitStats `json:"splits"`
+	Total   *SplitStats            `json:"total"`
+}
+
+// SplitStats counts the images and annotations of one split. EmptyImages
+// counts the images without annotations (background images). Classes are
+// keyed by name; ClassImages counts the images a class appears in.
+type SplitStats struct {
+	Images      int            `json:"images"`
+	Annotations int            `json:"annotations"`
+	EmptyImages int            `json:"empty_images"`
+	ClassCounts map[string]int `json:"class_counts"`
+	ClassImages map[string]int `json:"class_images"`
+}
+
+// newDatasetStats starts statistics for the given splits with every class
+// at zero, so classes missing from a split stand out
+func newDatasetStats(classes []string, splits ...string) *DatasetStats {
+	stats := &DatasetStats{Classes: classes, Splits: make(map[string]*SplitStats), Total: newSplitStats(classes)}
+	for _, split := range splits {
+		stats.Splits[split] = newSplitStats(classes)
+	}
+	return stats
+}
+
+func newSplitStats(classes []string) *SplitStats {
+	s := &SplitStats{ClassCounts: make(map[string]int, len(classes)), ClassImages: make(map[string]int, len(classes))}
+	for _, name := range classes {
+		s.ClassCounts[name] = 0
+		s.ClassImages[name] = 0
+	}
+	return s
+}
+
+// add counts one image of a split from its annotations per class ID
+func (d *DatasetStats) add(split string, counts map[int]int) {
+	s, ok := d.Splits[split]
+	if !ok {
+		s = newSplitStats(d.Classes)
+		d.Splits[split] = s
+	}
+	for _, target := range []*SplitStats{s, d.Total} {
+		target.Images++
+		annotations := 0
+		for classID, n := range counts {
+			if n == 0 {
+				continue
+			}
+			name := d.className(classID)
+			target.ClassCounts[name] += n
+			target.ClassImages[name]++
+			annotations += n
+		}
+		target.Annotations += annotations
+		if annotations == 0 {
+			target.EmptyImages++
+		}
+	}
+}
+
+// className names a class ID, falling back to the number for IDs outside
+// the class list
+func (d *DatasetStats) className(classID int) string {
+	if classID >= 0 && classID < len(d.Classes) {
+		return d.Classes[classID]
+	}
+	return strconv.Itoa(classID)
+}
+
+// computeDatasetStats counts the annotations of the split pairs' labels
+func (c *Converter) computeDatasetStats(classes []string, trainPairs, valPairs []LabelPair) *DatasetStats {
+	stats := newDatasetStats(classes, "train", "val")
+	for split, pairs := range map[string][]LabelPair{"train": trainPairs, "val": valPairs} {
+		for _, pair := range pairs {
+			stats.add(split, c.labelClassCounts(pair.LabelPath))
+		}
+	}
+	return stats
+}
+
+// writeDatasetStats writes stats.json and keeps the statistics for the
+// comments CreateYAMLConfig appends to data.yaml
+func (c *Converter) writeDatasetStats(stats *DatasetStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", statsFile, err)
+	}
+	if err := c.output().WriteFile(statsFile, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", statsFile, err)
+	}
+	c.datasetStats = stats
+	return nil
+}
+
+// yamlComment renders the statistics as a comment block for data.yaml
+func (d *DatasetStats) yamlComment() string {
+	splits := []string{"train", "val"}
+	var b strings.Builder
+	b.WriteString("\n# Dataset statistics (details in " + statsFile + ")\n")
+	for _, split := range splits {
+		s := d.Splits[split]
+		fmt.Fprintf(&b, "#   %-6s %d images (%d without annotations), %d annotations\n", split+":", s.Images, s.EmptyImages, s.Annotations)
+	}
+
+	width := len("class")
+	for _, name := range d.Classes {
+		width = max(width, len(name))
+	}
+	fmt.Fprintf(&b, "#   %-*s %8s %8s\n", width, "class", "train", "val")
+	for _, name := range d.Classes {
+		fmt.Fprintf(&b, "#   %-*s %8d %8d\n", width, name, d.Splits["train"].ClassCounts[name], d.Splits["val"].ClassCounts[name])
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConvertWritesDatasetStats(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		sourceDir := t.TempDir()
+		createTestFiles(t, sourceDir)
+		outputDir := filepath.Join(t.TempDir(), "out")
+
+		var out strings.Builder
+		converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42, Stream: stream, InputFormat: InputYOLO})
+		converter.SetOutput(&out)
+		if err := converter.Convert(); err != nil {
+			t.Fatalf("Conversion failed (stream %v): %v\n%s", stream, err, out.String())
+		}
+
+		data, err := os.ReadFile(filepath.Join(outputDir, statsFile))
+		if err != nil {
+			t.Fatalf("Expected %s (stream %v): %v", statsFile, stream, err)
+		}
+		var stats DatasetStats
+		if err := json.Unmarshal(data, &stats); err != nil {
+			t.Fatal(err)
+		}
+		train, val := stats.Splits["train"], stats.Splits["val"]
+		if train.Images+val.Images != 3 || stats.Total.Images != 3 {
+			t.Errorf("Expected 3 images across the splits, got %+v", stats)
+		}
+		// The test labels hold 3 books and 2 people
+		if stats.Total.ClassCounts["book"] != 3 || stats.Total.ClassCounts["person"] != 2 || stats.Total.Annotations != 5 {
+			t.Errorf("Unexpected class counts: %+v", stats.Total)
+		}
+		if train.ClassCounts["book"]+val.ClassCounts["book"] != 3 || stats.Total.ClassImages["book"] != 3 {
+			t.Errorf("Split counts don't add up: train %+v, val %+v", train, val)
+		}
+
+		yamlData, err := os.ReadFile(filepath.Join(outputDir, "data.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(yamlData), "# Dataset statistics") || !strings.Contains(string(yamlData), "#   book ") {
+			t.Errorf("Expected the statistics as comments in data.yaml:\n%s", yamlData)
+		}
+		var config YAMLConfig
+		if err := yaml.Unmarshal(yamlData, &config); err != nil || config.NC != 2 {
+			t.Errorf("Expected data.yaml to stay valid, got %v (nc %d)", err, config.NC)
+		}
+	}
+}
+
+func TestDatasetStatsYAMLComment(t *testing.T) {
+	stats := newDatasetStats([]string{"car", "pedestrian"}, "train", "val")
+	stats.add("train", map[int]int{0: 2, 1: 1})
+	stats.add("train", nil)
+	stats.add("val", map[int]int{0: 1})
+
+	want := `
+# Dataset statistics (details in stats.json)
+#   train: 2 images (1 without annotations), 3 annotations
+#   val:   1 images (0 without annotations), 1 annotations
+#   class         train      val
+#   car               2        1
+#   pedestrian        1        0
+`
+	if got := stats.yamlComment(); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
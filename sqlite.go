@@ -0,0 +1,422 @@
+package main
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// sqlitePageSize is the page size of written SQLite databases
+const sqlitePageSize = 4096
+
+// Largest payloads stored on a b-tree page; the writer doesn't create
+// overflow pages, so larger rows and index keys are rejected
+const (
+	sqliteMaxTablePayload = sqlitePageSize - 35
+	sqliteMaxIndexPayload = (sqlitePageSize-12)*64/255 - 23
+)
+
+// B-tree page types
+const (
+	sqliteIndexInterior = 0x02
+	sqliteTableInterior = 0x05
+	sqliteIndexLeaf     = 0x0a
+	sqliteTableLeaf     = 0x0d
+)
+
+// sqliteTable is a table to write. Its first column is an INTEGER PRIMARY
+// KEY, the rowid; values are int64, float64, string or nil.
+type sqliteTable struct {
+	name string
+	sql  string
+	rows [][]any
+}
+
+// sqliteIndex is an index on columns of a table, by position
+type sqliteIndex struct {
+	name    string
+	table   string
+	sql     string
+	columns []int
+}
+
+// writeSQLite writes a database holding the tables and indexes in the
+// SQLite file format. Every b-tree is built bottom-up from sorted cells,
+// which is all a database written once and then only read needs: there are
+// no free pages, overflow pages or journals.
+func writeSQLite(w io.Writer, tables []sqliteTable, indexes []sqliteIndex) error {
+	// Page 1 holds the schema table, laid out last once the roots are known
+	s := &sqliteWriter{pages: [][]byte{nil}}
+	var schema []sqliteCell
+
+	byName := make(map[string]*sqliteTable, len(tables))
+	for i := range tables {
+		table := &tables[i]
+		byName[table.name] = table
+		cells := make([]sqliteCell, len(table.rows))
+		for j, row := range table.rows {
+			// The rowid alias is stored as NULL in the record
+			rowid := row[0].(int64)
+			record := sqliteRecord(append([]any{nil}, row[1:]...))
+			if len(record) > sqliteMaxTablePayload {
+				return fmt.Errorf("row %d of %s is too large", rowid, table.name)
+			}
+			cells[j] = sqliteCell{rowid: rowid, payload: record}
+		}
+		sort.Slice(cells, func(a, b int) bool { return cells[a].rowid < cells[b].rowid })
+		root := s.tableTree(cells)
+		schema = append(schema, sqliteCell{payload: sqliteRecord([]any{"table", table.name, table.name, int64(root), table.sql})})
+	}
+
+	for _, index := range indexes {
+		table, ok := byName[index.table]
+		if !ok {
+			return fmt.Errorf("index %s is on unknown table %s", index.name, index.table)
+		}
+		keys := make([][]any, len(table.rows))
+		for j, row := range table.rows {
+			key := make([]any, 0, len(index.columns)+1)
+			for _, col := range index.columns {
+				key = append(key, row[col])
+			}
+			keys[j] = append(key, row[0])
+		}
+		sort.Slice(keys, func(a, b int) bool { return compareSQLiteKeys(keys[a], keys[b]) < 0 })
+		cells := make([]sqliteCell, len(keys))
+		for j, key := range keys {
+			cells[j] = sqliteCell{payload: sqliteRecord(key)}
+			if len(cells[j].payload) > sqliteMaxIndexPayload {
+				return fmt.Errorf("a key of index %s is too large", index.name)
+			}
+		}
+		root := s.indexTree(cells)
+		schema = append(schema, sqliteCell{payload: sqliteRecord([]any{"index", index.name, index.table, int64(root), index.sql})})
+	}
+
+	size := 100 + headerSize(sqliteTableLeaf)
+	for i := range schema {
+		schema[i].rowid = int64(i + 1)
+		size += 2 + len(schema[i].encode(sqliteTableLeaf))
+	}
+	if size > sqlitePageSize {
+		return fmt.Errorf("the database schema doesn't fit on its page")
+	}
+	s.pages[0] = s.page(sqliteTableLeaf, schema, 0, 100)
+	copy(s.pages[0], sqliteHeader(len(s.pages)))
+
+	for _, page := range s.pages {
+		if _, err := w.Write(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteHeader returns the 100-byte file header of a database of the given
+// number of pages
+func sqliteHeader(pages int) []byte {
+	h := make([]byte, 100)
+	copy(h, "SQLite format 3\x00")
+	binary.BigEndian.PutUint16(h[16:], sqlitePageSize)
+	h[18], h[19] = 1, 1                   // rollback journal
+	h[21], h[22], h[23] = 64, 32, 32      // payload fractions
+	binary.BigEndian.PutUint32(h[24:], 1) // file change counter
+	binary.BigEndian.PutUint32(h[28:], uint32(pages))
+	binary.BigEndian.PutUint32(h[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(h[44:], 4) // schema format
+	binary.BigEndian.PutUint32(h[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(h[92:], 1) // the page count is valid for change 1
+	binary.BigEndian.PutUint32(h[96:], 3045000)
+	return h
+}
+
+// sqliteCell is a b-tree cell: a table row with its rowid, or an index key.
+// Interior cells point to the child holding the smaller keys.
+type sqliteCell struct {
+	rowid   int64
+	payload []byte
+	child   uint32
+}
+
+// encode returns the bytes of the cell on a page of the given type
+func (c sqliteCell) encode(pageType byte) []byte {
+	var b []byte
+	if pageType == sqliteTableInterior || pageType == sqliteIndexInterior {
+		b = binary.BigEndian.AppendUint32(b, c.child)
+	}
+	if pageType == sqliteTableInterior {
+		return appendSQLiteVarint(b, uint64(c.rowid))
+	}
+	b = appendSQLiteVarint(b, uint64(len(c.payload)))
+	if pageType == sqliteTableLeaf {
+		b = appendSQLiteVarint(b, uint64(c.rowid))
+	}
+	return append(b, c.payload...)
+}
+
+// headerSize returns the size of the header of a b-tree page
+func headerSize(pageType byte) int {
+	if pageType == sqliteTableInterior || pageType == sqliteIndexInterior {
+		return 12
+	}
+	return 8
+}
+
+// sqliteWriter collects the pages of a database; page n is pages[n-1]
+type sqliteWriter struct {
+	pages [][]byte
+}
+
+// add appends a page and returns its number
+func (s *sqliteWriter) add(page []byte) uint32 {
+	s.pages = append(s.pages, page)
+	return uint32(len(s.pages))
+}
+
+// page lays out a b-tree page: the header and cell pointers at the start,
+// the cells packed at the end. offset leaves room for the file header on
+// page 1; right is the right-most child of interior pages.
+func (s *sqliteWriter) page(pageType byte, cells []sqliteCell, right uint32, offset int) []byte {
+	page := make([]byte, sqlitePageSize)
+	header := page[offset:]
+	header[0] = pageType
+	binary.BigEndian.PutUint16(header[3:], uint16(len(cells)))
+	if headerSize(pageType) == 12 {
+		binary.BigEndian.PutUint32(header[8:], right)
+	}
+
+	pointers := offset + headerSize(pageType)
+	end := sqlitePageSize
+	for i, cell := range cells {
+		data := cell.encode(pageType)
+		end -= len(data)
+		copy(page[end:], data)
+		binary.BigEndian.PutUint16(page[pointers+2*i:], uint16(end))
+	}
+	binary.BigEndian.PutUint16(header[5:], uint16(end))
+	return page
+}
+
+// tableTree writes a table b-tree of rows sorted by rowid and returns its
+// root page. Interior cells hold the largest rowid of their left child.
+func (s *sqliteWriter) tableTree(cells []sqliteCell) uint32 {
+	type node struct {
+		page   uint32
+		maxKey int64
+	}
+	var level []node
+	for _, run := range packSQLiteCells(sqliteTableLeaf, cells) {
+		var maxKey int64
+		if len(run) > 0 {
+			maxKey = run[len(run)-1].rowid
+		}
+		level = append(level, node{s.add(s.page(sqliteTableLeaf, run, 0, 0)), maxKey})
+	}
+
+	for len(level) > 1 {
+		dividers := make([]sqliteCell, len(level)-1)
+		for i, n := range level[:len(level)-1] {
+			dividers[i] = sqliteCell{rowid: n.maxKey, child: n.page}
+		}
+		right := level[len(level)-1]
+
+		var parents []node
+		runs := packSQLiteCells(sqliteTableInterior, dividers)
+		for i, run := range runs {
+			if i == len(runs)-1 {
+				parents = append(parents, node{s.add(s.page(sqliteTableInterior, run, right.page, 0)), right.maxKey})
+				break
+			}
+			// The last divider of the run becomes the page's right-most child
+			last := run[len(run)-1]
+			parents = append(parents, node{s.add(s.page(sqliteTableInterior, run[:len(run)-1], last.child, 0)), last.rowid})
+		}
+		level = parents
+	}
+	return level[0].page
+}
+
+// packSQLiteCells splits cells into runs that each fit on a page
+func packSQLiteCells(pageType byte, cells []sqliteCell) [][]sqliteCell {
+	var runs [][]sqliteCell
+	start, size := 0, headerSize(pageType)
+	for i, cell := range cells {
+		n := 2 + len(cell.encode(pageType))
+		if size+n > sqlitePageSize && i > start {
+			runs = append(runs, cells[start:i])
+			start, size = i, headerSize(pageType)
+		}
+		size += n
+	}
+	return append(runs, cells[start:])
+}
+
+// indexTree writes an index b-tree of sorted keys and returns its root page.
+// Unlike in a table b-tree, interior cells are keys of their own: each one
+// sorts after the keys of its child and before those of the next child, so
+// filling a page promotes the following key to the level above.
+func (s *sqliteWriter) indexTree(cells []sqliteCell) uint32 {
+	pageType := byte(sqliteIndexLeaf)
+	var right uint32
+	for {
+		var children []uint32
+		var dividers, run []sqliteCell
+		size := headerSize(pageType)
+		for i, cell := range cells {
+			n := 2 + len(cell.encode(pageType))
+			if size+n <= sqlitePageSize {
+				run = append(run, cell)
+				size += n
+				continue
+			}
+			// Promote this key, or the one before it when this is the last
+			// key, so the next page isn't left empty
+			promoted, last := cell, i == len(cells)-1
+			if last {
+				promoted = run[len(run)-1]
+				run = run[:len(run)-1]
+			}
+			children = append(children, s.add(s.page(pageType, run, promoted.child, 0)))
+			dividers = append(dividers, promoted)
+			run, size = nil, headerSize(pageType)
+			if last {
+				run, size = []sqliteCell{cell}, size+n
+			}
+		}
+		last := s.add(s.page(pageType, run, right, 0))
+		if len(dividers) == 0 {
+			return last
+		}
+		for i := range dividers {
+			dividers[i].child = children[i]
+		}
+		cells, right, pageType = dividers, last, sqliteIndexInterior
+	}
+}
+
+// sqliteRecord encodes values in the record format: a header of serial
+// types, then the values
+func sqliteRecord(values []any) []byte {
+	var types, body []byte
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			types = appendSQLiteVarint(types, 0)
+		case int64:
+			serial, size := sqliteIntegerType(v)
+			types = appendSQLiteVarint(types, serial)
+			for i := size - 1; i >= 0; i-- {
+				body = append(body, byte(v>>(8*i)))
+			}
+		case float64:
+			types = appendSQLiteVarint(types, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			types = appendSQLiteVarint(types, uint64(len(v))*2+13)
+			body = append(body, v...)
+		default:
+			panic(fmt.Sprintf("unsupported SQLite value %T", value))
+		}
+	}
+
+	// The header size counts its own varint
+	headerLen := len(types) + 1
+	for len(appendSQLiteVarint(nil, uint64(headerLen))) != headerLen-len(types) {
+		headerLen++
+	}
+	record := appendSQLiteVarint(nil, uint64(headerLen))
+	record = append(record, types...)
+	return append(record, body...)
+}
+
+// sqliteIntegerType returns the serial type of an integer and the bytes it
+// takes, using the constants 0 and 1 of schema format 4
+func sqliteIntegerType(v int64) (uint64, int) {
+	switch {
+	case v == 0:
+		return 8, 0
+	case v == 1:
+		return 9, 0
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return 1, 1
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return 2, 2
+	case v >= -1<<23 && v < 1<<23:
+		return 3, 3
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return 4, 4
+	case v >= -1<<47 && v < 1<<47:
+		return 5, 6
+	}
+	return 6, 8
+}
+
+// appendSQLiteVarint appends v as an SQLite varint: big-endian groups of 7
+// bits, with a ninth byte holding 8 bits for the largest values
+func appendSQLiteVarint(b []byte, v uint64) []byte {
+	if v >= 1<<56 {
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+	var buf [8]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, buf[i:]...)
+}
+
+// compareSQLiteKeys orders index keys the way SQLite does: column by
+// column, NULL before numbers before text, and text by its bytes
+func compareSQLiteKeys(a, b []any) int {
+	rank := func(v any) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case int64, float64:
+			return 1
+		}
+		return 2
+	}
+	number := func(v any) float64 {
+		if i, ok := v.(int64); ok {
+			return float64(i)
+		}
+		return v.(float64)
+	}
+
+	for i := range min(len(a), len(b)) {
+		if c := cmp.Compare(rank(a[i]), rank(b[i])); c != 0 {
+			return c
+		}
+		var c int
+		switch x := a[i].(type) {
+		case int64:
+			if y, ok := b[i].(int64); ok {
+				c = cmp.Compare(x, y)
+			} else {
+				c = cmp.Compare(number(x), number(b[i]))
+			}
+		case float64:
+			c = cmp.Compare(x, number(b[i]))
+		case string:
+			c = strings.Compare(x, b[i].(string))
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
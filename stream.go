@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// streamBuffer is the capacity, per worker, of the channels between the
+// stages of a streaming conversion; it bounds the pairs in flight
+const streamBuffer = 4
+
+// validateStream rejects options a streaming conversion can't honor because
+// they need every pair at once or rewrite source files
+func (c *Converter) validateStream() error {
+	for _, option := range []struct {
+		set  bool
+		flag string
+	}{
+		{c.config.Classify || c.config.MultiLabel, "-classify and -multi-label"},
+		{c.config.InputFormat != "" && c.config.InputFormat != InputYOLO, "-input-format " + c.config.InputFormat},
+		{c.config.Incremental, "-incremental and -watch"},
+		{c.config.SplitFile != "", "-split-file"},
+		{c.config.SplitUnit == SplitUnitAnnotations, "-split-unit annotations"},
+		{c.config.SplitStrategy == SplitSequential, "-split-strategy sequential"},
+		{c.config.MinValInstances > 0, "-min-val-instances"},
+		{c.config.MaxPerClass > 0 || c.config.MinPerClass > 0, "-max-per-class and -min-per-class"},
+		{c.config.Sample > 0 || c.config.MaxImages > 0, "-sample and -max-images"},
+		{c.config.Crop, "-crop"},
+		{c.config.TileSize > 0, "-tile-size"},
+		{c.config.Augment != "", "-augment"},
+		{c.config.Anonymize != "", "-anonymize"},
+		{c.config.Transcode, "-transcode"},
+		{c.config.ApplyEXIFOrientation, "-apply-exif-orientation"},
+		{c.config.NormalizePixels, "-normalize-pixels"},
+		{c.config.Dedupe, "-dedupe"},
+		{c.config.DropDegenerate, "-drop-degenerate"},
+		{c.config.PruneEmptyClasses, "-prune-empty-classes"},
+		{c.config.ReportFile != "", "-report"},
+		{c.config.HTMLReportFile != "", "-html-report"},
+		{c.config.AnnotationsTable != "", "-annotations-table"},
+		{c.config.Catalog, "-catalog"},
+		{c.config.Provenance, "-provenance"},
+		{c.config.DatasetVersion != "", "-dataset-version"},
+		{c.config.MLflowURI != "", "-mlflow-uri"},
+		{c.config.ClearMLProject != "", "-clearml-project"},
+		{c.config.Aliases != "", "-aliases"},
+		{c.config.ConfidenceColumn || c.config.MinConf > 0, "-confidence-column and -min-conf"},
+		{c.config.Granularity == GranularityParent || c.config.Granularity == GranularityBoth, "-granularity " + c.config.Granularity},
+		{c.config.Strict, "-strict"},
+		{c.config.Gates != "", "-gates"},
+		{c.config.Leakage != "", "-leakage"},
+		{c.config.ImageQuality != "", "-image-quality"},
+		{c.config.ImageMetadata != "", "-image-metadata"},
+		{c.config.TaskMetadata != "", "-task-metadata"},
+		{c.config.Mapping, "-mapping"},
+		{c.config.OutputFormat != "" && c.config.OutputFormat != OutputYOLO, "-output-format " + c.config.OutputFormat},
+	} {
+		if option.set {
+			return fmt.Errorf("%s cannot be combined with -stream", option.flag)
+		}
+	}
+	return nil
+}
+
+// streamSplit assigns an image to a split by a seeded hash of its file name,
+// so the assignment needs no other image and is the same in every run. The
+// share of training images approaches TrainSplit as the dataset grows.
+func (c *Converter) streamSplit(imagePath string) string {
+	hash := fnv.New64a()
+	binary.Write(hash, binary.LittleEndian, c.config.Seed)
+	hash.Write([]byte(filepath.Base(imagePath)))
+	if float64(hash.Sum64()>>11)/(1<<53) < c.config.TrainSplit {
+		return "train"
+	}
+	return "val"
+}
+
+// countLabelFiles counts the label files in labels/ without holding the
+// directory listing in memory
+func (c *Converter) countLabelFiles() (int, error) {
+	dir, err := os.Open(filepath.Join(c.config.SourceDir, "labels"))
+	if err != nil {
+		return 0, fmt.Errorf("error scanning labels directory: %w", err)
+	}
+	defer dir.Close()
+
+	count := 0
+	for {
+		entries, err := dir.ReadDir(1024)
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".txt") {
+				count++
+			}
+		}
+		if err != nil || len(entries) == 0 {
+			return count, nil
+		}
+	}
+}
+
+// validatedPair is a pair passed from validation to split assignment
+type validatedPair struct {
+	pair   LabelPair
+	result labelFileResult
+}
+
+// assignedPair is a pair passed from split assignment to copying
+type assignedPair struct {
+	pair  LabelPair
+	split string
+}
+
+// convertStreaming converts a YOLO export in a pipeline of bounded channels:
+// discovery, validation, split assignment and copying overlap, and only the
+// pairs in flight are held in memory, which stays flat on exports with
+// millions of files. Warnings come out in the order files are processed.
+func (c *Converter) convertStreaming() (err error) {
+	if err := c.ValidateSourceStructure(); err != nil {
+		return err
+	}
+	classes, err := c.LoadClasses()
+	if err != nil {
+		return err
+	}
+
+	// Labels with Windows encodings or line endings are normalized into
+	// staging as they are validated
+	defer c.removeStaging()
+	normalizedDir, err := c.normalizedLabelDir()
+	if err != nil {
+		return err
+	}
+	var labelIssues labelIssueCounts
+
+	writer, err := c.openOutput()
+	if err != nil {
+		return err
+	}
+	defer finishOutput(writer, &err)
+	defer c.reportFileErrors()
+	if err := c.CreateYOLOStructure(); err != nil {
+		return err
+	}
+
+	// Interrupting the conversion stops every stage
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	var failOnce sync.Once
+	var failure error
+	fail := func(err error) {
+		failOnce.Do(func() {
+			failure = err
+			cancel()
+		})
+	}
+	// Stages print from several goroutines
+	var outMu sync.Mutex
+	printf := func(format string, args ...any) {
+		outMu.Lock()
+		defer outMu.Unlock()
+		fmt.Fprintf(c.out, format, args...)
+	}
+
+	workers := c.parallelism(math.MaxInt)
+	var wg sync.WaitGroup
+
+	// Discover pairs
+	discovered := make(chan LabelPair, workers*streamBuffer)
+	filtered := 0
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(discovered)
+		n, err := c.walkImageLabelPairs(func(pair LabelPair, found bool) error {
+			if !found {
+				printf("Warning: No label file found for %s\n", filepath.Base(pair.ImagePath))
+				return nil
+			}
+			select {
+			case discovered <- pair:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		filtered = n
+		if err != nil && ctx.Err() == nil {
+			fail(fmt.Errorf("error scanning images directory: %w", err))
+		}
+	}()
+
+	// Validate their labels
+	validated := make(chan validatedPair, workers*streamBuffer)
+	var validators sync.WaitGroup
+	for range workers {
+		validators.Add(1)
+		go func() {
+			defer validators.Done()
+			for pair := range discovered {
+				pair, issues, err := normalizeLabel(pair, normalizedDir)
+				if err != nil {
+					fail(err)
+					return
+				}
+				labelIssues.add(issues)
+				select {
+				case validated <- validatedPair{pair, c.validateLabelFile(pair)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		validators.Wait()
+		close(validated)
+	}()
+
+	// Collect the statistics and assign splits
+	assigned := make(chan assignedPair, workers*streamBuffer)
+	stats := &ValidationStats{}
+	splitCounts := make(map[string]int)
+	splitStats := newDatasetStats(classes, "train", "val")
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(assigned)
+		for v := range validated {
+			for _, message := range v.result.messages {
+				printf("%s", message)
+			}
+			stats.TotalFiles++
+			stats.add(v.result.stats)
+			split := c.streamSplit(v.pair.ImagePath)
+			splitCounts[split]++
+			splitStats.add(split, v.result.stats.ClassCounts)
+			select {
+			case assigned <- assignedPair{v.pair, split}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Copy them into the output
+	var unstripped atomic.Int64
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range assigned {
+				if ctx.Err() != nil {
+					return
+				}
+				kept, err := c.copyPair(a.pair, path.Join("images", a.split), path.Join("labels", a.split))
+				if err != nil {
+					fail(err)
+					return
+				}
+				if kept {
+					unstripped.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if failure != nil {
+		return failure
+	}
+	if err := c.interrupted(); err != nil {
+		return err
+	}
+
+	if stats.TotalFiles == 0 {
+		return fmt.Errorf("%w: no valid image-label pairs found", ErrNoPairs)
+	}
+	if filtered > 0 {
+		fmt.Fprintf(c.out, "Skipped %d images by -include/-exclude patterns\n", filtered)
+	}
+	labels, err := c.countLabelFiles()
+	if err != nil {
+		return err
+	}
+	if orphans := labels - stats.TotalFiles - filtered; orphans > 0 {
+		if !c.config.AllowOrphanLabels {
+			return fmt.Errorf("found %d label files without images; images may have failed to export (convert without -stream to list them, or use -allow-orphan-labels)", orphans)
+		}
+		fmt.Fprintf(c.out, "Warning: %d label files have no image\n", orphans)
+		c.skipped += orphans
+	}
+	labelIssues.report(c)
+	if n := unstripped.Load(); n > 0 {
+		fmt.Fprintf(c.out, "Warning: metadata could not be stripped from %d images (only JPEG and PNG are supported)\n", n)
+	}
+	fmt.Fprintf(c.out, "Validation stats: %+v\n", stats)
+	metrics.AddValidationErrors(stats.InvalidLines)
+	fmt.Fprintf(c.out, "Dataset split: %d training, %d validation\n", splitCounts["train"], splitCounts["val"])
+
+	c.selectTask(stats)
+	if classes, _, err = c.checkEmptyClasses(classes, nil, stats); err != nil {
+		return err
+	}
+	if err := c.writeDatasetStats(splitStats); err != nil {
+		return err
+	}
+	if err := c.writeClassWeights(splitStats); err != nil {
+		return err
+	}
+	if err := c.CreateYAMLConfig(classes); err != nil {
+		return err
+	}
+	if c.config.FiftyOne {
+		if err := c.writeFiftyOneScript(); err != nil {
+			return err
+		}
+	}
+
+	c.counts = ProvenanceCounts{TrainImages: splitCounts["train"], ValImages: splitCounts["val"],
+		Annotations: stats.TotalAnnotations, Classes: len(classes), ValidationFindings: len(c.findings)}
+	fmt.Fprintln(c.out, "\nConversion completed successfully!")
+	fmt.Fprintf(c.out, "Dataset ready for YOLO training at: %s\n", c.config.OutputDir)
+	fmt.Fprintf(c.out, "Training images: %d\n", splitCounts["train"])
+	fmt.Fprintf(c.out, "Validation images: %d\n", splitCounts["val"])
+	fmt.Fprintf(c.out, "Total annotations: %d\n", stats.TotalAnnotations)
+	return nil
+}
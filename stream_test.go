@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertStreaming(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+	outputDir := filepath.Join(tempDir, "yolo_output")
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: outputDir, TrainSplit: 0.5, Seed: 42, Stream: true, Parallel: 2})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"image1", "image2", "image3"} {
+		image, _ := filepath.Glob(filepath.Join(outputDir, "images", "*", name+".*"))
+		label, _ := filepath.Glob(filepath.Join(outputDir, "labels", "*", name+".txt"))
+		if len(image) != 1 || len(label) != 1 {
+			t.Fatalf("Expected one image and label for %s, got %v and %v", name, image, label)
+		}
+		// The split comes from the name alone
+		want := converter.streamSplit(image[0])
+		if split := filepath.Base(filepath.Dir(image[0])); split != want {
+			t.Errorf("Expected %s in %s, got %s", name, want, split)
+		}
+		if split := filepath.Base(filepath.Dir(label[0])); split != want {
+			t.Errorf("Expected the label of %s in %s, got %s", name, want, split)
+		}
+	}
+	config := readDataYAML(t, outputDir)
+	if config.NC != 2 {
+		t.Errorf("Expected 2 classes in data.yaml, got %d", config.NC)
+	}
+}
+
+func TestStreamSplit(t *testing.T) {
+	converter := NewConverter(Config{TrainSplit: 0.8, Seed: 7})
+	train := 0
+	for i := range 2000 {
+		if converter.streamSplit(fmt.Sprintf("images/img%04d.jpg", i)) == "train" {
+			train++
+		}
+	}
+	if train < 1500 || train > 1700 {
+		t.Errorf("Expected about 80%% of 2000 images in training, got %d", train)
+	}
+	if converter.streamSplit("a/x.jpg") != converter.streamSplit("b/x.jpg") {
+		t.Error("Expected the split to depend on the file name only")
+	}
+}
+
+func TestConvertStreamingRejects(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestFiles(t, tempDir)
+
+	converter := NewConverter(Config{SourceDir: tempDir, OutputDir: filepath.Join(tempDir, "out"), TrainSplit: 0.8, Stream: true, Dedupe: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil {
+		t.Error("Expected -stream with -dedupe to fail")
+	}
+
+	// A label without an image stops the conversion
+	if err := os.WriteFile(filepath.Join(tempDir, "labels", "lost.txt"), []byte("0 0.5 0.5 0.1 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	converter = NewConverter(Config{SourceDir: tempDir, OutputDir: filepath.Join(tempDir, "out"), TrainSplit: 0.8, Stream: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil {
+		t.Error("Expected an orphan label to fail the conversion")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// annotationTableColumns are the columns of the -annotations-table export
+var annotationTableColumns = []string{"image", "split", "class_id", "class", "cx", "cy", "w", "h"}
+
+// annotationRow is one annotation of the table export
+type annotationRow struct {
+	image   string
+	split   string
+	classID int
+	class   string
+	bounds  [4]float64
+}
+
+// annotationRows lists the annotations of the splits, one row per label
+// line, with polygons and oriented boxes as the boxes enclosing them
+func annotationRows(classes []string, splits map[string][]LabelPair) ([]annotationRow, error) {
+	var rows []annotationRow
+	for _, split := range []string{"train", "val"} {
+		for _, pair := range splits[split] {
+			boxes, err := readLabelBoxes(pair.LabelPath, len(classes))
+			if err != nil {
+				return nil, err
+			}
+			for _, box := range boxes {
+				rows = append(rows, annotationRow{
+					image:   filepath.Base(pair.ImagePath),
+					split:   split,
+					classID: box.ClassID,
+					class:   classes[box.ClassID],
+					bounds:  box.Bounds,
+				})
+			}
+		}
+	}
+	return rows, nil
+}
+
+// writeAnnotationTable writes every annotation of the dataset as a row of a
+// flat table, for analysis in pandas or DuckDB: Parquet for a .parquet path
+// and CSV otherwise
+func (c *Converter) writeAnnotationTable(classes []string, splits map[string][]LabelPair) error {
+	rows, err := annotationRows(classes, splits)
+	if err != nil {
+		return err
+	}
+
+	path := c.config.AnnotationsTable
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create annotation table: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if strings.EqualFold(filepath.Ext(path), ".parquet") {
+		err = writeAnnotationParquet(w, rows)
+	} else {
+		err = writeAnnotationCSV(w, rows)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write annotation table: %w", err)
+	}
+	fmt.Fprintf(c.out, "Wrote %d annotations to %s\n", len(rows), path)
+	return nil
+}
+
+// writeAnnotationCSV writes the rows as CSV with a header line
+func writeAnnotationCSV(w io.Writer, rows []annotationRow) error {
+	records := csv.NewWriter(w)
+	records.Write(annotationTableColumns)
+	for _, row := range rows {
+		records.Write([]string{
+			row.image, row.split, strconv.Itoa(row.classID), row.class,
+			formatCoord(row.bounds[0]), formatCoord(row.bounds[1]), formatCoord(row.bounds[2]), formatCoord(row.bounds[3]),
+		})
+	}
+	records.Flush()
+	return records.Error()
+}
+
+// writeAnnotationParquet writes the rows as a Parquet file
+func writeAnnotationParquet(w io.Writer, rows []annotationRow) error {
+	columns := []parquetColumn{
+		{name: "image", kind: parquetByteArray},
+		{name: "split", kind: parquetByteArray},
+		{name: "class_id", kind: parquetInt32},
+		{name: "class", kind: parquetByteArray},
+		{name: "cx", kind: parquetDouble},
+		{name: "cy", kind: parquetDouble},
+		{name: "w", kind: parquetDouble},
+		{name: "h", kind: parquetDouble},
+	}
+	for _, row := range rows {
+		columns[0].strings = append(columns[0].strings, row.image)
+		columns[1].strings = append(columns[1].strings, row.split)
+		columns[2].int32s = append(columns[2].int32s, int32(row.classID))
+		columns[3].strings = append(columns[3].strings, row.class)
+		for i := range row.bounds {
+			columns[4+i].doubles = append(columns[4+i].doubles, row.bounds[i])
+		}
+	}
+	return writeParquet(w, columns, len(rows))
+}
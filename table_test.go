@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnnotationTableCSV(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image2.txt"), []byte("0 0.2 0.2 0.6 0.2 0.6 0.8\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	table := filepath.Join(t.TempDir(), "annotations.csv")
+
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: filepath.Join(t.TempDir(), "out"), TrainSplit: 0.8, Seed: 42, AnnotationsTable: table})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(records[0], ",") != "image,split,class_id,class,cx,cy,w,h" {
+		t.Errorf("Unexpected header %v", records[0])
+	}
+	if len(records) != 6 {
+		t.Fatalf("Expected a row per annotation (5), got %d", len(records)-1)
+	}
+	rows := make(map[string]string)
+	for _, record := range records[1:] {
+		rows[record[0]+" "+record[3]] = strings.Join(record[4:], " ")
+	}
+	// Polygons are listed as the box enclosing them
+	if got := rows["image2.png book"]; got != "0.400000 0.500000 0.400000 0.600000" {
+		t.Errorf("Unexpected polygon row %q", got)
+	}
+	if got := rows["image1.jpg person"]; got != "0.200000 0.800000 0.100000 0.100000" {
+		t.Errorf("Unexpected box row %q", got)
+	}
+}
+
+// thriftFields reads the top-level fields of a Thrift compact struct,
+// returning the integer ones by ID and skipping the rest
+func thriftFields(t *testing.T, data []byte) map[int]int64 {
+	fields := make(map[int]int64)
+	r := bytes.NewReader(data)
+	var skip func(kind byte)
+	varint := func() int64 {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int64(v>>1) ^ -int64(v&1)
+	}
+	readStruct := func() {
+		id := 0
+		for {
+			header, err := r.ReadByte()
+			if err != nil || header == 0 {
+				return
+			}
+			kind := header & 0x0f
+			if delta := int(header >> 4); delta != 0 {
+				id += delta
+			} else {
+				id = int(varint())
+			}
+			if kind == thriftI32 || kind == thriftI64 {
+				fields[id] = varint()
+				continue
+			}
+			skip(kind)
+		}
+	}
+	skip = func(kind byte) {
+		switch kind {
+		case thriftI32, thriftI64:
+			varint()
+		case thriftBinary:
+			n, _ := binary.ReadUvarint(r)
+			r.Seek(int64(n), io.SeekCurrent)
+		case thriftList:
+			header, _ := r.ReadByte()
+			size := int(header >> 4)
+			if size == 15 {
+				n, _ := binary.ReadUvarint(r)
+				size = int(n)
+			}
+			for range size {
+				skip(header & 0x0f)
+			}
+		case thriftStruct:
+			id := 0
+			for {
+				header, _ := r.ReadByte()
+				if header == 0 {
+					break
+				}
+				if delta := int(header >> 4); delta != 0 {
+					id += delta
+				} else {
+					id = int(varint())
+				}
+				skip(header & 0x0f)
+			}
+		default:
+			t.Fatalf("Unexpected Thrift type %d", kind)
+		}
+	}
+	readStruct()
+	return fields
+}
+
+func TestAnnotationTableParquet(t *testing.T) {
+	rows := []annotationRow{
+		{image: "a.jpg", split: "train", classID: 0, class: "book", bounds: [4]float64{0.5, 0.5, 0.3, 0.3}},
+		{image: "b.jpg", split: "val", classID: 1, class: "person", bounds: [4]float64{0.2, 0.8, 0.1, 0.1}},
+	}
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, []parquetColumn{
+		{name: "image", kind: parquetByteArray, strings: []string{rows[0].image, rows[1].image}},
+		{name: "class_id", kind: parquetInt32, int32s: []int32{0, 1}},
+	}, len(rows)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatal("Expected the Parquet magic at both ends")
+	}
+	footer := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := thriftFields(t, data[len(data)-8-footer:len(data)-8])
+	if meta[1] != 1 || meta[3] != 2 {
+		t.Errorf("Expected version 1 and 2 rows in the file metadata, got %v", meta)
+	}
+	// The first data page follows the magic: its header, then the PLAIN values
+	page := thriftFields(t, data[4:])
+	if page[1] != 0 || page[2] != 18 {
+		t.Errorf("Expected a data page of 18 bytes, got %v", page)
+	}
+	if !bytes.Contains(data, []byte("\x05\x00\x00\x00a.jpg\x05\x00\x00\x00b.jpg")) {
+		t.Error("Expected the PLAIN-encoded image column")
+	}
+
+	var table bytes.Buffer
+	if err := writeAnnotationParquet(&table, rows); err != nil {
+		t.Fatal(err)
+	}
+	footer = int(binary.LittleEndian.Uint32(table.Bytes()[table.Len()-8:]))
+	meta = thriftFields(t, table.Bytes()[table.Len()-8-footer:table.Len()-8])
+	if meta[3] != 2 {
+		t.Errorf("Expected 2 rows in the annotation table, got %v", meta)
+	}
+}
+
+func TestAnnotationTableRejectedWithCrop(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: t.TempDir(), AnnotationsTable: "annotations.csv", Crop: true})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err == nil || !strings.Contains(err.Error(), "-annotations-table") {
+		t.Errorf("Expected -annotations-table to be rejected with -crop, got %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Task metadata modes, selected with -task-metadata: a JSON sidecar per
+// image, or one file for the whole dataset
+const (
+	TaskMetadataSidecar = "sidecar"
+	TaskMetadataFile    = "file"
+)
+
+// Where the task metadata is written: sidecars go to
+// tasks/<split>/<image stem>.json, the single file to tasks.json
+const (
+	taskSidecarDir   = "tasks"
+	taskMetadataFile = "tasks.json"
+)
+
+// TaskMetadata traces an image of the dataset back to its Label Studio task
+type TaskMetadata struct {
+	// Image and Split locate the image in the dataset, in tasks.json only
+	Image string `json:"image,omitempty"`
+	Split string `json:"split,omitempty"`
+
+	TaskID int `json:"task_id"`
+	// AnnotationIDs and Annotators are the annotations that passed the
+	// filters; Annotators are emails, or user IDs for exports without them
+	AnnotationIDs []int    `json:"annotation_ids,omitempty"`
+	Annotators    []string `json:"annotators,omitempty"`
+	// CompletedAt is when the latest of those annotations was last updated
+	CompletedAt string `json:"completed_at,omitempty"`
+	// SourceURL is the image or video reference of the task's data
+	SourceURL string `json:"source_url"`
+	// FromPredictions marks tasks converted from their predictions
+	FromPredictions bool `json:"from_predictions,omitempty"`
+}
+
+// validateTaskMetadata checks -task-metadata before the conversion starts,
+// once the input format is known
+func (c *Converter) validateTaskMetadata() error {
+	c.taskMetadata, c.taskStems = nil, nil
+	switch c.config.TaskMetadata {
+	case "":
+		return nil
+	case TaskMetadataSidecar, TaskMetadataFile:
+	default:
+		return fmt.Errorf("unknown task metadata mode %q (expected sidecar or file)", c.config.TaskMetadata)
+	}
+	if c.config.InputFormat != InputLSJSON {
+		return fmt.Errorf("-task-metadata requires a Label Studio JSON export (-input-format ls-json)")
+	}
+	if c.config.Classify || c.config.MultiLabel || c.config.Crop {
+		return fmt.Errorf("-task-metadata cannot be combined with -classify, -multi-label or -crop")
+	}
+	if c.config.OutputFormat != "" && c.config.OutputFormat != OutputYOLO {
+		return fmt.Errorf("-task-metadata requires the yolo output format")
+	}
+	return nil
+}
+
+// recordTask remembers the task an image was converted from
+func (c *Converter) recordTask(imageName string, task LSTask) {
+	if c.config.TaskMetadata == "" && !c.config.Mapping {
+		return
+	}
+	if c.taskMetadata == nil {
+		c.taskMetadata = make(map[string]TaskMetadata)
+		c.taskStems = make(map[string]TaskMetadata)
+	}
+	metadata := TaskMetadata{TaskID: task.ID, SourceURL: taskImage(task)}
+	if video := taskVideo(task); video != "" {
+		metadata.SourceURL = video
+	}
+
+	var used []LSAnnotation
+	for _, a := range task.Annotations {
+		if c.filter == nil || c.filter.reason(a) == "" {
+			used = append(used, a)
+		}
+	}
+	for _, a := range used {
+		metadata.AnnotationIDs = append(metadata.AnnotationIDs, a.ID)
+		annotator := a.CompletedBy.Email
+		if annotator == "" && a.CompletedBy.ID != 0 {
+			annotator = strconv.Itoa(a.CompletedBy.ID)
+		}
+		if annotator != "" && !slices.Contains(metadata.Annotators, annotator) {
+			metadata.Annotators = append(metadata.Annotators, annotator)
+		}
+	}
+	if len(used) > 0 {
+		latest := latestAnnotation(used)
+		metadata.CompletedAt = latest.UpdatedAt
+		if metadata.CompletedAt == "" {
+			metadata.CompletedAt = latest.CreatedAt
+		}
+	} else if c.config.UsePredictions && len(task.Predictions) > 0 {
+		metadata.FromPredictions = true
+	}
+	c.taskMetadata[imageName] = metadata
+	c.taskStems[strings.TrimSuffix(imageName, filepath.Ext(imageName))] = metadata
+}
+
+// taskFor returns the task metadata of a dataset image, matching by file
+// name or, for transcoded images, by name without extension
+func (c *Converter) taskFor(imagePath string) (TaskMetadata, bool) {
+	name := filepath.Base(imagePath)
+	if metadata, ok := c.taskMetadata[name]; ok {
+		return metadata, true
+	}
+	metadata, ok := c.taskStems[strings.TrimSuffix(name, filepath.Ext(name))]
+	return metadata, ok
+}
+
+// writeTaskMetadata writes the Label Studio task of every image of the
+// splits, as sidecars or as one tasks.json
+func (c *Converter) writeTaskMetadata(trainPairs, valPairs []LabelPair) error {
+	if c.config.TaskMetadata == "" {
+		return nil
+	}
+	// Sidecars of images gone since the last conversion would linger
+	if c.config.TaskMetadata == TaskMetadataSidecar && archiveFormat(c.config.OutputDir) == "" {
+		if err := os.RemoveAll(filepath.Join(c.config.OutputDir, taskSidecarDir)); err != nil {
+			return fmt.Errorf("failed to remove old task sidecars: %w", err)
+		}
+	}
+
+	var records []TaskMetadata
+	missing := 0
+	for _, split := range []struct {
+		name  string
+		pairs []LabelPair
+	}{{"train", trainPairs}, {"val", valPairs}} {
+		if c.config.TaskMetadata == TaskMetadataSidecar {
+			if err := c.output().MkdirAll(path.Join(taskSidecarDir, split.name)); err != nil {
+				return fmt.Errorf("failed to create task sidecar directory: %w", err)
+			}
+		}
+		for _, pair := range split.pairs {
+			metadata, ok := c.taskFor(pair.ImagePath)
+			if !ok {
+				missing++
+				continue
+			}
+			name := filepath.Base(pair.ImagePath)
+			if c.config.TaskMetadata == TaskMetadataFile {
+				metadata.Image = path.Join("images", split.name, name)
+				metadata.Split = split.name
+				records = append(records, metadata)
+				continue
+			}
+			data, err := json.MarshalIndent(metadata, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode task metadata: %w", err)
+			}
+			sidecar := path.Join(taskSidecarDir, split.name, strings.TrimSuffix(name, filepath.Ext(name))+".json")
+			if err := c.output().WriteFile(sidecar, append(data, '\n')); err != nil {
+				return fmt.Errorf("failed to write %s: %w", sidecar, err)
+			}
+		}
+	}
+	if missing > 0 {
+		fmt.Fprintf(c.out, "Warning: %d images have no Label Studio task (tiles, augmentations or balancing duplicates)\n", missing)
+	}
+
+	if c.config.TaskMetadata == TaskMetadataSidecar {
+		fmt.Fprintf(c.out, "Wrote the Label Studio task of each image to %s/\n", taskSidecarDir)
+		return nil
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Image < records[j].Image })
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode task metadata: %w", err)
+	}
+	if err := c.output().WriteFile(taskMetadataFile, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", taskMetadataFile, err)
+	}
+	fmt.Fprintf(c.out, "Wrote the Label Studio tasks of %d images to %s\n", len(records), taskMetadataFile)
+	return nil
+}
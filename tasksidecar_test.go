@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createAttributedExport writes the Label Studio export of
+// createLabelStudioExport with annotators and completion times on task 1
+func createAttributedExport(t *testing.T, baseDir string) {
+	createLabelStudioExport(t, baseDir)
+	exportPath := filepath.Join(baseDir, "export.json")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	var tasks []LSTask
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		t.Fatalf("Failed to decode export: %v", err)
+	}
+	first := tasks[0].Annotations[0]
+	first.ID = 11
+	first.CompletedBy = LSUser{ID: 7, Email: "ann@example.com"}
+	first.UpdatedAt = "2024-03-01T10:00:00Z"
+	second := first
+	second.ID = 12
+	second.CompletedBy = LSUser{ID: 8}
+	second.UpdatedAt = "2024-03-02T09:30:00Z"
+	tasks[0].Annotations = []LSAnnotation{first, second}
+
+	if data, err = json.Marshal(tasks); err != nil {
+		t.Fatalf("Failed to encode tasks: %v", err)
+	}
+	if err := os.WriteFile(exportPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+}
+
+func TestTaskMetadataSidecars(t *testing.T) {
+	sourceDir := t.TempDir()
+	createAttributedExport(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputLSJSON, TaskMetadata: TaskMetadataSidecar})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "tasks", "train", "a1b2-cat.json"))
+	if err != nil {
+		t.Fatalf("Missing sidecar: %v", err)
+	}
+	var metadata TaskMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		t.Fatalf("Failed to decode sidecar: %v", err)
+	}
+	if metadata.TaskID != 1 || metadata.SourceURL != "/data/upload/1/a1b2-cat.jpg" {
+		t.Errorf("Expected task 1 from /data/upload/1/a1b2-cat.jpg, got %+v", metadata)
+	}
+	if strings.Join(metadata.Annotators, ",") != "ann@example.com,8" {
+		t.Errorf("Expected annotators by email, then ID, got %v", metadata.Annotators)
+	}
+	if len(metadata.AnnotationIDs) != 2 || metadata.CompletedAt != "2024-03-02T09:30:00Z" {
+		t.Errorf("Expected both annotations completed at the latest update, got %+v", metadata)
+	}
+	if metadata.Image != "" || metadata.Split != "" {
+		t.Errorf("Expected sidecars without image and split, got %+v", metadata)
+	}
+	for _, name := range []string{"dog photo.json", "mask.json"} {
+		if _, err := os.Stat(filepath.Join(outputDir, "tasks", "train", name)); err != nil {
+			t.Errorf("Missing sidecar %s: %v", name, err)
+		}
+	}
+
+	// A sidecar of an image gone from the export is removed on the next run
+	stale := filepath.Join(outputDir, "tasks", "train", "gone.json")
+	if err := os.WriteFile(stale, []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write stale sidecar: %v", err)
+	}
+	converter = NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputLSJSON, TaskMetadata: TaskMetadataSidecar})
+	converter.SetOutput(&strings.Builder{})
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Second conversion failed: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale sidecar to be removed, got %v", err)
+	}
+}
+
+func TestTaskMetadataFile(t *testing.T) {
+	sourceDir := t.TempDir()
+	createAttributedExport(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputLSJSON, TaskMetadata: TaskMetadataFile})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "tasks.json"))
+	if err != nil {
+		t.Fatalf("Missing tasks.json: %v", err)
+	}
+	var records []TaskMetadata
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("Failed to decode tasks.json: %v", err)
+	}
+	var images []string
+	for _, record := range records {
+		images = append(images, record.Image)
+		if record.Split != "train" {
+			t.Errorf("Expected %s in train, got %q", record.Image, record.Split)
+		}
+	}
+	want := "images/train/a1b2-cat.jpg,images/train/dog photo.png,images/train/mask.png"
+	if strings.Join(images, ",") != want {
+		t.Errorf("Expected records %s, got %s", want, strings.Join(images, ","))
+	}
+	if records[1].TaskID != 2 || records[1].SourceURL != "/data/local-files/?d=photos/dog%20photo.png" {
+		t.Errorf("Expected task 2 for the dog photo, got %+v", records[1])
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "tasks")); !os.IsNotExist(err) {
+		t.Errorf("Expected no sidecars in file mode, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Wrote the Label Studio tasks of 3 images to tasks.json") {
+		t.Errorf("Expected a summary, got:\n%s", out.String())
+	}
+}
+
+func TestValidateTaskMetadata(t *testing.T) {
+	tests := []struct {
+		config Config
+		want   string
+	}{
+		{Config{TaskMetadata: "csv", InputFormat: InputLSJSON}, "unknown task metadata mode"},
+		{Config{TaskMetadata: TaskMetadataFile, InputFormat: InputYOLO}, "requires a Label Studio JSON export"},
+		{Config{TaskMetadata: TaskMetadataSidecar, InputFormat: InputLSJSON, Classify: true}, "cannot be combined"},
+		{Config{TaskMetadata: TaskMetadataSidecar, InputFormat: InputLSJSON, OutputFormat: OutputTFRecord}, "requires the yolo output format"},
+	}
+	for _, tt := range tests {
+		err := NewConverter(tt.config).validateTaskMetadata()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.config, tt.want, err)
+		}
+	}
+	if err := NewConverter(Config{TaskMetadata: TaskMetadataFile, InputFormat: InputLSJSON}).validateTaskMetadata(); err != nil {
+		t.Errorf("Expected a valid configuration, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tfrecordImagesPerShard sizes the TFRecord shards when -tfrecord-shards
+// isn't set
+const tfrecordImagesPerShard = 1000
+
+// tfrecordLabelMap is the class list of a TFRecord dataset, in the
+// StringIntLabelMap text format of the TF Object Detection API
+const tfrecordLabelMap = "label_map.pbtxt"
+
+// writeTFRecords writes the splits as sharded TFRecord files in the schema
+// of the TF Object Detection API, plus the label map that names their class
+// IDs. Shards are built in the staging directory one at a time and then
+// written to the output.
+func (c *Converter) writeTFRecords(classes []string, splits map[string][]LabelPair) error {
+	writer := c.output()
+	staging, err := c.stagingDirectory()
+	if err != nil {
+		return err
+	}
+
+	for _, split := range []string{"train", "val"} {
+		pairs := splits[split]
+		if len(pairs) == 0 {
+			continue
+		}
+		shards := c.config.TFRecordShards
+		if shards <= 0 {
+			shards = (len(pairs) + tfrecordImagesPerShard - 1) / tfrecordImagesPerShard
+		}
+		shards = min(shards, len(pairs))
+
+		for shard := range shards {
+			if err := c.interrupted(); err != nil {
+				return err
+			}
+			name := fmt.Sprintf("%s.record-%05d-of-%05d", split, shard, shards)
+			path := filepath.Join(staging, name)
+			if err := c.writeTFRecordShard(path, pairs[shard*len(pairs)/shards:(shard+1)*len(pairs)/shards], classes); err != nil {
+				return err
+			}
+			err := writer.CopyFile(name, path)
+			os.Remove(path)
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", name, err)
+			}
+		}
+		fmt.Fprintf(c.out, "Wrote %d %s images to %d TFRecord files\n", len(pairs), split, shards)
+	}
+
+	if err := writer.WriteFile(tfrecordLabelMap, tfrecordLabelMapText(classes)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tfrecordLabelMap, err)
+	}
+	return nil
+}
+
+// writeTFRecordShard writes the examples of pairs to one TFRecord file
+func (c *Converter) writeTFRecordShard(path string, pairs []LabelPair, classes []string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buffered := bufio.NewWriterSize(file, copyBufferSize)
+	for _, pair := range pairs {
+		example, err := tfExample(pair, classes)
+		if err != nil {
+			return err
+		}
+		if err := writeTFRecord(buffered, example); err != nil {
+			return err
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// tfExample encodes an image and its labels as a tf.train.Example. Boxes are
+// normalized corners; polygons become the box enclosing them. Class labels
+// are 1-based, as the label map reserves 0 for the background.
+func tfExample(pair LabelPair, classes []string) ([]byte, error) {
+	encoded, err := os.ReadFile(pair.ImagePath)
+	if err != nil {
+		return nil, err
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image %s: %w", pair.ImagePath, err)
+	}
+	if format != "jpeg" && format != "png" {
+		return nil, fmt.Errorf("image %s is %s; TFRecord output needs JPEG or PNG images", pair.ImagePath, format)
+	}
+
+	boxes, err := readLabelBoxes(pair.LabelPath, len(classes))
+	if err != nil {
+		return nil, err
+	}
+	var xmin, xmax, ymin, ymax []float32
+	var classTexts [][]byte
+	var classLabels []int64
+	for _, box := range boxes {
+		x0, y0, x1, y1 := box.corners()
+		xmin = append(xmin, float32(x0))
+		xmax = append(xmax, float32(x1))
+		ymin = append(ymin, float32(y0))
+		ymax = append(ymax, float32(y1))
+		classTexts = append(classTexts, []byte(classes[box.ClassID]))
+		classLabels = append(classLabels, int64(box.ClassID+1))
+	}
+
+	name := []byte(filepath.Base(pair.ImagePath))
+	sum := sha256.Sum256(encoded)
+	return encodeTFExample(map[string][]byte{
+		"image/height":             int64Feature(int64(config.Height)),
+		"image/width":              int64Feature(int64(config.Width)),
+		"image/filename":           bytesFeature(name),
+		"image/source_id":          bytesFeature(name),
+		"image/key/sha256":         bytesFeature([]byte(hex.EncodeToString(sum[:]))),
+		"image/encoded":            bytesFeature(encoded),
+		"image/format":             bytesFeature([]byte(format)),
+		"image/object/bbox/xmin":   floatFeature(xmin...),
+		"image/object/bbox/xmax":   floatFeature(xmax...),
+		"image/object/bbox/ymin":   floatFeature(ymin...),
+		"image/object/bbox/ymax":   floatFeature(ymax...),
+		"image/object/class/text":  bytesFeature(classTexts...),
+		"image/object/class/label": int64Feature(classLabels...),
+	}), nil
+}
+
+// tfrecordLabelMapText returns the label map of the classes, with IDs
+// starting at 1
+func tfrecordLabelMapText(classes []string) []byte {
+	var b strings.Builder
+	for i, name := range classes {
+		fmt.Fprintf(&b, "item {\n  id: %d\n  name: '%s'\n}\n", i+1, strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(name))
+	}
+	return []byte(b.String())
+}
+
+// protoBytes is the wire type of length-delimited protocol buffer fields,
+// the only one tf.train.Example needs at the message level:
+//
+//	Example  { Features features = 1; }
+//	Features { map<string, Feature> feature = 1; }
+//	Feature  { oneof kind { BytesList bytes_list = 1; FloatList float_list = 2; Int64List int64_list = 3; } }
+//
+// Float and int64 lists are packed; bytes lists repeat their value field.
+const protoBytes = 2
+
+// appendProtoField appends a length-delimited field
+func appendProtoField(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|protoBytes))
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// bytesFeature encodes a Feature holding a BytesList
+func bytesFeature(values ...[]byte) []byte {
+	var list []byte
+	for _, value := range values {
+		list = appendProtoField(list, 1, value)
+	}
+	return appendProtoField(nil, 1, list)
+}
+
+// floatFeature encodes a Feature holding a FloatList
+func floatFeature(values ...float32) []byte {
+	var packed []byte
+	for _, value := range values {
+		packed = binary.LittleEndian.AppendUint32(packed, math.Float32bits(value))
+	}
+	var list []byte
+	if len(values) > 0 {
+		list = appendProtoField(list, 1, packed)
+	}
+	return appendProtoField(nil, 2, list)
+}
+
+// int64Feature encodes a Feature holding an Int64List
+func int64Feature(values ...int64) []byte {
+	var packed []byte
+	for _, value := range values {
+		packed = binary.AppendUvarint(packed, uint64(value))
+	}
+	var list []byte
+	if len(values) > 0 {
+		list = appendProtoField(list, 1, packed)
+	}
+	return appendProtoField(nil, 3, list)
+}
+
+// encodeTFExample encodes an Example from encoded Features by name, sorted
+// so the same input always gives the same bytes
+func encodeTFExample(features map[string][]byte) []byte {
+	keys := make([]string, 0, len(features))
+	for key := range features {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var feature []byte
+	for _, key := range keys {
+		var entry []byte
+		entry = appendProtoField(entry, 1, []byte(key))
+		entry = appendProtoField(entry, 2, features[key])
+		feature = appendProtoField(feature, 1, entry)
+	}
+	return appendProtoField(nil, 1, feature)
+}
+
+// tfrecordCRC is the CRC-32C table of TFRecord checksums
+var tfrecordCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// maskedCRC returns the masked CRC-32C that TFRecord stores for data
+func maskedCRC(data []byte) uint32 {
+	crc := crc32.Checksum(data, tfrecordCRC)
+	return (crc>>15 | crc<<17) + 0xa282ead8
+}
+
+// writeTFRecord writes one record: its length and the length's checksum,
+// then the data and its checksum
+func writeTFRecord(w io.Writer, data []byte) error {
+	header := binary.LittleEndian.AppendUint64(nil, uint64(len(data)))
+	header = binary.LittleEndian.AppendUint32(header, maskedCRC(header))
+	footer := binary.LittleEndian.AppendUint32(nil, maskedCRC(data))
+	for _, b := range [][]byte{header, data, footer} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
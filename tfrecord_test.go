@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// readTFRecords reads the records of a TFRecord file, checking their CRCs
+func readTFRecords(t *testing.T, path string) [][]byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records [][]byte
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("Truncated record header in %s", path)
+		}
+		if binary.LittleEndian.Uint32(data[8:12]) != maskedCRC(data[:8]) {
+			t.Fatalf("Bad length CRC in %s", path)
+		}
+		length := int(binary.LittleEndian.Uint64(data[:8]))
+		record := data[12 : 12+length]
+		if binary.LittleEndian.Uint32(data[12+length:16+length]) != maskedCRC(record) {
+			t.Fatalf("Bad data CRC in %s", path)
+		}
+		records = append(records, record)
+		data = data[16+length:]
+	}
+	return records
+}
+
+// protoFields splits a message of length-delimited fields into their values
+// by field number
+func protoFields(t *testing.T, data []byte) map[uint64][][]byte {
+	fields := make(map[uint64][][]byte)
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if key&7 != protoBytes {
+			t.Fatalf("Unexpected wire type %d", key&7)
+		}
+		length, m := binary.Uvarint(data[n:])
+		value := data[n+m : n+m+int(length)]
+		fields[key>>3] = append(fields[key>>3], value)
+		data = data[n+m+int(length):]
+	}
+	return fields
+}
+
+// tfFeature is a decoded Feature: one of its lists is set
+type tfFeature struct {
+	bytes  []string
+	floats []float32
+	ints   []int64
+}
+
+// decodeTFExample decodes the features of a tf.train.Example
+func decodeTFExample(t *testing.T, data []byte) map[string]tfFeature {
+	features := make(map[string]tfFeature)
+	for _, entry := range protoFields(t, protoFields(t, data)[1][0])[1] {
+		fields := protoFields(t, entry)
+		var feature tfFeature
+		kind := protoFields(t, fields[2][0])
+		if lists := kind[1]; len(lists) > 0 {
+			for _, value := range protoFields(t, lists[0])[1] {
+				feature.bytes = append(feature.bytes, string(value))
+			}
+		}
+		if lists := kind[2]; len(lists) > 0 {
+			for _, packed := range protoFields(t, lists[0])[1] {
+				for i := 0; i < len(packed); i += 4 {
+					feature.floats = append(feature.floats, math.Float32frombits(binary.LittleEndian.Uint32(packed[i:])))
+				}
+			}
+		}
+		if lists := kind[3]; len(lists) > 0 {
+			for _, packed := range protoFields(t, lists[0])[1] {
+				for len(packed) > 0 {
+					value, n := binary.Uvarint(packed)
+					feature.ints = append(feature.ints, int64(value))
+					packed = packed[n:]
+				}
+			}
+		}
+		features[string(fields[1][0])] = feature
+	}
+	return features
+}
+
+func TestConvertTFRecord(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image1.jpg"), 64, 48)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image2.png"), 32, 32)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "image3.jpeg"), 16, 16)
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image2.txt"), []byte("0 0.2 0.2 0.6 0.2 0.6 0.8\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "tfrecord")
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.7, Seed: 42, OutputFormat: OutputTFRecord, TFRecordShards: 2})
+	converter.SetOutput(io.Discard)
+	if err := converter.Convert(); err != nil {
+		t.Fatal(err)
+	}
+
+	labelMap, err := os.ReadFile(filepath.Join(outputDir, tfrecordLabelMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(labelMap) != "item {\n  id: 1\n  name: 'book'\n}\nitem {\n  id: 2\n  name: 'person'\n}\n" {
+		t.Errorf("Unexpected label map:\n%s", labelMap)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(outputDir, "*.record-*"))
+	sort.Strings(files)
+	var names []string
+	examples := make(map[string]map[string]tfFeature)
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+		for _, record := range readTFRecords(t, file) {
+			example := decodeTFExample(t, record)
+			examples[example["image/filename"].bytes[0]] = example
+		}
+	}
+	if strings.Join(names, " ") != "train.record-00000-of-00002 train.record-00001-of-00002 val.record-00000-of-00001" {
+		t.Errorf("Unexpected shards %v", names)
+	}
+	if len(examples) != 3 {
+		t.Fatalf("Expected 3 examples, got %d", len(examples))
+	}
+
+	image1 := examples["image1.jpg"]
+	if image1["image/width"].ints[0] != 64 || image1["image/height"].ints[0] != 48 || image1["image/format"].bytes[0] != "jpeg" {
+		t.Errorf("Unexpected image features %+v %+v %+v", image1["image/width"], image1["image/height"], image1["image/format"])
+	}
+	if got := image1["image/object/class/label"].ints; len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Expected 1-based class labels [1 2], got %v", got)
+	}
+	if got := image1["image/object/class/text"].bytes; strings.Join(got, ",") != "book,person" {
+		t.Errorf("Expected class texts book,person, got %v", got)
+	}
+	if got := image1["image/object/bbox/xmin"].floats; len(got) != 2 || math.Abs(float64(got[0])-0.35) > 1e-6 {
+		t.Errorf("Expected xmin 0.35 for the first box, got %v", got)
+	}
+	encoded, _ := os.ReadFile(filepath.Join(sourceDir, "images", "image1.jpg"))
+	if image1["image/encoded"].bytes[0] != string(encoded) {
+		t.Error("Expected the image bytes to be embedded unchanged")
+	}
+
+	// Polygons become the box enclosing them
+	image2 := examples["image2.png"]
+	box := []float32{image2["image/object/bbox/xmin"].floats[0], image2["image/object/bbox/ymin"].floats[0],
+		image2["image/object/bbox/xmax"].floats[0], image2["image/object/bbox/ymax"].floats[0]}
+	for i, want := range []float32{0.2, 0.2, 0.6, 0.8} {
+		if math.Abs(float64(box[i]-want)) > 1e-6 {
+			t.Errorf("Expected the polygon's box [0.2 0.2 0.6 0.8], got %v", box)
+			break
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "data.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected no YOLO files in a TFRecord dataset, got %v", err)
+	}
+}
+
+func TestOutputFormatRejectsIncompatibleOptions(t *testing.T) {
+	for _, config := range []Config{
+		{OutputFormat: "coco"},
+		{OutputFormat: OutputTFRecord, Crop: true},
+		{OutputFormat: OutputTFRecord, Stream: true},
+	} {
+		config.SourceDir = t.TempDir()
+		config.OutputDir = filepath.Join(t.TempDir(), "out")
+		config.TrainSplit = 0.8
+		converter := NewConverter(config)
+		converter.SetOutput(io.Discard)
+		if err := converter.Convert(); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
+
+func TestTFRecordFraming(t *testing.T) {
+	// The CRC-32C of "123456789" is 0xe3069283; TFRecord stores it rotated
+	// right by 15 bits plus 0xa282ead8
+	if got := maskedCRC([]byte("123456789")); got != 0xc78ab0e5 {
+		t.Errorf("Unexpected masked CRC %#x", got)
+	}
+	if got := int64Feature(1, 300); string(got) != "\x1a\x05\x0a\x03\x01\xac\x02" {
+		t.Errorf("Unexpected Int64List encoding %q", got)
+	}
+}
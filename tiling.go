@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tileMinVisibility is the fraction of a region's area that must fall inside
+// a tile for the region to be labeled in it; smaller slivers are dropped
+const tileMinVisibility = 0.2
+
+// validateTiling checks the tiling options
+func (c *Converter) validateTiling() error {
+	if c.config.TileSize < 0 {
+		return fmt.Errorf("tile size must not be negative")
+	}
+	if c.config.TileOverlap < 0 || c.config.TileOverlap >= 1 {
+		return fmt.Errorf("tile overlap must be at least 0 and below 1, got %g", c.config.TileOverlap)
+	}
+	if c.config.TileBackground < 0 || c.config.TileBackground > 1 {
+		return fmt.Errorf("tile background fraction must be between 0 and 1, got %g", c.config.TileBackground)
+	}
+	if c.config.TileSize > 0 && (c.config.OBB || c.skeleton != nil) {
+		return fmt.Errorf("tiling supports box and polygon labels only")
+	}
+	return nil
+}
+
+// tileOffsets returns the start of every tile along an axis of the given
+// length. Tiles advance by size minus the overlap and the last tile is moved
+// back to end at the image edge.
+func tileOffsets(length, size int, overlap float64) []int {
+	if length <= size {
+		return []int{0}
+	}
+	stride := int(math.Max(1, math.Round(float64(size)*(1-overlap))))
+	var offsets []int
+	for offset := 0; ; offset += stride {
+		if offset+size >= length {
+			return append(offsets, length-size)
+		}
+		offsets = append(offsets, offset)
+	}
+}
+
+// tileImages slices images larger than -tile-size into overlapping tiles in
+// the staging directory, clipping and re-normalizing their labels per tile.
+// Tiles without labels are dropped except for a -tile-background fraction.
+// The tiles of an image share a group so they end up in the same split.
+func (c *Converter) tileImages(pairs []LabelPair) ([]LabelPair, error) {
+	staging, err := c.stagingDirectory()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(staging, "tiles")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	rng := rand.New(rand.NewSource(c.config.Seed))
+	var tiled []LabelPair
+	images, tiles, backgrounds := 0, 0, 0
+	for _, pair := range pairs {
+		width, height, err := readImageSize(pair.ImagePath)
+		if err != nil {
+			fmt.Fprintf(c.out, "Warning: Cannot read size of %s; keeping it untiled\n", filepath.Base(pair.ImagePath))
+			tiled = append(tiled, pair)
+			continue
+		}
+		size := c.config.TileSize
+		if width <= size && height <= size {
+			tiled = append(tiled, pair)
+			continue
+		}
+
+		data, err := os.ReadFile(pair.LabelPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read label %s: %w", pair.LabelPath, err)
+		}
+		img, err := decodeImage(pair.ImagePath)
+		if err != nil {
+			fmt.Fprintf(c.out, "Warning: Cannot decode %s; keeping it untiled: %v\n", filepath.Base(pair.ImagePath), err)
+			tiled = append(tiled, pair)
+			continue
+		}
+		// Labels are drawn on the upright image
+		img = orient(img, exifOrientation(pair.ImagePath))
+		sub, ok := img.(interface {
+			SubImage(image.Rectangle) image.Image
+		})
+		if !ok {
+			return nil, fmt.Errorf("image %s does not support tiling", pair.ImagePath)
+		}
+
+		group := pair.Group
+		if group == "" {
+			group = "tile:" + pair.ImagePath
+		}
+		base := strings.TrimSuffix(filepath.Base(pair.ImagePath), filepath.Ext(pair.ImagePath))
+		lines := strings.Split(string(data), "\n")
+		images++
+
+		for _, y := range tileOffsets(height, size, c.config.TileOverlap) {
+			for _, x := range tileOffsets(width, size, c.config.TileOverlap) {
+				rect := image.Rect(x, y, min(x+size, width), min(y+size, height))
+				labels := c.tileLabels(lines, rect, width, height)
+				if len(labels) == 0 {
+					if rng.Float64() >= c.config.TileBackground {
+						continue
+					}
+					backgrounds++
+				}
+
+				encoded, ext, err := encodeCrop(sub.SubImage(rect.Add(img.Bounds().Min)), filepath.Ext(pair.ImagePath))
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode tile of %s: %w", pair.ImagePath, err)
+				}
+				name := fmt.Sprintf("%s_%d_%d", base, x, y)
+				tile := LabelPair{
+					ImagePath: filepath.Join(dir, name+ext),
+					LabelPath: filepath.Join(dir, name+".txt"),
+					Group:     group,
+				}
+				if err := os.WriteFile(tile.ImagePath, encoded, 0644); err != nil {
+					return nil, fmt.Errorf("failed to write tile %s: %w", tile.ImagePath, err)
+				}
+				content := strings.Join(labels, "\n")
+				if content != "" {
+					content += "\n"
+				}
+				if err := os.WriteFile(tile.LabelPath, []byte(content), 0644); err != nil {
+					return nil, fmt.Errorf("failed to write label %s: %w", tile.LabelPath, err)
+				}
+				tiled = append(tiled, tile)
+				tiles++
+			}
+		}
+	}
+
+	fmt.Fprintf(c.out, "Tiled %d images into %d tiles of %d pixels (%d background tiles kept)\n", images, tiles, c.config.TileSize, backgrounds)
+	return tiled, nil
+}
+
+// tileLabels clips the box and polygon labels of an image to a tile given in
+// pixels, normalized to the tile
+func (c *Converter) tileLabels(lines []string, tile image.Rectangle, width, height int) []string {
+	var labels []string
+	tw, th := float64(tile.Dx()), float64(tile.Dy())
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		kind, warning := c.parseLine(parts)
+		if warning != "" {
+			continue
+		}
+
+		// Pixel coordinates relative to the tile
+		coords := make([]float64, len(parts)-1)
+		for i := range coords {
+			coords[i], _ = strconv.ParseFloat(parts[i+1], 64)
+		}
+		if kind == AnnotationBox {
+			x0, y0 := (coords[0]-coords[2]/2)*float64(width), (coords[1]-coords[3]/2)*float64(height)
+			x1, y1 := (coords[0]+coords[2]/2)*float64(width), (coords[1]+coords[3]/2)*float64(height)
+			coords = []float64{x0, y0, x1, y0, x1, y1, x0, y1}
+		} else {
+			for i := range coords {
+				if i%2 == 0 {
+					coords[i] *= float64(width)
+				} else {
+					coords[i] *= float64(height)
+				}
+			}
+		}
+		for i := range coords {
+			if i%2 == 0 {
+				coords[i] -= float64(tile.Min.X)
+			} else {
+				coords[i] -= float64(tile.Min.Y)
+			}
+		}
+
+		area := polygonArea(coords)
+		clipped := clipPolygon(coords, tw, th)
+		if len(clipped) < 6 || area == 0 || polygonArea(clipped)/area < tileMinVisibility {
+			continue
+		}
+
+		for i := range clipped {
+			if i%2 == 0 {
+				clipped[i] /= tw
+			} else {
+				clipped[i] /= th
+			}
+		}
+		if kind == AnnotationBox {
+			box := coordBounds(clipped)
+			clipped = box[:]
+		}
+		fields := []string{parts[0]}
+		for _, v := range clipped {
+			fields = append(fields, formatCoord(v))
+		}
+		labels = append(labels, strings.Join(fields, " "))
+	}
+	return labels
+}
+
+// clipPolygon clips a polygon of flat x, y pairs to the rectangle from 0, 0
+// to width, height (Sutherland-Hodgman)
+func clipPolygon(coords []float64, width, height float64) []float64 {
+	edges := []struct {
+		inside func(x, y float64) bool
+		cross  func(x0, y0, x1, y1 float64) (float64, float64)
+	}{
+		{func(x, y float64) bool { return x >= 0 }, func(x0, y0, x1, y1 float64) (float64, float64) {
+			return 0, y0 + (y1-y0)*(0-x0)/(x1-x0)
+		}},
+		{func(x, y float64) bool { return x <= width }, func(x0, y0, x1, y1 float64) (float64, float64) {
+			return width, y0 + (y1-y0)*(width-x0)/(x1-x0)
+		}},
+		{func(x, y float64) bool { return y >= 0 }, func(x0, y0, x1, y1 float64) (float64, float64) {
+			return x0 + (x1-x0)*(0-y0)/(y1-y0), 0
+		}},
+		{func(x, y float64) bool { return y <= height }, func(x0, y0, x1, y1 float64) (float64, float64) {
+			return x0 + (x1-x0)*(height-y0)/(y1-y0), height
+		}},
+	}
+
+	points := coords
+	for _, edge := range edges {
+		var clipped []float64
+		n := len(points) / 2
+		for i := 0; i < n; i++ {
+			x0, y0 := points[2*((i+n-1)%n)], points[2*((i+n-1)%n)+1]
+			x1, y1 := points[2*i], points[2*i+1]
+			in0, in1 := edge.inside(x0, y0), edge.inside(x1, y1)
+			if in1 {
+				if !in0 {
+					x, y := edge.cross(x0, y0, x1, y1)
+					clipped = append(clipped, x, y)
+				}
+				clipped = append(clipped, x1, y1)
+			} else if in0 {
+				x, y := edge.cross(x0, y0, x1, y1)
+				clipped = append(clipped, x, y)
+			}
+		}
+		points = clipped
+		if len(points) == 0 {
+			break
+		}
+	}
+	return points
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTileOffsets(t *testing.T) {
+	tests := []struct {
+		length, size int
+		overlap      float64
+		want         []int
+	}{
+		{500, 640, 0.2, []int{0}},
+		{1280, 640, 0, []int{0, 640}},
+		{1000, 640, 0.2, []int{0, 360}},
+		{1600, 640, 0.2, []int{0, 512, 960}},
+	}
+	for _, tt := range tests {
+		if got := tileOffsets(tt.length, tt.size, tt.overlap); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tileOffsets(%d, %d, %g) = %v, expected %v", tt.length, tt.size, tt.overlap, got, tt.want)
+		}
+	}
+}
+
+func TestClipPolygon(t *testing.T) {
+	// A square half outside the left edge
+	clipped := clipPolygon([]float64{-10, 0, 10, 0, 10, 10, -10, 10}, 100, 100)
+	if got := polygonArea(clipped); got != 100 {
+		t.Errorf("Expected an area of 100 after clipping, got %v (%v)", got, clipped)
+	}
+	if clipped := clipPolygon([]float64{-20, 0, -10, 0, -10, 10}, 100, 100); len(clipped) != 0 {
+		t.Errorf("Expected a polygon outside the tile to vanish, got %v", clipped)
+	}
+}
+
+func TestTileImages(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "aerial.png")
+	writeTestImage(t, imagePath, 200, 100)
+	labelPath := filepath.Join(dir, "aerial.txt")
+	// A box in the first tile, one straddling both tiles and a sliver that
+	// barely reaches into the second tile
+	content := "0 0.1 0.5 0.1 0.2\n1 0.5 0.5 0.2 0.2\n2 0.41 0.2 0.2 0.1\n"
+	if err := os.WriteFile(labelPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	smallPath := filepath.Join(dir, "small.png")
+	writeTestImage(t, smallPath, 80, 80)
+
+	converter := NewConverter(Config{TileSize: 100, TileOverlap: 0})
+	converter.SetOutput(io.Discard)
+	defer converter.removeStaging()
+	pairs, err := converter.tileImages([]LabelPair{
+		{ImagePath: imagePath, LabelPath: labelPath},
+		{ImagePath: smallPath, LabelPath: filepath.Join(dir, "small.txt")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 3 || pairs[2].ImagePath != smallPath {
+		t.Fatalf("Expected two tiles and the untiled small image, got %v", pairs)
+	}
+	if filepath.Base(pairs[1].ImagePath) != "aerial_100_0.png" || pairs[0].Group != pairs[1].Group || pairs[0].Group == "" {
+		t.Errorf("Unexpected tiles %+v", pairs[:2])
+	}
+	if width, height, err := readImageSize(pairs[0].ImagePath); err != nil || width != 100 || height != 100 {
+		t.Errorf("Expected 100x100 tiles, got %dx%d, %v", width, height, err)
+	}
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	want := "0 0.200000 0.500000 0.200000 0.200000\n" +
+		"1 0.900000 0.500000 0.200000 0.200000\n" +
+		"2 0.810000 0.200000 0.380000 0.100000\n"
+	if got := read(pairs[0].LabelPath); got != want {
+		t.Errorf("First tile: expected %q, got %q", want, got)
+	}
+	// The sliver keeps 5% of its area in the second tile and is dropped
+	if got, want := read(pairs[1].LabelPath), "1 0.100000 0.500000 0.200000 0.200000\n"; got != want {
+		t.Errorf("Second tile: expected %q, got %q", want, got)
+	}
+}
+
+func TestTileBackgrounds(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "field.png")
+	writeTestImage(t, imagePath, 400, 100)
+	labelPath := filepath.Join(dir, "field.txt")
+	if err := os.WriteFile(labelPath, []byte("0 0.1 0.5 0.1 0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		background float64
+		tiles      int
+	}{{0, 1}, {1, 4}} {
+		converter := NewConverter(Config{TileSize: 100, TileBackground: tt.background})
+		var out strings.Builder
+		converter.SetOutput(&out)
+		pairs, err := converter.tileImages([]LabelPair{{ImagePath: imagePath, LabelPath: labelPath}})
+		converter.removeStaging()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pairs) != tt.tiles {
+			t.Errorf("Background %g: expected %d tiles, got %d: %s", tt.background, tt.tiles, len(pairs), out.String())
+		}
+	}
+}
+
+func TestTilingRejectsOBB(t *testing.T) {
+	converter := NewConverter(Config{TileSize: 640, OBB: true})
+	if err := converter.validateTiling(); err == nil {
+		t.Error("Expected tiling OBB labels to be rejected")
+	}
+}
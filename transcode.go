@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// trainableExtensions are the image formats YOLO trainers read directly
+var trainableExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".bmp":  true,
+	".tif":  true,
+	".tiff": true,
+	".webp": true,
+}
+
+// transcodeImages converts images in formats trainers can't read, such as
+// HEIC and AVIF, to JPEG in the staging directory. Without -transcode it only
+// warns about them.
+func (c *Converter) transcodeImages(pairs []LabelPair) ([]LabelPair, error) {
+	formats := make(map[string]int)
+	for _, pair := range pairs {
+		if ext := strings.ToLower(filepath.Ext(pair.ImagePath)); !trainableExtensions[ext] {
+			formats[ext]++
+		}
+	}
+	if len(formats) == 0 {
+		return pairs, nil
+	}
+
+	var names []string
+	total := 0
+	for ext, n := range formats {
+		names = append(names, ext)
+		total += n
+	}
+	sort.Strings(names)
+	if !c.config.Transcode {
+		fmt.Fprintf(c.out, "Warning: %d images are in formats YOLO training can't read (%s); use -transcode to convert them to JPEG\n",
+			total, strings.Join(names, ", "))
+		return pairs, nil
+	}
+
+	staging, err := c.stagingDirectory()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(staging, "transcoded")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	result := make([]LabelPair, len(pairs))
+	for i, pair := range pairs {
+		result[i] = pair
+		ext := filepath.Ext(pair.ImagePath)
+		if trainableExtensions[strings.ToLower(ext)] {
+			continue
+		}
+		jpegPath := filepath.Join(dir, strings.TrimSuffix(filepath.Base(pair.ImagePath), ext)+".jpg")
+		if err := transcodeToJPEG(pair.ImagePath, jpegPath); err != nil {
+			return nil, err
+		}
+		result[i].ImagePath = jpegPath
+	}
+	fmt.Fprintf(c.out, "Transcoded %d images (%s) to JPEG\n", total, strings.Join(names, ", "))
+	return result, nil
+}
+
+// transcodeToJPEG writes an image as JPEG, decoding it in Go when the format
+// is supported and with ffmpeg otherwise
+func transcodeToJPEG(src, dst string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	img, _, decodeErr := image.Decode(file)
+	file.Close()
+
+	if decodeErr != nil {
+		ffmpeg, err := exec.LookPath("ffmpeg")
+		if err != nil {
+			return fmt.Errorf("ffmpeg is required to transcode %s: %w", filepath.Base(src), err)
+		}
+		cmd := exec.Command(ffmpeg, "-v", "error", "-y", "-i", src, "-frames:v", "1", "-q:v", "2", dst)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("ffmpeg failed on %s: %w: %s", src, err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if err := jpeg.Encode(out, img, &jpeg.Options{Quality: 95}); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to encode %s: %w", dst, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranscodeImages(t *testing.T) {
+	dir := t.TempDir()
+	gifPath := filepath.Join(dir, "anim.gif")
+	file, err := os.Create(gifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(file, image.NewPaletted(image.Rect(0, 0, 40, 30), palette.Plan9), nil); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	jpegPath := filepath.Join(dir, "photo.jpg")
+	writeTestImage(t, jpegPath, 10, 10)
+
+	pairs := []LabelPair{
+		{ImagePath: gifPath, LabelPath: filepath.Join(dir, "anim.txt")},
+		{ImagePath: jpegPath, LabelPath: filepath.Join(dir, "photo.txt")},
+	}
+
+	// Without -transcode the formats are only reported
+	converter := NewConverter(Config{})
+	var out strings.Builder
+	converter.SetOutput(&out)
+	same, err := converter.transcodeImages(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same[0].ImagePath != gifPath || !strings.Contains(out.String(), "1 images are in formats YOLO training can't read (.gif)") {
+		t.Errorf("Expected a warning only, got %v: %s", same, out.String())
+	}
+
+	converter = NewConverter(Config{Transcode: true})
+	converter.SetOutput(&out)
+	defer converter.removeStaging()
+	transcoded, err := converter.transcodeImages(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(transcoded[0].ImagePath) != "anim.jpg" || transcoded[0].LabelPath != pairs[0].LabelPath {
+		t.Errorf("Expected anim.gif to become anim.jpg with the same label, got %+v", transcoded[0])
+	}
+	if transcoded[1] != pairs[1] {
+		t.Errorf("Expected the JPEG to be left alone, got %+v", transcoded[1])
+	}
+	if width, height, err := readImageSize(transcoded[0].ImagePath); err != nil || width != 40 || height != 30 {
+		t.Errorf("Expected a 40x30 JPEG, got %dx%d, %v", width, height, err)
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VIAImage is an image of a VGG Image Annotator project with its regions
+type VIAImage struct {
+	Filename string
+	Regions  []VIARegion
+}
+
+// UnmarshalJSON reads the regions of an image, a list since VIA 2 and an
+// object keyed by region index in VIA 1
+func (img *VIAImage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Filename string          `json:"filename"`
+		Regions  json.RawMessage `json:"regions"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	img.Filename = raw.Filename
+	if len(raw.Regions) == 0 || raw.Regions[0] == '[' {
+		return json.Unmarshal(orNull(raw.Regions), &img.Regions)
+	}
+
+	var indexed map[string]VIARegion
+	if err := json.Unmarshal(raw.Regions, &indexed); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(indexed))
+	for key := range indexed {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(keys[i])
+		b, _ := strconv.Atoi(keys[j])
+		return a < b
+	})
+	for _, key := range keys {
+		img.Regions = append(img.Regions, indexed[key])
+	}
+	return nil
+}
+
+// orNull returns data, or JSON null when it is empty
+func orNull(data []byte) []byte {
+	if len(data) == 0 {
+		return []byte("null")
+	}
+	return data
+}
+
+// VIARegion is a region: its shape in pixels and its attributes, one of
+// which names the class
+type VIARegion struct {
+	Shape      VIAShape       `json:"shape_attributes"`
+	Attributes map[string]any `json:"region_attributes"`
+}
+
+// VIAShape is the geometry of a region; Name tells which fields are set
+type VIAShape struct {
+	Name       string    `json:"name"`
+	X          float64   `json:"x"`
+	Y          float64   `json:"y"`
+	Width      float64   `json:"width"`
+	Height     float64   `json:"height"`
+	AllPointsX []float64 `json:"all_points_x"`
+	AllPointsY []float64 `json:"all_points_y"`
+	CX         float64   `json:"cx"`
+	CY         float64   `json:"cy"`
+	R          float64   `json:"r"`
+	RX         float64   `json:"rx"`
+	RY         float64   `json:"ry"`
+	Theta      float64   `json:"theta"`
+}
+
+// viaProject is a VIA export: its images sorted by file name, plus the
+// options of each region attribute in the order the project declares them
+type viaProject struct {
+	images  []VIAImage
+	options map[string][]string
+}
+
+// loadVIAProject reads a VIA project or annotation export, as JSON or CSV
+func loadVIAProject(path string) (*viaProject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VIA export: %w", err)
+	}
+	var project *viaProject
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		project, err = parseVIACSV(data)
+	} else {
+		project, err = parseVIAJSON(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse VIA export %s: %w", path, err)
+	}
+	sort.SliceStable(project.images, func(i, j int) bool { return project.images[i].Filename < project.images[j].Filename })
+	return project, nil
+}
+
+// parseVIAJSON reads a project file, whose images are under
+// _via_img_metadata, or an annotation export of just the images
+func parseVIAJSON(data []byte) (*viaProject, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, err
+	}
+
+	project := &viaProject{options: make(map[string][]string)}
+	metadata := data
+	if raw, ok := top["_via_img_metadata"]; ok {
+		metadata = raw
+		var attributes struct {
+			Region map[string]struct {
+				Options json.RawMessage `json:"options"`
+			} `json:"region"`
+		}
+		if raw, ok := top["_via_attributes"]; ok {
+			if err := json.Unmarshal(raw, &attributes); err != nil {
+				return nil, err
+			}
+		}
+		for name, attribute := range attributes.Region {
+			options, err := orderedKeys(attribute.Options)
+			if err != nil {
+				return nil, err
+			}
+			project.options[name] = options
+		}
+	}
+
+	var images map[string]VIAImage
+	if err := json.Unmarshal(metadata, &images); err != nil {
+		return nil, err
+	}
+	for _, image := range images {
+		project.images = append(project.images, image)
+	}
+	return project, nil
+}
+
+// orderedKeys returns the keys of a JSON object in document order
+func orderedKeys(data json.RawMessage) ([]string, error) {
+	if len(data) == 0 || data[0] != '{' {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.Token()
+	var keys []string
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key.(string))
+	}
+	return keys, nil
+}
+
+// viaCSVColumns are the columns read from a VIA CSV export, where the shape
+// and attributes of each region are JSON strings
+var viaCSVColumns = []string{"filename", "region_shape_attributes", "region_attributes"}
+
+// parseVIACSV reads a CSV export, one row per region or per image without
+// regions
+func parseVIACSV(data []byte) (*viaProject, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimPrefix(strings.TrimSpace(name), "#")] = i
+	}
+	index := make([]int, len(viaCSVColumns))
+	for i, name := range viaCSVColumns {
+		col, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("no %s column", name)
+		}
+		index[i] = col
+	}
+
+	project := &viaProject{options: make(map[string][]string)}
+	positions := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return project, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := record[index[0]]
+		i, ok := positions[name]
+		if !ok {
+			i = len(project.images)
+			positions[name] = i
+			project.images = append(project.images, VIAImage{Filename: name})
+		}
+
+		var region VIARegion
+		if err := json.Unmarshal([]byte(record[index[1]]), &region.Shape); err != nil {
+			return nil, fmt.Errorf("region of %s: %w", name, err)
+		}
+		if region.Shape.Name == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(record[index[2]]), &region.Attributes); err != nil {
+			return nil, fmt.Errorf("region of %s: %w", name, err)
+		}
+		project.images[i].Regions = append(project.images[i].Regions, region)
+	}
+}
+
+// findVIAFile returns the configured export or the only JSON or CSV file in
+// the source directory
+func (c *Converter) findVIAFile() (string, error) {
+	if c.config.TasksFile != "" {
+		return c.config.TasksFile, nil
+	}
+	var candidates []string
+	for _, pattern := range []string{"*.json", "*.csv"} {
+		matches, err := filepath.Glob(filepath.Join(c.config.SourceDir, pattern))
+		if err != nil {
+			return "", err
+		}
+		candidates = append(candidates, matches...)
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no VIA export found in %s (use -tasks)", c.config.SourceDir)
+	case 1:
+		return candidates[0], nil
+	}
+	return "", fmt.Errorf("multiple JSON and CSV files found in %s, choose one with -tasks", c.config.SourceDir)
+}
+
+// classAttribute returns the region attribute that names the class:
+// -via-attribute, or the only attribute the regions use
+func (c *Converter) classAttribute(project *viaProject) (string, error) {
+	if c.config.VIAAttribute != "" {
+		return c.config.VIAAttribute, nil
+	}
+	names := make(map[string]bool)
+	for name := range project.options {
+		names[name] = true
+	}
+	for _, image := range project.images {
+		for _, region := range image.Regions {
+			for name := range region.Attributes {
+				names[name] = true
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	switch len(sorted) {
+	case 0:
+		return "", fmt.Errorf("the VIA regions have no attributes to take classes from")
+	case 1:
+		return sorted[0], nil
+	}
+	return "", fmt.Errorf("the VIA regions have several attributes (%s); choose the class one with -via-attribute", strings.Join(sorted, ", "))
+}
+
+// viaClassName returns the class a region attribute names: the text of a
+// text, dropdown or radio attribute, or the first checked checkbox option
+func viaClassName(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key, checked := range v {
+			if checked == true {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		if len(keys) > 0 {
+			return keys[0]
+		}
+	}
+	return ""
+}
+
+// loadVIA converts a VGG Image Annotator export into YOLO label files in a
+// staging directory and pairs them with the images in the source's images/
+// directory. VIA stores pixels without the image size, so each image's size
+// is read from its header. Regions become the Label Studio results they
+// correspond to and convert like ls-json ones.
+func (c *Converter) loadVIA() ([]string, []LabelPair, error) {
+	if c.skeleton != nil {
+		return nil, nil, fmt.Errorf("-skeleton requires -input-format %s", InputLSJSON)
+	}
+	path, err := c.findVIAFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	project, err := loadVIAProject(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprintf(c.out, "Loaded %d images from %s\n", len(project.images), path)
+	attribute, err := c.classAttribute(project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The attribute's options number the classes, unless classes.txt pins
+	// the IDs; other names are added as they appear
+	classes := project.options[attribute]
+	if _, err := os.Stat(filepath.Join(c.config.SourceDir, "classes.txt")); err == nil {
+		if classes, err = c.LoadClasses(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stagingDir, err := c.stagingDirectory()
+	if err != nil {
+		return nil, nil, err
+	}
+	builder := newLabelBuilder(classes, c.config.MaskTolerance, nil)
+	builder.obb = c.config.OBB
+	if builder.shapeModes, err = parseShapeModes(c.config.ShapeModes); err != nil {
+		return nil, nil, err
+	}
+
+	var pairs []LabelPair
+	unclassified := 0
+	for i, image := range project.images {
+		imageName := imageFileName(image.Filename)
+		imagePath := filepath.Join(c.config.SourceDir, "images", imageName)
+		if _, err := os.Stat(imagePath); err != nil {
+			fmt.Fprintf(c.out, "Warning: Image %s not found\n", imageName)
+			continue
+		}
+
+		var results []LSResult
+		if len(image.Regions) > 0 {
+			width, height, err := readImageSize(imagePath)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read the size of %s: %w", imagePath, err)
+			}
+			for _, region := range image.Regions {
+				name := viaClassName(region.Attributes[attribute])
+				if name == "" {
+					unclassified++
+					continue
+				}
+				results = append(results, viaResult(region.Shape, name, width, height))
+			}
+		}
+		lines := builder.taskLines(c, i, results)
+
+		labelPath := filepath.Join(stagingDir, strings.TrimSuffix(imageName, filepath.Ext(imageName))+".txt")
+		if err := os.WriteFile(labelPath, []byte(strings.Join(lines, "")), 0644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write label %s: %w", labelPath, err)
+		}
+		pairs = append(pairs, LabelPair{ImagePath: imagePath, LabelPath: labelPath})
+	}
+
+	if unclassified > 0 {
+		fmt.Fprintf(c.out, "Warning: Skipped %d regions without a %s attribute\n", unclassified, attribute)
+		c.skipped += unclassified
+	}
+	for shape, count := range builder.unsupported {
+		fmt.Fprintf(c.out, "Warning: Skipped %d unsupported %s regions\n", count, strings.TrimSuffix(shape, "labels"))
+	}
+	fmt.Fprintf(c.out, "Found %d classes: %v\n", len(builder.classes), builder.classes)
+	fmt.Fprintf(c.out, "Found %d image-label pairs\n", len(pairs))
+	return builder.classes, pairs, nil
+}
+
+// viaResult converts a region shape in pixels into a Label Studio result in
+// percentages of the image size. Circles are ellipses with equal radii.
+func viaResult(shape VIAShape, name string, width, height int) LSResult {
+	w, h := float64(width), float64(height)
+	result := LSResult{OriginalWidth: width, OriginalHeight: height}
+	value := &result.Value
+	labels := []string{name}
+
+	switch shape.Name {
+	case "rect":
+		result.Type = "rectanglelabels"
+		value.RectangleLabels = labels
+		value.X, value.Y = shape.X/w*100, shape.Y/h*100
+		value.Width, value.Height = shape.Width/w*100, shape.Height/h*100
+	case "polygon", "polyline":
+		result.Type = shape.Name + "labels"
+		if shape.Name == "polygon" {
+			value.PolygonLabels = labels
+		} else {
+			value.PolylineLabels = labels
+		}
+		for i := range min(len(shape.AllPointsX), len(shape.AllPointsY)) {
+			value.Points = append(value.Points, []float64{shape.AllPointsX[i] / w * 100, shape.AllPointsY[i] / h * 100})
+		}
+	case "ellipse", "circle":
+		result.Type = "ellipselabels"
+		value.EllipseLabels = labels
+		rx, ry := shape.RX, shape.RY
+		if shape.Name == "circle" {
+			rx, ry = shape.R, shape.R
+		}
+		value.X, value.Y = shape.CX/w*100, shape.CY/h*100
+		value.RadiusX, value.RadiusY = rx/w*100, ry/h*100
+		value.Rotation = shape.Theta * 180 / math.Pi
+	default:
+		// Points are counted as unsupported
+		result.Type = shape.Name
+		value.Labels = labels
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// viaProjectFile is a VIA 2 project with every shape, a region without a
+// class and a checkbox attribute
+const viaProjectFile = `{
+  "_via_settings": {"project": {"name": "pets"}},
+  "_via_img_metadata": {
+    "b.png1234": {
+      "filename": "b.png", "size": 1234, "file_attributes": {},
+      "regions": [
+        {"shape_attributes": {"name": "circle", "cx": 50, "cy": 50, "r": 10}, "region_attributes": {"species": "cat"}},
+        {"shape_attributes": {"name": "point", "cx": 5, "cy": 5}, "region_attributes": {"species": "cat"}}
+      ]
+    },
+    "a.jpg100": {
+      "filename": "a.jpg", "size": 100, "file_attributes": {},
+      "regions": [
+        {"shape_attributes": {"name": "rect", "x": 20, "y": 10, "width": 40, "height": 20}, "region_attributes": {"species": "cat"}},
+        {"shape_attributes": {"name": "polygon", "all_points_x": [20, 100, 60], "all_points_y": [10, 10, 60]}, "region_attributes": {"species": {"dog": true, "cat": false}}},
+        {"shape_attributes": {"name": "rect", "x": 0, "y": 0, "width": 10, "height": 10}, "region_attributes": {"species": ""}}
+      ]
+    },
+    "c.jpg1": {"filename": "http://example.com/c.jpg", "size": 1, "file_attributes": {}, "regions": []}
+  },
+  "_via_attributes": {
+    "region": {"species": {"type": "dropdown", "options": {"dog": "", "cat": "", "bird": ""}}},
+    "file": {}
+  }
+}`
+
+func TestVIAProjectConversion(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sourceDir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestImage(t, filepath.Join(sourceDir, "images", "a.jpg"), 200, 100)
+	writeTestImage(t, filepath.Join(sourceDir, "images", "b.png"), 100, 100)
+	if err := os.WriteFile(filepath.Join(sourceDir, "via_project.json"), []byte(viaProjectFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 1, Seed: 42, InputFormat: InputVIA})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	// Classes follow the attribute's options; circles become ellipses,
+	// converted to polygons by default
+	a, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != "1 0.200000 0.200000 0.200000 0.200000\n0 0.100000 0.100000 0.500000 0.100000 0.300000 0.600000\n" {
+		t.Errorf("Unexpected label a.txt %q", a)
+	}
+	b, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", "b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], "1 0.600000 0.500000 ") {
+		t.Errorf("Expected the circle as one polygon of class 1, got %q", b)
+	}
+
+	log := out.String()
+	for _, want := range []string{"Found 3 classes: [dog cat bird]", "Skipped 1 regions without a species attribute", "Skipped 1 unsupported point regions", "Image c.jpg not found"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, log)
+		}
+	}
+}
+
+func TestVIACSVAndVersion1Exports(t *testing.T) {
+	csvExport := "#filename,file_size,file_attributes,region_count,region_id,region_shape_attributes,region_attributes\n" +
+		`a.jpg,100,"{}",1,0,"{""name"":""rect"",""x"":20,""y"":10,""width"":40,""height"":20}","{""label"":""car""}"` + "\n" +
+		`b.jpg,100,"{}",0,0,"{}","{}"` + "\n"
+	version1 := `{"a.jpg100": {"filename": "a.jpg", "size": 100, "regions": {
+		"1": {"shape_attributes": {"name": "rect", "x": 0, "y": 0, "width": 20, "height": 20}, "region_attributes": {"label": "bus"}},
+		"0": {"shape_attributes": {"name": "rect", "x": 20, "y": 10, "width": 40, "height": 20}, "region_attributes": {"label": "car"}}
+	}}}`
+
+	for name, export := range map[string]string{"export.csv": csvExport, "export.json": version1} {
+		sourceDir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(sourceDir, "images"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeTestImage(t, filepath.Join(sourceDir, "images", "a.jpg"), 200, 100)
+		writeTestImage(t, filepath.Join(sourceDir, "images", "b.jpg"), 200, 100)
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(export), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		converter := NewConverter(Config{SourceDir: sourceDir, InputFormat: InputVIA})
+		converter.SetOutput(&strings.Builder{})
+		classes, pairs, err := converter.loadSource()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		label, err := os.ReadFile(pairs[0].LabelPath)
+		converter.removeStaging()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(label), "0 0.200000 0.200000 0.200000 0.200000\n") || classes[0] != "car" {
+			t.Errorf("%s: unexpected classes %v and first label %q", name, classes, label)
+		}
+	}
+}
+
+func TestVIAAmbiguousClassAttribute(t *testing.T) {
+	project := &viaProject{images: []VIAImage{{Filename: "a.jpg", Regions: []VIARegion{
+		{Attributes: map[string]any{"species": "cat", "color": "black"}},
+	}}}}
+	converter := NewConverter(Config{})
+	if _, err := converter.classAttribute(project); err == nil || !strings.Contains(err.Error(), "color, species") {
+		t.Errorf("Expected an error naming both attributes, got %v", err)
+	}
+	converter = NewConverter(Config{VIAAttribute: "color"})
+	if attribute, err := converter.classAttribute(project); err != nil || attribute != "color" {
+		t.Errorf("Expected -via-attribute to choose color, got %q, %v", attribute, err)
+	}
+}
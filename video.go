@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LSKeyframe is one keyframe of a video object track
+type LSKeyframe struct {
+	Frame    int     `json:"frame"`
+	Enabled  bool    `json:"enabled"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Width    float64 `json:"width"`
+	Height   float64 `json:"height"`
+	Rotation float64 `json:"rotation"`
+}
+
+// taskVideo returns the video reference of a task, if it is a video task
+func taskVideo(task LSTask) string {
+	video, _ := task.Data["video"].(string)
+	return video
+}
+
+// trackBox returns the rectangle of a video track at a frame, following Label
+// Studio's rules: boxes are interpolated linearly between keyframes, and a
+// keyframe with enabled false ends the track until the next keyframe
+func trackBox(sequence []LSKeyframe, frame int) (LSKeyframe, bool) {
+	i := sort.Search(len(sequence), func(i int) bool {
+		return sequence[i].Frame > frame
+	}) - 1
+	if i < 0 {
+		return LSKeyframe{}, false
+	}
+
+	k := sequence[i]
+	if k.Frame == frame {
+		return k, true
+	}
+	if !k.Enabled {
+		return LSKeyframe{}, false
+	}
+	if i+1 == len(sequence) {
+		// The last keyframe holds until the end of the video
+		return k, true
+	}
+
+	next := sequence[i+1]
+	t := float64(frame-k.Frame) / float64(next.Frame-k.Frame)
+	lerp := func(a, b float64) float64 { return a + (b-a)*t }
+	return LSKeyframe{
+		Frame:    frame,
+		Enabled:  true,
+		X:        lerp(k.X, next.X),
+		Y:        lerp(k.Y, next.Y),
+		Width:    lerp(k.Width, next.Width),
+		Height:   lerp(k.Height, next.Height),
+		Rotation: lerp(k.Rotation, next.Rotation),
+	}, true
+}
+
+// videoFile finds a task's video in the source's videos/ directory or root
+func (c *Converter) videoFile(ref string) (string, bool) {
+	name := imageFileName(ref)
+	for _, dir := range []string{filepath.Join(c.config.SourceDir, "videos"), c.config.SourceDir} {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// videoFrames returns the frame images of a video, where index 0 is Label
+// Studio's frame 1. Frames come from <frames-dir>/<video name>/ when
+// -frames-dir is set and are otherwise extracted with ffmpeg.
+func (c *Converter) videoFrames(ref, stem string) ([]string, error) {
+	dir := filepath.Join(c.config.FramesDir, stem)
+	if c.config.FramesDir == "" {
+		videoPath, ok := c.videoFile(ref)
+		if !ok {
+			return nil, fmt.Errorf("video %s not found (use -frames-dir for pre-extracted frames)", imageFileName(ref))
+		}
+
+		staging, err := c.stagingDirectory()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(staging, "frames", stem)
+		if err := extractFrames(videoPath, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read frames: %w", err)
+	}
+
+	var frames []string
+	for _, entry := range entries {
+		if !entry.IsDir() && c.isImageFile(entry.Name()) {
+			frames = append(frames, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(frames)
+	return frames, nil
+}
+
+// extractFrames writes every frame of a video to dir as numbered JPEGs
+func extractFrames(videoPath, dir string) error {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("ffmpeg is required to extract video frames (or use -frames-dir): %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create frames directory: %w", err)
+	}
+
+	cmd := exec.Command(ffmpeg, "-v", "error", "-i", videoPath,
+		"-fps_mode", "passthrough", "-q:v", "2", "-start_number", "1",
+		filepath.Join(dir, "frame_%06d.jpg"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed on %s: %w: %s", videoPath, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// stageFrame places a frame image in the staging directory under a name that
+// is unique across videos, linking it when possible
+func stageFrame(framePath, stagedPath string) error {
+	if err := os.Link(framePath, stagedPath); err == nil {
+		return nil
+	}
+	return copyFile(framePath, stagedPath)
+}
+
+// videoPairs converts the video rectangle tracks of a task into one labeled
+// frame per annotated frame, every FrameStep frames. The frames share a group
+// so a video never spans both splits.
+func (c *Converter) videoPairs(b *labelBuilder, task LSTask, results []LSResult, stagingDir string) ([]LabelPair, error) {
+	ref := taskVideo(task)
+	stem := strings.TrimSuffix(imageFileName(ref), filepath.Ext(imageFileName(ref)))
+
+	type track struct {
+		classID  int
+		sequence []LSKeyframe
+	}
+	var tracks []track
+	for _, result := range results {
+		names := result.Value.LabelNames()
+		if result.Type != "videorectangle" || len(names) == 0 {
+			if result.Type != "videorectangle" {
+				b.unsupported[result.Type]++
+			}
+			continue
+		}
+		sequence := append([]LSKeyframe{}, result.Value.Sequence...)
+		sort.SliceStable(sequence, func(i, j int) bool {
+			return sequence[i].Frame < sequence[j].Frame
+		})
+		tracks = append(tracks, track{b.classID(names[0]), sequence})
+	}
+	if len(tracks) == 0 {
+		return nil, nil
+	}
+
+	frames, err := c.videoFrames(ref, stem)
+	if err != nil {
+		return nil, err
+	}
+
+	step := c.config.FrameStep
+	if step < 1 {
+		step = 1
+	}
+
+	var pairs []LabelPair
+	for frame := 1; frame <= len(frames); frame += step {
+		var lines []string
+		for _, tr := range tracks {
+			k, ok := trackBox(tr.sequence, frame)
+			if !ok {
+				continue
+			}
+			rect := LSResult{Value: LSValue{X: k.X, Y: k.Y, Width: k.Width, Height: k.Height, Rotation: k.Rotation}}
+			lines = append(lines, boxLine(tr.classID, rectangleBounds(rect)))
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		name := fmt.Sprintf("%s_%06d", stem, frame)
+		imagePath := filepath.Join(stagingDir, name+filepath.Ext(frames[frame-1]))
+		if err := stageFrame(frames[frame-1], imagePath); err != nil {
+			return nil, fmt.Errorf("failed to stage frame %d of %s: %w", frame, stem, err)
+		}
+		labelPath := filepath.Join(stagingDir, name+".txt")
+		if err := os.WriteFile(labelPath, []byte(strings.Join(lines, "")), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write label %s: %w", labelPath, err)
+		}
+		pairs = append(pairs, LabelPair{ImagePath: imagePath, LabelPath: labelPath, Group: "video:" + stem})
+	}
+
+	fmt.Fprintf(c.out, "Extracted %d labeled frames from %s\n", len(pairs), stem)
+	return pairs, nil
+}
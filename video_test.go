@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrackBox(t *testing.T) {
+	sequence := []LSKeyframe{
+		{Frame: 2, Enabled: true, X: 10, Y: 10, Width: 20, Height: 20},
+		{Frame: 4, Enabled: false, X: 30, Y: 10, Width: 20, Height: 20},
+		{Frame: 7, Enabled: true, X: 50, Y: 50, Width: 10, Height: 10},
+	}
+
+	tests := []struct {
+		frame int
+		ok    bool
+		x     float64
+	}{
+		{1, false, 0},
+		{2, true, 10},
+		{3, true, 20},
+		{4, true, 30},
+		{5, false, 0},
+		{7, true, 50},
+		{9, true, 50},
+	}
+	for _, tt := range tests {
+		k, ok := trackBox(sequence, tt.frame)
+		if ok != tt.ok || (ok && k.X != tt.x) {
+			t.Errorf("trackBox at frame %d = (%v, %v), expected x %v and %v", tt.frame, k.X, ok, tt.x, tt.ok)
+		}
+	}
+}
+
+func TestVideoConversionWithFramesDir(t *testing.T) {
+	sourceDir := t.TempDir()
+	framesDir := t.TempDir()
+
+	var tasks []LSTask
+	for i, video := range []string{"clip_a.mp4", "clip_b.mp4"} {
+		stem := strings.TrimSuffix(video, ".mp4")
+		if err := os.MkdirAll(filepath.Join(framesDir, stem), 0755); err != nil {
+			t.Fatalf("Failed to create frames directory: %v", err)
+		}
+		for frame := 1; frame <= 6; frame++ {
+			name := filepath.Join(framesDir, stem, fmt.Sprintf("%04d.jpg", frame))
+			if err := os.WriteFile(name, []byte("fake frame"), 0644); err != nil {
+				t.Fatalf("Failed to write frame: %v", err)
+			}
+		}
+
+		tasks = append(tasks, LSTask{
+			ID:   i + 1,
+			Data: map[string]interface{}{"video": "/data/upload/1/" + video},
+			Annotations: []LSAnnotation{{Result: []LSResult{{
+				Type: "videorectangle",
+				Value: LSValue{Labels: []string{"car"}, Sequence: []LSKeyframe{
+					{Frame: 1, Enabled: true, X: 0, Y: 0, Width: 20, Height: 20},
+					{Frame: 5, Enabled: false, X: 40, Y: 0, Width: 20, Height: 20},
+				}},
+			}}}},
+		})
+	}
+	data, _ := json.Marshal(tasks)
+	if err := os.WriteFile(filepath.Join(sourceDir, "export.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.5, Seed: 42, InputFormat: InputLSJSON, FramesDir: framesDir, FrameStep: 2})
+	converter.SetOutput(&out)
+	if err := converter.Convert(); err != nil {
+		t.Fatalf("Conversion failed: %v\n%s", err, out.String())
+	}
+
+	// Frames 1, 3 and 5 are kept; the track ends after frame 5
+	label, err := os.ReadFile(filepath.Join(outputDir, "labels", "train", "clip_a_000003.txt"))
+	if os.IsNotExist(err) {
+		label, err = os.ReadFile(filepath.Join(outputDir, "labels", "val", "clip_a_000003.txt"))
+	}
+	if err != nil {
+		t.Fatalf("Missing interpolated frame label: %v", err)
+	}
+	if string(label) != "0 0.300000 0.100000 0.200000 0.200000\n" {
+		t.Errorf("Unexpected interpolated label %q", label)
+	}
+
+	// Each video lands entirely in one split
+	for _, split := range []string{"train", "val"} {
+		entries, _ := os.ReadDir(filepath.Join(outputDir, "images", split))
+		videos := make(map[string]int)
+		for _, entry := range entries {
+			videos[entry.Name()[:6]]++
+		}
+		if len(videos) != 1 || len(entries) != 3 {
+			t.Errorf("Expected the %s split to hold the 3 frames of one video, got %v", split, videos)
+		}
+	}
+}
+
+func TestSplitDatasetKeepsGroupsTogether(t *testing.T) {
+	var pairs []LabelPair
+	for group := 0; group < 4; group++ {
+		for i := 0; i < 5; i++ {
+			pairs = append(pairs, LabelPair{ImagePath: fmt.Sprintf("g%d_%d.jpg", group, i), Group: fmt.Sprint(group)})
+		}
+	}
+
+	converter := NewConverter(Config{TrainSplit: 0.5, Seed: 7})
+	converter.SetOutput(&strings.Builder{})
+	train, val := converter.SplitDataset(pairs)
+
+	if len(train) != 10 || len(val) != 10 {
+		t.Errorf("Expected two groups in each split, got %d training and %d validation", len(train), len(val))
+	}
+	seen := make(map[string]bool)
+	for _, pair := range train {
+		seen[pair.Group] = true
+	}
+	for _, pair := range val {
+		if seen[pair.Group] {
+			t.Errorf("Group %s is in both splits", pair.Group)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// parallelWalker reads the directories of a tree on several goroutines, which
+// hides the latency of network file systems where a sequential walk over
+// millions of files takes minutes
+type parallelWalker struct {
+	visit func(path string, entry os.DirEntry) error
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// queue holds directories waiting to be read; pending counts them and
+	// the directories being read
+	queue   []string
+	pending int
+	err     error
+}
+
+// walkParallel calls visit for every file below root, reading directories
+// with the given number of goroutines. visit is called concurrently and in
+// no particular order; the first error stops the walk. Like filepath.Walk,
+// it doesn't follow symbolic links to directories.
+func walkParallel(root string, workers int, visit func(path string, entry os.DirEntry) error) error {
+	w := &parallelWalker{visit: visit, queue: []string{root}, pending: 1}
+	w.cond = sync.NewCond(&w.mu)
+
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+	return w.err
+}
+
+func (w *parallelWalker) work() {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && w.pending > 0 && w.err == nil {
+			w.cond.Wait()
+		}
+		if w.err != nil || len(w.queue) == 0 {
+			w.mu.Unlock()
+			return
+		}
+		dir := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+		w.mu.Unlock()
+
+		err := w.readDir(dir)
+
+		w.mu.Lock()
+		w.pending--
+		if err != nil && w.err == nil {
+			w.err = err
+		}
+		if w.pending == 0 || w.err != nil {
+			w.cond.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// readDir visits the files of one directory and queues its subdirectories
+func (w *parallelWalker) readDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			w.mu.Lock()
+			w.queue = append(w.queue, path)
+			w.pending++
+			w.cond.Signal()
+			w.mu.Unlock()
+			continue
+		}
+		if err := w.visit(path, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkLess orders paths the way filepath.Walk visits them, by path element:
+// the separator sorts before every other byte, so a directory's files come
+// before those of a sibling whose name extends it
+func walkLess(a, b string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ca, cb := a[i], b[i]
+		switch {
+		case ca == cb:
+			continue
+		case ca == filepath.Separator:
+			return true
+		case cb == filepath.Separator:
+			return false
+		}
+		return ca < cb
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// writeWalkTree writes files in nested directories, including sibling names
+// that extend each other
+func writeWalkTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range []string{"a.jpg", "b/c.jpg", "b/d/e.jpg", "b-x/f.jpg", "b.jpg", "g/h/i/j.jpg", "g/k.jpg"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestWalkParallel(t *testing.T) {
+	root := writeWalkTree(t)
+
+	var want []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			want = append(want, path)
+		}
+		return err
+	})
+
+	var mu sync.Mutex
+	var got []string
+	err := walkParallel(root, 4, func(path string, entry os.DirEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(got, func(i, j int) bool { return walkLess(got[i], got[j]) })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the files in walk order\n%v\ngot\n%v", want, got)
+	}
+}
+
+func TestWalkParallelStopsOnError(t *testing.T) {
+	root := writeWalkTree(t)
+	stop := errors.New("stop")
+	err := walkParallel(root, 3, func(path string, entry os.DirEntry) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the visit error, got %v", err)
+	}
+	if err := walkParallel(filepath.Join(root, "missing"), 2, func(string, os.DirEntry) error { return nil }); err == nil {
+		t.Error("Expected an error for a missing root")
+	}
+}
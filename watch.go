@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch runs an incremental conversion and then converts again whenever the
+// source changes, waiting for debounce of quiet time so that exports being
+// copied in are picked up in one pass. It returns when ctx is cancelled.
+func (c *Converter) Watch(ctx context.Context, debounce time.Duration) error {
+	if archiveFormat(c.config.OutputDir) != "" {
+		return fmt.Errorf("watch mode requires a directory output")
+	}
+	c.config.Incremental = true
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := c.addWatches(watcher); err != nil {
+		return err
+	}
+
+	c.runWatchedConversion(ctx)
+	fmt.Fprintf(c.out, "\nWatching %s for changes (Ctrl+C to stop)...\n", c.config.SourceDir)
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	// Scheduled runs fire from their own timer; a nil channel never does
+	var scheduled <-chan time.Time
+	var scheduleTimer *time.Timer
+	if c.schedule != nil {
+		next := c.schedule.Next(time.Now())
+		scheduleTimer = time.NewTimer(time.Until(next))
+		defer scheduleTimer.Stop()
+		scheduled = scheduleTimer.C
+		fmt.Fprintf(c.out, "Next scheduled conversion at %s\n", next.Format(time.RFC3339))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !c.isSourcePath(event.Name) {
+				continue
+			}
+
+			// New subdirectories in images/ need their own watch
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					c.watchTree(watcher, event.Name)
+				}
+			}
+			timer.Reset(debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(c.out, "Watch error: %v\n", err)
+
+		case <-timer.C:
+			// Directories may have been replaced wholesale by a fresh export
+			if err := c.addWatches(watcher); err != nil {
+				fmt.Fprintf(c.out, "Watch error: %v\n", err)
+			}
+			c.runWatchedConversion(ctx)
+
+		case <-scheduled:
+			// Conversions run on this goroutine, so scheduled runs never
+			// overlap with each other or with change-triggered ones
+			fmt.Fprintf(c.out, "Running the scheduled conversion\n")
+			if err := c.addWatches(watcher); err != nil {
+				fmt.Fprintf(c.out, "Watch error: %v\n", err)
+			}
+			c.runWatchedConversion(ctx)
+			next := c.schedule.Next(time.Now())
+			scheduleTimer.Reset(time.Until(next))
+			fmt.Fprintf(c.out, "Next scheduled conversion at %s\n", next.Format(time.RFC3339))
+		}
+	}
+}
+
+// addWatches watches the source root (for classes.txt), labels/, videos/ and
+// every directory below images/
+func (c *Converter) addWatches(watcher *fsnotify.Watcher) error {
+	if err := watcher.Add(c.config.SourceDir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", c.config.SourceDir, err)
+	}
+
+	for _, name := range []string{"labels", "videos"} {
+		dir := filepath.Join(c.config.SourceDir, name)
+		if _, err := os.Stat(dir); err == nil {
+			if err := watcher.Add(dir); err != nil {
+				return fmt.Errorf("failed to watch %s: %w", dir, err)
+			}
+		}
+	}
+
+	imagesDir := filepath.Join(c.config.SourceDir, "images")
+	if _, err := os.Stat(imagesDir); err == nil {
+		c.watchTree(watcher, imagesDir)
+	}
+	return nil
+}
+
+// exportFileExtensions are the extensions of the annotation files that
+// input formats read from the top of the source directory
+var exportFileExtensions = map[string][]string{
+	InputLSJSON:     {".json"},
+	InputCVAT:       {".xml"},
+	InputOpenImages: {".csv"},
+	InputVIA:        {".json", ".csv"},
+	InputLabelbox:   {".ndjson"},
+	InputAuto:       {".json", ".xml", ".csv", ".ndjson"},
+}
+
+// isSourcePath reports whether a changed path is part of the export, which
+// also keeps our own writes from retriggering when the output lives inside
+// the source directory. Top-level annotation files count for the input
+// formats that read them.
+func (c *Converter) isSourcePath(name string) bool {
+	rel, err := filepath.Rel(c.config.SourceDir, name)
+	if err != nil {
+		return false
+	}
+	if rel == "classes.txt" {
+		return true
+	}
+	if filepath.Dir(rel) == "." {
+		for _, ext := range exportFileExtensions[c.config.InputFormat] {
+			if strings.EqualFold(filepath.Ext(rel), ext) {
+				return true
+			}
+		}
+	}
+	top := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+	return top == "images" || top == "labels" || top == "videos"
+}
+
+// watchTree adds a watch for dir and all directories below it
+func (c *Converter) watchTree(watcher *fsnotify.Watcher, dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil {
+			fmt.Fprintf(c.out, "Warning: cannot watch %s: %v\n", path, err)
+		}
+		return nil
+	})
+}
+
+// runWatchedConversion converts once, reporting failures without stopping the
+// watch since the next export usually fixes them. Cancelling ctx stops the
+// conversion in progress.
+func (c *Converter) runWatchedConversion(ctx context.Context) {
+	err := c.ConvertContext(ctx)
+	if errors.Is(err, ErrInterrupted) {
+		fmt.Fprintf(c.out, "Conversion interrupted\n")
+	} else if err != nil {
+		fmt.Fprintf(c.out, "Conversion failed: %v\n", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchConvertsNewPairs(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+
+	converter := NewConverter(Config{
+		SourceDir:  sourceDir,
+		OutputDir:  outputDir,
+		TrainSplit: 0.8,
+		Seed:       42,
+	})
+	converter.SetOutput(io.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- converter.Watch(ctx, 20*time.Millisecond)
+	}()
+
+	waitForImage := func(name string) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			for _, split := range splitNames {
+				if _, err := os.Stat(filepath.Join(outputDir, "images", split, name)); err == nil {
+					return true
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	if !waitForImage("image1.jpg") {
+		t.Fatal("Initial conversion did not run")
+	}
+
+	if err := os.WriteFile(filepath.Join(sourceDir, "labels", "image4.txt"), []byte("0 0.5 0.5 0.2 0.2\n"), 0644); err != nil {
+		t.Fatalf("Failed to create label: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "images", "image4.jpg"), []byte("new image"), 0644); err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+
+	if !waitForImage("image4.jpg") {
+		t.Error("New pair was not converted after a source change")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch returned error: %v", err)
+	}
+}
+
+func TestWatchStopsConversionOnCancel(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFiles(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "yolo_output")
+
+	var out strings.Builder
+	converter := NewConverter(Config{SourceDir: sourceDir, OutputDir: outputDir, TrainSplit: 0.8, Seed: 42})
+	converter.SetOutput(&out)
+
+	// The watch's context reaches the conversion, which stops before copying
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := converter.Watch(ctx, 20*time.Millisecond); err != nil {
+		t.Fatalf("Watch returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Conversion interrupted") {
+		t.Errorf("Expected the conversion to be interrupted, got:\n%s", out.String())
+	}
+	for _, split := range splitNames {
+		if _, err := os.Stat(filepath.Join(outputDir, "images", split, "image1.jpg")); err == nil {
+			t.Errorf("Expected no images copied after cancellation, found one in %s", split)
+		}
+	}
+}
+
+func TestIsSourcePath(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: "/data/export", OutputDir: "/data/export/yolo_dataset"})
+
+	tests := map[string]bool{
+		"/data/export/classes.txt":               true,
+		"/data/export/images/a.jpg":              true,
+		"/data/export/images/cam1/a.jpg":         true,
+		"/data/export/labels/a.txt":              true,
+		"/data/export/yolo_dataset/data.yaml":    false,
+		"/data/export/notes.json":                false,
+		"/data/export/yolo_dataset/images/a.jpg": false,
+	}
+	for name, expected := range tests {
+		if got := converter.isSourcePath(name); got != expected {
+			t.Errorf("isSourcePath(%q) = %v, expected %v", name, got, expected)
+		}
+	}
+}
+
+func TestWatchRejectsArchiveOutput(t *testing.T) {
+	converter := NewConverter(Config{SourceDir: t.TempDir(), OutputDir: "dataset.tar.gz"})
+	if err := converter.Watch(context.Background(), time.Second); err == nil {
+		t.Error("Expected error for watch mode with archive output")
+	}
+}
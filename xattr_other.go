@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package main
+
+// readXattrs returns no extended attributes on platforms without support
+func readXattrs(path string) map[string][]byte {
+	return nil
+}
+
+// copyXattrs is a no-op on platforms without extended attribute support
+func copyXattrs(src, dst string) {}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package main
+
+import "errors"
+
+func setTestXattr(path, name, value string) error {
+	return errors.New("not supported on this platform")
+}
@@ -0,0 +1,47 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+// readXattrs returns the extended attributes of a file. Errors, such as a
+// file system without extended attributes, yield none.
+func readXattrs(path string) map[string][]byte {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil || size <= 0 {
+		return nil
+	}
+	list := make([]byte, size)
+	if size, err = unix.Listxattr(path, list); err != nil {
+		return nil
+	}
+
+	attrs := make(map[string][]byte)
+	for _, name := range bytes.Split(list[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		n, err := unix.Getxattr(path, string(name), nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, n)
+		if n, err = unix.Getxattr(path, string(name), value); err != nil {
+			continue
+		}
+		attrs[string(name)] = value[:n]
+	}
+	return attrs
+}
+
+// copyXattrs copies the extended attributes of src to dst where possible;
+// attributes the destination refuses, such as security labels, are skipped
+func copyXattrs(src, dst string) {
+	for name, value := range readXattrs(src) {
+		unix.Setxattr(dst, name, value, 0)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build linux || darwin
+
+package main
+
+import "golang.org/x/sys/unix"
+
+func setTestXattr(path, name, value string) error {
+	return unix.Setxattr(path, name, []byte(value), 0)
+}